@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/szcdx/runc/libcontainer"
+	"github.com/szcdx/runc/libcontainer/utils"
+	"github.com/urfave/cli"
+)
+
+// batchResult is the outcome of a batch operation on a single container.
+type batchResult struct {
+	// ID is the container ID
+	ID string `json:"id"`
+	// Error is the error message, if the operation failed
+	Error string `json:"error,omitempty"`
+}
+
+var batchCommand = cli.Command{
+	Name:  "batch",
+	Usage: "start, kill, or delete multiple containers concurrently",
+	ArgsUsage: `<start|kill|delete> <container-id> [container-id...]
+
+Where "<container-id>" is the name for the instance of the container.
+
+EXAMPLE:
+For example, to forcibly delete containers "ctr1", "ctr2", and "ctr3",
+running at most two operations at a time:
+
+       # runc batch --jobs 2 --force delete ctr1 ctr2 ctr3`,
+	Flags: []cli.Flag{
+		cli.IntFlag{
+			Name:  "jobs, j",
+			Value: 8,
+			Usage: "maximum number of operations to run concurrently",
+		},
+		cli.StringFlag{
+			Name:  "signal, s",
+			Value: "SIGTERM",
+			Usage: "signal to send to the containers' init processes (kill only)",
+		},
+		cli.BoolFlag{
+			Name:  "force, f",
+			Usage: "forcibly delete the containers if they are still running (delete only)",
+		},
+		cli.StringFlag{
+			Name:  "format",
+			Value: "table",
+			Usage: `select one of: ` + formatOptions,
+		},
+	},
+	Action: func(context *cli.Context) error {
+		if err := checkArgs(context, 2, minArgs); err != nil {
+			return err
+		}
+		jobs := context.Int("jobs")
+		if jobs <= 0 {
+			return errors.New("jobs must be greater than 0")
+		}
+		format := context.String("format")
+		if format != "table" && format != "json" {
+			return errors.New("invalid format option")
+		}
+		root := context.GlobalString("root")
+		var op func(id string) error
+		switch name := context.Args().First(); name {
+		case "start":
+			op = func(id string) error {
+				container, err := libcontainer.Load(root, id)
+				if err != nil {
+					return err
+				}
+				return batchStart(container)
+			}
+		case "kill":
+			signal, err := utils.ParseSignal(context.String("signal"))
+			if err != nil {
+				return err
+			}
+			op = func(id string) error {
+				container, err := libcontainer.Load(root, id)
+				if err != nil {
+					return err
+				}
+				return container.Signal(signal)
+			}
+		case "delete":
+			force := context.Bool("force")
+			op = func(id string) error {
+				return deleteContainerByID(root, id, force, "")
+			}
+		default:
+			return fmt.Errorf("unknown batch operation %q", name)
+		}
+
+		results := runBatch(context.Args().Tail(), jobs, op)
+
+		if format == "json" {
+			if err := json.NewEncoder(os.Stdout).Encode(results); err != nil {
+				return err
+			}
+		} else {
+			w := tabwriter.NewWriter(os.Stdout, 12, 1, 3, ' ', 0)
+			fmt.Fprint(w, "ID\tRESULT\n")
+			for _, r := range results {
+				res := "ok"
+				if r.Error != "" {
+					res = r.Error
+				}
+				fmt.Fprintf(w, "%s\t%s\n", r.ID, res)
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+
+		failed := 0
+		for _, r := range results {
+			if r.Error != "" {
+				failed++
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d operations failed", failed, len(results))
+		}
+		return nil
+	},
+}
+
+// runBatch calls op for every id, running at most jobs calls concurrently,
+// and returns the results in the same order as ids.
+func runBatch(ids []string, jobs int, op func(id string) error) []batchResult {
+	results := make([]batchResult, len(ids))
+	idx := make(chan int)
+	var wg sync.WaitGroup
+	if jobs > len(ids) {
+		jobs = len(ids)
+	}
+	for i := 0; i < jobs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range idx {
+				results[i].ID = ids[i]
+				if err := op(ids[i]); err != nil {
+					results[i].Error = err.Error()
+				}
+			}
+		}()
+	}
+	for i := range ids {
+		idx <- i
+	}
+	close(idx)
+	wg.Wait()
+	return results
+}
+
+func batchStart(container *libcontainer.Container) error {
+	status, err := container.Status()
+	if err != nil {
+		return err
+	}
+	if status != libcontainer.Created {
+		return fmt.Errorf("cannot start a container in the %s state", status)
+	}
+	return container.Exec()
+}
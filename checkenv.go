@@ -0,0 +1,300 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/moby/sys/mountinfo"
+	"github.com/szcdx/runc/libcontainer/cgroups"
+	"github.com/szcdx/runc/libcontainer/seccomp"
+	"github.com/urfave/cli"
+	"golang.org/x/sys/unix"
+)
+
+// Severities of the check-environment findings.
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+)
+
+// finding is a problem with the host environment found by check-environment.
+type finding struct {
+	// Check is the name of the check which reported the problem
+	Check string `json:"check"`
+	// Severity is either "error" or "warning"
+	Severity string `json:"severity"`
+	// Message describes the problem
+	Message string `json:"message"`
+	// Remedy describes how to fix the problem
+	Remedy string `json:"remedy,omitempty"`
+}
+
+var checkEnvironmentCommand = cli.Command{
+	Name:  "check-environment",
+	Usage: "check the host for setups known to be dangerous or unsupported",
+	Description: `The check-environment command inspects the host runc is running on for
+setups which are known to be dangerous (such as a world-writable state
+directory or a writable runc binary) or to break some of runc's features
+(such as missing cgroup controllers or an outdated libseccomp), and prints
+its findings. It exits with an error if any of the findings is an error.`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "format",
+			Value: "table",
+			Usage: `select one of: ` + formatOptions,
+		},
+	},
+	Action: func(context *cli.Context) error {
+		if err := checkArgs(context, 0, exactArgs); err != nil {
+			return err
+		}
+		var findings []finding
+		for _, check := range []func() []finding{
+			func() []finding { return checkStateRoot(context.GlobalString("root")) },
+			checkRuncBinary,
+			checkProc,
+			checkCgroupControllers,
+			checkSeccomp,
+			checkLockdown,
+		} {
+			findings = append(findings, check()...)
+		}
+
+		switch context.String("format") {
+		case "table":
+			w := tabwriter.NewWriter(os.Stdout, 12, 1, 3, ' ', 0)
+			fmt.Fprint(w, "SEVERITY\tCHECK\tMESSAGE\tREMEDY\n")
+			for _, f := range findings {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", f.Severity, f.Check, f.Message, f.Remedy)
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		case "json":
+			if findings == nil {
+				findings = []finding{}
+			}
+			if err := json.NewEncoder(os.Stdout).Encode(findings); err != nil {
+				return err
+			}
+		default:
+			return errors.New("invalid format option")
+		}
+
+		errs := 0
+		for _, f := range findings {
+			if f.Severity == severityError {
+				errs++
+			}
+		}
+		if errs > 0 {
+			return fmt.Errorf("%d error(s) found", errs)
+		}
+		return nil
+	},
+}
+
+// checkStateRoot checks that the state directory can not be tampered with by
+// unprivileged users.
+func checkStateRoot(root string) []finding {
+	fi, err := os.Stat(root)
+	if err != nil {
+		// A missing root is created by runc with safe permissions.
+		return nil
+	}
+	mode := fi.Mode().Perm()
+	switch {
+	case mode&0o002 != 0:
+		return []finding{{
+			Check:    "state-root",
+			Severity: severityError,
+			Message:  fmt.Sprintf("state directory %s is world-writable (mode %#o)", root, mode),
+			Remedy:   "chmod o-w " + root,
+		}}
+	case mode&0o020 != 0:
+		return []finding{{
+			Check:    "state-root",
+			Severity: severityWarning,
+			Message:  fmt.Sprintf("state directory %s is group-writable (mode %#o)", root, mode),
+			Remedy:   "chmod g-w " + root,
+		}}
+	}
+	return nil
+}
+
+// checkRuncBinary checks that the runc binary can not be overwritten, either
+// through its file permissions or through a writable bind mount.
+func checkRuncBinary() []finding {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil
+	}
+	if p, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = p
+	}
+	fi, err := os.Stat(exe)
+	if err != nil {
+		return nil
+	}
+	var findings []finding
+	if mode := fi.Mode().Perm(); mode&0o022 != 0 {
+		findings = append(findings, finding{
+			Check:    "runc-binary",
+			Severity: severityError,
+			Message:  fmt.Sprintf("runc binary %s is writable by group or others (mode %#o)", exe, mode),
+			Remedy:   "chmod go-w " + exe,
+		})
+	}
+	if st, ok := fi.Sys().(*unix.Stat_t); ok && st.Uid != 0 && os.Geteuid() == 0 {
+		findings = append(findings, finding{
+			Check:    "runc-binary",
+			Severity: severityWarning,
+			Message:  fmt.Sprintf("runc binary %s is owned by uid %d rather than root", exe, st.Uid),
+			Remedy:   "chown root " + exe,
+		})
+	}
+	mounts, err := mountinfo.GetMounts(mountinfo.SingleEntryFilter(exe))
+	if err == nil && len(mounts) > 0 {
+		m := mounts[len(mounts)-1]
+		readOnly := false
+		for _, o := range strings.Split(m.Options, ",") {
+			if o == "ro" {
+				readOnly = true
+			}
+		}
+		if !readOnly {
+			findings = append(findings, finding{
+				Check:    "runc-binary",
+				Severity: severityWarning,
+				Message:  fmt.Sprintf("runc binary %s is bind-mounted read-write", exe),
+				Remedy:   "remount the binary read-only (mount -o remount,bind,ro " + exe + ")",
+			})
+		}
+	}
+	return findings
+}
+
+// checkProc checks that /proc is a real procfs mount.
+func checkProc() []finding {
+	var st unix.Statfs_t
+	if err := unix.Statfs("/proc", &st); err != nil {
+		return []finding{{
+			Check:    "proc",
+			Severity: severityError,
+			Message:  fmt.Sprintf("unable to statfs /proc: %v", err),
+			Remedy:   "mount -t proc proc /proc",
+		}}
+	}
+	if st.Type != unix.PROC_SUPER_MAGIC {
+		return []finding{{
+			Check:    "proc",
+			Severity: severityError,
+			Message:  fmt.Sprintf("/proc is not a procfs mount (filesystem type %#x)", st.Type),
+			Remedy:   "mount -t proc proc /proc",
+		}}
+	}
+	return nil
+}
+
+// checkCgroupControllers checks that the cgroup controllers most container
+// configurations rely upon are available.
+func checkCgroupControllers() []finding {
+	required := []string{"cpu", "memory", "pids"}
+	available := make(map[string]bool)
+	if cgroups.IsCgroup2UnifiedMode() {
+		data, err := os.ReadFile("/sys/fs/cgroup/cgroup.controllers")
+		if err != nil {
+			return []finding{{
+				Check:    "cgroups",
+				Severity: severityError,
+				Message:  fmt.Sprintf("unable to read the available cgroup controllers: %v", err),
+			}}
+		}
+		for _, c := range strings.Fields(string(data)) {
+			available[c] = true
+		}
+	} else {
+		required = append(required, "devices")
+		data, err := os.ReadFile("/proc/cgroups")
+		if err != nil {
+			return []finding{{
+				Check:    "cgroups",
+				Severity: severityError,
+				Message:  fmt.Sprintf("unable to read the available cgroup controllers: %v", err),
+			}}
+		}
+		// Format: subsys_name hierarchy num_cgroups enabled.
+		for _, line := range strings.Split(string(data), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) == 4 && fields[3] == "1" {
+				available[fields[0]] = true
+			}
+		}
+	}
+	var findings []finding
+	for _, c := range required {
+		if !available[c] {
+			findings = append(findings, finding{
+				Check:    "cgroups",
+				Severity: severityWarning,
+				Message:  fmt.Sprintf("cgroup controller %q is not available, resource limits using it will be ignored or fail", c),
+				Remedy:   "enable the controller in the kernel configuration or on the kernel command line",
+			})
+		}
+	}
+	return findings
+}
+
+// checkSeccomp checks that runc is built with seccomp support, and that
+// libseccomp is recent enough to support all the features runc provides.
+func checkSeccomp() []finding {
+	if !seccomp.Enabled {
+		return []finding{{
+			Check:    "seccomp",
+			Severity: severityWarning,
+			Message:  "runc is built without seccomp support, seccomp profiles will be rejected",
+			Remedy:   "rebuild runc with the seccomp build tag",
+		}}
+	}
+	major, minor, patch := seccomp.Version()
+	if major < 2 || (major == 2 && minor < 5) {
+		return []finding{{
+			Check:    "seccomp",
+			Severity: severityWarning,
+			Message:  fmt.Sprintf("libseccomp %d.%d.%d is older than 2.5.0, SCMP_ACT_NOTIFY and some filter flags are not supported", major, minor, patch),
+			Remedy:   "upgrade libseccomp to 2.5.0 or later",
+		}}
+	}
+	return nil
+}
+
+// checkLockdown checks whether the kernel lockdown mode restricts features
+// used by runc.
+func checkLockdown() []finding {
+	data, err := os.ReadFile("/sys/kernel/security/lockdown")
+	if err != nil {
+		// No lockdown support, or securityfs is not mounted.
+		return nil
+	}
+	// The current mode is in brackets, e.g. "none [integrity] confidentiality".
+	mode := ""
+	for _, m := range strings.Fields(string(data)) {
+		if strings.HasPrefix(m, "[") && strings.HasSuffix(m, "]") {
+			mode = strings.Trim(m, "[]")
+		}
+	}
+	if mode == "confidentiality" {
+		return []finding{{
+			Check:    "lockdown",
+			Severity: severityWarning,
+			Message:  "kernel lockdown is in confidentiality mode, checkpoint/restore and some eBPF based features may not work",
+			Remedy:   "use the integrity lockdown mode if these features are needed",
+		}}
+	}
+	return nil
+}
@@ -7,11 +7,13 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 
 	criu "github.com/checkpoint-restore/go-criu/v6/rpc"
 	"github.com/opencontainers/runtime-spec/specs-go"
 	"github.com/sirupsen/logrus"
 	"github.com/szcdx/runc/libcontainer"
+	"github.com/szcdx/runc/libcontainer/configs"
 	"github.com/szcdx/runc/libcontainer/userns"
 	"github.com/urfave/cli"
 	"golang.org/x/sys/unix"
@@ -31,6 +33,9 @@ checkpointed.`,
 		cli.StringFlag{Name: "parent-path", Value: "", Usage: "path for previous criu image files in pre-dump"},
 		cli.BoolFlag{Name: "leave-running", Usage: "leave the process running after checkpointing"},
 		cli.BoolFlag{Name: "tcp-established", Usage: "allow open tcp connections"},
+		cli.BoolFlag{Name: "tcp-close", Usage: "checkpoint established tcp connections as closed"},
+		cli.BoolFlag{Name: "tcp-skip-in-flight", Usage: "skip the tcp connections which are not established yet (requires --tcp-established)"},
+		cli.StringFlag{Name: "network-lock", Value: "", Usage: "method to lock the network with while checkpointing: iptables|nftables (default: iptables)"},
 		cli.BoolFlag{Name: "ext-unix-sk", Usage: "allow external unix sockets"},
 		cli.BoolFlag{Name: "shell-job", Usage: "allow shell jobs"},
 		cli.BoolFlag{Name: "lazy-pages", Usage: "use userfaultfd to lazily restore memory pages"},
@@ -41,6 +46,10 @@ checkpointed.`,
 		cli.StringFlag{Name: "manage-cgroups-mode", Value: "", Usage: "cgroups mode: soft|full|strict|ignore (default: soft)"},
 		cli.StringSliceFlag{Name: "empty-ns", Usage: "create a namespace, but don't restore its properties"},
 		cli.BoolFlag{Name: "auto-dedup", Usage: "enable auto deduplication of memory images"},
+		cli.BoolFlag{Name: "track-mem", Usage: "track memory changes, dumping on top of the last pre-dump unless --parent-path is set"},
+		cli.BoolFlag{Name: "sparse-images", Usage: "punch holes in the zero-filled blocks of memory images"},
+		cli.DurationFlag{Name: "freeze-timeout", Usage: "freeze the container (cgroup v2) before checkpointing, failing if it takes longer than this to be frozen"},
+		cli.StringFlag{Name: "stream", Value: "", Usage: "tcp://HOST:PORT to stream the images to (with criu-image-streamer), instead of writing them to the image path"},
 	},
 	Action: func(context *cli.Context) error {
 		if err := checkArgs(context, 1, exactArgs); err != nil {
@@ -80,6 +89,13 @@ checkpointed.`,
 
 func prepareImagePaths(context *cli.Context) (string, string, error) {
 	imagePath := context.String("image-path")
+	// The snapshot images are only read.
+	if snapshot := context.String("from-snapshot"); snapshot != "" {
+		if imagePath != "" {
+			return "", "", errors.New("--from-snapshot and --image-path can not be used together")
+		}
+		return snapshot, "", nil
+	}
 	if imagePath == "" {
 		imagePath = getDefaultImagePath()
 	}
@@ -122,19 +138,35 @@ func criuOptions(context *cli.Context) (*libcontainer.CriuOpts, error) {
 		ParentImage:             parentPath,
 		LeaveRunning:            context.Bool("leave-running"),
 		TcpEstablished:          context.Bool("tcp-established"),
+		TcpClose:                context.Bool("tcp-close"),
+		TcpSkipInFlight:         context.Bool("tcp-skip-in-flight"),
 		ExternalUnixConnections: context.Bool("ext-unix-sk"),
 		ShellJob:                context.Bool("shell-job"),
 		FileLocks:               context.Bool("file-locks"),
 		PreDump:                 context.Bool("pre-dump"),
 		AutoDedup:               context.Bool("auto-dedup"),
+		TrackMem:                context.Bool("track-mem"),
 		LazyPages:               context.Bool("lazy-pages"),
+		SparseImages:            context.Bool("sparse-images"),
 		StatusFd:                context.Int("status-fd"),
+		FreezeTimeout:           context.Duration("freeze-timeout"),
 		LsmProfile:              context.String("lsm-profile"),
 		LsmMountContext:         context.String("lsm-mount-context"),
+		Template:                context.String("from-snapshot") != "",
 	}
 
 	// CRIU options below may or may not be set.
 
+	for _, path := range context.StringSlice("network-restore-hook") {
+		if !filepath.IsAbs(path) {
+			return nil, errors.New("--network-restore-hook must be an absolute path")
+		}
+		opts.NetworkRestoreHooks = append(opts.NetworkRestoreHooks, configs.NewCommandHook(configs.Command{
+			Path: path,
+			Args: []string{path},
+		}))
+	}
+
 	if psOpt := context.String("page-server"); psOpt != "" {
 		address, port, err := net.SplitHostPort(psOpt)
 
@@ -151,6 +183,14 @@ func criuOptions(context *cli.Context) (*libcontainer.CriuOpts, error) {
 		}
 	}
 
+	if stream := context.String("stream"); stream != "" {
+		addr, ok := strings.CutPrefix(stream, "tcp://")
+		if _, _, err := net.SplitHostPort(addr); !ok || err != nil {
+			return nil, errors.New("Use --stream tcp://HOST:PORT to specify the image stream address")
+		}
+		opts.Stream = addr
+	}
+
 	switch context.String("manage-cgroups-mode") {
 	case "":
 		// do nothing
@@ -166,6 +206,17 @@ func criuOptions(context *cli.Context) (*libcontainer.CriuOpts, error) {
 		return nil, errors.New("Invalid manage-cgroups-mode value")
 	}
 
+	switch context.String("network-lock") {
+	case "":
+		// do nothing
+	case "iptables":
+		opts.NetworkLock = criu.CriuNetworkLockMethod_IPTABLES
+	case "nftables":
+		opts.NetworkLock = criu.CriuNetworkLockMethod_NFTABLES
+	default:
+		return nil, errors.New("Invalid network-lock value")
+	}
+
 	// runc doesn't manage network devices and their configuration.
 	nsmask := unix.CLONE_NEWNET
 
@@ -0,0 +1,78 @@
+// mount-driver is a sample runc mount driver (see docs/mount-drivers.md),
+// which mounts the requested filesystem with mount(2), in a mount namespace
+// of its own, and sends it back to runc as an open_tree(2) file descriptor.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/szcdx/runc/libcontainer/utils"
+)
+
+type request struct {
+	ID          string   `json:"id"`
+	Type        string   `json:"type"`
+	Source      string   `json:"source"`
+	Destination string   `json:"destination"`
+	Options     []string `json:"options"`
+}
+
+func main() {
+	if len(os.Args) != 2 || os.Args[1] != "mount" {
+		fmt.Fprintln(os.Stderr, "usage:", os.Args[0], "mount")
+		os.Exit(1)
+	}
+	if err := mount(); err != nil {
+		fmt.Fprintln(os.Stderr, "fatal error:", err)
+		os.Exit(1)
+	}
+}
+
+func mount() error {
+	var req request
+	if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil {
+		return fmt.Errorf("bad request: %w", err)
+	}
+	if req.Type == "" {
+		return errors.New("bad request: no type")
+	}
+	sock := os.NewFile(3, "socket")
+	if sock == nil {
+		return errors.New("no socket")
+	}
+	defer sock.Close()
+
+	// Mount in a mount namespace of our own, so that nothing is left
+	// behind once the mount is sent.
+	runtime.LockOSThread()
+	if err := unix.Unshare(unix.CLONE_NEWNS); err != nil {
+		return os.NewSyscallError("unshare", err)
+	}
+	if err := unix.Mount("", "/", "", unix.MS_SLAVE|unix.MS_REC, ""); err != nil {
+		return &os.PathError{Op: "mount", Path: "/", Err: err}
+	}
+	dir, err := os.MkdirTemp("", "mount-driver")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(dir)
+	if err := unix.Mount(req.Source, dir, req.Type, 0, strings.Join(req.Options, ",")); err != nil {
+		return &os.PathError{Op: "mount", Path: dir, Err: err}
+	}
+	defer unix.Unmount(dir, unix.MNT_DETACH) //nolint:errcheck
+
+	fd, err := unix.OpenTree(unix.AT_FDCWD, dir, unix.OPEN_TREE_CLONE|unix.OPEN_TREE_CLOEXEC)
+	if err != nil {
+		return &os.PathError{Op: "open_tree", Path: dir, Err: err}
+	}
+	mnt := os.NewFile(uintptr(fd), req.Destination)
+	defer mnt.Close()
+	return utils.SendFile(sock, mnt)
+}
@@ -0,0 +1,60 @@
+// send-fds is a sample consumer of runc's --mount-socket API. It listens on
+// the given socket, and sends the given files (opened with O_PATH) to the
+// first runc connecting to it, for its fd://N mount sources (N being the
+// index of the file in the arguments).
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/szcdx/runc/libcontainer/utils"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage:", os.Args[0], "socket.sock [path ...]")
+		os.Exit(1)
+	}
+	if err := sendFds(os.Args[1], os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, "fatal error:", err)
+		os.Exit(1)
+	}
+}
+
+func sendFds(sockpath string, paths []string) error {
+	var files []*os.File
+	for _, p := range paths {
+		f, err := os.OpenFile(p, unix.O_PATH|unix.O_CLOEXEC, 0)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		files = append(files, f)
+	}
+
+	ln, err := net.Listen("unix", sockpath)
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+	conn, err := ln.Accept()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	socket, err := conn.(*net.UnixConn).File()
+	if err != nil {
+		return err
+	}
+	defer socket.Close()
+	for _, f := range files {
+		if err := utils.SendFile(socket, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
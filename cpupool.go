@@ -0,0 +1,41 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/szcdx/runc/libcontainer"
+	"github.com/urfave/cli"
+)
+
+var cpuPoolCommand = cli.Command{
+	Name:  "cpu-pool",
+	Usage: "show or set the pool of exclusive CPUs",
+	ArgsUsage: `[<cpus>]
+
+Where "<cpus>" is the new list of CPUs of the pool, such as "2-7".`,
+	Description: `The cpu-pool command shows the pool of CPUs from which the containers
+annotated with "org.opencontainers.runc.cpus.exclusive=<N>" are given N CPUs
+for their exclusive use, together with the CPUs allocated to every such
+container, or sets the pool if "<cpus>" is given. The pool is kept in the
+root directory, so the containers of different roots have separate pools.`,
+	Action: func(context *cli.Context) error {
+		if err := checkArgs(context, 1, maxArgs); err != nil {
+			return err
+		}
+		root := context.GlobalString("root")
+		if context.NArg() == 1 {
+			return libcontainer.SetCPUPool(root, context.Args().First())
+		}
+		pool, err := libcontainer.GetCPUPool(root)
+		if err != nil {
+			return err
+		}
+		data, err := json.MarshalIndent(pool, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(append(data, '\n'))
+		return err
+	},
+}
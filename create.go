@@ -38,6 +38,10 @@ command(s) that get executed on start, edit the args parameter of the spec. See
 			Name:  "pidfd-socket",
 			Usage: "path to an AF_UNIX socket which will receive a file descriptor referencing the init process",
 		},
+		cli.StringFlag{
+			Name:  "mount-socket",
+			Usage: "path to an AF_UNIX socket to receive the file descriptors of the fd://N mount sources from",
+		},
 		cli.StringFlag{
 			Name:  "pid-file",
 			Value: "",
@@ -47,6 +51,10 @@ command(s) that get executed on start, edit the args parameter of the spec. See
 			Name:  "no-pivot",
 			Usage: "do not use pivot root to jail process inside rootfs.  This should be used whenever the rootfs is on top of a ramdisk",
 		},
+		cli.BoolFlag{
+			Name:  "allow-ns-owner-mismatch",
+			Usage: "only warn about the namespaces to join which are owned by a user namespace the container has no privileges over",
+		},
 		cli.BoolFlag{
 			Name:  "no-new-keyring",
 			Usage: "do not create a new session keyring for the container.  This will cause the container to inherit the calling processes session key",
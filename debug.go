@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/szcdx/runc/libcontainer"
+	"github.com/urfave/cli"
+)
+
+// initLogEntry is an entry of the container's init log.
+type initLogEntry struct {
+	Time  string `json:"time"`
+	Level string `json:"level"`
+	Msg   string `json:"msg"`
+}
+
+var debugCommand = cli.Command{
+	Name:  "debug",
+	Usage: "show the log of a container's init process",
+	ArgsUsage: `<container-id>
+
+Where "<container-id>" is your name for the instance of the container.`,
+	Description: `The debug command shows the messages the container's init process emitted
+after the container was created, which runc could not report otherwise. For
+example, if the container process can not be executed upon "runc start", the
+reason is found there. The log is kept until the container is deleted.
+
+With --fds, it shows the file descriptors referring to the container (the files
+in its state directory, such as the exec fifo, its cgroup directories and files,
+pidfds of its processes, and its namespaces) which are held by processes on the
+host other than the container processes, together with the number of open file
+descriptors of each such process and its limit, to help find the leaks of file
+descriptors.`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "format, f",
+			Value: "table",
+			Usage: `select one of: ` + formatOptions,
+		},
+		cli.BoolFlag{
+			Name:  "fds",
+			Usage: "show the file descriptors referring to the container held by other processes",
+		},
+	},
+	Action: func(context *cli.Context) error {
+		if err := checkArgs(context, 1, exactArgs); err != nil {
+			return err
+		}
+		container, err := getContainer(context)
+		if err != nil {
+			return err
+		}
+		if context.Bool("fds") {
+			return showFdHolders(container, context.String("format"))
+		}
+		data, err := container.InitLog()
+		if err != nil {
+			return err
+		}
+		entries := []initLogEntry{}
+		s := bufio.NewScanner(bytes.NewReader(data))
+		for s.Scan() {
+			var e initLogEntry
+			if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+				// Raw stderr output, rather than a log entry.
+				e = initLogEntry{Msg: s.Text()}
+			}
+			entries = append(entries, e)
+		}
+		if err := s.Err(); err != nil {
+			return err
+		}
+
+		switch context.String("format") {
+		case "table":
+			w := tabwriter.NewWriter(os.Stdout, 12, 1, 3, ' ', 0)
+			fmt.Fprint(w, "TIME\tLEVEL\tMESSAGE\n")
+			for _, e := range entries {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", e.Time, e.Level, e.Msg)
+			}
+			return w.Flush()
+		case "json":
+			return json.NewEncoder(os.Stdout).Encode(entries)
+		default:
+			return errors.New("invalid format option")
+		}
+	},
+}
+
+// showFdHolders shows the file descriptors referring to the container which
+// are held by other processes.
+func showFdHolders(container *libcontainer.Container, format string) error {
+	holders, err := container.FdHolders()
+	if err != nil {
+		return err
+	}
+	switch format {
+	case "table":
+		w := tabwriter.NewWriter(os.Stdout, 6, 1, 3, ' ', 0)
+		fmt.Fprint(w, "PID\tCOMMAND\tFD\tKIND\tTARGET\tOPEN FDS\n")
+		for _, h := range holders {
+			limit := "unlimited"
+			if h.FdLimit != 0 {
+				limit = strconv.FormatUint(h.FdLimit, 10)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%d\t%s\t%s\t%d/%s\n", h.Pid, h.Comm, h.Fd, h.Kind, h.Target, h.OpenFds, limit)
+		}
+		return w.Flush()
+	case "json":
+		if holders == nil {
+			holders = []libcontainer.FdHolder{}
+		}
+		return json.NewEncoder(os.Stdout).Encode(holders)
+	default:
+		return errors.New("invalid format option")
+	}
+}
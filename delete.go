@@ -1,29 +1,87 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/szcdx/runc/libcontainer"
+	"github.com/szcdx/runc/types"
 	"github.com/urfave/cli"
 
 	"golang.org/x/sys/unix"
 )
 
-func killContainer(container *libcontainer.Container) error {
+// killContainer kills the container and destroys it. If the container has
+// its stop settings configured, it is stopped gracefully instead (see
+// libcontainer.Container.Stop).
+func killContainer(container *libcontainer.Container, usageFile string) error {
+	if container.Config().Stop != nil {
+		if err := container.Stop(); err != nil {
+			return err
+		}
+		return destroyContainer(container, usageFile)
+	}
 	_ = container.Signal(unix.SIGKILL)
 	for i := 0; i < 100; i++ {
 		time.Sleep(100 * time.Millisecond)
 		if err := container.Signal(unix.Signal(0)); err != nil {
-			return container.Destroy()
+			return destroyContainer(container, usageFile)
 		}
 	}
 	return errors.New("container init still running")
 }
 
+// destroyContainer destroys the container. If usageFile is set, the
+// container resource usage summary is written to it first.
+func destroyContainer(container *libcontainer.Container, usageFile string) error {
+	var usageErr error
+	if usageFile != "" {
+		usageErr = writeUsage(container, usageFile)
+	}
+	if err := container.Destroy(); err != nil {
+		return err
+	}
+	if usageErr != nil {
+		return fmt.Errorf("unable to write usage file: %w", usageErr)
+	}
+	return nil
+}
+
+func writeUsage(container *libcontainer.Container, path string) error {
+	s, err := container.Stats()
+	if err != nil {
+		return err
+	}
+	u := &types.Usage{ID: container.ID()}
+	if cg := s.CgroupStats; cg != nil {
+		u.CPUTotal = cg.CpuStats.CpuUsage.TotalUsage
+		u.CPUUser = cg.CpuStats.CpuUsage.UsageInUsermode
+		u.CPUKernel = cg.CpuStats.CpuUsage.UsageInKernelmode
+		u.MemoryPeak = cg.MemoryStats.Usage.MaxUsage
+		for _, e := range cg.BlkioStats.IoServiceBytesRecursive {
+			switch strings.ToLower(e.Op) {
+			case "read":
+				u.BlkioRead += e.Value
+			case "write":
+				u.BlkioWrite += e.Value
+			}
+		}
+	}
+	if oom, err := container.OOMKillInfo(); err == nil {
+		u.OOMKills = oom.Count
+	}
+	data, err := json.Marshal(u)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
 var deleteCommand = cli.Command{
 	Name:  "delete",
 	Usage: "delete any resources held by the container often used with detached container",
@@ -40,7 +98,11 @@ status of "ubuntu01" as "stopped" the following will delete resources held for
 	Flags: []cli.Flag{
 		cli.BoolFlag{
 			Name:  "force, f",
-			Usage: "Forcibly deletes the container if it is still running (uses SIGKILL)",
+			Usage: "Forcibly deletes the container if it is still running (uses SIGKILL, or the configured stop signal and grace period)",
+		},
+		cli.StringFlag{
+			Name:  "usage-file",
+			Usage: "write a summary of the container resource usage to the specified file before deleting it",
 		},
 	},
 	Action: func(context *cli.Context) error {
@@ -49,41 +111,57 @@ status of "ubuntu01" as "stopped" the following will delete resources held for
 		}
 
 		id := context.Args().First()
-		force := context.Bool("force")
-		container, err := getContainer(context)
-		if err != nil {
-			if errors.Is(err, libcontainer.ErrNotExist) {
-				// if there was an aborted start or something of the sort then the container's directory could exist but
-				// libcontainer does not see it because the state.json file inside that directory was never created.
-				path := filepath.Join(context.GlobalString("root"), id)
-				if e := os.RemoveAll(path); e != nil {
-					fmt.Fprintf(os.Stderr, "remove %s: %v\n", path, e)
-				}
-				if force {
-					return nil
-				}
-			}
-			return err
-		}
-		// When --force is given, we kill all container processes and
-		// then destroy the container. This is done even for a stopped
-		// container, because (in case it does not have its own PID
-		// namespace) there may be some leftover processes in the
-		// container's cgroup.
-		if force {
-			return killContainer(container)
-		}
-		s, err := container.Status()
-		if err != nil {
-			return err
-		}
-		switch s {
-		case libcontainer.Stopped:
-			return container.Destroy()
-		case libcontainer.Created:
-			return killContainer(container)
-		default:
-			return fmt.Errorf("cannot delete container %s that is not stopped: %s", id, s)
+		if id == "" {
+			return errEmptyID
 		}
+		return deleteContainerByID(context.GlobalString("root"), id, context.Bool("force"), context.String("usage-file"))
 	},
 }
+
+// deleteContainerByID deletes the container id (see deleteContainer). If
+// the container can't be loaded because it does not exist, its directory
+// is removed anyway.
+func deleteContainerByID(root, id string, force bool, usageFile string) error {
+	container, err := libcontainer.Load(root, id)
+	if err != nil {
+		if errors.Is(err, libcontainer.ErrNotExist) {
+			// if there was an aborted start or something of the sort then the container's directory could exist but
+			// libcontainer does not see it because the state.json file inside that directory was never created.
+			path := filepath.Join(root, id)
+			if e := os.RemoveAll(path); e != nil {
+				warn("remove "+path, e)
+			}
+			if force {
+				return nil
+			}
+		}
+		return err
+	}
+	return deleteContainer(container, force, usageFile)
+}
+
+// deleteContainer destroys a stopped or created container. If force is set,
+// a running container is killed first. If usageFile is set, the container
+// resource usage summary is written to it before the container is destroyed.
+func deleteContainer(container *libcontainer.Container, force bool, usageFile string) error {
+	// When --force is given, we kill all container processes and
+	// then destroy the container. This is done even for a stopped
+	// container, because (in case it does not have its own PID
+	// namespace) there may be some leftover processes in the
+	// container's cgroup.
+	if force {
+		return killContainer(container, usageFile)
+	}
+	s, err := container.Status()
+	if err != nil {
+		return err
+	}
+	switch s {
+	case libcontainer.Stopped:
+		return destroyContainer(container, usageFile)
+	case libcontainer.Created:
+		return killContainer(container, usageFile)
+	default:
+		return fmt.Errorf("cannot delete container %s that is not stopped: %s", container.ID(), s)
+	}
+}
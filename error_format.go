@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+
+	"github.com/szcdx/runc/libcontainer"
+	"github.com/szcdx/runc/libcontainer/apparmor"
+	"github.com/szcdx/runc/libcontainer/cgroups"
+	"github.com/szcdx/runc/libcontainer/configs/validate"
+)
+
+// errorFormat is the format of the error runc fails with, as set by the
+// --error-format global option: either "text" or "json".
+var errorFormat = "text"
+
+// jsonError is the error runc fails with if --error-format is json. It is
+// written to stderr as a single line, and so are the warnings and the errors
+// logged before it (if the log goes to stderr), but nothing else is.
+type jsonError struct {
+	// Level is "error", or "warning" for the warnings.
+	Level string `json:"level"`
+	// Code is a stable identifier of the kind of the error, such as
+	// "container-not-found", or "unknown" for the errors which are not
+	// (yet) told apart.
+	Code string `json:"code"`
+	// Subsystem is the part of runc the error comes from, such as
+	// "container" or "cgroups".
+	Subsystem string `json:"subsystem"`
+	// Message is the error message, the same as with --error-format text.
+	Message string `json:"message"`
+	// Hint is a suggestion on how to deal with the error, if any.
+	Hint string `json:"hint,omitempty"`
+}
+
+type errorClass struct {
+	err                   error
+	code, subsystem, hint string
+}
+
+// errorClasses are the known kinds of errors, in the order they are
+// checked in.
+var errorClasses = []errorClass{
+	{errUsage, "invalid-argument", "cli", "see the command help"},
+	{errEmptyID, "invalid-argument", "cli", "the container id is to be given as the first argument"},
+	{errSpecNotFound, "spec-not-found", "cli", "run runc in the bundle directory, or use --bundle"},
+	{libcontainer.ErrNotExist, "container-not-found", "container", "check the container id, and the --root option"},
+	{libcontainer.ErrExist, "container-exists", "container", "delete the container, or use another id"},
+	{libcontainer.ErrInvalidID, "invalid-id", "container", "use an id of letters, digits, underscores, plus and minus signs, and periods"},
+	{libcontainer.ErrRunning, "container-running", "container", "stop the container first"},
+	{libcontainer.ErrNotRunning, "container-not-running", "container", ""},
+	{libcontainer.ErrPaused, "container-paused", "container", "resume the container first"},
+	{libcontainer.ErrNotPaused, "container-not-paused", "container", ""},
+	{libcontainer.ErrProcessGone, "process-gone", "container", ""},
+	{libcontainer.ErrRateLimited, "rate-limited", "exec", "retry after the time given in the message"},
+	{libcontainer.ErrCriuMissingFeatures, "criu-missing-features", "criu", "upgrade criu"},
+	{cgroups.ErrExists, "cgroup-exists", "cgroups", "use another cgroups path, or delete the container using it"},
+	{cgroups.ErrReadOnly, "cgroup-read-only", "cgroups", `set the "org.opencontainers.runc.cgroup.read-only" annotation to "skip-limits" to run without resource limits`},
+	{validate.ErrNamespaceOwner, "namespace-owner-mismatch", "namespaces", "join the user namespace owning the namespace, or use --allow-ns-owner-mismatch"},
+	{validate.ErrInvalidConfig, "invalid-config", "config", "fix the container configuration"},
+	{apparmor.ErrApparmorNotEnabled, "apparmor-not-enabled", "apparmor", "remove the apparmor profile from the config"},
+	{os.ErrPermission, "permission-denied", "runc", "check the permissions, or run runc as root"},
+	{os.ErrNotExist, "not-found", "runc", ""},
+	{os.ErrExist, "already-exists", "runc", ""},
+}
+
+// newJSONError returns the jsonError for err.
+func newJSONError(err error) *jsonError {
+	for _, c := range errorClasses {
+		if errors.Is(err, c.err) {
+			return &jsonError{Level: "error", Code: c.code, Subsystem: c.subsystem, Message: err.Error(), Hint: c.hint}
+		}
+	}
+	return &jsonError{Level: "error", Code: "unknown", Subsystem: "runc", Message: err.Error()}
+}
+
+// writeJSONError writes the jsonError for err to stderr.
+func writeJSONError(err error) {
+	data, _ := json.Marshal(newJSONError(err))
+	data = append(data, '\n')
+	_, _ = os.Stderr.Write(data)
+}
+
+// jsonErrorFormatter is the logrus formatter used with --error-format json
+// while the log goes to stderr, so that everything written there is a
+// jsonError: the warnings and errors are, and the other entries are dropped.
+type jsonErrorFormatter struct{}
+
+func (jsonErrorFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	if entry.Level > logrus.WarnLevel {
+		return nil, nil
+	}
+	e := &jsonError{Code: "unknown", Subsystem: "runc", Message: entry.Message}
+	if err, ok := entry.Data[logrus.ErrorKey].(error); ok {
+		e = newJSONError(err)
+		if entry.Message != "" {
+			e.Message = entry.Message + ": " + e.Message
+		}
+	}
+	e.Level = "error"
+	if entry.Level == logrus.WarnLevel {
+		e.Level = "warning"
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// setErrorFormat sets errorFormat from the --error-format global option in
+// args, before they are parsed by cli, so that the errors of the parsing
+// itself, and the warnings, are in that format too. flags are the global
+// options, to tell the ones taking a value. The value is checked once args
+// are parsed.
+func setErrorFormat(args []string, flags []cli.Flag) {
+	withValue := make(map[string]bool)
+	for _, f := range flags {
+		switch f.(type) {
+		case cli.BoolFlag, cli.BoolTFlag:
+		default:
+			for _, name := range strings.Split(f.GetName(), ",") {
+				withValue[strings.TrimSpace(name)] = true
+			}
+		}
+	}
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" || !strings.HasPrefix(arg, "-") {
+			// The command.
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !hasValue && withValue[name] && i+1 < len(args) {
+			i++
+			value = args[i]
+		}
+		if name == "error-format" && value == "json" {
+			errorFormat = value
+		}
+	}
+	if errorFormat == "json" {
+		logrus.SetFormatter(jsonErrorFormatter{})
+	}
+}
+
+// onUsageError is the cli OnUsageErrorFunc of runc and its commands. It
+// prints the usage as cli does by default, and returns err as a usageError.
+func onUsageError(context *cli.Context, err error, isSubcommand bool) error {
+	_, _ = fmt.Fprintln(context.App.Writer, "Incorrect Usage:", err.Error())
+	_, _ = fmt.Fprintln(context.App.Writer)
+	switch {
+	case isSubcommand:
+		_ = cli.ShowSubcommandHelp(context)
+	case context.Command.Name != "":
+		_ = cli.ShowCommandHelp(context, context.Command.Name)
+	default:
+		_ = cli.ShowAppHelp(context)
+	}
+	return usageError(err.Error())
+}
+
+// warn writes the warning msg, with err if not nil, to stderr: as a line of
+// text, or through logrus with --error-format json.
+func warn(msg string, err error) {
+	if errorFormat == "json" {
+		logrus.WithError(err).Warn(msg)
+		return
+	}
+	if err != nil {
+		msg += ": " + err.Error()
+	}
+	fmt.Fprintln(os.Stderr, msg)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+
+	"github.com/szcdx/runc/libcontainer"
+	"github.com/szcdx/runc/libcontainer/cgroups"
+)
+
+func TestNewJSONError(t *testing.T) {
+	for _, tc := range []struct {
+		err             error
+		code, subsystem string
+	}{
+		{err: libcontainer.ErrNotExist, code: "container-not-found", subsystem: "container"},
+		{err: fmt.Errorf("runc run failed: %w", libcontainer.ErrExist), code: "container-exists", subsystem: "container"},
+		{err: &cgroups.ReadOnlyError{Path: "/sys/fs/cgroup"}, code: "cgroup-read-only", subsystem: "cgroups"},
+		{err: usageError("runc: \"start\" requires exactly 1 argument(s)"), code: "invalid-argument", subsystem: "cli"},
+		{err: fmt.Errorf("JSON specification file config.json %w", errSpecNotFound), code: "spec-not-found", subsystem: "cli"},
+		{err: fmt.Errorf("unable to create cgroup: %w", cgroups.ErrExists), code: "cgroup-exists", subsystem: "cgroups"},
+		{err: &os.PathError{Op: "open", Path: "/x", Err: errors.New("no such file or directory")}, code: "unknown", subsystem: "runc"},
+		{err: &os.PathError{Op: "open", Path: "/x", Err: os.ErrNotExist}, code: "not-found", subsystem: "runc"},
+		{err: fmt.Errorf("something else"), code: "unknown", subsystem: "runc"},
+	} {
+		e := newJSONError(tc.err)
+		if e.Code != tc.code || e.Subsystem != tc.subsystem || e.Message != tc.err.Error() {
+			t.Errorf("%v: expected code %q and subsystem %q, got %+v", tc.err, tc.code, tc.subsystem, e)
+		}
+	}
+}
+
+func TestSetErrorFormat(t *testing.T) {
+	defer func() {
+		errorFormat = "text"
+		logrus.SetFormatter(new(logrus.TextFormatter))
+	}()
+	flags := []cli.Flag{
+		cli.BoolFlag{Name: "debug"},
+		cli.StringFlag{Name: "root"},
+		cli.StringFlag{Name: "error-format"},
+	}
+	for _, tc := range []struct {
+		args   []string
+		format string
+	}{
+		{args: []string{"runc", "--error-format", "json", "state", "id"}, format: "json"},
+		{args: []string{"runc", "--debug", "--root", "/run/x", "-error-format=json", "state"}, format: "json"},
+		{args: []string{"runc", "--root", "--error-format", "state"}, format: "text"},
+		{args: []string{"runc", "state", "--error-format", "json"}, format: "text"},
+		{args: []string{"runc", "--error-format", "yaml", "state"}, format: "text"},
+	} {
+		errorFormat = "text"
+		setErrorFormat(tc.args, flags)
+		if errorFormat != tc.format {
+			t.Errorf("%v: expected format %q, got %q", tc.args, tc.format, errorFormat)
+		}
+	}
+}
+
+func TestJSONErrorFormatter(t *testing.T) {
+	logger := logrus.New()
+	var b strings.Builder
+	logger.Out = &b
+	logger.Formatter = jsonErrorFormatter{}
+	logger.Debug("debug")
+	logger.Info("info")
+	logger.WithError(libcontainer.ErrNotExist).Warn("load container x")
+	logger.Error("failed")
+
+	expected := `{"level":"warning","code":"container-not-found","subsystem":"container","message":"load container x: container does not exist","hint":"check the container id, and the --root option"}
+{"level":"error","code":"unknown","subsystem":"runc","message":"failed"}
+`
+	if b.String() != expected {
+		t.Errorf("expected %q, got %q", expected, b.String())
+	}
+}
@@ -1,13 +1,13 @@
 package main
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"sync"
 	"time"
 
+	"github.com/szcdx/runc/internal/jsonutil"
 	"github.com/szcdx/runc/libcontainer"
 	"github.com/szcdx/runc/libcontainer/cgroups"
 	"github.com/szcdx/runc/libcontainer/intelrdt"
@@ -17,6 +17,13 @@ import (
 	"github.com/urfave/cli"
 )
 
+// pressureLevels are the memory pressure levels of "runc events --memory-pressure".
+var pressureLevels = map[string]libcontainer.PressureLevel{
+	"low":      libcontainer.LowPressure,
+	"medium":   libcontainer.MediumPressure,
+	"critical": libcontainer.CriticalPressure,
+}
+
 var eventsCommand = cli.Command{
 	Name:  "events",
 	Usage: "display container events such as OOM notifications, cpu, memory, and IO usage statistics",
@@ -28,6 +35,8 @@ information is displayed once every 5 seconds.`,
 	Flags: []cli.Flag{
 		cli.DurationFlag{Name: "interval", Value: 5 * time.Second, Usage: "set the stats collection interval"},
 		cli.BoolFlag{Name: "stats", Usage: "display the container's stats then exit"},
+		cli.IntSliceFlag{Name: "memory-threshold", Usage: "emit an event when memory usage rises above the specified percentage of the memory limit (can be specified multiple times)"},
+		cli.StringSliceFlag{Name: "memory-pressure", Usage: "emit an event when the container reaches the specified memory pressure level: low, medium, or critical (can be specified multiple times)"},
 	},
 	Action: func(context *cli.Context) error {
 		if err := checkArgs(context, 1, exactArgs); err != nil {
@@ -56,7 +65,7 @@ information is displayed once every 5 seconds.`,
 		group.Add(1)
 		go func() {
 			defer group.Done()
-			enc := json.NewEncoder(os.Stdout)
+			enc := jsonutil.NewEncoder(os.Stdout)
 			for e := range events {
 				if err := enc.Encode(e); err != nil {
 					logrus.Error(err)
@@ -87,6 +96,53 @@ information is displayed once every 5 seconds.`,
 		if err != nil {
 			return err
 		}
+		// Closing done stops the notifications below.
+		done := make(chan struct{})
+		defer close(done)
+		thresholds := make(chan uint)
+		for _, percent := range context.IntSlice("memory-threshold") {
+			if percent <= 0 {
+				return fmt.Errorf("invalid memory threshold %d", percent)
+			}
+			t, err := container.NotifyMemoryThreshold(uint(percent), done)
+			if err != nil {
+				return err
+			}
+			go func(percent uint) {
+				for range t {
+					select {
+					case thresholds <- percent:
+					case <-done:
+						return
+					}
+				}
+			}(uint(percent))
+		}
+		pressures := make(chan string)
+		for _, level := range context.StringSlice("memory-pressure") {
+			l, ok := pressureLevels[level]
+			if !ok {
+				return fmt.Errorf("invalid memory pressure level %q", level)
+			}
+			p, err := container.NotifyMemoryPressureUntil(l, done)
+			if err != nil {
+				return err
+			}
+			go func(level string) {
+				for range p {
+					select {
+					case pressures <- level:
+					case <-done:
+						return
+					}
+				}
+			}(level)
+		}
+		// The pids controller may be missing (or not delegated).
+		pidsHits, err := container.NotifyPidsLimit(done)
+		if err != nil {
+			logrus.Debugf("unable to get pids limit notifications: %v", err)
+		}
 		for {
 			select {
 			case _, ok := <-n:
@@ -94,10 +150,32 @@ information is displayed once every 5 seconds.`,
 					// this means an oom event was received, if it is !ok then
 					// the channel was closed because the container stopped and
 					// the cgroups no longer exist.
-					events <- &types.Event{Type: "oom", ID: container.ID()}
+					e := &types.Event{Type: "oom", ID: container.ID()}
+					if info, err := container.OOMKillInfo(); err != nil {
+						logrus.Debugf("unable to get OOM kill details: %v", err)
+					} else {
+						e.Data = &types.OOM{Count: info.Count, Pid: info.Pid, Comm: info.Comm, RSS: info.RSS}
+					}
+					events <- e
 				} else {
 					n = nil
 				}
+			case _, ok := <-pidsHits:
+				if !ok {
+					pidsHits = nil
+					continue
+				}
+				e := &types.Event{Type: "pids_limit_hit", ID: container.ID()}
+				if s, err := container.Stats(); err != nil {
+					logrus.Debugf("unable to get pids stats: %v", err)
+				} else if cg := s.CgroupStats; cg != nil && cg.PidsStats.LimitHits > 0 {
+					e.Data = &types.PidsLimitHit{Count: cg.PidsStats.LimitHits, Limit: cg.PidsStats.Limit}
+				}
+				events <- e
+			case percent := <-thresholds:
+				events <- &types.Event{Type: "memory_threshold", ID: container.ID(), Data: &types.MemoryThreshold{Percent: percent}}
+			case level := <-pressures:
+				events <- &types.Event{Type: "memory_pressure", ID: container.ID(), Data: &types.MemoryPressure{Level: level}}
 			case s := <-stats:
 				events <- &types.Event{Type: "stats", ID: container.ID(), Data: convertLibcontainerStats(s)}
 			}
@@ -119,6 +197,7 @@ func convertLibcontainerStats(ls *libcontainer.Stats) *types.Stats {
 	var s types.Stats
 	s.Pids.Current = cg.PidsStats.Current
 	s.Pids.Limit = cg.PidsStats.Limit
+	s.Pids.LimitHits = cg.PidsStats.LimitHits
 
 	s.CPU.Usage.Kernel = cg.CpuStats.CpuUsage.UsageInKernelmode
 	s.CPU.Usage.User = cg.CpuStats.CpuUsage.UsageInUsermode
@@ -129,6 +208,8 @@ func convertLibcontainerStats(ls *libcontainer.Stats) *types.Stats {
 	s.CPU.Throttling.Periods = cg.CpuStats.ThrottlingData.Periods
 	s.CPU.Throttling.ThrottledPeriods = cg.CpuStats.ThrottlingData.ThrottledPeriods
 	s.CPU.Throttling.ThrottledTime = cg.CpuStats.ThrottlingData.ThrottledTime
+	s.CPU.Throttling.Bursts = cg.CpuStats.ThrottlingData.Bursts
+	s.CPU.Throttling.BurstTime = cg.CpuStats.ThrottlingData.BurstTime
 	s.CPU.PSI = cg.CpuStats.PSI
 
 	s.CPUSet = types.CPUSet(cg.CPUSetStats)
@@ -137,9 +218,12 @@ func convertLibcontainerStats(ls *libcontainer.Stats) *types.Stats {
 	s.Memory.Kernel = convertMemoryEntry(cg.MemoryStats.KernelUsage)
 	s.Memory.KernelTCP = convertMemoryEntry(cg.MemoryStats.KernelTCPUsage)
 	s.Memory.Swap = convertMemoryEntry(cg.MemoryStats.SwapUsage)
+	s.Memory.Zswap = convertMemoryEntry(cg.MemoryStats.ZswapUsage)
 	s.Memory.Usage = convertMemoryEntry(cg.MemoryStats.Usage)
 	s.Memory.Raw = cg.MemoryStats.Stats
 	s.Memory.PSI = cg.MemoryStats.PSI
+	s.Memory.Events = cg.MemoryStats.Events
+	s.Memory.EventsLocal = cg.MemoryStats.EventsLocal
 
 	s.Blkio.IoServiceBytesRecursive = convertBlkioEntry(cg.BlkioStats.IoServiceBytesRecursive)
 	s.Blkio.IoServicedRecursive = convertBlkioEntry(cg.BlkioStats.IoServicedRecursive)
@@ -150,12 +234,26 @@ func convertLibcontainerStats(ls *libcontainer.Stats) *types.Stats {
 	s.Blkio.IoTimeRecursive = convertBlkioEntry(cg.BlkioStats.IoTimeRecursive)
 	s.Blkio.SectorsRecursive = convertBlkioEntry(cg.BlkioStats.SectorsRecursive)
 	s.Blkio.PSI = cg.BlkioStats.PSI
+	s.Blkio.Weight = cg.BlkioStats.Weight
+	s.Blkio.WeightDevice = convertBlkioEntry(cg.BlkioStats.WeightDevice)
+	s.Blkio.ThrottleDevice = convertBlkioEntry(cg.BlkioStats.ThrottleDevice)
 
 	s.Hugetlb = make(map[string]types.Hugetlb)
 	for k, v := range cg.HugetlbStats {
 		s.Hugetlb[k] = convertHugtlb(v)
 	}
 
+	if len(cg.MiscStats) > 0 {
+		s.Misc = make(map[string]types.Misc)
+		for k, v := range cg.MiscStats {
+			s.Misc[k] = types.Misc{Usage: v.Usage, Events: v.Events, Limit: v.Limit}
+		}
+	}
+
+	if r := cg.RdmaStats; len(r.RdmaLimit) > 0 || len(r.RdmaCurrent) > 0 {
+		s.Rdma = &types.Rdma{Limit: r.RdmaLimit, Current: r.RdmaCurrent}
+	}
+
 	if is := ls.IntelRdtStats; is != nil {
 		if intelrdt.IsCATEnabled() {
 			s.IntelRdt.L3CacheInfo = convertL3CacheInfo(is.L3CacheInfo)
@@ -180,11 +278,16 @@ func convertLibcontainerStats(ls *libcontainer.Stats) *types.Stats {
 }
 
 func convertHugtlb(c cgroups.HugetlbStats) types.Hugetlb {
-	return types.Hugetlb{
+	h := types.Hugetlb{
 		Usage:   c.Usage,
 		Max:     c.MaxUsage,
 		Failcnt: c.Failcnt,
 	}
+	if c.Fault != nil {
+		fault := convertHugtlb(*c.Fault)
+		h.Fault = &fault
+	}
+	return h
 }
 
 func convertMemoryEntry(c cgroups.MemoryData) types.MemoryEntry {
@@ -8,8 +8,12 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/docker/go-units"
 	"github.com/opencontainers/runtime-spec/specs-go"
 	"github.com/szcdx/runc/libcontainer"
+	"github.com/szcdx/runc/libcontainer/cgroups"
+	"github.com/szcdx/runc/libcontainer/configs"
+	"github.com/szcdx/runc/libcontainer/specconv"
 	"github.com/szcdx/runc/libcontainer/utils"
 	"github.com/urfave/cli"
 )
@@ -87,6 +91,10 @@ following will output a list of processes running in the container:
 			Value: &cli.StringSlice{},
 			Usage: "add a capability to the bounding set for the process",
 		},
+		cli.StringFlag{
+			Name:  "seccomp",
+			Usage: "path to the seccomp profile (in the OCI format) to use for the process rather than the container's one, or 'unconfined'",
+		},
 		cli.IntFlag{
 			Name:  "preserve-fds",
 			Usage: "Pass N additional file descriptors to the container (stdio + $LISTEN_FDS + N in total)",
@@ -95,10 +103,22 @@ following will output a list of processes running in the container:
 			Name:  "cgroup",
 			Usage: "run the process in an (existing) sub-cgroup(s). Format is [<controller>:]<cgroup>.",
 		},
+		cli.StringFlag{
+			Name:  "cpu-quota",
+			Usage: "CPU CFS hardcap limit (in usecs) of the sub-cgroup given by --cgroup, which is created if needed (cgroup v1 only)",
+		},
+		cli.StringFlag{
+			Name:  "memory",
+			Usage: "memory limit (in bytes) of the sub-cgroup given by --cgroup, which is created if needed (cgroup v1 only)",
+		},
 		cli.BoolFlag{
 			Name:  "ignore-paused",
 			Usage: "allow exec in a paused container",
 		},
+		cli.BoolFlag{
+			Name:  "join",
+			Usage: "join mode: run the process with no process start rate limit",
+		},
 	},
 	Action: func(context *cli.Context) error {
 		if err := checkArgs(context, 1, minArgs); err != nil {
@@ -143,6 +163,38 @@ func getSubCgroupPaths(args []string) (map[string]string, error) {
 	return paths, nil
 }
 
+// getSubCgroupResources returns the limits of the sub-cgroup of the process
+// set by --cpu-quota and --memory, or nil if there are none.
+func getSubCgroupResources(context *cli.Context) (*configs.Resources, error) {
+	if !context.IsSet("cpu-quota") && !context.IsSet("memory") {
+		return nil, nil
+	}
+	// The container's cgroup has processes (at least the init), so on
+	// cgroup v2, its sub-cgroups can't have controllers enabled.
+	if cgroups.IsCgroup2UnifiedMode() {
+		return nil, errors.New("--cpu-quota and --memory are not supported on cgroup v2")
+	}
+	if len(context.StringSlice("cgroup")) == 0 {
+		return nil, errors.New("--cpu-quota and --memory require --cgroup")
+	}
+	r := &configs.Resources{}
+	if val := context.String("cpu-quota"); val != "" {
+		v, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for cpu-quota: %w", err)
+		}
+		r.CpuQuota = v
+	}
+	if val := context.String("memory"); val != "" {
+		v, err := units.RAMInBytes(val)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for memory: %w", err)
+		}
+		r.Memory = v
+	}
+	return r, nil
+}
+
 func execProcess(context *cli.Context) (int, error) {
 	container, err := getContainer(context)
 	if err != nil {
@@ -174,11 +226,22 @@ func execProcess(context *cli.Context) (int, error) {
 	if err != nil {
 		return -1, err
 	}
+	if context.Bool("join") {
+		return joinProcess(context, container, p)
+	}
 
 	cgPaths, err := getSubCgroupPaths(context.StringSlice("cgroup"))
 	if err != nil {
 		return -1, err
 	}
+	cgResources, err := getSubCgroupResources(context)
+	if err != nil {
+		return -1, err
+	}
+	seccomp, unconfined, err := getSeccomp(context)
+	if err != nil {
+		return -1, err
+	}
 
 	r := &runner{
 		enableSubreaper: false,
@@ -192,10 +255,65 @@ func execProcess(context *cli.Context) (int, error) {
 		init:            false,
 		preserveFDs:     context.Int("preserve-fds"),
 		subCgroupPaths:  cgPaths,
+		subCgroupRes:    cgResources,
+		seccomp:         seccomp,
+		noSeccomp:       unconfined,
 	}
 	return r.run(p)
 }
 
+// joinProcess runs the process p in the container in the join mode (see
+// libcontainer.Container.Join).
+func joinProcess(context *cli.Context, container *libcontainer.Container, p *specs.Process) (int, error) {
+	for _, name := range []string{"cgroup", "cpu-quota", "memory", "ignore-paused"} {
+		if context.IsSet(name) {
+			return -1, fmt.Errorf("--%s can not be used with --join", name)
+		}
+	}
+	seccomp, unconfined, err := getSeccomp(context)
+	if err != nil {
+		return -1, err
+	}
+	r := &runner{
+		container:     container,
+		consoleSocket: context.String("console-socket"),
+		pidfdSocket:   context.String("pidfd-socket"),
+		detach:        context.Bool("detach"),
+		pidFile:       context.String("pid-file"),
+		action:        CT_ACT_JOIN,
+		preserveFDs:   context.Int("preserve-fds"),
+		seccomp:       seccomp,
+		noSeccomp:     unconfined,
+	}
+	return r.run(p)
+}
+
+// getSeccomp returns the seccomp profile of the process set by --seccomp,
+// or whether the process is to run without any.
+func getSeccomp(context *cli.Context) (*configs.Seccomp, bool, error) {
+	path := context.String("seccomp")
+	switch path {
+	case "":
+		return nil, false, nil
+	case "unconfined":
+		return nil, true, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+	var s specs.LinuxSeccomp
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, false, fmt.Errorf("invalid seccomp profile %s: %w", path, err)
+	}
+	config, err := specconv.SetupSeccomp(&s)
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid seccomp profile %s: %w", path, err)
+	}
+	// An empty profile disables seccomp.
+	return config, config == nil, nil
+}
+
 func getProcess(context *cli.Context, bundle string) (*specs.Process, error) {
 	if path := context.String("process"); path != "" {
 		f, err := os.Open(path)
@@ -218,6 +336,15 @@ func getProcess(context *cli.Context, bundle string) (*specs.Process, error) {
 		return nil, err
 	}
 	p := spec.Process
+	if err := setProcessOptions(context, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// setProcessOptions sets the process args, and the settings given by the
+// command line options, in p.
+func setProcessOptions(context *cli.Context, p *specs.Process) error {
 	p.Args = context.Args()[1:]
 	// override the cwd, if passed
 	if context.String("cwd") != "" {
@@ -230,6 +357,9 @@ func getProcess(context *cli.Context, bundle string) (*specs.Process, error) {
 		p.SelinuxLabel = l
 	}
 	if caps := context.StringSlice("cap"); len(caps) > 0 {
+		if p.Capabilities == nil {
+			p.Capabilities = &specs.LinuxCapabilities{}
+		}
 		for _, c := range caps {
 			p.Capabilities.Bounding = append(p.Capabilities.Bounding, c)
 			p.Capabilities.Effective = append(p.Capabilities.Effective, c)
@@ -254,21 +384,21 @@ func getProcess(context *cli.Context, bundle string) (*specs.Process, error) {
 		if len(u) > 1 {
 			gid, err := strconv.Atoi(u[1])
 			if err != nil {
-				return nil, fmt.Errorf("parsing %s as int for gid failed: %w", u[1], err)
+				return fmt.Errorf("parsing %s as int for gid failed: %w", u[1], err)
 			}
 			p.User.GID = uint32(gid)
 		}
 		uid, err := strconv.Atoi(u[0])
 		if err != nil {
-			return nil, fmt.Errorf("parsing %s as int for uid failed: %w", u[0], err)
+			return fmt.Errorf("parsing %s as int for uid failed: %w", u[0], err)
 		}
 		p.User.UID = uint32(uid)
 	}
 	for _, gid := range context.Int64Slice("additional-gids") {
 		if gid < 0 {
-			return nil, fmt.Errorf("additional-gids must be a positive number %d", gid)
+			return fmt.Errorf("additional-gids must be a positive number %d", gid)
 		}
 		p.User.AdditionalGids = append(p.User.AdditionalGids, uint32(gid))
 	}
-	return p, validateProcessSpec(p)
+	return validateProcessSpec(p)
 }
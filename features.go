@@ -3,17 +3,30 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/opencontainers/runtime-spec/specs-go"
 	"github.com/opencontainers/runtime-spec/specs-go/features"
 	"github.com/szcdx/runc/libcontainer/capabilities"
 	"github.com/szcdx/runc/libcontainer/configs"
+	"github.com/szcdx/runc/libcontainer/criu"
 	"github.com/szcdx/runc/libcontainer/seccomp"
 	"github.com/szcdx/runc/libcontainer/specconv"
 	runcfeatures "github.com/szcdx/runc/types/features"
 	"github.com/urfave/cli"
 )
 
+// checkpointFeatures are the criu features reported by runc features.
+var checkpointFeatures = []string{
+	criu.MemTrack,
+	criu.LazyPages,
+	criu.PidfdStore,
+	"memfd_hugetlb",
+	"tun",
+	"sk_unix_file",
+}
+
 var featuresCommand = cli.Command{
 	Name:      "features",
 	Usage:     "show the enabled features",
@@ -33,9 +46,10 @@ var featuresCommand = cli.Command{
 			OCIVersionMin: "1.0.0",
 			OCIVersionMax: specs.Version,
 			Annotations: map[string]string{
-				runcfeatures.AnnotationRuncVersion:           version,
-				runcfeatures.AnnotationRuncCommit:            gitCommit,
-				runcfeatures.AnnotationRuncCheckpointEnabled: "true",
+				runcfeatures.AnnotationRuncVersion:                     version,
+				runcfeatures.AnnotationRuncCommit:                      gitCommit,
+				runcfeatures.AnnotationRuncCheckpointEnabled:           "true",
+				runcfeatures.AnnotationRuncCgroupAccountingOnlyEnabled: "true",
 			},
 			Hooks:        configs.KnownHookNames(),
 			MountOptions: specconv.KnownMountOptions(),
@@ -79,6 +93,20 @@ var featuresCommand = cli.Command{
 			feat.Annotations[runcfeatures.AnnotationLibseccompVersion] = fmt.Sprintf("%d.%d.%d", major, minor, patch)
 		}
 
+		if v, err := criu.Version(); err == nil {
+			feat.Annotations[runcfeatures.AnnotationRuncCheckpointCriuVersion] = fmt.Sprintf("%d.%d.%d", v/10000, v/100%100, v%100)
+			if supported, err := criu.FeatureCheck(checkpointFeatures...); err == nil {
+				var names []string
+				for name, ok := range supported {
+					if ok {
+						names = append(names, name)
+					}
+				}
+				sort.Strings(names)
+				feat.Annotations[runcfeatures.AnnotationRuncCheckpointFeatures] = strings.Join(names, ",")
+			}
+		}
+
 		enc := json.NewEncoder(context.App.Writer)
 		enc.SetIndent("", "    ")
 		return enc.Encode(feat)
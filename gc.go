@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/szcdx/runc/libcontainer/cgroups/systemd"
+	"github.com/urfave/cli"
+)
+
+var gcCommand = cli.Command{
+	Name:  "gc",
+	Usage: "clean up the resources leaked by removed containers",
+	ArgsUsage: `
+
+The gc command removes the systemd transient scopes left behind by the
+containers which are gone, so that containers with the same ids can be
+created again: the scopes which failed (for example, because their processes
+were killed by the OOM killer), and the ones with no processes left. The names
+of the removed scopes are printed.
+
+Only the scopes named after the given scope prefix (the one given in the
+"slice:prefix:name" cgroups path of the containers created with the systemd
+cgroup driver) are looked at.
+
+EXAMPLE:
+       # runc gc --scope-prefix runc`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "scope-prefix",
+			Value: "runc",
+			Usage: "the prefix of the names of the container scopes",
+		},
+	},
+	Action: func(context *cli.Context) error {
+		if err := checkArgs(context, 0, exactArgs); err != nil {
+			return err
+		}
+		prefix := context.String("scope-prefix")
+		if prefix == "" || strings.ContainsAny(prefix, "*?[/") {
+			return usageError(fmt.Sprintf("invalid scope prefix %q", prefix))
+		}
+		if !systemd.IsRunningSystemd() {
+			return errors.New("systemd is not running")
+		}
+		rootless, err := shouldUseRootlessCgroupManager(context)
+		if err != nil {
+			return err
+		}
+		units, err := systemd.CleanupLeakedUnits(prefix, rootless)
+		for _, u := range units {
+			fmt.Println(u)
+		}
+		return err
+	},
+}
@@ -0,0 +1,187 @@
+package jsonutil_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+
+	"github.com/szcdx/runc/internal/jsonutil"
+	"github.com/szcdx/runc/libcontainer"
+	"github.com/szcdx/runc/libcontainer/configs"
+	"github.com/szcdx/runc/libcontainer/specconv"
+)
+
+func testState() *libcontainer.State {
+	config := &configs.Config{
+		Rootfs:   "/var/lib/containers/rootfs",
+		Hostname: "test",
+		Cgroups: &configs.Cgroup{Path: "/test", Resources: &configs.Resources{
+			Memory:    1 << 30,
+			CpuShares: 1024,
+			Unified:   map[string]string{"memory.high": "max"},
+		}},
+		Namespaces: configs.Namespaces{{Type: configs.NEWNS}, {Type: configs.NEWPID}},
+		Labels:     []string{"bundle=/run/bundle", "annotation=<a&b>"},
+		Hooks: configs.Hooks{
+			configs.Poststop: configs.HookList{configs.NewCommandHook(configs.Command{
+				Path:    "/usr/bin/hook",
+				Args:    []string{"hook", "poststop"},
+				Timeout: new(time.Duration),
+			})},
+		},
+	}
+	for i := 0; i < 30; i++ {
+		config.Mounts = append(config.Mounts, &configs.Mount{
+			Source:      "/some/source/path",
+			Destination: "/some/destination/path",
+			Device:      "bind",
+			Flags:       4096,
+		})
+		config.MaskPaths = append(config.MaskPaths, "/proc/some/path")
+	}
+	return &libcontainer.State{
+		BaseState: libcontainer.BaseState{
+			ID:                   "test",
+			InitProcessPid:       42,
+			InitProcessStartTime: 12345,
+			Created:              time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC),
+			Config:               *config,
+		},
+		CgroupPaths:    map[string]string{"": "/sys/fs/cgroup/test"},
+		NamespacePaths: map[configs.NamespaceType]string{configs.NEWNS: "/proc/42/ns/mnt"},
+	}
+}
+
+// TestCompatible checks that jsonutil encodes the state exactly as
+// encoding/json does (so that the state files written by either can be read
+// by the other), and decodes it back to the same value.
+func TestCompatible(t *testing.T) {
+	state := testState()
+	want, err := json.Marshal(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := jsonutil.Marshal(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("want:\n%s\ngot:\n%s", want, got)
+	}
+
+	var wantState, gotState libcontainer.State
+	if err := json.Unmarshal(want, &wantState); err != nil {
+		t.Fatal(err)
+	}
+	if err := jsonutil.NewDecoder(bytes.NewReader(want)).Decode(&gotState); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(gotState, wantState) {
+		t.Errorf("want %+v, got %+v", wantState, gotState)
+	}
+}
+
+func TestEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	enc := jsonutil.NewEncoder(&buf)
+	for _, name := range []string{"a", "<b>"} {
+		if err := enc.Encode(map[string]string{"name": name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// One value per line, with the HTML characters escaped.
+	if got, want := buf.String(), "{\"name\":\"a\"}\n{\"name\":\"\\u003cb\\u003e\"}\n"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestDecoderStream(t *testing.T) {
+	// Like json.Decoder, the data after the first value is left alone.
+	dec := jsonutil.NewDecoder(strings.NewReader(`{"id": "a"} {"id": "b"}`))
+	var state libcontainer.State
+	if err := dec.Decode(&state); err != nil {
+		t.Fatal(err)
+	}
+	if state.ID != "a" {
+		t.Errorf("want a, got %s", state.ID)
+	}
+	if err := jsonutil.NewDecoder(strings.NewReader(" \n")).Decode(&state); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+}
+
+func BenchmarkSaveState(b *testing.B) {
+	state := testState()
+	b.Run("encoding/json", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := json.Marshal(state); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("jsonutil", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := jsonutil.Marshal(state); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkLoadState(b *testing.B) {
+	data, err := json.Marshal(testState())
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Run("encoding/json", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var state *libcontainer.State
+			if err := json.NewDecoder(bytes.NewReader(data)).Decode(&state); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("jsonutil", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var state *libcontainer.State
+			if err := jsonutil.NewDecoder(bytes.NewReader(data)).Decode(&state); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkLoadSpec(b *testing.B) {
+	data, err := json.Marshal(specconv.Example())
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Run("encoding/json", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var spec *specs.Spec
+			if err := json.NewDecoder(bytes.NewReader(data)).Decode(&spec); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("jsonutil", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var spec *specs.Spec
+			if err := jsonutil.NewDecoder(bytes.NewReader(data)).Decode(&spec); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
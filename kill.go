@@ -2,13 +2,10 @@ package main
 
 import (
 	"errors"
-	"fmt"
-	"strconv"
-	"strings"
 
 	"github.com/szcdx/runc/libcontainer"
+	"github.com/szcdx/runc/libcontainer/utils"
 	"github.com/urfave/cli"
-	"golang.org/x/sys/unix"
 )
 
 var killCommand = cli.Command{
@@ -30,6 +27,10 @@ signal to the init process of the "ubuntu01" container:
 			Usage:  "(obsoleted, do not use)",
 			Hidden: true,
 		},
+		cli.StringFlag{
+			Name:  "thaw",
+			Usage: "what to do if the container is paused: 'thaw' (send the signal and thaw), 'refreeze' (thaw, send the signal, and freeze again), or 'fail'",
+		},
 	},
 	Action: func(context *cli.Context) error {
 		if err := checkArgs(context, 1, minArgs); err != nil {
@@ -48,30 +49,18 @@ signal to the init process of the "ubuntu01" container:
 			sigstr = "SIGTERM"
 		}
 
-		signal, err := parseSignal(sigstr)
+		signal, err := utils.ParseSignal(sigstr)
+		if err != nil {
+			return err
+		}
+		policy, err := libcontainer.ParsePausedSignalPolicy(context.String("thaw"))
 		if err != nil {
 			return err
 		}
-		err = container.Signal(signal)
-		if errors.Is(err, libcontainer.ErrNotRunning) && context.Bool("all") {
+		err = container.SignalWithPolicy(signal, policy)
+		if (errors.Is(err, libcontainer.ErrNotRunning) || errors.Is(err, libcontainer.ErrProcessGone)) && context.Bool("all") {
 			err = nil
 		}
 		return err
 	},
 }
-
-func parseSignal(rawSignal string) (unix.Signal, error) {
-	s, err := strconv.Atoi(rawSignal)
-	if err == nil {
-		return unix.Signal(s), nil
-	}
-	sig := strings.ToUpper(rawSignal)
-	if !strings.HasPrefix(sig, "SIG") {
-		sig = "SIG" + sig
-	}
-	signal := unix.SignalNum(sig)
-	if signal == 0 {
-		return -1, fmt.Errorf("unknown signal %q", rawSignal)
-	}
-	return signal, nil
-}
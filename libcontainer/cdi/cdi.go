@@ -0,0 +1,306 @@
+// Package cdi resolves Container Device Interface (CDI) device names, such as
+// "vendor.com/gpu=0", against the CDI specs of the host, and applies the
+// container edits of these devices (device nodes, mounts, environment
+// variables, hooks and additional groups) to an OCI runtime spec.
+package cdi
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/sirupsen/logrus"
+	"sigs.k8s.io/yaml"
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+
+	"github.com/szcdx/runc/libcontainer/devices"
+)
+
+// Annotation is the annotation listing the CDI devices to inject into the
+// container, as a comma separated list of fully qualified device names
+// (such as "vendor.com/gpu=0,vendor.com/gpu=1").
+const Annotation = "org.opencontainers.runc.cdi.devices"
+
+// DefaultDirs are the directories with the CDI specs, by increasing
+// priority: a device defined in several of them is taken from the last one.
+var DefaultDirs = []string{"/etc/cdi", "/var/run/cdi"}
+
+// ApplyAnnotation applies the edits of the CDI devices listed in the
+// Annotation annotation of spec, if any, to spec. The CDI specs are looked
+// up in dirs.
+func ApplyAnnotation(spec *specs.Spec, dirs []string) error {
+	val := spec.Annotations[Annotation]
+	if val == "" {
+		return nil
+	}
+	if err := Apply(spec, strings.Split(val, ","), dirs); err != nil {
+		return fmt.Errorf("annotation %s: %w", Annotation, err)
+	}
+	return nil
+}
+
+// Apply applies the edits of the CDI devices names, which are fully
+// qualified device names (in the vendor/class=name form), to spec. The CDI
+// specs are looked up in dirs.
+func Apply(spec *specs.Spec, names []string, dirs []string) error {
+	devs, err := loadDevices(dirs)
+	if err != nil {
+		return err
+	}
+	applied := make(map[*cdi.Spec]bool)
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if _, _, err := parseName(name); err != nil {
+			return err
+		}
+		dev := devs[name]
+		if dev == nil {
+			return fmt.Errorf("unresolvable CDI device %s", name)
+		}
+		// The edits common to all the devices of a spec are applied once.
+		if !applied[dev.spec] {
+			if err := applyEdits(spec, &dev.spec.ContainerEdits); err != nil {
+				return fmt.Errorf("CDI spec %s: %w", dev.spec.Kind, err)
+			}
+			applied[dev.spec] = true
+		}
+		if err := applyEdits(spec, &dev.ContainerEdits); err != nil {
+			return fmt.Errorf("CDI device %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// parseName parses a fully qualified device name, returning its kind
+// (vendor/class) and name.
+func parseName(name string) (string, string, error) {
+	kind, dev, ok := strings.Cut(name, "=")
+	vendor, class, ok2 := strings.Cut(kind, "/")
+	if !ok || !ok2 || vendor == "" || class == "" || dev == "" {
+		return "", "", fmt.Errorf("invalid CDI device name %q: expected vendor/class=name", name)
+	}
+	return kind, dev, nil
+}
+
+// device is a device of a CDI spec.
+type device struct {
+	*cdi.Device
+	spec *cdi.Spec
+}
+
+// loadDevices loads the CDI specs in dirs, in the JSON or YAML format, and
+// returns their devices by fully qualified name.
+func loadDevices(dirs []string) (map[string]*device, error) {
+	devs := make(map[string]*device)
+	for _, dir := range dirs {
+		files, err := filepath.Glob(filepath.Join(dir, "*"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(files)
+		for _, file := range files {
+			switch filepath.Ext(file) {
+			case ".json", ".yaml", ".yml":
+			default:
+				logrus.Debugf("ignoring %s: not a CDI spec", file)
+				continue
+			}
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			s := new(cdi.Spec)
+			// A JSON spec is also a YAML one.
+			if err := yaml.Unmarshal(data, s); err != nil {
+				return nil, fmt.Errorf("invalid CDI spec %s: %w", file, err)
+			}
+			if err := cdi.ValidateVersion(s); err != nil {
+				return nil, fmt.Errorf("invalid CDI spec %s: %w", file, err)
+			}
+			for i := range s.Devices {
+				name := s.Kind + "=" + s.Devices[i].Name
+				if _, _, err := parseName(name); err != nil {
+					return nil, fmt.Errorf("invalid CDI spec %s: %w", file, err)
+				}
+				devs[name] = &device{Device: &s.Devices[i], spec: s}
+			}
+		}
+	}
+	return devs, nil
+}
+
+// applyEdits applies the edits e to spec.
+func applyEdits(spec *specs.Spec, e *cdi.ContainerEdits) error {
+	if len(e.NetDevices) > 0 {
+		return errors.New("network devices are not supported")
+	}
+	if e.IntelRdt != nil {
+		return errors.New("Intel RDT edits are not supported")
+	}
+	if len(e.Env) > 0 {
+		if spec.Process == nil {
+			spec.Process = &specs.Process{}
+		}
+		spec.Process.Env = mergeEnv(spec.Process.Env, e.Env)
+	}
+	for _, d := range e.DeviceNodes {
+		if err := addDeviceNode(spec, d); err != nil {
+			return err
+		}
+	}
+	for _, m := range e.Mounts {
+		if !filepath.IsAbs(m.ContainerPath) {
+			return fmt.Errorf("mount destination %q must be an absolute path", m.ContainerPath)
+		}
+		mnt := specs.Mount{
+			Destination: m.ContainerPath,
+			Source:      m.HostPath,
+			Type:        m.Type,
+			Options:     m.Options,
+		}
+		// The mounts are bind mounts unless told otherwise.
+		if mnt.Type == "" || mnt.Type == "bind" {
+			mnt.Type = "bind"
+			if !hasBindOption(mnt.Options) {
+				mnt.Options = append([]string{"bind"}, mnt.Options...)
+			}
+		}
+		spec.Mounts = append(spec.Mounts, mnt)
+	}
+	for _, h := range e.Hooks {
+		if err := addHook(spec, h); err != nil {
+			return err
+		}
+	}
+	if len(e.AdditionalGIDs) > 0 {
+		if spec.Process == nil {
+			spec.Process = &specs.Process{}
+		}
+		spec.Process.User.AdditionalGids = append(spec.Process.User.AdditionalGids, e.AdditionalGIDs...)
+	}
+	return nil
+}
+
+func hasBindOption(options []string) bool {
+	for _, o := range options {
+		if o == "bind" || o == "rbind" {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeEnv returns the environment env with the variables in add set,
+// replacing the ones with the same name.
+func mergeEnv(env, add []string) []string {
+	out := make([]string, 0, len(env)+len(add))
+next:
+	for _, v := range env {
+		name, _, _ := strings.Cut(v, "=")
+		for _, a := range add {
+			if n, _, _ := strings.Cut(a, "="); n == name {
+				continue next
+			}
+		}
+		out = append(out, v)
+	}
+	return append(out, add...)
+}
+
+// addDeviceNode adds the device node d to spec, along with the device
+// cgroup rule allowing it.
+func addDeviceNode(spec *specs.Spec, d *cdi.DeviceNode) error {
+	if !filepath.IsAbs(d.Path) {
+		return fmt.Errorf("device path %q must be an absolute path", d.Path)
+	}
+	dev := specs.LinuxDevice{
+		Path:     d.Path,
+		Type:     d.Type,
+		Major:    d.Major,
+		Minor:    d.Minor,
+		FileMode: d.FileMode,
+		UID:      d.UID,
+		GID:      d.GID,
+	}
+	if dev.Type == "" || (dev.Type != "p" && dev.Major == 0 && dev.Minor == 0) {
+		hostPath := d.HostPath
+		if hostPath == "" {
+			hostPath = d.Path
+		}
+		host, err := devices.DeviceFromPath(hostPath, "")
+		if err != nil {
+			return fmt.Errorf("device %s: %w", d.Path, err)
+		}
+		dev.Type = string(host.Type)
+		dev.Major, dev.Minor = host.Major, host.Minor
+		if dev.FileMode == nil {
+			mode := host.FileMode
+			dev.FileMode = &mode
+		}
+	}
+	perms := d.Permissions
+	if perms == "" {
+		perms = "rwm"
+	}
+	if !devices.Permissions(perms).IsValid() {
+		return fmt.Errorf("device %s: invalid permissions %q", d.Path, perms)
+	}
+
+	if spec.Linux == nil {
+		spec.Linux = &specs.Linux{}
+	}
+	devs := spec.Linux.Devices[:0:0]
+	for _, old := range spec.Linux.Devices {
+		if old.Path != dev.Path {
+			devs = append(devs, old)
+		}
+	}
+	spec.Linux.Devices = append(devs, dev)
+	if dev.Type == "p" {
+		// Not subject to the device cgroup.
+		return nil
+	}
+	if spec.Linux.Resources == nil {
+		spec.Linux.Resources = &specs.LinuxResources{}
+	}
+	major, minor := dev.Major, dev.Minor
+	spec.Linux.Resources.Devices = append(spec.Linux.Resources.Devices, specs.LinuxDeviceCgroup{
+		Allow:  true,
+		Type:   dev.Type,
+		Major:  &major,
+		Minor:  &minor,
+		Access: perms,
+	})
+	return nil
+}
+
+// addHook adds the hook h to spec.
+func addHook(spec *specs.Spec, h *cdi.Hook) error {
+	if spec.Hooks == nil {
+		spec.Hooks = &specs.Hooks{}
+	}
+	hook := specs.Hook{Path: h.Path, Args: h.Args, Env: h.Env, Timeout: h.Timeout}
+	var hooks *[]specs.Hook
+	switch h.HookName {
+	case "prestart":
+		hooks = &spec.Hooks.Prestart //nolint:staticcheck // Deprecated, but still supported.
+	case "createRuntime":
+		hooks = &spec.Hooks.CreateRuntime
+	case "createContainer":
+		hooks = &spec.Hooks.CreateContainer
+	case "startContainer":
+		hooks = &spec.Hooks.StartContainer
+	case "poststart":
+		hooks = &spec.Hooks.Poststart
+	case "poststop":
+		hooks = &spec.Hooks.Poststop
+	default:
+		return errors.New("unknown hook name " + h.HookName)
+	}
+	*hooks = append(*hooks, hook)
+	return nil
+}
@@ -0,0 +1,143 @@
+package cdi
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+)
+
+const testSpec = `{
+	"cdiVersion": "0.6.0",
+	"kind": "example.com/gpu",
+	"containerEdits": {
+		"env": ["GPU_DRIVER=1"]
+	},
+	"devices": [
+		{
+			"name": "0",
+			"containerEdits": {
+				"env": ["GPU=0", "PATH=/gpu/bin"],
+				"deviceNodes": [{"path": "/dev/gpu0", "type": "c", "major": 250, "minor": 0, "permissions": "rw"}],
+				"mounts": [{"hostPath": "/usr/lib/gpu", "containerPath": "/gpu/lib", "options": ["ro"]}],
+				"hooks": [{"hookName": "createContainer", "path": "/usr/bin/gpu-hook", "args": ["gpu-hook", "0"]}]
+			}
+		},
+		{
+			"name": "1",
+			"containerEdits": {
+				"deviceNodes": [{"path": "/dev/gpu1", "type": "c", "major": 250, "minor": 1}]
+			}
+		}
+	]
+}`
+
+func writeSpec(t *testing.T, dir, name, data string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestApply(t *testing.T) {
+	dir := t.TempDir()
+	writeSpec(t, dir, "gpu.json", testSpec)
+	writeSpec(t, dir, "other.yaml", `cdiVersion: 0.7.0
+kind: example.com/other
+devices:
+  - name: a
+    containerEdits:
+      env:
+        - OTHER=a
+      additionalGids: [44]
+`)
+	writeSpec(t, dir, "README", "not a spec")
+
+	spec := &specs.Spec{
+		Process:     &specs.Process{Env: []string{"PATH=/bin", "TERM=xterm"}},
+		Annotations: map[string]string{Annotation: "example.com/gpu=0, example.com/gpu=1,example.com/other=a"},
+	}
+	if err := ApplyAnnotation(spec, []string{dir, filepath.Join(dir, "missing")}); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedEnv := []string{"TERM=xterm", "GPU_DRIVER=1", "GPU=0", "PATH=/gpu/bin", "OTHER=a"}
+	if !reflect.DeepEqual(spec.Process.Env, expectedEnv) {
+		t.Errorf("expected env %v, got %v", expectedEnv, spec.Process.Env)
+	}
+	if !reflect.DeepEqual(spec.Process.User.AdditionalGids, []uint32{44}) {
+		t.Errorf("expected additional gids [44], got %v", spec.Process.User.AdditionalGids)
+	}
+	if len(spec.Linux.Devices) != 2 || spec.Linux.Devices[0].Path != "/dev/gpu0" || spec.Linux.Devices[1].Minor != 1 {
+		t.Errorf("unexpected devices %+v", spec.Linux.Devices)
+	}
+	rules := spec.Linux.Resources.Devices
+	if len(rules) != 2 || !rules[0].Allow || rules[0].Access != "rw" || rules[1].Access != "rwm" || *rules[1].Minor != 1 {
+		t.Errorf("unexpected device rules %+v", rules)
+	}
+	expectedMount := specs.Mount{Destination: "/gpu/lib", Source: "/usr/lib/gpu", Type: "bind", Options: []string{"bind", "ro"}}
+	if len(spec.Mounts) != 1 || !reflect.DeepEqual(spec.Mounts[0], expectedMount) {
+		t.Errorf("unexpected mounts %+v", spec.Mounts)
+	}
+	if len(spec.Hooks.CreateContainer) != 1 || spec.Hooks.CreateContainer[0].Path != "/usr/bin/gpu-hook" {
+		t.Errorf("unexpected hooks %+v", spec.Hooks)
+	}
+}
+
+func TestApplyPriority(t *testing.T) {
+	low, high := t.TempDir(), t.TempDir()
+	writeSpec(t, low, "gpu.json", testSpec)
+	writeSpec(t, high, "gpu.json", `{"cdiVersion": "0.6.0", "kind": "example.com/gpu", "devices": [
+		{"name": "1", "containerEdits": {"env": ["OVERRIDE=1"]}}
+	]}`)
+
+	spec := &specs.Spec{}
+	if err := Apply(spec, []string{"example.com/gpu=1"}, []string{low, high}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(spec.Process.Env, []string{"OVERRIDE=1"}) || spec.Linux != nil {
+		t.Errorf("expected the device of the last directory, got env %v", spec.Process.Env)
+	}
+	// The other devices are still there.
+	if err := Apply(spec, []string{"example.com/gpu=0"}, []string{low, high}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeSpec(t, dir, "gpu.json", testSpec)
+	for _, name := range []string{"example.com/gpu=2", "example.com/tpu=0", "gpu=0", "example.com/gpu"} {
+		if err := Apply(&specs.Spec{}, []string{name}, []string{dir}); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+
+	writeSpec(t, dir, "bad.json", `{"cdiVersion": "0.6.0", "kind": "example.com/bad", "devices": [{"name": "0", "containerEdits": {"hooks": [{"hookName": "prerun", "path": "/bin/true"}]}}]}`)
+	if err := Apply(&specs.Spec{}, []string{"example.com/bad=0"}, []string{dir}); err == nil {
+		t.Error("unknown hook: expected error, got nil")
+	}
+}
+
+func TestLoadDevicesVersion(t *testing.T) {
+	for _, tc := range []struct {
+		name, data string
+		isErr      bool
+	}{
+		{name: "no version", data: `{"kind": "example.com/gpu", "devices": []}`, isErr: true},
+		{name: "unknown version", data: `{"cdiVersion": "9.9.9", "kind": "example.com/gpu", "devices": []}`, isErr: true},
+		{name: "too old version", data: "cdiVersion: 0.6.0\nkind: example.com/gpu\ndevices:\n  - name: \"0\"\n    containerEdits:\n      additionalGids: [44]\n", isErr: true},
+		{name: "valid", data: "cdiVersion: 0.7.0\nkind: example.com/gpu\ndevices:\n  - name: \"0\"\n    containerEdits:\n      additionalGids: [44]\n"},
+	} {
+		dir := t.TempDir()
+		writeSpec(t, dir, "gpu.yaml", tc.data)
+		_, err := loadDevices([]string{dir})
+		if tc.isErr && err == nil {
+			t.Errorf("%s: expected error, got nil", tc.name)
+		} else if !tc.isErr && err != nil {
+			t.Errorf("%s: expected no error, got %v", tc.name, err)
+		}
+	}
+}
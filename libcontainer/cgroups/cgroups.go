@@ -11,14 +11,57 @@ var (
 	// is not configured to set device rules.
 	ErrDevicesUnsupported = errors.New("cgroup manager is not configured to set device rules")
 
+	// ErrExists is returned by Apply if the cgroup is configured to be
+	// Exclusive, but already exists.
+	ErrExists = errors.New("cgroup already exists")
+
 	// DevicesSetV1 and DevicesSetV2 are functions to set devices for
 	// cgroup v1 and v2, respectively. Unless libcontainer/cgroups/devices
 	// package is imported, it is set to nil, so cgroup managers can't
 	// manage devices.
 	DevicesSetV1 func(path string, r *configs.Resources) error
 	DevicesSetV2 func(path string, r *configs.Resources) error
+
+	// ErrNoManager is returned by NewManager if no cgroup manager
+	// implementation is available.
+	ErrNoManager = errors.New("no cgroup manager available (libcontainer/cgroups/manager package is not imported)")
+
+	// NewManagerFunc is the function used by NewManager. Unless
+	// libcontainer/cgroups/manager package is imported, it is nil.
+	NewManagerFunc func(config *configs.Cgroup, paths map[string]string) (Manager, error)
 )
 
+// NewManager returns a cgroup manager for the config, which is chosen based
+// on the cgroup version in use (fs for v1, fs2 for v2), and on whether
+// config.Systemd is set (systemd, backed by fs or fs2).
+//
+// The paths are the ones returned by the GetPaths method of a manager
+// created earlier for the same cgroup (such as when the cgroup is managed
+// by a different process), or nil to derive them from the config. For
+// cgroup v2, the only key allowed is "" (the unified cgroup path).
+//
+// The libcontainer/cgroups/manager package has to be imported for this to
+// work, and the libcontainer/cgroups/devices package, for the managers to
+// be able to set device rules:
+//
+//	import (
+//		_ "github.com/szcdx/runc/libcontainer/cgroups/devices"
+//		_ "github.com/szcdx/runc/libcontainer/cgroups/manager"
+//	)
+//
+// NewManager, and the Manager interface, are meant to be used by the
+// programs managing cgroups on their own (without libcontainer), and are
+// kept backward compatible: the arguments and the methods are not changed
+// or removed, and their semantics stay the same.
+func NewManager(config *configs.Cgroup, paths map[string]string) (Manager, error) {
+	if NewManagerFunc == nil {
+		return nil, ErrNoManager
+	}
+	return NewManagerFunc(config, paths)
+}
+
+// Manager manages a cgroup (or, on cgroup v1, a set of cgroups, one per
+// controller). See NewManager.
 type Manager interface {
 	// Apply creates a cgroup, if not yet created, and adds a process
 	// with the specified pid into that cgroup.  A special value of -1
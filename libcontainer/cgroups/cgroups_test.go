@@ -1,7 +1,10 @@
 package cgroups
 
 import (
+	"errors"
 	"testing"
+
+	"github.com/szcdx/runc/libcontainer/configs"
 )
 
 func TestParseCgroups(t *testing.T) {
@@ -19,3 +22,10 @@ func TestParseCgroups(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestNewManagerNotAvailable(t *testing.T) {
+	// The manager package is not imported by this package.
+	if _, err := NewManager(&configs.Cgroup{}, nil); !errors.Is(err, ErrNoManager) {
+		t.Fatalf("expected ErrNoManager, got %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+package cgroups
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParseCPUList parses a list of CPUs in the cpuset format (such as "0-2,5"),
+// and returns the sorted CPU numbers. An empty list is valid.
+func ParseCPUList(list string) ([]int, error) {
+	seen := make(map[int]bool)
+	var cpus []int
+	for _, r := range strings.Split(list, ",") {
+		r = strings.TrimSpace(r)
+		if r == "" {
+			continue
+		}
+		first, last, isRange := strings.Cut(r, "-")
+		start, err := strconv.ParseUint(first, 10, 16)
+		if err != nil {
+			return nil, err
+		}
+		end := start
+		if isRange {
+			if end, err = strconv.ParseUint(last, 10, 16); err != nil {
+				return nil, err
+			}
+			if start > end {
+				return nil, errors.New("invalid range: " + r)
+			}
+		}
+		for cpu := int(start); cpu <= int(end); cpu++ {
+			if !seen[cpu] {
+				seen[cpu] = true
+				cpus = append(cpus, cpu)
+			}
+		}
+	}
+	sort.Ints(cpus)
+	return cpus, nil
+}
+
+// FormatCPUList formats the sorted list of CPUs in the cpuset format.
+func FormatCPUList(cpus []int) string {
+	var b strings.Builder
+	for i := 0; i < len(cpus); {
+		j := i
+		for j+1 < len(cpus) && cpus[j+1] == cpus[j]+1 {
+			j++
+		}
+		if b.Len() > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.Itoa(cpus[i]))
+		if j > i {
+			b.WriteByte('-')
+			b.WriteString(strconv.Itoa(cpus[j]))
+		}
+		i = j + 1
+	}
+	return b.String()
+}
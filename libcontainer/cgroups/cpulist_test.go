@@ -0,0 +1,35 @@
+package cgroups
+
+import "testing"
+
+func TestCPUList(t *testing.T) {
+	testCases := []struct {
+		in, out string
+		isErr   bool
+	}{
+		{in: "", out: ""},
+		{in: "0", out: "0"},
+		{in: "0-3", out: "0-3"},
+		{in: "7,1-2,3,9-10", out: "1-3,7,9-10"},
+		{in: " 1, 1-2,,", out: "1-2"},
+		{in: "3-1", isErr: true},
+		{in: "1-", isErr: true},
+		{in: "a", isErr: true},
+	}
+	for _, tc := range testCases {
+		cpus, err := ParseCPUList(tc.in)
+		if tc.isErr {
+			if err == nil {
+				t.Errorf("%q: expected error, got nil", tc.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.in, err)
+			continue
+		}
+		if out := FormatCPUList(cpus); out != tc.out {
+			t.Errorf("%q: expected %q, got %q", tc.in, tc.out, out)
+		}
+	}
+}
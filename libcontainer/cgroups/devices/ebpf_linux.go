@@ -1,10 +1,16 @@
 package devices
 
 import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
+	"path/filepath"
 	"runtime"
+	"strings"
 	"sync"
 	"unsafe"
 
@@ -13,6 +19,8 @@ import (
 	"github.com/cilium/ebpf/link"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/sys/unix"
+
+	"github.com/szcdx/runc/libcontainer/utils"
 )
 
 func nilCloser() error {
@@ -149,6 +157,165 @@ func haveBpfProgReplace() bool {
 //
 // https://github.com/torvalds/linux/commit/ebc614f687369f9df99828572b1d85a7c2de3d92
 func loadAttachCgroupDeviceFilter(insts asm.Instructions, license string, dirFd int) (func() error, error) {
+	prog, err := newCgroupDeviceFilter(insts, license)
+	if err != nil {
+		return nilCloser, err
+	}
+	return attachCgroupDeviceFilter(prog, dirFd)
+}
+
+// loadAttachPinnedCgroupDeviceFilter is like loadAttachCgroupDeviceFilter,
+// except that the program is shared by all the cgroups with the same device
+// rules, through the bpf filesystem directory pinDir: it is loaded and pinned
+// there by the first of them, and the others attach the pinned program. The
+// cgroup at dirPath is recorded as a user of the pinned program, and the
+// pinned programs no cgroup uses anymore are removed.
+func loadAttachPinnedCgroupDeviceFilter(insts asm.Instructions, license string, dirFd int, dirPath, pinDir string) (func() error, error) {
+	name, prog, err := pinnedCgroupDeviceFilter(insts, license, pinDir)
+	if err != nil {
+		return nilCloser, err
+	}
+	closer, err := attachCgroupDeviceFilter(prog, dirFd)
+	if err != nil {
+		return closer, err
+	}
+	if err := addPinnedFilterUser(pinDir, name, dirPath); err != nil {
+		logrus.Warnf("unable to record the user of the pinned device filter %s: %v", name, err)
+	}
+	removeUnusedPinnedFilters(pinDir, name, dirPath)
+	return closer, nil
+}
+
+const (
+	// pinnedFilterPrefix is the prefix of the names of the device filter
+	// programs pinned by runc.
+	pinnedFilterPrefix = "runc-devices-"
+
+	// pinnedFilterUsersSuffix is the suffix of the name of the directory,
+	// next to a pinned program, with an entry for each cgroup using it.
+	// These are directories too, as a bpf filesystem has no regular files.
+	pinnedFilterUsersSuffix = ".users"
+)
+
+// pinnedCgroupDeviceFilter returns the name and the device filter program
+// pinned in pinDir for the instructions insts, loading and pinning it if
+// there is none.
+func pinnedCgroupDeviceFilter(insts asm.Instructions, license, pinDir string) (string, *ebpf.Program, error) {
+	// The name of the pinned program is the digest of what it is made of,
+	// so the same rules (which the emulator made canonical) give the same
+	// program, and different rules never do.
+	h := sha256.New()
+	if err := insts.Marshal(h, binary.LittleEndian); err != nil {
+		return "", nil, err
+	}
+	h.Write([]byte(license))
+	name := pinnedFilterPrefix + hex.EncodeToString(h.Sum(nil))
+	path := filepath.Join(pinDir, name)
+
+	prog, err := ebpf.LoadPinnedProgram(path, nil)
+	if err == nil {
+		if err := checkPinnedCgroupDeviceFilter(prog, insts); err != nil {
+			prog.Close()
+			return "", nil, fmt.Errorf("pinned program %s: %w", path, err)
+		}
+		return name, prog, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", nil, fmt.Errorf("unable to load pinned device filter: %w", err)
+	}
+	prog, err = newCgroupDeviceFilter(insts, license)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := prog.Pin(path); err != nil {
+		prog.Close()
+		// Another container may have pinned the same program meanwhile.
+		if errors.Is(err, os.ErrExist) {
+			return pinnedCgroupDeviceFilter(insts, license, pinDir)
+		}
+		return "", nil, fmt.Errorf("unable to pin device filter: %w", err)
+	}
+	return name, prog, nil
+}
+
+// checkPinnedCgroupDeviceFilter returns an error if the pinned program prog
+// is not the device filter made of the instructions insts, as anyone able to
+// write to the pin directory could have pinned another program there.
+func checkPinnedCgroupDeviceFilter(prog *ebpf.Program, insts asm.Instructions) error {
+	if prog.Type() != ebpf.CGroupDevice {
+		return errors.New("not a device filter")
+	}
+	info, err := prog.Info()
+	if err != nil {
+		return err
+	}
+	tag, err := insts.Tag(utils.NativeEndian)
+	if err != nil {
+		return err
+	}
+	if info.Tag != tag {
+		return fmt.Errorf("tag %s does not match the device rules (%s)", info.Tag, tag)
+	}
+	return nil
+}
+
+// addPinnedFilterUser records the cgroup at dirPath as a user of the pinned
+// program name.
+func addPinnedFilterUser(pinDir, name, dirPath string) error {
+	users := filepath.Join(pinDir, name+pinnedFilterUsersSuffix)
+	if err := os.Mkdir(users, 0o700); err != nil && !errors.Is(err, os.ErrExist) {
+		return err
+	}
+	err := os.Mkdir(filepath.Join(users, url.PathEscape(dirPath)), 0o700)
+	if err != nil && !errors.Is(err, os.ErrExist) {
+		return err
+	}
+	return nil
+}
+
+// removeUnusedPinnedFilters removes the pinned programs in pinDir which no
+// cgroup uses anymore, that is, the ones whose recorded users are all gone,
+// or use another program (such as the cgroup at dirPath, which now uses the
+// program name). This is done on a best effort basis: removing a pinned
+// program which is still in use only means that it is no longer shared with
+// the next cgroups, as the cgroups it is attached to keep it.
+func removeUnusedPinnedFilters(pinDir, name, dirPath string) {
+	entries, err := os.ReadDir(pinDir)
+	if err != nil {
+		logrus.Debugf("unable to list pinned device filters: %v", err)
+		return
+	}
+	for _, e := range entries {
+		prog := e.Name()
+		if !strings.HasPrefix(prog, pinnedFilterPrefix) || strings.HasSuffix(prog, pinnedFilterUsersSuffix) || prog == name {
+			continue
+		}
+		users := filepath.Join(pinDir, prog+pinnedFilterUsersSuffix)
+		cgs, _ := os.ReadDir(users)
+		used := false
+		for _, cg := range cgs {
+			path, err := url.PathUnescape(cg.Name())
+			if err == nil && path != dirPath {
+				if _, err := os.Stat(path); err == nil {
+					used = true
+					continue
+				}
+			}
+			_ = os.Remove(filepath.Join(users, cg.Name()))
+		}
+		if used {
+			continue
+		}
+		if err := os.Remove(filepath.Join(pinDir, prog)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			logrus.Debugf("unable to remove unused pinned device filter %s: %v", prog, err)
+			continue
+		}
+		_ = os.Remove(users)
+	}
+}
+
+// newCgroupDeviceFilter loads an eBPF device filter program.
+func newCgroupDeviceFilter(insts asm.Instructions, license string) (*ebpf.Program, error) {
 	// Increase `ulimit -l` limit to avoid BPF_PROG_LOAD error (#2167).
 	// This limit is not inherited into the container.
 	memlockLimit := &unix.Rlimit{
@@ -157,23 +324,23 @@ func loadAttachCgroupDeviceFilter(insts asm.Instructions, license string, dirFd
 	}
 	_ = unix.Setrlimit(unix.RLIMIT_MEMLOCK, memlockLimit)
 
-	// Get the list of existing programs.
-	oldProgs, err := findAttachedCgroupDeviceFilters(dirFd)
-	if err != nil {
-		return nilCloser, err
-	}
-	useReplaceProg := haveBpfProgReplace() && len(oldProgs) == 1
-
-	// Generate new program.
 	spec := &ebpf.ProgramSpec{
 		Type:         ebpf.CGroupDevice,
 		Instructions: insts,
 		License:      license,
 	}
-	prog, err := ebpf.NewProgram(spec)
+	return ebpf.NewProgram(spec)
+}
+
+// attachCgroupDeviceFilter attaches the eBPF device filter program prog to
+// the cgroup directory dirFd, replacing the programs attached to it.
+func attachCgroupDeviceFilter(prog *ebpf.Program, dirFd int) (func() error, error) {
+	// Get the list of existing programs.
+	oldProgs, err := findAttachedCgroupDeviceFilters(dirFd)
 	if err != nil {
 		return nilCloser, err
 	}
+	useReplaceProg := haveBpfProgReplace() && len(oldProgs) == 1
 
 	// If there is only one old program, we can just replace it directly.
 	var (
@@ -0,0 +1,64 @@
+package devices
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRemoveUnusedPinnedFilters(t *testing.T) {
+	pinDir, cgroupDir := t.TempDir(), t.TempDir()
+	alive := filepath.Join(cgroupDir, "alive")
+	if err := os.Mkdir(alive, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	gone := filepath.Join(cgroupDir, "gone")
+	updated := filepath.Join(cgroupDir, "updated")
+	if err := os.Mkdir(updated, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	// The programs are regular files here, rather than pinned programs.
+	for prog, users := range map[string][]string{
+		"runc-devices-used":    {alive, gone},
+		"runc-devices-unused":  {gone},
+		"runc-devices-old":     {updated},
+		"runc-devices-new":     {updated},
+		"runc-devices-nousers": nil,
+	} {
+		if err := os.WriteFile(filepath.Join(pinDir, prog), nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		for _, user := range users {
+			if err := addPinnedFilterUser(pinDir, prog, user); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := os.WriteFile(filepath.Join(pinDir, "other"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	removeUnusedPinnedFilters(pinDir, "runc-devices-new", updated)
+
+	for name, exists := range map[string]bool{
+		"runc-devices-used": true,
+		"runc-devices-used.users/" + url.PathEscape(alive):  true,
+		"runc-devices-used.users/" + url.PathEscape(gone):   false,
+		"runc-devices-unused":                               false,
+		"runc-devices-unused.users":                         false,
+		"runc-devices-old":                                  false,
+		"runc-devices-new":                                  true,
+		"runc-devices-new.users/" + url.PathEscape(updated): true,
+		"runc-devices-nousers":                              false,
+		"other":                                             true,
+	} {
+		_, err := os.Stat(filepath.Join(pinDir, name))
+		if exists && err != nil {
+			t.Errorf("%s: expected to exist, got %v", name, err)
+		} else if !exists && !os.IsNotExist(err) {
+			t.Errorf("%s: expected to be removed, got %v", name, err)
+		}
+	}
+}
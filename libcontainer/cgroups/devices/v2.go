@@ -64,7 +64,12 @@ func setV2(dirPath string, r *configs.Resources) error {
 		return fmt.Errorf("cannot get dir FD for %s", dirPath)
 	}
 	defer unix.Close(dirFD)
-	if _, err := loadAttachCgroupDeviceFilter(insts, license, dirFD); err != nil {
+	if r.DevicesPinDir != "" {
+		_, err = loadAttachPinnedCgroupDeviceFilter(insts, license, dirFD, dirPath, r.DevicesPinDir)
+	} else {
+		_, err = loadAttachCgroupDeviceFilter(insts, license, dirFD)
+	}
+	if err != nil {
 		if !canSkipEBPFError(r) {
 			return err
 		}
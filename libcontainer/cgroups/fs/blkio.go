@@ -2,12 +2,14 @@ package fs
 
 import (
 	"bufio"
+	"errors"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
 
 	"github.com/szcdx/runc/libcontainer/cgroups"
+	"github.com/szcdx/runc/libcontainer/cgroups/fscommon"
 	"github.com/szcdx/runc/libcontainer/configs"
 )
 
@@ -164,6 +166,10 @@ func getBlkioStat(dir, file string) ([]cgroups.BlkioStatEntry, error) {
 }
 
 func (s *BlkioGroup) GetStats(path string, stats *cgroups.Stats) error {
+	if err := s.getLimits(path, &stats.BlkioStats); err != nil {
+		return err
+	}
+
 	type blkioStatInfo struct {
 		filename            string
 		blkioStatEntriesPtr *[]cgroups.BlkioStatEntry
@@ -296,6 +302,37 @@ func (s *BlkioGroup) GetStats(path string, stats *cgroups.Stats) error {
 	return nil
 }
 
+// getLimits reads back the configured weights and throttles. The files
+// which are not present are skipped.
+func (s *BlkioGroup) getLimits(path string, stats *cgroups.BlkioStats) error {
+	s.detectWeightFilenames(path)
+	weight, _, err := fscommon.GetBlkioWeights(path, s.weightFilename)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	stats.Weight = weight
+	_, stats.WeightDevice, err = fscommon.GetBlkioWeights(path, s.weightDeviceFilename)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	for _, t := range []struct{ file, op string }{
+		{"blkio.throttle.read_bps_device", "rbps"},
+		{"blkio.throttle.write_bps_device", "wbps"},
+		{"blkio.throttle.read_iops_device", "riops"},
+		{"blkio.throttle.write_iops_device", "wiops"},
+	} {
+		entries, err := getBlkioStat(path, t.file)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			e.Op = t.op
+			stats.ThrottleDevice = append(stats.ThrottleDevice, e)
+		}
+	}
+	return nil
+}
+
 func (s *BlkioGroup) detectWeightFilenames(path string) {
 	if s.weightFilename != "" {
 		// Already detected.
@@ -862,3 +862,26 @@ func TestBlkioSetThrottleWriteIOpsDevice(t *testing.T) {
 		t.Fatal("Got the wrong value, set blkio.throttle.write_iops_device failed.")
 	}
 }
+
+func TestBlkioLimitStats(t *testing.T) {
+	path := tempDir(t, "blkio")
+	writeFileContents(t, path, map[string]string{
+		"blkio.weight":                     "500\n",
+		"blkio.weight_device":              "8:0 300\n8:16 200\n",
+		"blkio.throttle.read_bps_device":   "8:0 1048576\n",
+		"blkio.throttle.write_iops_device": "8:16 100\n",
+	})
+	blkio := &BlkioGroup{}
+	actualStats := *cgroups.NewStats()
+	if err := blkio.GetStats(path, &actualStats); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedStats := cgroups.BlkioStats{Weight: 500}
+	appendBlkioStatEntry(&expectedStats.WeightDevice, 8, 0, 300, "")
+	appendBlkioStatEntry(&expectedStats.WeightDevice, 8, 16, 200, "")
+	appendBlkioStatEntry(&expectedStats.ThrottleDevice, 8, 0, 1048576, "rbps")
+	appendBlkioStatEntry(&expectedStats.ThrottleDevice, 8, 16, 100, "wiops")
+
+	expectBlkioStatsEquals(t, expectedStats, actualStats.BlkioStats)
+}
@@ -49,7 +49,16 @@ func (s *CpuGroup) SetRtSched(path string, r *configs.Resources) error {
 }
 
 func (s *CpuGroup) Set(path string, r *configs.Resources) error {
-	if r.CpuShares != 0 {
+	// The shares of an idle cgroup can not be set, so set cpu.idle first
+	// and skip the shares if it is 1.
+	if r.CPUIdle != nil {
+		idle := strconv.FormatInt(*r.CPUIdle, 10)
+		if err := cgroups.WriteFile(path, "cpu.idle", idle); err != nil {
+			return err
+		}
+	}
+
+	if r.CpuShares != 0 && !isIdle(r) {
 		shares := r.CpuShares
 		if err := cgroups.WriteFile(path, "cpu.shares", strconv.FormatUint(shares, 10)); err != nil {
 			return err
@@ -124,16 +133,14 @@ func (s *CpuGroup) Set(path string, r *configs.Resources) error {
 		}
 	}
 
-	if r.CPUIdle != nil {
-		idle := strconv.FormatInt(*r.CPUIdle, 10)
-		if err := cgroups.WriteFile(path, "cpu.idle", idle); err != nil {
-			return err
-		}
-	}
-
 	return s.SetRtSched(path, r)
 }
 
+// isIdle reports whether the cgroup is to be made idle (SCHED_IDLE).
+func isIdle(r *configs.Resources) bool {
+	return r.CPUIdle != nil && *r.CPUIdle == 1
+}
+
 func (s *CpuGroup) GetStats(path string, stats *cgroups.Stats) error {
 	const file = "cpu.stat"
 	f, err := cgroups.OpenFile(path, file, os.O_RDONLY)
@@ -160,6 +167,12 @@ func (s *CpuGroup) GetStats(path string, stats *cgroups.Stats) error {
 
 		case "throttled_time":
 			stats.CpuStats.ThrottlingData.ThrottledTime = v
+
+		case "nr_bursts":
+			stats.CpuStats.ThrottlingData.Bursts = v
+
+		case "burst_time":
+			stats.CpuStats.ThrottlingData.BurstTime = v
 		}
 	}
 	return nil
@@ -39,6 +39,41 @@ func TestCpuSetShares(t *testing.T) {
 	}
 }
 
+func TestCpuSetIdle(t *testing.T) {
+	path := tempDir(t, "cpu")
+
+	writeFileContents(t, path, map[string]string{
+		"cpu.idle":   "0",
+		"cpu.shares": "1024",
+	})
+
+	idle := int64(1)
+	r := &configs.Resources{
+		CPUIdle:   &idle,
+		CpuShares: 512,
+	}
+	cpu := &CpuGroup{}
+	if err := cpu.Set(path, r); err != nil {
+		t.Fatal(err)
+	}
+
+	value, err := fscommon.GetCgroupParamUint(path, "cpu.idle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != 1 {
+		t.Fatalf("expected cpu.idle to be 1, got %d", value)
+	}
+	// The shares of an idle cgroup are left alone.
+	value, err = fscommon.GetCgroupParamUint(path, "cpu.shares")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != 1024 {
+		t.Fatalf("expected cpu.shares not to be set, got %d", value)
+	}
+}
+
 func TestCpuSetBandWidth(t *testing.T) {
 	path := tempDir(t, "cpu")
 
@@ -123,10 +158,12 @@ func TestCpuStats(t *testing.T) {
 		nrPeriods     = 2000
 		nrThrottled   = 200
 		throttledTime = uint64(18446744073709551615)
+		nrBursts      = 20
+		burstTime     = 123456789
 	)
 
-	cpuStatContent := fmt.Sprintf("nr_periods %d\nnr_throttled %d\nthrottled_time %d\n",
-		nrPeriods, nrThrottled, throttledTime)
+	cpuStatContent := fmt.Sprintf("nr_periods %d\nnr_throttled %d\nthrottled_time %d\nnr_bursts %d\nburst_time %d\n",
+		nrPeriods, nrThrottled, throttledTime, nrBursts, burstTime)
 	writeFileContents(t, path, map[string]string{
 		"cpu.stat": cpuStatContent,
 	})
@@ -142,6 +179,8 @@ func TestCpuStats(t *testing.T) {
 		Periods:          nrPeriods,
 		ThrottledPeriods: nrThrottled,
 		ThrottledTime:    throttledTime,
+		Bursts:           nrBursts,
+		BurstTime:        burstTime,
 	}
 
 	expectThrottlingDataEquals(t, expectedStats, actualStats.CpuStats.ThrottlingData)
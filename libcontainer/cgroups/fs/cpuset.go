@@ -25,17 +25,14 @@ func (s *CpusetGroup) Apply(path string, r *configs.Resources, pid int) error {
 }
 
 func (s *CpusetGroup) Set(path string, r *configs.Resources) error {
+	tx := cgroups.NewTx(path)
 	if r.CpusetCpus != "" {
-		if err := cgroups.WriteFile(path, "cpuset.cpus", r.CpusetCpus); err != nil {
-			return err
-		}
+		tx.Write("cpuset.cpus", r.CpusetCpus)
 	}
 	if r.CpusetMems != "" {
-		if err := cgroups.WriteFile(path, "cpuset.mems", r.CpusetMems); err != nil {
-			return err
-		}
+		tx.Write("cpuset.mems", r.CpusetMems)
 	}
-	return nil
+	return tx.Commit()
 }
 
 func getCpusetStat(path string, file string) ([]uint16, error) {
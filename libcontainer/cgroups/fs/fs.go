@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"sync"
 
 	"golang.org/x/sys/unix"
@@ -27,8 +28,8 @@ var subsystems = []subsystem{
 	&PerfEventGroup{},
 	&FreezerGroup{},
 	&RdmaGroup{},
+	&MiscGroup{},
 	&NameGroup{GroupName: "name=systemd", Join: true},
-	&NameGroup{GroupName: "misc", Join: true},
 }
 
 var errSubsystemDoesNotExist = errors.New("cgroup: subsystem does not exist")
@@ -58,6 +59,9 @@ type Manager struct {
 	mu      sync.Mutex
 	cgroups *configs.Cgroup
 	paths   map[string]string
+	// foreign is set once an Exclusive cgroup is found to exist, so that
+	// it is not removed by Destroy.
+	foreign bool
 }
 
 func NewManager(cg *configs.Cgroup, paths map[string]string) (*Manager, error) {
@@ -111,6 +115,12 @@ func (m *Manager) Apply(pid int) (err error) {
 
 	c := m.cgroups
 
+	if c.Exclusive {
+		if err := m.createExclusive(); err != nil {
+			return err
+		}
+	}
+
 	for _, sys := range subsystems {
 		name := sys.Name()
 		p, ok := m.paths[name]
@@ -139,9 +149,47 @@ func (m *Manager) Apply(pid int) (err error) {
 	return nil
 }
 
+// createExclusive creates the cgroup directories, failing with
+// cgroups.ErrExists if any of them already exists.
+func (m *Manager) createExclusive() (retErr error) {
+	created := make(map[string]struct{})
+	defer func() {
+		if retErr != nil {
+			for p := range created {
+				_ = os.Remove(p)
+			}
+		}
+	}()
+	for _, sys := range subsystems {
+		p, ok := m.paths[sys.Name()]
+		if !ok {
+			continue
+		}
+		// Several controllers may share a directory.
+		if _, ok := created[p]; ok {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			return err
+		}
+		if err := os.Mkdir(p, 0o755); err != nil {
+			if os.IsExist(err) {
+				m.foreign = true
+				return fmt.Errorf("%w: %s", cgroups.ErrExists, p)
+			}
+			return err
+		}
+		created[p] = struct{}{}
+	}
+	return nil
+}
+
 func (m *Manager) Destroy() error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	if m.foreign {
+		return nil
+	}
 	return cgroups.RemovePaths(m.paths)
 }
 
@@ -1,6 +1,9 @@
 package fs
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/szcdx/runc/libcontainer/cgroups"
@@ -48,3 +51,44 @@ func BenchmarkGetStats(b *testing.B) {
 		b.Fatalf("stats: %+v", st)
 	}
 }
+
+func TestCreateExclusive(t *testing.T) {
+	dir := t.TempDir()
+	paths := map[string]string{
+		"memory":  filepath.Join(dir, "memory", "a", "b"),
+		"cpu":     filepath.Join(dir, "cpu,cpuacct", "a", "b"),
+		"cpuacct": filepath.Join(dir, "cpu,cpuacct", "a", "b"),
+	}
+	cg := &configs.Cgroup{Exclusive: true, Resources: &configs.Resources{}}
+	m := &Manager{cgroups: cg, paths: paths}
+	if err := m.createExclusive(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another manager with the same paths must not take the cgroup, nor
+	// remove it.
+	m2 := &Manager{cgroups: cg, paths: paths}
+	if err := m2.createExclusive(); !errors.Is(err, cgroups.ErrExists) {
+		t.Fatalf("expected ErrExists, got %v", err)
+	}
+	if err := m2.Destroy(); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range paths {
+		if _, err := os.Stat(p); err != nil {
+			t.Errorf("expected %s to be kept: %v", p, err)
+		}
+	}
+
+	// The directories created before the one found to exist are removed.
+	if err := os.Remove(paths["memory"]); err != nil {
+		t.Fatal(err)
+	}
+	m3 := &Manager{cgroups: cg, paths: paths}
+	if err := m3.createExclusive(); !errors.Is(err, cgroups.ErrExists) {
+		t.Fatalf("expected ErrExists, got %v", err)
+	}
+	if _, err := os.Stat(paths["memory"]); !os.IsNotExist(err) {
+		t.Errorf("expected %s to be removed, got %v", paths["memory"], err)
+	}
+}
@@ -2,6 +2,7 @@ package fs
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"strconv"
 
@@ -22,19 +23,26 @@ func (s *HugetlbGroup) Apply(path string, _ *configs.Resources, pid int) error {
 
 func (s *HugetlbGroup) Set(path string, r *configs.Resources) error {
 	const suffix = ".limit_in_bytes"
+	fault := r.HugetlbAccounting != "reservation"
+	rsvd := r.HugetlbAccounting != "fault"
 	skipRsvd := false
 
 	for _, hugetlb := range r.HugetlbLimit {
 		prefix := "hugetlb." + hugetlb.Pagesize
 		val := strconv.FormatUint(hugetlb.Limit, 10)
-		if err := cgroups.WriteFile(path, prefix+suffix, val); err != nil {
-			return err
+		if fault {
+			if err := cgroups.WriteFile(path, prefix+suffix, val); err != nil {
+				return err
+			}
 		}
-		if skipRsvd {
+		if !rsvd || skipRsvd {
 			continue
 		}
 		if err := cgroups.WriteFile(path, prefix+".rsvd"+suffix, val); err != nil {
 			if errors.Is(err, os.ErrNotExist) {
+				if !fault {
+					return fmt.Errorf("hugetlb reservation accounting is not supported: %w", err)
+				}
 				skipRsvd = true
 				continue
 			}
@@ -49,36 +57,50 @@ func (s *HugetlbGroup) GetStats(path string, stats *cgroups.Stats) error {
 	if !cgroups.PathExists(path) {
 		return nil
 	}
-	rsvd := ".rsvd"
-	hugetlbStats := cgroups.HugetlbStats{}
 	for _, pageSize := range cgroups.HugePageSizes() {
-	again:
-		prefix := "hugetlb." + pageSize + rsvd
-
-		value, err := fscommon.GetCgroupParamUint(path, prefix+".usage_in_bytes")
-		if err != nil {
-			if rsvd != "" && errors.Is(err, os.ErrNotExist) {
-				rsvd = ""
-				goto again
+		prefix := "hugetlb." + pageSize
+		// The reservation based counters are preferred, and the page
+		// fault based ones are then reported separately.
+		hugetlbStats, err := getHugetlbStats(path, prefix+".rsvd")
+		if err == nil {
+			faultStats, err := getHugetlbStats(path, prefix)
+			if err == nil {
+				hugetlbStats.Fault = &faultStats
+			} else if !errors.Is(err, os.ErrNotExist) {
+				return err
 			}
-			return err
+		} else if errors.Is(err, os.ErrNotExist) {
+			hugetlbStats, err = getHugetlbStats(path, prefix)
 		}
-		hugetlbStats.Usage = value
-
-		value, err = fscommon.GetCgroupParamUint(path, prefix+".max_usage_in_bytes")
-		if err != nil {
-			return err
-		}
-		hugetlbStats.MaxUsage = value
-
-		value, err = fscommon.GetCgroupParamUint(path, prefix+".failcnt")
 		if err != nil {
 			return err
 		}
-		hugetlbStats.Failcnt = value
-
 		stats.HugetlbStats[pageSize] = hugetlbStats
 	}
 
 	return nil
 }
+
+func getHugetlbStats(path, prefix string) (cgroups.HugetlbStats, error) {
+	var hugetlbStats cgroups.HugetlbStats
+
+	value, err := fscommon.GetCgroupParamUint(path, prefix+".usage_in_bytes")
+	if err != nil {
+		return hugetlbStats, err
+	}
+	hugetlbStats.Usage = value
+
+	value, err = fscommon.GetCgroupParamUint(path, prefix+".max_usage_in_bytes")
+	if err != nil {
+		return hugetlbStats, err
+	}
+	hugetlbStats.MaxUsage = value
+
+	value, err = fscommon.GetCgroupParamUint(path, prefix+".failcnt")
+	if err != nil {
+		return hugetlbStats, err
+	}
+	hugetlbStats.Failcnt = value
+
+	return hugetlbStats, nil
+}
@@ -92,6 +92,65 @@ func TestHugetlbStats(t *testing.T) {
 	}
 }
 
+func TestHugetlbSetHugetlbAccounting(t *testing.T) {
+	const hugetlbLimit = 512
+	for _, accounting := range []string{"", "fault", "reservation"} {
+		path := tempDir(t, "hugetlb")
+		r := &configs.Resources{HugetlbAccounting: accounting}
+		for _, pageSize := range cgroups.HugePageSizes() {
+			writeFileContents(t, path, map[string]string{
+				fmt.Sprintf(limit, pageSize):     "0",
+				fmt.Sprintf(rsvdLimit, pageSize): "0",
+			})
+			r.HugetlbLimit = append(r.HugetlbLimit, &configs.HugepageLimit{
+				Pagesize: pageSize,
+				Limit:    hugetlbLimit,
+			})
+		}
+		hugetlb := &HugetlbGroup{}
+		if err := hugetlb.Set(path, r); err != nil {
+			t.Fatal(err)
+		}
+		for _, pageSize := range cgroups.HugePageSizes() {
+			for f, set := range map[string]bool{
+				limit:     accounting != "reservation",
+				rsvdLimit: accounting != "fault",
+			} {
+				file := fmt.Sprintf(f, pageSize)
+				value, err := fscommon.GetCgroupParamUint(path, file)
+				if err != nil {
+					t.Fatal(err)
+				}
+				want := uint64(0)
+				if set {
+					want = hugetlbLimit
+				}
+				if value != want {
+					t.Errorf("accounting %q: %s: expected %d, got %d", accounting, file, want, value)
+				}
+			}
+		}
+	}
+}
+
+func TestHugetlbSetHugetlbReservationNotSupported(t *testing.T) {
+	path := tempDir(t, "hugetlb")
+	writeFileContents(t, path, map[string]string{
+		fmt.Sprintf(limit, "2MB"): "0",
+	})
+	r := &configs.Resources{
+		HugetlbAccounting: "reservation",
+		HugetlbLimit:      []*configs.HugepageLimit{{Pagesize: "2MB", Limit: 512}},
+	}
+	// Make sure the files are not created by the test mode.
+	cgroups.TestMode = false
+	defer func() { cgroups.TestMode = true }()
+	hugetlb := &HugetlbGroup{}
+	if err := hugetlb.Set(path, r); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
 func TestHugetlbRStatsRsvd(t *testing.T) {
 	path := tempDir(t, "hugetlb")
 	for _, pageSize := range cgroups.HugePageSizes() {
@@ -114,6 +173,34 @@ func TestHugetlbRStatsRsvd(t *testing.T) {
 	}
 }
 
+func TestHugetlbStatsFault(t *testing.T) {
+	path := tempDir(t, "hugetlb")
+	for _, pageSize := range cgroups.HugePageSizes() {
+		writeFileContents(t, path, map[string]string{
+			fmt.Sprintf(usage, pageSize):        "64\n",
+			fmt.Sprintf(maxUsage, pageSize):     "512\n",
+			fmt.Sprintf(failcnt, pageSize):      "1\n",
+			fmt.Sprintf(rsvdUsage, pageSize):    hugetlbUsageContents,
+			fmt.Sprintf(rsvdMaxUsage, pageSize): hugetlbMaxUsageContents,
+			fmt.Sprintf(rsvdFailcnt, pageSize):  hugetlbFailcnt,
+		})
+	}
+
+	hugetlb := &HugetlbGroup{}
+	actualStats := *cgroups.NewStats()
+	err := hugetlb.GetStats(path, &actualStats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedStats := cgroups.HugetlbStats{
+		Usage: 128, MaxUsage: 256, Failcnt: 100,
+		Fault: &cgroups.HugetlbStats{Usage: 64, MaxUsage: 512, Failcnt: 1},
+	}
+	for _, pageSize := range cgroups.HugePageSizes() {
+		expectHugetlbStatEquals(t, expectedStats, actualStats.HugetlbStats[pageSize])
+	}
+}
+
 func TestHugetlbStatsNoUsageFile(t *testing.T) {
 	path := tempDir(t, "hugetlb")
 	writeFileContents(t, path, map[string]string{
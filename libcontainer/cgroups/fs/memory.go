@@ -34,39 +34,7 @@ func (s *MemoryGroup) Apply(path string, _ *configs.Resources, pid int) error {
 	return apply(path, pid)
 }
 
-func setMemory(path string, val int64) error {
-	if val == 0 {
-		return nil
-	}
-
-	err := cgroups.WriteFile(path, cgroupMemoryLimit, strconv.FormatInt(val, 10))
-	if !errors.Is(err, unix.EBUSY) {
-		return err
-	}
-
-	// EBUSY means the kernel can't set new limit as it's too low
-	// (lower than the current usage). Return more specific error.
-	usage, err := fscommon.GetCgroupParamUint(path, cgroupMemoryUsage)
-	if err != nil {
-		return err
-	}
-	max, err := fscommon.GetCgroupParamUint(path, cgroupMemoryMaxUsage)
-	if err != nil {
-		return err
-	}
-
-	return fmt.Errorf("unable to set memory limit to %d (current usage: %d, peak usage: %d)", val, usage, max)
-}
-
-func setSwap(path string, val int64) error {
-	if val == 0 {
-		return nil
-	}
-
-	return cgroups.WriteFile(path, cgroupMemorySwapLimit, strconv.FormatInt(val, 10))
-}
-
-func setMemoryAndSwap(path string, r *configs.Resources) error {
+func (s *MemoryGroup) Set(path string, r *configs.Resources) error {
 	// If the memory update is set to -1 and the swap is not explicitly
 	// set, we should also set swap to -1, it means unlimited memory.
 	if r.Memory == -1 && r.MemorySwap == 0 {
@@ -76,6 +44,18 @@ func setMemoryAndSwap(path string, r *configs.Resources) error {
 		}
 	}
 
+	tx := cgroups.NewTx(path)
+	setMemory := func() {
+		if r.Memory != 0 {
+			tx.Write(cgroupMemoryLimit, strconv.FormatInt(r.Memory, 10))
+		}
+	}
+	setSwap := func() {
+		if r.MemorySwap != 0 {
+			tx.Write(cgroupMemorySwapLimit, strconv.FormatInt(r.MemorySwap, 10))
+		}
+	}
+	swapFirst := false
 	// When memory and swap memory are both set, we need to handle the cases
 	// for updating container.
 	if r.Memory != 0 && r.MemorySwap != 0 {
@@ -87,56 +67,48 @@ func setMemoryAndSwap(path string, r *configs.Resources) error {
 		// When update memory limit, we should adapt the write sequence
 		// for memory and swap memory, so it won't fail because the new
 		// value and the old value don't fit kernel's validation.
-		if r.MemorySwap == -1 || curLimit < uint64(r.MemorySwap) {
-			if err := setSwap(path, r.MemorySwap); err != nil {
-				return err
-			}
-			if err := setMemory(path, r.Memory); err != nil {
-				return err
-			}
-			return nil
-		}
-	}
-
-	if err := setMemory(path, r.Memory); err != nil {
-		return err
+		swapFirst = r.MemorySwap == -1 || curLimit < uint64(r.MemorySwap)
 	}
-	if err := setSwap(path, r.MemorySwap); err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func (s *MemoryGroup) Set(path string, r *configs.Resources) error {
-	if err := setMemoryAndSwap(path, r); err != nil {
-		return err
+	if swapFirst {
+		setSwap()
+		setMemory()
+	} else {
+		setMemory()
+		setSwap()
 	}
 
 	// ignore KernelMemory and KernelMemoryTCP
 
 	if r.MemoryReservation != 0 {
-		if err := cgroups.WriteFile(path, "memory.soft_limit_in_bytes", strconv.FormatInt(r.MemoryReservation, 10)); err != nil {
-			return err
-		}
+		tx.Write("memory.soft_limit_in_bytes", strconv.FormatInt(r.MemoryReservation, 10))
 	}
 
 	if r.OomKillDisable {
-		if err := cgroups.WriteFile(path, "memory.oom_control", "1"); err != nil {
-			return err
+		tx.Write("memory.oom_control", "1")
+	}
+	if r.MemorySwappiness != nil && int64(*r.MemorySwappiness) != -1 {
+		if *r.MemorySwappiness > 100 {
+			return fmt.Errorf("invalid memory swappiness value: %d (valid range is 0-100)", *r.MemorySwappiness)
 		}
+		tx.Write("memory.swappiness", strconv.FormatUint(*r.MemorySwappiness, 10))
 	}
-	if r.MemorySwappiness == nil || int64(*r.MemorySwappiness) == -1 {
-		return nil
-	} else if *r.MemorySwappiness <= 100 {
-		if err := cgroups.WriteFile(path, "memory.swappiness", strconv.FormatUint(*r.MemorySwappiness, 10)); err != nil {
+
+	err := tx.Commit()
+	var pathErr *os.PathError
+	if errors.Is(err, unix.EBUSY) && errors.As(err, &pathErr) && filepath.Base(pathErr.Path) == cgroupMemoryLimit {
+		// EBUSY means the kernel can't set new limit as it's too low
+		// (lower than the current usage). Return more specific error.
+		usage, err := fscommon.GetCgroupParamUint(path, cgroupMemoryUsage)
+		if err != nil {
 			return err
 		}
-	} else {
-		return fmt.Errorf("invalid memory swappiness value: %d (valid range is 0-100)", *r.MemorySwappiness)
+		max, err := fscommon.GetCgroupParamUint(path, cgroupMemoryMaxUsage)
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("unable to set memory limit to %d (current usage: %d, peak usage: %d)", r.Memory, usage, max)
 	}
-
-	return nil
+	return err
 }
 
 func (s *MemoryGroup) GetStats(path string, stats *cgroups.Stats) error {
@@ -0,0 +1,31 @@
+package fs
+
+import (
+	"os"
+
+	"github.com/szcdx/runc/libcontainer/cgroups"
+	"github.com/szcdx/runc/libcontainer/cgroups/fscommon"
+	"github.com/szcdx/runc/libcontainer/configs"
+)
+
+type MiscGroup struct{}
+
+func (s *MiscGroup) Name() string {
+	return "misc"
+}
+
+func (s *MiscGroup) Apply(path string, _ *configs.Resources, pid int) error {
+	return apply(path, pid)
+}
+
+func (s *MiscGroup) Set(path string, r *configs.Resources) error {
+	return fscommon.MiscSet(path, r)
+}
+
+func (s *MiscGroup) GetStats(path string, stats *cgroups.Stats) error {
+	// misc.events is only available since kernel 5.15.
+	if err := fscommon.MiscGetStats(path, stats); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
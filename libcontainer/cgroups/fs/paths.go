@@ -155,14 +155,16 @@ func innerPath(c *configs.Cgroup) (string, error) {
 func subsysPath(root, inner, subsystem string) (string, error) {
 	// If the cgroup name/path is absolute do not look relative to the cgroup of the init process.
 	if filepath.IsAbs(inner) {
-		mnt, err := cgroups.FindCgroupMountpoint(root, subsystem)
+		mnt, mntRoot, err := cgroups.FindCgroupMountpointAndRoot(root, subsystem)
 		// If we didn't mount the subsystem, there is no point we make the path.
 		if err != nil {
 			return "", err
 		}
 
 		// Sometimes subsystems can be mounted together as 'cpu,cpuacct'.
-		return filepath.Join(root, filepath.Base(mnt), inner), nil
+		// The path is relative to the cgroup namespace root, which is
+		// not necessarily the root of the mount.
+		return cgroups.MountPath(filepath.Join(root, filepath.Base(mnt)), mntRoot, inner), nil
 	}
 
 	// Use GetOwnCgroupPath for dind-like cases, when cgroupns is not
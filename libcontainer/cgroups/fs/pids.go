@@ -1,7 +1,9 @@
 package fs
 
 import (
+	"errors"
 	"math"
+	"os"
 	"strconv"
 
 	"github.com/szcdx/runc/libcontainer/cgroups"
@@ -56,7 +58,14 @@ func (s *PidsGroup) GetStats(path string, stats *cgroups.Stats) error {
 		max = 0
 	}
 
+	// pids.events is there since Linux 4.3.
+	hits, err := fscommon.GetValueByKey(path, "pids.events", "max")
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
 	stats.PidsStats.Current = current
 	stats.PidsStats.Limit = max
+	stats.PidsStats.LimitHits = hits
 	return nil
 }
@@ -55,6 +55,18 @@ func expectBlkioStatsEquals(t *testing.T, expected, actual cgroups.BlkioStats) {
 	if err := blkioStatEntryEquals(expected.IoTimeRecursive, actual.IoTimeRecursive); err != nil {
 		t.Errorf("blkio IoTimeRecursive do not match: %s", err)
 	}
+
+	if expected.Weight != actual.Weight {
+		t.Errorf("blkio Weight do not match: expected: %d, actual: %d", expected.Weight, actual.Weight)
+	}
+
+	if err := blkioStatEntryEquals(expected.WeightDevice, actual.WeightDevice); err != nil {
+		t.Errorf("blkio WeightDevice do not match: %s", err)
+	}
+
+	if err := blkioStatEntryEquals(expected.ThrottleDevice, actual.ThrottleDevice); err != nil {
+		t.Errorf("blkio ThrottleDevice do not match: %s", err)
+	}
 }
 
 func expectThrottlingDataEquals(t *testing.T, expected, actual cgroups.ThrottlingData) {
@@ -66,8 +78,8 @@ func expectThrottlingDataEquals(t *testing.T, expected, actual cgroups.Throttlin
 
 func expectHugetlbStatEquals(t *testing.T, expected, actual cgroups.HugetlbStats) {
 	t.Helper()
-	if expected != actual {
-		t.Errorf("Expected hugetlb stats: %v, actual: %v", expected, actual)
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected hugetlb stats: %+v, actual: %+v", expected, actual)
 	}
 }
 
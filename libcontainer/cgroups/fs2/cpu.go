@@ -29,7 +29,8 @@ func setCpu(dirPath string, r *configs.Resources) error {
 	}
 
 	// NOTE: .CpuShares is not used here. Conversion is the caller's responsibility.
-	if r.CpuWeight != 0 {
+	// The weight of an idle cgroup can not be set (the kernel returns EINVAL).
+	if r.CpuWeight != 0 && (r.CPUIdle == nil || *r.CPUIdle != 1) {
 		if err := cgroups.WriteFile(dirPath, "cpu.weight", strconv.FormatUint(r.CpuWeight, 10)); err != nil {
 			return err
 		}
@@ -109,6 +110,12 @@ func statCpu(dirPath string, stats *cgroups.Stats) error {
 
 		case "throttled_usec":
 			stats.CpuStats.ThrottlingData.ThrottledTime = v * 1000
+
+		case "nr_bursts":
+			stats.CpuStats.ThrottlingData.Bursts = v
+
+		case "burst_usec":
+			stats.CpuStats.ThrottlingData.BurstTime = v * 1000
 		}
 	}
 	if err := sc.Err(); err != nil {
@@ -0,0 +1,56 @@
+package fs2
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/szcdx/runc/libcontainer/cgroups"
+)
+
+const exampleCPUStatData = `usage_usec 2000000
+user_usec 1500000
+system_usec 500000
+nr_periods 100
+nr_throttled 10
+throttled_usec 20000
+nr_bursts 5
+burst_usec 3000
+`
+
+func TestStatCPU(t *testing.T) {
+	// We're using a fake cgroupfs.
+	cgroups.TestMode = true
+
+	fakeCgroupDir := t.TempDir()
+	statPath := filepath.Join(fakeCgroupDir, "cpu.stat")
+	if err := os.WriteFile(statPath, []byte(exampleCPUStatData), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stats cgroups.Stats
+	if err := statCpu(fakeCgroupDir, &stats); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedUsage := cgroups.CpuUsage{
+		TotalUsage:        2000000000,
+		UsageInUsermode:   1500000000,
+		UsageInKernelmode: 500000000,
+	}
+	if u := stats.CpuStats.CpuUsage; u.TotalUsage != expectedUsage.TotalUsage ||
+		u.UsageInUsermode != expectedUsage.UsageInUsermode ||
+		u.UsageInKernelmode != expectedUsage.UsageInKernelmode {
+		t.Errorf("expected usage %+v, got %+v", expectedUsage, u)
+	}
+	expectedThrottling := cgroups.ThrottlingData{
+		Periods:          100,
+		ThrottledPeriods: 10,
+		ThrottledTime:    20000000,
+		Bursts:           5,
+		BurstTime:        3000000,
+	}
+	if stats.CpuStats.ThrottlingData != expectedThrottling {
+		t.Errorf("expected throttling data %+v, got %+v", expectedThrottling, stats.CpuStats.ThrottlingData)
+	}
+}
@@ -1,28 +1,103 @@
 package fs2
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
 	"github.com/szcdx/runc/libcontainer/cgroups"
 	"github.com/szcdx/runc/libcontainer/configs"
 )
 
 func isCpusetSet(r *configs.Resources) bool {
-	return r.CpusetCpus != "" || r.CpusetMems != ""
+	return r.CpusetCpus != "" || r.CpusetMems != "" || r.CpusetPartition != ""
 }
 
-func setCpuset(dirPath string, r *configs.Resources) error {
+func setCpuset(tx *cgroups.Tx, r *configs.Resources) {
 	if !isCpusetSet(r) {
-		return nil
+		return
 	}
 
 	if r.CpusetCpus != "" {
-		if err := cgroups.WriteFile(dirPath, "cpuset.cpus", r.CpusetCpus); err != nil {
-			return err
-		}
+		tx.Write("cpuset.cpus", r.CpusetCpus)
 	}
 	if r.CpusetMems != "" {
-		if err := cgroups.WriteFile(dirPath, "cpuset.mems", r.CpusetMems); err != nil {
+		tx.Write("cpuset.mems", r.CpusetMems)
+	}
+}
+
+// setCpusetPartition makes the cgroup a cpuset partition of the given type,
+// after checking the constraints the kernel imposes on the parent cgroup
+// and the CPUs. Since the kernel does not fail the write if the partition
+// can not be created, but marks it as invalid instead, the resulting state
+// is checked as well.
+func setCpusetPartition(dirPath, partition string) error {
+	if partition != "member" {
+		if err := checkCpusetPartition(dirPath); err != nil {
+			return fmt.Errorf("unable to set cpuset partition %q: %w", partition, err)
+		}
+	}
+	if err := cgroups.WriteFile(dirPath, "cpuset.cpus.partition", partition); err != nil {
+		return err
+	}
+	state, err := cgroups.ReadFile(dirPath, "cpuset.cpus.partition")
+	if err != nil {
+		return err
+	}
+	state = strings.TrimSpace(state)
+	if strings.Contains(state, "invalid") {
+		return fmt.Errorf("unable to set cpuset partition %q: the partition is %s", partition, state)
+	}
+	return nil
+}
+
+// checkCpusetPartition checks that the cgroup can become a partition root:
+// its parent has to be a partition root itself (unless the kernel supports
+// remote partitions, which are set up using cpuset.cpus.exclusive in the
+// ancestors), and its CPUs must be a subset of its parent's effective CPUs.
+func checkCpusetPartition(dirPath string) error {
+	parent := filepath.Dir(dirPath)
+	if !cgroups.PathExists(filepath.Join(parent, "cpuset.cpus.exclusive")) {
+		state, err := cgroups.ReadFile(parent, "cpuset.cpus.partition")
+		// The root cgroup has no cpuset.cpus.partition, and is always
+		// a partition root.
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
 			return err
 		}
+		if state = strings.TrimSpace(state); err == nil && state != "root" && state != "isolated" {
+			return fmt.Errorf("parent cgroup %s is not a partition root (%s)", parent, state)
+		}
+	}
+
+	cpus, err := cgroups.ReadFile(dirPath, "cpuset.cpus")
+	if err != nil {
+		return err
+	}
+	list, err := cgroups.ParseCPUList(cpus)
+	if err != nil {
+		return err
+	}
+	if len(list) == 0 {
+		return errors.New("no cpuset CPUs are set")
+	}
+	parentCPUs, err := cgroups.ReadFile(parent, "cpuset.cpus.effective")
+	if err != nil {
+		return err
+	}
+	parentList, err := cgroups.ParseCPUList(parentCPUs)
+	if err != nil {
+		return err
+	}
+	available := make(map[int]bool, len(parentList))
+	for _, cpu := range parentList {
+		available[cpu] = true
+	}
+	for _, cpu := range list {
+		if !available[cpu] {
+			return fmt.Errorf("CPU %d is not available in parent cgroup %s", cpu, parent)
+		}
 	}
 	return nil
 }
@@ -0,0 +1,59 @@
+package fs2
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/szcdx/runc/libcontainer/cgroups"
+)
+
+func TestSetCpusetPartition(t *testing.T) {
+	// We're using a fake cgroupfs.
+	cgroups.TestMode = true
+
+	testCases := []struct {
+		name            string
+		parentPartition string
+		parentCPUs      string
+		cpus            string
+		isErr           bool
+	}{
+		{name: "valid", parentPartition: "root", parentCPUs: "0-3", cpus: "2-3"},
+		{name: "parent is not a partition root", parentPartition: "member", parentCPUs: "0-3", cpus: "2-3", isErr: true},
+		{name: "CPUs not available", parentPartition: "root", parentCPUs: "0-3", cpus: "3-4", isErr: true},
+		{name: "no CPUs", parentPartition: "root", parentCPUs: "0-3", cpus: "\n", isErr: true},
+	}
+	for _, tc := range testCases {
+		parent := t.TempDir()
+		dir := filepath.Join(parent, "child")
+		if err := os.Mkdir(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		for path, data := range map[string]string{
+			filepath.Join(parent, "cpuset.cpus.partition"): tc.parentPartition + "\n",
+			filepath.Join(parent, "cpuset.cpus.effective"): tc.parentCPUs + "\n",
+			filepath.Join(dir, "cpuset.cpus"):              tc.cpus,
+			filepath.Join(dir, "cpuset.cpus.partition"):    "member\n",
+		} {
+			if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		err := setCpusetPartition(dir, "isolated")
+		if tc.isErr {
+			if err == nil {
+				t.Errorf("%s: expected error, got nil", tc.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if partition, _ := cgroups.ReadFile(dir, "cpuset.cpus.partition"); partition != "isolated" {
+			t.Errorf("%s: expected the partition to be set, got %q", tc.name, partition)
+		}
+	}
+}
@@ -57,6 +57,9 @@ func needAnyControllers(r *configs.Resources) (bool, error) {
 	if isHugeTlbSet(r) && have("hugetlb") {
 		return true, nil
 	}
+	if len(r.Misc) > 0 && have("misc") {
+		return true, nil
+	}
 
 	return false, nil
 }
@@ -69,7 +72,13 @@ func containsDomainController(r *configs.Resources) bool {
 }
 
 // CreateCgroupPath creates cgroupv2 path, enabling all the supported controllers.
-func CreateCgroupPath(path string, c *configs.Cgroup) (Err error) {
+func CreateCgroupPath(path string, c *configs.Cgroup) error {
+	return createCgroupPath(path, c, false)
+}
+
+// createCgroupPath is CreateCgroupPath, failing with cgroups.ErrExists if
+// the cgroup itself already exists and exclusive is set.
+func createCgroupPath(path string, c *configs.Cgroup, exclusive bool) (Err error) {
 	if !strings.HasPrefix(path, UnifiedMountpoint) {
 		return fmt.Errorf("invalid cgroup path %s", path)
 	}
@@ -96,6 +105,9 @@ func CreateCgroupPath(path string, c *configs.Cgroup) (Err error) {
 				if !os.IsExist(err) {
 					return err
 				}
+				if exclusive && i == len(elements)-1 {
+					return fmt.Errorf("%w: %s", cgroups.ErrExists, current)
+				}
 			} else {
 				// If the directory was created, be sure it is not left around on errors.
 				current := current
@@ -24,7 +24,11 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
+	"github.com/moby/sys/mountinfo"
+
+	"github.com/szcdx/runc/libcontainer/cgroups"
 	"github.com/szcdx/runc/libcontainer/configs"
 	"github.com/szcdx/runc/libcontainer/utils"
 )
@@ -36,10 +40,45 @@ func defaultDirPath(c *configs.Cgroup) (string, error) {
 		return "", fmt.Errorf("cgroup: either Path or Name and Parent should be used, got %+v", c)
 	}
 
-	return _defaultDirPath(UnifiedMountpoint, c.Path, c.Parent, c.Name)
+	mntRoot, err := unifiedMountRoot()
+	if err != nil {
+		return "", err
+	}
+	return _defaultDirPath(UnifiedMountpoint, mntRoot, c.Path, c.Parent, c.Name)
+}
+
+var (
+	unifiedMountRootOnce sync.Once
+	unifiedMountRootPath string
+	unifiedMountRootErr  error
+)
+
+// unifiedMountRoot returns the root of the cgroup v2 hierarchy mounted at
+// UnifiedMountpoint, as found in mountinfo (relative to the cgroup namespace
+// root). It is "/" unless runc runs in a container which has the cgroupfs
+// bind-mounted from a cgroup of the host.
+func unifiedMountRoot() (string, error) {
+	unifiedMountRootOnce.Do(func() {
+		mounts, err := mountinfo.GetMounts(func(m *mountinfo.Info) (bool, bool) {
+			return m.FSType != "cgroup2" || m.Mountpoint != UnifiedMountpoint, false
+		})
+		if err != nil {
+			unifiedMountRootErr = err
+			return
+		}
+		unifiedMountRootPath = "/"
+		// If there are a few, the last one is the one visible.
+		if len(mounts) > 0 {
+			unifiedMountRootPath = mounts[len(mounts)-1].Root
+		}
+	})
+	return unifiedMountRootPath, unifiedMountRootErr
 }
 
-func _defaultDirPath(root, cgPath, cgParent, cgName string) (string, error) {
+// _defaultDirPath returns the path of the cgroup under root, the mountpoint
+// of the cgroup v2 hierarchy the root of which is mntRoot (see
+// cgroups.MountPath).
+func _defaultDirPath(root, mntRoot, cgPath, cgParent, cgName string) (string, error) {
 	if (cgName != "" || cgParent != "") && cgPath != "" {
 		return "", errors.New("cgroup: either Path or Name and Parent should be used")
 	}
@@ -52,7 +91,7 @@ func _defaultDirPath(root, cgPath, cgParent, cgName string) (string, error) {
 		innerPath = filepath.Join(cgParent, cgName)
 	}
 	if filepath.IsAbs(innerPath) {
-		return filepath.Join(root, innerPath), nil
+		return cgroups.MountPath(root, mntRoot, innerPath), nil
 	}
 
 	// we don't need to use /proc/thread-self here because runc always runs
@@ -67,7 +106,7 @@ func _defaultDirPath(root, cgPath, cgParent, cgName string) (string, error) {
 	// A parent cgroup (with no tasks in it) is what we need.
 	ownCgroup = filepath.Dir(ownCgroup)
 
-	return filepath.Join(root, ownCgroup, innerPath), nil
+	return cgroups.MountPath(root, mntRoot, filepath.Join(ownCgroup, innerPath)), nil
 }
 
 // parseCgroupFile parses /proc/PID/cgroup file and return string
@@ -47,6 +47,27 @@ func TestParseCgroupFromReader(t *testing.T) {
 	}
 }
 
+func TestDefaultDirPathMountRoot(t *testing.T) {
+	cases := []struct {
+		mntRoot  string
+		cgPath   string
+		expected string
+	}{
+		{mntRoot: "/", cgPath: "/foo/bar", expected: "/sys/fs/cgroup/foo/bar"},
+		{mntRoot: "/docker/abc", cgPath: "/docker/abc/foo", expected: "/sys/fs/cgroup/foo"},
+		{mntRoot: "/docker/abc", cgPath: "/foo/bar", expected: "/sys/fs/cgroup/foo/bar"},
+	}
+	for _, c := range cases {
+		got, err := _defaultDirPath(UnifiedMountpoint, c.mntRoot, c.cgPath, "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != c.expected {
+			t.Errorf("mount root %s, path %s: expected %q, got %q", c.mntRoot, c.cgPath, c.expected, got)
+		}
+	}
+}
+
 func TestDefaultDirPath(t *testing.T) {
 	if !cgroups.IsCgroup2UnifiedMode() {
 		t.Skip("need cgroupv2")
@@ -76,7 +97,7 @@ func TestDefaultDirPath(t *testing.T) {
 		},
 	}
 	for _, c := range cases {
-		got, err := _defaultDirPath(UnifiedMountpoint, c.cgPath, c.cgParent, c.cgName)
+		got, err := _defaultDirPath(UnifiedMountpoint, "/", c.cgPath, c.cgParent, c.cgName)
 		if err != nil {
 			t.Fatal(err)
 		}
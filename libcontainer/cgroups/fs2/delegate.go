@@ -0,0 +1,69 @@
+package fs2
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/szcdx/runc/libcontainer/configs"
+)
+
+// ChownCgroup delegates the cgroup dirPath to the owner set in the cgroup
+// config (c.OwnerUID and c.OwnerGID), if any, the same way systemd does it
+// for the units with Delegate=yes: the directory itself, and the files the
+// kernel lists as safe to delegate, are chowned to the owner, which can then
+// create sub-cgroups, move processes between them, and enable controllers
+// for them. The resource limits of the cgroup itself stay owned by root.
+func ChownCgroup(dirPath string, c *configs.Cgroup) error {
+	if c.OwnerUID == nil {
+		return nil
+	}
+	uid, gid := *c.OwnerUID, -1
+	if c.OwnerGID != nil {
+		gid = *c.OwnerGID
+	}
+	// The directory itself must be chowned.
+	if err := os.Chown(dirPath, uid, gid); err != nil {
+		return err
+	}
+
+	filesToChown, err := cgroupFilesToChown()
+	if err != nil {
+		return err
+	}
+	for _, v := range filesToChown {
+		err := os.Chown(filepath.Join(dirPath, v), uid, gid)
+		// Some files might not be present.
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// The kernel exposes a list of files that should be chowned to the delegate
+// uid in /sys/kernel/cgroup/delegate.  If the file is not present
+// (Linux < 4.15), use the initial values mentioned in cgroups(7).
+func cgroupFilesToChown() ([]string, error) {
+	const cgroupDelegateFile = "/sys/kernel/cgroup/delegate"
+
+	f, err := os.Open(cgroupDelegateFile)
+	if err != nil {
+		return []string{"cgroup.procs", "cgroup.subtree_control", "cgroup.threads"}, nil
+	}
+	defer f.Close()
+
+	filesToChown := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		filesToChown = append(filesToChown, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", cgroupDelegateFile, err)
+	}
+
+	return filesToChown, nil
+}
@@ -0,0 +1,57 @@
+package fs2
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/szcdx/runc/libcontainer/configs"
+)
+
+func TestChownCgroup(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("Test requires root.")
+	}
+	dir := t.TempDir()
+	files, err := cgroupFilesToChown()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Leave the last file out, as some files might not be present.
+	for _, f := range files[:len(files)-1] {
+		if err := os.WriteFile(filepath.Join(dir, f), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "memory.max"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// No owner, nothing to do.
+	if err := ChownCgroup(dir, &configs.Cgroup{}); err != nil {
+		t.Fatal(err)
+	}
+	uid, gid := 100000, 100001
+	if err := ChownCgroup(dir, &configs.Cgroup{OwnerUID: &uid, OwnerGID: &gid}); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(path string, wantUID, wantGID int) {
+		t.Helper()
+		fi, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		st := fi.Sys().(*syscall.Stat_t)
+		if int(st.Uid) != wantUID || int(st.Gid) != wantGID {
+			t.Errorf("%s: expected owner %d:%d, got %d:%d", path, wantUID, wantGID, st.Uid, st.Gid)
+		}
+	}
+	check(dir, uid, gid)
+	for _, f := range files[:len(files)-1] {
+		check(filepath.Join(dir, f), uid, gid)
+	}
+	// The resource limits are not delegated.
+	check(filepath.Join(dir, "memory.max"), 0, 0)
+}
@@ -20,6 +20,9 @@ type Manager struct {
 	// controllers is content of "cgroup.controllers" file.
 	// excludes pseudo-controllers ("devices" and "freezer").
 	controllers map[string]struct{}
+	// foreign is set once an Exclusive cgroup is found to exist, so that
+	// it is not removed by Destroy.
+	foreign bool
 }
 
 // NewManager creates a manager for cgroup v2 unified hierarchy.
@@ -63,7 +66,11 @@ func (m *Manager) getControllers() error {
 }
 
 func (m *Manager) Apply(pid int) error {
-	if err := CreateCgroupPath(m.dirPath, m.config); err != nil {
+	if err := createCgroupPath(m.dirPath, m.config, m.config.Exclusive); err != nil {
+		if errors.Is(err, cgroups.ErrExists) {
+			m.foreign = true
+			return err
+		}
 		// Related tests:
 		// - "runc create (no limits + no cgrouppath + no permission) succeeds"
 		// - "runc create (rootless + no limits + cgrouppath + no permission) fails with permission error"
@@ -78,6 +85,11 @@ func (m *Manager) Apply(pid int) error {
 		}
 		return err
 	}
+	if m.config.Delegate {
+		if err := ChownCgroup(m.dirPath, m.config); err != nil {
+			return err
+		}
+	}
 	if err := cgroups.WriteCgroupProc(m.dirPath, pid); err != nil {
 		return err
 	}
@@ -134,7 +146,7 @@ func (m *Manager) GetStats() (*cgroups.Stats, error) {
 		errs = append(errs, err)
 	}
 	// misc (since kernel 5.13)
-	if err := statMisc(m.dirPath, st); err != nil && !os.IsNotExist(err) {
+	if err := fscommon.MiscGetStats(m.dirPath, st); err != nil && !os.IsNotExist(err) {
 		errs = append(errs, err)
 	}
 	if len(errs) > 0 && !m.config.Rootless {
@@ -155,6 +167,9 @@ func (m *Manager) Freeze(state configs.FreezerState) error {
 }
 
 func (m *Manager) Destroy() error {
+	if m.foreign {
+		return nil
+	}
 	return cgroups.RemovePath(m.dirPath)
 }
 
@@ -169,12 +184,16 @@ func (m *Manager) Set(r *configs.Resources) error {
 	if err := m.getControllers(); err != nil {
 		return err
 	}
+	// The plain writes are collected, and done together, in the same
+	// order as the other ones.
+	tx := cgroups.NewTx(m.dirPath)
 	// pids (since kernel 4.5)
-	if err := setPids(m.dirPath, r); err != nil {
+	setPids(tx, r)
+	// memory (since kernel 4.5)
+	if err := setMemory(m.dirPath, tx, r); err != nil {
 		return err
 	}
-	// memory (since kernel 4.5)
-	if err := setMemory(m.dirPath, r); err != nil {
+	if err := tx.Commit(); err != nil {
 		return err
 	}
 	// io (since kernel 4.5)
@@ -196,9 +215,16 @@ func (m *Manager) Set(r *configs.Resources) error {
 		}
 	}
 	// cpuset (since kernel 5.0)
-	if err := setCpuset(m.dirPath, r); err != nil {
+	setCpuset(tx, r)
+	if err := tx.Commit(); err != nil {
 		return err
 	}
+	// The cpuset partition can only be set once the CPUs are.
+	if r.CpusetPartition != "" {
+		if err := setCpusetPartition(m.dirPath, r.CpusetPartition); err != nil {
+			return err
+		}
+	}
 	// hugetlb (since kernel 5.6)
 	if err := setHugeTlb(m.dirPath, r); err != nil {
 		return err
@@ -207,11 +233,15 @@ func (m *Manager) Set(r *configs.Resources) error {
 	if err := fscommon.RdmaSet(m.dirPath, r); err != nil {
 		return err
 	}
+	// misc (since kernel 5.13)
+	if err := fscommon.MiscSet(m.dirPath, r); err != nil {
+		return err
+	}
 	// freezer (since kernel 5.2, pseudo-controller)
 	if err := setFreezer(m.dirPath, r.Freezer); err != nil {
 		return err
 	}
-	if err := m.setUnified(r.Unified); err != nil {
+	if err := m.setUnified(r); err != nil {
 		return err
 	}
 	m.config.Resources = r
@@ -228,26 +258,25 @@ func setDevices(dirPath string, r *configs.Resources) error {
 	return cgroups.DevicesSetV2(dirPath, r)
 }
 
-func (m *Manager) setUnified(res map[string]string) error {
-	for k, v := range res {
-		if strings.Contains(k, "/") {
-			return fmt.Errorf("unified resource %q must be a file name (no slashes)", k)
-		}
-		if err := cgroups.WriteFile(m.dirPath, k, v); err != nil {
-			// Check for both EPERM and ENOENT since O_CREAT is used by WriteFile.
-			if errors.Is(err, os.ErrPermission) || errors.Is(err, os.ErrNotExist) {
-				// Check if a controller is available,
-				// to give more specific error if not.
-				sk := strings.SplitN(k, ".", 2)
-				if len(sk) != 2 {
-					return fmt.Errorf("unified resource %q must be in the form CONTROLLER.PARAMETER", k)
-				}
-				c := sk[0]
-				if _, ok := m.controllers[c]; !ok && c != "cgroup" {
-					return fmt.Errorf("unified resource %q can't be set: controller %q not available", k, c)
+func (m *Manager) setUnified(r *configs.Resources) error {
+	res, err := cgroups.ParseUnified(r.Unified, r.UnifiedAllowUnknown)
+	if err != nil {
+		return err
+	}
+	for _, u := range res {
+		for _, v := range u.Values {
+			if err := cgroups.WriteFile(m.dirPath, u.Name, v); err != nil {
+				// Check for both EPERM and ENOENT since O_CREAT is used by WriteFile.
+				if errors.Is(err, os.ErrPermission) || errors.Is(err, os.ErrNotExist) {
+					// Check if a controller is available,
+					// to give more specific error if not.
+					c := strings.SplitN(u.Name, ".", 2)[0]
+					if _, ok := m.controllers[c]; !ok && c != "cgroup" {
+						return fmt.Errorf("unified resource %q can't be set: controller %q not available", u.Name, c)
+					}
 				}
+				return fmt.Errorf("unable to set unified resource %q: %w", u.Name, err)
 			}
-			return fmt.Errorf("unable to set unified resource %q: %w", k, err)
 		}
 	}
 
@@ -2,6 +2,7 @@ package fs2
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"strconv"
 
@@ -19,18 +20,25 @@ func setHugeTlb(dirPath string, r *configs.Resources) error {
 		return nil
 	}
 	const suffix = ".max"
+	fault := r.HugetlbAccounting != "reservation"
+	rsvd := r.HugetlbAccounting != "fault"
 	skipRsvd := false
 	for _, hugetlb := range r.HugetlbLimit {
 		prefix := "hugetlb." + hugetlb.Pagesize
 		val := strconv.FormatUint(hugetlb.Limit, 10)
-		if err := cgroups.WriteFile(dirPath, prefix+suffix, val); err != nil {
-			return err
+		if fault {
+			if err := cgroups.WriteFile(dirPath, prefix+suffix, val); err != nil {
+				return err
+			}
 		}
-		if skipRsvd {
+		if !rsvd || skipRsvd {
 			continue
 		}
 		if err := cgroups.WriteFile(dirPath, prefix+".rsvd"+suffix, val); err != nil {
 			if errors.Is(err, os.ErrNotExist) {
+				if !fault {
+					return fmt.Errorf("hugetlb reservation accounting is not supported: %w", err)
+				}
 				skipRsvd = true
 				continue
 			}
@@ -42,27 +50,32 @@ func setHugeTlb(dirPath string, r *configs.Resources) error {
 }
 
 func statHugeTlb(dirPath string, stats *cgroups.Stats) error {
-	hugetlbStats := cgroups.HugetlbStats{}
-	rsvd := ".rsvd"
 	for _, pagesize := range cgroups.HugePageSizes() {
-	again:
-		prefix := "hugetlb." + pagesize + rsvd
+		prefix := "hugetlb." + pagesize
+		hugetlbStats := cgroups.HugetlbStats{}
 		value, err := fscommon.GetCgroupParamUint(dirPath, prefix+".current")
 		if err != nil {
-			if rsvd != "" && errors.Is(err, os.ErrNotExist) {
-				rsvd = ""
-				goto again
-			}
 			return err
 		}
 		hugetlbStats.Usage = value
 
+		// There are no reservation based events.
 		value, err = fscommon.GetValueByKey(dirPath, prefix+".events", "max")
 		if err != nil {
 			return err
 		}
 		hugetlbStats.Failcnt = value
 
+		// The reservation based usage is preferred, and the page fault
+		// based counters are then reported separately.
+		value, err = fscommon.GetCgroupParamUint(dirPath, prefix+".rsvd.current")
+		if err == nil {
+			fault := hugetlbStats
+			hugetlbStats = cgroups.HugetlbStats{Usage: value, Fault: &fault}
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+
 		stats.HugetlbStats[pagesize] = hugetlbStats
 	}
 
@@ -3,14 +3,17 @@ package fs2
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 
 	"github.com/sirupsen/logrus"
 
 	"github.com/szcdx/runc/libcontainer/cgroups"
+	"github.com/szcdx/runc/libcontainer/cgroups/fscommon"
 	"github.com/szcdx/runc/libcontainer/configs"
 )
 
@@ -20,7 +23,8 @@ func isIoSet(r *configs.Resources) bool {
 		len(r.BlkioThrottleReadBpsDevice) > 0 ||
 		len(r.BlkioThrottleWriteBpsDevice) > 0 ||
 		len(r.BlkioThrottleReadIOPSDevice) > 0 ||
-		len(r.BlkioThrottleWriteIOPSDevice) > 0
+		len(r.BlkioThrottleWriteIOPSDevice) > 0 ||
+		len(r.BlkioLatencyDevice) > 0
 }
 
 // bfqDeviceWeightSupported checks for per-device BFQ weight support (added
@@ -67,37 +71,80 @@ func setIo(dirPath string, r *configs.Resources) error {
 			}
 		}
 	}
-	if bfqDeviceWeightSupported(bfq) {
-		for _, wd := range r.BlkioWeightDevice {
+	bfqDevice := bfqDeviceWeightSupported(bfq)
+	for _, wd := range r.BlkioWeightDevice {
+		// The leaf weight has no cgroup v2 equivalent.
+		if wd.Weight == 0 {
+			continue
+		}
+		if bfqDevice {
 			if _, err := bfq.WriteString(wd.WeightString() + "\n"); err != nil {
 				return fmt.Errorf("setting device weight %q: %w", wd.WeightString(), err)
 			}
+			continue
 		}
-	}
-	for _, td := range r.BlkioThrottleReadBpsDevice {
-		if err := cgroups.WriteFile(dirPath, "io.max", td.StringName("rbps")); err != nil {
+		// Fallback to io.weight (only present if the io.cost controller
+		// is enabled), with a conversion scheme.
+		v := fmt.Sprintf("%d:%d %d", wd.Major, wd.Minor, cgroups.ConvertBlkIOToIOWeightValue(wd.Weight))
+		if err := cgroups.WriteFile(dirPath, "io.weight", v); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				logrus.Warnf("unable to set device weight %q: neither BFQ nor io.cost is available", wd.WeightString())
+				continue
+			}
 			return err
 		}
 	}
-	for _, td := range r.BlkioThrottleWriteBpsDevice {
-		if err := cgroups.WriteFile(dirPath, "io.max", td.StringName("wbps")); err != nil {
-			return err
+	for _, t := range []struct {
+		name    string
+		devices []*configs.ThrottleDevice
+	}{
+		{"rbps", r.BlkioThrottleReadBpsDevice},
+		{"wbps", r.BlkioThrottleWriteBpsDevice},
+		{"riops", r.BlkioThrottleReadIOPSDevice},
+		{"wiops", r.BlkioThrottleWriteIOPSDevice},
+	} {
+		for _, td := range t.devices {
+			if err := cgroups.WriteFile(dirPath, "io.max", ioMaxString(td, t.name)); err != nil {
+				return err
+			}
 		}
 	}
-	for _, td := range r.BlkioThrottleReadIOPSDevice {
-		if err := cgroups.WriteFile(dirPath, "io.max", td.StringName("riops")); err != nil {
+	if len(r.BlkioLatencyDevice) > 0 {
+		if err := setIoLatency(dirPath, r.BlkioLatencyDevice); err != nil {
 			return err
 		}
 	}
-	for _, td := range r.BlkioThrottleWriteIOPSDevice {
-		if err := cgroups.WriteFile(dirPath, "io.max", td.StringName("wiops")); err != nil {
+
+	return nil
+}
+
+// setIoLatency sets the IO latency targets of the cgroup. There is no
+// io.latency in the root cgroup, as the targets are only enforced among
+// siblings.
+func setIoLatency(dirPath string, devices []*configs.LatencyDevice) error {
+	if filepath.Clean(dirPath) == UnifiedMountpoint {
+		return errors.New("unable to set io latency targets: not supported for the root cgroup")
+	}
+	for _, ld := range devices {
+		if err := cgroups.WriteFile(dirPath, "io.latency", ld.String()); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("unable to set io latency target %q: io.latency is not supported by the kernel: %w", ld.String(), err)
+			}
 			return err
 		}
 	}
-
 	return nil
 }
 
+// ioMaxString formats the throttle to be written to io.max. Like on cgroup
+// v1, a zero rate removes the limit.
+func ioMaxString(td *configs.ThrottleDevice, name string) string {
+	if td.Rate == 0 {
+		return fmt.Sprintf("%d:%d %s=max", td.Major, td.Minor, name)
+	}
+	return td.StringName(name)
+}
+
 func readCgroup2MapFile(dirPath string, name string) (map[string][]string, error) {
 	ret := map[string][]string{}
 	f, err := cgroups.OpenFile(dirPath, name, os.O_RDONLY)
@@ -188,6 +235,74 @@ func statIo(dirPath string, stats *cgroups.Stats) error {
 			*targetTable = append(*targetTable, entry)
 		}
 	}
+	if err := statIoLimits(dirPath, &parsedStats); err != nil {
+		return err
+	}
 	stats.BlkioStats = parsedStats
 	return nil
 }
+
+// statIoLimits reads back the configured weights and throttles, which are
+// reported in the cgroup v1 terms. The files which are not present (such
+// as io.max in the root cgroup, or io.weight without io.cost) are skipped.
+func statIoLimits(dirPath string, stats *cgroups.BlkioStats) error {
+	weight, devices, err := fscommon.GetBlkioWeights(dirPath, "io.bfq.weight")
+	if errors.Is(err, os.ErrNotExist) {
+		weight, devices, err = fscommon.GetBlkioWeights(dirPath, "io.weight")
+		for i := range devices {
+			devices[i].Value = uint64(cgroups.ConvertIOWeightToBlkIOValue(devices[i].Value))
+		}
+		weight = uint64(cgroups.ConvertIOWeightToBlkIOValue(weight))
+	}
+	switch {
+	case err == nil:
+		stats.Weight, stats.WeightDevice = weight, devices
+	case !errors.Is(err, os.ErrNotExist):
+		return err
+	}
+
+	const file = "io.max"
+	values, err := readCgroup2MapFile(dirPath, file)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	for dev, limits := range values {
+		d := strings.Split(dev, ":")
+		if len(d) != 2 {
+			continue
+		}
+		major, err := strconv.ParseUint(d[0], 10, 64)
+		if err != nil {
+			return &parseError{Path: dirPath, File: file, Err: err}
+		}
+		minor, err := strconv.ParseUint(d[1], 10, 64)
+		if err != nil {
+			return &parseError{Path: dirPath, File: file, Err: err}
+		}
+		for _, limit := range limits {
+			op, v, ok := strings.Cut(limit, "=")
+			if !ok || v == "max" {
+				continue
+			}
+			switch op {
+			case "rbps", "wbps", "riops", "wiops":
+			default:
+				continue
+			}
+			value, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				return &parseError{Path: dirPath, File: file, Err: err}
+			}
+			stats.ThrottleDevice = append(stats.ThrottleDevice, cgroups.BlkioStatEntry{
+				Major: major,
+				Minor: minor,
+				Op:    op,
+				Value: value,
+			})
+		}
+	}
+	return nil
+}
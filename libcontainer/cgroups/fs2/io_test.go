@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/szcdx/runc/libcontainer/cgroups"
+	"github.com/szcdx/runc/libcontainer/configs"
 )
 
 const exampleIoStatData = `254:1 rbytes=6901432320 wbytes=14245535744 rios=263278 wios=248603 dbytes=0 dios=0
@@ -79,3 +80,94 @@ func TestStatIo(t *testing.T) {
 		t.Errorf("parsed cgroupv2 io.stat doesn't match expected result: \ngot %#v\nexpected %#v\n", gotStats.BlkioStats, exampleIoStatsParsed)
 	}
 }
+
+func TestStatIoLimits(t *testing.T) {
+	// We're using a fake cgroupfs.
+	cgroups.TestMode = true
+	fakeCgroupDir := t.TempDir()
+	for file, data := range map[string]string{
+		"io.stat":   "",
+		"io.max":    "8:0 rbps=1048576 wbps=max riops=max wiops=100\n8:16 rbps=max wbps=max riops=max wiops=max\n",
+		"io.weight": "default 100\n8:0 10000\n",
+	} {
+		if err := os.WriteFile(filepath.Join(fakeCgroupDir, file), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var gotStats cgroups.Stats
+	if err := statIo(fakeCgroupDir, &gotStats); err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(gotStats.BlkioStats.ThrottleDevice, func(i, j int) bool {
+		return lessBlkioStatEntry(gotStats.BlkioStats.ThrottleDevice[i], gotStats.BlkioStats.ThrottleDevice[j])
+	})
+	expected := cgroups.BlkioStats{
+		// The io.weight values are converted to the blkio weight range.
+		Weight: 20,
+		WeightDevice: []cgroups.BlkioStatEntry{
+			{Major: 8, Minor: 0, Value: 1000},
+		},
+		ThrottleDevice: []cgroups.BlkioStatEntry{
+			{Major: 8, Minor: 0, Op: "rbps", Value: 1048576},
+			{Major: 8, Minor: 0, Op: "wiops", Value: 100},
+		},
+	}
+	if !reflect.DeepEqual(gotStats.BlkioStats, expected) {
+		t.Errorf("unexpected io limits: \ngot %#v\nexpected %#v\n", gotStats.BlkioStats, expected)
+	}
+}
+
+func TestSetIoUnlimited(t *testing.T) {
+	// We're using a fake cgroupfs.
+	cgroups.TestMode = true
+	fakeCgroupDir := t.TempDir()
+
+	r := &configs.Resources{
+		BlkioThrottleReadBpsDevice: []*configs.ThrottleDevice{configs.NewThrottleDevice(8, 0, 0)},
+	}
+	if err := setIo(fakeCgroupDir, r); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(fakeCgroupDir, "io.max"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Like on cgroup v1, a zero rate removes the limit.
+	if string(data) != "8:0 rbps=max" {
+		t.Errorf("expected io.max to be %q, got %q", "8:0 rbps=max", data)
+	}
+}
+
+func TestSetIoLatency(t *testing.T) {
+	// We're using a fake cgroupfs.
+	cgroups.TestMode = true
+	fakeCgroupDir := t.TempDir()
+
+	r := &configs.Resources{
+		BlkioLatencyDevice: []*configs.LatencyDevice{configs.NewLatencyDevice(8, 0, 0)},
+	}
+	if err := setIo(fakeCgroupDir, r); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(fakeCgroupDir, "io.latency"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A zero target removes it.
+	if string(data) != "8:0 target=max" {
+		t.Errorf("expected io.latency to be %q, got %q", "8:0 target=max", data)
+	}
+
+	r.BlkioLatencyDevice[0].Target = 5000
+	if err := setIo(fakeCgroupDir, r); err != nil {
+		t.Fatal(err)
+	}
+	data, err = os.ReadFile(filepath.Join(fakeCgroupDir, "io.latency"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "8:0 target=5000" {
+		t.Errorf("expected io.latency to be %q, got %q", "8:0 target=5000", data)
+	}
+}
@@ -33,10 +33,11 @@ func numToStr(value int64) (ret string) {
 }
 
 func isMemorySet(r *configs.Resources) bool {
-	return r.MemoryReservation != 0 || r.Memory != 0 || r.MemorySwap != 0
+	return r.MemoryReservation != 0 || r.Memory != 0 || r.MemorySwap != 0 ||
+		r.MemoryZswapMax != nil || r.MemoryZswapWriteback != nil
 }
 
-func setMemory(dirPath string, r *configs.Resources) error {
+func setMemory(dirPath string, tx *cgroups.Tx, r *configs.Resources) error {
 	if !isMemorySet(r) {
 		return nil
 	}
@@ -56,23 +57,34 @@ func setMemory(dirPath string, r *configs.Resources) error {
 	}
 	// never write empty string to `memory.swap.max`, it means set to 0.
 	if swapStr != "" {
-		if err := cgroups.WriteFile(dirPath, "memory.swap.max", swapStr); err != nil {
-			return err
-		}
+		tx.Write("memory.swap.max", swapStr)
 	}
 
 	if val := numToStr(r.Memory); val != "" {
-		if err := cgroups.WriteFile(dirPath, "memory.max", val); err != nil {
-			return err
-		}
+		tx.Write("memory.max", val)
 	}
 
 	// cgroup.Resources.KernelMemory is ignored
 
 	if val := numToStr(r.MemoryReservation); val != "" {
-		if err := cgroups.WriteFile(dirPath, "memory.low", val); err != nil {
-			return err
+		tx.Write("memory.low", val)
+	}
+
+	// memory.zswap.max since kernel 5.19.
+	if r.MemoryZswapMax != nil {
+		val := "0"
+		if *r.MemoryZswapMax != 0 {
+			val = numToStr(*r.MemoryZswapMax)
 		}
+		tx.Write("memory.zswap.max", val)
+	}
+	// memory.zswap.writeback since kernel 6.8.
+	if r.MemoryZswapWriteback != nil {
+		val := "0"
+		if *r.MemoryZswapWriteback {
+			val = "1"
+		}
+		tx.Write("memory.zswap.writeback", val)
 	}
 
 	return nil
@@ -130,10 +142,61 @@ func statMemory(dirPath string, stats *cgroups.Stats) error {
 	// swap. So set it to 0 for v1 compatibility.
 	swapUsage.MaxUsage = 0
 	stats.MemoryStats.SwapUsage = swapUsage
+	// memory.zswap.current since kernel 5.19.
+	if stats.MemoryStats.ZswapUsage, err = getMemoryDataV2(dirPath, "zswap"); err != nil {
+		return err
+	}
+
+	if stats.MemoryStats.Events, err = statMemoryEvents(dirPath, "memory.events"); err != nil {
+		return err
+	}
+	// memory.events.local since kernel 5.2.
+	if stats.MemoryStats.EventsLocal, err = statMemoryEvents(dirPath, "memory.events.local"); err != nil {
+		return err
+	}
 
 	return nil
 }
 
+// statMemoryEvents reads the memory events counters from file (either
+// memory.events or memory.events.local). It returns nil if the file
+// does not exist.
+func statMemoryEvents(dirPath, file string) (*cgroups.MemoryEvents, error) {
+	f, err := cgroups.OpenFile(dirPath, file, os.O_RDONLY)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	events := &cgroups.MemoryEvents{}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		k, v, err := fscommon.ParseKeyValue(sc.Text())
+		if err != nil {
+			return nil, &parseError{Path: dirPath, File: file, Err: err}
+		}
+		switch k {
+		case "low":
+			events.Low = v
+		case "high":
+			events.High = v
+		case "max":
+			events.Max = v
+		case "oom":
+			events.OOM = v
+		case "oom_kill":
+			events.OOMKill = v
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, &parseError{Path: dirPath, File: file, Err: err}
+	}
+	return events, nil
+}
+
 func getMemoryDataV2(path, name string) (cgroups.MemoryData, error) {
 	memoryData := cgroups.MemoryData{}
 
@@ -1,12 +1,14 @@
 package fs2
 
 import (
+	"math"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/szcdx/runc/libcontainer/cgroups"
+	"github.com/szcdx/runc/libcontainer/configs"
 )
 
 const exampleMemoryStatData = `anon 790425600
@@ -153,3 +155,104 @@ func TestRootStatsFromMeminfo(t *testing.T) {
 		t.Errorf("swap limit %d should be at least mem limit %d", stats.MemoryStats.SwapUsage.Limit, stats.MemoryStats.Usage.Limit)
 	}
 }
+
+func TestStatMemoryZswap(t *testing.T) {
+	// We're using a fake cgroupfs.
+	cgroups.TestMode = true
+	fakeCgroupDir := t.TempDir()
+
+	for file, data := range map[string]string{
+		"memory.stat":          exampleMemoryStatData,
+		"memory.current":       "123456789",
+		"memory.max":           "999999999",
+		"memory.zswap.current": "1048576",
+		"memory.zswap.max":     "max",
+	} {
+		if err := os.WriteFile(filepath.Join(fakeCgroupDir, file), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	gotStats := cgroups.NewStats()
+	if err := statMemory(fakeCgroupDir, gotStats); err != nil {
+		t.Fatal(err)
+	}
+	expected := cgroups.MemoryData{Usage: 1048576, Limit: math.MaxUint64}
+	if gotStats.MemoryStats.ZswapUsage != expected {
+		t.Errorf("expected zswap usage %+v, got %+v", expected, gotStats.MemoryStats.ZswapUsage)
+	}
+}
+
+func TestSetMemoryZswap(t *testing.T) {
+	// We're using a fake cgroupfs.
+	cgroups.TestMode = true
+	fakeCgroupDir := t.TempDir()
+
+	zswapMax, writeback := int64(0), false
+	tx := cgroups.NewTx(fakeCgroupDir)
+	r := &configs.Resources{MemoryZswapMax: &zswapMax, MemoryZswapWriteback: &writeback}
+	if err := setMemory(fakeCgroupDir, tx, r); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	for file, expected := range map[string]string{
+		"memory.zswap.max":       "0",
+		"memory.zswap.writeback": "0",
+	} {
+		got, err := cgroups.ReadFile(fakeCgroupDir, file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != expected {
+			t.Errorf("%s: expected %q, got %q", file, expected, got)
+		}
+	}
+	// No other memory limits are set.
+	if _, err := os.Stat(filepath.Join(fakeCgroupDir, "memory.max")); !os.IsNotExist(err) {
+		t.Errorf("expected memory.max to not be written, got %v", err)
+	}
+
+	zswapMax, writeback = -1, true
+	if err := setMemory(fakeCgroupDir, tx, r); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := cgroups.ReadFile(fakeCgroupDir, "memory.zswap.max"); got != "max" {
+		t.Errorf("memory.zswap.max: expected max, got %q", got)
+	}
+	if got, _ := cgroups.ReadFile(fakeCgroupDir, "memory.zswap.writeback"); got != "1" {
+		t.Errorf("memory.zswap.writeback: expected 1, got %q", got)
+	}
+}
+
+func TestStatMemoryEvents(t *testing.T) {
+	// We're using a fake cgroupfs.
+	cgroups.TestMode = true
+	fakeCgroupDir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(fakeCgroupDir, "memory.events"), []byte("low 1\nhigh 2\nmax 3\noom 4\noom_kill 5\noom_group_kill 0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := statMemoryEvents(fakeCgroupDir, "memory.events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := cgroups.MemoryEvents{Low: 1, High: 2, Max: 3, OOM: 4, OOMKill: 5}
+	if events == nil || *events != expected {
+		t.Errorf("expected %+v, got %+v", expected, events)
+	}
+
+	// memory.events.local is not available before kernel 5.2.
+	events, err = statMemoryEvents(fakeCgroupDir, "memory.events.local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if events != nil {
+		t.Errorf("expected nil, got %+v", events)
+	}
+}
@@ -17,17 +17,13 @@ func isPidsSet(r *configs.Resources) bool {
 	return r.PidsLimit != 0
 }
 
-func setPids(dirPath string, r *configs.Resources) error {
+func setPids(tx *cgroups.Tx, r *configs.Resources) {
 	if !isPidsSet(r) {
-		return nil
+		return
 	}
 	if val := numToStr(r.PidsLimit); val != "" {
-		if err := cgroups.WriteFile(dirPath, "pids.max", val); err != nil {
-			return err
-		}
+		tx.Write("pids.max", val)
 	}
-
-	return nil
 }
 
 func statPidsFromCgroupProcs(dirPath string, stats *cgroups.Stats) error {
@@ -66,7 +62,13 @@ func statPids(dirPath string, stats *cgroups.Stats) error {
 		max = 0
 	}
 
+	hits, err := fscommon.GetValueByKey(dirPath, "pids.events", "max")
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
 	stats.PidsStats.Current = current
 	stats.PidsStats.Limit = max
+	stats.PidsStats.LimitHits = hits
 	return nil
 }
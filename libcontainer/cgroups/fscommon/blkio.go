@@ -0,0 +1,76 @@
+package fscommon
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/szcdx/runc/libcontainer/cgroups"
+)
+
+// GetBlkioWeights reads a block IO weight file, such as blkio.weight or
+// blkio.weight_device on cgroup v1, or io.weight or io.bfq.weight on
+// cgroup v2, and returns the default weight and the per-device weights
+// it contains. The file is either a single number (the default weight),
+// or has a "default <weight>" line, followed by "<major>:<minor> <weight>"
+// lines. The weights are returned as is, without any conversion.
+func GetBlkioWeights(dir, file string) (uint64, []cgroups.BlkioStatEntry, error) {
+	f, err := cgroups.OpenFile(dir, file, os.O_RDONLY)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer f.Close()
+
+	var (
+		weight  uint64
+		devices []cgroups.BlkioStatEntry
+	)
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		switch {
+		case len(fields) == 0:
+			continue
+		case len(fields) == 1:
+			fields = []string{"default", fields[0]}
+		case len(fields) != 2:
+			return 0, nil, &ParseError{Path: dir, File: file, Err: errors.New("malformed line: " + sc.Text())}
+		}
+		v, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, nil, &ParseError{Path: dir, File: file, Err: err}
+		}
+		if fields[0] == "default" {
+			weight = v
+			continue
+		}
+		major, minor, err := parseDevice(fields[0])
+		if err != nil {
+			return 0, nil, &ParseError{Path: dir, File: file, Err: err}
+		}
+		devices = append(devices, cgroups.BlkioStatEntry{Major: major, Minor: minor, Value: v})
+	}
+	if err := sc.Err(); err != nil {
+		return 0, nil, &ParseError{Path: dir, File: file, Err: err}
+	}
+	return weight, devices, nil
+}
+
+// parseDevice parses a block device number in the "<major>:<minor>" format.
+func parseDevice(dev string) (major, minor uint64, _ error) {
+	maj, min, ok := strings.Cut(dev, ":")
+	if !ok {
+		return 0, 0, errors.New("invalid device: " + dev)
+	}
+	major, err := strconv.ParseUint(maj, 10, 32)
+	if err != nil {
+		return 0, 0, err
+	}
+	minor, err = strconv.ParseUint(min, 10, 32)
+	if err != nil {
+		return 0, 0, err
+	}
+	return major, minor, nil
+}
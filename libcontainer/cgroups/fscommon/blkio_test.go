@@ -0,0 +1,62 @@
+package fscommon
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/szcdx/runc/libcontainer/cgroups"
+)
+
+func TestGetBlkioWeights(t *testing.T) {
+	// We're using a fake cgroupfs.
+	cgroups.TestMode = true
+	dir := t.TempDir()
+
+	testCases := []struct {
+		data    string
+		weight  uint64
+		devices []cgroups.BlkioStatEntry
+		isErr   bool
+	}{
+		{data: "500\n", weight: 500},
+		{data: "default 100\n", weight: 100},
+		{
+			data:   "default 100\n8:0 200\n259:1 300\n",
+			weight: 100,
+			devices: []cgroups.BlkioStatEntry{
+				{Major: 8, Minor: 0, Value: 200},
+				{Major: 259, Minor: 1, Value: 300},
+			},
+		},
+		{
+			data: "8:0 200\n",
+			devices: []cgroups.BlkioStatEntry{
+				{Major: 8, Minor: 0, Value: 200},
+			},
+		},
+		{data: "8 200\n", isErr: true},
+		{data: "8:0 200 300\n", isErr: true},
+		{data: "default max\n", isErr: true},
+	}
+	for _, tc := range testCases {
+		if err := os.WriteFile(filepath.Join(dir, "io.weight"), []byte(tc.data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		weight, devices, err := GetBlkioWeights(dir, "io.weight")
+		if tc.isErr {
+			if err == nil {
+				t.Errorf("%q: expected error, got nil", tc.data)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.data, err)
+			continue
+		}
+		if weight != tc.weight || !reflect.DeepEqual(devices, tc.devices) {
+			t.Errorf("%q: expected %d %+v, got %d %+v", tc.data, tc.weight, tc.devices, weight, devices)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package fscommon
+
+import (
+	"bufio"
+	"errors"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/szcdx/runc/libcontainer/cgroups"
+	"github.com/szcdx/runc/libcontainer/configs"
+)
+
+// MiscGetStats reads the misc controller stats. The files are the same for
+// cgroup v1 and v2.
+func MiscGetStats(dirPath string, stats *cgroups.Stats) error {
+	for _, file := range []string{"current", "events"} {
+		fd, err := cgroups.OpenFile(dirPath, "misc."+file, os.O_RDONLY)
+		if err != nil {
+			return err
+		}
+
+		s := bufio.NewScanner(fd)
+		for s.Scan() {
+			key, value, err := ParseKeyValue(s.Text())
+			if err != nil {
+				fd.Close()
+				return err
+			}
+
+			key = strings.TrimSuffix(key, ".max")
+
+			if _, ok := stats.MiscStats[key]; !ok {
+				stats.MiscStats[key] = cgroups.MiscStats{}
+			}
+
+			tmp := stats.MiscStats[key]
+
+			switch file {
+			case "current":
+				tmp.Usage = value
+			case "events":
+				tmp.Events = value
+			}
+
+			stats.MiscStats[key] = tmp
+		}
+		fd.Close()
+
+		if err := s.Err(); err != nil {
+			return err
+		}
+	}
+
+	return miscGetLimits(dirPath, stats)
+}
+
+// miscGetLimits reads misc.max, which is absent in the root cgroup.
+func miscGetLimits(dirPath string, stats *cgroups.Stats) error {
+	fd, err := cgroups.OpenFile(dirPath, "misc.max", os.O_RDONLY)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer fd.Close()
+
+	s := bufio.NewScanner(fd)
+	for s.Scan() {
+		// Format: <resource> <limit>, where limit is either "max" or a number.
+		key, value, ok := strings.Cut(s.Text(), " ")
+		if !ok {
+			return &ParseError{Path: dirPath, File: "misc.max", Err: errors.New("invalid format")}
+		}
+		limit := uint64(math.MaxUint64)
+		if value != "max" {
+			limit, err = strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return &ParseError{Path: dirPath, File: "misc.max", Err: err}
+			}
+		}
+		tmp := stats.MiscStats[key]
+		tmp.Limit = limit
+		stats.MiscStats[key] = tmp
+	}
+	return s.Err()
+}
+
+// MiscSet sets the misc controller limits.
+func MiscSet(dirPath string, r *configs.Resources) error {
+	for key, limit := range r.Misc {
+		value := "max"
+		if limit >= 0 {
+			value = strconv.FormatInt(limit, 10)
+		}
+		if err := cgroups.WriteFile(dirPath, "misc.max", key+" "+value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,12 +1,14 @@
-package fs2
+package fscommon
 
 import (
+	"math"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/szcdx/runc/libcontainer/cgroups"
+	"github.com/szcdx/runc/libcontainer/configs"
 )
 
 const exampleMiscCurrentData = `res_a 123
@@ -32,7 +34,7 @@ func TestStatMiscPodCgroupEmpty(t *testing.T) {
 
 	gotStats := cgroups.NewStats()
 
-	err := statMisc(fakeCgroupDir, gotStats)
+	err := MiscGetStats(fakeCgroupDir, gotStats)
 	if err != nil {
 		t.Errorf("expected no error when statting empty misc.current/misc.events for cgroupv2, but got %#v", err)
 	}
@@ -58,7 +60,7 @@ func TestStatMiscPodCgroupNotFound(t *testing.T) {
 
 	// use a fake root path to mismatch the file we wrote.
 	// this triggers the non-root path which should fail to find misc.events.
-	err := statMisc(fakeCgroupDir, gotStats)
+	err := MiscGetStats(fakeCgroupDir, gotStats)
 	if err == nil {
 		t.Errorf("expected error when statting misc.current for cgroupv2 root, but was nil")
 	}
@@ -86,7 +88,7 @@ func TestStatMiscPodCgroup(t *testing.T) {
 	gotStats := cgroups.NewStats()
 
 	// use a fake root path to trigger the pod cgroup lookup.
-	err := statMisc(fakeCgroupDir, gotStats)
+	err := MiscGetStats(fakeCgroupDir, gotStats)
 	if err != nil {
 		t.Errorf("expected no error when statting misc for cgroupv2 root, but got %#+v", err)
 	}
@@ -101,3 +103,61 @@ func TestStatMiscPodCgroup(t *testing.T) {
 		t.Errorf("parsed cgroupv2 misc.current for res_c doesn't match expected result: \ngot %#v\nexpected %#v\n", gotStats.MiscStats["res_c"].Usage, expectedUsageBytes)
 	}
 }
+
+func TestStatMiscLimits(t *testing.T) {
+	// We're using a fake cgroupfs.
+	cgroups.TestMode = true
+	fakeCgroupDir := t.TempDir()
+
+	for file, data := range map[string]string{
+		"misc.current": exampleMiscCurrentData,
+		"misc.events":  exampleMiscEventsData,
+		"misc.max":     "res_a max\nres_b 16\nres_c 0",
+	} {
+		if err := os.WriteFile(filepath.Join(fakeCgroupDir, file), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	gotStats := cgroups.NewStats()
+	if err := MiscGetStats(fakeCgroupDir, gotStats); err != nil {
+		t.Fatal(err)
+	}
+
+	for key, exp := range map[string]cgroups.MiscStats{
+		"res_a": {Usage: 123, Events: 1, Limit: math.MaxUint64},
+		"res_b": {Usage: 456, Events: 2, Limit: 16},
+		"res_c": {Usage: 42, Events: 3, Limit: 0},
+	} {
+		if got := gotStats.MiscStats[key]; got != exp {
+			t.Errorf("%s: expected %+v, got %+v", key, exp, got)
+		}
+	}
+}
+
+func TestMiscSet(t *testing.T) {
+	// We're using a fake cgroupfs.
+	cgroups.TestMode = true
+	fakeCgroupDir := t.TempDir()
+
+	for key, tc := range map[string]struct {
+		limit int64
+		exp   string
+	}{
+		"sev":    {limit: 4, exp: "sev 4"},
+		"sev_es": {limit: -1, exp: "sev_es max"},
+	} {
+		r := &configs.Resources{Misc: map[string]int64{key: tc.limit}}
+		if err := MiscSet(fakeCgroupDir, r); err != nil {
+			t.Fatal(err)
+		}
+		// The fake cgroupfs file is just overwritten by every write.
+		got, err := os.ReadFile(filepath.Join(fakeCgroupDir, "misc.max"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tc.exp {
+			t.Errorf("expected %q, got %q", tc.exp, got)
+		}
+	}
+}
@@ -1,10 +1,13 @@
 package fscommon
 
 import (
+	"math"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 
+	"github.com/szcdx/runc/libcontainer/cgroups"
 	"github.com/szcdx/runc/libcontainer/configs"
 )
 
@@ -55,3 +58,33 @@ func TestRdmaSet(t *testing.T) {
 		t.Fatalf("rdma_test: Got the wrong value for hca_Objects")
 	}
 }
+
+func TestRdmaGetStats(t *testing.T) {
+	dir := t.TempDir()
+	for file, data := range map[string]string{
+		"rdma.max":     "mlx5_1 hca_handle=100 hca_object=max\nmlx4_0 hca_handle=max hca_object=max\n",
+		"rdma.current": "mlx5_1 hca_handle=3 hca_object=42\nmlx4_0 hca_handle=0 hca_object=0\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var stats cgroups.Stats
+	if err := RdmaGetStats(dir, &stats); err != nil {
+		t.Fatal(err)
+	}
+	expected := cgroups.RdmaStats{
+		RdmaLimit: []cgroups.RdmaEntry{
+			{Device: "mlx5_1", HcaHandles: 100, HcaObjects: math.MaxUint32},
+			{Device: "mlx4_0", HcaHandles: math.MaxUint32, HcaObjects: math.MaxUint32},
+		},
+		RdmaCurrent: []cgroups.RdmaEntry{
+			{Device: "mlx5_1", HcaHandles: 3, HcaObjects: 42},
+			{Device: "mlx4_0"},
+		},
+	}
+	if !reflect.DeepEqual(stats.RdmaStats, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, stats.RdmaStats)
+	}
+}
@@ -25,3 +25,31 @@ func GetAllPids(path string) ([]int, error) {
 	})
 	return pids, err
 }
+
+// GetAllPidsByCgroup is like GetAllPids, except that the pids are mapped to
+// the cgroups they are in, as seen from path (that is, "/" for path itself,
+// and "/foo" for its sub-cgroup foo).
+func GetAllPidsByCgroup(path string) (map[int]string, error) {
+	pids := make(map[int]string)
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, iErr error) error {
+		if iErr != nil {
+			return iErr
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		cPids, err := readProcsFile(p)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(path, p)
+		if err != nil {
+			return err
+		}
+		for _, pid := range cPids {
+			pids[pid] = filepath.Join("/", rel)
+		}
+		return nil
+	})
+	return pids, err
+}
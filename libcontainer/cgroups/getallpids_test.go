@@ -1,6 +1,9 @@
 package cgroups
 
 import (
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -15,3 +18,31 @@ func BenchmarkGetAllPids(b *testing.B) {
 	}
 	b.Logf("iter: %d, total: %d", b.N, total)
 }
+
+func TestGetAllPidsByCgroup(t *testing.T) {
+	TestMode = true
+	defer func() { TestMode = false }()
+
+	dir := t.TempDir()
+	for sub, procs := range map[string]string{
+		"":        "1\n2\n",
+		"foo":     "3\n",
+		"foo/bar": "",
+		"baz":     "4\n",
+	} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, sub, CgroupProcesses), []byte(procs), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pids, err := GetAllPidsByCgroup(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[int]string{1: "/", 2: "/", 3: "/foo", 4: "/baz"}
+	if !reflect.DeepEqual(pids, expected) {
+		t.Errorf("expected %v, got %v", expected, pids)
+	}
+}
@@ -0,0 +1,36 @@
+package manager
+
+import (
+	"github.com/szcdx/runc/libcontainer/cgroups"
+	"github.com/szcdx/runc/libcontainer/configs"
+)
+
+// accountingManager is the cgroup manager used in the accounting-only mode
+// (see configs.Cgroup.AccountingOnly). It wraps the manager chosen for the
+// environment, which is given no resources, so that it creates and joins
+// the cgroup (with accounting enabled, in case of systemd), but never sets
+// any limits.
+type accountingManager struct {
+	cgroups.Manager
+	config *configs.Cgroup
+}
+
+func newAccountingManager(config *configs.Cgroup, paths map[string]string) (cgroups.Manager, error) {
+	c := *config
+	// SkipDevices makes the managers leave the device access alone.
+	c.Resources = &configs.Resources{SkipDevices: true}
+	m, err := newWithPaths(&c, paths)
+	if err != nil {
+		return nil, err
+	}
+	return &accountingManager{Manager: m, config: config}, nil
+}
+
+// Set does nothing, as no limits are applied in the accounting-only mode.
+func (m *accountingManager) Set(_ *configs.Resources) error {
+	return nil
+}
+
+func (m *accountingManager) GetCgroups() (*configs.Cgroup, error) {
+	return m.config, nil
+}
@@ -1,8 +1,12 @@
 package manager
 
 import (
+	"os"
+	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/szcdx/runc/libcontainer/cgroups"
 	"github.com/szcdx/runc/libcontainer/cgroups/systemd"
 	"github.com/szcdx/runc/libcontainer/configs"
 )
@@ -53,3 +57,136 @@ func testNilResources(t *testing.T, systemd bool) {
 	_, _ = mgr.OOMKillCount()
 	_ = mgr.Destroy()
 }
+
+func TestAccountingOnly(t *testing.T) {
+	cg := &configs.Cgroup{
+		Name:           "test-accounting-only",
+		AccountingOnly: true,
+		Resources:      &configs.Resources{Memory: 1 << 20},
+	}
+	mgr, err := New(cg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The limits are never set.
+	if err := mgr.Set(cg.Resources); err != nil {
+		t.Fatal(err)
+	}
+	got, err := mgr.GetCgroups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != cg {
+		t.Fatalf("expected the original config, got %+v", got)
+	}
+}
+
+func TestSystemdFallback(t *testing.T) {
+	if systemd.Available(false) == nil {
+		t.Skip("requires systemd to be unavailable")
+	}
+	cg := &configs.Cgroup{
+		Systemd:         true,
+		Parent:          "system.slice",
+		ScopePrefix:     "runc-test",
+		Name:            "fallback",
+		Resources:       &configs.Resources{},
+		SystemdFallback: true,
+	}
+	mgr, err := New(cg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cg.SystemdDegraded {
+		t.Fatal("expected the degraded mode to be set")
+	}
+	// The paths are the ones systemd would use.
+	for _, path := range mgr.GetPaths() {
+		if !strings.HasSuffix(path, "/system.slice/runc-test-fallback.scope") {
+			t.Errorf("unexpected path %q", path)
+		}
+	}
+	// Without systemd, the container can't be registered.
+	if _, err := RegisterSystemd(cg, mgr.GetPaths(), -1); err == nil {
+		t.Fatal("expected an error")
+	}
+	if !cg.SystemdDegraded {
+		t.Fatal("expected the degraded mode to be kept")
+	}
+
+	// Without the fallback, systemd is required.
+	cg.SystemdFallback, cg.SystemdDegraded = false, false
+	if _, err := New(cg); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestNewManager(t *testing.T) {
+	cg := &configs.Cgroup{
+		Name:      "test-new-manager",
+		Resources: &configs.Resources{},
+	}
+	mgr, err := cgroups.NewManager(cg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := mgr.GetPaths()
+	if len(paths) == 0 {
+		t.Fatal("expected some paths")
+	}
+	// A manager for the same cgroup can be created from the paths.
+	mgr2, err := cgroups.NewManager(cg, paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := mgr2.GetPaths(); !reflect.DeepEqual(got, paths) {
+		t.Fatalf("expected paths %v, got %v", paths, got)
+	}
+}
+
+func TestReadOnlyDegraded(t *testing.T) {
+	cg := &configs.Cgroup{
+		Name:             "test-read-only",
+		ReadOnlyDegraded: true,
+		Resources:        &configs.Resources{Memory: 1 << 20},
+	}
+	mgr, err := New(cg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mgr.Apply(-1); err != nil {
+		t.Fatal(err)
+	}
+	if err := mgr.Set(cg.Resources); err != nil {
+		t.Fatal(err)
+	}
+	if mgr.Exists() {
+		t.Fatal("expected no cgroup")
+	}
+	if paths := mgr.GetPaths(); len(paths) != 0 {
+		t.Fatalf("expected no paths, got %v", paths)
+	}
+	if pids, err := mgr.GetAllPids(); err != nil || len(pids) != 0 {
+		t.Fatalf("expected no pids, got %v (%v)", pids, err)
+	}
+
+	// The processes are found from the init process.
+	if err := mgr.Apply(os.Getpid()); err != nil {
+		t.Fatal(err)
+	}
+	pids, err := mgr.GetAllPids()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pids) == 0 || pids[0] != os.Getpid() {
+		t.Fatalf("expected pid %d first, got %v", os.Getpid(), pids)
+	}
+	// And none once it is gone.
+	cg.ReadOnlyDegradedStartTime++
+	if pids, err := mgr.GetAllPids(); err != nil || len(pids) != 0 {
+		t.Fatalf("expected no pids, got %v (%v)", pids, err)
+	}
+	if err := mgr.Destroy(); err != nil {
+		t.Fatal(err)
+	}
+}
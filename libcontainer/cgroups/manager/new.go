@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"path/filepath"
 
+	"github.com/sirupsen/logrus"
+
 	"github.com/szcdx/runc/libcontainer/cgroups"
 	"github.com/szcdx/runc/libcontainer/cgroups/fs"
 	"github.com/szcdx/runc/libcontainer/cgroups/fs2"
@@ -12,6 +14,10 @@ import (
 	"github.com/szcdx/runc/libcontainer/configs"
 )
 
+func init() {
+	cgroups.NewManagerFunc = NewWithPaths
+}
+
 // New returns the instance of a cgroup manager, which is chosen
 // based on the local environment (whether cgroup v1 or v2 is used)
 // and the config (whether config.Systemd is set or not).
@@ -31,9 +37,98 @@ func NewWithPaths(config *configs.Cgroup, paths map[string]string) (cgroups.Mana
 	if config == nil {
 		return nil, errors.New("cgroups/manager.New: config must not be nil")
 	}
+	if config.Systemd && config.SystemdFallback && !config.Rootless && paths == nil {
+		if err := systemd.Available(false); err != nil {
+			logrus.Warnf("%v; falling back to the fs cgroup manager", err)
+			config.SystemdDegraded = true
+		}
+	}
+	if config.ReadOnlyDegraded {
+		return &readOnlyManager{config: config}, nil
+	}
+	m, err := newManager(config, paths)
+	if err != nil || paths != nil || config.Rootless {
+		return m, err
+	}
+	// The cgroup is about to be created, so make sure it can be.
+	if err := checkWritable(m); err != nil {
+		if !config.SkipLimitsIfReadOnly {
+			return nil, err
+		}
+		logrus.Warnf("%v; running the container without cgroup limits", err)
+		config.ReadOnlyDegraded = true
+		return &readOnlyManager{config: config}, nil
+	}
+	return m, nil
+}
+
+func newManager(config *configs.Cgroup, paths map[string]string) (cgroups.Manager, error) {
+	if config.Systemd && config.SystemdDegraded {
+		return newDegraded(config, paths)
+	}
 	if config.Systemd && !systemd.IsRunningSystemd() {
 		return nil, errors.New("systemd not running on this host, cannot use systemd cgroups manager")
 	}
+	if config.AccountingOnly {
+		return newAccountingManager(config, paths)
+	}
+	return newWithPaths(config, paths)
+}
+
+// newDegraded returns the fs cgroup manager for a systemd config which is
+// in the degraded mode (see configs.Cgroup.SystemdDegraded). The cgroup
+// paths, unless known, are the ones the systemd manager would use, so that
+// the container can be registered with systemd later (see RegisterSystemd).
+func newDegraded(config *configs.Cgroup, paths map[string]string) (cgroups.Manager, error) {
+	if cgroups.IsCgroup2UnifiedMode() {
+		path, err := getUnifiedPath(paths)
+		if err != nil {
+			return nil, fmt.Errorf("manager.NewWithPaths: inconsistent paths: %w", err)
+		}
+		if path == "" {
+			m, err := systemd.NewUnifiedManager(config, "")
+			if err != nil {
+				return nil, err
+			}
+			path = m.Path("")
+		}
+		return fs2.NewManager(config, path)
+	}
+	if paths == nil {
+		m, err := systemd.NewLegacyManager(config, nil)
+		if err != nil {
+			return nil, err
+		}
+		paths = m.GetPaths()
+	}
+	return fs.NewManager(config, paths)
+}
+
+// RegisterSystemd registers a container created in the degraded mode (see
+// configs.Cgroup.SystemdDegraded) with systemd, by starting its unit with
+// the process pid (the container init) in it, and returns the systemd
+// cgroup manager to be used for it from now on. On success, the degraded
+// mode is cleared in config.
+func RegisterSystemd(config *configs.Cgroup, paths map[string]string, pid int) (cgroups.Manager, error) {
+	if !config.Systemd || !config.SystemdDegraded {
+		return nil, errors.New("cgroup is not in the systemd degraded mode")
+	}
+	if err := systemd.Available(config.Rootless); err != nil {
+		return nil, err
+	}
+	config.SystemdDegraded = false
+	m, err := newWithPaths(config, paths)
+	if err == nil {
+		err = m.Apply(pid)
+	}
+	if err != nil {
+		config.SystemdDegraded = true
+		return nil, err
+	}
+	return m, nil
+}
+
+func newWithPaths(config *configs.Cgroup, paths map[string]string) (cgroups.Manager, error) {
 
 	// Cgroup v2 aka unified hierarchy.
 	if cgroups.IsCgroup2UnifiedMode() {
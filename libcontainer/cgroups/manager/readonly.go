@@ -0,0 +1,158 @@
+package manager
+
+import (
+	"errors"
+	"os"
+	"strconv"
+
+	"github.com/szcdx/runc/libcontainer/cgroups"
+	"github.com/szcdx/runc/libcontainer/configs"
+	"github.com/szcdx/runc/libcontainer/system"
+)
+
+// errReadOnlyDegraded is returned by the readOnlyManager methods which need
+// the container's own cgroup.
+var errReadOnlyDegraded = errors.New("container has no cgroup of its own, as the cgroup filesystem was read-only when it was created")
+
+// readOnlyManager is the cgroup manager used in the read-only degraded mode
+// (see configs.Cgroup.ReadOnlyDegraded). The container has no cgroup of its
+// own, so there is nothing to create, join, limit or remove, and its
+// processes are found from its init process instead.
+type readOnlyManager struct {
+	config *configs.Cgroup
+}
+
+// checkWritable returns a *cgroups.ReadOnlyError if any of the cgroup paths
+// of the manager m is on a read-only cgroup filesystem.
+func checkWritable(m cgroups.Manager) error {
+	for _, path := range m.GetPaths() {
+		if err := cgroups.CheckWritable(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Apply records the process pid as the init process of the container (the
+// last one wins, as the init process is only known once runc init forked
+// it), for GetPids to find the processes of the container from.
+func (m *readOnlyManager) Apply(pid int) error {
+	if pid <= 0 {
+		return nil
+	}
+	stat, err := system.Stat(pid)
+	if err != nil {
+		return err
+	}
+	m.config.ReadOnlyDegradedPid = pid
+	m.config.ReadOnlyDegradedStartTime = stat.StartTime
+	return nil
+}
+
+// GetPids returns the processes of the container: the ones in the PID
+// namespace of its init process, or, if it has no PID namespace of its
+// own, the init process and its descendants. It returns none once the
+// init process is gone.
+func (m *readOnlyManager) GetPids() ([]int, error) {
+	pid := m.config.ReadOnlyDegradedPid
+	if pid <= 0 {
+		return nil, nil
+	}
+	stat, err := system.Stat(pid)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if stat.StartTime != m.config.ReadOnlyDegradedStartTime || stat.State == system.Zombie {
+		return nil, nil
+	}
+	initNs, err := os.Readlink("/proc/" + strconv.Itoa(pid) + "/ns/pid")
+	if err != nil {
+		return nil, err
+	}
+	selfNs, err := os.Readlink("/proc/self/ns/pid")
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+	var pids []int
+	children := make(map[int][]int)
+	for _, e := range entries {
+		p, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		if initNs != selfNs {
+			// The processes may exit meanwhile, so skip the errors.
+			if ns, err := os.Readlink("/proc/" + e.Name() + "/ns/pid"); err == nil && ns == initNs {
+				pids = append(pids, p)
+			}
+			continue
+		}
+		if stat, err := system.Stat(p); err == nil {
+			children[stat.PPid] = append(children[stat.PPid], p)
+		}
+	}
+	if initNs != selfNs {
+		return pids, nil
+	}
+	for todo := []int{pid}; len(todo) > 0; {
+		p := todo[0]
+		todo = append(todo[1:], children[p]...)
+		pids = append(pids, p)
+	}
+	return pids, nil
+}
+
+// GetAllPids is the same as GetPids, as there are no sub-cgroups.
+func (m *readOnlyManager) GetAllPids() ([]int, error) {
+	return m.GetPids()
+}
+
+// GetStats returns empty stats, as there is no cgroup to get them from.
+func (m *readOnlyManager) GetStats() (*cgroups.Stats, error) {
+	return cgroups.NewStats(), nil
+}
+
+func (m *readOnlyManager) Freeze(_ configs.FreezerState) error {
+	return errReadOnlyDegraded
+}
+
+func (m *readOnlyManager) Destroy() error {
+	return nil
+}
+
+func (m *readOnlyManager) Path(_ string) string {
+	return ""
+}
+
+// Set does nothing, as no limits are applied in the read-only degraded mode.
+func (m *readOnlyManager) Set(_ *configs.Resources) error {
+	return nil
+}
+
+func (m *readOnlyManager) GetPaths() map[string]string {
+	return map[string]string{}
+}
+
+func (m *readOnlyManager) GetCgroups() (*configs.Cgroup, error) {
+	return m.config, nil
+}
+
+func (m *readOnlyManager) GetFreezerState() (configs.FreezerState, error) {
+	return configs.Undefined, nil
+}
+
+func (m *readOnlyManager) Exists() bool {
+	return false
+}
+
+func (m *readOnlyManager) OOMKillCount() (uint64, error) {
+	return 0, nil
+}
@@ -0,0 +1,47 @@
+package cgroups
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+)
+
+// ErrReadOnly is returned, wrapped in a *ReadOnlyError, by the cgroup
+// managers if the cgroup filesystem is mounted read-only (see CheckWritable).
+var ErrReadOnly = errors.New("cgroup filesystem is read-only")
+
+// ReadOnlyError is the error returned if a cgroup can not be created,
+// joined or modified because the cgroup filesystem is mounted read-only.
+type ReadOnlyError struct {
+	// Path is the cgroup path.
+	Path string
+}
+
+func (e *ReadOnlyError) Error() string {
+	return "unable to use cgroup " + e.Path + ": " + ErrReadOnly.Error()
+}
+
+func (e *ReadOnlyError) Unwrap() error {
+	return ErrReadOnly
+}
+
+// CheckWritable returns a *ReadOnlyError if the cgroup filesystem the
+// cgroup path is on is mounted read-only. The path does not have to exist,
+// in which case its closest existing parent is checked.
+func CheckWritable(path string) error {
+	for dir := path; ; dir = filepath.Dir(dir) {
+		var st unix.Statfs_t
+		err := unix.Statfs(dir, &st)
+		if err == nil {
+			if st.Flags&unix.ST_RDONLY != 0 {
+				return &ReadOnlyError{Path: path}
+			}
+			return nil
+		}
+		if !errors.Is(err, unix.ENOENT) || filepath.Dir(dir) == dir {
+			return &os.PathError{Op: "statfs", Path: dir, Err: err}
+		}
+	}
+}
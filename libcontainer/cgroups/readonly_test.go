@@ -0,0 +1,38 @@
+package cgroups
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestCheckWritable(t *testing.T) {
+	dir := t.TempDir()
+	if err := CheckWritable(dir); err != nil {
+		t.Fatal(err)
+	}
+	// A path which does not exist yet.
+	if err := CheckWritable(filepath.Join(dir, "a", "b")); err != nil {
+		t.Fatal(err)
+	}
+
+	if os.Geteuid() != 0 {
+		t.Skip("requires root")
+	}
+	if err := unix.Mount("tmpfs", dir, "tmpfs", unix.MS_RDONLY, ""); err != nil {
+		t.Skip(err)
+	}
+	defer unix.Unmount(dir, unix.MNT_DETACH) //nolint:errcheck
+	path := filepath.Join(dir, "a", "b")
+	err := CheckWritable(path)
+	var roErr *ReadOnlyError
+	if !errors.As(err, &roErr) || !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("expected a *ReadOnlyError, got %v", err)
+	}
+	if roErr.Path != path {
+		t.Errorf("expected path %s, got %s", path, roErr.Path)
+	}
+}
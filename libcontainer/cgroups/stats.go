@@ -7,6 +7,12 @@ type ThrottlingData struct {
 	ThrottledPeriods uint64 `json:"throttled_periods,omitempty"`
 	// Aggregate time the container was throttled for in nanoseconds.
 	ThrottledTime uint64 `json:"throttled_time,omitempty"`
+	// Number of periods in which the container used its CPU burst
+	// (since Linux 5.14).
+	Bursts uint64 `json:"bursts,omitempty"`
+	// Aggregate time the container ran over its quota using its CPU
+	// burst, in nanoseconds (since Linux 5.14).
+	BurstTime uint64 `json:"burst_time,omitempty"`
 }
 
 // CpuUsage denotes the usage of a CPU.
@@ -17,6 +23,7 @@ type CpuUsage struct {
 	TotalUsage uint64 `json:"total_usage,omitempty"`
 	// Total CPU time consumed per core.
 	// Units: nanoseconds.
+	// Not available on cgroup v2, which has no per-core CPU accounting.
 	PercpuUsage []uint64 `json:"percpu_usage,omitempty"`
 	// CPU time consumed per core in kernel mode
 	// Units: nanoseconds.
@@ -93,6 +100,8 @@ type MemoryStats struct {
 	SwapUsage MemoryData `json:"swap_usage,omitempty"`
 	// usage of swap only
 	SwapOnlyUsage MemoryData `json:"swap_only_usage,omitempty"`
+	// usage of compressed swap (zswap), cgroup v2 only
+	ZswapUsage MemoryData `json:"zswap_usage,omitempty"`
 	// usage of kernel memory
 	KernelUsage MemoryData `json:"kernel_usage,omitempty"`
 	// usage of kernel TCP memory
@@ -105,6 +114,30 @@ type MemoryStats struct {
 
 	Stats map[string]uint64 `json:"stats,omitempty"`
 	PSI   *PSIStats         `json:"psi,omitempty"`
+
+	// memory events (cgroup v2 only), from memory.events
+	Events *MemoryEvents `json:"events,omitempty"`
+	// memory events of the cgroup itself, not including its descendants
+	// (cgroup v2 only, since kernel 5.2), from memory.events.local
+	EventsLocal *MemoryEvents `json:"events_local,omitempty"`
+}
+
+// MemoryEvents are the counters of the cgroup v2 memory events.
+type MemoryEvents struct {
+	// number of times the cgroup was reclaimed due to high memory pressure
+	// even though its usage was under the low boundary
+	Low uint64 `json:"low"`
+	// number of times the cgroup was throttled and routed to perform direct
+	// memory reclaim because the high memory boundary was exceeded
+	High uint64 `json:"high"`
+	// number of times the cgroup usage was about to go over the max boundary
+	Max uint64 `json:"max"`
+	// number of times the cgroup's memory usage reached the limit and
+	// allocation was about to fail
+	OOM uint64 `json:"oom"`
+	// number of processes belonging to the cgroup killed by any kind of
+	// OOM killer
+	OOMKill uint64 `json:"oom_kill"`
 }
 
 type PageUsageByNUMA struct {
@@ -130,6 +163,9 @@ type PidsStats struct {
 	Current uint64 `json:"current,omitempty"`
 	// active pids hard limit
 	Limit uint64 `json:"limit,omitempty"`
+	// number of times a fork or clone failed because of the limit (the
+	// "max" counter of pids.events)
+	LimitHits uint64 `json:"limit_hits,omitempty"`
 }
 
 type BlkioStatEntry struct {
@@ -150,6 +186,14 @@ type BlkioStats struct {
 	IoTimeRecursive         []BlkioStatEntry `json:"io_time_recursive,omitempty"`
 	SectorsRecursive        []BlkioStatEntry `json:"sectors_recursive,omitempty"`
 	PSI                     *PSIStats        `json:"psi,omitempty"`
+
+	// The configured limits, read back from the cgroup. The weights are in
+	// the cgroup v1 range (10 to 1000) on both cgroup versions, and the
+	// throttles (with Op being one of "rbps", "wbps", "riops", and "wiops")
+	// only include the devices which are limited.
+	Weight         uint64           `json:"weight,omitempty"`
+	WeightDevice   []BlkioStatEntry `json:"weight_device,omitempty"`
+	ThrottleDevice []BlkioStatEntry `json:"throttle_device,omitempty"`
 }
 
 type HugetlbStats struct {
@@ -159,6 +203,10 @@ type HugetlbStats struct {
 	MaxUsage uint64 `json:"max_usage,omitempty"`
 	// number of times hugetlb usage allocation failure.
 	Failcnt uint64 `json:"failcnt"`
+	// Fault are the page fault based counters, if the above are the
+	// reservation based ones (which is the case if the kernel has them).
+	// For cgroup v2, the allocation failures are only reported here.
+	Fault *HugetlbStats `json:"fault,omitempty"`
 }
 
 type RdmaEntry struct {
@@ -177,6 +225,8 @@ type MiscStats struct {
 	Usage uint64 `json:"usage,omitempty"`
 	// number of times the resource usage was about to go over the max boundary
 	Events uint64 `json:"events,omitempty"`
+	// resource limit for a key in misc (math.MaxUint64 if there is no limit)
+	Limit uint64 `json:"limit,omitempty"`
 }
 
 type Stats struct {
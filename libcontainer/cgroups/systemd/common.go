@@ -51,6 +51,20 @@ func IsRunningSystemd() bool {
 	return isRunningSystemd
 }
 
+// Available checks whether systemd is running and can be reached over
+// dbus, so that the systemd cgroup managers can be used. Unlike
+// IsRunningSystemd, it does not cache the result, as systemd may come up
+// later (such as when runc is used early at boot).
+func Available(rootless bool) error {
+	if fi, err := os.Lstat("/run/systemd/system"); err != nil || !fi.IsDir() {
+		return errors.New("systemd not running on this host")
+	}
+	if _, err := newDbusConnManager(rootless).getConnection(); err != nil {
+		return fmt.Errorf("unable to connect to systemd: %w", err)
+	}
+	return nil
+}
+
 // systemd represents slice hierarchy using `-`, so we need to follow suit when
 // generating the path of slice. Essentially, test-a-b.slice becomes
 // /test.slice/test-a.slice/test-a-b.slice.
@@ -145,11 +159,10 @@ retry:
 		}
 		if retry {
 			// In case a unit with the same name exists, this may
-			// be a leftover failed unit. Reset it, so systemd can
-			// remove it, and retry once.
-			err = resetFailedUnit(cm, unitName)
-			if err != nil {
-				logrus.Warnf("unable to reset failed unit: %v", err)
+			// be a leftover failed or abandoned unit. Clean it up,
+			// so systemd can remove it, and retry once.
+			if _, err := cleanupLeakedUnit(cm, unitName); err != nil {
+				logrus.Warnf("unable to clean up leaked unit: %v", err)
 			}
 			retry = false
 			goto retry
@@ -219,6 +232,24 @@ func getUnitTypeProperty(cm *dbusConnManager, unitName string, unitType string,
 	return prop, err
 }
 
+// runcUnitProperties are the unit properties which runc sets itself, and
+// relies upon, so they can not be changed once the unit is started.
+var runcUnitProperties = map[string]bool{
+	"PIDs":  true,
+	"Slice": true,
+	"Wants": true,
+}
+
+// checkUnitProperties checks that properties can be set on a started unit.
+func checkUnitProperties(properties []systemdDbus.Property) error {
+	for _, p := range properties {
+		if runcUnitProperties[p.Name] {
+			return fmt.Errorf("property %s is set by runc and can not be changed", p.Name)
+		}
+	}
+	return nil
+}
+
 func setUnitProperties(cm *dbusConnManager, name string, properties ...systemdDbus.Property) error {
 	return cm.retryOnDisconnect(func(c *systemdDbus.Conn) error {
 		return c.SetUnitPropertiesContext(context.TODO(), name, true, properties...)
@@ -0,0 +1,105 @@
+package systemd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+
+	systemdDbus "github.com/coreos/go-systemd/v22/dbus"
+	"github.com/sirupsen/logrus"
+
+	"github.com/szcdx/runc/libcontainer/cgroups"
+)
+
+// isUnitLeaked returns whether the unit is a leftover of a removed
+// container, which keeps a new unit with the same name from being created:
+// either a failed unit (such as a scope whose processes were OOM killed),
+// or an abandoned one having no processes left in its cgroup. A unit which
+// failed has to be reset to be removed, and the other ones stopped.
+func isUnitLeaked(cm *dbusConnManager, unitName string) (leaked, failed bool, _ error) {
+	var props map[string]interface{}
+	err := cm.retryOnDisconnect(func(c *systemdDbus.Conn) (err error) {
+		props, err = c.GetUnitPropertiesContext(context.TODO(), unitName)
+		return err
+	})
+	if err != nil {
+		return false, false, err
+	}
+	switch state, _ := props["ActiveState"].(string); state {
+	case "failed":
+		return true, true, nil
+	case "active":
+	default:
+		return false, false, nil
+	}
+	cgroup, _ := props["ControlGroup"].(string)
+	if cgroup == "" {
+		return false, false, nil
+	}
+	empty, err := isCgroupEmpty(cgroup)
+	return empty, false, err
+}
+
+// isCgroupEmpty returns whether there are no processes in the cgroup (as
+// given by the ControlGroup property of a unit) or its sub-cgroups.
+func isCgroupEmpty(cgroup string) (bool, error) {
+	mnt := "/sys/fs/cgroup"
+	if !cgroups.IsCgroup2UnifiedMode() {
+		// The units' cgroups are the ones of the systemd hierarchy.
+		var err error
+		mnt, err = cgroups.FindCgroupMountpoint("", "name=systemd")
+		if err != nil {
+			return false, err
+		}
+	}
+	pids, err := cgroups.GetAllPids(filepath.Join(mnt, cgroup))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return true, nil
+		}
+		return false, err
+	}
+	return len(pids) == 0, nil
+}
+
+// cleanupLeakedUnit removes the unit if it is leaked (see isUnitLeaked), and
+// returns whether it was.
+func cleanupLeakedUnit(cm *dbusConnManager, unitName string) (bool, error) {
+	leaked, failed, err := isUnitLeaked(cm, unitName)
+	if err != nil || !leaked {
+		return false, err
+	}
+	if failed {
+		return true, resetFailedUnit(cm, unitName)
+	}
+	logrus.Debugf("stopping abandoned unit %s", unitName)
+	return true, stopUnit(cm, unitName)
+}
+
+// CleanupLeakedUnits removes the leaked transient scopes (see isUnitLeaked)
+// of the containers with the given scope prefix (see
+// configs.Cgroup.ScopePrefix), and returns their names. The units of the
+// containers having processes are left alone.
+func CleanupLeakedUnits(scopePrefix string, rootless bool) ([]string, error) {
+	cm := newDbusConnManager(rootless)
+	var units []systemdDbus.UnitStatus
+	err := cm.retryOnDisconnect(func(c *systemdDbus.Conn) (err error) {
+		units, err = c.ListUnitsByPatternsContext(context.TODO(), nil, []string{scopePrefix + "-*.scope"})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	for _, u := range units {
+		ok, err := cleanupLeakedUnit(cm, u.Name)
+		if err != nil {
+			return removed, err
+		}
+		if ok {
+			removed = append(removed, u.Name)
+		}
+	}
+	return removed, nil
+}
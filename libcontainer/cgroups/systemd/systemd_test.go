@@ -1,6 +1,7 @@
 package systemd
 
 import (
+	"math"
 	"os"
 	"reflect"
 	"testing"
@@ -179,3 +180,47 @@ func TestUnifiedResToSystemdProps(t *testing.T) {
 		})
 	}
 }
+
+// TestUnifiedResToSystemdPropsMax checks the forms of the values of the
+// files which can be "max", which need no systemd connection.
+func TestUnifiedResToSystemdPropsMax(t *testing.T) {
+	cm := newDbusConnManager(os.Geteuid() != 0)
+
+	props, err := unifiedResToSystemdProps(cm, map[string]string{
+		"memory.high":     " max\n",
+		"memory.low":      "0",
+		"memory.max":      "-1",
+		"memory.swap.max": "1048576",
+		"pids.max":        "-1",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expProps := []systemdDbus.Property{
+		newProp("MemoryHigh", uint64(math.MaxUint64)),
+		newProp("MemoryLow", uint64(0)),
+		newProp("MemoryMax", uint64(math.MaxUint64)),
+		newProp("MemorySwapMax", uint64(1048576)),
+		newProp("TasksMax", uint64(math.MaxUint64)),
+	}
+	if !reflect.DeepEqual(expProps, props) {
+		t.Errorf("wrong properties (exp %+v, got %+v)", expProps, props)
+	}
+
+	for _, v := range []string{"-2", "1k", "max max"} {
+		if _, err := unifiedResToSystemdProps(cm, map[string]string{"memory.max": v}); err == nil {
+			t.Errorf("memory.max=%q: expected error, got nil", v)
+		}
+	}
+}
+
+func TestCheckUnitProperties(t *testing.T) {
+	if err := checkUnitProperties([]systemdDbus.Property{newProp("CollectMode", "inactive-or-failed")}); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	for _, name := range []string{"Slice", "Wants", "PIDs"} {
+		if err := checkUnitProperties([]systemdDbus.Property{newProp(name, "")}); err == nil {
+			t.Errorf("expected an error for %s, got nil", name)
+		}
+	}
+}
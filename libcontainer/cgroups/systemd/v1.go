@@ -2,6 +2,7 @@ package systemd
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -20,6 +21,9 @@ type LegacyManager struct {
 	cgroups *configs.Cgroup
 	paths   map[string]string
 	dbus    *dbusConnManager
+	// foreign is set once the unit of an Exclusive cgroup is found to
+	// exist, so that it is not stopped by Destroy.
+	foreign bool
 }
 
 func NewLegacyManager(cg *configs.Cgroup, paths map[string]string) (*LegacyManager, error) {
@@ -69,7 +73,7 @@ var legacySubsystems = []subsystem{
 	&fs.NetClsGroup{},
 	&fs.NameGroup{GroupName: "name=systemd"},
 	&fs.RdmaGroup{},
-	&fs.NameGroup{GroupName: "misc"},
+	&fs.MiscGroup{},
 }
 
 func genV1ResourcesProperties(r *configs.Resources, cm *dbusConnManager) ([]systemdDbus.Property, error) {
@@ -206,6 +210,10 @@ func (m *LegacyManager) Apply(pid int) error {
 	properties = append(properties, c.SystemdProps...)
 
 	if err := startUnit(m.dbus, unitName, properties, pid == -1); err != nil {
+		if c.Exclusive && isUnitExists(err) {
+			m.foreign = true
+			return fmt.Errorf("%w: unit %s", cgroups.ErrExists, unitName)
+		}
 		return err
 	}
 
@@ -219,6 +227,9 @@ func (m *LegacyManager) Apply(pid int) error {
 func (m *LegacyManager) Destroy() error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	if m.foreign {
+		return nil
+	}
 
 	stopErr := stopUnit(m.dbus, getUnitName(m.cgroups))
 
@@ -385,6 +396,25 @@ func (m *LegacyManager) Set(r *configs.Resources) error {
 	return nil
 }
 
+// SetUnitProperties sets the given properties of the container's unit, in
+// addition to the ones derived from the resources (see Set).
+func (m *LegacyManager) SetUnitProperties(properties ...systemdDbus.Property) error {
+	if err := checkUnitProperties(properties); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := setUnitProperties(m.dbus, getUnitName(m.cgroups), properties...); err != nil {
+		return fmt.Errorf("unable to set unit properties: %w", err)
+	}
+	// Do not append to SystemdProps in place, as it may share its array
+	// with the config of the caller.
+	props := make([]systemdDbus.Property, 0, len(m.cgroups.SystemdProps)+len(properties))
+	props = append(props, m.cgroups.SystemdProps...)
+	m.cgroups.SystemdProps = append(props, properties...)
+	return nil
+}
+
 func (m *LegacyManager) GetPaths() map[string]string {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -1,11 +1,8 @@
 package systemd
 
 import (
-	"bufio"
-	"errors"
 	"fmt"
 	"math"
-	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -31,6 +28,9 @@ type UnifiedManager struct {
 	path  string
 	dbus  *dbusConnManager
 	fsMgr cgroups.Manager
+	// foreign is set once the unit of an Exclusive cgroup is found to
+	// exist, so that it is not stopped by Destroy.
+	foreign bool
 }
 
 func NewUnifiedManager(config *configs.Cgroup, path string) (*UnifiedManager, error) {
@@ -70,18 +70,22 @@ func shouldSetCPUIdle(cm *dbusConnManager, v string) bool {
 //
 // For the list of systemd unit properties, see systemd.resource-control(5).
 func unifiedResToSystemdProps(cm *dbusConnManager, res map[string]string) (props []systemdDbus.Property, _ error) {
-	var err error
-
-	for k, v := range res {
-		if strings.Contains(k, "/") {
-			return nil, fmt.Errorf("unified resource %q must be a file name (no slashes)", k)
-		}
-		if strings.IndexByte(k, '.') <= 0 {
-			return nil, fmt.Errorf("unified resource %q must be in the form CONTROLLER.PARAMETER", k)
+	// The values are validated and normalized (such as "-1" to "max") the
+	// same way as for fs2.Set. Unknown files are allowed, as they are
+	// ignored here anyway.
+	parsed, err := cgroups.ParseUnified(res, true)
+	if err != nil {
+		return nil, err
+	}
+	idle := ""
+	for _, r := range parsed {
+		if r.Name == "cpu.idle" {
+			idle = r.Values[0]
 		}
-		// Kernel is quite forgiving to extra whitespace
-		// around the value, and so should we.
-		v = strings.TrimSpace(v)
+	}
+
+	for _, r := range parsed {
+		k, v := r.Name, r.Values[0]
 		// Please keep cases in alphabetical order.
 		switch k {
 		case "cpu.idle":
@@ -97,27 +101,24 @@ func unifiedResToSystemdProps(cm *dbusConnManager, res map[string]string) (props
 			quota := int64(0) // 0 means "unlimited" for addCpuQuota, if period is set
 			period := defCPUQuotaPeriod
 			sv := strings.Fields(v)
-			if len(sv) < 1 || len(sv) > 2 {
-				return nil, fmt.Errorf("unified resource %q value invalid: %q", k, v)
-			}
 			// quota
 			if sv[0] != "max" {
 				quota, err = strconv.ParseInt(sv[0], 10, 64)
 				if err != nil {
-					return nil, fmt.Errorf("unified resource %q period value conversion error: %w", k, err)
+					return nil, fmt.Errorf("unified resource %q quota value conversion error: %w", k, err)
 				}
 			}
 			// period
 			if len(sv) == 2 {
 				period, err = strconv.ParseUint(sv[1], 10, 64)
 				if err != nil {
-					return nil, fmt.Errorf("unified resource %q quota value conversion error: %w", k, err)
+					return nil, fmt.Errorf("unified resource %q period value conversion error: %w", k, err)
 				}
 			}
 			addCpuQuota(cm, &props, quota, period)
 
 		case "cpu.weight":
-			if shouldSetCPUIdle(cm, strings.TrimSpace(res["cpu.idle"])) {
+			if shouldSetCPUIdle(cm, idle) {
 				// Do not add duplicate CPUWeight property
 				// (see case "cpu.idle" above).
 				logrus.Warn("unable to apply both cpu.weight and cpu.idle to systemd, ignoring cpu.weight")
@@ -151,12 +152,9 @@ func unifiedResToSystemdProps(cm *dbusConnManager, res map[string]string) (props
 			}
 
 		case "memory.high", "memory.low", "memory.min", "memory.max", "memory.swap.max":
-			num := uint64(math.MaxUint64)
-			if v != "max" {
-				num, err = strconv.ParseUint(v, 10, 64)
-				if err != nil {
-					return nil, fmt.Errorf("unified resource %q value conversion error: %w", k, err)
-				}
+			num, err := unifiedMaxToUint(v)
+			if err != nil {
+				return nil, fmt.Errorf("unified resource %q value conversion error: %w", k, err)
 			}
 			m := map[string]string{
 				"memory.high":     "MemoryHigh",
@@ -169,13 +167,9 @@ func unifiedResToSystemdProps(cm *dbusConnManager, res map[string]string) (props
 				newProp(m[k], num))
 
 		case "pids.max":
-			num := uint64(math.MaxUint64)
-			if v != "max" {
-				var err error
-				num, err = strconv.ParseUint(v, 10, 64)
-				if err != nil {
-					return nil, fmt.Errorf("unified resource %q value conversion error: %w", k, err)
-				}
+			num, err := unifiedMaxToUint(v)
+			if err != nil {
+				return nil, fmt.Errorf("unified resource %q value conversion error: %w", k, err)
 			}
 			props = append(props,
 				newProp("TasksMax", num))
@@ -192,13 +186,23 @@ func unifiedResToSystemdProps(cm *dbusConnManager, res map[string]string) (props
 		default:
 			// Ignore the unknown resource here -- will still be
 			// applied in Set which calls fs2.Set.
-			logrus.Debugf("don't know how to convert unified resource %q=%q to systemd unit property; skipping (will still be applied to cgroupfs)", k, v)
+			logrus.Debugf("don't know how to convert unified resource %q=%q to systemd unit property; skipping (will still be applied to cgroupfs)", k, res[k])
 		}
 	}
 
 	return props, nil
 }
 
+// unifiedMaxToUint converts a normalized value of a cgroups.UnifiedMax file
+// (either "max" or a number) to a systemd unit property value, where "max"
+// is math.MaxUint64 (infinity).
+func unifiedMaxToUint(v string) (uint64, error) {
+	if v == "max" {
+		return math.MaxUint64, nil
+	}
+	return strconv.ParseUint(v, 10, 64)
+}
+
 func genV2ResourcesProperties(dirPath string, r *configs.Resources, cm *dbusConnManager) ([]systemdDbus.Property, error) {
 	// We need this check before setting systemd properties, otherwise
 	// the container is OOM-killed and the systemd unit is removed
@@ -237,6 +241,17 @@ func genV2ResourcesProperties(dirPath string, r *configs.Resources, cm *dbusConn
 		properties = append(properties,
 			newProp("MemorySwapMax", uint64(swap)))
 	}
+	// MemoryZSwapMax is supported since systemd v253, and
+	// MemoryZSwapWriteback since v256. Older versions leave
+	// these to fs2.Set.
+	if r.MemoryZswapMax != nil && systemdVersion(cm) >= 253 {
+		properties = append(properties,
+			newProp("MemoryZSwapMax", uint64(*r.MemoryZswapMax)))
+	}
+	if r.MemoryZswapWriteback != nil && systemdVersion(cm) >= 256 {
+		properties = append(properties,
+			newProp("MemoryZSwapWriteback", *r.MemoryZswapWriteback))
+	}
 
 	idleSet := false
 	// The logic here is the same as in shouldSetCPUIdle.
@@ -329,6 +344,10 @@ func (m *UnifiedManager) Apply(pid int) error {
 	properties = append(properties, c.SystemdProps...)
 
 	if err := startUnit(m.dbus, unitName, properties, pid == -1); err != nil {
+		if c.Exclusive && isUnitExists(err) {
+			m.foreign = true
+			return fmt.Errorf("%w: unit %s", cgroups.ErrExists, unitName)
+		}
 		return fmt.Errorf("unable to start unit %q (properties %+v): %w", unitName, properties, err)
 	}
 
@@ -336,57 +355,15 @@ func (m *UnifiedManager) Apply(pid int) error {
 		return err
 	}
 
-	if c.OwnerUID != nil {
-		// The directory itself must be chowned.
-		err := os.Chown(m.path, *c.OwnerUID, -1)
-		if err != nil {
-			return err
-		}
-
-		filesToChown, err := cgroupFilesToChown()
-		if err != nil {
-			return err
-		}
-
-		for _, v := range filesToChown {
-			err := os.Chown(m.path+"/"+v, *c.OwnerUID, -1)
-			// Some files might not be present.
-			if err != nil && !errors.Is(err, os.ErrNotExist) {
-				return err
-			}
-		}
-	}
-
-	return nil
-}
-
-// The kernel exposes a list of files that should be chowned to the delegate
-// uid in /sys/kernel/cgroup/delegate.  If the file is not present
-// (Linux < 4.15), use the initial values mentioned in cgroups(7).
-func cgroupFilesToChown() ([]string, error) {
-	const cgroupDelegateFile = "/sys/kernel/cgroup/delegate"
-
-	f, err := os.Open(cgroupDelegateFile)
-	if err != nil {
-		return []string{"cgroup.procs", "cgroup.subtree_control", "cgroup.threads"}, nil
-	}
-	defer f.Close()
-
-	filesToChown := []string{}
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		filesToChown = append(filesToChown, scanner.Text())
-	}
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading %s: %w", cgroupDelegateFile, err)
-	}
-
-	return filesToChown, nil
+	return fs2.ChownCgroup(m.path, c)
 }
 
 func (m *UnifiedManager) Destroy() error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	if m.foreign {
+		return nil
+	}
 
 	unitName := getUnitName(m.cgroups)
 	if err := stopUnit(m.dbus, unitName); err != nil {
@@ -493,6 +470,25 @@ func (m *UnifiedManager) Set(r *configs.Resources) error {
 	return m.fsMgr.Set(r)
 }
 
+// SetUnitProperties sets the given properties of the container's unit, in
+// addition to the ones derived from the resources (see Set).
+func (m *UnifiedManager) SetUnitProperties(properties ...systemdDbus.Property) error {
+	if err := checkUnitProperties(properties); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := setUnitProperties(m.dbus, getUnitName(m.cgroups), properties...); err != nil {
+		return fmt.Errorf("unable to set unit properties: %w", err)
+	}
+	// Do not append to SystemdProps in place, as it may share its array
+	// with the config of the caller.
+	props := make([]systemdDbus.Property, 0, len(m.cgroups.SystemdProps)+len(properties))
+	props = append(props, m.cgroups.SystemdProps...)
+	m.cgroups.SystemdProps = append(props, properties...)
+	return nil
+}
+
 func (m *UnifiedManager) GetPaths() map[string]string {
 	paths := make(map[string]string, 1)
 	paths[""] = m.path
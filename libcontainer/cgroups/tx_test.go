@@ -0,0 +1,56 @@
+package cgroups
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTx(t *testing.T) {
+	TestMode = true
+	defer func() { TestMode = false }()
+
+	dir := t.TempDir()
+	tx := NewTx(dir)
+	tx.Write("pids.max", "10")
+	tx.Write("memory.max", "1048576")
+	tx.Write("pids.max", "20")
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	for file, want := range map[string]string{"pids.max": "20", "memory.max": "1048576"} {
+		got, err := ReadFile(dir, file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("%s: want %q, got %q", file, want, got)
+		}
+	}
+
+	// The writes are gone after Commit.
+	if err := os.Remove(filepath.Join(dir, "pids.max")); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "pids.max")); !os.IsNotExist(err) {
+		t.Fatalf("expected pids.max to not be written again, got %v", err)
+	}
+
+	// The files are always in the directory.
+	tx.Write("../pids.max", "10")
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "..", "pids.max")); !os.IsNotExist(err) {
+		t.Fatalf("expected pids.max to not be written outside of the directory, got %v", err)
+	}
+
+	tx = NewTx("")
+	tx.Write("pids.max", "10")
+	if err := tx.Commit(); err == nil {
+		t.Fatal("expected an error")
+	}
+}
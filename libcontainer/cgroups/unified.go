@@ -0,0 +1,209 @@
+package cgroups
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// UnifiedFormat is the format of the value of a cgroup v2 interface file,
+// as used in configs.Resources.Unified.
+type UnifiedFormat int
+
+const (
+	// UnifiedUnknown is the format of the files unknown to runc. Such files
+	// are only accepted if explicitly allowed, and their values are written
+	// as is.
+	UnifiedUnknown UnifiedFormat = iota
+	// UnifiedSingle is a single value, such as "100" for cpu.weight.
+	UnifiedSingle
+	// UnifiedMax is a non-negative number or "max", such as memory.max.
+	UnifiedMax
+	// UnifiedQuotaPeriod is a quota ("max" or a number) optionally followed
+	// by a period, as used by cpu.max.
+	UnifiedQuotaPeriod
+	// UnifiedKeyed is one or more lines of space separated fields, usually
+	// a key (such as a device number) followed by values, such as io.max.
+	UnifiedKeyed
+)
+
+// unifiedFormats are the formats of the known cgroup v2 files.
+var unifiedFormats = map[string]UnifiedFormat{
+	"cgroup.max.depth":       UnifiedMax,
+	"cgroup.max.descendants": UnifiedMax,
+	"cpu.idle":               UnifiedSingle,
+	"cpu.max":                UnifiedQuotaPeriod,
+	"cpu.max.burst":          UnifiedSingle,
+	"cpu.uclamp.max":         UnifiedSingle,
+	"cpu.uclamp.min":         UnifiedSingle,
+	"cpu.weight":             UnifiedSingle,
+	"cpu.weight.nice":        UnifiedSingle,
+	"cpuset.cpus":            UnifiedSingle,
+	"cpuset.cpus.exclusive":  UnifiedSingle,
+	"cpuset.cpus.partition":  UnifiedSingle,
+	"cpuset.mems":            UnifiedSingle,
+	"io.bfq.weight":          UnifiedKeyed,
+	"io.latency":             UnifiedKeyed,
+	"io.max":                 UnifiedKeyed,
+	"io.weight":              UnifiedKeyed,
+	"memory.high":            UnifiedMax,
+	"memory.low":             UnifiedMax,
+	"memory.max":             UnifiedMax,
+	"memory.min":             UnifiedMax,
+	"memory.oom.group":       UnifiedSingle,
+	"memory.swap.high":       UnifiedMax,
+	"memory.swap.max":        UnifiedMax,
+	"memory.zswap.max":       UnifiedMax,
+	"misc.max":               UnifiedKeyed,
+	"pids.max":               UnifiedMax,
+	"rdma.max":               UnifiedKeyed,
+}
+
+// unifiedManaged are the files which are managed by runc itself, and thus
+// can not be set using the unified resources.
+var unifiedManaged = map[string]string{
+	"cgroup.freeze":          "use the freezer instead",
+	"cgroup.kill":            "use signals instead",
+	"cgroup.procs":           "processes are placed into the cgroup by runc",
+	"cgroup.subtree_control": "controllers are enabled by runc",
+	"cgroup.threads":         "processes are placed into the cgroup by runc",
+	"cgroup.type":            "the cgroup type is managed by runc",
+}
+
+// unifiedReadOnlySuffixes are the suffixes of the read-only files, which
+// can not be set.
+var unifiedReadOnlySuffixes = []string{
+	".current", ".events", ".events.local", ".numa_stat", ".peak", ".stat",
+}
+
+// UnifiedResource is a validated and normalized cgroup v2 resource.
+type UnifiedResource struct {
+	// Name is the name of the cgroup v2 file.
+	Name string
+	// Format is the format of the file.
+	Format UnifiedFormat
+	// Values are the values to write to the file. Keyed files can have
+	// more than one value (one per line), and every value is written
+	// separately. All other files have exactly one value.
+	Values []string
+}
+
+// UnifiedFileFormat returns the format of the cgroup v2 file name.
+func UnifiedFileFormat(name string) UnifiedFormat {
+	if f, ok := unifiedFormats[name]; ok {
+		return f
+	}
+	// hugetlb.<pagesize>.max and hugetlb.<pagesize>.rsvd.max.
+	if strings.HasPrefix(name, "hugetlb.") && strings.HasSuffix(name, ".max") {
+		return UnifiedMax
+	}
+	return UnifiedUnknown
+}
+
+// ParseUnified validates and normalizes the cgroup v2 resources from res
+// (see configs.Resources.Unified), and returns them sorted by name. Files
+// unknown to runc are only accepted if allowUnknown is set.
+func ParseUnified(res map[string]string, allowUnknown bool) ([]UnifiedResource, error) {
+	names := make([]string, 0, len(res))
+	for k := range res {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	parsed := make([]UnifiedResource, 0, len(res))
+	for _, k := range names {
+		r, err := parseUnifiedResource(k, res[k], allowUnknown)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, r)
+	}
+	return parsed, nil
+}
+
+func parseUnifiedResource(k, v string, allowUnknown bool) (UnifiedResource, error) {
+	if strings.Contains(k, "/") {
+		return UnifiedResource{}, fmt.Errorf("unified resource %q must be a file name (no slashes)", k)
+	}
+	if strings.IndexByte(k, '.') <= 0 {
+		return UnifiedResource{}, fmt.Errorf("unified resource %q must be in the form CONTROLLER.PARAMETER", k)
+	}
+	if reason, ok := unifiedManaged[k]; ok {
+		return UnifiedResource{}, fmt.Errorf("unified resource %q can't be set: %s", k, reason)
+	}
+	for _, s := range unifiedReadOnlySuffixes {
+		if strings.HasSuffix(k, s) {
+			return UnifiedResource{}, fmt.Errorf("unified resource %q can't be set: read-only file", k)
+		}
+	}
+
+	r := UnifiedResource{Name: k, Format: UnifiedFileFormat(k)}
+	// Kernel is quite forgiving to extra whitespace
+	// around the value, and so should we.
+	fields := strings.Fields(v)
+	invalid := func() (UnifiedResource, error) {
+		return UnifiedResource{}, fmt.Errorf("unified resource %q value invalid: %q", k, v)
+	}
+
+	switch r.Format {
+	case UnifiedUnknown:
+		if !allowUnknown {
+			return UnifiedResource{}, fmt.Errorf("unified resource %q is not known to runc (unknown resources must be explicitly allowed)", k)
+		}
+		r.Values = []string{v}
+	case UnifiedSingle:
+		if len(fields) != 1 {
+			return invalid()
+		}
+		r.Values = fields
+	case UnifiedMax:
+		if len(fields) != 1 {
+			return invalid()
+		}
+		max, err := normalizeMax(fields[0])
+		if err != nil {
+			return invalid()
+		}
+		r.Values = []string{max}
+	case UnifiedQuotaPeriod:
+		if len(fields) < 1 || len(fields) > 2 {
+			return invalid()
+		}
+		quota, err := normalizeMax(fields[0])
+		if err != nil {
+			return invalid()
+		}
+		if len(fields) == 2 {
+			period, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				return invalid()
+			}
+			quota += " " + strconv.FormatUint(period, 10)
+		}
+		r.Values = []string{quota}
+	case UnifiedKeyed:
+		for _, line := range strings.Split(v, "\n") {
+			if f := strings.Fields(line); len(f) > 0 {
+				r.Values = append(r.Values, strings.Join(f, " "))
+			}
+		}
+		if len(r.Values) == 0 {
+			return invalid()
+		}
+	}
+	return r, nil
+}
+
+// normalizeMax normalizes a value which is either "max" or a non-negative
+// number. A value of -1 means "max".
+func normalizeMax(v string) (string, error) {
+	if v == "max" || v == "-1" {
+		return "max", nil
+	}
+	n, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatUint(n, 10), nil
+}
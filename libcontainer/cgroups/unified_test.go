@@ -0,0 +1,51 @@
+package cgroups
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseUnified(t *testing.T) {
+	testCases := []struct {
+		name, value  string
+		allowUnknown bool
+		values       []string // nil means an error is expected
+	}{
+		{name: "memory.max", value: " 1024\n", values: []string{"1024"}},
+		{name: "memory.max", value: "-1", values: []string{"max"}},
+		{name: "memory.max", value: "0100", values: []string{"100"}},
+		{name: "memory.max", value: "1 2"},
+		{name: "memory.max", value: "lots"},
+		{name: "hugetlb.2MB.rsvd.max", value: "max", values: []string{"max"}},
+		{name: "cpu.max", value: "max  100000", values: []string{"max 100000"}},
+		{name: "cpu.max", value: "50000", values: []string{"50000"}},
+		{name: "cpu.max", value: "max 1 2"},
+		{name: "cpu.weight", value: "100", values: []string{"100"}},
+		{name: "cpuset.cpus", value: "0-3, 5"},
+		{name: "io.max", value: "8:0 rbps=1024\n\n8:16  wiops=100 ", values: []string{"8:0 rbps=1024", "8:16 wiops=100"}},
+		{name: "io.max", value: "\n"},
+		{name: "cgroup.procs", value: "1"},
+		{name: "cgroup.subtree_control", value: "+memory"},
+		{name: "memory.stat", value: "1"},
+		{name: "foo.bar", value: "1"},
+		{name: "foo.bar", value: " 1 ", allowUnknown: true, values: []string{" 1 "}},
+		{name: "../memory.max", value: "1"},
+		{name: "memory", value: "1"},
+	}
+	for _, tc := range testCases {
+		res, err := ParseUnified(map[string]string{tc.name: tc.value}, tc.allowUnknown)
+		if tc.values == nil {
+			if err == nil {
+				t.Errorf("%s=%q: expected error, got %+v", tc.name, tc.value, res)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s=%q: unexpected error: %v", tc.name, tc.value, err)
+			continue
+		}
+		if !reflect.DeepEqual(res[0].Values, tc.values) {
+			t.Errorf("%s=%q: expected %q, got %q", tc.name, tc.value, tc.values, res[0].Values)
+		}
+	}
+}
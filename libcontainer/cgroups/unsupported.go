@@ -0,0 +1,118 @@
+package cgroups
+
+import (
+	"github.com/szcdx/runc/libcontainer/configs"
+)
+
+// UnsupportedField is a resource limit which is set in the configuration,
+// but can not be applied on the current host.
+type UnsupportedField struct {
+	// Field is the name of the configs.Resources field, such as
+	// "OomKillDisable".
+	Field string
+	// Controller is the cgroup controller the limit belongs to.
+	Controller string
+	// Reason is why the limit can not be applied.
+	Reason string
+}
+
+func (f UnsupportedField) String() string {
+	return f.Field + " (" + f.Controller + "): " + f.Reason
+}
+
+// resourceField describes how a configs.Resources field is applied.
+type resourceField struct {
+	name string
+	// controllers are the v1 and v2 controllers of the field. An empty
+	// name means the field has no equivalent in that cgroup version.
+	v1, v2 string
+	isSet  func(r *configs.Resources) bool
+}
+
+var resourceFields = []resourceField{
+	{name: "Memory", v1: "memory", v2: "memory", isSet: func(r *configs.Resources) bool { return r.Memory != 0 }},
+	{name: "MemoryReservation", v1: "memory", v2: "memory", isSet: func(r *configs.Resources) bool { return r.MemoryReservation != 0 }},
+	{name: "MemorySwap", v1: "memory", v2: "memory", isSet: func(r *configs.Resources) bool { return r.MemorySwap != 0 }},
+	{name: "MemoryZswapMax", v2: "memory", isSet: func(r *configs.Resources) bool { return r.MemoryZswapMax != nil }},
+	{name: "MemoryZswapWriteback", v2: "memory", isSet: func(r *configs.Resources) bool { return r.MemoryZswapWriteback != nil }},
+	{name: "MemorySwappiness", v1: "memory", isSet: func(r *configs.Resources) bool { return r.MemorySwappiness != nil }},
+	{name: "OomKillDisable", v1: "memory", isSet: func(r *configs.Resources) bool { return r.OomKillDisable }},
+	{name: "CpuShares", v1: "cpu", v2: "cpu", isSet: func(r *configs.Resources) bool { return r.CpuShares != 0 }},
+	// CpuWeight is only used on cgroup v2, but it is normally set
+	// together with CpuShares, which is its v1 equivalent.
+	{name: "CpuWeight", v2: "cpu", isSet: func(r *configs.Resources) bool { return r.CpuWeight != 0 && r.CpuShares == 0 }},
+	{name: "CpuQuota", v1: "cpu", v2: "cpu", isSet: func(r *configs.Resources) bool { return r.CpuQuota != 0 }},
+	{name: "CpuPeriod", v1: "cpu", v2: "cpu", isSet: func(r *configs.Resources) bool { return r.CpuPeriod != 0 }},
+	{name: "CpuBurst", v1: "cpu", v2: "cpu", isSet: func(r *configs.Resources) bool { return r.CpuBurst != nil }},
+	{name: "CpuRtRuntime", v1: "cpu", isSet: func(r *configs.Resources) bool { return r.CpuRtRuntime != 0 }},
+	{name: "CpuRtPeriod", v1: "cpu", isSet: func(r *configs.Resources) bool { return r.CpuRtPeriod != 0 }},
+	{name: "CPUIdle", v1: "cpu", v2: "cpu", isSet: func(r *configs.Resources) bool { return r.CPUIdle != nil }},
+	{name: "CpusetCpus", v1: "cpuset", v2: "cpuset", isSet: func(r *configs.Resources) bool { return r.CpusetCpus != "" }},
+	{name: "CpusetMems", v1: "cpuset", v2: "cpuset", isSet: func(r *configs.Resources) bool { return r.CpusetMems != "" }},
+	{name: "CpusetPartition", v2: "cpuset", isSet: func(r *configs.Resources) bool { return r.CpusetPartition != "" }},
+	{name: "PidsLimit", v1: "pids", v2: "pids", isSet: func(r *configs.Resources) bool { return r.PidsLimit > 0 }},
+	{name: "BlkioWeight", v1: "blkio", v2: "io", isSet: func(r *configs.Resources) bool { return r.BlkioWeight != 0 }},
+	{name: "BlkioLeafWeight", v1: "blkio", isSet: func(r *configs.Resources) bool { return r.BlkioLeafWeight != 0 }},
+	{name: "BlkioWeightDevice", v1: "blkio", v2: "io", isSet: func(r *configs.Resources) bool { return len(r.BlkioWeightDevice) > 0 }},
+	{name: "BlkioThrottleReadBpsDevice", v1: "blkio", v2: "io", isSet: func(r *configs.Resources) bool { return len(r.BlkioThrottleReadBpsDevice) > 0 }},
+	{name: "BlkioThrottleWriteBpsDevice", v1: "blkio", v2: "io", isSet: func(r *configs.Resources) bool { return len(r.BlkioThrottleWriteBpsDevice) > 0 }},
+	{name: "BlkioThrottleReadIOPSDevice", v1: "blkio", v2: "io", isSet: func(r *configs.Resources) bool { return len(r.BlkioThrottleReadIOPSDevice) > 0 }},
+	{name: "BlkioThrottleWriteIOPSDevice", v1: "blkio", v2: "io", isSet: func(r *configs.Resources) bool { return len(r.BlkioThrottleWriteIOPSDevice) > 0 }},
+	{name: "BlkioLatencyDevice", v2: "io", isSet: func(r *configs.Resources) bool { return len(r.BlkioLatencyDevice) > 0 }},
+	{name: "HugetlbLimit", v1: "hugetlb", v2: "hugetlb", isSet: func(r *configs.Resources) bool { return len(r.HugetlbLimit) > 0 }},
+	{name: "NetPrioIfpriomap", v1: "net_prio", isSet: func(r *configs.Resources) bool { return len(r.NetPrioIfpriomap) > 0 }},
+	{name: "NetClsClassid", v1: "net_cls", isSet: func(r *configs.Resources) bool { return r.NetClsClassid != 0 }},
+	{name: "Rdma", v1: "rdma", v2: "rdma", isSet: func(r *configs.Resources) bool { return len(r.Rdma) > 0 }},
+	{name: "Misc", v1: "misc", v2: "misc", isSet: func(r *configs.Resources) bool { return len(r.Misc) > 0 }},
+}
+
+// UnsupportedFields returns the resource limits set in r which can not be
+// applied on the current host, because the cgroup version in use has no
+// equivalent of them, or because their controller is not available. The
+// cgroup managers ignore such limits, so this is the way to find out
+// about them.
+func UnsupportedFields(r *configs.Resources) []UnsupportedField {
+	if r == nil {
+		return nil
+	}
+	// If the controllers can't be found out, only the fields which
+	// have no equivalent in the cgroup version in use are reported.
+	var controllers map[string]bool
+	if subsystems, err := GetAllSubsystems(); err == nil {
+		controllers = make(map[string]bool, len(subsystems))
+		for _, s := range subsystems {
+			controllers[s] = true
+		}
+	}
+	return unsupportedFields(r, IsCgroup2UnifiedMode(), controllers)
+}
+
+func unsupportedFields(r *configs.Resources, v2 bool, controllers map[string]bool) []UnsupportedField {
+	var fields []UnsupportedField
+	for _, f := range resourceFields {
+		if !f.isSet(r) {
+			continue
+		}
+		controller, other := f.v1, f.v2
+		version, otherVersion := "v1", "v2"
+		if v2 {
+			controller, other = other, controller
+			version, otherVersion = otherVersion, version
+		}
+		switch {
+		case controller == "":
+			fields = append(fields, UnsupportedField{
+				Field:      f.name,
+				Controller: other,
+				Reason:     "only supported by cgroup " + otherVersion,
+			})
+		case controllers != nil && !controllers[controller]:
+			fields = append(fields, UnsupportedField{
+				Field:      f.name,
+				Controller: controller,
+				Reason:     "the cgroup " + version + " " + controller + " controller is not available",
+			})
+		}
+	}
+	return fields
+}
@@ -0,0 +1,54 @@
+package cgroups
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/szcdx/runc/libcontainer/configs"
+)
+
+func TestUnsupportedFields(t *testing.T) {
+	swappiness := uint64(10)
+	r := &configs.Resources{
+		Memory:           1 << 20,
+		MemorySwappiness: &swappiness,
+		CpuShares:        512,
+		CpuWeight:        20,
+		PidsLimit:        10,
+		BlkioWeight:      100,
+		Misc:             map[string]int64{"sev": 1},
+		CpusetPartition:  "isolated",
+	}
+	all := map[string]bool{
+		"cpu": true, "cpuset": true, "memory": true, "pids": true,
+		"blkio": true, "io": true, "misc": true,
+	}
+
+	testCases := []struct {
+		name        string
+		v2          bool
+		controllers map[string]bool
+		fields      []string
+	}{
+		{name: "v1", controllers: all, fields: []string{"CpusetPartition"}},
+		{name: "v2", v2: true, controllers: all, fields: []string{"MemorySwappiness"}},
+		{name: "v1, no misc controller", controllers: map[string]bool{"cpu": true, "memory": true, "pids": true, "blkio": true}, fields: []string{"CpusetPartition", "Misc"}},
+		{name: "v2, no io controller", v2: true, controllers: map[string]bool{"cpu": true, "memory": true, "pids": true, "misc": true}, fields: []string{"MemorySwappiness", "CpusetPartition", "BlkioWeight"}},
+		{name: "v2, unknown controllers", v2: true, fields: []string{"MemorySwappiness"}},
+	}
+	for _, tc := range testCases {
+		var fields []string
+		for _, f := range unsupportedFields(r, tc.v2, tc.controllers) {
+			fields = append(fields, f.Field)
+		}
+		if !reflect.DeepEqual(fields, tc.fields) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.fields, fields)
+		}
+	}
+
+	// CpuWeight alone has no v1 equivalent.
+	r = &configs.Resources{CpuWeight: 20}
+	if fields := unsupportedFields(r, false, all); len(fields) != 1 || fields[0].Field != "CpuWeight" {
+		t.Errorf("expected CpuWeight to be unsupported on v1, got %v", fields)
+	}
+}
@@ -14,6 +14,7 @@ import (
 
 	"github.com/sirupsen/logrus"
 	"github.com/szcdx/runc/libcontainer/userns"
+	"github.com/szcdx/runc/libcontainer/utils"
 	"golang.org/x/sys/unix"
 )
 
@@ -210,6 +211,43 @@ func parseCgroupFromReader(r io.Reader) (map[string]string, error) {
 	return cgroups, nil
 }
 
+// MountPath returns the path of the cgroup (as found in /proc/PID/cgroup)
+// under mnt, the mountpoint of the cgroup hierarchy with the given root
+// (as found in /proc/PID/mountinfo). Both the cgroup and the mount root are
+// relative to the root of the cgroup namespace of the process reading them,
+// so this works the same whether runc runs in its own cgroup namespace (as
+// nested containers do) or not.
+//
+// A cgroup which is not under the mount root (such as the cgroup of a
+// process in a parent cgroup namespace, seen as "/.."), is taken as relative
+// to the mount root, as the path it has in the hierarchy can not be known.
+func MountPath(mnt, root, cgroup string) string {
+	if rel, ok := relCgroupPath(root, cgroup); ok {
+		return filepath.Join(mnt, rel)
+	}
+	return filepath.Join(mnt, utils.CleanPath(cgroup))
+}
+
+// relCgroupPath returns the path of cgroup relative to root, if it is under
+// root. Both are relative to the cgroup namespace root, and the paths out of
+// the namespace (which start with "/..") can not be compared.
+func relCgroupPath(root, cgroup string) (string, bool) {
+	outside := func(p string) bool {
+		return p == "/.." || strings.HasPrefix(p, "/../")
+	}
+	if root == "" {
+		root = "/"
+	}
+	if outside(root) || outside(cgroup) || !filepath.IsAbs(cgroup) {
+		return "", false
+	}
+	rel, err := filepath.Rel(root, cgroup)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", false
+	}
+	return rel, true
+}
+
 func PathExists(path string) bool {
 	if _, err := os.Stat(path); err != nil {
 		return false
@@ -446,3 +484,13 @@ func ConvertBlkIOToIOWeightValue(blkIoWeight uint16) uint64 {
 	}
 	return 1 + (uint64(blkIoWeight)-10)*9999/990
 }
+
+// ConvertIOWeightToBlkIOValue converts the cgroup v2 io weight (range is
+// [1, 10000]) back to the cgroup v1 blkio weight (range is [10, 1000]). It
+// is the inverse of ConvertBlkIOToIOWeightValue.
+func ConvertIOWeightToBlkIOValue(ioWeight uint64) uint16 {
+	if ioWeight == 0 {
+		return 0
+	}
+	return uint16(10 + ((ioWeight-1)*990+9999/2)/9999)
+}
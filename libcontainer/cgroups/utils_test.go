@@ -420,6 +420,32 @@ func TestIgnoreCgroup2Mount(t *testing.T) {
 	}
 }
 
+func TestMountPath(t *testing.T) {
+	const mnt = "/sys/fs/cgroup/memory"
+	testCases := []struct {
+		root, cgroup, expected string
+	}{
+		// The cgroupfs mounted in the cgroup namespace (or in the host).
+		{root: "/", cgroup: "/", expected: mnt},
+		{root: "/", cgroup: "/foo/bar", expected: mnt + "/foo/bar"},
+		// The cgroupfs bind-mounted from a host cgroup, with no cgroup
+		// namespace (the cgroup paths are the host ones).
+		{root: "/docker/abc", cgroup: "/docker/abc", expected: mnt},
+		{root: "/docker/abc", cgroup: "/docker/abc/foo", expected: mnt + "/foo"},
+		{root: "/docker/abc", cgroup: "/docker/abcd", expected: mnt + "/docker/abcd"},
+		{root: "/docker/abc", cgroup: "/foo", expected: mnt + "/foo"},
+		// The cgroupfs mounted outside of the cgroup namespace.
+		{root: "/../..", cgroup: "/foo", expected: mnt + "/foo"},
+		// A cgroup outside of the cgroup namespace.
+		{root: "/", cgroup: "/../foo", expected: mnt + "/foo"},
+	}
+	for _, tc := range testCases {
+		if got := MountPath(mnt, tc.root, tc.cgroup); got != tc.expected {
+			t.Errorf("root %s, cgroup %s: expected %s, got %s", tc.root, tc.cgroup, tc.expected, got)
+		}
+	}
+}
+
 func TestFindCgroupMountpointAndRoot(t *testing.T) {
 	fakeMountInfo := `35 27 0:29 / /foo rw,nosuid,nodev,noexec,relatime shared:18 - cgroup cgroup rw,devices
 35 27 0:29 / /sys/fs/cgroup/devices rw,nosuid,nodev,noexec,relatime shared:18 - cgroup cgroup rw,devices`
@@ -646,3 +672,23 @@ func TestConvertBlkIOToIOWeightValue(t *testing.T) {
 		}
 	}
 }
+
+func TestConvertIOWeightToBlkIOValue(t *testing.T) {
+	cases := map[uint64]uint16{
+		0:     0,
+		1:     10,
+		10000: 1000,
+	}
+	for i, expected := range cases {
+		got := ConvertIOWeightToBlkIOValue(i)
+		if got != expected {
+			t.Errorf("expected ConvertIOWeightToBlkIOValue(%d) to be %d, got %d", i, expected, got)
+		}
+	}
+	// The conversion must round trip.
+	for w := uint16(10); w <= 1000; w++ {
+		if got := ConvertIOWeightToBlkIOValue(ConvertBlkIOToIOWeightValue(w)); got != w {
+			t.Errorf("blkio weight %d converted back to %d", w, got)
+		}
+	}
+}
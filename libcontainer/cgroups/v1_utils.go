@@ -252,12 +252,7 @@ func getCgroupPathHelper(subsystem, cgroup string) (string, error) {
 
 	// This is needed for nested containers, because in /proc/self/cgroup we
 	// see paths from host, which don't exist in container.
-	relCgroup, err := filepath.Rel(root, cgroup)
-	if err != nil {
-		return "", err
-	}
-
-	return filepath.Join(mnt, relCgroup), nil
+	return MountPath(mnt, root, cgroup), nil
 }
 
 func getControllerPath(subsystem string, cgroups map[string]string) (string, error) {
@@ -64,3 +64,28 @@ func (td *ThrottleDevice) String() string {
 func (td *ThrottleDevice) StringName(name string) string {
 	return fmt.Sprintf("%d:%d %s=%d", td.Major, td.Minor, name, td.Rate)
 }
+
+// LatencyDevice struct holds a `major:minor target` pair
+type LatencyDevice struct {
+	BlockIODevice
+	// Target is the IO completion latency target for the device, in
+	// microseconds. Zero removes the target.
+	Target uint64 `json:"target"`
+}
+
+// NewLatencyDevice returns a configured LatencyDevice pointer
+func NewLatencyDevice(major, minor int64, target uint64) *LatencyDevice {
+	ld := &LatencyDevice{}
+	ld.Major = major
+	ld.Minor = minor
+	ld.Target = target
+	return ld
+}
+
+// String formats the struct to be writable to the cgroup specific file
+func (ld *LatencyDevice) String() string {
+	if ld.Target == 0 {
+		return fmt.Sprintf("%d:%d target=max", ld.Major, ld.Minor)
+	}
+	return fmt.Sprintf("%d:%d target=%d", ld.Major, ld.Minor, ld.Target)
+}
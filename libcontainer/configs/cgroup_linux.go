@@ -48,6 +48,61 @@ type Cgroup struct {
 	// Not all cgroup manager implementations support changing
 	// the ownership.
 	OwnerUID *int `json:"owner_uid,omitempty"`
+
+	// The host GID that should own the cgroup, or nil to accept the
+	// default ownership. Only used together with OwnerUID.
+	OwnerGID *int `json:"owner_gid,omitempty"`
+
+	// Delegate tells to delegate the cgroup (cgroup v2 only) to the
+	// container's root user, which OwnerUID and OwnerGID are then set to,
+	// so that the container can manage its own sub-cgroups. This is done
+	// by all the cgroup managers, while OwnerUID alone is only used by
+	// the systemd one.
+	Delegate bool `json:"delegate,omitempty"`
+
+	// AccountingOnly tells to create and join the cgroup for resource
+	// accounting (and stats) only. No limits are applied (Resources are
+	// ignored), and the controller files are never written to, except for
+	// what the kernel requires to join a cgroup (cpuset.cpus and
+	// cpuset.mems on cgroup v1). Device access is not restricted either,
+	// so this is only suitable for trusted containers.
+	AccountingOnly bool `json:"accounting_only,omitempty"`
+
+	// SystemdFallback tells to use the fs cgroup manager (with the cgroup
+	// paths systemd would use) if systemd can not be reached when the
+	// container is created, rather than to fail. Ignored unless Systemd
+	// is set, and not supported for rootless containers.
+	SystemdFallback bool `json:"systemd_fallback,omitempty"`
+
+	// Exclusive tells Apply to fail, rather than to join the cgroup, if it
+	// already exists, as it is then the cgroup of another container. The
+	// cgroup is checked for and created atomically (or, with systemd, the
+	// unit is), and is left alone by Destroy on such a failure.
+	Exclusive bool `json:"exclusive,omitempty"`
+
+	// SystemdDegraded tells that the container was created using the
+	// fallback (see SystemdFallback), so its cgroup is not known to
+	// systemd. It is cleared once the container is registered with
+	// systemd (which is attempted when its resources are updated).
+	SystemdDegraded bool `json:"systemd_degraded,omitempty"`
+
+	// SkipLimitsIfReadOnly tells to run the container without a cgroup of
+	// its own, and so without any limits, if the cgroup filesystem is
+	// mounted read-only when the container is created, rather than to
+	// fail with cgroups.ErrReadOnly. Not used for rootless containers,
+	// which already ignore the cgroup permission errors.
+	SkipLimitsIfReadOnly bool `json:"skip_limits_if_read_only,omitempty"`
+
+	// ReadOnlyDegraded tells that the container was created without a
+	// cgroup of its own (see SkipLimitsIfReadOnly), so it stays in the
+	// cgroup of the process which created it.
+	ReadOnlyDegraded bool `json:"read_only_degraded,omitempty"`
+
+	// ReadOnlyDegradedPid and ReadOnlyDegradedStartTime are the PID and
+	// the start time of the init process of a container in the read-only
+	// degraded mode, used to find the processes of the container.
+	ReadOnlyDegradedPid       int    `json:"read_only_degraded_pid,omitempty"`
+	ReadOnlyDegradedStartTime uint64 `json:"read_only_degraded_start_time,omitempty"`
 }
 
 type Resources struct {
@@ -63,6 +118,14 @@ type Resources struct {
 	// Total memory usage (memory + swap); set `-1` to enable unlimited swap
 	MemorySwap int64 `json:"memory_swap"`
 
+	// Compressed swap (zswap) usage limit (in bytes); set `-1` for no
+	// limit, or 0 to disable zswap (cgroup v2 only, since Linux 5.19).
+	MemoryZswapMax *int64 `json:"memory_zswap_max,omitempty"`
+
+	// Whether the pages in zswap can be written back to the swap device
+	// (cgroup v2 only, since Linux 6.8).
+	MemoryZswapWriteback *bool `json:"memory_zswap_writeback,omitempty"`
+
 	// CPU shares (relative weight vs. other containers)
 	CpuShares uint64 `json:"cpu_shares"`
 
@@ -87,6 +150,12 @@ type Resources struct {
 	// MEM to use
 	CpusetMems string `json:"cpuset_mems"`
 
+	// CpusetPartition is the cpuset partition type of the cgroup (cgroup
+	// v2 only): "member", "root", or "isolated". A partition root (or an
+	// isolated one, which is also excluded from scheduler load balancing)
+	// gets its CpusetCpus for its exclusive use.
+	CpusetPartition string `json:"cpuset_partition,omitempty"`
+
 	// cgroup SCHED_IDLE
 	CPUIdle *int64 `json:"cpu_idle,omitempty"`
 
@@ -114,12 +183,23 @@ type Resources struct {
 	// IO write rate limit per cgroup per device, IO per second.
 	BlkioThrottleWriteIOPSDevice []*ThrottleDevice `json:"blkio_throttle_write_iops_device"`
 
+	// BlkioLatencyDevice is the IO latency target per device (cgroup v2
+	// only). A cgroup whose target is missed throttles the IO of its
+	// siblings with higher (or no) targets on that device.
+	BlkioLatencyDevice []*LatencyDevice `json:"blkio_latency_device,omitempty"`
+
 	// set the freeze value for the process
 	Freezer FreezerState `json:"freezer"`
 
 	// Hugetlb limit (in bytes)
 	HugetlbLimit []*HugepageLimit `json:"hugetlb_limit"`
 
+	// HugetlbAccounting chooses which of the hugetlb limits HugetlbLimit
+	// sets: "fault" for the page fault based ones, "reservation" for the
+	// reservation based ones (the hugetlb.<pagesize>.rsvd.* files), or ""
+	// for both (the reservation based ones only if the kernel has them).
+	HugetlbAccounting string `json:"hugetlb_accounting,omitempty"`
+
 	// Whether to disable OOM Killer
 	OomKillDisable bool `json:"oom_kill_disable"`
 
@@ -135,6 +215,10 @@ type Resources struct {
 	// Rdma resource restriction configuration
 	Rdma map[string]LinuxRdma `json:"rdma"`
 
+	// Misc controller limits, by resource name (such as "sgx_epc" or
+	// "sev" for AMD SEV ASIDs). A negative limit means no limit.
+	Misc map[string]int64 `json:"misc,omitempty"`
+
 	// Used on cgroups v2:
 
 	// CpuWeight sets a proportional bandwidth limit.
@@ -143,6 +227,18 @@ type Resources struct {
 	// Unified is cgroupv2-only key-value map.
 	Unified map[string]string `json:"unified"`
 
+	// UnifiedAllowUnknown allows Unified to contain files which are not
+	// known to runc. Their values are written as is, without validation.
+	UnifiedAllowUnknown bool `json:"unified_allow_unknown,omitempty"`
+
+	// DevicesPinDir is a directory, in a bpf filesystem, where the device
+	// filter program (cgroup v2 only) is pinned, so that it is loaded once
+	// and shared by all the containers with the same device rules, rather
+	// than loaded for each container. The pinned programs no cgroup uses
+	// anymore are removed when a program is pinned or shared; removing
+	// them does not affect the cgroups they are attached to.
+	DevicesPinDir string `json:"devices_pin_dir,omitempty"`
+
 	// SkipDevices allows to skip configuring device permissions.
 	// Used by e.g. kubelet while creating a parent cgroup (kubepods)
 	// common for many containers, and by runc update.
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"os"
 	"os/exec"
 	"time"
 
@@ -39,6 +40,11 @@ type Seccomp struct {
 	DefaultErrnoRet  *uint                    `json:"default_errno_ret"`
 	ListenerPath     string                   `json:"listener_path,omitempty"`
 	ListenerMetadata string                   `json:"listener_metadata,omitempty"`
+	// KeepListenerFd makes runc keep a copy of the seccomp notify fd of the
+	// container init, so that it can be sent to the seccomp agent again
+	// (for example, after the agent is restarted or upgraded). See
+	// Container.ResendSeccompNotifyFd.
+	KeepListenerFd bool `json:"keep_listener_fd,omitempty"`
 }
 
 // Action is taken upon rule match in Seccomp
@@ -83,6 +89,16 @@ type Syscall struct {
 	Action   Action `json:"action"`
 	ErrnoRet *uint  `json:"errnoRet"`
 	Args     []*Arg `json:"args"`
+
+	// FallbackAction, if set, is used instead of Action when Action is
+	// Notify but seccomp user notification is not supported by the
+	// libseccomp library or the kernel. ErrnoRet applies to it as usual.
+	FallbackAction Action `json:"fallback_action,omitempty"`
+
+	// Arches, if set, limits the rule to the specified architectures
+	// (in the same format as Seccomp.Architectures). By default, the
+	// rule applies to all the filter architectures.
+	Arches []string `json:"arches,omitempty"`
 }
 
 // Config defines configuration options for executing a process inside a contained environment.
@@ -137,10 +153,42 @@ type Config struct {
 	// Routes can be specified to create entries in the route table as the container is started
 	Routes []*Route `json:"routes"`
 
+	// PortForwards specifies the host ports forwarded into the container's
+	// network namespace.
+	PortForwards []*PortForward `json:"port_forwards,omitempty"`
+
+	// RootlessNetwork specifies the user-mode network stack to connect the
+	// container's network namespace to the host network with, if any.
+	RootlessNetwork *RootlessNetwork `json:"rootless_network,omitempty"`
+
 	// Cgroups specifies specific cgroup settings for the various subsystems that the container is
 	// placed into to limit the resources the container has available
 	Cgroups *Cgroup `json:"cgroups"`
 
+	// ExclusiveCPUs is the number of CPUs to allocate to the container for
+	// its exclusive use, from the CPU pool of the runc root directory. The
+	// allocated CPUs are set as Cgroups.Resources.CpusetCpus when the
+	// container is created, and returned to the pool once it is destroyed.
+	ExclusiveCPUs int `json:"exclusive_cpus,omitempty"`
+
+	// LaunchRecord makes runc measure what the container is launched with
+	// (its configuration, root filesystem, seccomp profile and LSM labels)
+	// when it is created, and keep the measurement in the container state.
+	LaunchRecord bool `json:"launch_record,omitempty"`
+
+	// LaunchRecordRootfs makes the launch record include the measurement
+	// of the root filesystem, which takes a while for the large ones, as
+	// all its files are read.
+	LaunchRecordRootfs bool `json:"launch_record_rootfs,omitempty"`
+
+	// ProcLimits makes runc bind mount files reflecting the cgroup limits
+	// of the container over /proc/meminfo, /proc/cpuinfo and
+	// /sys/devices/system/cpu/online, so that tools such as free(1) see
+	// the memory and CPUs the container is limited to rather than the ones
+	// of the host. The files are generated when the container is created,
+	// and again on runc update and runc exec.
+	ProcLimits bool `json:"proc_limits,omitempty"`
+
 	// AppArmorProfile specifies the profile to apply to the process running in the container and is
 	// change at the time the process is execed
 	AppArmorProfile string `json:"apparmor_profile,omitempty"`
@@ -178,6 +226,14 @@ type Config struct {
 	// sysctl -w my.property.name value in Linux.
 	Sysctl map[string]string `json:"sysctl"`
 
+	// NetSysctl are the typed network sysctls, which are written after
+	// (and must not conflict with) Sysctl.
+	NetSysctl *NetSysctl `json:"net_sysctl,omitempty"`
+
+	// Firewall is the network isolation policy installed in the
+	// container's network namespace, if any.
+	Firewall *Firewall `json:"firewall,omitempty"`
+
 	// Seccomp allows actions to be taken whenever a syscall is made within the container.
 	// A number of rules are given, each having an action to be taken if a syscall matches it.
 	// A default action to be taken if no rules match is also given.
@@ -190,6 +246,11 @@ type Config struct {
 	// CommandHooks are serialized to JSON, but other hooks are not.
 	Hooks Hooks
 
+	// InContainerHooks are run, in order, inside the container's namespaces
+	// once the container is created, before any poststart hook. See
+	// InContainerHook for details.
+	InContainerHooks []InContainerHook `json:"in_container_hooks,omitempty"`
+
 	// Version is the version of opencontainer specification that is supported.
 	Version string `json:"version"`
 
@@ -200,6 +261,11 @@ type Config struct {
 	// callers keyring in this case.
 	NoNewKeyring bool `json:"no_new_keyring"`
 
+	// AllowNamespaceOwnerMismatch makes the validation only warn about the
+	// namespaces to join which are owned by a user namespace the container
+	// has no privileges over, instead of failing.
+	AllowNamespaceOwnerMismatch bool `json:"allow_namespace_owner_mismatch,omitempty"`
+
 	// IntelRdt specifies settings for Intel RDT group that the container is placed into
 	// to limit the resources (e.g., L3 cache, memory bandwidth) the container has available
 	IntelRdt *IntelRdt `json:"intel_rdt,omitempty"`
@@ -217,6 +283,17 @@ type Config struct {
 	// TimeOffsets specifies the offset for supporting time namespaces.
 	TimeOffsets map[string]specs.LinuxTimeOffset `json:"time_offsets,omitempty"`
 
+	// Time is the time configuration (timezone) of the container.
+	Time *Time `json:"time,omitempty"`
+
+	// Stop is how the container is stopped (see Container.Stop). It can
+	// be changed after the container is created.
+	Stop *Stop `json:"stop,omitempty"`
+
+	// ExecRateLimit, if set, limits the rate at which processes can be
+	// started in the container (see Container.Start).
+	ExecRateLimit *ExecRateLimit `json:"exec_rate_limit,omitempty"`
+
 	// Scheduler represents the scheduling attributes for a process.
 	Scheduler *Scheduler `json:"scheduler,omitempty"`
 
@@ -319,6 +396,13 @@ const (
 	// Poststop commands are executed after the container init process exits.
 	// Poststop commands are called in the Runtime Namespace.
 	Poststop HookName = "poststop"
+
+	// NetworkRestore commands are executed once the container is restored,
+	// before its processes are resumed, with the network namespace of the restored container open as fd 3,
+	// for example to set up its interfaces and addresses again after a live
+	// migration. They are not part of the OCI runtime spec.
+	// NetworkRestore commands are called in the Runtime Namespace.
+	NetworkRestore HookName = "networkRestore"
 )
 
 // KnownHookNames returns the known hook names.
@@ -394,14 +478,20 @@ func (hooks *Hooks) MarshalJSON() ([]byte, error) {
 		return serializableHooks
 	}
 
-	return json.Marshal(map[string]interface{}{
+	m := map[string]interface{}{
 		"prestart":        serialize((*hooks)[Prestart]),
 		"createRuntime":   serialize((*hooks)[CreateRuntime]),
 		"createContainer": serialize((*hooks)[CreateContainer]),
 		"startContainer":  serialize((*hooks)[StartContainer]),
 		"poststart":       serialize((*hooks)[Poststart]),
 		"poststop":        serialize((*hooks)[Poststop]),
-	})
+	}
+	// The hooks which are not part of the OCI runtime spec are only
+	// serialized if there are any.
+	if len((*hooks)[NetworkRestore]) > 0 {
+		m[string(NetworkRestore)] = serialize((*hooks)[NetworkRestore])
+	}
+	return json.Marshal(m)
 }
 
 // Run executes all hooks for the given hook name.
@@ -416,11 +506,37 @@ func (hooks Hooks) Run(name HookName, state *specs.State) error {
 	return nil
 }
 
+// RunWithFiles executes all hooks for the given hook name, giving the files
+// to the hooks which take files (see FileHook), and ignoring them for others.
+func (hooks Hooks) RunWithFiles(name HookName, state *specs.State, files ...*os.File) error {
+	for i, h := range hooks[name] {
+		var err error
+		if fh, ok := h.(FileHook); ok {
+			err = fh.RunWithFiles(state, files)
+		} else {
+			err = h.Run(state)
+		}
+		if err != nil {
+			return fmt.Errorf("error running %s hook #%d: %w", name, i, err)
+		}
+	}
+
+	return nil
+}
+
 type Hook interface {
 	// Run executes the hook with the provided state.
 	Run(*specs.State) error
 }
 
+// FileHook is a Hook which can also be run with files.
+type FileHook interface {
+	Hook
+	// RunWithFiles executes the hook with the provided state, and the
+	// files open as fds 3 and up.
+	RunWithFiles(*specs.State, []*os.File) error
+}
+
 // NewFunctionHook will call the provided function when the hook is run.
 func NewFunctionHook(f func(*specs.State) error) FuncHook {
 	return FuncHook{
@@ -444,6 +560,20 @@ type Command struct {
 	Timeout *time.Duration `json:"timeout"`
 }
 
+// InContainerHook is a command which is executed inside the container's
+// namespaces rather than on the host. The executable (Path) is opened on the
+// host and executed in the container using execveat(2), so it does not have
+// to be present in the container's root filesystem. Args, Env, Dir and
+// Timeout have the same meaning as for CommandHook, except that Dir is a
+// path inside the container. As with other hooks, the container state is
+// passed to the hook on stdin.
+type InContainerHook struct {
+	Command
+	// Capabilities is the capability set of the hook process. If nil, the
+	// hook runs with no capabilities at all.
+	Capabilities *Capabilities `json:"capabilities,omitempty"`
+}
+
 // NewCommandHook will execute the provided command when the hook is run.
 func NewCommandHook(cmd Command) CommandHook {
 	return CommandHook{
@@ -456,18 +586,23 @@ type CommandHook struct {
 }
 
 func (c Command) Run(s *specs.State) error {
+	return c.RunWithFiles(s, nil)
+}
+
+func (c Command) RunWithFiles(s *specs.State, files []*os.File) error {
 	b, err := json.Marshal(s)
 	if err != nil {
 		return err
 	}
 	var stdout, stderr bytes.Buffer
 	cmd := exec.Cmd{
-		Path:   c.Path,
-		Args:   c.Args,
-		Env:    c.Env,
-		Stdin:  bytes.NewReader(b),
-		Stdout: &stdout,
-		Stderr: &stderr,
+		Path:       c.Path,
+		Args:       c.Args,
+		Env:        c.Env,
+		Stdin:      bytes.NewReader(b),
+		Stdout:     &stdout,
+		Stderr:     &stderr,
+		ExtraFiles: files,
 	}
 	if err := cmd.Start(); err != nil {
 		return err
@@ -81,6 +81,27 @@ func TestMarshalHooks(t *testing.T) {
 	}
 }
 
+func TestMarshalUnmarshalNetworkRestoreHooks(t *testing.T) {
+	hookCmd := configs.NewCommandHook(configs.Command{
+		Path: "/usr/libexec/cni/restore",
+	})
+	hook := configs.Hooks{
+		configs.Poststop:       configs.HookList{hookCmd},
+		configs.NetworkRestore: configs.HookList{hookCmd, hookCmd},
+	}
+	hooks, err := hook.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	umMhook := configs.Hooks{}
+	if err := umMhook.UnmarshalJSON(hooks); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(umMhook, hook) {
+		t.Errorf("Expected %+v to equal %+v", umMhook, hook)
+	}
+}
+
 func TestMarshalUnmarshalHooks(t *testing.T) {
 	timeout := time.Second
 
@@ -206,6 +227,49 @@ exit 0
 	}
 }
 
+func TestHooksRunWithFiles(t *testing.T) {
+	state := &specs.State{
+		Version: "1",
+		ID:      "1",
+		Status:  "running",
+		Pid:     1,
+		Bundle:  "/bundle",
+	}
+	out, err := os.CreateTemp(t.TempDir(), "out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	var funcRun bool
+	hooks := configs.Hooks{
+		configs.NetworkRestore: configs.HookList{
+			configs.NewCommandHook(configs.Command{
+				Path: "/bin/sh",
+				Args: []string{"sh", "-c", "echo hook >&3"},
+			}),
+			// A hook which does not take files is run as usual.
+			configs.NewFunctionHook(func(*specs.State) error {
+				funcRun = true
+				return nil
+			}),
+		},
+	}
+	if err := hooks.RunWithFiles(configs.NetworkRestore, state, out); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "hook\n" {
+		t.Errorf("expected the hook to write to fd 3, got %q", data)
+	}
+	if !funcRun {
+		t.Error("function hook not run")
+	}
+}
+
 func TestCommandHookRunTimeout(t *testing.T) {
 	state := &specs.State{
 		Version: "1",
@@ -0,0 +1,27 @@
+package configs
+
+import "math"
+
+// ExecRateLimit limits the rate at which processes can be started in the
+// container (that is, the rate of runc exec), to protect the host from
+// floods of them, such as the ones caused by too frequent health checks.
+//
+// It works as a token bucket: starting a process takes a token, the bucket
+// holds up to Burst tokens, and is refilled at Rate tokens per second.
+type ExecRateLimit struct {
+	// Rate is how many processes per second can be started in the long
+	// run.
+	Rate float64 `json:"rate"`
+
+	// Burst is how many processes can be started at once. If it is 0,
+	// the rate rounded up (but at least 1) is used.
+	Burst int `json:"burst,omitempty"`
+}
+
+// BucketSize returns the size of the token bucket (see Burst).
+func (l *ExecRateLimit) BucketSize() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return math.Max(1, math.Ceil(l.Rate))
+}
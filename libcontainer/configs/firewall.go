@@ -0,0 +1,29 @@
+package configs
+
+// Firewall is a minimal network isolation policy, installed as an nftables
+// ruleset (in a table of its own) in the container's network namespace. All
+// the incoming traffic is dropped, except for the one on the loopback
+// interface, the replies to the outgoing connections, the IPv6 neighbor
+// discovery, and the traffic which matches one of the Allow rules.
+type Firewall struct {
+	// Allow are the rules for the incoming traffic which is accepted.
+	Allow []*FirewallRule `json:"allow,omitempty"`
+}
+
+// FirewallRule matches the incoming traffic by its source network and
+// destination port. The empty fields match anything.
+type FirewallRule struct {
+	// Source is the source network, in CIDR notation, such as
+	// "10.0.0.0/8" or "fd00::/8".
+	Source string `json:"source,omitempty"`
+
+	// Protocol is the transport protocol, "tcp" or "udp".
+	Protocol string `json:"protocol,omitempty"`
+
+	// Port is the destination port, or the first one of the range
+	// Port-PortEnd if PortEnd is set. Requires Protocol.
+	Port uint16 `json:"port,omitempty"`
+
+	// PortEnd is the last destination port of the range.
+	PortEnd uint16 `json:"port_end,omitempty"`
+}
@@ -13,4 +13,18 @@ type IntelRdt struct {
 	// The unit of memory bandwidth is specified in "percentages" by
 	// default, and in "MBps" if MBA Software Controller is enabled.
 	MemBwSchema string `json:"memBwSchema,omitempty"`
+
+	// EnableMonitoring creates a monitoring group for the container, so
+	// that its cache occupancy and memory bandwidth (Intel RDT CMT and MBM)
+	// are monitored on their own, even in a CLOS shared with others. With
+	// no ClosID and no schema, the container stays in the root CLOS.
+	EnableMonitoring bool `json:"enableMonitoring,omitempty"`
+
+	// The flag to indicate if Intel RDT CMT is enabled. CMT (Cache Monitoring Technology) supports monitoring of
+	// the last-level cache (LLC) occupancy for the container.
+	EnableCMT bool `json:"enableCMT,omitempty"`
+
+	// The flag to indicate if Intel RDT MBM is enabled. MBM (Memory Bandwidth Monitoring) supports monitoring of
+	// total and local memory bandwidth for the container.
+	EnableMBM bool `json:"enableMBM,omitempty"`
 }
@@ -4,4 +4,13 @@ const (
 	// EXT_COPYUP is a directive to copy up the contents of a directory when
 	// a tmpfs is mounted over it.
 	EXT_COPYUP = 1 << iota //nolint:golint,revive // ignore "don't use ALL_CAPS" warning
+	// EXT_MANAGED is a directive to create the upper and work directories
+	// of an overlay mount in the state directory of the container (so that
+	// they are removed along with it). The files written to the mount are
+	// then kept on the file system of the state directory (usually the
+	// /run tmpfs, whose size is the host's), until the container is
+	// deleted, so they are neither limited by the container's resources,
+	// nor gone with its cgroup. It is not supported with user namespaces,
+	// nor with checkpoint/restore.
+	EXT_MANAGED //nolint:golint,revive // ignore "don't use ALL_CAPS" warning
 )
@@ -1,6 +1,16 @@
 package configs
 
-import "golang.org/x/sys/unix"
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// MountFdPrefix is the prefix of the mount sources which refer to a file
+// descriptor passed to runc, as fd://N, rather than to a path.
+const MountFdPrefix = "fd://"
 
 type MountIDMapping struct {
 	// Recursive indicates if the mapping needs to be recursive.
@@ -55,6 +65,10 @@ type Mount struct {
 	// Mapping is the MOUNT_ATTR_IDMAP configuration for the mount. If non-nil,
 	// the mount is configured to use MOUNT_ATTR_IDMAP-style id mappings.
 	IDMapping *MountIDMapping `json:"id_mapping,omitempty"`
+
+	// Driver is the path of the mount driver, an external binary which
+	// creates the mount rather than runc, if any.
+	Driver string `json:"driver,omitempty"`
 }
 
 func (m *Mount) IsBind() bool {
@@ -64,3 +78,18 @@ func (m *Mount) IsBind() bool {
 func (m *Mount) IsIDMapped() bool {
 	return m.IDMapping != nil
 }
+
+// IsFdSource tells whether the source of the mount is a file descriptor
+// passed to runc, rather than a path.
+func (m *Mount) IsFdSource() bool {
+	return strings.HasPrefix(m.Source, MountFdPrefix)
+}
+
+// SourceFd returns N for a mount with a fd://N source.
+func (m *Mount) SourceFd() (int, error) {
+	n, err := strconv.Atoi(strings.TrimPrefix(m.Source, MountFdPrefix))
+	if err != nil || n < 0 || !m.IsFdSource() {
+		return -1, fmt.Errorf("invalid mount source fd %q", m.Source)
+	}
+	return n, nil
+}
@@ -0,0 +1,46 @@
+package configs
+
+import "strconv"
+
+const (
+	// PingGroupRangeSysctl is the sysctl which sets PingGroupRange.
+	PingGroupRangeSysctl = "net.ipv4.ping_group_range"
+	// UnprivilegedPortStartSysctl is the sysctl which sets
+	// UnprivilegedPortStart.
+	UnprivilegedPortStartSysctl = "net.ipv4.ip_unprivileged_port_start"
+)
+
+// NetSysctl holds the commonly needed network sysctls of a container, which
+// can be set without resorting to raw sysctl strings. They are written in
+// the container's network namespace, together with the other sysctls.
+type NetSysctl struct {
+	// PingGroupRange is the range of the group IDs (in the container) which
+	// can create ICMP echo sockets, so that ping works without privileges.
+	PingGroupRange *GroupRange `json:"ping_group_range,omitempty"`
+
+	// UnprivilegedPortStart is the first port which can be bound to by
+	// unprivileged processes. Setting it to 0 allows binding to any port.
+	UnprivilegedPortStart *uint16 `json:"unprivileged_port_start,omitempty"`
+}
+
+// GroupRange is an inclusive range of group IDs.
+type GroupRange struct {
+	Start uint32 `json:"start"`
+	End   uint32 `json:"end"`
+}
+
+// Sysctl returns the sysctls (in the format of Config.Sysctl) which are
+// to be written for n. It is safe to call on a nil NetSysctl.
+func (n *NetSysctl) Sysctl() map[string]string {
+	if n == nil {
+		return nil
+	}
+	sysctl := make(map[string]string)
+	if r := n.PingGroupRange; r != nil {
+		sysctl[PingGroupRangeSysctl] = strconv.FormatUint(uint64(r.Start), 10) + " " + strconv.FormatUint(uint64(r.End), 10)
+	}
+	if p := n.UnprivilegedPortStart; p != nil {
+		sysctl[UnprivilegedPortStartSysctl] = strconv.FormatUint(uint64(*p), 10)
+	}
+	return sysctl
+}
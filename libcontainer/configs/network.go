@@ -5,7 +5,9 @@ package configs
 // The network configuration can be omitted from a container causing the
 // container to be setup with the host's networking stack
 type Network struct {
-	// Type sets the networks type, commonly veth and loopback
+	// Type sets the networks type: loopback, veth (a veth pair, with the
+	// host end attached to Bridge) or macvlan (a macvlan interface on top
+	// of the Parent interface of the host)
 	Type string `json:"type"`
 
 	// Name of the network interface
@@ -14,6 +16,14 @@ type Network struct {
 	// The bridge to use.
 	Bridge string `json:"bridge"`
 
+	// Parent is the host interface the macvlan interface is created on, in
+	// the case of type macvlan.
+	Parent string `json:"parent,omitempty"`
+
+	// MacvlanMode is the mode of the macvlan interface: bridge (the
+	// default), private, vepa or passthru, in the case of type macvlan.
+	MacvlanMode string `json:"macvlan_mode,omitempty"`
+
 	// MacAddress contains the MAC address to set on the network interface
 	MacAddress string `json:"mac_address"`
 
@@ -73,3 +83,50 @@ type Route struct {
 	// InterfaceName specifies the device to set this route up for, for example eth0.
 	InterfaceName string `json:"interface_name"`
 }
+
+// PortForward defines a port of the host forwarded into the container's
+// network namespace.
+//
+// runc listens on the host port when the container is created, and a helper
+// process proxies the connections (or datagrams) it gets to the container
+// port, from inside the network namespace of the container. As this needs
+// no privilege on the host, it is how rootless containers can expose their
+// ports.
+type PortForward struct {
+	// Protocol is the protocol of the port: tcp (the default) or udp.
+	Protocol string `json:"protocol,omitempty"`
+
+	// HostIP is the host address to listen on. All the addresses of the
+	// host are listened on if empty.
+	HostIP string `json:"host_ip,omitempty"`
+
+	// HostPort is the host port to listen on.
+	HostPort uint16 `json:"host_port"`
+
+	// ContainerIP is the container address to forward to, 127.0.0.1 if
+	// empty.
+	ContainerIP string `json:"container_ip,omitempty"`
+
+	// ContainerPort is the container port to forward to.
+	ContainerPort uint16 `json:"container_port"`
+}
+
+// RootlessNetwork defines the user-mode network stack connecting the
+// network namespace of the container to the network of the host, which
+// needs no privilege on the host (and is typically used for rootless
+// containers).
+//
+// runc starts the network stack once the network namespace of the container
+// is created, and stops it when the container is deleted.
+type RootlessNetwork struct {
+	// Mode is the network stack: slirp4netns or pasta.
+	Mode string `json:"mode"`
+
+	// Path is the path of the network stack binary. The binary named after
+	// the mode is looked up in PATH if empty.
+	Path string `json:"path,omitempty"`
+
+	// Args are the additional arguments of the network stack binary, such
+	// as "--disable-host-loopback" for slirp4netns.
+	Args []string `json:"args,omitempty"`
+}
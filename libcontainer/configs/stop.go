@@ -0,0 +1,20 @@
+package configs
+
+import "time"
+
+// DefaultStopGracePeriod is how long a container is given to exit after
+// the stop signal is sent to it, before it is killed, unless configured
+// otherwise.
+const DefaultStopGracePeriod = 10 * time.Second
+
+// Stop is how the container is stopped: the stop signal is sent to the
+// container init and, if the container does not exit within the grace
+// period, it is killed with SIGKILL.
+type Stop struct {
+	// Signal is the stop signal. If it is 0, SIGTERM is used.
+	Signal int `json:"signal,omitempty"`
+
+	// GracePeriod is how long to wait for the container to exit after
+	// sending the stop signal. If it is 0, DefaultStopGracePeriod is used.
+	GracePeriod time.Duration `json:"grace_period,omitempty"`
+}
@@ -0,0 +1,80 @@
+package configs
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ipcSysctls are the sysctls of the IPC namespace, other than the
+// "fs.mqueue." ones.
+var ipcSysctls = map[string]bool{
+	"kernel.msgmax":          true,
+	"kernel.msgmnb":          true,
+	"kernel.msgmni":          true,
+	"kernel.sem":             true,
+	"kernel.shmall":          true,
+	"kernel.shmmax":          true,
+	"kernel.shmmni":          true,
+	"kernel.shm_rmid_forced": true,
+}
+
+// SysctlNamespace returns the type of the namespace the sysctl key (in the
+// dot separated form, such as "net.ipv4.ip_forward") belongs to, or an
+// empty type if it is not known to belong to any.
+func SysctlNamespace(key string) NamespaceType {
+	switch {
+	case ipcSysctls[key] || strings.HasPrefix(key, "fs.mqueue."):
+		return NEWIPC
+	case strings.HasPrefix(key, "net."):
+		return NEWNET
+	case key == "kernel.hostname" || key == "kernel.domainname":
+		return NEWUTS
+	}
+	return ""
+}
+
+// RejectedSysctl is a sysctl of the container which can't be set.
+type RejectedSysctl struct {
+	// Key is the sysctl, in the dot separated form.
+	Key string
+	// Err is why the sysctl can't be set.
+	Err error
+}
+
+// SysctlError is the error returned for the sysctls of the container which
+// can't be set, listing all of them (not only the first one) along with
+// the reason.
+type SysctlError struct {
+	Rejected []RejectedSysctl
+}
+
+func (e *SysctlError) Error() string {
+	var b strings.Builder
+	b.WriteString(strconv.Itoa(len(e.Rejected)))
+	if len(e.Rejected) == 1 {
+		b.WriteString(" sysctl rejected: ")
+	} else {
+		b.WriteString(" sysctls rejected: ")
+	}
+	for i, r := range e.Rejected {
+		if i > 0 {
+			b.WriteString("; ")
+		}
+		b.WriteString(strconv.Quote(r.Key) + ": " + r.Err.Error())
+	}
+	return b.String()
+}
+
+// Add adds the sysctl key, rejected because of err, to e.
+func (e *SysctlError) Add(key string, err error) {
+	e.Rejected = append(e.Rejected, RejectedSysctl{Key: key, Err: err})
+}
+
+// Unwrap returns the errors of the rejected sysctls.
+func (e *SysctlError) Unwrap() []error {
+	errs := make([]error, len(e.Rejected))
+	for i, r := range e.Rejected {
+		errs[i] = r.Err
+	}
+	return errs
+}
@@ -0,0 +1,44 @@
+package configs
+
+import "path/filepath"
+
+const (
+	// ZoneinfoDir is the directory of the host's timezone database.
+	ZoneinfoDir = "/usr/share/zoneinfo"
+	// LocaltimePath is the path in the container where the timezone file
+	// is bind mounted.
+	LocaltimePath = "/etc/localtime"
+	// LocaltimeEnv is the value of the TZ environment variable which is set
+	// for the container processes if the timezone is configured, so that
+	// they all use the bind mounted timezone file.
+	LocaltimeEnv = "TZ=:" + LocaltimePath
+)
+
+// Time is the time configuration of a container. Setting the timezone
+// makes runc bind mount the timezone file (read-only) onto /etc/localtime
+// in the container, and set TZ for the container processes accordingly. If
+// /etc/localtime is a symlink in the rootfs, it is replaced by the mount
+// point, so that the file it points to is left as is.
+type Time struct {
+	// Timezone is the name of the container's timezone in the host's
+	// timezone database, such as "Europe/Berlin".
+	Timezone string `json:"timezone,omitempty"`
+
+	// TimezoneFile is the path of the container's timezone file (in the
+	// tzfile(5) format) on the host. It is an alternative to Timezone.
+	TimezoneFile string `json:"timezone_file,omitempty"`
+}
+
+// TimezoneSource returns the path of the container's timezone file on the
+// host, or an empty string if the timezone is not configured.
+func (t *Time) TimezoneSource() string {
+	switch {
+	case t == nil:
+		return ""
+	case t.TimezoneFile != "":
+		return t.TimezoneFile
+	case t.Timezone != "":
+		return filepath.Join(ZoneinfoDir, t.Timezone)
+	}
+	return ""
+}
@@ -0,0 +1,121 @@
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/szcdx/runc/libcontainer/configs"
+)
+
+// ErrNamespaceOwner is returned, wrapped in a *NamespaceOwnerError, by
+// Validate if a namespace the container is to join can not be joined, as
+// it belongs to a user namespace the container has no privileges over.
+var ErrNamespaceOwner = errors.New("namespace is owned by another user namespace")
+
+// NamespaceOwnerError is the error returned if a namespace to join (other
+// than the user namespace) is owned by a user namespace which is neither the
+// one the namespaces are joined from, nor one of its descendants. Joining it
+// would fail with EPERM.
+type NamespaceOwnerError struct {
+	// Type is the type of the namespace.
+	Type configs.NamespaceType
+	// Path is the path of the namespace.
+	Path string
+	// UserNSPath is the path of the user namespace the container joins,
+	// or "" if the namespaces are joined from the user namespace of runc.
+	UserNSPath string
+}
+
+func (e *NamespaceOwnerError) Error() string {
+	userns := "the user namespace of runc"
+	if e.UserNSPath != "" {
+		userns = "user namespace " + e.UserNSPath
+	}
+	return fmt.Sprintf("unable to join %s namespace %s: %v, not by %s or one of its descendants", configs.NsName(e.Type), e.Path, ErrNamespaceOwner, userns)
+}
+
+func (e *NamespaceOwnerError) Unwrap() error {
+	return ErrNamespaceOwner
+}
+
+// namespaceOwnerCheck checks that the namespaces to join can be joined: the
+// user namespace is joined first (or, when a new one is created, after the
+// other namespaces), and the other namespaces can only be joined from the
+// user namespace which owns them or one of its ancestors.
+func namespaceOwnerCheck(config *configs.Config) error {
+	userns := config.Namespaces.PathOf(configs.NEWUSER)
+	ref := userns
+	if ref == "" {
+		ref = "/proc/self/ns/user"
+	}
+	var refSt unix.Stat_t
+	for _, ns := range config.Namespaces {
+		if ns.Path == "" || ns.Type == configs.NEWUSER {
+			continue
+		}
+		if refSt.Ino == 0 {
+			if err := unix.Stat(ref, &refSt); err != nil {
+				return &os.PathError{Op: "stat", Path: ref, Err: err}
+			}
+		}
+		owned, err := isOwnedBy(ns.Path, &refSt)
+		if err != nil {
+			// ENOTTY means the kernel (before 4.9) can not tell the
+			// owner, or the path is not a namespace, which setns
+			// reports.
+			if errors.Is(err, unix.ENOTTY) {
+				continue
+			}
+			return fmt.Errorf("%s namespace %s: %w", configs.NsName(ns.Type), ns.Path, err)
+		}
+		if !owned {
+			err := &NamespaceOwnerError{Type: ns.Type, Path: ns.Path, UserNSPath: userns}
+			if config.AllowNamespaceOwnerMismatch {
+				logrus.Warn(err)
+				continue
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// isOwnedBy returns whether the namespace at path is owned by the user
+// namespace ref, or one of its descendants.
+func isOwnedBy(path string, ref *unix.Stat_t) (bool, error) {
+	fd, err := unix.Open(path, unix.O_RDONLY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return false, &os.PathError{Op: "open", Path: path, Err: err}
+	}
+	defer unix.Close(fd)
+	owner, err := unix.IoctlRetInt(fd, unix.NS_GET_USERNS)
+	if err != nil {
+		return false, os.NewSyscallError("ioctl NS_GET_USERNS", err)
+	}
+	for {
+		var st unix.Stat_t
+		if err := unix.Fstat(owner, &st); err != nil {
+			unix.Close(owner)
+			return false, os.NewSyscallError("fstat", err)
+		}
+		if st.Dev == ref.Dev && st.Ino == ref.Ino {
+			unix.Close(owner)
+			return true, nil
+		}
+		parent, err := unix.IoctlRetInt(owner, unix.NS_GET_PARENT)
+		unix.Close(owner)
+		if err != nil {
+			// EPERM means there is no parent runc can see: the
+			// top of its user namespace hierarchy is reached.
+			if errors.Is(err, unix.EPERM) {
+				return false, nil
+			}
+			return false, os.NewSyscallError("ioctl NS_GET_PARENT", err)
+		}
+		owner = parent
+	}
+}
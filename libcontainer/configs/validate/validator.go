@@ -3,8 +3,12 @@ package validate
 import (
 	"errors"
 	"fmt"
+	"math"
+	"net"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -13,12 +17,34 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/szcdx/runc/libcontainer/cgroups"
 	"github.com/szcdx/runc/libcontainer/configs"
+	"github.com/szcdx/runc/libcontainer/firewall"
 	"github.com/szcdx/runc/libcontainer/intelrdt"
+	"github.com/szcdx/runc/libcontainer/seccomp"
 	"golang.org/x/sys/unix"
 )
 
 type check func(config *configs.Config) error
 
+// ErrInvalidConfig is matched (by errors.Is) by the errors Validate returns.
+var ErrInvalidConfig = errors.New("invalid configuration")
+
+// configError is an error Validate returns, which it wraps as is.
+type configError struct {
+	err error
+}
+
+func (e *configError) Error() string {
+	return e.err.Error()
+}
+
+func (e *configError) Unwrap() error {
+	return e.err
+}
+
+func (e *configError) Is(target error) bool {
+	return target == ErrInvalidConfig
+}
+
 func Validate(config *configs.Config) error {
 	checks := []check{
 		cgroupsCheck,
@@ -26,16 +52,24 @@ func Validate(config *configs.Config) error {
 		network,
 		uts,
 		security,
+		seccompCheck,
 		namespaces,
+		namespaceOwnerCheck,
 		sysctl,
+		netSysctlCheck,
+		firewallCheck,
 		intelrdtCheck,
 		rootlessEUIDCheck,
 		mountsStrict,
 		scheduler,
+		timeCheck,
+		exclusiveCPUsCheck,
+		stopCheck,
+		execRateLimitCheck,
 	}
 	for _, c := range checks {
 		if err := c(config); err != nil {
-			return err
+			return &configError{err: err}
 		}
 	}
 	// Relaxed validation rules for backward compatibility
@@ -75,15 +109,147 @@ func network(config *configs.Config) error {
 			return errors.New("unable to apply network settings without a private NET namespace")
 		}
 	}
+	for _, n := range config.Networks {
+		if err := checkNetwork(config, n); err != nil {
+			return fmt.Errorf("%s network %s: %w", n.Type, n.Name, err)
+		}
+	}
+	if len(config.PortForwards) > 0 && !config.Namespaces.Contains(configs.NEWNET) {
+		return errors.New("unable to forward ports without a private NET namespace")
+	}
+	seen := make(map[string]bool)
+	for _, p := range config.PortForwards {
+		if err := checkPortForward(p); err != nil {
+			return fmt.Errorf("port forward %s:%d: %w", p.HostIP, p.HostPort, err)
+		}
+		// The same port can't be listened on twice.
+		proto := p.Protocol
+		if proto == "" {
+			proto = "tcp"
+		}
+		key := proto + "/" + net.JoinHostPort(p.HostIP, strconv.Itoa(int(p.HostPort)))
+		if seen[key] {
+			return fmt.Errorf("port forward %s:%d: duplicated host port", p.HostIP, p.HostPort)
+		}
+		seen[key] = true
+	}
+	if n := config.RootlessNetwork; n != nil {
+		switch n.Mode {
+		case "slirp4netns", "pasta":
+		default:
+			return fmt.Errorf("invalid rootless network mode %q", n.Mode)
+		}
+		if !config.Namespaces.Contains(configs.NEWNET) || config.Namespaces.PathOf(configs.NEWNET) != "" {
+			return errors.New("rootless network can only be set up in a new network namespace")
+		}
+		if n.Path != "" && !filepath.IsAbs(n.Path) {
+			return fmt.Errorf("rootless network binary path %q must be absolute", n.Path)
+		}
+	}
+	return nil
+}
+
+// checkPortForward validates a forwarded port of the container.
+func checkPortForward(p *configs.PortForward) error {
+	switch p.Protocol {
+	case "", "tcp", "udp":
+	default:
+		return fmt.Errorf("invalid protocol %q", p.Protocol)
+	}
+	if p.HostPort == 0 || p.ContainerPort == 0 {
+		return errors.New("the host and container ports must be set")
+	}
+	for _, ip := range []string{p.HostIP, p.ContainerIP} {
+		if ip != "" && net.ParseIP(ip) == nil {
+			return fmt.Errorf("invalid address %q", ip)
+		}
+	}
+	return nil
+}
+
+// checkNetwork validates a network interface of the container.
+func checkNetwork(config *configs.Config, n *configs.Network) error {
+	switch n.Type {
+	case "loopback":
+		return nil
+	case "veth":
+		if n.Bridge == "" || n.HostInterfaceName == "" {
+			return errors.New("the bridge and host interface name must be set")
+		}
+	case "macvlan":
+		if n.Parent == "" {
+			return errors.New("the parent interface must be set")
+		}
+		switch n.MacvlanMode {
+		case "", "bridge", "private", "vepa", "passthru":
+		default:
+			return fmt.Errorf("invalid macvlan mode %q", n.MacvlanMode)
+		}
+	default:
+		return errors.New("unknown network type")
+	}
+	if n.Name == "" {
+		return errors.New("the interface name must be set")
+	}
+	if config.Namespaces.PathOf(configs.NEWNET) != "" {
+		return errors.New("interfaces can only be created in a new network namespace")
+	}
+	if config.RootlessEUID {
+		return errors.New("interfaces can't be created for rootless containers")
+	}
+	for _, addr := range []string{n.Address, n.IPv6Address} {
+		if addr == "" {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(addr); err != nil {
+			return fmt.Errorf("invalid address %q", addr)
+		}
+	}
+	for _, gw := range []string{n.Gateway, n.IPv6Gateway} {
+		if gw != "" && net.ParseIP(gw) == nil {
+			return fmt.Errorf("invalid gateway %q", gw)
+		}
+	}
+	if n.MacAddress != "" {
+		if _, err := net.ParseMAC(n.MacAddress); err != nil {
+			return fmt.Errorf("invalid MAC address %q", n.MacAddress)
+		}
+	}
 	return nil
 }
 
+// maxUTSNameLen is the maximum length of the host and domain names
+// (__NEW_UTS_LEN in the kernel).
+const maxUTSNameLen = 64
+
 func uts(config *configs.Config) error {
-	if config.Hostname != "" && !config.Namespaces.Contains(configs.NEWUTS) {
-		return errors.New("unable to set hostname without a private UTS namespace")
+	names := []struct{ kind, value string }{
+		{"hostname", config.Hostname},
+		{"domainname", config.Domainname},
+	}
+	for _, n := range names {
+		if n.value == "" {
+			continue
+		}
+		if !config.Namespaces.Contains(configs.NEWUTS) {
+			return fmt.Errorf("unable to set %s without a private UTS namespace", n.kind)
+		}
+		if len(n.value) > maxUTSNameLen {
+			return fmt.Errorf("invalid %s %q: longer than %d bytes", n.kind, n.value, maxUTSNameLen)
+		}
+		if strings.IndexByte(n.value, 0) != -1 {
+			return fmt.Errorf("invalid %s %q: contains a NUL byte", n.kind, n.value)
+		}
+		// A UTS namespace which is joined, rather than created, is not
+		// owned by a user namespace created for the container, so the
+		// names can not be changed in it from there.
+		if config.Namespaces.PathOf(configs.NEWUTS) != "" &&
+			config.Namespaces.Contains(configs.NEWUSER) && config.Namespaces.PathOf(configs.NEWUSER) == "" {
+			return fmt.Errorf("unable to set %s in a joined UTS namespace from a new user namespace", n.kind)
+		}
 	}
-	if config.Domainname != "" && !config.Namespaces.Contains(configs.NEWUTS) {
-		return errors.New("unable to set domainname without a private UTS namespace")
+	if v, ok := config.Sysctl["kernel.domainname"]; ok && config.Domainname != "" && v != config.Domainname {
+		return fmt.Errorf("sysctl %q (%q) conflicts with the OCI %q field (%q)", "kernel.domainname", v, "domainname", config.Domainname)
 	}
 	return nil
 }
@@ -101,6 +267,26 @@ func security(config *configs.Config) error {
 	return nil
 }
 
+// seccompCheck validates the seccomp profile, so that a bad profile fails
+// the container creation rather than runc init.
+func seccompCheck(config *configs.Config) error {
+	var errs []error
+	for _, err := range seccomp.Validate(config.Seccomp) {
+		var w *seccomp.Warning
+		if errors.As(err, &w) {
+			// Those are usually harmless, and common in real world
+			// profiles, so don't be too loud about them.
+			logrus.Debugf("seccomp: %v", w)
+			continue
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid seccomp profile: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
 func namespaces(config *configs.Config) error {
 	if config.Namespaces.Contains(configs.NEWUSER) {
 		if _, err := os.Stat("/proc/self/ns/user"); os.IsNotExist(err) {
@@ -177,33 +363,42 @@ func convertSysctlVariableToDotsSeparator(val string) string {
 // /proc/sys isn't completely namespaced and depending on which namespaces
 // are specified, a subset of sysctls are permitted.
 func sysctl(config *configs.Config) error {
-	validSysctlMap := map[string]bool{
-		"kernel.msgmax":          true,
-		"kernel.msgmnb":          true,
-		"kernel.msgmni":          true,
-		"kernel.sem":             true,
-		"kernel.shmall":          true,
-		"kernel.shmmax":          true,
-		"kernel.shmmni":          true,
-		"kernel.shm_rmid_forced": true,
-	}
-
 	var (
 		netOnce    sync.Once
 		hostnet    bool
 		hostnetErr error
 	)
 
+	netSysctl := config.NetSysctl.Sysctl()
+	keys := make([]string, 0, len(config.Sysctl)+len(netSysctl))
+	conflicts := make(map[string]bool)
 	for s := range config.Sysctl {
 		s := convertSysctlVariableToDotsSeparator(s)
-		if validSysctlMap[s] || strings.HasPrefix(s, "fs.mqueue.") {
-			if config.Namespaces.Contains(configs.NEWIPC) {
-				continue
-			} else {
-				return fmt.Errorf("sysctl %q is not allowed in the hosts ipc namespace", s)
-			}
+		if _, ok := netSysctl[s]; ok {
+			conflicts[s] = true
+			continue
 		}
-		if strings.HasPrefix(s, "net.") {
+		keys = append(keys, s)
+	}
+	for s := range netSysctl {
+		keys = append(keys, s)
+	}
+	sort.Strings(keys)
+
+	// All the sysctls are checked, so that the error lists every rejected
+	// one rather than the first.
+	rejected := new(configs.SysctlError)
+	for _, s := range keys {
+		if conflicts[s] {
+			rejected.Add(s, errors.New("conflicts with the typed network sysctl setting it"))
+			continue
+		}
+		switch configs.SysctlNamespace(s) {
+		case configs.NEWIPC:
+			if !config.Namespaces.Contains(configs.NEWIPC) {
+				rejected.Add(s, errors.New("not allowed in the host's IPC namespace"))
+			}
+		case configs.NEWNET:
 			// Is container using host netns?
 			// Here "host" means "current", not "initial".
 			netOnce.Do(func() {
@@ -219,29 +414,62 @@ func sysctl(config *configs.Config) error {
 				hostnet, hostnetErr = isHostNetNS(path)
 			})
 			if hostnetErr != nil {
-				return fmt.Errorf("invalid netns path: %w", hostnetErr)
+				rejected.Add(s, fmt.Errorf("invalid netns path: %w", hostnetErr))
+			} else if hostnet {
+				rejected.Add(s, errors.New("not allowed in the host's network namespace"))
 			}
-			if hostnet {
-				return fmt.Errorf("sysctl %q not allowed in host network namespace", s)
-			}
-			continue
-		}
-		if config.Namespaces.Contains(configs.NEWUTS) {
-			switch s {
-			case "kernel.domainname":
-				// This is namespaced and there's no explicit OCI field for it.
-				continue
-			case "kernel.hostname":
+		case configs.NEWUTS:
+			if !config.Namespaces.Contains(configs.NEWUTS) {
+				rejected.Add(s, errors.New("not allowed in the host's UTS namespace"))
+			} else if s == "kernel.hostname" {
 				// This is namespaced but there's a conflicting (dedicated) OCI field for it.
-				return fmt.Errorf("sysctl %q is not allowed as it conflicts with the OCI %q field", s, "hostname")
+				rejected.Add(s, errors.New(`conflicts with the OCI "hostname" field`))
 			}
+		default:
+			rejected.Add(s, errors.New("not in a separate kernel namespace"))
 		}
-		return fmt.Errorf("sysctl %q is not in a separate kernel namespace", s)
 	}
+	if len(rejected.Rejected) > 0 {
+		return rejected
+	}
+	return nil
+}
 
+// netSysctlCheck validates the typed network sysctls.
+func netSysctlCheck(config *configs.Config) error {
+	n := config.NetSysctl
+	if n == nil || n.PingGroupRange == nil {
+		return nil
+	}
+	r := n.PingGroupRange
+	// The kernel limits the group IDs to GID_T_MAX.
+	if r.Start > r.End || r.End > math.MaxInt32 {
+		return fmt.Errorf("invalid ping group range %d-%d", r.Start, r.End)
+	}
+	// The group IDs have to be mapped in the user namespace owning the
+	// network namespace.
+	if config.Namespaces.Contains(configs.NEWUSER) && config.Namespaces.PathOf(configs.NEWUSER) == "" {
+		for _, gid := range []uint32{r.Start, r.End} {
+			if _, err := config.HostGID(int(gid)); err != nil {
+				return fmt.Errorf("invalid ping group range %d-%d: %w", r.Start, r.End, err)
+			}
+		}
+	}
 	return nil
 }
 
+// firewallCheck validates the firewall, which is only allowed in a network
+// namespace other than the host one.
+func firewallCheck(config *configs.Config) error {
+	if config.Firewall == nil {
+		return nil
+	}
+	if !config.Namespaces.Contains(configs.NEWNET) {
+		return errors.New("firewall requires a network namespace")
+	}
+	return firewall.Validate(config.Firewall)
+}
+
 func intelrdtCheck(config *configs.Config) error {
 	if config.IntelRdt != nil {
 		if config.IntelRdt.ClosID == "." || config.IntelRdt.ClosID == ".." || strings.Contains(config.IntelRdt.ClosID, "/") {
@@ -254,6 +482,15 @@ func intelrdtCheck(config *configs.Config) error {
 		if !intelrdt.IsMBAEnabled() && config.IntelRdt.MemBwSchema != "" {
 			return errors.New("intelRdt.memBwSchema is specified in config, but Intel RDT/MBA is not enabled")
 		}
+		if config.IntelRdt.EnableCMT && !intelrdt.IsCMTEnabled() {
+			return errors.New("intelRdt.enableCMT is specified in config, but Intel RDT/CMT is not enabled")
+		}
+		if config.IntelRdt.EnableMBM && !intelrdt.IsMBMEnabled() {
+			return errors.New("intelRdt.enableMBM is specified in config, but Intel RDT/MBM is not enabled")
+		}
+		if config.IntelRdt.EnableMonitoring && !intelrdt.IsMBMEnabled() && !intelrdt.IsCMTEnabled() {
+			return errors.New("intelRdt.enableMonitoring is specified in config, but neither Intel RDT/MBM nor CMT is enabled")
+		}
 	}
 
 	return nil
@@ -269,6 +506,10 @@ func cgroupsCheck(config *configs.Config) error {
 		return fmt.Errorf("cgroup: either Path or Name and Parent should be used, got %+v", c)
 	}
 
+	if err := cgroupDelegateCheck(config); err != nil {
+		return err
+	}
+
 	r := c.Resources
 	if r == nil {
 		return nil
@@ -283,8 +524,94 @@ func cgroupsCheck(config *configs.Config) error {
 		if err != nil {
 			return err
 		}
+		if _, err := cgroups.ParseUnified(r.Unified, r.UnifiedAllowUnknown); err != nil {
+			return err
+		}
+	}
+
+	if r.CPUIdle != nil && *r.CPUIdle != 0 && *r.CPUIdle != 1 {
+		return fmt.Errorf("invalid cpu idle value %d: must be 0 or 1", *r.CPUIdle)
+	}
+
+	switch r.HugetlbAccounting {
+	case "", "fault", "reservation":
+	default:
+		return fmt.Errorf("invalid hugetlb accounting %q: must be fault or reservation", r.HugetlbAccounting)
+	}
+
+	if r.DevicesPinDir != "" {
+		if !cgroups.IsCgroup2UnifiedMode() {
+			return errors.New("pinning the device filter requires cgroup v2")
+		}
+		if !filepath.IsAbs(r.DevicesPinDir) {
+			return fmt.Errorf("device filter pin directory %q must be an absolute path", r.DevicesPinDir)
+		}
 	}
 
+	if err := ioLatencyCheck(config); err != nil {
+		return err
+	}
+
+	return cpusetPartitionCheck(config)
+}
+
+// cgroupDelegateCheck checks that the cgroup can be delegated to the
+// container (see configs.Cgroup.Delegate).
+func cgroupDelegateCheck(config *configs.Config) error {
+	c := config.Cgroups
+	if !c.Delegate {
+		return nil
+	}
+	if !cgroups.IsCgroup2UnifiedMode() {
+		return errors.New("cgroup delegation requires cgroup v2")
+	}
+	if !config.Namespaces.Contains(configs.NEWUSER) {
+		return errors.New("cgroup delegation requires a user namespace")
+	}
+	if !config.Namespaces.Contains(configs.NEWCGROUP) || config.Namespaces.PathOf(configs.NEWCGROUP) != "" {
+		return errors.New("cgroup delegation requires a new cgroup namespace")
+	}
+	if c.OwnerUID == nil || c.OwnerGID == nil {
+		return errors.New("cgroup delegation requires the cgroup owner to be set")
+	}
+	return nil
+}
+
+// cpusetPartitionCheck validates the cpuset partition of the container's
+// cgroup. The constraints which depend on the parent cgroup are checked by
+// the cgroup manager before the partition is set.
+func cpusetPartitionCheck(config *configs.Config) error {
+	r := config.Cgroups.Resources
+	switch r.CpusetPartition {
+	case "":
+		return nil
+	case "member", "root", "isolated":
+	default:
+		return fmt.Errorf("invalid cpuset partition %q", r.CpusetPartition)
+	}
+	if !cgroups.IsCgroup2UnifiedMode() {
+		return errors.New("cpuset partitions require cgroup v2")
+	}
+	if _, ok := r.Unified["cpuset.cpus.partition"]; ok {
+		return errors.New("cpuset partition can't be set both by the typed field and in the unified map")
+	}
+	if r.CpusetPartition == "member" {
+		return nil
+	}
+	// A partition root must be given the CPUs for its exclusive use, and
+	// they have to be set before the partition (which is not the case for
+	// the unified map, written last).
+	if _, ok := r.Unified["cpuset.cpus"]; ok {
+		return fmt.Errorf("cpuset partition %q can't be used together with cpuset.cpus in the unified map", r.CpusetPartition)
+	}
+	if r.CpusetCpus == "" && config.ExclusiveCPUs == 0 {
+		return fmt.Errorf("cpuset partition %q requires cpuset CPUs to be set", r.CpusetPartition)
+	}
+	if r.CpusetCpus != "" {
+		if _, err := cgroups.ParseCPUList(r.CpusetCpus); err != nil {
+			return fmt.Errorf("invalid cpuset CPUs %q: %w", r.CpusetCpus, err)
+		}
+	}
 	return nil
 }
 
@@ -308,6 +635,120 @@ func checkBindOptions(m *configs.Mount) error {
 	return nil
 }
 
+// checkTmpfsOptions validates the tmpfs size=, mode=, uid= and gid=
+// options of m, the ids being checked to be mapped in the user namespace of
+// the container, if any.
+func checkTmpfsOptions(config *configs.Config, m *configs.Mount) error {
+	if m.Device != "tmpfs" {
+		if m.Extensions&configs.EXT_COPYUP != 0 {
+			return errors.New("tmpcopyup is only supported for tmpfs mounts")
+		}
+		return nil
+	}
+	for _, o := range strings.Split(m.Data, ",") {
+		key, value, _ := strings.Cut(o, "=")
+		switch key {
+		case "size":
+			// A size in bytes, with an optional k, m, g, t, p or e
+			// suffix, or a percentage of the RAM.
+			num := strings.TrimRight(value, "kKmMgGtTpPeE%")
+			if len(value)-len(num) > 1 {
+				return fmt.Errorf("invalid tmpfs size %q", value)
+			}
+			if _, err := strconv.ParseUint(num, 10, 64); err != nil {
+				return fmt.Errorf("invalid tmpfs size %q", value)
+			}
+		case "mode":
+			if mode, err := strconv.ParseUint(value, 8, 32); err != nil || mode > 0o7777 {
+				return fmt.Errorf("invalid tmpfs mode %q", value)
+			}
+		case "uid":
+			uid, err := strconv.Atoi(value)
+			if err != nil || uid < 0 {
+				return fmt.Errorf("invalid tmpfs uid %q", value)
+			}
+			if _, err := config.HostUID(uid); err != nil {
+				return fmt.Errorf("tmpfs uid=%d: %w", uid, err)
+			}
+		case "gid":
+			gid, err := strconv.Atoi(value)
+			if err != nil || gid < 0 {
+				return fmt.Errorf("invalid tmpfs gid %q", value)
+			}
+			if _, err := config.HostGID(gid); err != nil {
+				return fmt.Errorf("tmpfs gid=%d: %w", gid, err)
+			}
+		}
+	}
+	return nil
+}
+
+func checkFdSource(m *configs.Mount) error {
+	if !m.IsFdSource() {
+		return nil
+	}
+	if _, err := m.SourceFd(); err != nil {
+		return err
+	}
+	switch {
+	case !m.IsBind():
+		return fmt.Errorf("%s sources are only supported for bind mounts", configs.MountFdPrefix)
+	case m.IsIDMapped():
+		return fmt.Errorf("%s sources are not supported for id-mapped mounts", configs.MountFdPrefix)
+	case m.Relabel != "":
+		return fmt.Errorf("%s sources can't be relabeled", configs.MountFdPrefix)
+	}
+	return nil
+}
+
+func checkMountDriver(m *configs.Mount) error {
+	if m.Driver == "" {
+		return nil
+	}
+	if !filepath.IsAbs(m.Driver) {
+		return fmt.Errorf("mount driver %q is not an absolute path", m.Driver)
+	}
+	switch {
+	case m.IsBind(), m.IsIDMapped():
+		return errors.New("mount drivers are not supported for bind mounts")
+	case m.Extensions&(configs.EXT_COPYUP|configs.EXT_MANAGED) != 0:
+		return errors.New("mount drivers are not supported with tmpcopyup or managed=true")
+	}
+	switch m.Device {
+	case "proc", "sysfs", "mqueue", "cgroup", "cgroup2":
+		return fmt.Errorf("mount drivers are not supported for %s mounts", m.Device)
+	}
+	return nil
+}
+
+func checkManagedMount(config *configs.Config, m *configs.Mount) error {
+	if m.Extensions&configs.EXT_MANAGED == 0 {
+		return nil
+	}
+	if m.Device != "overlay" {
+		return errors.New("managed=true is only supported for overlay mounts")
+	}
+	// The upper and work directories are in the state directory, which
+	// is neither reachable from a user namespace nor idmapped.
+	if config.Namespaces.Contains(configs.NEWUSER) {
+		return errors.New("managed overlay mounts are not supported with user namespaces")
+	}
+	hasLower := false
+	for _, o := range strings.Split(m.Data, ",") {
+		key, _, _ := strings.Cut(o, "=")
+		switch key {
+		case "lowerdir":
+			hasLower = true
+		case "upperdir", "workdir":
+			return fmt.Errorf("managed overlay mounts cannot have %s set", key)
+		}
+	}
+	if !hasLower {
+		return errors.New("managed overlay mounts must have lowerdir set")
+	}
+	return nil
+}
+
 func checkIDMapMounts(config *configs.Config, m *configs.Mount) error {
 	// Make sure MOUNT_ATTR_IDMAP is not set on any of our mounts. This
 	// attribute is handled differently to all other attributes (through
@@ -325,6 +766,9 @@ func checkIDMapMounts(config *configs.Config, m *configs.Mount) error {
 	if config.RootlessEUID {
 		return errors.New("id-mapped mounts are not supported for rootless containers")
 	}
+	if !idmapMountsSupported() {
+		return errors.New("id-mapped mounts are not supported by the kernel (Linux 5.12 or later is needed)")
+	}
 	if m.IDMapping.UserNSPath == "" {
 		if len(m.IDMapping.UIDMappings) == 0 || len(m.IDMapping.GIDMappings) == 0 {
 			return errors.New("id-mapped mounts must have both uid and gid mappings specified")
@@ -338,6 +782,23 @@ func checkIDMapMounts(config *configs.Config, m *configs.Mount) error {
 	return nil
 }
 
+var (
+	idmapOnce      sync.Once
+	idmapSupported bool
+)
+
+// idmapMountsSupported tells whether the kernel supports id-mapped mounts,
+// that is, mount_setattr(2), which came along with MOUNT_ATTR_IDMAP.
+func idmapMountsSupported() bool {
+	idmapOnce.Do(func() {
+		// With a bad fd, this fails with EBADF if mount_setattr(2) is
+		// supported, without doing anything.
+		err := unix.MountSetattr(-1, "", unix.AT_EMPTY_PATH, &unix.MountAttr{})
+		idmapSupported = !errors.Is(err, unix.ENOSYS)
+	})
+	return idmapSupported
+}
+
 func mountsWarn(config *configs.Config) error {
 	for _, m := range config.Mounts {
 		if !filepath.IsAbs(m.Destination) {
@@ -355,6 +816,18 @@ func mountsStrict(config *configs.Config) error {
 		if err := checkIDMapMounts(config, m); err != nil {
 			return fmt.Errorf("invalid mount %+v: %w", m, err)
 		}
+		if err := checkManagedMount(config, m); err != nil {
+			return fmt.Errorf("invalid mount %+v: %w", m, err)
+		}
+		if err := checkMountDriver(m); err != nil {
+			return fmt.Errorf("invalid mount %+v: %w", m, err)
+		}
+		if err := checkFdSource(m); err != nil {
+			return fmt.Errorf("invalid mount %+v: %w", m, err)
+		}
+		if err := checkTmpfsOptions(config, m); err != nil {
+			return fmt.Errorf("invalid mount %+v: %w", m, err)
+		}
 	}
 	return nil
 }
@@ -396,3 +869,111 @@ func scheduler(config *configs.Config) error {
 	}
 	return nil
 }
+
+// timeCheck validates the time configuration.
+func timeCheck(config *configs.Config) error {
+	t := config.Time
+	if t == nil {
+		return nil
+	}
+	if t.Timezone != "" && t.TimezoneFile != "" {
+		return errors.New("time: only one of timezone and timezone file can be set")
+	}
+	if t.Timezone != "" {
+		if !filepath.IsLocal(t.Timezone) {
+			return fmt.Errorf("time: invalid timezone %q", t.Timezone)
+		}
+	}
+	if t.TimezoneFile != "" && !filepath.IsAbs(t.TimezoneFile) {
+		return fmt.Errorf("time: timezone file %q is not an absolute path", t.TimezoneFile)
+	}
+	if src := t.TimezoneSource(); src != "" {
+		fi, err := os.Stat(src)
+		if err != nil {
+			return fmt.Errorf("time: invalid timezone: %w", err)
+		}
+		if !fi.Mode().IsRegular() {
+			return fmt.Errorf("time: invalid timezone: %s is not a file", src)
+		}
+		for _, m := range config.Mounts {
+			if filepath.Clean(m.Destination) == configs.LocaltimePath {
+				return fmt.Errorf("time: timezone is set, but %s is also mounted", configs.LocaltimePath)
+			}
+		}
+	}
+	return nil
+}
+
+// exclusiveCPUsCheck validates the number of exclusive CPUs.
+func exclusiveCPUsCheck(config *configs.Config) error {
+	if config.ExclusiveCPUs < 0 {
+		return fmt.Errorf("invalid number of exclusive CPUs: %d", config.ExclusiveCPUs)
+	}
+	if config.ExclusiveCPUs > 0 && config.Cgroups != nil && config.Cgroups.Resources != nil && config.Cgroups.Resources.CpusetCpus != "" {
+		return errors.New("exclusive CPUs can't be used together with cpuset CPUs")
+	}
+	if config.ExclusiveCPUs > 0 && config.Cgroups != nil && config.Cgroups.AccountingOnly {
+		return errors.New("exclusive CPUs can't be used in the accounting-only cgroup mode")
+	}
+	return nil
+}
+
+// stopCheck validates how the container is stopped.
+func stopCheck(config *configs.Config) error {
+	s := config.Stop
+	if s == nil {
+		return nil
+	}
+	// SIGRTMAX is 64.
+	if s.Signal < 0 || s.Signal > 64 {
+		return fmt.Errorf("invalid stop signal %d", s.Signal)
+	}
+	if s.GracePeriod < 0 {
+		return fmt.Errorf("invalid stop grace period %s", s.GracePeriod)
+	}
+	return nil
+}
+
+func execRateLimitCheck(config *configs.Config) error {
+	l := config.ExecRateLimit
+	if l == nil {
+		return nil
+	}
+	if !(l.Rate > 0) || math.IsInf(l.Rate, 1) {
+		return fmt.Errorf("invalid exec rate %v", l.Rate)
+	}
+	if l.Burst < 0 {
+		return fmt.Errorf("invalid exec burst %d", l.Burst)
+	}
+	return nil
+}
+
+// ioLatencyCheck validates the IO latency targets of the container's
+// cgroup. The kernel only enforces them among sibling cgroups, so there are
+// none for the root cgroup.
+func ioLatencyCheck(config *configs.Config) error {
+	r := config.Cgroups.Resources
+	if len(r.BlkioLatencyDevice) == 0 {
+		return nil
+	}
+	if !cgroups.IsCgroup2UnifiedMode() {
+		return errors.New("io latency targets require cgroup v2")
+	}
+	if config.Cgroups.Path == "/" {
+		return errors.New("io latency targets can't be set for the root cgroup")
+	}
+	if _, ok := r.Unified["io.latency"]; ok {
+		return errors.New("io latency targets can't be set both by the typed field and in the unified map")
+	}
+	seen := make(map[configs.BlockIODevice]bool, len(r.BlkioLatencyDevice))
+	for _, ld := range r.BlkioLatencyDevice {
+		if ld.Major < 0 || ld.Minor < 0 {
+			return fmt.Errorf("invalid io latency target device %d:%d", ld.Major, ld.Minor)
+		}
+		if seen[ld.BlockIODevice] {
+			return fmt.Errorf("duplicate io latency target for device %d:%d", ld.Major, ld.Minor)
+		}
+		seen[ld.BlockIODevice] = true
+	}
+	return nil
+}
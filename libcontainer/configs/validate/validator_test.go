@@ -1,12 +1,19 @@
 package validate
 
 import (
+	"errors"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/szcdx/runc/libcontainer/cgroups"
 	"github.com/szcdx/runc/libcontainer/configs"
+	"github.com/szcdx/runc/libcontainer/system/kernelversion"
 	"golang.org/x/sys/unix"
 )
 
@@ -66,6 +73,123 @@ func TestValidateNetworkRoutesWithoutNETNamespace(t *testing.T) {
 	}
 }
 
+func TestValidateNetworks(t *testing.T) {
+	testCases := []struct {
+		name    string
+		network configs.Network
+		netns   string
+		isErr   bool
+	}{
+		{name: "loopback", network: configs.Network{Type: "loopback"}},
+		{
+			name:    "veth",
+			network: configs.Network{Type: "veth", Name: "eth0", Bridge: "br0", HostInterfaceName: "veth0", Address: "10.0.0.2/24", Gateway: "10.0.0.1"},
+		},
+		{
+			name:    "macvlan",
+			network: configs.Network{Type: "macvlan", Name: "eth0", Parent: "eno1", MacvlanMode: "vepa", IPv6Address: "fd00::2/64"},
+		},
+		{name: "unknown type", network: configs.Network{Type: "ipvlan", Name: "eth0"}, isErr: true},
+		{name: "veth without bridge", network: configs.Network{Type: "veth", Name: "eth0", HostInterfaceName: "veth0"}, isErr: true},
+		{name: "macvlan without parent", network: configs.Network{Type: "macvlan", Name: "eth0"}, isErr: true},
+		{name: "invalid mode", network: configs.Network{Type: "macvlan", Name: "eth0", Parent: "eno1", MacvlanMode: "bridged"}, isErr: true},
+		{name: "no name", network: configs.Network{Type: "macvlan", Parent: "eno1"}, isErr: true},
+		{name: "invalid address", network: configs.Network{Type: "macvlan", Name: "eth0", Parent: "eno1", Address: "10.0.0.2"}, isErr: true},
+		{name: "invalid gateway", network: configs.Network{Type: "macvlan", Name: "eth0", Parent: "eno1", Gateway: "10.0.0"}, isErr: true},
+		{name: "invalid MAC", network: configs.Network{Type: "macvlan", Name: "eth0", Parent: "eno1", MacAddress: "02:42"}, isErr: true},
+		{name: "joined netns", network: configs.Network{Type: "macvlan", Name: "eth0", Parent: "eno1"}, netns: "/proc/1/ns/net", isErr: true},
+	}
+	for _, tc := range testCases {
+		network := tc.network
+		config := &configs.Config{
+			Rootfs:     "/var",
+			Namespaces: []configs.Namespace{{Type: configs.NEWNET, Path: tc.netns}},
+			Networks:   []*configs.Network{&network},
+		}
+		err := Validate(config)
+		if tc.isErr && err == nil {
+			t.Errorf("%s: expected error, got nil", tc.name)
+		} else if !tc.isErr && err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+	}
+}
+
+func TestValidatePortForwards(t *testing.T) {
+	testCases := []struct {
+		name     string
+		forwards []*configs.PortForward
+		noNetns  bool
+		isErr    bool
+	}{
+		{name: "tcp", forwards: []*configs.PortForward{{HostPort: 8080, ContainerPort: 80}}},
+		{
+			name: "same port, tcp and udp",
+			forwards: []*configs.PortForward{
+				{Protocol: "tcp", HostIP: "::1", HostPort: 53, ContainerPort: 53},
+				{Protocol: "udp", HostIP: "::1", HostPort: 53, ContainerIP: "10.0.0.2", ContainerPort: 53},
+			},
+		},
+		{name: "no netns", forwards: []*configs.PortForward{{HostPort: 8080, ContainerPort: 80}}, noNetns: true, isErr: true},
+		{name: "invalid protocol", forwards: []*configs.PortForward{{Protocol: "sctp", HostPort: 8080, ContainerPort: 80}}, isErr: true},
+		{name: "no container port", forwards: []*configs.PortForward{{HostPort: 8080}}, isErr: true},
+		{name: "invalid address", forwards: []*configs.PortForward{{HostIP: "localhost", HostPort: 8080, ContainerPort: 80}}, isErr: true},
+		{
+			name: "duplicated host port",
+			forwards: []*configs.PortForward{
+				{HostPort: 8080, ContainerPort: 80},
+				{HostPort: 8080, ContainerPort: 81},
+			},
+			isErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		config := &configs.Config{
+			Rootfs:       "/var",
+			Namespaces:   []configs.Namespace{{Type: configs.NEWNET}},
+			PortForwards: tc.forwards,
+		}
+		if tc.noNetns {
+			config.Namespaces = nil
+		}
+		err := Validate(config)
+		if tc.isErr && err == nil {
+			t.Errorf("%s: expected error, got nil", tc.name)
+		} else if !tc.isErr && err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+	}
+}
+
+func TestValidateRootlessNetwork(t *testing.T) {
+	testCases := []struct {
+		name    string
+		network configs.RootlessNetwork
+		netns   string
+		isErr   bool
+	}{
+		{name: "slirp4netns", network: configs.RootlessNetwork{Mode: "slirp4netns", Args: []string{"--disable-host-loopback"}}},
+		{name: "pasta", network: configs.RootlessNetwork{Mode: "pasta", Path: "/usr/local/bin/pasta"}},
+		{name: "unknown mode", network: configs.RootlessNetwork{Mode: "vpnkit"}, isErr: true},
+		{name: "relative path", network: configs.RootlessNetwork{Mode: "pasta", Path: "bin/pasta"}, isErr: true},
+		{name: "joined netns", network: configs.RootlessNetwork{Mode: "pasta"}, netns: "/proc/1/ns/net", isErr: true},
+	}
+	for _, tc := range testCases {
+		network := tc.network
+		config := &configs.Config{
+			Rootfs:          "/var",
+			Namespaces:      []configs.Namespace{{Type: configs.NEWNET, Path: tc.netns}},
+			RootlessNetwork: &network,
+		}
+		err := Validate(config)
+		if tc.isErr && err == nil {
+			t.Errorf("%s: expected error, got nil", tc.name)
+		} else if !tc.isErr && err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+	}
+}
+
 func TestValidateHostname(t *testing.T) {
 	config := &configs.Config{
 		Rootfs:   "/var",
@@ -123,6 +247,65 @@ func TestValidateUTSWithoutUTSNamespace(t *testing.T) {
 	}
 }
 
+func TestValidateUTSNames(t *testing.T) {
+	testCases := []struct {
+		desc       string
+		hostname   string
+		domainname string
+		sysctl     map[string]string
+		ns         []configs.Namespace
+		isErr      bool
+	}{
+		{desc: "max length", domainname: strings.Repeat("a", 64)},
+		{desc: "too long domainname", domainname: strings.Repeat("a", 65), isErr: true},
+		{desc: "too long hostname", hostname: strings.Repeat("a", 65), isErr: true},
+		{desc: "NUL byte", domainname: "run\x00c", isErr: true},
+		{
+			desc:       "same kernel.domainname sysctl",
+			domainname: "runc",
+			sysctl:     map[string]string{"kernel.domainname": "runc"},
+		},
+		{
+			desc:       "conflicting kernel.domainname sysctl",
+			domainname: "runc",
+			sysctl:     map[string]string{"kernel.domainname": "other"},
+			isErr:      true,
+		},
+		{
+			desc:       "joined UTS namespace",
+			domainname: "runc",
+			ns:         []configs.Namespace{{Type: configs.NEWUTS, Path: "/proc/self/ns/uts"}},
+		},
+		{
+			desc:       "joined UTS namespace, new user namespace",
+			domainname: "runc",
+			ns: []configs.Namespace{
+				{Type: configs.NEWUTS, Path: "/proc/self/ns/uts"},
+				{Type: configs.NEWUSER},
+			},
+			isErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		ns := tc.ns
+		if ns == nil {
+			ns = []configs.Namespace{{Type: configs.NEWUTS}}
+		}
+		config := &configs.Config{
+			Hostname:   tc.hostname,
+			Domainname: tc.domainname,
+			Sysctl:     tc.sysctl,
+			Namespaces: configs.Namespaces(ns),
+		}
+		err := uts(config)
+		if tc.isErr && err == nil {
+			t.Errorf("%s: expected error, got nil", tc.desc)
+		} else if !tc.isErr && err != nil {
+			t.Errorf("%s: expected no error, got %v", tc.desc, err)
+		}
+	}
+}
+
 func TestValidateSecurityWithMaskPaths(t *testing.T) {
 	config := &configs.Config{
 		Rootfs:    "/var",
@@ -261,6 +444,47 @@ func TestValidateTimeOffsetsWithoutTimeNamespace(t *testing.T) {
 	}
 }
 
+func TestValidateTime(t *testing.T) {
+	tzFile := filepath.Join(t.TempDir(), "tz")
+	if err := os.WriteFile(tzFile, []byte("TZif"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		time   configs.Time
+		mounts []*configs.Mount
+		isErr  bool
+	}{
+		{time: configs.Time{TimezoneFile: tzFile}},
+		{time: configs.Time{TimezoneFile: "relative/tz"}, isErr: true},
+		{time: configs.Time{TimezoneFile: "/non/existent"}, isErr: true},
+		{time: configs.Time{TimezoneFile: "/"}, isErr: true},
+		{time: configs.Time{Timezone: "../../../etc/passwd"}, isErr: true},
+		{time: configs.Time{Timezone: "/etc/passwd"}, isErr: true},
+		{time: configs.Time{Timezone: "UTC", TimezoneFile: tzFile}, isErr: true},
+		{
+			time:   configs.Time{TimezoneFile: tzFile},
+			mounts: []*configs.Mount{{Source: tzFile, Destination: "/etc/localtime/", Device: "bind"}},
+			isErr:  true,
+		},
+		{time: configs.Time{}},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		config := &configs.Config{
+			Rootfs: "/var",
+			Time:   &tc.time,
+			Mounts: tc.mounts,
+		}
+		err := Validate(config)
+		if tc.isErr && err == nil {
+			t.Errorf("time %+v: expected error, got nil", tc.time)
+		}
+		if !tc.isErr && err != nil {
+			t.Errorf("time %+v: unexpected error: %v", tc.time, err)
+		}
+	}
+}
+
 // TestConvertSysctlVariableToDotsSeparator tests whether the sysctl variable
 // can be correctly converted to a dot as a separator.
 func TestConvertSysctlVariableToDotsSeparator(t *testing.T) {
@@ -342,6 +566,92 @@ func TestValidateValidSysctl(t *testing.T) {
 	}
 }
 
+func TestValidateNetSysctl(t *testing.T) {
+	if _, err := os.Stat("/proc/self/ns/user"); os.IsNotExist(err) {
+		t.Skip("Test requires userns.")
+	}
+	port := uint16(80)
+	netns := configs.Namespaces{{Type: configs.NEWNET}}
+	userns := configs.Namespaces{{Type: configs.NEWNET}, {Type: configs.NEWUSER}}
+	mappings := []configs.IDMap{{HostID: 100000, ContainerID: 0, Size: 1000}}
+	testCases := []struct {
+		name   string
+		config *configs.Config
+		isErr  bool
+	}{
+		{
+			name: "valid",
+			config: &configs.Config{
+				Namespaces: netns,
+				NetSysctl: &configs.NetSysctl{
+					PingGroupRange:        &configs.GroupRange{Start: 0, End: 2147483647},
+					UnprivilegedPortStart: &port,
+				},
+			},
+		},
+		{
+			name: "host netns",
+			config: &configs.Config{
+				NetSysctl: &configs.NetSysctl{UnprivilegedPortStart: &port},
+			},
+			isErr: true,
+		},
+		{
+			name: "conflicting sysctl",
+			config: &configs.Config{
+				Namespaces: netns,
+				Sysctl:     map[string]string{"net/ipv4/ip_unprivileged_port_start": "1024"},
+				NetSysctl:  &configs.NetSysctl{UnprivilegedPortStart: &port},
+			},
+			isErr: true,
+		},
+		{
+			name: "invalid range",
+			config: &configs.Config{
+				Namespaces: netns,
+				NetSysctl:  &configs.NetSysctl{PingGroupRange: &configs.GroupRange{Start: 10, End: 1}},
+			},
+			isErr: true,
+		},
+		{
+			name: "range too large",
+			config: &configs.Config{
+				Namespaces: netns,
+				NetSysctl:  &configs.NetSysctl{PingGroupRange: &configs.GroupRange{Start: 0, End: 4294967295}},
+			},
+			isErr: true,
+		},
+		{
+			name: "mapped range",
+			config: &configs.Config{
+				Namespaces:  userns,
+				UIDMappings: mappings,
+				GIDMappings: mappings,
+				NetSysctl:   &configs.NetSysctl{PingGroupRange: &configs.GroupRange{Start: 0, End: 999}},
+			},
+		},
+		{
+			name: "unmapped range",
+			config: &configs.Config{
+				Namespaces:  userns,
+				UIDMappings: mappings,
+				GIDMappings: mappings,
+				NetSysctl:   &configs.NetSysctl{PingGroupRange: &configs.GroupRange{Start: 0, End: 2147483647}},
+			},
+			isErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		tc.config.Rootfs = "/var"
+		err := Validate(tc.config)
+		if tc.isErr && err == nil {
+			t.Errorf("%s: expected error, got nil", tc.name)
+		} else if !tc.isErr && err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+	}
+}
+
 func TestValidateSysctlWithSameNs(t *testing.T) {
 	config := &configs.Config{
 		Rootfs: "/var",
@@ -415,6 +725,36 @@ func TestValidateSysctlWithoutNETNamespace(t *testing.T) {
 	}
 }
 
+func TestValidateSysctlRejectedList(t *testing.T) {
+	config := &configs.Config{
+		Rootfs: "/var",
+		Sysctl: map[string]string{
+			"net.ipv4.ip_forward": "1",
+			"kernel.shmmax":       "1",
+			"kernel/domainname":   "runc",
+			"kernel.ctl":          "ctl",
+		},
+		Namespaces: []configs.Namespace{{Type: configs.NEWIPC}},
+	}
+
+	err := Validate(config)
+	var serr *configs.SysctlError
+	if !errors.As(err, &serr) {
+		t.Fatalf("expected a sysctl error, got %v", err)
+	}
+	var keys []string
+	for _, r := range serr.Rejected {
+		keys = append(keys, r.Key)
+	}
+	expected := []string{"kernel.ctl", "kernel.domainname", "net.ipv4.ip_forward"}
+	if strings.Join(keys, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected rejected sysctls %v, got %v", expected, keys)
+	}
+	if !strings.Contains(err.Error(), `"net.ipv4.ip_forward": not allowed in the host's network namespace`) {
+		t.Errorf("unexpected error message: %v", err)
+	}
+}
+
 func TestValidateMounts(t *testing.T) {
 	testCases := []struct {
 		isErr bool
@@ -489,6 +829,193 @@ func TestValidateBindMounts(t *testing.T) {
 	}
 }
 
+func TestValidateTmpfsOptions(t *testing.T) {
+	userns := configs.Namespaces{{Type: configs.NEWUSER}}
+	mapping := []configs.IDMap{{ContainerID: 0, HostID: 100000, Size: 1000}}
+	testCases := []struct {
+		isErr      bool
+		data       string
+		userns     bool
+		extensions int
+		device     string
+	}{
+		{isErr: false, data: "size=65536k,mode=1777,uid=1000,gid=1000"},
+		{isErr: false, data: "size=50%,nr_inodes=1k"},
+		{isErr: false, data: "size=1048576,mode=755"},
+		{isErr: false, data: "uid=999,gid=999", userns: true},
+		{isErr: false, data: "mode=700", extensions: configs.EXT_COPYUP},
+
+		{isErr: true, data: "size=big"},
+		{isErr: true, data: "size=64kk"},
+		{isErr: true, data: "mode=999"},
+		{isErr: true, data: "mode=17777"},
+		{isErr: true, data: "uid=-1"},
+		{isErr: true, data: "gid=root"},
+		{isErr: true, data: "uid=1000", userns: true},
+		{isErr: true, data: "gid=1000", userns: true},
+		{isErr: true, device: "proc", extensions: configs.EXT_COPYUP},
+	}
+
+	for _, tc := range testCases {
+		device := tc.device
+		if device == "" {
+			device = "tmpfs"
+		}
+		config := &configs.Config{
+			Rootfs: "/var",
+			Mounts: []*configs.Mount{
+				{
+					Destination: "/tmp",
+					Device:      device,
+					Data:        tc.data,
+					Extensions:  tc.extensions,
+				},
+			},
+		}
+		if tc.userns {
+			config.Namespaces = userns
+			config.UIDMappings = mapping
+			config.GIDMappings = mapping
+		}
+
+		err := Validate(config)
+		if tc.isErr && err == nil {
+			t.Errorf("%s mount data:%v userns:%v, expected error, got nil", device, tc.data, tc.userns)
+		}
+		if !tc.isErr && err != nil {
+			t.Errorf("%s mount data:%v userns:%v, expected nil, got error %v", device, tc.data, tc.userns, err)
+		}
+	}
+}
+
+func TestValidateFdSources(t *testing.T) {
+	testCases := []struct {
+		isErr bool
+		mount configs.Mount
+	}{
+		{isErr: false, mount: configs.Mount{Source: "fd://0", Device: "bind", Flags: unix.MS_BIND}},
+		{isErr: false, mount: configs.Mount{Source: "fd://12", Device: "bind", Flags: unix.MS_BIND | unix.MS_REC}},
+
+		{isErr: true, mount: configs.Mount{Source: "fd://", Device: "bind", Flags: unix.MS_BIND}},
+		{isErr: true, mount: configs.Mount{Source: "fd://-1", Device: "bind", Flags: unix.MS_BIND}},
+		{isErr: true, mount: configs.Mount{Source: "fd://x", Device: "bind", Flags: unix.MS_BIND}},
+		{isErr: true, mount: configs.Mount{Source: "fd://0", Device: "tmpfs"}},
+		{isErr: true, mount: configs.Mount{Source: "fd://0", Device: "bind", Flags: unix.MS_BIND, Relabel: "z"}},
+	}
+
+	for _, tc := range testCases {
+		m := tc.mount
+		m.Destination = "/data"
+		config := &configs.Config{
+			Rootfs: "/var",
+			Mounts: []*configs.Mount{&m},
+		}
+
+		err := Validate(config)
+		if tc.isErr && err == nil {
+			t.Errorf("%+v: expected error, got nil", m)
+		}
+		if !tc.isErr && err != nil {
+			t.Errorf("%+v: expected nil, got error %v", m, err)
+		}
+	}
+}
+
+func TestValidateMountDrivers(t *testing.T) {
+	testCases := []struct {
+		isErr bool
+		mount configs.Mount
+	}{
+		{isErr: false, mount: configs.Mount{Device: "nfs"}},
+		{isErr: false, mount: configs.Mount{Device: "tmpfs", Flags: unix.MS_RDONLY}},
+
+		{isErr: true, mount: configs.Mount{Device: "nfs", Driver: "driver"}},
+		{isErr: true, mount: configs.Mount{Device: "bind", Flags: unix.MS_BIND}},
+		{isErr: true, mount: configs.Mount{Device: "proc"}},
+		{isErr: true, mount: configs.Mount{Device: "tmpfs", Extensions: configs.EXT_COPYUP}},
+	}
+
+	for _, tc := range testCases {
+		m := tc.mount
+		m.Destination = "/data"
+		if m.Driver == "" {
+			m.Driver = "/usr/libexec/driver"
+		}
+		config := &configs.Config{
+			Rootfs: "/var",
+			Mounts: []*configs.Mount{&m},
+		}
+
+		err := Validate(config)
+		if tc.isErr && err == nil {
+			t.Errorf("%+v: expected error, got nil", m)
+		}
+		if !tc.isErr && err != nil {
+			t.Errorf("%+v: expected nil, got error %v", m, err)
+		}
+	}
+}
+
+func TestValidateManagedMounts(t *testing.T) {
+	testCases := []struct {
+		isErr  bool
+		device string
+		data   string
+	}{
+		{isErr: false, device: "overlay", data: "lowerdir=/a:/b"},
+		{isErr: false, device: "overlay", data: "lowerdir=/a,userxattr"},
+
+		{isErr: true, device: "tmpfs", data: "lowerdir=/a"},
+		{isErr: true, device: "overlay", data: ""},
+		{isErr: true, device: "overlay", data: "lowerdir=/a,upperdir=/b"},
+		{isErr: true, device: "overlay", data: "lowerdir=/a,workdir=/c"},
+	}
+
+	for _, tc := range testCases {
+		config := &configs.Config{
+			Rootfs: "/var",
+			Mounts: []*configs.Mount{
+				{
+					Destination: "/data",
+					Device:      tc.device,
+					Data:        tc.data,
+					Extensions:  configs.EXT_MANAGED,
+				},
+			},
+		}
+
+		err := Validate(config)
+		if tc.isErr && err == nil {
+			t.Errorf("%s mount data:%v, expected error, got nil", tc.device, tc.data)
+		}
+		if !tc.isErr && err != nil {
+			t.Errorf("%s mount data:%v, expected nil, got error %v", tc.device, tc.data, err)
+		}
+	}
+
+	// Not supported with user namespaces.
+	config := &configs.Config{
+		Rootfs: "/var",
+		Namespaces: configs.Namespaces{
+			{Type: configs.NEWUSER},
+			{Type: configs.NEWNS},
+		},
+		UIDMappings: []configs.IDMap{{ContainerID: 0, HostID: 1000, Size: 1}},
+		GIDMappings: []configs.IDMap{{ContainerID: 0, HostID: 1000, Size: 1}},
+		Mounts: []*configs.Mount{
+			{
+				Destination: "/data",
+				Device:      "overlay",
+				Data:        "lowerdir=/a",
+				Extensions:  configs.EXT_MANAGED,
+			},
+		},
+	}
+	if err := Validate(config); err == nil || !strings.Contains(err.Error(), "user namespaces") {
+		t.Errorf("expected a user namespace error, got %v", err)
+	}
+}
+
 func TestValidateIDMapMounts(t *testing.T) {
 	mapping := []configs.IDMap{
 		{
@@ -790,6 +1317,16 @@ func TestValidateIDMapMounts(t *testing.T) {
 	}
 }
 
+func TestIDMapMountsSupported(t *testing.T) {
+	want, err := kernelversion.GreaterEqualThan(kernelversion.KernelVersion{Kernel: 5, Major: 12})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := idmapMountsSupported(); got != want {
+		t.Errorf("expected idmapMountsSupported() to be %v, got %v", want, got)
+	}
+}
+
 func TestValidateScheduler(t *testing.T) {
 	testCases := []struct {
 		isErr     bool
@@ -842,3 +1379,352 @@ func TestValidateScheduler(t *testing.T) {
 		}
 	}
 }
+
+func TestValidateCpusetPartition(t *testing.T) {
+	if !cgroups.IsCgroup2UnifiedMode() {
+		t.Skip("Test requires cgroup v2.")
+	}
+	testCases := []struct {
+		name      string
+		resources *configs.Resources
+		exclusive int
+		isErr     bool
+	}{
+		{name: "isolated", resources: &configs.Resources{CpusetCpus: "2-3", CpusetPartition: "isolated"}},
+		{name: "root with exclusive CPUs", resources: &configs.Resources{CpusetPartition: "root"}, exclusive: 2},
+		{name: "member", resources: &configs.Resources{CpusetPartition: "member"}},
+		{name: "invalid type", resources: &configs.Resources{CpusetCpus: "2-3", CpusetPartition: "leaf"}, isErr: true},
+		{name: "no CPUs", resources: &configs.Resources{CpusetPartition: "root"}, isErr: true},
+		{name: "invalid CPUs", resources: &configs.Resources{CpusetCpus: "3-2", CpusetPartition: "root"}, isErr: true},
+		{
+			name: "unified partition",
+			resources: &configs.Resources{
+				CpusetCpus:      "2-3",
+				CpusetPartition: "root",
+				Unified:         map[string]string{"cpuset.cpus.partition": "root"},
+			},
+			isErr: true,
+		},
+		{
+			name: "unified CPUs",
+			resources: &configs.Resources{
+				CpusetPartition: "root",
+				Unified:         map[string]string{"cpuset.cpus": "2-3"},
+			},
+			isErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		config := &configs.Config{
+			Rootfs:        "/var",
+			Cgroups:       &configs.Cgroup{Resources: tc.resources},
+			ExclusiveCPUs: tc.exclusive,
+		}
+		err := Validate(config)
+		if tc.isErr && err == nil {
+			t.Errorf("%s: expected error, got nil", tc.name)
+		} else if !tc.isErr && err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+	}
+}
+
+func TestValidateIoLatency(t *testing.T) {
+	latency := []*configs.LatencyDevice{configs.NewLatencyDevice(8, 0, 10000)}
+	if !cgroups.IsCgroup2UnifiedMode() {
+		config := &configs.Config{
+			Rootfs:  "/var",
+			Cgroups: &configs.Cgroup{Resources: &configs.Resources{BlkioLatencyDevice: latency}},
+		}
+		if err := Validate(config); err == nil {
+			t.Error("expected error on cgroup v1, got nil")
+		}
+		return
+	}
+	testCases := []struct {
+		name      string
+		path      string
+		resources *configs.Resources
+		isErr     bool
+	}{
+		{name: "target", resources: &configs.Resources{BlkioLatencyDevice: latency}},
+		{name: "root cgroup", path: "/", resources: &configs.Resources{BlkioLatencyDevice: latency}, isErr: true},
+		{
+			name: "duplicate device",
+			resources: &configs.Resources{
+				BlkioLatencyDevice: append(latency, configs.NewLatencyDevice(8, 0, 0)),
+			},
+			isErr: true,
+		},
+		{
+			name: "unified",
+			resources: &configs.Resources{
+				BlkioLatencyDevice: latency,
+				Unified:            map[string]string{"io.latency": "8:0 target=100"},
+			},
+			isErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		config := &configs.Config{
+			Rootfs:  "/var",
+			Cgroups: &configs.Cgroup{Path: tc.path, Resources: tc.resources},
+		}
+		err := Validate(config)
+		if tc.isErr && err == nil {
+			t.Errorf("%s: expected error, got nil", tc.name)
+		} else if !tc.isErr && err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+	}
+}
+
+func TestValidateHugetlbAccounting(t *testing.T) {
+	for _, accounting := range []string{"", "fault", "reservation", "rsvd"} {
+		config := &configs.Config{
+			Rootfs:  "/var",
+			Cgroups: &configs.Cgroup{Resources: &configs.Resources{HugetlbAccounting: accounting}},
+		}
+		err := Validate(config)
+		isErr := accounting == "rsvd"
+		if isErr && err == nil {
+			t.Errorf("accounting %q: expected error, got nil", accounting)
+		} else if !isErr && err != nil {
+			t.Errorf("accounting %q: unexpected error: %v", accounting, err)
+		}
+	}
+}
+
+func TestValidateDevicesPinDir(t *testing.T) {
+	if !cgroups.IsCgroup2UnifiedMode() {
+		t.Skip("Test requires cgroup v2.")
+	}
+	for _, dir := range []string{"", "/sys/fs/bpf/runc", "sys/fs/bpf"} {
+		config := &configs.Config{
+			Rootfs:  "/var",
+			Cgroups: &configs.Cgroup{Resources: &configs.Resources{DevicesPinDir: dir}},
+		}
+		err := Validate(config)
+		isErr := dir == "sys/fs/bpf"
+		if isErr && err == nil {
+			t.Errorf("pin dir %q: expected error, got nil", dir)
+		} else if !isErr && err != nil {
+			t.Errorf("pin dir %q: unexpected error: %v", dir, err)
+		}
+	}
+}
+
+func TestValidateCgroupDelegate(t *testing.T) {
+	owner := 1000
+	userns := configs.Namespace{Type: configs.NEWUSER}
+	cgroupns := configs.Namespace{Type: configs.NEWCGROUP}
+	testCases := []struct {
+		name       string
+		namespaces configs.Namespaces
+		owner      *int
+		isErr      bool
+	}{
+		{name: "ok", namespaces: configs.Namespaces{userns, cgroupns}, owner: &owner},
+		{name: "no userns", namespaces: configs.Namespaces{cgroupns}, owner: &owner, isErr: true},
+		{name: "no cgroupns", namespaces: configs.Namespaces{userns}, owner: &owner, isErr: true},
+		{
+			name:       "joined cgroupns",
+			namespaces: configs.Namespaces{userns, {Type: configs.NEWCGROUP, Path: "/proc/1/ns/cgroup"}},
+			owner:      &owner,
+			isErr:      true,
+		},
+		{name: "no owner", namespaces: configs.Namespaces{userns, cgroupns}, isErr: true},
+	}
+	for _, tc := range testCases {
+		config := &configs.Config{
+			Rootfs:     "/var",
+			Namespaces: tc.namespaces,
+			Cgroups: &configs.Cgroup{
+				Delegate: true,
+				OwnerUID: tc.owner,
+				OwnerGID: tc.owner,
+			},
+		}
+		err := cgroupDelegateCheck(config)
+		// Delegation is not supported on cgroup v1 at all.
+		isErr := tc.isErr || !cgroups.IsCgroup2UnifiedMode()
+		if isErr && err == nil {
+			t.Errorf("%s: expected error, got nil", tc.name)
+		} else if !isErr && err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+	}
+}
+
+func TestValidateCPUIdle(t *testing.T) {
+	for _, idle := range []int64{-1, 0, 1, 2} {
+		idle := idle
+		config := &configs.Config{
+			Rootfs:  "/var",
+			Cgroups: &configs.Cgroup{Resources: &configs.Resources{CPUIdle: &idle}},
+		}
+		err := Validate(config)
+		isErr := idle != 0 && idle != 1
+		if isErr && err == nil {
+			t.Errorf("idle %d: expected error, got nil", idle)
+		} else if !isErr && err != nil {
+			t.Errorf("idle %d: unexpected error: %v", idle, err)
+		}
+	}
+}
+
+func TestValidateStop(t *testing.T) {
+	testCases := []struct {
+		stop  *configs.Stop
+		isErr bool
+	}{
+		{stop: nil},
+		{stop: &configs.Stop{Signal: 2, GracePeriod: time.Second}},
+		{stop: &configs.Stop{Signal: 64}},
+		{stop: &configs.Stop{Signal: 65}, isErr: true},
+		{stop: &configs.Stop{Signal: -1}, isErr: true},
+		{stop: &configs.Stop{GracePeriod: -time.Second}, isErr: true},
+	}
+	for _, tc := range testCases {
+		config := &configs.Config{Rootfs: "/var", Stop: tc.stop}
+		err := Validate(config)
+		if tc.isErr && err == nil {
+			t.Errorf("%+v: expected error, got nil", tc.stop)
+		} else if !tc.isErr && err != nil {
+			t.Errorf("%+v: unexpected error: %v", tc.stop, err)
+		}
+	}
+}
+
+func TestValidateExecRateLimit(t *testing.T) {
+	testCases := []struct {
+		limit *configs.ExecRateLimit
+		isErr bool
+	}{
+		{limit: nil},
+		{limit: &configs.ExecRateLimit{Rate: 0.5}},
+		{limit: &configs.ExecRateLimit{Rate: 10, Burst: 20}},
+		{limit: &configs.ExecRateLimit{Rate: 0}, isErr: true},
+		{limit: &configs.ExecRateLimit{Rate: -1}, isErr: true},
+		{limit: &configs.ExecRateLimit{Rate: 1, Burst: -1}, isErr: true},
+	}
+	for _, tc := range testCases {
+		config := &configs.Config{Rootfs: "/var", ExecRateLimit: tc.limit}
+		err := Validate(config)
+		if tc.isErr && err == nil {
+			t.Errorf("%+v: expected error, got nil", tc.limit)
+		} else if !tc.isErr && err != nil {
+			t.Errorf("%+v: unexpected error: %v", tc.limit, err)
+		}
+	}
+}
+
+func TestValidateFirewall(t *testing.T) {
+	netns := configs.Namespaces{{Type: configs.NEWNET}}
+	testCases := []struct {
+		name       string
+		namespaces configs.Namespaces
+		rule       configs.FirewallRule
+		isErr      bool
+	}{
+		{name: "valid", namespaces: netns, rule: configs.FirewallRule{Source: "10.0.0.0/8", Protocol: "tcp", Port: 22}},
+		{name: "host netns", rule: configs.FirewallRule{Protocol: "tcp", Port: 22}, isErr: true},
+		{name: "invalid source", namespaces: netns, rule: configs.FirewallRule{Source: "10.0.0.0"}, isErr: true},
+		{name: "invalid protocol", namespaces: netns, rule: configs.FirewallRule{Protocol: "icmp"}, isErr: true},
+		{name: "port without protocol", namespaces: netns, rule: configs.FirewallRule{Port: 80}, isErr: true},
+		{name: "invalid port range", namespaces: netns, rule: configs.FirewallRule{Protocol: "udp", Port: 90, PortEnd: 80}, isErr: true},
+	}
+	for _, tc := range testCases {
+		config := &configs.Config{
+			Rootfs:     "/var",
+			Namespaces: tc.namespaces,
+			Firewall:   &configs.Firewall{Allow: []*configs.FirewallRule{&tc.rule}},
+		}
+		err := Validate(config)
+		if tc.isErr && err == nil {
+			t.Errorf("%s: expected error, got nil", tc.name)
+		} else if !tc.isErr && err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+	}
+}
+
+func TestValidateNamespaceOwner(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("requires root")
+	}
+	// Each of the processes gets a user namespace owning its network
+	// namespace.
+	pids := make([]int, 2)
+	for i := range pids {
+		cmd := exec.Command("sleep", "30")
+		cmd.SysProcAttr = &unix.SysProcAttr{Cloneflags: unix.CLONE_NEWUSER | unix.CLONE_NEWNET}
+		if err := cmd.Start(); err != nil {
+			t.Skipf("unable to create user namespaces: %v", err)
+		}
+		t.Cleanup(func() {
+			_ = cmd.Process.Kill()
+			_ = cmd.Wait()
+		})
+		pids[i] = cmd.Process.Pid
+	}
+	ns := func(pid int, typ string) string {
+		return "/proc/" + strconv.Itoa(pid) + "/ns/" + typ
+	}
+	testCases := []struct {
+		name       string
+		namespaces configs.Namespaces
+		allow      bool
+		isErr      bool
+	}{
+		{
+			name:       "joined from the runc user namespace",
+			namespaces: configs.Namespaces{{Type: configs.NEWNET, Path: ns(pids[0], "net")}},
+		},
+		{
+			name: "joined from a new user namespace",
+			namespaces: configs.Namespaces{
+				{Type: configs.NEWUSER},
+				{Type: configs.NEWNET, Path: ns(pids[0], "net")},
+			},
+		},
+		{
+			name: "joined from the owner",
+			namespaces: configs.Namespaces{
+				{Type: configs.NEWUSER, Path: ns(pids[0], "user")},
+				{Type: configs.NEWNET, Path: ns(pids[0], "net")},
+			},
+		},
+		{
+			name: "joined from another user namespace",
+			namespaces: configs.Namespaces{
+				{Type: configs.NEWUSER, Path: ns(pids[1], "user")},
+				{Type: configs.NEWNET, Path: ns(pids[0], "net")},
+			},
+			isErr: true,
+		},
+		{
+			name: "mismatch allowed",
+			namespaces: configs.Namespaces{
+				{Type: configs.NEWUSER, Path: ns(pids[1], "user")},
+				{Type: configs.NEWNET, Path: ns(pids[0], "net")},
+			},
+			allow: true,
+		},
+	}
+	for _, tc := range testCases {
+		config := &configs.Config{
+			Namespaces:                  tc.namespaces,
+			AllowNamespaceOwnerMismatch: tc.allow,
+		}
+		err := namespaceOwnerCheck(config)
+		if tc.isErr {
+			var ownerErr *NamespaceOwnerError
+			if !errors.As(err, &ownerErr) || !errors.Is(err, ErrNamespaceOwner) {
+				t.Errorf("%s: expected a NamespaceOwnerError, got %v", tc.name, err)
+			}
+		} else if err != nil {
+			t.Errorf("%s: expected no error, got %v", tc.name, err)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package libcontainer
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -15,6 +16,7 @@ import (
 	"sync"
 	"time"
 
+	systemdDbus "github.com/coreos/go-systemd/v22/dbus"
 	"github.com/opencontainers/runtime-spec/specs-go"
 	"github.com/sirupsen/logrus"
 	"github.com/vishvananda/netlink/nl"
@@ -22,9 +24,12 @@ import (
 	"golang.org/x/sys/unix"
 
 	"github.com/szcdx/runc/libcontainer/cgroups"
+	"github.com/szcdx/runc/libcontainer/cgroups/fscommon"
+	"github.com/szcdx/runc/libcontainer/cgroups/manager"
 	"github.com/szcdx/runc/libcontainer/configs"
 	"github.com/szcdx/runc/libcontainer/dmz"
 	"github.com/szcdx/runc/libcontainer/intelrdt"
+	"github.com/szcdx/runc/libcontainer/seccomp"
 	"github.com/szcdx/runc/libcontainer/system"
 	"github.com/szcdx/runc/libcontainer/system/kernelversion"
 	"github.com/szcdx/runc/libcontainer/utils"
@@ -41,11 +46,22 @@ type Container struct {
 	intelRdtManager      *intelrdt.Manager
 	initProcess          parentProcess
 	initProcessStartTime uint64
+	initProcessPidfd     *os.File
+	noPidfd              bool
 	m                    sync.Mutex
 	criuVersion          int
 	state                containerState
 	created              time.Time
 	fifo                 *os.File
+	initLog              *os.File
+	seccompHolder        *seccompHolder
+	rootlessNetwork      *rootlessNetwork
+	portForwarder        *portForwarder
+	networkLock          *nftablesLock
+	exclusiveCPUs        string
+	subCgroups           []string
+	launchRecord         *LaunchRecord
+	checkpointChain      *CheckpointChain
 }
 
 // State represents a running container's state
@@ -75,6 +91,37 @@ type State struct {
 
 	// Intel RDT "resource control" filesystem path
 	IntelRdtPath string `json:"intel_rdt_path"`
+
+	// The CPUs allocated to the container for its exclusive use, if any
+	// (see configs.Config.ExclusiveCPUs).
+	ExclusiveCPUs string `json:"exclusive_cpus,omitempty"`
+
+	// Paths of the sub-cgroups created for the processes with resources of
+	// their own (see Process.Resources), which are removed along with the
+	// container, as the processes may not be waited for.
+	SubCgroups []string `json:"sub_cgroups,omitempty"`
+
+	// Pid and start time of the process keeping a copy of the seccomp
+	// notify fd, if any (see configs.Seccomp.KeepListenerFd).
+	SeccompHolderPid       int    `json:"seccomp_holder_pid,omitempty"`
+	SeccompHolderStartTime uint64 `json:"seccomp_holder_start_time,omitempty"`
+
+	// Pid and start time of the user-mode network stack process of the
+	// container, if any (see configs.Config.RootlessNetwork).
+	RootlessNetworkPid       int    `json:"rootless_network_pid,omitempty"`
+	RootlessNetworkStartTime uint64 `json:"rootless_network_start_time,omitempty"`
+
+	// Pid and start time of the process forwarding the ports of the
+	// container, if any (see configs.Config.PortForwards).
+	PortForwarderPid       int    `json:"port_forwarder_pid,omitempty"`
+	PortForwarderStartTime uint64 `json:"port_forwarder_start_time,omitempty"`
+
+	// The measurement of what the container was launched with, if any
+	// (see configs.Config.LaunchRecord).
+	LaunchRecord *LaunchRecord `json:"launch_record,omitempty"`
+
+	// The dumps made by Checkpoint of the container left running, if any.
+	CheckpointChain *CheckpointChain `json:"checkpoint_chain,omitempty"`
 }
 
 // ID returns the container's unique ID
@@ -148,6 +195,13 @@ func (c *Container) Stats() (*Stats, error) {
 			return stats, fmt.Errorf("unable to get container Intel RDT stats: %w", err)
 		}
 	}
+	if c.config.Namespaces.Contains(configs.NEWNET) {
+		if stats.Interfaces, err = c.netnsInterfaceStats(); err == nil {
+			return stats, nil
+		}
+		// Fall back to the host end of the veth pairs.
+		logrus.Debugf("unable to get network stats from the container network namespace: %v", err)
+	}
 	for _, iface := range c.config.Networks {
 		switch iface.Type {
 		case "veth":
@@ -173,6 +227,24 @@ func (c *Container) Set(config configs.Config) error {
 	if status == Stopped {
 		return ErrNotRunning
 	}
+	if c.config.Cgroups.AccountingOnly {
+		return errors.New("can't update resources of a container in the accounting-only cgroup mode")
+	}
+	if c.config.Cgroups.ReadOnlyDegraded {
+		return errors.New("can't update resources of a container with no cgroup of its own (the cgroup filesystem was read-only)")
+	}
+	if c.exclusiveCPUs != "" {
+		switch config.Cgroups.Resources.CpusetCpus {
+		case "":
+			config.Cgroups.Resources.CpusetCpus = c.exclusiveCPUs
+		case c.exclusiveCPUs:
+		default:
+			return errors.New("can't change cpuset CPUs of a container with exclusive CPUs")
+		}
+	}
+	if c.config.Cgroups.SystemdDegraded {
+		c.registerSystemd()
+	}
 	if err := c.cgroupManager.Set(config.Cgroups.Resources); err != nil {
 		// Set configs back
 		if err2 := c.cgroupManager.Set(c.config.Cgroups.Resources); err2 != nil {
@@ -192,28 +264,84 @@ func (c *Container) Set(config configs.Config) error {
 			return err
 		}
 	}
+	c.refreshProcLimits(config.Cgroups.Resources)
 	// After config setting succeed, update config and states
 	c.config = &config
 	_, err = c.updateState(nil)
 	return err
 }
 
+// registerSystemd tries to register the container, which was created while
+// systemd was not available, with systemd (see configs.Cgroup.SystemdFallback),
+// and to switch to the systemd cgroup manager. If that fails, the fs cgroup
+// manager is kept.
+func (c *Container) registerSystemd() {
+	m, err := manager.RegisterSystemd(c.config.Cgroups, c.cgroupManager.GetPaths(), c.initProcess.pid())
+	if err != nil {
+		logrus.Warnf("unable to register the container with systemd, keeping the fs cgroup manager: %v", err)
+		return
+	}
+	logrus.Infof("container %s registered with systemd", c.id)
+	c.cgroupManager = m
+	if p, ok := c.initProcess.(*initProcess); ok {
+		p.manager = m
+	}
+}
+
+// SetSystemdProperties sets the given properties of the container's systemd
+// unit. It only works for the containers using the systemd cgroup driver.
+func (c *Container) SetSystemdProperties(properties ...systemdDbus.Property) error {
+	m, ok := c.cgroupManager.(interface {
+		SetUnitProperties(...systemdDbus.Property) error
+	})
+	if !ok {
+		return errors.New("container does not use the systemd cgroup driver")
+	}
+	c.m.Lock()
+	defer c.m.Unlock()
+	status, err := c.currentStatus()
+	if err != nil {
+		return err
+	}
+	if status == Stopped {
+		return ErrNotRunning
+	}
+	return m.SetUnitProperties(properties...)
+}
+
 // Start starts a process inside the container. Returns error if process fails
 // to start. You can track process lifecycle with passed Process structure.
+//
+// If the container has a process start rate limit (see
+// configs.ExecRateLimit) which is exceeded, a *RateLimitError is returned.
 func (c *Container) Start(process *Process) error {
 	c.m.Lock()
 	defer c.m.Unlock()
 	if c.config.Cgroups.Resources.SkipDevices {
 		return errors.New("can't start container with SkipDevices set")
 	}
+	var j journal
+	if !process.Init {
+		if err := c.takeExecToken(); err != nil {
+			return err
+		}
+		j.record("refund exec token", c.refundExecToken)
+	}
 	if process.Init {
+		j.record("remove exec fifo", func() error {
+			c.deleteExecFifo()
+			return nil
+		})
 		if err := c.createExecFifo(); err != nil {
+			if err := j.rollback(); err != nil {
+				logrus.Warn(err)
+			}
 			return err
 		}
 	}
-	if err := c.start(process); err != nil {
-		if process.Init {
-			c.deleteExecFifo()
+	if err := c.start(process, &j); err != nil {
+		if err := j.rollback(); err != nil {
+			logrus.Warn(err)
 		}
 		return err
 	}
@@ -312,7 +440,20 @@ type openResult struct {
 	err  error
 }
 
-func (c *Container) start(process *Process) (retErr error) {
+// start starts the process. For the init process, it records in j how to
+// undo the start, which the caller is to roll back if start (or anything
+// the caller does afterwards) fails.
+func (c *Container) start(process *Process, j *journal) (retErr error) {
+	if process.Init {
+		// The files passed to runc init are closed once it is started,
+		// and also if anything fails before that.
+		defer c.closeInitFiles()
+	}
+	if !process.Init {
+		// Such as for free(1) run in the container to see its current
+		// memory usage.
+		c.refreshProcLimits(c.config.Cgroups.Resources)
+	}
 	parent, err := c.newParentProcess(process)
 	if err != nil {
 		return fmt.Errorf("unable to create new parent process: %w", err)
@@ -335,9 +476,26 @@ func (c *Container) start(process *Process) (retErr error) {
 	if err := parent.start(); err != nil {
 		return fmt.Errorf("unable to start container process: %w", err)
 	}
+	if p, ok := parent.(*setnsProcess); ok && len(p.createdCgroups) > 0 {
+		if err := c.recordSubCgroups(p.createdCgroups); err != nil {
+			logrus.Warnf("unable to record the sub-cgroups of the process: %v", err)
+		}
+	}
 
 	if process.Init {
-		c.fifo.Close()
+		c.closeInitFiles()
+		// The init process is started, so its cgroups are to be removed
+		// (after it is terminated) if anything below fails.
+		j.record("remove cgroups", c.cgroupManager.Destroy)
+		if c.intelRdtManager != nil {
+			j.record("remove Intel RDT group", c.intelRdtManager.Destroy)
+		}
+		j.record("terminate init", func() error {
+			return ignoreTerminateErrors(parent.terminate())
+		})
+		if err := c.runInContainerHooks(); err != nil {
+			return err
+		}
 		if c.config.Hooks != nil {
 			s, err := c.currentOCIState()
 			if err != nil {
@@ -345,9 +503,6 @@ func (c *Container) start(process *Process) (retErr error) {
 			}
 
 			if err := c.config.Hooks.Run(configs.Poststart, s); err != nil {
-				if err := ignoreTerminateErrors(parent.terminate()); err != nil {
-					logrus.Warn(fmt.Errorf("error running poststart hook: %w", err))
-				}
 				return err
 			}
 		}
@@ -355,15 +510,181 @@ func (c *Container) start(process *Process) (retErr error) {
 	return nil
 }
 
+// runInContainerHooks runs the container's InContainerHooks, in order, in
+// the namespaces of the container being created.
+func (c *Container) runInContainerHooks() error {
+	if len(c.config.InContainerHooks) == 0 {
+		return nil
+	}
+	s, err := c.currentOCIState()
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	for i, h := range c.config.InContainerHooks {
+		if err := c.runInContainerHook(h, b); err != nil {
+			return fmt.Errorf("error running in-container hook #%d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (c *Container) runInContainerHook(h configs.InContainerHook, state []byte) error {
+	exe, err := os.Open(h.Path)
+	if err != nil {
+		return err
+	}
+	defer exe.Close()
+
+	args := h.Args
+	if len(args) == 0 {
+		args = []string{h.Path}
+	}
+	caps := h.Capabilities
+	if caps == nil {
+		caps = &configs.Capabilities{}
+	}
+	var stdout, stderr bytes.Buffer
+	p := &Process{
+		Args:         args,
+		Env:          h.Env,
+		Cwd:          h.Dir,
+		Stdin:        bytes.NewReader(state),
+		Stdout:       &stdout,
+		Stderr:       &stderr,
+		Capabilities: caps,
+		Exe:          exe,
+	}
+	// Nothing is recorded for a non-init process.
+	if err := c.start(p, new(journal)); err != nil {
+		return err
+	}
+	errC := make(chan error, 1)
+	go func() {
+		_, err := p.Wait()
+		if err != nil {
+			err = fmt.Errorf("%w, stdout: %s, stderr: %s", err, stdout.String(), stderr.String())
+		}
+		errC <- err
+	}()
+	var timerCh <-chan time.Time
+	if h.Timeout != nil {
+		timer := time.NewTimer(*h.Timeout)
+		defer timer.Stop()
+		timerCh = timer.C
+	}
+	select {
+	case err := <-errC:
+		return err
+	case <-timerCh:
+		_ = p.Signal(unix.SIGKILL)
+		<-errC
+		return fmt.Errorf("hook ran past specified timeout of %.1fs", h.Timeout.Seconds())
+	}
+}
+
+// PausedSignalPolicy determines how a signal is delivered to a container
+// which is paused (i.e. its cgroup is frozen).
+type PausedSignalPolicy int
+
+const (
+	// PausedSignalDefault sends the signal as is, thawing the container
+	// afterwards only if the signal is SIGKILL. This is the historical
+	// behavior of Signal.
+	PausedSignalDefault PausedSignalPolicy = iota
+	// PausedSignalFail refuses to signal a paused container, returning
+	// ErrPaused instead.
+	PausedSignalFail
+	// PausedSignalThaw queues the signal and thaws the container so that
+	// the signal is handled. The container is left running.
+	PausedSignalThaw
+	// PausedSignalRefreeze thaws the container, sends the signal, and
+	// freezes the container again, so it is left paused.
+	PausedSignalRefreeze
+)
+
+// ParsePausedSignalPolicy converts the textual representation of a
+// PausedSignalPolicy (as used by runc kill --thaw) to its value.
+func ParsePausedSignalPolicy(s string) (PausedSignalPolicy, error) {
+	switch s {
+	case "", "default":
+		return PausedSignalDefault, nil
+	case "fail":
+		return PausedSignalFail, nil
+	case "thaw":
+		return PausedSignalThaw, nil
+	case "refreeze":
+		return PausedSignalRefreeze, nil
+	}
+	return PausedSignalDefault, fmt.Errorf("invalid paused signal policy %q", s)
+}
+
+func (p PausedSignalPolicy) String() string {
+	switch p {
+	case PausedSignalDefault:
+		return "default"
+	case PausedSignalFail:
+		return "fail"
+	case PausedSignalThaw:
+		return "thaw"
+	case PausedSignalRefreeze:
+		return "refreeze"
+	default:
+		return "unknown"
+	}
+}
+
 // Signal sends a specified signal to container's init.
 //
 // When s is SIGKILL and the container does not have its own PID namespace, all
 // the container's processes are killed. In this scenario, the libcontainer
 // user may be required to implement a proper child reaper.
+//
+// Signal is equivalent to SignalWithPolicy with PausedSignalDefault.
 func (c *Container) Signal(s os.Signal) error {
+	return c.SignalWithPolicy(s, PausedSignalDefault)
+}
+
+// SignalWithPolicy is like Signal, but lets the caller decide what to do
+// when the container is paused (see PausedSignalPolicy).
+func (c *Container) SignalWithPolicy(s os.Signal, policy PausedSignalPolicy) (retErr error) {
 	c.m.Lock()
 	defer c.m.Unlock()
 
+	paused, err := c.isPaused()
+	if err != nil {
+		return err
+	}
+	if paused {
+		switch policy {
+		case PausedSignalDefault:
+		case PausedSignalFail:
+			return ErrPaused
+		case PausedSignalThaw:
+			// Thaw after the signal is sent, so it is queued
+			// before any container process gets to run.
+			defer func() {
+				if retErr == nil {
+					retErr = c.thaw()
+				}
+			}()
+		case PausedSignalRefreeze:
+			if err := c.cgroupManager.Freeze(configs.Thawed); err != nil {
+				return fmt.Errorf("unable to thaw container: %w", err)
+			}
+			defer func() {
+				if err := c.cgroupManager.Freeze(configs.Frozen); err != nil && retErr == nil {
+					retErr = fmt.Errorf("unable to refreeze container: %w", err)
+				}
+			}()
+		default:
+			return fmt.Errorf("invalid paused signal policy %d", policy)
+		}
+	}
+
 	// When a container has its own PID namespace, inside it the init PID
 	// is 1, and thus it is handled specially by the kernel. In particular,
 	// killing init with SIGKILL from an ancestor namespace will also kill
@@ -382,16 +703,26 @@ func (c *Container) Signal(s os.Signal) error {
 	if !c.hasInit() {
 		return ErrNotRunning
 	}
-	if err := c.initProcess.signal(s); err != nil {
+	if err := c.signalInit(s); err != nil {
 		return fmt.Errorf("unable to signal init: %w", err)
 	}
-	if s == unix.SIGKILL {
+	if s == unix.SIGKILL && policy == PausedSignalDefault && paused {
 		// For cgroup v1, killing a process in a frozen cgroup
 		// does nothing until it's thawed. Only thaw the cgroup
 		// for SIGKILL.
-		if paused, _ := c.isPaused(); paused {
-			_ = c.cgroupManager.Freeze(configs.Thawed)
-		}
+		_ = c.cgroupManager.Freeze(configs.Thawed)
+	}
+	return nil
+}
+
+// thaw thaws the container's cgroup and updates the container state
+// accordingly. It must be called with c.m held.
+func (c *Container) thaw() error {
+	if err := c.cgroupManager.Freeze(configs.Thawed); err != nil {
+		return fmt.Errorf("unable to thaw container: %w", err)
+	}
+	if c.hasInit() {
+		return c.state.transition(&runningState{c: c})
 	}
 	return nil
 }
@@ -436,6 +767,7 @@ func (c *Container) includeExecFifo(cmd *exec.Cmd) error {
 		return err
 	}
 	c.fifo = fifo
+	trackFd(int(fifo.Fd()), "exec fifo", c.id, fifoName)
 
 	cmd.ExtraFiles = append(cmd.ExtraFiles, fifo)
 	cmd.Env = append(cmd.Env,
@@ -443,6 +775,47 @@ func (c *Container) includeExecFifo(cmd *exec.Cmd) error {
 	return nil
 }
 
+// closeInitFiles closes the files passed to runc init by includeExecFifo and
+// includeInitLog.
+func (c *Container) closeInitFiles() {
+	closeTracked(c.fifo)
+	c.fifo = nil
+	closeTracked(c.initLog)
+	c.initLog = nil
+}
+
+// includeInitLog passes the init log file (see InitLog) to runc init, which
+// writes to it the messages it can no longer send to the parent.
+func (c *Container) includeInitLog(cmd *exec.Cmd) error {
+	initLog, err := os.OpenFile(filepath.Join(c.stateDir, initLogFilename),
+		unix.O_WRONLY|unix.O_CREAT|unix.O_APPEND|unix.O_CLOEXEC, 0o600)
+	if err != nil {
+		return err
+	}
+	c.initLog = initLog
+	trackFd(int(initLog.Fd()), "init log", c.id, initLog.Name())
+
+	cmd.ExtraFiles = append(cmd.ExtraFiles, initLog)
+	cmd.Env = append(cmd.Env,
+		"_LIBCONTAINER_INITLOG="+strconv.Itoa(stdioFdCount+len(cmd.ExtraFiles)-1))
+	return nil
+}
+
+// InitLog returns the contents of the container's init log. Once the
+// container is created, the container init can no longer report to runc,
+// so the messages it emits afterwards (such as the error if the container
+// process can not be executed upon start) are written to this log, in JSON
+// lines as produced by logrus, along with the raw output to its stderr until
+// the container process is executed. It is empty if there are no such
+// messages.
+func (c *Container) InitLog() ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(c.stateDir, initLogFilename))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return data, err
+}
+
 // No longer needed in Go 1.21.
 func slicesContains[S ~[]E, E comparable](slice S, needle E) bool {
 	for _, val := range slice {
@@ -600,6 +973,21 @@ func (c *Container) newParentProcess(p *Process) (parentProcess, error) {
 		)
 	}
 
+	if (p.Seccomp != nil || p.SeccompUnconfined) && p.Init {
+		return nil, errors.New("Seccomp can not be set for the init process")
+	}
+	if len(p.MountFiles) > 0 && !p.Init {
+		return nil, errors.New("MountFiles can only be set for the init process")
+	}
+	if p.Exe != nil {
+		if p.Init {
+			return nil, errors.New("Exe can not be set for the init process")
+		}
+		cmd.ExtraFiles = append(cmd.ExtraFiles, p.Exe)
+		cmd.Env = append(cmd.Env,
+			"_LIBCONTAINER_EXEFD="+strconv.Itoa(stdioFdCount+len(cmd.ExtraFiles)-1))
+	}
+
 	if safeExe != nil {
 		// Due to a Go stdlib bug, we need to add safeExe to the set of
 		// ExtraFiles otherwise it is possible for the stdlib to clobber the fd
@@ -629,6 +1017,9 @@ func (c *Container) newParentProcess(p *Process) (parentProcess, error) {
 		if err := c.includeExecFifo(cmd); err != nil {
 			return nil, fmt.Errorf("unable to setup exec fifo: %w", err)
 		}
+		if err := c.includeInitLog(cmd); err != nil {
+			return nil, fmt.Errorf("unable to setup init log: %w", err)
+		}
 		return c.newInitProcess(p, cmd, comm)
 	}
 	return c.newSetnsProcess(p, cmd, comm)
@@ -647,17 +1038,25 @@ func (c *Container) newInitProcess(p *Process, cmd *exec.Cmd, comm *processComm)
 		return nil, err
 	}
 
+	cfg := c.newInitConfig(p)
+	if err := c.setupManagedMounts(cfg); err != nil {
+		return nil, err
+	}
+	if err := c.setupProcLimits(cfg); err != nil {
+		return nil, err
+	}
+
 	init := &initProcess{
 		cmd:             cmd,
 		comm:            comm,
 		manager:         c.cgroupManager,
 		intelRdtManager: c.intelRdtManager,
-		config:          c.newInitConfig(p),
+		config:          cfg,
 		container:       c,
 		process:         p,
 		bootstrapData:   data,
 	}
-	c.initProcess = init
+	c.setInitProcess(init)
 	return init, nil
 }
 
@@ -685,7 +1084,26 @@ func (c *Container) newSetnsProcess(p *Process, cmd *exec.Cmd, comm *processComm
 		bootstrapData:   data,
 		initProcessPid:  state.InitProcessPid,
 	}
+	if c.intelRdtManager != nil {
+		proc.intelRdtMonPath = c.intelRdtManager.GetMonitoringPath()
+	}
+	if p.Resources != nil {
+		if len(p.SubCgroupPaths) == 0 {
+			return nil, errors.New("process resources can only be set for a sub-cgroup")
+		}
+		// The container's cgroup has processes (at least the init), so
+		// the no internal process rule does not let its sub-cgroups have
+		// controllers enabled.
+		if cgroups.IsCgroup2UnifiedMode() {
+			return nil, errors.New("process resources are not supported on cgroup v2")
+		}
+	}
 	if len(p.SubCgroupPaths) > 0 {
+		// The paths may be the ones of the cgroup manager, not a copy.
+		proc.cgroupPaths = make(map[string]string, len(state.CgroupPaths))
+		for k, v := range state.CgroupPaths {
+			proc.cgroupPaths[k] = v
+		}
 		if add, ok := p.SubCgroupPaths[""]; ok {
 			// cgroup v1: using the same path for all controllers.
 			// cgroup v2: the only possible way.
@@ -717,6 +1135,27 @@ func (c *Container) newSetnsProcess(p *Process, cmd *exec.Cmd, comm *processComm
 	return proc, nil
 }
 
+// recordSubCgroups adds the sub-cgroups created for a process to the
+// container state, for them to be removed with the container even if the
+// process is not waited for, such as with runc exec -d. The ones which are
+// gone already are dropped.
+func (c *Container) recordSubCgroups(paths []string) error {
+	seen := make(map[string]struct{})
+	var keep []string
+	for _, path := range append(c.subCgroups, paths...) {
+		if _, ok := seen[path]; ok {
+			continue
+		}
+		seen[path] = struct{}{}
+		if _, err := os.Stat(path); err == nil {
+			keep = append(keep, path)
+		}
+	}
+	c.subCgroups = keep
+	_, err := c.updateState(nil)
+	return err
+}
+
 func (c *Container) newInitConfig(process *Process) *initConfig {
 	cfg := &initConfig{
 		Config:           c.config,
@@ -741,6 +1180,9 @@ func (c *Container) newInitConfig(process *Process) *initConfig {
 	if process.NoNewPrivileges != nil {
 		cfg.NoNewPrivileges = *process.NoNewPrivileges
 	}
+	if c.config.Time.TimezoneSource() != "" {
+		cfg.Env = timezoneEnv(process.Env)
+	}
 	if process.AppArmorProfile != "" {
 		cfg.AppArmorProfile = process.AppArmorProfile
 	}
@@ -753,6 +1195,27 @@ func (c *Container) newInitConfig(process *Process) *initConfig {
 	if cgroups.IsCgroup2UnifiedMode() {
 		cfg.Cgroup2Path = c.cgroupManager.Path("")
 	}
+	if process.Seccomp != nil || process.SeccompUnconfined {
+		// The process has its own seccomp profile, instead of the one
+		// of the container.
+		config := *c.config
+		config.Seccomp = process.Seccomp
+		cfg.Config = &config
+	}
+	// Without a state directory, there is no root directory to keep the
+	// cache in (and filepath.Dir would give the current directory).
+	if cfg.Config.Seccomp != nil && seccomp.Enabled && c.stateDir != "" {
+		// Compile the seccomp filter here rather than in runc init, so
+		// that the compiled program can be reused by other containers.
+		// On failure, leave it to runc init to report the error.
+		dir := filepath.Join(filepath.Dir(c.stateDir), SeccompCacheDir)
+		prog, err := seccomp.CompileCached(dir, cfg.Config.Seccomp)
+		if err != nil {
+			logrus.Debugf("unable to precompile seccomp filter: %v", err)
+		} else {
+			cfg.SeccompProgram = prog
+		}
+	}
 
 	return cfg
 }
@@ -831,18 +1294,96 @@ func (c *Container) NotifyOOM() (<-chan struct{}, error) {
 }
 
 // NotifyMemoryPressure returns a read-only channel signaling when the
-// container reaches a given pressure level.
+// container reaches a given pressure level. On cgroup v2, the levels are
+// emulated using PSI triggers on memory.pressure.
 func (c *Container) NotifyMemoryPressure(level PressureLevel) (<-chan struct{}, error) {
+	return c.NotifyMemoryPressureUntil(level, nil)
+}
+
+// NotifyMemoryPressureUntil is like NotifyMemoryPressure, but closing done
+// stops the notifications, and closes the channel.
+func (c *Container) NotifyMemoryPressureUntil(level PressureLevel, done <-chan struct{}) (<-chan struct{}, error) {
 	// XXX(cyphar): This requires cgroups.
 	if c.config.RootlessCgroups {
 		logrus.Warn("getting memory pressure notifications may fail if you don't have the full access to cgroups")
 	}
-	return notifyMemoryPressure(c.cgroupManager.Path("memory"), level)
+	path := c.cgroupManager.Path("memory")
+	if cgroups.IsCgroup2UnifiedMode() {
+		return notifyMemoryPressureV2(path, level, done)
+	}
+	return notifyMemoryPressure(path, level, done)
+}
+
+// NotifyMemoryThreshold returns a read-only channel signaling when the
+// container's memory usage rises above the given percentage of its memory
+// limit. The container must have a memory limit set. Closing done stops the
+// notifications, and closes the channel.
+func (c *Container) NotifyMemoryThreshold(percent uint, done <-chan struct{}) (<-chan struct{}, error) {
+	if percent == 0 || percent > 100 {
+		return nil, fmt.Errorf("invalid memory threshold %d%%: must be between 1 and 100", percent)
+	}
+	// XXX(cyphar): This requires cgroups.
+	if c.config.RootlessCgroups {
+		logrus.Warn("getting memory threshold notifications may fail if you don't have the full access to cgroups")
+	}
+	path := c.cgroupManager.Path("memory")
+	if path == "" {
+		return nil, errors.New("memory controller missing")
+	}
+	limitFile := "memory.limit_in_bytes"
+	if cgroups.IsCgroup2UnifiedMode() {
+		limitFile = "memory.max"
+	}
+	limit, err := fscommon.GetCgroupParamUint(path, limitFile)
+	if err != nil {
+		return nil, err
+	}
+	// On cgroup v1, no limit is a huge page-aligned number rather than "max".
+	if limit >= 1<<62 {
+		return nil, errors.New("memory threshold notifications require a memory limit")
+	}
+	threshold := limit / 100 * uint64(percent)
+	if cgroups.IsCgroup2UnifiedMode() {
+		return notifyOnMemoryThresholdV2(path, threshold, done)
+	}
+	return notifyOnMemoryThreshold(path, threshold, done)
+}
+
+// NotifyPidsLimit returns a read-only channel signaling when a process of
+// the container fails to fork or clone because of the pids limit. Closing
+// done stops the notifications, and closes the channel.
+func (c *Container) NotifyPidsLimit(done <-chan struct{}) (<-chan struct{}, error) {
+	// XXX(cyphar): This requires cgroups.
+	if c.config.RootlessCgroups {
+		logrus.Warn("getting pids limit notifications may fail if you don't have the full access to cgroups")
+	}
+	path := c.cgroupManager.Path("pids")
+	if path == "" {
+		return nil, errors.New("pids controller missing")
+	}
+	if cgroups.IsCgroup2UnifiedMode() {
+		return notifyOnPidsLimitV2(path, done)
+	}
+	return notifyOnPidsLimit(path, done)
+}
+
+// SeccompNotifyFd returns a new copy of the seccomp notify fd of the
+// container init. It is only available to the caller which started the
+// container with Process.SeccompNotify set, only if the container seccomp
+// profile has SCMP_ACT_NOTIFY rules, and until the init is waited for. The
+// caller is responsible for closing it.
+func (c *Container) SeccompNotifyFd() (*os.File, error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	if init, ok := c.initProcess.(*initProcess); ok && init.process.seccompNotifyFd != nil {
+		return init.process.SeccompNotifyFd()
+	}
+	return nil, errors.New("seccomp notify fd is not available")
 }
 
 func (c *Container) updateState(process parentProcess) (*State, error) {
 	if process != nil {
-		c.initProcess = process
+		c.setInitProcess(process)
 	}
 	state, err := c.currentState()
 	if err != nil {
@@ -916,15 +1457,14 @@ func (c *Container) hasInit() bool {
 	if c.initProcess == nil {
 		return false
 	}
-	pid := c.initProcess.pid()
-	stat, err := system.Stat(pid)
+	pidfd, err := c.initPidfd()
 	if err != nil {
 		return false
 	}
-	if stat.StartTime != c.initProcessStartTime || stat.State == system.Zombie || stat.State == system.Dead {
-		return false
+	if pidfd != nil {
+		return !pidfdExited(pidfd)
 	}
-	return true
+	return processAlive(c.initProcess.pid(), c.initProcessStartTime)
 }
 
 func (c *Container) isPaused() (bool, error) {
@@ -965,6 +1505,22 @@ func (c *Container) currentState() (*State, error) {
 		NamespacePaths:      make(map[configs.NamespaceType]string),
 		ExternalDescriptors: externalDescriptors,
 	}
+	state.ExclusiveCPUs = c.exclusiveCPUs
+	state.SubCgroups = c.subCgroups
+	state.LaunchRecord = c.launchRecord
+	state.CheckpointChain = c.checkpointChain
+	if c.seccompHolder != nil {
+		state.SeccompHolderPid = c.seccompHolder.pid
+		state.SeccompHolderStartTime = c.seccompHolder.startTime
+	}
+	if c.rootlessNetwork != nil {
+		state.RootlessNetworkPid = c.rootlessNetwork.pid
+		state.RootlessNetworkStartTime = c.rootlessNetwork.startTime
+	}
+	if c.portForwarder != nil {
+		state.PortForwarderPid = c.portForwarder.pid
+		state.PortForwarderStartTime = c.portForwarder.startTime
+	}
 	if pid > 0 {
 		for _, ns := range c.config.Namespaces {
 			state.NamespacePaths[ns.Type] = ns.GetPath(pid)
@@ -1175,6 +1731,17 @@ func (c *Container) bootstrapData(cloneFlags uintptr, nsMaps map[configs.Namespa
 	return bytes.NewReader(r.Serialize()), nil
 }
 
+// timezoneEnv returns env with TZ set to use the container's timezone file,
+// unless TZ is already set.
+func timezoneEnv(env []string) []string {
+	for _, e := range env {
+		if strings.HasPrefix(e, "TZ=") {
+			return env
+		}
+	}
+	return append(env[:len(env):len(env)], configs.LocaltimeEnv)
+}
+
 // ignoreTerminateErrors returns nil if the given err matches an error known
 // to indicate that the terminate occurred successfully or err was nil, otherwise
 // err is returned unaltered.
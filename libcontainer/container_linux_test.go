@@ -286,3 +286,33 @@ func TestGetContainerStateAfterUpdate(t *testing.T) {
 		t.Fatalf("expected Memory to be 2048 but received %q", state.Config.Cgroups.Memory)
 	}
 }
+
+func TestNewInitConfigSeccomp(t *testing.T) {
+	containerSeccomp := &configs.Seccomp{DefaultAction: configs.Allow}
+	container := &Container{
+		id: "myid",
+		config: &configs.Config{
+			Seccomp: containerSeccomp,
+		},
+		cgroupManager: &mockCgroupManager{},
+	}
+
+	cfg := container.newInitConfig(&Process{})
+	if cfg.Config.Seccomp != containerSeccomp {
+		t.Fatal("expected the container's seccomp profile")
+	}
+
+	own := &configs.Seccomp{DefaultAction: configs.Errno}
+	cfg = container.newInitConfig(&Process{Seccomp: own})
+	if cfg.Config.Seccomp != own {
+		t.Fatal("expected the process' own seccomp profile")
+	}
+	cfg = container.newInitConfig(&Process{SeccompUnconfined: true})
+	if cfg.Config.Seccomp != nil {
+		t.Fatal("expected no seccomp profile")
+	}
+	// The container config is left as is.
+	if container.config.Seccomp != containerSeccomp {
+		t.Fatal("container config changed")
+	}
+}
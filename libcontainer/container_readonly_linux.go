@@ -0,0 +1,47 @@
+package libcontainer
+
+// ReadOnlyContainer is a restricted view of a container, which only allows
+// to query its state, statistics, and processes. It is meant to be used by
+// monitoring tools, which don't need (and should not have) the privileges to
+// manage the container.
+type ReadOnlyContainer struct {
+	c *Container
+}
+
+// LoadReadOnly is like Load, but returns a read-only view of the container.
+//
+// It only requires read access to the container state and the container's
+// cgroup files, and never uses the exec fifo or the systemd cgroup manager.
+func LoadReadOnly(root, id string) (*ReadOnlyContainer, error) {
+	c, err := load(root, id, true)
+	if err != nil {
+		return nil, err
+	}
+	return &ReadOnlyContainer{c: c}, nil
+}
+
+// ID returns the container's unique ID.
+func (r *ReadOnlyContainer) ID() string {
+	return r.c.ID()
+}
+
+// Status returns the current status of the container.
+func (r *ReadOnlyContainer) Status() (Status, error) {
+	return r.c.Status()
+}
+
+// State returns the current container's state information.
+func (r *ReadOnlyContainer) State() (*State, error) {
+	return r.c.State()
+}
+
+// Stats returns statistics for the container.
+func (r *ReadOnlyContainer) Stats() (*Stats, error) {
+	return r.c.Stats()
+}
+
+// Processes returns the PIDs inside this container. The PIDs are in the
+// namespace of the calling process.
+func (r *ReadOnlyContainer) Processes() ([]int, error) {
+	return r.c.Processes()
+}
@@ -0,0 +1,167 @@
+package libcontainer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/szcdx/runc/libcontainer/cgroups"
+	"github.com/szcdx/runc/libcontainer/utils"
+)
+
+// cpuPoolFilename is the name of the file in the root directory which keeps
+// the pool of exclusive CPUs and their allocations.
+const cpuPoolFilename = "cpupool.json"
+
+// CPUPool is the pool of CPUs from which the containers created with
+// configs.Config.ExclusiveCPUs set get their CPUs. Every CPU of the pool
+// is given to at most one container at a time.
+type CPUPool struct {
+	// CPUs are the CPUs of the pool, in the cpuset format (e.g. "2-7").
+	CPUs string `json:"cpus"`
+	// Allocated are the CPUs allocated to the containers, by container ID.
+	Allocated map[string]string `json:"allocated,omitempty"`
+}
+
+// GetCPUPool returns the pool of exclusive CPUs of the root directory. If
+// the pool was never configured, it is empty.
+func GetCPUPool(root string) (*CPUPool, error) {
+	var pool *CPUPool
+	err := withCPUPool(root, func(p *CPUPool) (bool, error) {
+		pool = p
+		return false, nil
+	})
+	return pool, err
+}
+
+// SetCPUPool sets the pool of exclusive CPUs of the root directory to cpus.
+// The CPUs which are currently allocated must remain in the pool.
+func SetCPUPool(root, cpus string) error {
+	list, err := cgroups.ParseCPUList(cpus)
+	if err != nil {
+		return fmt.Errorf("invalid CPU pool %q: %w", cpus, err)
+	}
+	inPool := make(map[int]bool, len(list))
+	for _, cpu := range list {
+		inPool[cpu] = true
+	}
+	return withCPUPool(root, func(p *CPUPool) (bool, error) {
+		for id, allocated := range p.Allocated {
+			cpus, _ := cgroups.ParseCPUList(allocated)
+			for _, cpu := range cpus {
+				if !inPool[cpu] {
+					return false, fmt.Errorf("CPU %d is allocated to container %s", cpu, id)
+				}
+			}
+		}
+		p.CPUs = cgroups.FormatCPUList(list)
+		return true, nil
+	})
+}
+
+// allocateCPUs allocates n CPUs from the pool of root to the container id,
+// and returns them in the cpuset format.
+func allocateCPUs(root, id string, n int) (string, error) {
+	var allocated string
+	err := withCPUPool(root, func(p *CPUPool) (bool, error) {
+		pool, err := cgroups.ParseCPUList(p.CPUs)
+		if err != nil {
+			return false, fmt.Errorf("invalid CPU pool: %w", err)
+		}
+		used := make(map[int]bool)
+		for otherID, cpus := range p.Allocated {
+			// Drop the allocations of the containers which are gone
+			// without being destroyed.
+			if _, err := os.Stat(filepath.Join(root, otherID)); errors.Is(err, os.ErrNotExist) {
+				delete(p.Allocated, otherID)
+				continue
+			}
+			list, _ := cgroups.ParseCPUList(cpus)
+			for _, cpu := range list {
+				used[cpu] = true
+			}
+		}
+		var cpus []int
+		for _, cpu := range pool {
+			if len(cpus) == n {
+				break
+			}
+			if !used[cpu] {
+				cpus = append(cpus, cpu)
+			}
+		}
+		if len(cpus) < n {
+			return false, fmt.Errorf("unable to allocate %d exclusive CPUs: only %d CPUs of the pool (%q) are available", n, len(cpus), p.CPUs)
+		}
+		allocated = cgroups.FormatCPUList(cpus)
+		if p.Allocated == nil {
+			p.Allocated = make(map[string]string)
+		}
+		p.Allocated[id] = allocated
+		return true, nil
+	})
+	return allocated, err
+}
+
+// releaseCPUs returns the CPUs allocated to the container id to the pool.
+func releaseCPUs(root, id string) error {
+	return withCPUPool(root, func(p *CPUPool) (bool, error) {
+		if _, ok := p.Allocated[id]; !ok {
+			return false, nil
+		}
+		delete(p.Allocated, id)
+		return true, nil
+	})
+}
+
+// withCPUPool calls fn with the CPU pool of root, while holding a lock on
+// it, and saves the pool afterwards if fn returns true.
+func withCPUPool(root string, fn func(*CPUPool) (bool, error)) error {
+	if err := os.MkdirAll(root, 0o700); err != nil {
+		return err
+	}
+	dir, err := os.OpenFile(root, unix.O_DIRECTORY|unix.O_RDONLY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	if err := unix.Flock(int(dir.Fd()), unix.LOCK_EX); err != nil {
+		return &os.PathError{Op: "flock", Path: root, Err: err}
+	}
+	defer unix.Flock(int(dir.Fd()), unix.LOCK_UN) //nolint: errcheck
+
+	path := filepath.Join(root, cpuPoolFilename)
+	pool := &CPUPool{}
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, pool); err != nil {
+			return fmt.Errorf("unable to parse %s: %w", path, err)
+		}
+	}
+
+	save, err := fn(pool)
+	if err != nil || !save {
+		return err
+	}
+	tmpFile, err := os.CreateTemp(root, cpuPoolFilename+"-")
+	if err != nil {
+		return err
+	}
+	if err := utils.WriteJSON(tmpFile, pool); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpFile.Name())
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		os.Remove(tmpFile.Name())
+		return err
+	}
+	return os.Rename(tmpFile.Name(), path)
+}
@@ -0,0 +1,86 @@
+package libcontainer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/szcdx/runc/libcontainer/configs"
+)
+
+func TestCPUPool(t *testing.T) {
+	root := t.TempDir()
+	// allocateCPUs expects the state directories to exist.
+	for _, id := range []string{"a", "b", "c"} {
+		if err := os.Mkdir(filepath.Join(root, id), 0o700); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := allocateCPUs(root, "a", 1); err == nil {
+		t.Fatal("expected error allocating from an empty pool, got nil")
+	}
+	if err := SetCPUPool(root, "2-5"); err != nil {
+		t.Fatal(err)
+	}
+	cpus, err := allocateCPUs(root, "a", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cpus != "2-4" {
+		t.Fatalf("expected 2-4, got %q", cpus)
+	}
+	if _, err := allocateCPUs(root, "b", 2); err == nil {
+		t.Fatal("expected error allocating more CPUs than available, got nil")
+	}
+	if cpus, err = allocateCPUs(root, "b", 1); err != nil {
+		t.Fatal(err)
+	}
+	if cpus != "5" {
+		t.Fatalf("expected 5, got %q", cpus)
+	}
+	// The allocated CPUs must stay in the pool.
+	if err := SetCPUPool(root, "2-4"); err == nil {
+		t.Fatal("expected error removing allocated CPUs from the pool, got nil")
+	}
+
+	if err := releaseCPUs(root, "a"); err != nil {
+		t.Fatal(err)
+	}
+	// The allocation of a container which is gone is dropped.
+	if err := os.Remove(filepath.Join(root, "b")); err != nil {
+		t.Fatal(err)
+	}
+	if cpus, err = allocateCPUs(root, "c", 4); err != nil {
+		t.Fatal(err)
+	}
+	if cpus != "2-5" {
+		t.Fatalf("expected 2-5, got %q", cpus)
+	}
+
+	pool, err := GetCPUPool(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pool.CPUs != "2-5" || len(pool.Allocated) != 1 || pool.Allocated["c"] != "2-5" {
+		t.Fatalf("unexpected pool: %+v", pool)
+	}
+}
+
+func TestCPUPoolFilenameID(t *testing.T) {
+	if err := validateID(cpuPoolFilename); err == nil {
+		t.Fatalf("expected %q to be an invalid ID", cpuPoolFilename)
+	}
+}
+
+func TestWithOwnCgroups(t *testing.T) {
+	config := &configs.Config{
+		ExclusiveCPUs: 2,
+		Cgroups:       &configs.Cgroup{Resources: &configs.Resources{}},
+	}
+	c := withOwnCgroups(config)
+	c.Cgroups.Resources.CpusetCpus = "2-3"
+	if config.Cgroups.Resources.CpusetCpus != "" {
+		t.Fatal("expected the caller's config to be left unchanged")
+	}
+}
@@ -0,0 +1,162 @@
+// Package criu finds out which checkpoint/restore features criu(8) (and the
+// kernel) support, so that the ones a checkpoint or a migration strategy
+// depends on can be checked for up front.
+package criu
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	gocriu "github.com/checkpoint-restore/go-criu/v6"
+	"github.com/checkpoint-restore/go-criu/v6/rpc"
+	"golang.org/x/sys/unix"
+	"google.golang.org/protobuf/proto"
+)
+
+// The features checked with the feature check RPC of criu. Other features
+// are checked with criu check --feature, and have its names (such as "tun",
+// "memfd_hugetlb" or "sk_unix_file").
+const (
+	// MemTrack is the tracking of memory changes, for iterative dumps.
+	MemTrack = "mem_track"
+	// LazyPages is the restore of memory pages on demand, with userfaultfd.
+	LazyPages = "lazy_pages"
+	// PidfdStore is the storage of pidfds to detect pid reuse between
+	// iterative dumps.
+	PidfdStore = "pidfd_store"
+)
+
+// Path is the path (or the name, looked up in PATH) of the criu binary.
+var Path = "criu"
+
+// ErrNotFound is returned if the criu binary is not found.
+var ErrNotFound = errors.New("criu not found")
+
+// Version returns the version of criu, as a number such as 31700 for 3.17.
+func Version() (int, error) {
+	if _, err := exec.LookPath(Path); err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrNotFound, err)
+	}
+	c := gocriu.MakeCriu()
+	c.SetCriuPath(Path)
+	return c.GetCriuVersion()
+}
+
+// FeatureCheck returns which of the features are supported by criu and the
+// kernel. The features criu does not know about (such as the ones added by
+// newer criu versions) are left out of the result.
+func FeatureCheck(features ...string) (map[string]bool, error) {
+	if _, err := exec.LookPath(Path); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotFound, err)
+	}
+	supported := make(map[string]bool, len(features))
+	var (
+		req     rpc.CriuFeatures
+		withRPC bool
+	)
+	for _, f := range features {
+		switch f {
+		case MemTrack:
+			req.MemTrack = proto.Bool(true)
+		case LazyPages:
+			req.LazyPages = proto.Bool(true)
+		case PidfdStore:
+			req.PidfdStore = proto.Bool(true)
+		default:
+			ok, known, err := checkFeature(f)
+			if err != nil {
+				return nil, err
+			}
+			if known {
+				supported[f] = ok
+			}
+			continue
+		}
+		withRPC = true
+	}
+	if !withRPC {
+		return supported, nil
+	}
+
+	// Not using the FeatureCheck of go-criu, which returns the features
+	// of the request rather than the ones of the response.
+	resp, err := featureCheckRPC(&req)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range features {
+		switch f {
+		case MemTrack:
+			supported[f] = resp.GetMemTrack()
+		case LazyPages:
+			supported[f] = resp.GetLazyPages()
+		case PidfdStore:
+			supported[f] = resp.GetPidfdStore()
+		}
+	}
+	return supported, nil
+}
+
+// featureCheckRPC sends a feature check request for features to criu swrk,
+// and returns the features of the response.
+func featureCheckRPC(features *rpc.CriuFeatures) (*rpc.CriuFeatures, error) {
+	fds, err := unix.Socketpair(unix.AF_LOCAL, unix.SOCK_SEQPACKET|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, os.NewSyscallError("socketpair", err)
+	}
+	client := os.NewFile(uintptr(fds[0]), "criu-transport-client")
+	server := os.NewFile(uintptr(fds[1]), "criu-transport-server")
+	defer client.Close()
+
+	cmd := exec.Command(Path, "swrk", "3")
+	cmd.ExtraFiles = []*os.File{server}
+	err = cmd.Start()
+	server.Close()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		client.Close()
+		_ = cmd.Wait()
+	}()
+
+	t := rpc.CriuReqType_FEATURE_CHECK
+	data, err := proto.Marshal(&rpc.CriuReq{Type: &t, Features: features})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := client.Write(data); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 2*unix.Getpagesize())
+	n, err := client.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	resp := &rpc.CriuResp{}
+	if err := proto.Unmarshal(buf[:n], resp); err != nil {
+		return nil, err
+	}
+	if resp.GetType() != t || !resp.GetSuccess() {
+		return nil, fmt.Errorf("criu feature check failed (errno %d)", resp.GetCrErrno())
+	}
+	return resp.GetFeatures(), nil
+}
+
+// checkFeature checks the feature with criu check --feature, which fails
+// both if the feature is not supported and if criu does not know about it,
+// telling so in the latter case.
+func checkFeature(feature string) (supported, known bool, _ error) {
+	out, err := exec.Command(Path, "check", "--feature", feature).CombinedOutput()
+	if err == nil {
+		return true, true, nil
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return false, false, err
+	}
+	return false, !bytes.Contains(out, []byte("Unknown feature")), nil
+}
@@ -0,0 +1,103 @@
+package criu
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/checkpoint-restore/go-criu/v6/rpc"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestMain(m *testing.M) {
+	// The test binary is also a fake criu swrk, for TestFeatureCheckRPC.
+	if os.Getenv("_FAKE_CRIU_SWRK") == "1" {
+		fakeSwrk()
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// fakeSwrk answers a feature check request on fd 3, as a criu supporting
+// lazy pages but not memory tracking or pidfd store would.
+func fakeSwrk() {
+	conn := os.NewFile(3, "criu-transport-server")
+	buf := make([]byte, 4096)
+	n, err := conn.Read(buf)
+	if err != nil {
+		os.Exit(1)
+	}
+	req := &rpc.CriuReq{}
+	if err := proto.Unmarshal(buf[:n], req); err != nil {
+		os.Exit(1)
+	}
+	resp := &rpc.CriuResp{
+		Type:    req.Type,
+		Success: proto.Bool(true),
+		Features: &rpc.CriuFeatures{
+			MemTrack:   proto.Bool(false),
+			LazyPages:  proto.Bool(true),
+			PidfdStore: proto.Bool(false),
+		},
+	}
+	data, err := proto.Marshal(resp)
+	if err != nil {
+		os.Exit(1)
+	}
+	if _, err := conn.Write(data); err != nil {
+		os.Exit(1)
+	}
+}
+
+func TestFeatureCheckNotFound(t *testing.T) {
+	defer func(path string) { Path = path }(Path)
+	Path = filepath.Join(t.TempDir(), "criu")
+	if _, err := FeatureCheck(MemTrack); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if _, err := Version(); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestFeatureCheckCheck(t *testing.T) {
+	defer func(path string) { Path = path }(Path)
+	// A fake criu, supporting "tun", and not knowing about "future".
+	Path = filepath.Join(t.TempDir(), "criu")
+	script := `#!/bin/sh
+[ "$1 $2 $3" = "check --feature tun" ] && exit 0
+[ "$3" = "future" ] && echo "Error (criu/cr-check.c:1): Unknown feature future" >&2
+exit 1
+`
+	if err := os.WriteFile(Path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	supported, err := FeatureCheck("tun", "timens", "future")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !supported["tun"] || supported["timens"] || len(supported) != 2 {
+		t.Fatalf("unexpected features: %v", supported)
+	}
+	if _, ok := supported["future"]; ok {
+		t.Fatalf("unknown feature reported: %v", supported)
+	}
+}
+
+func TestFeatureCheckRPC(t *testing.T) {
+	defer func(path string) { Path = path }(Path)
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	Path = exe
+	t.Setenv("_FAKE_CRIU_SWRK", "1")
+	supported, err := FeatureCheck(MemTrack, LazyPages, PidfdStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if supported[MemTrack] || !supported[LazyPages] || supported[PidfdStore] || len(supported) != 3 {
+		t.Fatalf("unexpected features: %v", supported)
+	}
+}
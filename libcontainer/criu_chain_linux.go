@@ -0,0 +1,142 @@
+package libcontainer
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/checkpoint-restore/go-criu/v6/stats"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// CheckpointChain is the chain of the dumps of a container made by
+// Checkpoint: pre-dumps, each one but the first on top of the previous
+// one, possibly ended by a dump.
+type CheckpointChain struct {
+	Dumps []CheckpointDump `json:"dumps"`
+}
+
+// CheckpointDump is a dump of a container in a CheckpointChain.
+type CheckpointDump struct {
+	// Iteration is the number of the dump in the chain, from 1.
+	Iteration int `json:"iteration"`
+	// ImagesDirectory is the absolute path of the images of the dump.
+	ImagesDirectory string `json:"images_directory"`
+	// Parent is the path of the images of the previous dump, relative
+	// to ImagesDirectory, if any.
+	Parent  string    `json:"parent,omitempty"`
+	PreDump bool      `json:"pre_dump,omitempty"`
+	Created time.Time `json:"created"`
+	// The memory pages scanned, skipped as they are unchanged since
+	// the parent dump, and written, as reported by criu.
+	PagesScanned       uint64 `json:"pages_scanned"`
+	PagesSkippedParent uint64 `json:"pages_skipped_parent"`
+	PagesWritten       uint64 `json:"pages_written"`
+}
+
+// last returns the last dump of the chain, or nil.
+func (ch *CheckpointChain) last() *CheckpointDump {
+	if ch == nil || len(ch.Dumps) == 0 {
+		return nil
+	}
+	return &ch.Dumps[len(ch.Dumps)-1]
+}
+
+// chainParent returns the path, relative to imagesDir, of the images of the
+// last pre-dump of the container, to use as the parent of a dump to
+// imagesDir, or "" if the container has no such pre-dump. The images of all
+// the dumps of the chain must still be there.
+func (c *Container) chainParent(imagesDir string) (string, error) {
+	last := c.checkpointChain.last()
+	if last == nil || !last.PreDump {
+		return "", nil
+	}
+	for _, d := range c.checkpointChain.Dumps {
+		fi, err := os.Stat(d.ImagesDirectory)
+		if err == nil && !fi.IsDir() {
+			err = &os.PathError{Op: "stat", Path: d.ImagesDirectory, Err: unix.ENOTDIR}
+		}
+		if err != nil {
+			return "", fmt.Errorf("broken checkpoint chain (use --parent-path to start a new one): dump %d: %w", d.Iteration, err)
+		}
+	}
+	dir, err := filepath.Abs(imagesDir)
+	if err != nil {
+		return "", err
+	}
+	if dir == last.ImagesDirectory {
+		return "", fmt.Errorf("images directory %s is the one of the previous dump", dir)
+	}
+	return filepath.Rel(dir, last.ImagesDirectory)
+}
+
+// recordCheckpoint adds the dump just made with criuOpts to the checkpoint
+// chain of the container, or starts a new chain with it if it is not on top
+// of the last dump of the chain.
+func (c *Container) recordCheckpoint(criuOpts *CriuOpts) error {
+	dir, err := filepath.Abs(criuOpts.ImagesDirectory)
+	if err != nil {
+		return err
+	}
+	d := CheckpointDump{
+		Iteration:       1,
+		ImagesDirectory: dir,
+		Parent:          criuOpts.ParentImage,
+		PreDump:         criuOpts.PreDump,
+		Created:         time.Now().UTC(),
+	}
+	if err := readDumpStats(dir, &d); err != nil {
+		// Such as with a page server, or if the images are streamed.
+		logrus.Debugf("no checkpoint statistics: %v", err)
+	}
+
+	chain := c.checkpointChain
+	last := chain.last()
+	if last != nil && last.PreDump && d.Parent != "" && filepath.Join(dir, d.Parent) == last.ImagesDirectory {
+		d.Iteration = last.Iteration + 1
+		chain.Dumps = append(chain.Dumps, d)
+	} else {
+		chain = &CheckpointChain{Dumps: []CheckpointDump{d}}
+	}
+	c.checkpointChain = chain
+
+	// Unless it is left running, the container is about to be destroyed.
+	if !criuOpts.PreDump && !criuOpts.LeaveRunning {
+		return nil
+	}
+	_, err = c.updateState(nil)
+	return err
+}
+
+// readDumpStats reads the statistics of the dump with images in dir into d.
+func readDumpStats(dir string, d *CheckpointDump) error {
+	// stats.CriuGetDumpStats does not check the size of the image.
+	data, err := os.ReadFile(filepath.Join(dir, stats.StatsDump))
+	if err != nil {
+		return err
+	}
+	if len(data) < stats.PayloadOffset ||
+		uint64(len(data)) < stats.PayloadOffset+uint64(binary.LittleEndian.Uint32(data[stats.SizeOffset:])) {
+		return fmt.Errorf("%s: truncated", stats.StatsDump)
+	}
+
+	imgDir, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer imgDir.Close()
+	dump, err := stats.CriuGetDumpStats(imgDir)
+	if err != nil {
+		return fmt.Errorf("%s: %w", stats.StatsDump, err)
+	}
+	if dump == nil {
+		return fmt.Errorf("%s: no dump statistics", stats.StatsDump)
+	}
+	d.PagesScanned = dump.GetPagesScanned()
+	d.PagesSkippedParent = dump.GetPagesSkippedParent()
+	d.PagesWritten = dump.GetPagesWritten()
+	return nil
+}
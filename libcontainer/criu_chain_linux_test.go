@@ -0,0 +1,125 @@
+package libcontainer
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/checkpoint-restore/go-criu/v6/stats"
+	"google.golang.org/protobuf/proto"
+)
+
+func writeDumpStats(t *testing.T, dir string, scanned, skipped, written uint64) {
+	t.Helper()
+	var zero uint32
+	var lazy uint64
+	entry, err := proto.Marshal(&stats.StatsEntry{
+		Dump: &stats.DumpStatsEntry{
+			FreezingTime:       &zero,
+			FrozenTime:         &zero,
+			MemdumpTime:        &zero,
+			MemwriteTime:       &zero,
+			PagesScanned:       &scanned,
+			PagesSkippedParent: &skipped,
+			PagesWritten:       &written,
+			PagesLazy:          &lazy,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var data []byte
+	data = binary.LittleEndian.AppendUint32(data, stats.ImgServiceMagic)
+	data = binary.LittleEndian.AppendUint32(data, stats.StatsMagic)
+	data = binary.LittleEndian.AppendUint32(data, uint32(len(entry)))
+	data = append(data, entry...)
+	if err := os.WriteFile(filepath.Join(dir, stats.StatsDump), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadDumpStats(t *testing.T) {
+	dir := t.TempDir()
+	writeDumpStats(t, dir, 1000, 900, 100)
+	var d CheckpointDump
+	if err := readDumpStats(dir, &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.PagesScanned != 1000 || d.PagesSkippedParent != 900 || d.PagesWritten != 100 {
+		t.Errorf("unexpected stats: %+v", d)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, stats.StatsDump), []byte("garbage, not stats"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := readDumpStats(dir, &d); err == nil {
+		t.Error("expected error for bad stats, got nil")
+	}
+}
+
+func TestCheckpointChain(t *testing.T) {
+	base := t.TempDir()
+	one := filepath.Join(base, "one")
+	two := filepath.Join(base, "two")
+	for _, dir := range []string{one, two} {
+		if err := os.Mkdir(dir, 0o700); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// No chain, no parent.
+	c := &Container{}
+	if parent, err := c.chainParent(two); err != nil || parent != "" {
+		t.Fatalf("expected no parent, got %q, %v", parent, err)
+	}
+
+	c.checkpointChain = &CheckpointChain{Dumps: []CheckpointDump{
+		{Iteration: 1, ImagesDirectory: one, PreDump: true},
+	}}
+	parent, err := c.chainParent(two)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parent != "../one" {
+		t.Fatalf("expected parent ../one, got %q", parent)
+	}
+	if _, err := c.chainParent(one); err == nil {
+		t.Error("expected error for the images directory of the previous dump, got nil")
+	}
+
+	// A dump on top of the pre-dump is added to the chain.
+	writeDumpStats(t, two, 1000, 900, 100)
+	if err := c.recordCheckpoint(&CriuOpts{ImagesDirectory: two, ParentImage: parent}); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(c.checkpointChain.Dumps); n != 2 {
+		t.Fatalf("expected 2 dumps, got %d", n)
+	}
+	last := c.checkpointChain.last()
+	if last.Iteration != 2 || last.ImagesDirectory != two || last.PagesWritten != 100 {
+		t.Errorf("unexpected last dump: %+v", last)
+	}
+	// The chain is ended by a dump.
+	if parent, err := c.chainParent(filepath.Join(base, "three")); err != nil || parent != "" {
+		t.Errorf("expected no parent, got %q, %v", parent, err)
+	}
+
+	// A dump not on top of the last one starts a new chain.
+	if err := c.recordCheckpoint(&CriuOpts{ImagesDirectory: one}); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(c.checkpointChain.Dumps); n != 1 || c.checkpointChain.last().Iteration != 1 {
+		t.Errorf("expected a new chain, got %+v", c.checkpointChain)
+	}
+
+	// The images of the chain must still be there.
+	c.checkpointChain = &CheckpointChain{Dumps: []CheckpointDump{
+		{Iteration: 1, ImagesDirectory: filepath.Join(base, "gone"), PreDump: true},
+		{Iteration: 2, ImagesDirectory: one, Parent: "../gone", PreDump: true},
+	}}
+	if _, err := c.chainParent(two); err == nil {
+		t.Error("expected error for a broken chain, got nil")
+	}
+}
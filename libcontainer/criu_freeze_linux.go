@@ -0,0 +1,46 @@
+package libcontainer
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/szcdx/runc/libcontainer/configs"
+)
+
+// freezeForCheckpoint freezes the container using the cgroup v2 freezer
+// before it is dumped, so that the processes of busy multi-threaded
+// containers are all stopped by the time CRIU starts to dump them. CRIU,
+// told to use the same freezer, leaves the container frozen as it was.
+//
+// The container is frozen by the cgroup manager, which waits for all its
+// tasks to be frozen. If that fails, or takes longer than timeout, the
+// container is thawed.
+//
+// It returns the function to thaw the container, which is nil if the
+// container was already paused (and so is to stay frozen).
+func (c *Container) freezeForCheckpoint(timeout time.Duration) (func(), error) {
+	state, err := c.cgroupManager.GetFreezerState()
+	if err != nil {
+		return nil, err
+	}
+	if state == configs.Frozen {
+		return nil, nil
+	}
+	thaw := func() {
+		if err := c.cgroupManager.Freeze(configs.Thawed); err != nil {
+			logrus.Warnf("unable to thaw the container: %v", err)
+		}
+	}
+	start := time.Now()
+	if err := c.cgroupManager.Freeze(configs.Frozen); err != nil {
+		thaw()
+		return nil, fmt.Errorf("unable to freeze the container: %w", err)
+	}
+	if time.Since(start) > timeout {
+		thaw()
+		return nil, fmt.Errorf("timeout of %s reached waiting for the container to freeze", timeout)
+	}
+	return thaw, nil
+}
@@ -0,0 +1,81 @@
+package libcontainer
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/szcdx/runc/libcontainer/configs"
+)
+
+// freezerCgroupManager is a mockCgroupManager recording the freezer states
+// it is set to, taking delay to freeze.
+type freezerCgroupManager struct {
+	mockCgroupManager
+	state  configs.FreezerState
+	delay  time.Duration
+	err    error
+	states []configs.FreezerState
+}
+
+func (m *freezerCgroupManager) Freeze(state configs.FreezerState) error {
+	m.states = append(m.states, state)
+	if state == configs.Frozen {
+		time.Sleep(m.delay)
+		if m.err != nil {
+			return m.err
+		}
+	}
+	m.state = state
+	return nil
+}
+
+func (m *freezerCgroupManager) GetFreezerState() (configs.FreezerState, error) {
+	return m.state, nil
+}
+
+func TestFreezeForCheckpoint(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		m       *freezerCgroupManager
+		wantErr bool
+		states  []configs.FreezerState
+	}{
+		{
+			name:   "frozen",
+			m:      &freezerCgroupManager{state: configs.Thawed},
+			states: []configs.FreezerState{configs.Frozen, configs.Thawed},
+		},
+		{
+			name: "paused",
+			m:    &freezerCgroupManager{state: configs.Frozen},
+		},
+		{
+			name:    "timeout",
+			m:       &freezerCgroupManager{state: configs.Thawed, delay: 100 * time.Millisecond},
+			wantErr: true,
+			states:  []configs.FreezerState{configs.Frozen, configs.Thawed},
+		},
+		{
+			name:    "error",
+			m:       &freezerCgroupManager{state: configs.Thawed, err: errors.New("freezer not supported")},
+			wantErr: true,
+			states:  []configs.FreezerState{configs.Frozen, configs.Thawed},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &Container{cgroupManager: tc.m}
+			thaw, err := c.freezeForCheckpoint(50 * time.Millisecond)
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("expected error: %t, got %v", tc.wantErr, err)
+			}
+			if thaw != nil {
+				thaw()
+			}
+			if !reflect.DeepEqual(tc.m.states, tc.states) {
+				t.Fatalf("expected the freezer states %v, got %v", tc.states, tc.m.states)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package libcontainer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// lazyPagesLogFile is the log file of the lazy pages daemon, in the
+	// CRIU work directory.
+	lazyPagesLogFile = "lazy-pages.log"
+	// lazyPagesReadyTimeout is how long the lazy pages daemon is given to
+	// connect to the page server and get ready to serve the pages.
+	lazyPagesReadyTimeout = 30 * time.Second
+)
+
+// lazyPagesDaemon is the "criu lazy-pages" daemon, started by Restore for a
+// lazy migration (see CriuOpts.LazyPages) to fetch the memory pages of the
+// restored processes from the page server of the checkpointing side, as
+// they fault them in. It exits once all the pages are transferred.
+type lazyPagesDaemon struct {
+	cmd    *exec.Cmd
+	logDir string
+	done   chan error
+}
+
+// startLazyPagesDaemon starts the lazy pages daemon for the page server of
+// opts, and waits for it to be ready. It has to be so before the restore
+// is started, as CRIU connects to it to hand over the processes' memory.
+func startLazyPagesDaemon(opts *CriuOpts) (*lazyPagesDaemon, error) {
+	logDir := opts.ImagesDirectory
+	args := []string{
+		"lazy-pages", "--page-server",
+		"--address", opts.PageServer.Address,
+		"--port", strconv.Itoa(int(opts.PageServer.Port)),
+		"--images-dir", opts.ImagesDirectory,
+		"--log-file", lazyPagesLogFile,
+		"--status-fd", "3",
+	}
+	// CRIU finds the daemon's socket in its work directory, so they both
+	// have to use the same one.
+	if opts.WorkDirectory != "" {
+		args = append(args, "--work-dir", opts.WorkDirectory)
+		logDir = opts.WorkDirectory
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	cmd := exec.Command("criu", args...)
+	cmd.ExtraFiles = []*os.File{w}
+	// The daemon outlives a detached restore, and must not get the
+	// signals sent to the runc process group.
+	cmd.SysProcAttr = &unix.SysProcAttr{Setsid: true}
+	err = cmd.Start()
+	w.Close()
+	if err != nil {
+		return nil, fmt.Errorf("unable to start criu lazy-pages: %w", err)
+	}
+	d := &lazyPagesDaemon{cmd: cmd, logDir: logDir, done: make(chan error, 1)}
+	go func() {
+		d.done <- cmd.Wait()
+	}()
+
+	ready := make(chan error, 1)
+	go func() {
+		// The daemon writes a byte once it is ready; EOF means it has
+		// exited without being so.
+		_, err := r.Read(make([]byte, 1))
+		ready <- err
+	}()
+	timer := time.NewTimer(lazyPagesReadyTimeout)
+	defer timer.Stop()
+	select {
+	case err := <-ready:
+		if err == nil {
+			logrus.Debugf("criu lazy-pages (pid %d) is ready", cmd.Process.Pid)
+			return d, nil
+		}
+		return nil, d.error(<-d.done)
+	case <-timer.C:
+		_ = cmd.Process.Kill()
+		<-d.done
+		return nil, d.error(errors.New("timeout waiting for it to be ready"))
+	}
+}
+
+// error returns the error the daemon failed with, logging its errors.
+func (d *lazyPagesDaemon) error(err error) error {
+	logCriuErrors(d.logDir, lazyPagesLogFile)
+	if err == nil {
+		err = errors.New("exited")
+	}
+	return fmt.Errorf("criu lazy-pages failed: %w (see %s)", err, filepath.Join(d.logDir, lazyPagesLogFile))
+}
+
+// finish kills the daemon if the restore failed with restoreErr. Otherwise,
+// the daemon is left to transfer the rest of the pages, and its failure (if
+// runc is still there by then) is logged.
+func (d *lazyPagesDaemon) finish(restoreErr error) {
+	if restoreErr != nil {
+		_ = d.cmd.Process.Kill()
+		<-d.done
+		return
+	}
+	go func() {
+		var exitErr *exec.ExitError
+		// Other errors mean the daemon has been reaped by the runc
+		// process itself (as a child subreaper).
+		if err := <-d.done; errors.As(err, &exitErr) {
+			logrus.Warn(d.error(err))
+		}
+	}()
+}
@@ -300,6 +300,24 @@ func (c *Container) Checkpoint(criuOpts *CriuOpts) error {
 		return errors.New("invalid directory to save checkpoint")
 	}
 
+	if err := c.checkSeccompNotify(); err != nil {
+		return err
+	}
+	if err := c.checkManagedMounts(); err != nil {
+		return err
+	}
+
+	if criuOpts.Stream != "" {
+		if err := c.checkCriuVersion(31600); err != nil {
+			return errors.New("--stream requires at least CRIU 3.16")
+		}
+		// The streamed images are the ones of a full dump, which are
+		// all in the stream.
+		if criuOpts.PreDump || criuOpts.LazyPages || criuOpts.PageServer.Address != "" || criuOpts.ParentImage != "" {
+			return errors.New("--stream can't be used together with --pre-dump, --lazy-pages, --page-server or --parent-path")
+		}
+	}
+
 	// Since a container can be C/R'ed multiple times,
 	// the checkpoint directory may already exist.
 	if err := os.Mkdir(criuOpts.ImagesDirectory, 0o700); err != nil && !os.IsExist(err) {
@@ -348,6 +366,10 @@ func (c *Container) Checkpoint(criuOpts *CriuOpts) error {
 
 	c.handleCriuConfigurationFile(&rpcOpts)
 
+	if err := c.setCriuNetworkOpts(criuOpts, &rpcOpts); err != nil {
+		return err
+	}
+
 	// If the container is running in a network namespace and has
 	// a path to the network namespace configured, we will dump
 	// that network namespace as an external namespace and we
@@ -380,10 +402,25 @@ func (c *Container) Checkpoint(criuOpts *CriuOpts) error {
 		}
 	}
 
+	if criuOpts.TrackMem {
+		rpcOpts.TrackMem = proto.Bool(true)
+		if criuOpts.ParentImage == "" && criuOpts.Stream == "" {
+			criuOpts.ParentImage, err = c.chainParent(criuOpts.ImagesDirectory)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
 	// pre-dump may need parentImage param to complete iterative migration
 	if criuOpts.ParentImage != "" {
 		rpcOpts.ParentImg = proto.String(criuOpts.ParentImage)
 		rpcOpts.TrackMem = proto.Bool(true)
+		// With sparse images, also drop the pages of the parent
+		// images which are superseded by the new ones.
+		if criuOpts.SparseImages {
+			rpcOpts.AutoDedup = proto.Bool(true)
+		}
 	}
 
 	// append optional manage cgroups mode
@@ -467,6 +504,10 @@ func (c *Container) Checkpoint(criuOpts *CriuOpts) error {
 			return err
 		}
 
+		for _, m := range c.procLimitsMounts(c.config.Mounts) {
+			c.addCriuDumpMount(req, m)
+		}
+
 		for _, node := range c.config.Devices {
 			m := &configs.Mount{Destination: node.Path, Source: node.Path}
 			c.addCriuDumpMount(req, m)
@@ -482,16 +523,82 @@ func (c *Container) Checkpoint(criuOpts *CriuOpts) error {
 		if err != nil {
 			return err
 		}
+
+	}
+
+	if criuOpts.FreezeTimeout > 0 {
+		switch {
+		case !cgroups.IsCgroup2UnifiedMode():
+			logrus.Warn("freeze timeout is ignored on cgroup v1, where criu freezes the container itself")
+		case rpcOpts.FreezeCgroup == nil:
+			return errors.New("freezing the container before the checkpoint requires the cgroup v2 freezer, and criu 3.14 or later")
+		default:
+			thaw, err := c.freezeForCheckpoint(criuOpts.FreezeTimeout)
+			if err != nil {
+				return err
+			}
+			// With LeaveRunning or PreDump, the container is to
+			// go on running, and otherwise its processes are gone
+			// anyway (unless the dump fails).
+			if thaw != nil {
+				defer thaw()
+			}
+		}
+	}
+
+	var streamer *imageStreamer
+	if criuOpts.Stream != "" {
+		if err := setStreamConfig(&rpcOpts, criuOpts.ImagesDirectory); err != nil {
+			return err
+		}
+		streamer, err = dialImageStream(criuOpts.Stream, criuOpts.ImagesDirectory)
+		if err != nil {
+			return err
+		}
 	}
 
 	err = c.criuSwrk(nil, req, criuOpts, nil)
+	if streamer != nil {
+		if serr := streamer.finish(err); err == nil {
+			err = serr
+		}
+	}
 	if err != nil {
 		logCriuErrors(logDir, logFile)
 		return err
 	}
+	if err := c.recordCheckpoint(criuOpts); err != nil {
+		return err
+	}
+	// With a page server, or if the images are streamed, the memory pages
+	// are not in the images directory.
+	if criuOpts.SparseImages && rpcOpts.Ps == nil && criuOpts.Stream == "" {
+		sparsifyCheckpointImages(criuOpts)
+	}
 	return nil
 }
 
+// runNetworkRestoreHooks runs the NetworkRestore hooks of the container, and
+// then the ones of opts, with the network namespace of the restored init
+// (with pid) open as fd 3.
+func (c *Container) runNetworkRestoreHooks(pid int, opts *CriuOpts) error {
+	hooks := append(configs.HookList{}, c.config.Hooks[configs.NetworkRestore]...)
+	hooks = append(hooks, opts.NetworkRestoreHooks...)
+	if len(hooks) == 0 {
+		return nil
+	}
+	netns, err := os.Open(fmt.Sprintf("/proc/%d/ns/net", pid))
+	if err != nil {
+		return err
+	}
+	defer netns.Close()
+	s, err := c.currentOCIState()
+	if err != nil {
+		return err
+	}
+	return configs.Hooks{configs.NetworkRestore: hooks}.RunWithFiles(configs.NetworkRestore, s, netns)
+}
+
 func (c *Container) addCriuRestoreMount(req *criurpc.CriuReq, m *configs.Mount) {
 	mountDest := strings.TrimPrefix(m.Destination, c.config.Rootfs)
 	if dest, err := securejoin.SecureJoin(c.config.Rootfs, mountDest); err == nil {
@@ -613,6 +720,9 @@ func (c *Container) prepareCriuRestoreMounts(mounts []*configs.Mount) error {
 		}
 	}()
 	for _, m := range mounts {
+		if m.IsFdSource() {
+			return fmt.Errorf("mount %s: restoring a mount of a passed file descriptor (%s) is not supported", m.Destination, m.Source)
+		}
 		if !isPathInPrefixList(m.Destination, tmpfs) {
 			if err := c.makeCriuRestoreMountpoints(m); err != nil {
 				return err
@@ -660,6 +770,22 @@ func (c *Container) Restore(process *Process, criuOpts *CriuOpts) error {
 	if criuOpts.ImagesDirectory == "" {
 		return errors.New("invalid directory to restore checkpoint")
 	}
+	if err := c.checkManagedMounts(); err != nil {
+		return err
+	}
+	if criuOpts.Resources != nil {
+		c.config.Cgroups.Resources = criuOpts.Resources
+	}
+	if criuOpts.Template {
+		if err := c.checkTemplateRestore(criuOpts); err != nil {
+			return err
+		}
+		// The logs of the containers restored from the images
+		// are kept apart.
+		if criuOpts.WorkDirectory == "" {
+			criuOpts.WorkDirectory = filepath.Join(c.stateDir, "criu-work")
+		}
+	}
 	logDir := criuOpts.ImagesDirectory
 	imageDir, err := os.Open(criuOpts.ImagesDirectory)
 	if err != nil {
@@ -738,6 +864,9 @@ func (c *Container) Restore(process *Process, criuOpts *CriuOpts) error {
 		logDir = criuOpts.WorkDirectory
 	}
 	c.handleCriuConfigurationFile(req.Opts)
+	if err := c.setCriuNetworkOpts(criuOpts, req.Opts); err != nil {
+		return err
+	}
 
 	if err := c.handleRestoringNamespaces(req.Opts, &extraFiles); err != nil {
 		return err
@@ -774,6 +903,15 @@ func (c *Container) Restore(process *Process, criuOpts *CriuOpts) error {
 		c.addCriuRestoreMount(req, m)
 	}
 
+	if binds := c.procLimitsMounts(c.config.Mounts); len(binds) > 0 {
+		if err := c.writeProcLimits(c.config.Cgroups.Resources); err != nil {
+			return err
+		}
+		for _, m := range binds {
+			c.addCriuRestoreMount(req, m)
+		}
+	}
+
 	for _, node := range c.config.Devices {
 		m := &configs.Mount{Destination: node.Path, Source: node.Path}
 		c.addCriuRestoreMount(req, m)
@@ -781,6 +919,11 @@ func (c *Container) Restore(process *Process, criuOpts *CriuOpts) error {
 
 	if criuOpts.EmptyNs&unix.CLONE_NEWNET == 0 {
 		c.restoreNetwork(req, criuOpts)
+		if criuOpts.Template {
+			if err := c.restoreTemplateVeths(req, criuOpts); err != nil {
+				return err
+			}
+		}
 	}
 
 	// append optional manage cgroups mode
@@ -789,6 +932,27 @@ func (c *Container) Restore(process *Process, criuOpts *CriuOpts) error {
 		req.Opts.ManageCgroupsMode = &mode
 	}
 
+	// The images are received first, as they come with the descriptors.
+	var streamer *imageStreamer
+	if criuOpts.Stream != "" {
+		if err := c.checkCriuVersion(31600); err != nil {
+			return errors.New("--stream requires at least CRIU 3.16")
+		}
+		if err := setStreamConfig(req.Opts, criuOpts.ImagesDirectory); err != nil {
+			return err
+		}
+		streamer, err = acceptImageStream(criuOpts.Stream, criuOpts.ImagesDirectory)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			// Only set if the restore was not attempted.
+			if streamer != nil {
+				_ = streamer.finish(errors.New("restore not attempted"))
+			}
+		}()
+	}
+
 	var (
 		fds    []string
 		fdJSON []byte
@@ -808,10 +972,28 @@ func (c *Container) Restore(process *Process, criuOpts *CriuOpts) error {
 			req.Opts.InheritFd = append(req.Opts.InheritFd, inheritFd)
 		}
 	}
+	// For a lazy migration from a remote page server, the daemon fetching
+	// the pages is started by runc.
+	var lazy *lazyPagesDaemon
+	if criuOpts.LazyPages && criuOpts.PageServer.Address != "" {
+		lazy, err = startLazyPagesDaemon(criuOpts)
+		if err != nil {
+			return err
+		}
+	}
 	err = c.criuSwrk(process, req, criuOpts, extraFiles)
+	if streamer != nil {
+		if serr := streamer.finish(err); err == nil {
+			err = serr
+		}
+		streamer = nil
+	}
 	if err != nil {
 		logCriuErrors(logDir, logFile)
 	}
+	if lazy != nil {
+		lazy.finish(err)
+	}
 
 	// Now that CRIU is done let's close all opened FDs CRIU needed.
 	for _, fd := range extraFiles {
@@ -1131,11 +1313,33 @@ func (c *Container) criuNotifications(resp *criurpc.CriuResp, process *Process,
 		if err := unlockNetwork(c.config); err != nil {
 			return err
 		}
+		if c.networkLock != nil {
+			if err := c.networkLock.unlock(); err != nil {
+				return err
+			}
+			c.networkLock = nil
+		}
 	case "network-lock":
 		if err := lockNetwork(c.config); err != nil {
 			return err
 		}
+		if c.locksNetworkNftables(opts) {
+			l, err := lockNetworkNftables(fmt.Sprintf("/proc/%d/ns/net", c.initProcess.pid()))
+			if err != nil {
+				return err
+			}
+			c.networkLock = l
+		}
 	case "setup-namespaces":
+		// The network of the restored container stays locked until
+		// criu is done.
+		if c.locksNetworkNftables(opts) {
+			l, err := lockNetworkNftables(fmt.Sprintf("/proc/%d/ns/net", notify.GetPid()))
+			if err != nil {
+				return err
+			}
+			c.networkLock = l
+		}
 		if c.config.Hooks != nil {
 			s, err := c.currentOCIState()
 			if err != nil {
@@ -1153,6 +1357,22 @@ func (c *Container) criuNotifications(resp *criurpc.CriuResp, process *Process,
 	case "post-restore":
 		pid := notify.GetPid()
 
+		// The restored processes are not resumed yet. Set the new
+		// resources again, as CRIU may have restored the checkpointed
+		// ones (see --manage-cgroups-mode).
+		if opts.Resources != nil {
+			if err := c.cgroupManager.Set(opts.Resources); err != nil {
+				return fmt.Errorf("unable to set resources: %w", err)
+			}
+		}
+		// A container restored from a snapshot gets its own names.
+		if opts.Template && c.config.Namespaces.Contains(configs.NEWUTS) && c.config.Namespaces.PathOf(configs.NEWUTS) == "" &&
+			(c.config.Hostname != "" || c.config.Domainname != "") {
+			if err := setUTSNames(int(pid), c.config.Hostname, c.config.Domainname); err != nil {
+				return err
+			}
+		}
+
 		p, err := os.FindProcess(int(pid))
 		if err != nil {
 			return err
@@ -1170,6 +1390,15 @@ func (c *Container) criuNotifications(resp *criurpc.CriuResp, process *Process,
 		}); err != nil {
 			return err
 		}
+		// The port forwarder of the checkpointed container exited along
+		// with its init.
+		if len(c.config.PortForwards) > 0 {
+			fw, err := c.startPortForwarder(int(pid))
+			if err != nil {
+				return err
+			}
+			c.portForwarder = fw
+		}
 		// create a timestamp indicating when the restored checkpoint was started
 		c.created = time.Now().UTC()
 		if _, err := c.updateState(r); err != nil {
@@ -1180,6 +1409,9 @@ func (c *Container) criuNotifications(resp *criurpc.CriuResp, process *Process,
 				logrus.Error(err)
 			}
 		}
+		if err := c.runNetworkRestoreHooks(int(pid), opts); err != nil {
+			return err
+		}
 	case "orphan-pts-master":
 		scm, err := unix.ParseSocketControlMessage(oob)
 		if err != nil {
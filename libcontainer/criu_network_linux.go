@@ -0,0 +1,68 @@
+package libcontainer
+
+import (
+	"errors"
+
+	criurpc "github.com/checkpoint-restore/go-criu/v6/rpc"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/szcdx/runc/libcontainer/configs"
+)
+
+// criuNetworkLockSkip is the network lock method telling criu not to lock
+// the network (CRIU 3.19+), which go-criu does not know about yet.
+const criuNetworkLockSkip criurpc.CriuNetworkLockMethod = 3
+
+// setCriuNetworkOpts checks the TCP connections and network lock options
+// of criuOpts, and sets them in rpcOpts. They are the same for checkpoint
+// and restore, except TcpSkipInFlight which only matters on checkpoint.
+func (c *Container) setCriuNetworkOpts(criuOpts *CriuOpts, rpcOpts *criurpc.CriuOpts) error {
+	if criuOpts.TcpClose && criuOpts.TcpEstablished {
+		return errors.New("--tcp-close and --tcp-established can not be used together")
+	}
+	if criuOpts.TcpSkipInFlight && !criuOpts.TcpEstablished {
+		return errors.New("--tcp-skip-in-flight requires --tcp-established")
+	}
+	if criuOpts.TcpClose {
+		if err := c.checkCriuVersion(31500); err != nil {
+			return errors.New("--tcp-close requires at least CRIU 3.15")
+		}
+		rpcOpts.TcpClose = proto.Bool(true)
+	}
+	if criuOpts.TcpSkipInFlight {
+		rpcOpts.TcpSkipInFlight = proto.Bool(true)
+	}
+	switch criuOpts.NetworkLock {
+	case 0:
+		// CRIU default, iptables.
+	case criurpc.CriuNetworkLockMethod_IPTABLES:
+		rpcOpts.NetworkLock = criuOpts.NetworkLock.Enum()
+	case criurpc.CriuNetworkLockMethod_NFTABLES:
+		if !c.config.Namespaces.Contains(configs.NEWNET) || c.config.Namespaces.PathOf(configs.NEWNET) != "" {
+			return errors.New("--network-lock nftables requires the container to have its own network namespace")
+		}
+		if err := c.checkCriuVersion(31600); err != nil {
+			return errors.New("--network-lock nftables requires at least CRIU 3.16")
+		}
+		if c.locksNetworkNftables(criuOpts) {
+			// The network is locked by runc itself (see
+			// lockNetworkNftables), rather than by criu.
+			rpcOpts.NetworkLock = criuNetworkLockSkip.Enum()
+		} else {
+			// Older criu versions can't be told to skip the lock,
+			// so they lock the network with nftables themselves,
+			// provided they are built with nftables support.
+			rpcOpts.NetworkLock = criuOpts.NetworkLock.Enum()
+		}
+	default:
+		return errors.New("unsupported network lock method " + criuOpts.NetworkLock.String())
+	}
+	return nil
+}
+
+// locksNetworkNftables returns whether runc locks the network of the
+// container itself with nftables, rather than criu, which needs a criu
+// version able to skip the lock (3.19+).
+func (c *Container) locksNetworkNftables(criuOpts *CriuOpts) bool {
+	return criuOpts.NetworkLock == criurpc.CriuNetworkLockMethod_NFTABLES && c.checkCriuVersion(31900) == nil
+}
@@ -0,0 +1,63 @@
+package libcontainer
+
+import (
+	"testing"
+
+	criurpc "github.com/checkpoint-restore/go-criu/v6/rpc"
+
+	"github.com/szcdx/runc/libcontainer/configs"
+)
+
+func TestSetCriuNetworkOpts(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		criuVersion int
+		opts        CriuOpts
+		noNetns     bool
+		netnsPath   string
+		isErr       bool
+	}{
+		{name: "none", criuVersion: 30000},
+		{name: "tcp-close", criuVersion: 31500, opts: CriuOpts{TcpClose: true}},
+		{name: "tcp-close, old criu", criuVersion: 31400, opts: CriuOpts{TcpClose: true}, isErr: true},
+		{name: "tcp-close and tcp-established", criuVersion: 31500, opts: CriuOpts{TcpClose: true, TcpEstablished: true}, isErr: true},
+		{name: "skip-in-flight", criuVersion: 30000, opts: CriuOpts{TcpEstablished: true, TcpSkipInFlight: true}},
+		{name: "skip-in-flight alone", criuVersion: 30000, opts: CriuOpts{TcpSkipInFlight: true}, isErr: true},
+		{name: "iptables", criuVersion: 30000, opts: CriuOpts{NetworkLock: criurpc.CriuNetworkLockMethod_IPTABLES}},
+		{name: "nftables", criuVersion: 31900, opts: CriuOpts{NetworkLock: criurpc.CriuNetworkLockMethod_NFTABLES}},
+		{name: "nftables, criu lock", criuVersion: 31800, opts: CriuOpts{NetworkLock: criurpc.CriuNetworkLockMethod_NFTABLES}},
+		{name: "nftables, old criu", criuVersion: 31500, opts: CriuOpts{NetworkLock: criurpc.CriuNetworkLockMethod_NFTABLES}, isErr: true},
+		{name: "nftables, host netns", criuVersion: 31900, opts: CriuOpts{NetworkLock: criurpc.CriuNetworkLockMethod_NFTABLES}, noNetns: true, isErr: true},
+		{name: "nftables, joined netns", criuVersion: 31900, opts: CriuOpts{NetworkLock: criurpc.CriuNetworkLockMethod_NFTABLES}, netnsPath: "/run/netns/test", isErr: true},
+		{name: "unknown lock", criuVersion: 31900, opts: CriuOpts{NetworkLock: 42}, isErr: true},
+	} {
+		config := &configs.Config{}
+		if !tc.noNetns {
+			config.Namespaces = configs.Namespaces{{Type: configs.NEWNET, Path: tc.netnsPath}}
+		}
+		c := &Container{config: config, criuVersion: tc.criuVersion}
+		var rpcOpts criurpc.CriuOpts
+		err := c.setCriuNetworkOpts(&tc.opts, &rpcOpts)
+		if tc.isErr {
+			if err == nil {
+				t.Errorf("%s: expected error, got nil", tc.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: expected no error, got %v", tc.name, err)
+			continue
+		}
+		if rpcOpts.GetTcpClose() != tc.opts.TcpClose || rpcOpts.GetTcpSkipInFlight() != tc.opts.TcpSkipInFlight {
+			t.Errorf("%s: unexpected tcp options %v", tc.name, &rpcOpts)
+		}
+		expectedLock := tc.opts.NetworkLock
+		if expectedLock == criurpc.CriuNetworkLockMethod_NFTABLES && tc.criuVersion >= 31900 {
+			// Locked by runc, not criu.
+			expectedLock = criuNetworkLockSkip
+		}
+		if expectedLock != 0 && rpcOpts.GetNetworkLock() != expectedLock {
+			t.Errorf("%s: expected network lock %v, got %v", tc.name, expectedLock, rpcOpts.GetNetworkLock())
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package libcontainer
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/vishvananda/netlink/nl"
+	"github.com/vishvananda/netns"
+	"golang.org/x/sys/unix"
+)
+
+// With --network-lock nftables and CRIU 3.19+, runc locks the network of
+// the container itself while it is checkpointed or restored, rather than
+// criu (which is told to skip it), using an nftables table set up over
+// netlink, so that neither the iptables nor the nft binaries, nor a criu
+// built with nftables support, are needed on the host. The table drops all
+// the packets going in and out of the network namespace of the container,
+// except the ones marked by criu (see criuSocketMark), in the same way as
+// the criu iptables rules. Older criu versions lock the network with
+// nftables themselves.
+
+const (
+	// nftablesLockTable is the name of the table locking the network, of
+	// the inet family (for both IPv4 and IPv6).
+	nftablesLockTable = "runc-criu-lock"
+
+	// criuSocketMark is the mark of the packets of the sockets used by criu
+	// itself (SOCCR_MARK), which go through the lock.
+	criuSocketMark = 0xC114
+
+	nfAccept = 1
+	nfDrop   = 0
+)
+
+// nftablesLock is the nftables lock of the network namespace at nsPath.
+type nftablesLock struct {
+	nsPath string
+}
+
+// lockNetworkNftables locks the network of the network namespace at nsPath.
+func lockNetworkNftables(nsPath string) (*nftablesLock, error) {
+	msgs := []*nl.NetlinkRequest{
+		nftablesMsg(unix.NFT_MSG_NEWTABLE, unix.NLM_F_CREATE,
+			nl.NewRtAttr(unix.NFTA_TABLE_NAME, nl.ZeroTerminated(nftablesLockTable))),
+	}
+	for _, chain := range []struct {
+		name string
+		hook uint32
+	}{
+		{name: "input", hook: unix.NF_INET_LOCAL_IN},
+		{name: "output", hook: unix.NF_INET_LOCAL_OUT},
+	} {
+		hook := nl.NewRtAttr(unix.NFTA_CHAIN_HOOK|unix.NLA_F_NESTED, nil)
+		hook.AddRtAttr(unix.NFTA_HOOK_HOOKNUM, be32(chain.hook))
+		hook.AddRtAttr(unix.NFTA_HOOK_PRIORITY, be32(0))
+		msgs = append(msgs,
+			nftablesMsg(unix.NFT_MSG_NEWCHAIN, unix.NLM_F_CREATE,
+				nl.NewRtAttr(unix.NFTA_CHAIN_TABLE, nl.ZeroTerminated(nftablesLockTable)),
+				nl.NewRtAttr(unix.NFTA_CHAIN_NAME, nl.ZeroTerminated(chain.name)),
+				hook,
+				nl.NewRtAttr(unix.NFTA_CHAIN_POLICY, be32(nfDrop)),
+				nl.NewRtAttr(unix.NFTA_CHAIN_TYPE, nl.ZeroTerminated("filter"))),
+			nftablesMsg(unix.NFT_MSG_NEWRULE, unix.NLM_F_CREATE|unix.NLM_F_APPEND,
+				nl.NewRtAttr(unix.NFTA_RULE_TABLE, nl.ZeroTerminated(nftablesLockTable)),
+				nl.NewRtAttr(unix.NFTA_RULE_CHAIN, nl.ZeroTerminated(chain.name)),
+				acceptCriuMarkExprs()))
+	}
+	if err := sendNftables(nsPath, msgs); err != nil {
+		return nil, fmt.Errorf("unable to lock the network with nftables: %w", err)
+	}
+	return &nftablesLock{nsPath: nsPath}, nil
+}
+
+// unlock removes the lock, if the network namespace is still around.
+func (l *nftablesLock) unlock() error {
+	err := sendNftables(l.nsPath, []*nl.NetlinkRequest{
+		nftablesMsg(unix.NFT_MSG_DELTABLE, 0,
+			nl.NewRtAttr(unix.NFTA_TABLE_NAME, nl.ZeroTerminated(nftablesLockTable))),
+	})
+	if err != nil && !errors.Is(err, os.ErrNotExist) && !errors.Is(err, unix.ESRCH) {
+		return fmt.Errorf("unable to unlock the network with nftables: %w", err)
+	}
+	return nil
+}
+
+// acceptCriuMarkExprs returns the expressions of the rule accepting the
+// packets marked with criuSocketMark ("meta mark 0xc114 accept").
+func acceptCriuMarkExprs() *nl.RtAttr {
+	exprs := nl.NewRtAttr(unix.NFTA_RULE_EXPRESSIONS|unix.NLA_F_NESTED, nil)
+	expr := func(name string) *nl.RtAttr {
+		e := exprs.AddRtAttr(unix.NFTA_LIST_ELEM|unix.NLA_F_NESTED, nil)
+		e.AddRtAttr(unix.NFTA_EXPR_NAME, nl.ZeroTerminated(name))
+		return e.AddRtAttr(unix.NFTA_EXPR_DATA|unix.NLA_F_NESTED, nil)
+	}
+
+	meta := expr("meta")
+	meta.AddRtAttr(unix.NFTA_META_DREG, be32(unix.NFT_REG_1))
+	meta.AddRtAttr(unix.NFTA_META_KEY, be32(unix.NFT_META_MARK))
+
+	cmp := expr("cmp")
+	cmp.AddRtAttr(unix.NFTA_CMP_SREG, be32(unix.NFT_REG_1))
+	cmp.AddRtAttr(unix.NFTA_CMP_OP, be32(unix.NFT_CMP_EQ))
+	// The registers are in the host byte order.
+	cmp.AddRtAttr(unix.NFTA_CMP_DATA|unix.NLA_F_NESTED, nil).
+		AddRtAttr(unix.NFTA_DATA_VALUE, nl.Uint32Attr(criuSocketMark))
+
+	imm := expr("immediate")
+	imm.AddRtAttr(unix.NFTA_IMMEDIATE_DREG, be32(unix.NFT_REG_VERDICT))
+	imm.AddRtAttr(unix.NFTA_IMMEDIATE_DATA|unix.NLA_F_NESTED, nil).
+		AddRtAttr(unix.NFTA_DATA_VERDICT|unix.NLA_F_NESTED, nil).
+		AddRtAttr(unix.NFTA_VERDICT_CODE, be32(nfAccept))
+	return exprs
+}
+
+// nfgenmsg is the header of the nfnetlink messages.
+type nfgenmsg struct {
+	family uint8
+	resID  uint16
+}
+
+func (m *nfgenmsg) Len() int {
+	return 4
+}
+
+func (m *nfgenmsg) Serialize() []byte {
+	b := []byte{m.family, unix.NFNETLINK_V0, 0, 0}
+	binary.BigEndian.PutUint16(b[2:], m.resID)
+	return b
+}
+
+// nftablesMsg returns an nftables message of the msgType type, for the inet
+// family, with the attributes attrs. It is to be acknowledged.
+func nftablesMsg(msgType, flags int, attrs ...*nl.RtAttr) *nl.NetlinkRequest {
+	req := nl.NewNetlinkRequest(unix.NFNL_SUBSYS_NFTABLES<<8|msgType, flags|unix.NLM_F_ACK)
+	req.AddData(&nfgenmsg{family: unix.NFPROTO_INET})
+	for _, attr := range attrs {
+		req.AddData(attr)
+	}
+	return req
+}
+
+// nftablesBatch returns the serialized batch (transaction) of the nftables
+// messages msgs, which the kernel applies all at once, or not at all.
+func nftablesBatch(msgs []*nl.NetlinkRequest) []byte {
+	begin := nl.NewNetlinkRequest(unix.NFNL_MSG_BATCH_BEGIN, 0)
+	begin.AddData(&nfgenmsg{family: unix.AF_UNSPEC, resID: unix.NFNL_SUBSYS_NFTABLES})
+	end := nl.NewNetlinkRequest(unix.NFNL_MSG_BATCH_END, 0)
+	end.AddData(&nfgenmsg{family: unix.AF_UNSPEC, resID: unix.NFNL_SUBSYS_NFTABLES})
+
+	buf := begin.Serialize()
+	for _, msg := range msgs {
+		buf = append(buf, msg.Serialize()...)
+	}
+	return append(buf, end.Serialize()...)
+}
+
+// sendNftables sends the nftables messages msgs, as a batch, in the network
+// namespace at nsPath, and waits for them to be acknowledged.
+func sendNftables(nsPath string, msgs []*nl.NetlinkRequest) error {
+	ns, err := netns.GetFromPath(nsPath)
+	if err != nil {
+		return err
+	}
+	defer ns.Close()
+	s, err := nl.GetNetlinkSocketAt(ns, netns.None(), unix.NETLINK_NETFILTER)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	if err := unix.Sendto(s.GetFd(), nftablesBatch(msgs), 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		return os.NewSyscallError("sendto", err)
+	}
+	for acked := 0; acked < len(msgs); {
+		replies, _, err := s.Receive()
+		if err != nil {
+			return err
+		}
+		for _, m := range replies {
+			if m.Header.Type != unix.NLMSG_ERROR || len(m.Data) < 4 {
+				continue
+			}
+			if errno := -int32(nl.NativeEndian().Uint32(m.Data[:4])); errno != 0 {
+				return unix.Errno(errno)
+			}
+			acked++
+		}
+	}
+	return nil
+}
+
+// be32 returns v in the network byte order, as the nftables attributes
+// are (but the register data).
+func be32(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+	return b
+}
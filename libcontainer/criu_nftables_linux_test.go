@@ -0,0 +1,62 @@
+package libcontainer
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strconv"
+	"testing"
+
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+)
+
+func TestNftablesLock(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("requires root")
+	}
+	cmd := exec.Command("sleep", "30")
+	cmd.SysProcAttr = &unix.SysProcAttr{Cloneflags: unix.CLONE_NEWNET}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}()
+	nsPath := "/proc/" + strconv.Itoa(cmd.Process.Pid) + "/ns/net"
+
+	l, err := lockNetworkNftables(nsPath)
+	if errors.Is(err, unix.EPROTONOSUPPORT) || errors.Is(err, unix.EOPNOTSUPP) {
+		t.Skip("requires nftables")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Locking again is fine.
+	if _, err := lockNetworkNftables(nsPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.unlock(); err != nil {
+		t.Fatal(err)
+	}
+	delTable := func() error {
+		return sendNftables(nsPath, []*nl.NetlinkRequest{
+			nftablesMsg(unix.NFT_MSG_DELTABLE, 0,
+				nl.NewRtAttr(unix.NFTA_TABLE_NAME, nl.ZeroTerminated(nftablesLockTable))),
+		})
+	}
+	if err := delTable(); !errors.Is(err, unix.ENOENT) {
+		t.Fatalf("expected the lock table to be removed, got %v", err)
+	}
+	// Unlocking again is fine too.
+	if err := l.unlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = cmd.Process.Kill()
+	_ = cmd.Wait()
+	if err := l.unlock(); err != nil {
+		t.Fatalf("expected no error once the namespace is gone, got %v", err)
+	}
+}
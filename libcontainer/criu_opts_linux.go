@@ -1,6 +1,12 @@
 package libcontainer
 
-import criu "github.com/checkpoint-restore/go-criu/v6/rpc"
+import (
+	"time"
+
+	criu "github.com/checkpoint-restore/go-criu/v6/rpc"
+
+	"github.com/szcdx/runc/libcontainer/configs"
+)
 
 type CriuPageServerInfo struct {
 	Address string // IP address of CRIU page server
@@ -13,22 +19,32 @@ type VethPairName struct {
 }
 
 type CriuOpts struct {
-	ImagesDirectory         string             // directory for storing image files
-	WorkDirectory           string             // directory to cd and write logs/pidfiles/stats to
-	ParentImage             string             // directory for storing parent image files in pre-dump and dump
-	LeaveRunning            bool               // leave container in running state after checkpoint
-	TcpEstablished          bool               // checkpoint/restore established TCP connections
-	ExternalUnixConnections bool               // allow external unix connections
-	ShellJob                bool               // allow to dump and restore shell jobs
-	FileLocks               bool               // handle file locks, for safety
-	PreDump                 bool               // call criu predump to perform iterative checkpoint
-	PageServer              CriuPageServerInfo // allow to dump to criu page server
-	VethPairs               []VethPairName     // pass the veth to criu when restore
-	ManageCgroupsMode       criu.CriuCgMode    // dump or restore cgroup mode
-	EmptyNs                 uint32             // don't c/r properties for namespace from this mask
-	AutoDedup               bool               // auto deduplication for incremental dumps
-	LazyPages               bool               // restore memory pages lazily using userfaultfd
-	StatusFd                int                // fd for feedback when lazy server is ready
-	LsmProfile              string             // LSM profile used to restore the container
-	LsmMountContext         string             // LSM mount context value to use during restore
+	ImagesDirectory         string                     // directory for storing image files
+	WorkDirectory           string                     // directory to cd and write logs/pidfiles/stats to
+	ParentImage             string                     // directory for storing parent image files in pre-dump and dump
+	LeaveRunning            bool                       // leave container in running state after checkpoint
+	TcpEstablished          bool                       // checkpoint/restore established TCP connections
+	TcpClose                bool                       // checkpoint established TCP connections as closed, or restore them closed
+	TcpSkipInFlight         bool                       // skip the TCP connections which are not established yet on checkpoint
+	NetworkLock             criu.CriuNetworkLockMethod // method to lock the network with during checkpoint, and unlock it on restore (nftables: by runc, natively)
+	ExternalUnixConnections bool                       // allow external unix connections
+	ShellJob                bool                       // allow to dump and restore shell jobs
+	FileLocks               bool                       // handle file locks, for safety
+	PreDump                 bool                       // call criu predump to perform iterative checkpoint
+	PageServer              CriuPageServerInfo         // allow to dump to criu page server, or to restore lazily from it
+	VethPairs               []VethPairName             // pass the veth to criu when restore
+	ManageCgroupsMode       criu.CriuCgMode            // dump or restore cgroup mode
+	EmptyNs                 uint32                     // don't c/r properties for namespace from this mask
+	AutoDedup               bool                       // auto deduplication for incremental dumps
+	LazyPages               bool                       // restore memory pages lazily using userfaultfd
+	SparseImages            bool                       // punch holes in the zero-filled blocks of memory images
+	StatusFd                int                        // fd for feedback when lazy server is ready
+	LsmProfile              string                     // LSM profile used to restore the container
+	LsmMountContext         string                     // LSM mount context value to use during restore
+	Resources               *configs.Resources         // cgroup resources to use instead of the container's ones on restore
+	FreezeTimeout           time.Duration              // freeze the container (cgroup v2) before checkpoint, failing if it takes longer
+	Stream                  string                     // HOST:PORT to stream the images to on checkpoint, or to receive them at on restore
+	Template                bool                       // restore a new container from the images, leaving them untouched to be restored again
+	TrackMem                bool                       // track memory changes, dumping on top of the last pre-dump unless ParentImage is set
+	NetworkRestoreHooks     configs.HookList           // hooks to run after the container's NetworkRestore hooks on restore
 }
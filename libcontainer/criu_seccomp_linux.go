@@ -0,0 +1,34 @@
+package libcontainer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/szcdx/runc/libcontainer/configs"
+)
+
+// notifySyscalls returns the names of the syscalls of the seccomp profile
+// having SCMP_ACT_NOTIFY rules.
+func notifySyscalls(config *configs.Seccomp) []string {
+	if config == nil {
+		return nil
+	}
+	var names []string
+	for _, call := range config.Syscalls {
+		if call != nil && call.Action == configs.Notify {
+			names = append(names, call.Name)
+		}
+	}
+	return names
+}
+
+// checkSeccompNotify returns an error if the container has seccomp notify
+// rules. The kernel provides no way to get a notify fd for the seccomp
+// filters restored by CRIU, so the notified syscalls of a restored container
+// would block forever, with no agent to handle them.
+func (c *Container) checkSeccompNotify() error {
+	if syscalls := notifySyscalls(c.config.Seccomp); len(syscalls) > 0 {
+		return fmt.Errorf("unable to checkpoint a container with SCMP_ACT_NOTIFY rules (for %s)", strings.Join(syscalls, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package libcontainer
+
+import (
+	"testing"
+
+	"github.com/szcdx/runc/libcontainer/configs"
+)
+
+func TestCheckSeccompNotify(t *testing.T) {
+	rules := []*configs.Syscall{
+		{Name: "mkdir", Action: configs.Notify},
+		{Name: "chmod", Action: configs.Errno},
+		{Name: "mknod", Action: configs.Notify},
+	}
+	for _, tc := range []struct {
+		name    string
+		seccomp *configs.Seccomp
+		isErr   bool
+	}{
+		{name: "no seccomp"},
+		{name: "no notify rules", seccomp: &configs.Seccomp{Syscalls: rules[1:2]}},
+		{name: "notify rules", seccomp: &configs.Seccomp{Syscalls: rules}, isErr: true},
+		{name: "notify rules and listener", seccomp: &configs.Seccomp{Syscalls: rules, ListenerPath: "/run/agent.sock"}, isErr: true},
+	} {
+		c := &Container{config: &configs.Config{Seccomp: tc.seccomp}}
+		err := c.checkSeccompNotify()
+		if tc.isErr && err == nil {
+			t.Errorf("%s: expected error, got nil", tc.name)
+		} else if !tc.isErr && err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+	}
+}
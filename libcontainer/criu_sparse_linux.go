@@ -0,0 +1,123 @@
+package libcontainer
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// sparsifyImages punches holes in the zero-filled blocks of the memory
+// dump files (pages-*.img) in the images directory dir, so that they take
+// no disk space, and returns the number of bytes freed. The contents of
+// the files, as read back, are not changed. If the file system does not
+// support punching holes, the files are left as they are.
+func sparsifyImages(dir string) (int64, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "pages-*.img"))
+	if err != nil {
+		return 0, err
+	}
+	var freed int64
+	for _, file := range files {
+		n, err := punchZeroBlocks(file)
+		if err != nil {
+			if errors.Is(err, unix.EOPNOTSUPP) {
+				logrus.Debugf("not making checkpoint images sparse: %v", err)
+				return freed, nil
+			}
+			return freed, err
+		}
+		freed += n
+	}
+	return freed, nil
+}
+
+// punchZeroBlocks punches holes in the zero-filled page-sized blocks of the
+// file at path, and returns the number of bytes of disk space freed.
+func punchZeroBlocks(path string) (int64, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	before, err := allocatedSize(f)
+	if err != nil {
+		return 0, err
+	}
+
+	blockSize := int64(unix.Getpagesize())
+	zero := make([]byte, blockSize)
+	buf := make([]byte, 256*blockSize)
+	var off, holeStart, holeLen int64
+	punch := func() error {
+		if holeLen == 0 {
+			return nil
+		}
+		err := unix.Fallocate(int(f.Fd()), unix.FALLOC_FL_PUNCH_HOLE|unix.FALLOC_FL_KEEP_SIZE, holeStart, holeLen)
+		holeLen = 0
+		if err != nil {
+			return &os.PathError{Op: "fallocate", Path: path, Err: err}
+		}
+		return nil
+	}
+	for {
+		n, err := io.ReadFull(f, buf)
+		// Only the whole blocks are considered; the tail of the file
+		// (if its size is not a multiple of the block size) is kept.
+		for i := int64(0); i+blockSize <= int64(n); i += blockSize {
+			if !bytes.Equal(buf[i:i+blockSize], zero) {
+				if err := punch(); err != nil {
+					return 0, err
+				}
+				continue
+			}
+			if holeLen == 0 {
+				holeStart = off + i
+			}
+			holeLen += blockSize
+		}
+		off += int64(n)
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	if err := punch(); err != nil {
+		return 0, err
+	}
+
+	after, err := allocatedSize(f)
+	if err != nil {
+		return 0, err
+	}
+	return before - after, nil
+}
+
+// allocatedSize returns the disk space used by the file f, in bytes.
+func allocatedSize(f *os.File) (int64, error) {
+	var st unix.Stat_t
+	if err := unix.Fstat(int(f.Fd()), &st); err != nil {
+		return 0, &os.PathError{Op: "fstat", Path: f.Name(), Err: err}
+	}
+	return st.Blocks * 512, nil
+}
+
+// sparsifyCheckpointImages makes the memory dump files of a checkpoint
+// sparse, as requested by criuOpts.SparseImages. As the checkpoint is done
+// by then, and its images are valid whether sparse or not, a failure is
+// only warned about.
+func sparsifyCheckpointImages(criuOpts *CriuOpts) {
+	freed, err := sparsifyImages(criuOpts.ImagesDirectory)
+	if err != nil {
+		logrus.Warnf("unable to make checkpoint images sparse: %v", err)
+		return
+	}
+	logrus.Debugf("sparse checkpoint images: %d bytes freed", freed)
+}
@@ -0,0 +1,73 @@
+package libcontainer
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestSparsifyImages(t *testing.T) {
+	dir := t.TempDir()
+	pageSize := unix.Getpagesize()
+
+	// A mostly empty dump: 64 pages, of which only a few have data.
+	data := make([]byte, 64*pageSize)
+	for _, page := range []int{0, 1, 30, 63} {
+		copy(data[page*pageSize:], bytes.Repeat([]byte{byte(page + 1)}, 100))
+	}
+	pages := filepath.Join(dir, "pages-1.img")
+	if err := os.WriteFile(pages, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// Other images are left as they are.
+	other := filepath.Join(dir, "pagemap-1.img")
+	if err := os.WriteFile(other, make([]byte, 4*pageSize), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	size := func(path string) int64 {
+		t.Helper()
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		n, err := allocatedSize(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	before, otherBefore := size(pages), size(other)
+
+	freed, err := sparsifyImages(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if freed == 0 {
+		t.Skip("hole punching is not supported")
+	}
+	after := size(pages)
+	t.Logf("pages image: %d bytes allocated before, %d after, %d freed", before, after, freed)
+	if freed != before-after {
+		t.Errorf("expected %d bytes freed, got %d", before-after, freed)
+	}
+	// 60 of 64 pages are zero-filled.
+	if max := int64(4*pageSize) + before/16; after > max {
+		t.Errorf("expected at most %d bytes allocated after, got %d", max, after)
+	}
+	if size(other) != otherBefore {
+		t.Errorf("expected %s not to be changed", other)
+	}
+
+	got, err := os.ReadFile(pages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatal("expected the contents of the image not to change")
+	}
+}
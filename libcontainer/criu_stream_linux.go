@@ -0,0 +1,234 @@
+package libcontainer
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	criurpc "github.com/checkpoint-restore/go-criu/v6/rpc"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// streamConfigFile is the CRIU configuration file, in the images
+	// directory, which enables the image streaming (there is no RPC
+	// option for it).
+	streamConfigFile = "criu-stream.conf"
+	// streamerLogFile is the log file of criu-image-streamer, in the
+	// images directory.
+	streamerLogFile = "image-streamer.log"
+	// streamerReadyTimeout is how long criu-image-streamer is given to
+	// create the socket CRIU connects to.
+	streamerReadyTimeout = 10 * time.Second
+	// maxStreamDescriptorsSize is the maximum size of the descriptors
+	// sent ahead of the images.
+	maxStreamDescriptorsSize = 1 << 20
+	// streamAcceptTimeout is how long the restoring side waits for the
+	// checkpointing side to connect and send the descriptors.
+	streamAcceptTimeout = 5 * time.Minute
+)
+
+// imageStreamer is the criu-image-streamer process which CRIU sends the
+// images to (or gets them from) when they are streamed (see
+// CriuOpts.Stream), instead of being written to the images directory.
+//
+// The stream sent over the connection is made of the container's external
+// descriptors (see descriptorsFilename), as a 32-bit big endian length
+// followed by their JSON encoding, and then of the streamer's own stream.
+type imageStreamer struct {
+	cmd       *exec.Cmd
+	imagesDir string
+	done      chan error
+}
+
+// dialImageStream connects to the restoring side at addr, sends it the
+// descriptors from the images directory, and starts criu-image-streamer to
+// send the images captured from CRIU.
+func dialImageStream(addr, imagesDir string) (*imageStreamer, error) {
+	descriptors, err := os.ReadFile(filepath.Join(imagesDir, descriptorsFilename))
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("unable to stream the images: %w", err)
+	}
+	defer conn.Close()
+	header := binary.BigEndian.AppendUint32(nil, uint32(len(descriptors)))
+	if _, err := conn.Write(append(header, descriptors...)); err != nil {
+		return nil, fmt.Errorf("unable to stream the images: %w", err)
+	}
+	return startImageStreamer("capture", "streamer-capture.sock", conn.(*net.TCPConn), imagesDir)
+}
+
+// acceptImageStream waits for the checkpointing side to connect to addr,
+// writes the descriptors it sends to the images directory, and starts
+// criu-image-streamer to serve the images it sends to CRIU.
+func acceptImageStream(addr, imagesDir string) (*imageStreamer, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("unable to receive the images: %w", err)
+	}
+	logrus.Debugf("waiting for the images to be streamed to %s", l.Addr())
+	conn, err := receiveStreamDescriptors(l.(*net.TCPListener), imagesDir, streamAcceptTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("unable to receive the images: %w", err)
+	}
+	defer conn.Close()
+	return startImageStreamer("serve", "streamer-serve.sock", conn, imagesDir)
+}
+
+// receiveStreamDescriptors accepts the connection of the checkpointing side
+// on l, which it closes, and writes the descriptors it sends to the images
+// directory. Both have to be done within timeout.
+func receiveStreamDescriptors(l *net.TCPListener, imagesDir string, timeout time.Duration) (*net.TCPConn, error) {
+	deadline := time.Now().Add(timeout)
+	if err := l.SetDeadline(deadline); err != nil {
+		l.Close()
+		return nil, err
+	}
+	conn, err := l.AcceptTCP()
+	l.Close()
+	if err != nil {
+		return nil, err
+	}
+	if err := receiveDescriptors(conn, imagesDir, deadline); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// receiveDescriptors writes the descriptors read from conn, before
+// deadline, to the images directory.
+func receiveDescriptors(conn *net.TCPConn, imagesDir string, deadline time.Time) error {
+	if err := conn.SetReadDeadline(deadline); err != nil {
+		return err
+	}
+	var header [4]byte
+	if _, err := io.ReadFull(conn, header[:]); err != nil {
+		return err
+	}
+	size := binary.BigEndian.Uint32(header[:])
+	if size > maxStreamDescriptorsSize {
+		return fmt.Errorf("invalid descriptors size %d", size)
+	}
+	descriptors := make([]byte, size)
+	if _, err := io.ReadFull(conn, descriptors); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(imagesDir, descriptorsFilename), descriptors, 0o600); err != nil {
+		return err
+	}
+	// The images are read by criu-image-streamer, for as long as needed.
+	return conn.SetReadDeadline(time.Time{})
+}
+
+// startImageStreamer starts criu-image-streamer in the given mode, with its
+// stdout (for capture) or stdin (for serve) being conn, and waits for it to
+// create the socket CRIU connects to.
+func startImageStreamer(mode, socket string, conn *net.TCPConn, imagesDir string) (*imageStreamer, error) {
+	// The streamer gets the connection itself, so the images are not
+	// copied through runc.
+	f, err := conn.File()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	logFile, err := os.OpenFile(filepath.Join(imagesDir, streamerLogFile), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	defer logFile.Close()
+	socketPath := filepath.Join(imagesDir, socket)
+	if err := os.Remove(socketPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	cmd := exec.Command("criu-image-streamer", "--images-dir", imagesDir, mode)
+	if mode == "capture" {
+		cmd.Stdout = f
+	} else {
+		cmd.Stdin = f
+	}
+	cmd.Stderr = logFile
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("unable to start criu-image-streamer: %w", err)
+	}
+	s := &imageStreamer{cmd: cmd, imagesDir: imagesDir, done: make(chan error, 1)}
+	go func() {
+		s.done <- cmd.Wait()
+	}()
+
+	deadline := time.Now().Add(streamerReadyTimeout)
+	for {
+		if _, err := os.Stat(socketPath); err == nil {
+			return s, nil
+		}
+		select {
+		case err := <-s.done:
+			return nil, s.error(err)
+		default:
+		}
+		if time.Now().After(deadline) {
+			_ = cmd.Process.Kill()
+			return nil, s.error(<-s.done)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// error returns the error criu-image-streamer failed with.
+func (s *imageStreamer) error(err error) error {
+	if err == nil {
+		err = errors.New("exited early")
+	}
+	return fmt.Errorf("criu-image-streamer failed: %w (see %s)", err, filepath.Join(s.imagesDir, streamerLogFile))
+}
+
+// finish waits for criu-image-streamer to be done with the images, or kills
+// it if CRIU failed with criuErr. It returns the error the streamer failed
+// with, if any.
+func (s *imageStreamer) finish(criuErr error) error {
+	if criuErr != nil {
+		_ = s.cmd.Process.Kill()
+		<-s.done
+		return nil
+	}
+	if err := <-s.done; err != nil {
+		return s.error(err)
+	}
+	return nil
+}
+
+// setStreamConfig makes CRIU stream the images, by making it use a copy of
+// its configuration file with the stream option added.
+func setStreamConfig(rpcOpts *criurpc.CriuOpts, imagesDir string) error {
+	var config []byte
+	if path := rpcOpts.GetConfigFile(); path != "" {
+		var err error
+		config, err = os.ReadFile(path)
+		// As CRIU does, ignore a configuration file which does not
+		// exist.
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		if len(config) > 0 && config[len(config)-1] != '\n' {
+			config = append(config, '\n')
+		}
+	}
+	config = append(config, "stream\n"...)
+	path := filepath.Join(imagesDir, streamConfigFile)
+	if err := os.WriteFile(path, config, 0o600); err != nil {
+		return err
+	}
+	rpcOpts.ConfigFile = proto.String(path)
+	return nil
+}
@@ -0,0 +1,107 @@
+package libcontainer
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	criurpc "github.com/checkpoint-restore/go-criu/v6/rpc"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestSetStreamConfig(t *testing.T) {
+	dir := t.TempDir()
+	config := filepath.Join(dir, "runc.conf")
+	if err := os.WriteFile(config, []byte("tcp-established"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		config, expected string
+	}{
+		{config: "", expected: "stream\n"},
+		{config: filepath.Join(dir, "missing.conf"), expected: "stream\n"},
+		{config: config, expected: "tcp-established\nstream\n"},
+	} {
+		opts := &criurpc.CriuOpts{}
+		if tc.config != "" {
+			opts.ConfigFile = proto.String(tc.config)
+		}
+		if err := setStreamConfig(opts, dir); err != nil {
+			t.Fatal(err)
+		}
+		if opts.GetConfigFile() != filepath.Join(dir, streamConfigFile) {
+			t.Errorf("%q: unexpected config file %q", tc.config, opts.GetConfigFile())
+		}
+		data, err := os.ReadFile(opts.GetConfigFile())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != tc.expected {
+			t.Errorf("%q: expected %q, got %q", tc.config, tc.expected, data)
+		}
+	}
+}
+
+func TestReceiveStreamDescriptors(t *testing.T) {
+	const descriptors = `["/dev/null","/dev/null","/dev/null"]`
+	for _, tc := range []struct {
+		name    string
+		send    func(conn net.Conn) error
+		connect bool
+		wantErr error
+	}{
+		{name: "no connection", wantErr: os.ErrDeadlineExceeded},
+		{name: "no descriptors", connect: true, wantErr: os.ErrDeadlineExceeded},
+		{
+			name:    "descriptors",
+			connect: true,
+			send: func(conn net.Conn) error {
+				header := binary.BigEndian.AppendUint32(nil, uint32(len(descriptors)))
+				_, err := conn.Write(append(header, descriptors...))
+				return err
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			l, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.connect {
+				conn, err := net.Dial("tcp", l.Addr().String())
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer conn.Close()
+				if tc.send != nil {
+					if err := tc.send(conn); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			conn, err := receiveStreamDescriptors(l, dir, 100*time.Millisecond)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("expected %v, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			conn.Close()
+			data, err := os.ReadFile(filepath.Join(dir, descriptorsFilename))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != descriptors {
+				t.Fatalf("expected %q, got %q", descriptors, data)
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package libcontainer
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	criurpc "github.com/checkpoint-restore/go-criu/v6/rpc"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/szcdx/runc/libcontainer/configs"
+)
+
+// checkTemplateRestore checks that the container can be restored from images
+// which are used as a template (see CriuOpts.Template): the images are left
+// untouched, and the restored processes do not clash with the ones of the
+// other containers restored from them.
+func (c *Container) checkTemplateRestore(criuOpts *CriuOpts) error {
+	switch {
+	case criuOpts.AutoDedup:
+		return errors.New("--auto-dedup can not be used with a snapshot, as it removes the restored memory pages from the images")
+	case criuOpts.Stream != "":
+		return errors.New("--stream can not be used with a snapshot")
+	case criuOpts.TcpEstablished:
+		return errors.New("--tcp-established can not be used with a snapshot, as the connections would be restored more than once")
+	}
+	// The processes are restored with their checkpointed PIDs.
+	if !c.config.Namespaces.Contains(configs.NEWPID) || c.config.Namespaces.PathOf(configs.NEWPID) != "" {
+		return errors.New("restoring from a snapshot requires a new PID namespace")
+	}
+	return nil
+}
+
+// setUTSNames sets the host name and the domain name (if not empty) in the
+// UTS namespace of the process pid, as the restored namespace has the names
+// of the checkpointed container.
+func setUTSNames(pid int, hostname, domainname string) error {
+	path := fmt.Sprintf("/proc/%d/ns/uts", pid)
+	nsFd, err := unix.Open(path, unix.O_RDONLY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return &os.PathError{Op: "open", Path: path, Err: err}
+	}
+	defer unix.Close(nsFd)
+
+	errCh := make(chan error, 1)
+	go func() {
+		// The thread is not unlocked, so that it terminates with the
+		// goroutine, rather than being used again in the container's
+		// UTS namespace.
+		runtime.LockOSThread()
+		if err := unix.Setns(nsFd, unix.CLONE_NEWUTS); err != nil {
+			errCh <- os.NewSyscallError("setns", err)
+			return
+		}
+		if hostname != "" {
+			if err := unix.Sethostname([]byte(hostname)); err != nil {
+				errCh <- fmt.Errorf("unable to set the hostname: %w", err)
+				return
+			}
+		}
+		if domainname != "" {
+			if err := unix.Setdomainname([]byte(domainname)); err != nil {
+				errCh <- fmt.Errorf("unable to set the domainname: %w", err)
+				return
+			}
+		}
+		errCh <- nil
+	}()
+	return <-errCh
+}
+
+// restoreTemplateVeths makes criu restore the veth interfaces of the images
+// which are not mapped to a host interface already (by the configuration or
+// CriuOpts.VethPairs) with host peers named after the container id, so that
+// every container restored from the images gets veth pairs of its own. Their
+// addresses are the checkpointed ones, which the NetworkRestore hooks can
+// change.
+func (c *Container) restoreTemplateVeths(req *criurpc.CriuReq, criuOpts *CriuOpts) error {
+	// Only a new network namespace is restored from the images.
+	if !c.config.Namespaces.Contains(configs.NEWNET) || c.config.Namespaces.PathOf(configs.NEWNET) != "" {
+		return nil
+	}
+	names, err := criuImageVeths(criuOpts.ImagesDirectory)
+	if err != nil {
+		return err
+	}
+	mapped := make(map[string]bool)
+	for _, veth := range req.Opts.Veths {
+		mapped[veth.GetIfIn()] = true
+	}
+	for _, name := range names {
+		if mapped[name] {
+			continue
+		}
+		hostName := templateVethName(c.id, name)
+		logrus.Debugf("restoring veth %s with host peer %s", name, hostName)
+		req.Opts.Veths = append(req.Opts.Veths, &criurpc.CriuVethPair{
+			IfIn:  proto.String(name),
+			IfOut: proto.String(hostName),
+		})
+	}
+	return nil
+}
+
+// templateVethName returns the name of the host peer of the veth interface
+// name of the container id restored from a snapshot: "veth" followed by the
+// first 11 hex digits of the SHA-256 of "<id>/<name>", which fits in
+// IFNAMSIZ.
+func templateVethName(id, name string) string {
+	sum := sha256.Sum256([]byte(id + "/" + name))
+	return "veth" + hex.EncodeToString(sum[:])[:11]
+}
+
+const (
+	// criuImgCommonMagic and criuImgServiceMagic are the magic numbers
+	// prepended to the magic number of the criu images.
+	criuImgCommonMagic  = 0x54564319
+	criuImgServiceMagic = 0x55105940
+	// criuNetdevMagic is the magic number of the netdev images.
+	criuNetdevMagic = 0x57373951
+
+	// The net_device_entry fields and type used (see criu's
+	// images/netdev.proto).
+	netdevFieldType = 1
+	netdevFieldName = 5
+	netdevTypeVeth  = 2
+	criuMaxImgEntry = 1 << 20
+)
+
+// criuImageVeths returns the names of the veth interfaces saved in the
+// netdev images of the criu images in dir.
+func criuImageVeths(dir string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "netdev-*.img"))
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, path := range paths {
+		n, err := readNetdevVeths(path)
+		if err != nil {
+			return nil, fmt.Errorf("criu image %s: %w", path, err)
+		}
+		names = append(names, n...)
+	}
+	return names, nil
+}
+
+// readNetdevVeths returns the names of the veth interfaces of the criu
+// netdev image at path, made of its magic numbers followed by the
+// net_device_entry messages, each prefixed by its size (as a 32 bit
+// little endian integer).
+func readNetdevVeths(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+
+	var magic uint32
+	if err := binary.Read(r, binary.LittleEndian, &magic); err != nil {
+		return nil, err
+	}
+	if magic == criuImgCommonMagic || magic == criuImgServiceMagic {
+		if err := binary.Read(r, binary.LittleEndian, &magic); err != nil {
+			return nil, err
+		}
+	}
+	if magic != criuNetdevMagic {
+		return nil, fmt.Errorf("unexpected magic %#x", magic)
+	}
+
+	var names []string
+	for {
+		var size uint32
+		if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
+			if errors.Is(err, io.EOF) {
+				return names, nil
+			}
+			return nil, err
+		}
+		if size > criuMaxImgEntry {
+			return nil, fmt.Errorf("entry too large (%d bytes)", size)
+		}
+		entry := make([]byte, size)
+		if _, err := io.ReadFull(r, entry); err != nil {
+			return nil, err
+		}
+		typ, name, err := parseNetdevEntry(entry)
+		if err != nil {
+			return nil, err
+		}
+		if typ == netdevTypeVeth && name != "" {
+			names = append(names, name)
+		}
+	}
+}
+
+// parseNetdevEntry returns the type and the name of the net_device_entry
+// message b.
+func parseNetdevEntry(b []byte) (typ uint64, name string, _ error) {
+	for len(b) > 0 {
+		num, wtyp, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return 0, "", protowire.ParseError(n)
+		}
+		b = b[n:]
+		switch {
+		case num == netdevFieldType && wtyp == protowire.VarintType:
+			typ, n = protowire.ConsumeVarint(b)
+		case num == netdevFieldName && wtyp == protowire.BytesType:
+			var v []byte
+			v, n = protowire.ConsumeBytes(b)
+			name = string(v)
+		default:
+			n = protowire.ConsumeFieldValue(num, wtyp, b)
+		}
+		if n < 0 {
+			return 0, "", protowire.ParseError(n)
+		}
+		b = b[n:]
+	}
+	return typ, name, nil
+}
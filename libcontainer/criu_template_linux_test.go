@@ -0,0 +1,176 @@
+package libcontainer
+
+import (
+	"encoding/binary"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
+	"testing"
+
+	criurpc "github.com/checkpoint-restore/go-criu/v6/rpc"
+	"golang.org/x/sys/unix"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/szcdx/runc/libcontainer/configs"
+)
+
+func TestCheckTemplateRestore(t *testing.T) {
+	pidns := configs.Namespaces{{Type: configs.NEWPID}}
+	for _, tc := range []struct {
+		name       string
+		namespaces configs.Namespaces
+		opts       CriuOpts
+		isErr      bool
+	}{
+		{name: "valid", namespaces: pidns},
+		{name: "auto-dedup", namespaces: pidns, opts: CriuOpts{AutoDedup: true}, isErr: true},
+		{name: "stream", namespaces: pidns, opts: CriuOpts{Stream: "127.0.0.1:1234"}, isErr: true},
+		{name: "tcp-established", namespaces: pidns, opts: CriuOpts{TcpEstablished: true}, isErr: true},
+		{name: "host pidns", isErr: true},
+		{name: "joined pidns", namespaces: configs.Namespaces{{Type: configs.NEWPID, Path: "/proc/1/ns/pid"}}, isErr: true},
+	} {
+		c := &Container{config: &configs.Config{Namespaces: tc.namespaces}}
+		err := c.checkTemplateRestore(&tc.opts)
+		if tc.isErr && err == nil {
+			t.Errorf("%s: expected error, got nil", tc.name)
+		} else if !tc.isErr && err != nil {
+			t.Errorf("%s: expected no error, got %v", tc.name, err)
+		}
+	}
+}
+
+func TestSetUTSNames(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("requires root")
+	}
+	cmd := exec.Command("sleep", "30")
+	cmd.SysProcAttr = &unix.SysProcAttr{Cloneflags: unix.CLONE_NEWUTS}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}()
+	if err := setUTSNames(cmd.Process.Pid, "clone", "example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	var host unix.Utsname
+	if err := unix.Uname(&host); err != nil {
+		t.Fatal(err)
+	}
+	if unix.ByteSliceToString(host.Nodename[:]) == "clone" {
+		t.Fatal("the hostname of the test is changed")
+	}
+	var uts unix.Utsname
+	done := make(chan error, 1)
+	go func() {
+		runtime.LockOSThread()
+		fd, err := unix.Open("/proc/"+strconv.Itoa(cmd.Process.Pid)+"/ns/uts", unix.O_RDONLY|unix.O_CLOEXEC, 0)
+		if err == nil {
+			err = unix.Setns(fd, unix.CLONE_NEWUTS)
+			unix.Close(fd)
+		}
+		if err == nil {
+			err = unix.Uname(&uts)
+		}
+		done <- err
+	}()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if h, d := unix.ByteSliceToString(uts.Nodename[:]), unix.ByteSliceToString(uts.Domainname[:]); h != "clone" || d != "example.com" {
+		t.Fatalf("expected clone and example.com, got %s and %s", h, d)
+	}
+}
+
+// writeNetdevImage writes a criu netdev image to path, with an entry of
+// each of the types and names of ifaces.
+func writeNetdevImage(t *testing.T, path string, ifaces map[string]uint64) {
+	t.Helper()
+	img := binary.LittleEndian.AppendUint32(nil, criuImgCommonMagic)
+	img = binary.LittleEndian.AppendUint32(img, criuNetdevMagic)
+	for name, typ := range ifaces {
+		var entry []byte
+		entry = protowire.AppendTag(entry, netdevFieldType, protowire.VarintType)
+		entry = protowire.AppendVarint(entry, typ)
+		// ifindex and mtu, which are skipped.
+		entry = protowire.AppendTag(entry, 2, protowire.VarintType)
+		entry = protowire.AppendVarint(entry, 7)
+		entry = protowire.AppendTag(entry, 3, protowire.VarintType)
+		entry = protowire.AppendVarint(entry, 1500)
+		entry = protowire.AppendTag(entry, netdevFieldName, protowire.BytesType)
+		entry = protowire.AppendString(entry, name)
+		img = binary.LittleEndian.AppendUint32(img, uint32(len(entry)))
+		img = append(img, entry...)
+	}
+	if err := os.WriteFile(path, img, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRestoreTemplateVeths(t *testing.T) {
+	dir := t.TempDir()
+	writeNetdevImage(t, filepath.Join(dir, "netdev-9.img"), map[string]uint64{
+		"lo":   1,
+		"eth0": netdevTypeVeth,
+	})
+	writeNetdevImage(t, filepath.Join(dir, "netdev-10.img"), map[string]uint64{
+		"eth1": netdevTypeVeth,
+	})
+
+	opts := &CriuOpts{ImagesDirectory: dir}
+	for _, tc := range []struct {
+		name       string
+		namespaces configs.Namespaces
+		want       []*criurpc.CriuVethPair
+	}{
+		{
+			name:       "new netns",
+			namespaces: configs.Namespaces{{Type: configs.NEWNET}},
+			want: []*criurpc.CriuVethPair{
+				{IfIn: proto.String("eth1"), IfOut: proto.String("host1")},
+				{IfIn: proto.String("eth0"), IfOut: proto.String(templateVethName("clone", "eth0"))},
+			},
+		},
+		{
+			name:       "joined netns",
+			namespaces: configs.Namespaces{{Type: configs.NEWNET, Path: "/proc/1/ns/net"}},
+			want: []*criurpc.CriuVethPair{
+				{IfIn: proto.String("eth1"), IfOut: proto.String("host1")},
+			},
+		},
+	} {
+		c := &Container{id: "clone", config: &configs.Config{Namespaces: tc.namespaces}}
+		req := &criurpc.CriuReq{Opts: &criurpc.CriuOpts{
+			Veths: []*criurpc.CriuVethPair{{IfIn: proto.String("eth1"), IfOut: proto.String("host1")}},
+		}}
+		if err := c.restoreTemplateVeths(req, opts); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if !reflect.DeepEqual(req.Opts.Veths, tc.want) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.want, req.Opts.Veths)
+		}
+	}
+
+	// The host peers of different containers differ, and fit in IFNAMSIZ.
+	a, b := templateVethName("a", "eth0"), templateVethName("b", "eth0")
+	if a == b || len(a) > unix.IFNAMSIZ-1 {
+		t.Errorf("unexpected host peer names %q and %q", a, b)
+	}
+}
+
+func TestReadNetdevVethsBadMagic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "netdev-9.img")
+	if err := os.WriteFile(path, []byte{1, 2, 3, 4}, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readNetdevVeths(path); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
@@ -0,0 +1,184 @@
+package libcontainer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	securejoin "github.com/cyphar/filepath-securejoin"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/szcdx/runc/libcontainer/configs"
+	"github.com/szcdx/runc/libcontainer/devices"
+)
+
+// UpdateDevices adds the devices in add to the running container, and
+// removes the ones at the paths (in the container) in remove from it. The
+// device cgroup rules of the container are updated to allow (or no longer
+// allow) the access to these devices, and their nodes are created in (or
+// removed from) the container's root filesystem. A device is only denied
+// once removed if no other rule (such as a wildcard one) allows it.
+//
+// This is not supported for the containers with a user namespace, as the
+// device nodes would have to be bind mounted from the host in the mount
+// namespace of the container.
+func (c *Container) UpdateDevices(add []*devices.Device, remove []string) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	status, err := c.currentStatus()
+	if err != nil {
+		return err
+	}
+	if status == Stopped {
+		return ErrNotRunning
+	}
+	if c.config.RootlessEUID || c.config.Namespaces.Contains(configs.NEWUSER) {
+		return errors.New("can't update the devices of a container with a user namespace")
+	}
+	if c.config.Cgroups.AccountingOnly || c.config.Cgroups.ReadOnlyDegraded {
+		return errors.New("can't update the devices of a container with no device cgroup rules")
+	}
+
+	devs, rules, removed, err := updateDeviceList(c.config.Devices, c.config.Cgroups.Resources.Devices, add, remove)
+	if err != nil {
+		return err
+	}
+	config := *c.config
+	cgroup := *config.Cgroups
+	resources := *cgroup.Resources
+	resources.Devices = rules
+	cgroup.Resources = &resources
+	config.Cgroups = &cgroup
+	config.Devices = devs
+
+	if err := c.cgroupManager.Set(&resources); err != nil {
+		// Set configs back
+		if err2 := c.cgroupManager.Set(c.config.Cgroups.Resources); err2 != nil {
+			logrus.Warnf("Setting back cgroup configs failed due to error: %v, your state.json and actual configs might be inconsistent.", err2)
+		}
+		return err
+	}
+	// The root of the container, in its mount namespace.
+	rootfs := filepath.Join("/proc", strconv.Itoa(c.initProcess.pid()), "root")
+	for _, node := range removed {
+		if err := removeDeviceNode(rootfs, node); err != nil {
+			logrus.Warnf("unable to remove device node %s: %v", node.Path, err)
+		}
+	}
+	for i, node := range add {
+		if err := createDeviceNode(rootfs, node, false); err != nil {
+			c.rollbackDevices(rootfs, add[:i], removed)
+			return fmt.Errorf("unable to create device node %s: %w", node.Path, err)
+		}
+	}
+	c.config = &config
+	_, err = c.updateState(nil)
+	return err
+}
+
+// rollbackDevices undoes a failed UpdateDevices: the device nodes in created
+// are removed, the ones in removed are created again, and the device cgroup
+// rules are set back to the ones of the container's configuration.
+func (c *Container) rollbackDevices(rootfs string, created, removed []*devices.Device) {
+	for _, node := range created {
+		if err := removeDeviceNode(rootfs, node); err != nil {
+			logrus.Warnf("unable to remove device node %s: %v", node.Path, err)
+		}
+	}
+	for _, node := range removed {
+		if err := createDeviceNode(rootfs, node, false); err != nil {
+			logrus.Warnf("unable to create device node %s again: %v", node.Path, err)
+		}
+	}
+	if err := c.cgroupManager.Set(c.config.Cgroups.Resources); err != nil {
+		logrus.Warnf("Setting back cgroup configs failed due to error: %v, your state.json and actual configs might be inconsistent.", err)
+	}
+}
+
+// updateDeviceList returns the devices and device cgroup rules cur and rules,
+// with the devices in add added (replacing the ones with the same path), and
+// the devices at the paths in remove removed, along with the rules allowing
+// them. The removed devices are returned as well.
+func updateDeviceList(cur []*devices.Device, rules []*devices.Rule, add []*devices.Device, remove []string) ([]*devices.Device, []*devices.Rule, []*devices.Device, error) {
+	paths := make(map[string]*devices.Device, len(cur))
+	for _, d := range cur {
+		paths[d.Path] = d
+	}
+	drop := make(map[string]bool)
+	var removed []*devices.Device
+	for _, path := range remove {
+		d := paths[path]
+		if d == nil {
+			return nil, nil, nil, fmt.Errorf("device %s not found in the container", path)
+		}
+		drop[path] = true
+		removed = append(removed, d)
+	}
+	for _, d := range add {
+		if !filepath.IsAbs(d.Path) {
+			return nil, nil, nil, fmt.Errorf("device path %q must be absolute", d.Path)
+		}
+		if !d.Type.CanMknod() || d.Major == devices.Wildcard || d.Minor == devices.Wildcard {
+			return nil, nil, nil, fmt.Errorf("device %s: invalid device %s", d.Path, d.CgroupString())
+		}
+		if !d.Permissions.IsValid() {
+			return nil, nil, nil, fmt.Errorf("device %s: invalid permissions %q", d.Path, d.Permissions)
+		}
+		if old := paths[d.Path]; old != nil && !drop[d.Path] {
+			drop[d.Path] = true
+			removed = append(removed, old)
+		}
+	}
+
+	newDevs := make([]*devices.Device, 0, len(cur)+len(add))
+	for _, d := range cur {
+		if !drop[d.Path] {
+			newDevs = append(newDevs, d)
+		}
+	}
+	newRules := make([]*devices.Rule, 0, len(rules)+len(add))
+next:
+	for _, r := range rules {
+		if r.Allow {
+			for _, d := range removed {
+				if r.Type == d.Type && r.Major == d.Major && r.Minor == d.Minor {
+					continue next
+				}
+			}
+		}
+		newRules = append(newRules, r)
+	}
+	for _, d := range add {
+		dev := *d
+		dev.Allow = true
+		newDevs = append(newDevs, &dev)
+		rule := dev.Rule
+		newRules = append(newRules, &rule)
+	}
+	return newDevs, newRules, removed, nil
+}
+
+// removeDeviceNode removes the node of the device from the root filesystem
+// of the container, if it is still a device node.
+func removeDeviceNode(rootfs string, node *devices.Device) error {
+	dest, err := securejoin.SecureJoin(rootfs, node.Path)
+	if err != nil {
+		return err
+	}
+	var st unix.Stat_t
+	if err := unix.Lstat(dest, &st); err != nil {
+		if errors.Is(err, unix.ENOENT) {
+			return nil
+		}
+		return &os.PathError{Op: "lstat", Path: dest, Err: err}
+	}
+	switch st.Mode & unix.S_IFMT {
+	case unix.S_IFBLK, unix.S_IFCHR, unix.S_IFIFO:
+	default:
+		return fmt.Errorf("%s is not a device node", node.Path)
+	}
+	return os.Remove(dest)
+}
@@ -0,0 +1,109 @@
+package libcontainer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/szcdx/runc/libcontainer/configs"
+	"github.com/szcdx/runc/libcontainer/devices"
+)
+
+func TestUpdateDeviceList(t *testing.T) {
+	null := &devices.Device{
+		Rule: devices.Rule{Type: devices.CharDevice, Major: 1, Minor: 3, Permissions: "rwm", Allow: true},
+		Path: "/dev/null",
+	}
+	loop := &devices.Device{
+		Rule: devices.Rule{Type: devices.BlockDevice, Major: 7, Minor: 0, Permissions: "rwm", Allow: true},
+		Path: "/dev/loop0",
+	}
+	cur := []*devices.Device{null, loop}
+	rules := []*devices.Rule{
+		{Type: devices.WildcardDevice, Major: devices.Wildcard, Minor: devices.Wildcard, Permissions: "rwm"},
+		&null.Rule,
+		&loop.Rule,
+	}
+	fuse := &devices.Device{
+		Rule: devices.Rule{Type: devices.CharDevice, Major: 10, Minor: 229, Permissions: "rw"},
+		Path: "/dev/fuse",
+	}
+
+	devs, newRules, removed, err := updateDeviceList(cur, rules, []*devices.Device{fuse}, []string{"/dev/loop0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 1 || removed[0] != loop {
+		t.Errorf("expected /dev/loop0 to be removed, got %v", removed)
+	}
+	if len(devs) != 2 || devs[0] != null || devs[1].Path != "/dev/fuse" || !devs[1].Allow {
+		t.Errorf("unexpected devices %+v", devs)
+	}
+	var ruleStrings []string
+	for _, r := range newRules {
+		ruleStrings = append(ruleStrings, r.CgroupString())
+	}
+	expected := []string{"a *:* rwm", "c 1:3 rwm", "c 10:229 rw"}
+	if len(ruleStrings) != len(expected) {
+		t.Fatalf("expected rules %v, got %v", expected, ruleStrings)
+	}
+	for i := range expected {
+		if ruleStrings[i] != expected[i] {
+			t.Errorf("expected rules %v, got %v", expected, ruleStrings)
+			break
+		}
+	}
+	if !newRules[2].Allow {
+		t.Error("expected the rule of the added device to allow it")
+	}
+
+	// Adding a device at the path of another one replaces it.
+	_, _, removed, err = updateDeviceList(cur, rules, []*devices.Device{{Rule: fuse.Rule, Path: "/dev/null"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 1 || removed[0] != null {
+		t.Errorf("expected /dev/null to be replaced, got %v", removed)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		add    []*devices.Device
+		remove []string
+	}{
+		{name: "unknown device", remove: []string{"/dev/fuse"}},
+		{name: "relative path", add: []*devices.Device{{Rule: fuse.Rule, Path: "dev/fuse"}}},
+		{name: "wildcard", add: []*devices.Device{{Rule: devices.Rule{Type: devices.CharDevice, Major: 10, Minor: devices.Wildcard, Permissions: "rw"}, Path: "/dev/x"}}},
+		{name: "invalid permissions", add: []*devices.Device{{Rule: devices.Rule{Type: devices.CharDevice, Major: 10, Minor: 229, Permissions: "rwx"}, Path: "/dev/fuse"}}},
+	} {
+		if _, _, _, err := updateDeviceList(cur, rules, tc.add, tc.remove); err == nil {
+			t.Errorf("%s: expected error, got nil", tc.name)
+		}
+	}
+}
+
+func TestRollbackDevices(t *testing.T) {
+	rootfs := t.TempDir()
+	fifo := func(path string) *devices.Device {
+		return &devices.Device{
+			Path:     path,
+			FileMode: 0o600,
+			Rule:     devices.Rule{Type: devices.FifoDevice, Major: 0, Minor: 0, Permissions: "rwm"},
+		}
+	}
+	created, removed := fifo("/dev/created"), fifo("/dev/removed")
+	if err := createDeviceNode(rootfs, created, false); err != nil {
+		t.Fatal(err)
+	}
+	c := &Container{
+		config:        &configs.Config{Cgroups: &configs.Cgroup{Resources: &configs.Resources{}}},
+		cgroupManager: &mockCgroupManager{},
+	}
+	c.rollbackDevices(rootfs, []*devices.Device{created}, []*devices.Device{removed})
+	if _, err := os.Lstat(filepath.Join(rootfs, "dev/created")); !os.IsNotExist(err) {
+		t.Errorf("expected the created node to be removed, got %v", err)
+	}
+	if fi, err := os.Lstat(filepath.Join(rootfs, "dev/removed")); err != nil || fi.Mode()&os.ModeNamedPipe == 0 {
+		t.Errorf("expected the removed node to be created again, got %v", err)
+	}
+}
@@ -10,4 +10,8 @@ var (
 	ErrRunning    = errors.New("container still running")
 	ErrNotRunning = errors.New("container not running")
 	ErrNotPaused  = errors.New("container not paused")
+
+	// ErrProcessGone is returned when signaling a process which has
+	// exited, possibly with its pid reused by another process since.
+	ErrProcessGone = errors.New("process is gone")
 )
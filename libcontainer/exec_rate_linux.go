@@ -0,0 +1,143 @@
+package libcontainer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/szcdx/runc/libcontainer/configs"
+	"github.com/szcdx/runc/libcontainer/utils"
+)
+
+// execRateFilename is the name of the file in the container's state
+// directory which keeps the token bucket of configs.ExecRateLimit.
+const execRateFilename = "exec-rate.json"
+
+// ErrRateLimited is returned (wrapped in a *RateLimitError) by Start if the
+// process can not be started because of the container's process start rate
+// limit (see configs.ExecRateLimit).
+var ErrRateLimited = errors.New("too many processes started in the container")
+
+// RateLimitError is the error returned by Start if the process start rate
+// limit of the container is exceeded.
+type RateLimitError struct {
+	// RetryAfter is how long it takes until a process can be started.
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%v, retry after %s", ErrRateLimited, e.RetryAfter.Round(time.Millisecond))
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return ErrRateLimited
+}
+
+// execBucket is the token bucket of configs.ExecRateLimit.
+type execBucket struct {
+	Tokens  float64   `json:"tokens"`
+	Updated time.Time `json:"updated"`
+}
+
+// take takes a token from the bucket at the time now, refilling it first.
+// If there are no tokens, it returns how long it takes for one to appear.
+func (b *execBucket) take(l *configs.ExecRateLimit, now time.Time) (time.Duration, bool) {
+	size := l.BucketSize()
+	if b.Updated.IsZero() {
+		b.Tokens = size
+	} else if elapsed := now.Sub(b.Updated); elapsed > 0 {
+		b.Tokens += elapsed.Seconds() * l.Rate
+	}
+	if b.Tokens > size {
+		b.Tokens = size
+	}
+	b.Updated = now
+	if b.Tokens < 1 {
+		return time.Duration((1 - b.Tokens) / l.Rate * float64(time.Second)), false
+	}
+	b.Tokens--
+	return 0, true
+}
+
+// refund puts back a token taken from the bucket.
+func (b *execBucket) refund(l *configs.ExecRateLimit) {
+	b.Tokens++
+	if size := l.BucketSize(); b.Tokens > size {
+		b.Tokens = size
+	}
+}
+
+// takeExecToken enforces the process start rate limit of the container, if
+// any. The token bucket is kept in the state directory, so that the limit
+// applies to all the runc processes starting processes in the container.
+func (c *Container) takeExecToken() error {
+	l := c.config.ExecRateLimit
+	if l == nil {
+		return nil
+	}
+	var (
+		retryAfter time.Duration
+		ok         bool
+	)
+	if err := c.updateExecBucket(func(b *execBucket) {
+		retryAfter, ok = b.take(l, time.Now())
+	}); err != nil {
+		return err
+	}
+	if !ok {
+		return &RateLimitError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// refundExecToken puts back the token taken by takeExecToken, if the
+// process could not be started after all.
+func (c *Container) refundExecToken() error {
+	l := c.config.ExecRateLimit
+	if l == nil {
+		return nil
+	}
+	return c.updateExecBucket(func(b *execBucket) {
+		b.refund(l)
+	})
+}
+
+// updateExecBucket calls update with the token bucket of the container, and
+// saves it afterwards, with the bucket file locked.
+func (c *Container) updateExecBucket(update func(b *execBucket)) error {
+	path := filepath.Join(c.stateDir, execRateFilename)
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|unix.O_CLOEXEC, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := unix.Flock(int(f.Fd()), unix.LOCK_EX); err != nil {
+		return &os.PathError{Op: "flock", Path: path, Err: err}
+	}
+	defer unix.Flock(int(f.Fd()), unix.LOCK_UN) //nolint: errcheck
+
+	var b execBucket
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &b); err != nil {
+			return fmt.Errorf("unable to parse %s: %w", path, err)
+		}
+	}
+	update(&b)
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return utils.WriteJSON(f, b)
+}
@@ -0,0 +1,78 @@
+package libcontainer
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/szcdx/runc/libcontainer/configs"
+)
+
+func TestExecBucket(t *testing.T) {
+	l := &configs.ExecRateLimit{Rate: 2, Burst: 3}
+	now := time.Now()
+	var b execBucket
+
+	// The bucket starts full.
+	for i := 0; i < 3; i++ {
+		if _, ok := b.take(l, now); !ok {
+			t.Fatalf("take %d: expected a token", i)
+		}
+	}
+	retryAfter, ok := b.take(l, now)
+	if ok {
+		t.Fatal("expected no token")
+	}
+	if retryAfter != 500*time.Millisecond {
+		t.Fatalf("expected to retry after 500ms, got %s", retryAfter)
+	}
+
+	// The bucket is refilled at the rate.
+	now = now.Add(500 * time.Millisecond)
+	if _, ok := b.take(l, now); !ok {
+		t.Fatal("expected a token")
+	}
+	if _, ok := b.take(l, now); ok {
+		t.Fatal("expected no token")
+	}
+
+	// But not over its size.
+	now = now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		if _, ok := b.take(l, now); !ok {
+			t.Fatalf("take %d: expected a token", i)
+		}
+	}
+	if _, ok := b.take(l, now); ok {
+		t.Fatal("expected no token")
+	}
+}
+
+func TestTakeExecToken(t *testing.T) {
+	c := &Container{
+		stateDir: t.TempDir(),
+		config:   &configs.Config{ExecRateLimit: &configs.ExecRateLimit{Rate: 0.001}},
+	}
+	if err := c.takeExecToken(); err != nil {
+		t.Fatal(err)
+	}
+	// The state is kept in the file, so it is shared with other
+	// instances of the container.
+	c2 := &Container{stateDir: c.stateDir, config: c.config}
+	err := c2.takeExecToken()
+	var rlErr *RateLimitError
+	if !errors.As(err, &rlErr) || !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expected a RateLimitError, got %v", err)
+	}
+	if rlErr.RetryAfter <= 0 {
+		t.Fatalf("expected a positive retry after, got %s", rlErr.RetryAfter)
+	}
+
+	// A refunded token can be taken again.
+	if err := c.refundExecToken(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c2.takeExecToken(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,101 @@
+package libcontainer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/szcdx/runc/libcontainer/cgroups"
+	"github.com/szcdx/runc/libcontainer/cgroups/fscommon"
+)
+
+// NotifyExit returns a channel which is closed once the process p, started
+// or restored in the container, has exited, whether or not it is a child of
+// the calling process, so that a caller does not have to rely on SIGCHLD
+// (or on waiting for the process) to notice its exit. It uses a pidfd of
+// the process or, for the container init on cgroup v2 with kernels which do
+// not support pidfd_open(2), the container cgroup becoming empty.
+//
+// It is to be called before the process is waited for, so that its pid
+// can't have been reused.
+func (c *Container) NotifyExit(p *Process) (<-chan struct{}, error) {
+	pid, err := p.Pid()
+	if err != nil {
+		return nil, err
+	}
+	fd, err := unix.PidfdOpen(pid, 0)
+	if err == nil {
+		return notifyPidfdExit(fd, c.id), nil
+	}
+	if !errors.Is(err, unix.ENOSYS) || !p.Init || !cgroups.IsCgroup2UnifiedMode() {
+		return nil, os.NewSyscallError("pidfd_open", err)
+	}
+	return notifyCgroupEmpty(c.cgroupManager.Path(""), c.id)
+}
+
+// notifyPidfdExit returns a channel which is closed once the process
+// referred to by pidfd has exited. The pidfd is closed then.
+func notifyPidfdExit(pidfd int, id string) <-chan struct{} {
+	trackFd(pidfd, "exit event", id, "anon_inode:[pidfd]")
+	ch := make(chan struct{})
+	go func() {
+		defer func() {
+			untrackFd(pidfd)
+			unix.Close(pidfd)
+			close(ch)
+		}()
+		// A pidfd is readable once the process has exited.
+		fds := []unix.PollFd{{Fd: int32(pidfd), Events: unix.POLLIN}}
+		for {
+			_, err := unix.Poll(fds, -1)
+			if err == unix.EINTR { //nolint:errorlint // unix errors are bare
+				continue
+			}
+			if err != nil {
+				logrus.Warnf("unable to poll pidfd: %v", err)
+			}
+			return
+		}
+	}()
+	return ch
+}
+
+// notifyCgroupEmpty returns a channel which is closed once the cgroup v2
+// at dir is empty (or gone).
+func notifyCgroupEmpty(dir, id string) (<-chan struct{}, error) {
+	const cgEvName = "cgroup.events"
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+	path := filepath.Join(dir, cgEvName)
+	if _, err := unix.InotifyAddWatch(fd, path, unix.IN_MODIFY); err != nil {
+		unix.Close(fd)
+		return nil, &os.PathError{Op: "inotify_add_watch", Path: path, Err: err}
+	}
+	trackFd(fd, "exit event", id, path)
+	ch := make(chan struct{})
+	go func() {
+		defer func() {
+			untrackFd(fd)
+			unix.Close(fd)
+			close(ch)
+		}()
+		var buf [unix.SizeofInotifyEvent + unix.PathMax + 1]byte
+		for {
+			// Check before waiting, not to miss the change which
+			// happened before the watch was added.
+			if populated, err := fscommon.GetValueByKey(dir, cgEvName, "populated"); err != nil || populated == 0 {
+				return
+			}
+			if _, err := unix.Read(fd, buf[:]); err != nil && err != unix.EINTR { //nolint:errorlint // unix errors are bare
+				logrus.Warnf("unable to read event data from inotify: %v", err)
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
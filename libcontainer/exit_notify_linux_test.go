@@ -0,0 +1,87 @@
+package libcontainer
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/szcdx/runc/libcontainer/cgroups"
+)
+
+func TestNotifyPidfdExit(t *testing.T) {
+	cmd := exec.Command("sleep", "30")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}()
+	pidfd, err := unix.PidfdOpen(cmd.Process.Pid, 0)
+	if err != nil {
+		t.Skipf("pidfd_open: %v", err)
+	}
+	exited := notifyPidfdExit(pidfd, "test")
+
+	select {
+	case <-exited:
+		t.Fatal("notified before the process exited")
+	case <-time.After(100 * time.Millisecond):
+	}
+	if err := cmd.Process.Kill(); err != nil {
+		t.Fatal(err)
+	}
+	// The exit is noticed before the process is waited for.
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("exit not notified")
+	}
+	if st, err := cmd.Process.Wait(); err != nil || st.Success() {
+		t.Fatalf("unexpected wait result: %v, %v", st, err)
+	}
+}
+
+func TestNotifyCgroupEmpty(t *testing.T) {
+	// We're using a fake cgroupfs.
+	cgroups.TestMode = true
+	dir := t.TempDir()
+	events := filepath.Join(dir, "cgroup.events")
+	if err := os.WriteFile(events, []byte("populated 1\nfrozen 0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// Rewrite the file in place, as cgroupfs does, not to have it
+	// truncated (and so, not populated) in the meantime.
+	write := func(data string) {
+		t.Helper()
+		f, err := os.OpenFile(events, os.O_WRONLY, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if _, err := f.WriteAt([]byte(data), 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	exited, err := notifyCgroupEmpty(dir, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	write("populated 1\nfrozen 1\n")
+	select {
+	case <-exited:
+		t.Fatal("notified while the cgroup is populated")
+	case <-time.After(100 * time.Millisecond):
+	}
+	write("populated 0\nfrozen 0\n")
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("empty cgroup not notified")
+	}
+}
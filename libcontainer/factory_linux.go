@@ -1,7 +1,6 @@
 package libcontainer
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
@@ -10,6 +9,7 @@ import (
 	"golang.org/x/sys/unix"
 
 	//nolint:revive // Enable cgroup manager to manage devices
+	"github.com/szcdx/runc/internal/jsonutil"
 	_ "github.com/szcdx/runc/libcontainer/cgroups/devices"
 	"github.com/szcdx/runc/libcontainer/cgroups/manager"
 	"github.com/szcdx/runc/libcontainer/configs"
@@ -21,8 +21,14 @@ import (
 const (
 	stateFilename    = "state.json"
 	execFifoFilename = "exec.fifo"
+	initLogFilename  = "init.log"
 )
 
+// SeccompCacheDir is the name of the directory inside the state directory
+// (root) used to cache compiled seccomp filters. It is not a valid
+// container id.
+const SeccompCacheDir = ".seccomp-cache"
+
 // Create creates a new container with the given id inside a given state
 // directory (root), and returns a Container object.
 //
@@ -43,6 +49,11 @@ func Create(root, id string, config *configs.Config) (*Container, error) {
 	if err := validate.Validate(config); err != nil {
 		return nil, err
 	}
+	config = withTimeMounts(config)
+	if config.ExclusiveCPUs > 0 {
+		// The allocated CPUs are set in the cgroup configuration.
+		config = withOwnCgroups(config)
+	}
 	if err := os.MkdirAll(root, 0o700); err != nil {
 		return nil, err
 	}
@@ -99,14 +110,74 @@ func Create(root, id string, config *configs.Config) (*Container, error) {
 		cgroupManager:   cm,
 		intelRdtManager: intelrdt.NewManager(config, id, ""),
 	}
+	if config.ExclusiveCPUs > 0 {
+		// This is done once the state directory exists, so that the
+		// allocation is not mistaken for a stale one (see allocateCPUs).
+		cpus, err := allocateCPUs(root, id, config.ExclusiveCPUs)
+		if err != nil {
+			_ = os.Remove(stateDir)
+			return nil, err
+		}
+		config.Cgroups.Resources.CpusetCpus = cpus
+		c.exclusiveCPUs = cpus
+	}
+	if config.LaunchRecord {
+		// The configuration is measured as the container is launched
+		// with it, including the allocated CPUs.
+		c.launchRecord, err = newLaunchRecord(id, config)
+		if err != nil {
+			if c.exclusiveCPUs != "" {
+				_ = releaseCPUs(root, id)
+			}
+			_ = os.Remove(stateDir)
+			return nil, err
+		}
+	}
 	c.state = &stoppedState{c: c}
 	return c, nil
 }
 
+// withTimeMounts returns config with the mounts needed for its time
+// configuration added, or config itself if there are none. The config
+// passed to Create is not modified.
+func withTimeMounts(config *configs.Config) *configs.Config {
+	src := config.Time.TimezoneSource()
+	if src == "" {
+		return config
+	}
+	c := *config
+	c.Mounts = append(c.Mounts[:len(c.Mounts):len(c.Mounts)], &configs.Mount{
+		Source:      src,
+		Destination: configs.LocaltimePath,
+		Device:      "bind",
+		Flags:       unix.MS_BIND | unix.MS_RDONLY | unix.MS_NOSUID | unix.MS_NODEV | unix.MS_NOEXEC,
+	})
+	return &c
+}
+
+// withOwnCgroups returns a copy of config with a copy of its cgroup
+// configuration, which can be changed without changing the caller's one.
+func withOwnCgroups(config *configs.Config) *configs.Config {
+	c := *config
+	if config.Cgroups != nil {
+		cg := *config.Cgroups
+		if cg.Resources != nil {
+			r := *cg.Resources
+			cg.Resources = &r
+		}
+		c.Cgroups = &cg
+	}
+	return &c
+}
+
 // Load takes a path to the state directory (root) and an id of an existing
 // container, and returns a Container object reconstructed from the saved
 // state. This presents a read only view of the container.
 func Load(root, id string) (*Container, error) {
+	return load(root, id, false)
+}
+
+func load(root, id string, readOnly bool) (*Container, error) {
 	if root == "" {
 		return nil, errors.New("root not set")
 	}
@@ -127,7 +198,15 @@ func Load(root, id string) (*Container, error) {
 		processStartTime: state.InitProcessStartTime,
 		fds:              state.ExternalDescriptors,
 	}
-	cm, err := manager.NewWithPaths(state.Config.Cgroups, state.CgroupPaths)
+	cgroupConfig := state.Config.Cgroups
+	if readOnly && cgroupConfig != nil && cgroupConfig.Systemd {
+		// Reading the cgroup files does not require systemd, and
+		// using a fs manager avoids talking to it over dbus.
+		c := *cgroupConfig
+		c.Systemd = false
+		cgroupConfig = &c
+	}
+	cm, err := manager.NewWithPaths(cgroupConfig, state.CgroupPaths)
 	if err != nil {
 		return nil, err
 	}
@@ -141,6 +220,28 @@ func Load(root, id string) (*Container, error) {
 		stateDir:             stateDir,
 		created:              state.Created,
 	}
+	c.exclusiveCPUs = state.ExclusiveCPUs
+	c.subCgroups = state.SubCgroups
+	c.launchRecord = state.LaunchRecord
+	c.checkpointChain = state.CheckpointChain
+	if state.SeccompHolderPid > 0 {
+		c.seccompHolder = &seccompHolder{
+			pid:       state.SeccompHolderPid,
+			startTime: state.SeccompHolderStartTime,
+		}
+	}
+	if state.RootlessNetworkPid > 0 {
+		c.rootlessNetwork = &rootlessNetwork{
+			pid:       state.RootlessNetworkPid,
+			startTime: state.RootlessNetworkStartTime,
+		}
+	}
+	if state.PortForwarderPid > 0 {
+		c.portForwarder = &portForwarder{
+			pid:       state.PortForwarderPid,
+			startTime: state.PortForwarderStartTime,
+		}
+	}
 	c.state = &loadedState{c: c}
 	if err := c.refreshState(); err != nil {
 		return nil, err
@@ -162,7 +263,7 @@ func loadState(root string) (*State, error) {
 	}
 	defer f.Close()
 	var state *State
-	if err := json.NewDecoder(f).Decode(&state); err != nil {
+	if err := jsonutil.NewDecoder(f).Decode(&state); err != nil {
 		return nil, err
 	}
 	return state, nil
@@ -184,7 +285,8 @@ func loadState(root string) (*State, error) {
 // - period (.).
 //
 // In addition, IDs that can't be used to represent a file name
-// (such as . or ..) are rejected.
+// (such as . or ..), SeccompCacheDir, and the name of the CPU pool file,
+// are rejected.
 
 func validateID(id string) error {
 	if len(id) < 1 {
@@ -212,5 +314,9 @@ func validateID(id string) error {
 		return ErrInvalidID
 	}
 
+	if id == SeccompCacheDir || id == cpuPoolFilename {
+		return ErrInvalidID
+	}
+
 	return nil
 }
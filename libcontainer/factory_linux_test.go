@@ -80,6 +80,43 @@ func TestFactoryLoadContainer(t *testing.T) {
 	}
 }
 
+func TestFactoryLoadReadOnly(t *testing.T) {
+	root := t.TempDir()
+	id := "ro"
+	state := &State{
+		BaseState: BaseState{
+			InitProcessPid: 1024,
+			Config: configs.Config{
+				Rootfs: "/mycontainer/root",
+				Cgroups: &configs.Cgroup{
+					Resources: &configs.Resources{},
+					Systemd:   true,
+				},
+			},
+		},
+	}
+	if err := os.Mkdir(filepath.Join(root, id), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := marshal(filepath.Join(root, id, stateFilename), state); err != nil {
+		t.Fatal(err)
+	}
+	container, err := LoadReadOnly(root, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if container.ID() != id {
+		t.Fatalf("expected container id %q but received %q", id, container.ID())
+	}
+	st, err := container.State()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !st.Config.Cgroups.Systemd {
+		t.Fatal("expected the saved config to be intact")
+	}
+}
+
 func marshal(path string, v interface{}) error {
 	f, err := os.Create(path)
 	if err != nil {
@@ -0,0 +1,241 @@
+package libcontainer
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/szcdx/runc/libcontainer/configs"
+)
+
+// The long-lived fds libcontainer holds (that is, the ones which outlive
+// the function which opened them, such as the fds used for memory event
+// notifications) are tracked, so that a program using libcontainer to
+// manage many containers can find out which of them it holds, and why,
+// if it runs out of fds (see TrackedFds).
+
+// TrackedFd is a long-lived fd held by libcontainer.
+type TrackedFd struct {
+	// Fd is the fd number.
+	Fd int `json:"fd"`
+	// Kind is what the fd is used for, such as "exec fifo".
+	Kind string `json:"kind"`
+	// Container is the ID of the container the fd is held for, if known.
+	Container string `json:"container,omitempty"`
+	// Path is the path of the file the fd refers to.
+	Path string `json:"path"`
+	// Since is when the fd was opened.
+	Since time.Time `json:"since"`
+}
+
+var trackedFds = struct {
+	sync.Mutex
+	fds map[int]TrackedFd
+}{fds: make(map[int]TrackedFd)}
+
+// trackFd records that the fd is held by libcontainer.
+func trackFd(fd int, kind, container, path string) {
+	trackedFds.Lock()
+	defer trackedFds.Unlock()
+	trackedFds.fds[fd] = TrackedFd{Fd: fd, Kind: kind, Container: container, Path: path, Since: time.Now()}
+}
+
+// untrackFd records that the fd is no longer held. It is to be called
+// before the fd is closed, as the number may be reused right after.
+func untrackFd(fd int) {
+	trackedFds.Lock()
+	defer trackedFds.Unlock()
+	delete(trackedFds.fds, fd)
+}
+
+// closeTracked untracks and closes the file f, if it is not nil.
+func closeTracked(f *os.File) {
+	if f == nil {
+		return
+	}
+	untrackFd(int(f.Fd()))
+	f.Close()
+}
+
+// TrackedFds returns the long-lived fds currently held by libcontainer in
+// this process, ordered by the fd number.
+func TrackedFds() []TrackedFd {
+	trackedFds.Lock()
+	fds := make([]TrackedFd, 0, len(trackedFds.fds))
+	for _, fd := range trackedFds.fds {
+		fds = append(fds, fd)
+	}
+	trackedFds.Unlock()
+	sort.Slice(fds, func(i, j int) bool { return fds[i].Fd < fds[j].Fd })
+	return fds
+}
+
+// FdHolder is an fd referring to a container, held by a process which is
+// not in the container.
+type FdHolder struct {
+	// Pid and Comm are the process holding the fd.
+	Pid  int    `json:"pid"`
+	Comm string `json:"comm"`
+	// Fd is the fd number in the process.
+	Fd int `json:"fd"`
+	// Kind is what the fd refers to: "state" (a file in the container's
+	// state directory, such as the exec fifo), "cgroup", "pidfd" (a pidfd
+	// of a container process), or "namespace".
+	Kind string `json:"kind"`
+	// Target is what the fd refers to, as shown in /proc/<pid>/fd.
+	Target string `json:"target"`
+	// OpenFds is the number of fds the process has open, and FdLimit is
+	// its RLIMIT_NOFILE soft limit (0 if unknown).
+	OpenFds int    `json:"openFds"`
+	FdLimit uint64 `json:"fdLimit"`
+}
+
+// FdHolders finds the fds referring to the container which are held by
+// the processes on the host, other than the container processes and the
+// caller, to help diagnose fd leaks. An fd refers to the container if it
+// is a file in its state directory or in its cgroups, a pidfd of one of
+// its processes, or one of its (own) namespaces.
+func (c *Container) FdHolders() ([]FdHolder, error) {
+	c.m.Lock()
+	dirs := []string{c.stateDir}
+	for _, path := range c.cgroupManager.GetPaths() {
+		dirs = append(dirs, path)
+	}
+	var initPid int
+	if status, err := c.currentStatus(); err == nil && status != Stopped {
+		initPid = c.initProcess.pid()
+	}
+	namespaces := c.config.Namespaces
+	c.m.Unlock()
+
+	pids := make(map[int]bool)
+	if list, err := c.cgroupManager.GetAllPids(); err == nil {
+		for _, pid := range list {
+			pids[pid] = true
+		}
+	}
+	if initPid != 0 {
+		pids[initPid] = true
+	}
+	// Only the container's own namespaces are of interest, as the host
+	// ones are referred to by everything.
+	nsLinks := make(map[string]bool)
+	if initPid != 0 {
+		for _, ns := range namespaces {
+			if ns.Path != "" {
+				continue
+			}
+			link, err := os.Readlink("/proc/" + strconv.Itoa(initPid) + "/ns/" + configs.NsName(ns.Type))
+			if err == nil {
+				nsLinks[link] = true
+			}
+		}
+	}
+	match := func(pid int, fd, target string) string {
+		for _, dir := range dirs {
+			if dir != "" && (target == dir || strings.HasPrefix(target, dir+"/")) {
+				if dir == c.stateDir {
+					return "state"
+				}
+				return "cgroup"
+			}
+		}
+		if nsLinks[target] {
+			return "namespace"
+		}
+		if target == "anon_inode:[pidfd]" {
+			if p := pidfdPid(pid, fd); p != 0 && pids[p] {
+				return "pidfd"
+			}
+		}
+		return ""
+	}
+
+	procs, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+	self := os.Getpid()
+	var holders []FdHolder
+	for _, proc := range procs {
+		pid, err := strconv.Atoi(proc.Name())
+		if err != nil || pid == self || pids[pid] {
+			continue
+		}
+		fdDir := filepath.Join("/proc", proc.Name(), "fd")
+		fds, err := os.ReadDir(fdDir)
+		if err != nil {
+			// The process is gone, or is not ours to look at.
+			continue
+		}
+		var found []FdHolder
+		for _, fd := range fds {
+			target, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+			if err != nil {
+				continue
+			}
+			kind := match(pid, fd.Name(), target)
+			if kind == "" {
+				continue
+			}
+			n, _ := strconv.Atoi(fd.Name())
+			found = append(found, FdHolder{Pid: pid, Fd: n, Kind: kind, Target: target})
+		}
+		if len(found) == 0 {
+			continue
+		}
+		comm, _ := os.ReadFile(filepath.Join("/proc", proc.Name(), "comm"))
+		limit := fdLimit(pid)
+		for i := range found {
+			found[i].Comm = strings.TrimSpace(string(comm))
+			found[i].OpenFds = len(fds)
+			found[i].FdLimit = limit
+		}
+		holders = append(holders, found...)
+	}
+	return holders, nil
+}
+
+// pidfdPid returns the PID of the process the pidfd fd of process pid
+// refers to, or 0 if it is unknown.
+func pidfdPid(pid int, fd string) int {
+	f, err := os.Open(filepath.Join("/proc", strconv.Itoa(pid), "fdinfo", fd))
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if val, ok := strings.CutPrefix(s.Text(), "Pid:"); ok {
+			p, _ := strconv.Atoi(strings.TrimSpace(val))
+			if p > 0 {
+				return p
+			}
+		}
+	}
+	return 0
+}
+
+// fdLimit returns the RLIMIT_NOFILE soft limit of the process pid, or 0
+// if it is unknown.
+func fdLimit(pid int) uint64 {
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "limits"))
+	if err != nil {
+		return 0
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if val, ok := strings.CutPrefix(line, "Max open files"); ok {
+			fields := strings.Fields(val)
+			if len(fields) > 0 {
+				n, _ := strconv.ParseUint(fields[0], 10, 64)
+				return n
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,55 @@
+package libcontainer
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestTrackedFds(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trackFd(int(f.Fd()), "test", "ctr", path)
+
+	found := false
+	for _, fd := range TrackedFds() {
+		if fd.Fd == int(f.Fd()) {
+			found = true
+			if fd.Kind != "test" || fd.Container != "ctr" || fd.Path != path {
+				t.Errorf("unexpected tracked fd: %+v", fd)
+			}
+		}
+	}
+	if !found {
+		t.Fatal("expected the fd to be tracked")
+	}
+
+	fd := int(f.Fd())
+	closeTracked(f)
+	for _, tracked := range TrackedFds() {
+		if tracked.Fd == fd {
+			t.Fatalf("expected the fd not to be tracked after closing, got %+v", tracked)
+		}
+	}
+}
+
+func TestPidfdPid(t *testing.T) {
+	pidfd, err := unix.PidfdOpen(os.Getpid(), 0)
+	if err != nil {
+		t.Skipf("pidfd_open: %v", err)
+	}
+	defer unix.Close(pidfd)
+
+	if pid := pidfdPid(os.Getpid(), strconv.Itoa(pidfd)); pid != os.Getpid() {
+		t.Fatalf("expected %d, got %d", os.Getpid(), pid)
+	}
+	if limit := fdLimit(os.Getpid()); limit == 0 {
+		t.Fatal("expected the fd limit to be known")
+	}
+}
@@ -0,0 +1,178 @@
+// Package firewall installs the network isolation policy of a container
+// (see configs.Firewall) as an nftables ruleset in its network namespace,
+// using the nft(8) binary of the host.
+package firewall
+
+import (
+	"bytes"
+	"fmt"
+	"net/netip"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/szcdx/runc/libcontainer/configs"
+)
+
+// Table returns the name of the nftables table (of the inet family) holding
+// the ruleset of the container id. There is one per container, so that the
+// containers sharing a network namespace do not replace or remove the rules
+// of one another. The traffic is then only accepted if all of their rulesets
+// accept it.
+func Table(id string) string {
+	return "runc-" + id
+}
+
+// quotedTable returns the name of the table of the container id, quoted for
+// the nft(8) scripts, as container ids may have characters (such as dots)
+// which the nft(8) identifiers can not have.
+func quotedTable(id string) string {
+	return `"` + Table(id) + `"`
+}
+
+// Ruleset returns the nftables ruleset for fw of the container id, in the
+// nft(8) script format. The ruleset replaces the table of the container, if
+// it already exists.
+func Ruleset(id string, fw *configs.Firewall) (string, error) {
+	table := quotedTable(id)
+	var b strings.Builder
+	// Adding the table first makes deleting it never fail.
+	fmt.Fprintf(&b, "add table inet %s\n", table)
+	fmt.Fprintf(&b, "delete table inet %s\n", table)
+	fmt.Fprintf(&b, "table inet %s {\n", table)
+	b.WriteString("\tchain input {\n")
+	b.WriteString("\t\ttype filter hook input priority filter; policy drop;\n")
+	b.WriteString("\t\tiifname \"lo\" accept\n")
+	b.WriteString("\t\tct state established,related accept\n")
+	b.WriteString("\t\tct state invalid drop\n")
+	b.WriteString("\t\ticmpv6 type { nd-neighbor-solicit, nd-neighbor-advert, nd-router-advert } accept\n")
+	for _, r := range fw.Allow {
+		rule, err := ruleExpr(r)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString("\t\t" + strings.TrimSpace(rule+" accept") + "\n")
+	}
+	b.WriteString("\t}\n}\n")
+	return b.String(), nil
+}
+
+// Validate checks that the rules of fw are valid.
+func Validate(fw *configs.Firewall) error {
+	for _, r := range fw.Allow {
+		if _, err := ruleExpr(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ruleExpr returns the nftables match expression for r.
+func ruleExpr(r *configs.FirewallRule) (string, error) {
+	var expr []string
+	if r.Source != "" {
+		prefix, err := netip.ParsePrefix(r.Source)
+		if err != nil {
+			return "", fmt.Errorf("invalid firewall rule source: %w", err)
+		}
+		family := "ip"
+		if prefix.Addr().Is6() {
+			family = "ip6"
+		}
+		expr = append(expr, family+" saddr "+prefix.Masked().String())
+	}
+	switch r.Protocol {
+	case "":
+		if r.Port != 0 || r.PortEnd != 0 {
+			return "", fmt.Errorf("invalid firewall rule: port %d requires a protocol", r.Port)
+		}
+	case "tcp", "udp":
+		switch {
+		case r.Port == 0 && r.PortEnd == 0:
+			expr = append(expr, "meta l4proto "+r.Protocol)
+		case r.PortEnd == 0 || r.PortEnd == r.Port:
+			expr = append(expr, r.Protocol+" dport "+strconv.Itoa(int(r.Port)))
+		case r.Port != 0 && r.PortEnd > r.Port:
+			expr = append(expr, r.Protocol+" dport "+strconv.Itoa(int(r.Port))+"-"+strconv.Itoa(int(r.PortEnd)))
+		default:
+			return "", fmt.Errorf("invalid firewall rule port range %d-%d", r.Port, r.PortEnd)
+		}
+	default:
+		return "", fmt.Errorf("invalid firewall rule protocol %q: must be tcp or udp", r.Protocol)
+	}
+	return strings.Join(expr, " "), nil
+}
+
+// Apply installs the ruleset for fw of the container id in the network
+// namespace nsPath.
+func Apply(nsPath, id string, fw *configs.Firewall) error {
+	ruleset, err := Ruleset(id, fw)
+	if err != nil {
+		return err
+	}
+	return nft(nsPath, ruleset)
+}
+
+// Remove removes the ruleset of the container id from the network namespace
+// nsPath, if it is there, leaving the ones of the other containers alone.
+// This is only needed for the namespaces which outlive the container.
+func Remove(nsPath, id string) error {
+	table := quotedTable(id)
+	return nft(nsPath, fmt.Sprintf("add table inet %s\ndelete table inet %s\n", table, table))
+}
+
+// nft runs nft(8) in the network namespace nsPath, with script as its input.
+func nft(nsPath, script string) error {
+	path, err := exec.LookPath("nft")
+	if err != nil {
+		return fmt.Errorf("unable to set up the firewall: %w", err)
+	}
+	cmd := exec.Command(path, "-f", "-")
+	cmd.Stdin = strings.NewReader(script)
+	var out bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &out
+	if err := runInNetns(nsPath, cmd.Run); err != nil {
+		return fmt.Errorf("unable to set up the firewall: nft: %w: %s", err, bytes.TrimSpace(out.Bytes()))
+	}
+	return nil
+}
+
+// runInNetns calls fn on an OS thread which is in the network namespace
+// nsPath. The processes started by fn are in that network namespace, too.
+func runInNetns(nsPath string, fn func() error) error {
+	ns, err := os.Open(nsPath)
+	if err != nil {
+		return err
+	}
+	defer ns.Close()
+
+	errCh := make(chan error, 1)
+	go func() {
+		// The thread is not unlocked if it can't be switched back to
+		// the original namespace, so that it is terminated when the
+		// goroutine exits, rather than reused.
+		runtime.LockOSThread()
+		cur, err := os.Open("/proc/thread-self/ns/net")
+		if err != nil {
+			runtime.UnlockOSThread()
+			errCh <- err
+			return
+		}
+		defer cur.Close()
+		if err := unix.Setns(int(ns.Fd()), unix.CLONE_NEWNET); err != nil {
+			runtime.UnlockOSThread()
+			errCh <- &os.PathError{Op: "setns", Path: nsPath, Err: err}
+			return
+		}
+		err = fn()
+		if err := unix.Setns(int(cur.Fd()), unix.CLONE_NEWNET); err == nil {
+			runtime.UnlockOSThread()
+		}
+		errCh <- err
+	}()
+	return <-errCh
+}
@@ -0,0 +1,56 @@
+package firewall
+
+import (
+	"testing"
+
+	"github.com/szcdx/runc/libcontainer/configs"
+)
+
+func TestRuleset(t *testing.T) {
+	fw := &configs.Firewall{Allow: []*configs.FirewallRule{
+		{Protocol: "tcp", Port: 80},
+		{Source: "10.1.2.3/8", Protocol: "udp", Port: 8000, PortEnd: 8080},
+		{Source: "fd00::/8"},
+		{Protocol: "udp"},
+		{},
+	}}
+	const expected = `add table inet "runc-ctr.1"
+delete table inet "runc-ctr.1"
+table inet "runc-ctr.1" {
+	chain input {
+		type filter hook input priority filter; policy drop;
+		iifname "lo" accept
+		ct state established,related accept
+		ct state invalid drop
+		icmpv6 type { nd-neighbor-solicit, nd-neighbor-advert, nd-router-advert } accept
+		tcp dport 80 accept
+		ip saddr 10.0.0.0/8 udp dport 8000-8080 accept
+		ip6 saddr fd00::/8 accept
+		meta l4proto udp accept
+		accept
+	}
+}
+`
+	ruleset, err := Ruleset("ctr.1", fw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ruleset != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, ruleset)
+	}
+}
+
+func TestRulesetInvalid(t *testing.T) {
+	for _, r := range []configs.FirewallRule{
+		{Source: "10.0.0.1"},
+		{Protocol: "icmp"},
+		{Port: 22},
+		{Protocol: "tcp", PortEnd: 22},
+		{Protocol: "tcp", Port: 22, PortEnd: 21},
+	} {
+		r := r
+		if err := Validate(&configs.Firewall{Allow: []*configs.FirewallRule{&r}}); err == nil {
+			t.Errorf("%+v: expected an error", r)
+		}
+	}
+}
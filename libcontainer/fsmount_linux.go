@@ -0,0 +1,201 @@
+package libcontainer
+
+import (
+	"errors"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// The fsconfig(2) commands used (see linux/mount.h).
+const (
+	fsconfigSetFlag   = 0
+	fsconfigSetString = 1
+	fsconfigCmdCreate = 6
+)
+
+// errFsMountFlags is returned by fsMount if the mount flags can't be
+// expressed with the new mount API.
+var errFsMountFlags = errors.New("mount flags not supported by the new mount API")
+
+// fsMountAttrs are the mount flags which are mount attributes, and so are
+// set by fsmount(2).
+var fsMountAttrs = map[uintptr]int{
+	unix.MS_RDONLY:      unix.MOUNT_ATTR_RDONLY,
+	unix.MS_NOSUID:      unix.MOUNT_ATTR_NOSUID,
+	unix.MS_NODEV:       unix.MOUNT_ATTR_NODEV,
+	unix.MS_NOEXEC:      unix.MOUNT_ATTR_NOEXEC,
+	unix.MS_NOATIME:     unix.MOUNT_ATTR_NOATIME,
+	unix.MS_NODIRATIME:  unix.MOUNT_ATTR_NODIRATIME,
+	unix.MS_RELATIME:    unix.MOUNT_ATTR_RELATIME,
+	unix.MS_STRICTATIME: unix.MOUNT_ATTR_STRICTATIME,
+	unix.MS_NOSYMFOLLOW: unix.MOUNT_ATTR_NOSYMFOLLOW,
+}
+
+// fsMountSbFlags are the mount flags which are superblock flags, and so are
+// set by fsconfig(2).
+var fsMountSbFlags = map[uintptr]string{
+	unix.MS_RDONLY:      "ro",
+	unix.MS_SYNCHRONOUS: "sync",
+	unix.MS_DIRSYNC:     "dirsync",
+	unix.MS_LAZYTIME:    "lazytime",
+	unix.MS_MANDLOCK:    "mand",
+}
+
+// useFsMount tells whether a mount can be done with fsMount: it is a new
+// mount of a filesystem, rather than a bind mount, a move, a remount or a
+// propagation change.
+func useFsMount(srcFile *mountSource, fstype string, flags uintptr) bool {
+	const notNew = unix.MS_BIND | unix.MS_MOVE | unix.MS_REMOUNT |
+		unix.MS_SHARED | unix.MS_PRIVATE | unix.MS_SLAVE | unix.MS_UNBINDABLE
+	return srcFile == nil && fstype != "" && fstype != "bind" && flags&notNew == 0
+}
+
+// fsMount mounts a new filesystem of type fstype from source on dst, with
+// the flags and the (comma separated) options in data, using the new mount
+// API (fsopen(2), fsconfig(2), fsmount(2) and move_mount(2)). Rather than
+// mount(2), it sets the options one by one, and returns the messages the
+// filesystem logged while doing so, which tell what was wrong if it failed.
+func fsMount(source, dst, fstype string, flags uintptr, data string) (log []string, _ error) {
+	attrs := 0
+	var sbFlags []string
+	for f := uintptr(1); f != 0; f <<= 1 {
+		if flags&f == 0 || f == unix.MS_SILENT || f == unix.MS_REC {
+			continue
+		}
+		attr, isAttr := fsMountAttrs[f]
+		sbFlag, isSbFlag := fsMountSbFlags[f]
+		if !isAttr && !isSbFlag {
+			return nil, errFsMountFlags
+		}
+		attrs |= attr
+		if isSbFlag {
+			sbFlags = append(sbFlags, sbFlag)
+		}
+	}
+
+	fsfd, err := unix.Fsopen(fstype, unix.FSOPEN_CLOEXEC)
+	if err != nil {
+		return nil, &mountError{op: "fsopen", source: source, target: dst, data: fstype, err: err}
+	}
+	defer unix.Close(fsfd)
+	config := func(cmd uint, key, value string) error {
+		if err := fsconfig(fsfd, cmd, key, value); err != nil {
+			log = append(log, readFsLog(fsfd)...)
+			return &mountError{op: "fsconfig", source: source, target: dst, data: key, err: err}
+		}
+		return nil
+	}
+
+	if source != "" {
+		if err := config(fsconfigSetString, "source", source); err != nil {
+			return log, err
+		}
+	}
+	for _, f := range sbFlags {
+		if err := config(fsconfigSetFlag, f, ""); err != nil {
+			return log, err
+		}
+	}
+	for _, opt := range splitMountData(data) {
+		key, value, ok := strings.Cut(opt, "=")
+		if ok {
+			err = config(fsconfigSetString, key, value)
+		} else {
+			err = config(fsconfigSetFlag, key, "")
+		}
+		if err != nil {
+			return log, err
+		}
+	}
+	if err := config(fsconfigCmdCreate, "", ""); err != nil {
+		return log, err
+	}
+	log = append(log, readFsLog(fsfd)...)
+
+	mfd, err := unix.Fsmount(fsfd, unix.FSMOUNT_CLOEXEC, attrs)
+	if err != nil {
+		return log, &mountError{op: "fsmount", source: source, target: dst, flags: flags, err: err}
+	}
+	defer unix.Close(mfd)
+	// Follow dst if it is a symlink (such as a /proc/thread-self/fd/NN
+	// one), as mount(2) does.
+	if err := unix.MoveMount(mfd, "", unix.AT_FDCWD, dst, unix.MOVE_MOUNT_F_EMPTY_PATH|unix.MOVE_MOUNT_T_SYMLINKS); err != nil {
+		return log, &mountError{op: "move_mount", source: source, target: dst, err: err}
+	}
+	return log, nil
+}
+
+// fsMountUnsupported tells whether fsMount failed with err because the new
+// mount API can't be used for the mount (such as with kernels without it),
+// in which case mount(2) is to be used instead.
+func fsMountUnsupported(err error) bool {
+	return errors.Is(err, errFsMountFlags) || errors.Is(err, unix.ENOSYS) ||
+		errors.Is(err, unix.EOPNOTSUPP)
+}
+
+// fsconfig is fsconfig(2), for the commands which take a key and a value
+// (or no value).
+func fsconfig(fd int, cmd uint, key, value string) error {
+	var k, v *byte
+	var err error
+	if key != "" {
+		if k, err = unix.BytePtrFromString(key); err != nil {
+			return err
+		}
+	}
+	if cmd == fsconfigSetString {
+		if v, err = unix.BytePtrFromString(value); err != nil {
+			return err
+		}
+	}
+	_, _, errno := unix.Syscall6(unix.SYS_FSCONFIG, uintptr(fd), uintptr(cmd),
+		uintptr(unsafe.Pointer(k)), uintptr(unsafe.Pointer(v)), 0, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// readFsLog returns the messages logged in the filesystem context fsfd,
+// such as "e tmpfs: Bad value for 'size'".
+func readFsLog(fsfd int) []string {
+	var msgs []string
+	buf := make([]byte, 4096)
+	for {
+		n, err := unix.Read(fsfd, buf)
+		if err != nil || n <= 0 {
+			return msgs
+		}
+		msgs = append(msgs, strings.TrimSpace(string(buf[:n])))
+	}
+}
+
+// splitMountData splits the mount options in data, separated by commas
+// except within double quotes (as in an SELinux context="a:b:c:s0:c1,c2"),
+// removing the quotes.
+func splitMountData(data string) []string {
+	var (
+		opts   []string
+		opt    strings.Builder
+		quoted bool
+	)
+	for _, r := range data {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == ',' && !quoted:
+			if opt.Len() > 0 {
+				opts = append(opts, opt.String())
+			}
+			opt.Reset()
+		default:
+			opt.WriteRune(r)
+		}
+	}
+	if opt.Len() > 0 {
+		opts = append(opts, opt.String())
+	}
+	return opts
+}
@@ -0,0 +1,75 @@
+package libcontainer
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestSplitMountData(t *testing.T) {
+	for _, tc := range []struct {
+		data string
+		opts []string
+	}{
+		{"", nil},
+		{"mode=755", []string{"mode=755"}},
+		{"newinstance,ptmxmode=0666,,mode=0620", []string{"newinstance", "ptmxmode=0666", "mode=0620"}},
+		{`size=65536k,context="system_u:object_r:container_file_t:s0:c1,c2"`, []string{"size=65536k", "context=system_u:object_r:container_file_t:s0:c1,c2"}},
+	} {
+		if opts := splitMountData(tc.data); !reflect.DeepEqual(opts, tc.opts) {
+			t.Errorf("%q: expected %q, got %q", tc.data, tc.opts, opts)
+		}
+	}
+}
+
+func TestFsMount(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("requires root")
+	}
+	dir := t.TempDir()
+	_, err := fsMount("tmpfs", dir, "tmpfs", unix.MS_NOEXEC|unix.MS_NOSUID, "mode=700,size=1m")
+	if errors.Is(err, unix.ENOSYS) {
+		t.Skip("the new mount API is not supported")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unix.Unmount(dir, unix.MNT_DETACH) //nolint:errcheck
+
+	var st unix.Statfs_t
+	if err := unix.Statfs(dir, &st); err != nil {
+		t.Fatal(err)
+	}
+	if st.Type != unix.TMPFS_MAGIC || st.Flags&unix.ST_NOEXEC == 0 || st.Flags&unix.ST_NOSUID == 0 {
+		t.Errorf("unexpected mount: type %x, flags %x", st.Type, st.Flags)
+	}
+	fi, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0o700 {
+		t.Errorf("expected mode 0700, got %v", fi.Mode().Perm())
+	}
+
+	// A bad option is explained by the filesystem.
+	log, err := fsMount("tmpfs", dir, "tmpfs", 0, "size=bogus")
+	if err == nil {
+		t.Fatal("expected error for a bad option, got nil")
+	}
+	if !strings.Contains(strings.Join(log, "\n"), "size") {
+		t.Errorf("expected the log to tell about the size option, got %q", log)
+	}
+	if fsMountUnsupported(err) {
+		t.Errorf("expected no fallback to mount(2) for a bad option, got %v", err)
+	}
+	// Mount flags which the new mount API has no equivalent of.
+	if _, err := fsMount("tmpfs", dir, "tmpfs", unix.MS_POSIXACL, ""); !errors.Is(err, errFsMountFlags) {
+		t.Errorf("expected errFsMountFlags, got %v", err)
+	} else if !fsMountUnsupported(err) {
+		t.Errorf("expected a fallback to mount(2) for %v", err)
+	}
+}
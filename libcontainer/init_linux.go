@@ -22,6 +22,7 @@ import (
 	"github.com/szcdx/runc/libcontainer/capabilities"
 	"github.com/szcdx/runc/libcontainer/cgroups"
 	"github.com/szcdx/runc/libcontainer/configs"
+	"github.com/szcdx/runc/libcontainer/seccomp"
 	"github.com/szcdx/runc/libcontainer/system"
 	"github.com/szcdx/runc/libcontainer/utils"
 )
@@ -31,6 +32,12 @@ type initType string
 const (
 	initSetns    initType = "setns"
 	initStandard initType = "standard"
+	// initSeccompHolder is not a container process, but the process keeping
+	// a copy of the seccomp notify fd (see startSeccompHolder).
+	initSeccompHolder initType = "seccomp-holder"
+	// initPortForwarder is not a container process either, but the process
+	// forwarding the ports of the container (see startPortForwarder).
+	initPortForwarder initType = "port-forwarder"
 )
 
 type pid struct {
@@ -70,6 +77,7 @@ type initConfig struct {
 	RootlessCgroups  bool                  `json:"rootless_cgroups,omitempty"`
 	SpecState        *specs.State          `json:"spec_state,omitempty"`
 	Cgroup2Path      string                `json:"cgroup2_path,omitempty"`
+	SeccompProgram   *seccomp.Program      `json:"seccomp_program,omitempty"`
 }
 
 // Init is part of "runc init" implementation.
@@ -77,6 +85,21 @@ func Init() {
 	runtime.GOMAXPROCS(1)
 	runtime.LockOSThread()
 
+	switch initType(os.Getenv("_LIBCONTAINER_INITTYPE")) {
+	case initSeccompHolder:
+		if err := seccompHolderMain(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	case initPortForwarder:
+		if err := portForwarderMain(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	if err := startInitialization(); err != nil {
 		// If the error is returned, it was not communicated
 		// back to the parent (which is not a common case),
@@ -105,12 +128,21 @@ func startInitialization() (retErr error) {
 	syncPipe := newSyncSocket(os.NewFile(uintptr(syncPipeFd), "sync"))
 	defer syncPipe.Close()
 
+	var initLog *os.File
 	defer func() {
 		// If this defer is ever called, this means initialization has failed.
 		// Send the error back to the parent process in the form of an initError.
 		ierr := initError{Message: retErr.Error()}
 		if err := writeSyncArg(syncPipe, procError, ierr); err != nil {
 			fmt.Fprintln(os.Stderr, err)
+			// The parent is gone (as it is once the container is
+			// created), so keep the error in the init log.
+			if initLog != nil {
+				l := logrus.New()
+				l.SetOutput(initLog)
+				l.SetFormatter(new(logrus.JSONFormatter))
+				l.Error(retErr)
+			}
 			return
 		}
 		// The error is sent, no need to also return it (or it will be reported twice).
@@ -188,6 +220,27 @@ func startInitialization() (retErr error) {
 		dmzExe = os.NewFile(uintptr(dmzFd), "runc-dmz")
 	}
 
+	// Get the fd of the executable to run, if any (see Process.Exe).
+	var exe *os.File
+	if exeFdStr := os.Getenv("_LIBCONTAINER_EXEFD"); exeFdStr != "" {
+		exeFd, err := strconv.Atoi(exeFdStr)
+		if err != nil {
+			return fmt.Errorf("unable to convert _LIBCONTAINER_EXEFD: %w", err)
+		}
+		unix.CloseOnExec(exeFd)
+		exe = os.NewFile(uintptr(exeFd), "exe")
+	}
+
+	// Get the init log, if any (see Container.InitLog).
+	if initLogFdStr := os.Getenv("_LIBCONTAINER_INITLOG"); initLogFdStr != "" {
+		initLogFd, err := strconv.Atoi(initLogFdStr)
+		if err != nil {
+			return fmt.Errorf("unable to convert _LIBCONTAINER_INITLOG: %w", err)
+		}
+		unix.CloseOnExec(initLogFd)
+		initLog = os.NewFile(uintptr(initLogFd), "init-log")
+	}
+
 	// clear the current process's environment to clean any libcontainer
 	// specific env vars.
 	os.Clearenv()
@@ -208,10 +261,10 @@ func startInitialization() (retErr error) {
 	}
 
 	// If init succeeds, it will not return, hence none of the defers will be called.
-	return containerInit(it, &config, syncPipe, consoleSocket, pidfdSocket, fifofd, logFD, dmzExe)
+	return containerInit(it, &config, syncPipe, consoleSocket, pidfdSocket, fifofd, logFD, dmzExe, exe, initLog)
 }
 
-func containerInit(t initType, config *initConfig, pipe *syncSocket, consoleSocket, pidfdSocket *os.File, fifoFd, logFd int, dmzExe *os.File) error {
+func containerInit(t initType, config *initConfig, pipe *syncSocket, consoleSocket, pidfdSocket *os.File, fifoFd, logFd int, dmzExe, exe, initLog *os.File) error {
 	if err := populateProcessEnvironment(config.Env); err != nil {
 		return err
 	}
@@ -225,6 +278,7 @@ func containerInit(t initType, config *initConfig, pipe *syncSocket, consoleSock
 			config:        config,
 			logFd:         logFd,
 			dmzExe:        dmzExe,
+			exe:           exe,
 		}
 		return i.Init()
 	case initStandard:
@@ -237,6 +291,7 @@ func containerInit(t initType, config *initConfig, pipe *syncSocket, consoleSock
 			fifoFd:        fifoFd,
 			logFd:         logFd,
 			dmzExe:        dmzExe,
+			initLog:       initLog,
 		}
 		return i.Init()
 	}
@@ -408,6 +463,15 @@ func syncParentHooks(pipe *syncSocket) error {
 	return readSync(pipe, procHooksDone)
 }
 
+// initSeccomp loads the container's seccomp filter, using the program
+// compiled by the parent if there is one.
+func initSeccomp(config *initConfig) (*os.File, error) {
+	if config.SeccompProgram != nil {
+		return seccomp.LoadProgram(config.SeccompProgram)
+	}
+	return seccomp.InitSeccomp(config.Config.Seccomp)
+}
+
 // syncParentSeccomp sends the fd associated with the seccomp file descriptor
 // to the parent, and wait for the parent to do pidfd_getfd() to grab a copy.
 func syncParentSeccomp(pipe *syncSocket, seccompFd *os.File) error {
@@ -1101,6 +1101,49 @@ func TestHook(t *testing.T) {
 	}
 }
 
+func TestInContainerHook(t *testing.T) {
+	if testing.Short() {
+		return
+	}
+
+	config := newTemplateConfig(t, nil)
+	// The hook path is a host path; use the (static) busybox binary
+	// from the rootfs, as host binaries may need libraries which are
+	// not present in the container.
+	config.InContainerHooks = []configs.InContainerHook{
+		{
+			Command: configs.Command{
+				Path: filepath.Join(config.Rootfs, "bin/busybox"),
+				Args: []string{"sh", "-c", "grep CapEff /proc/self/status > /inhook"},
+			},
+		},
+	}
+
+	buffers := runContainerOk(t, config, "cat", "/inhook")
+	if out := strings.TrimSpace(buffers.Stdout.String()); out != "CapEff:\t0000000000000000" {
+		t.Fatalf("unexpected in-container hook output: %q", out)
+	}
+
+	// A script from the host, run by an interpreter of the container. The
+	// fd of the script file is not left to it, only the one of a copy.
+	script := filepath.Join(t.TempDir(), "hook.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\n(echo script; readlink /proc/$$/fd/*) > /inhook\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	config = newTemplateConfig(t, nil)
+	config.InContainerHooks = []configs.InContainerHook{
+		{Command: configs.Command{Path: script}},
+	}
+	buffers = runContainerOk(t, config, "cat", "/inhook")
+	out, fds, _ := strings.Cut(buffers.Stdout.String(), "\n")
+	if out != "script" {
+		t.Fatalf("unexpected in-container script hook output: %q", out)
+	}
+	if strings.Contains(fds, "hook.sh") {
+		t.Fatalf("script fd left open to the script: %q", fds)
+	}
+}
+
 func TestSTDIOPermissions(t *testing.T) {
 	if testing.Short() {
 		return
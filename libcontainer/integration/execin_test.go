@@ -2,6 +2,7 @@ package integration
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -67,6 +68,60 @@ func TestExecIn(t *testing.T) {
 	}
 }
 
+func TestJoin(t *testing.T) {
+	if testing.Short() {
+		return
+	}
+	config := newTemplateConfig(t, nil)
+	container, err := newContainer(t, config)
+	ok(t, err)
+	defer destroyContainer(container)
+
+	stdinR, stdinW, err := os.Pipe()
+	ok(t, err)
+	process := &libcontainer.Process{
+		Cwd:   "/",
+		Args:  []string{"cat"},
+		Env:   standardEnvironment,
+		Stdin: stdinR,
+		Init:  true,
+	}
+	err = container.Run(process)
+	_ = stdinR.Close()
+	defer stdinW.Close() //nolint: errcheck
+	ok(t, err)
+
+	buffers := newStdBuffers()
+	ps := &libcontainer.Process{
+		Cwd:    "/",
+		Args:   []string{"ps"},
+		Env:    standardEnvironment,
+		Stdin:  buffers.Stdin,
+		Stdout: buffers.Stdout,
+		Stderr: buffers.Stderr,
+	}
+	ok(t, container.Join(ps))
+	waitProcess(ps, t)
+	if out := buffers.Stdout.String(); !strings.Contains(out, "cat") {
+		t.Fatalf("unexpected running process, output %q", out)
+	}
+
+	// A paused container can not be joined.
+	ok(t, container.Pause())
+	err = container.Join(&libcontainer.Process{
+		Cwd:  "/",
+		Args: []string{"true"},
+		Env:  standardEnvironment,
+	})
+	ok(t, container.Resume())
+	if !errors.Is(err, libcontainer.ErrPaused) {
+		t.Fatalf("expected ErrPaused, got %v", err)
+	}
+
+	_ = stdinW.Close()
+	waitProcess(process, t)
+}
+
 func TestExecInUsernsRlimit(t *testing.T) {
 	if _, err := os.Stat("/proc/self/ns/user"); os.IsNotExist(err) {
 		t.Skip("Test requires userns.")
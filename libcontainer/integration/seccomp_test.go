@@ -11,6 +11,7 @@ import (
 	libseccomp "github.com/seccomp/libseccomp-golang"
 	"github.com/szcdx/runc/libcontainer"
 	"github.com/szcdx/runc/libcontainer/configs"
+	"github.com/szcdx/runc/libcontainer/seccomp/notify"
 )
 
 func TestSeccompDenyGetcwdWithErrno(t *testing.T) {
@@ -417,3 +418,66 @@ func TestSeccompMultipleConditionSameArgDeniesStderr(t *testing.T) {
 		t.Fatalf("Something was written to stderr, write call succeeded!\n")
 	}
 }
+
+func TestSeccompNotifyInProcess(t *testing.T) {
+	if testing.Short() {
+		return
+	}
+	if apiLevel, _ := libseccomp.GetAPI(); apiLevel < 6 {
+		t.Skip("seccomp notify not supported")
+	}
+
+	config := newTemplateConfig(t, nil)
+	config.Seccomp = &configs.Seccomp{
+		DefaultAction: configs.Allow,
+		Syscalls: []*configs.Syscall{
+			{
+				Name:   "getcwd",
+				Action: configs.Notify,
+			},
+		},
+	}
+
+	container, err := newContainer(t, config)
+	ok(t, err)
+	defer container.Destroy() //nolint:errcheck
+
+	buffers := newStdBuffers()
+	pwd := &libcontainer.Process{
+		Cwd:           "/",
+		Args:          []string{"pwd"},
+		Env:           standardEnvironment,
+		Stdin:         buffers.Stdin,
+		Stdout:        buffers.Stdout,
+		Stderr:        buffers.Stderr,
+		Init:          true,
+		SeccompNotify: true,
+	}
+
+	err = container.Run(pwd)
+	ok(t, err)
+
+	fd, err := container.SeccompNotifyFd()
+	ok(t, err)
+	l := notify.NewListener(fd)
+	defer l.Close()
+	go func() {
+		_ = l.Serve(func(req *notify.Request) *notify.Response {
+			if req.Syscall != "getcwd" {
+				return &notify.Response{ID: req.ID, Continue: true}
+			}
+			return &notify.Response{ID: req.ID, Errno: int32(syscall.ESRCH)}
+		})
+	}()
+
+	_, err = pwd.Wait()
+	if err == nil {
+		t.Fatal("Expecting error (negative return code); instead exited cleanly!")
+	}
+
+	expected := "pwd: getcwd: No such process"
+	actual := strings.Trim(buffers.Stderr.String(), "\n")
+	if actual != expected {
+		t.Fatalf("Expected output %s but got %s\n", expected, actual)
+	}
+}
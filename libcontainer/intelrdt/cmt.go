@@ -12,7 +12,7 @@ func getCMTNumaNodeStats(numaPath string) (*CMTNumaNodeStats, error) {
 	stats := &CMTNumaNodeStats{}
 
 	if enabledMonFeatures.llcOccupancy {
-		llcOccupancy, err := getIntelRdtParamUint(numaPath, "llc_occupancy")
+		llcOccupancy, err := getMonitoringCounter(numaPath, "llc_occupancy")
 		if err != nil {
 			return nil, err
 		}
@@ -435,12 +435,13 @@ func (m *Manager) getIntelRdtPath() (string, error) {
 		return "", err
 	}
 
-	clos := m.id
 	if m.config.IntelRdt != nil && m.config.IntelRdt.ClosID != "" {
-		clos = m.config.IntelRdt.ClosID
+		return filepath.Join(rootPath, m.config.IntelRdt.ClosID), nil
 	}
-
-	return filepath.Join(rootPath, clos), nil
+	if m.isMonitoringOnly() {
+		return rootPath, nil
+	}
+	return filepath.Join(rootPath, m.id), nil
 }
 
 // Applies Intel RDT configuration to the process with the specified pid
@@ -474,22 +475,46 @@ func (m *Manager) Apply(pid int) (err error) {
 		return newLastCmdError(err)
 	}
 
+	// A task can only be moved to a monitoring group of its own CLOS, so
+	// this comes second.
+	if m.config.IntelRdt.EnableMonitoring {
+		monPath := filepath.Join(path, monGroupsDir, m.id)
+		if err := os.MkdirAll(monPath, 0o755); err != nil {
+			return newLastCmdError(err)
+		}
+		if err := WriteIntelRdtTasks(monPath, pid); err != nil {
+			return err
+		}
+	}
+
 	m.path = path
 	return nil
 }
 
 // Destroys the Intel RDT container-specific 'container_id' group
 func (m *Manager) Destroy() error {
+	if m.config.IntelRdt == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	// Don't remove resctrl group if closid has been explicitly specified. The
 	// group is likely externally managed, i.e. by some other entity than us.
-	// There are probably other containers/tasks sharing the same group.
-	if m.config.IntelRdt != nil && m.config.IntelRdt.ClosID == "" {
-		m.mu.Lock()
-		defer m.mu.Unlock()
+	// There are probably other containers/tasks sharing the same group. The
+	// same goes for the root group, with only monitoring enabled.
+	if m.config.IntelRdt.ClosID == "" && !m.isMonitoringOnly() {
+		// Along with the monitoring group in it, if any.
 		if err := os.RemoveAll(m.GetPath()); err != nil {
 			return err
 		}
 		m.path = ""
+		return nil
+	}
+	// The monitoring group is the container's own, though.
+	if monPath := m.GetMonitoringPath(); monPath != "" {
+		if err := os.Remove(monPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
 	}
 	return nil
 }
@@ -580,7 +605,11 @@ func (m *Manager) GetStats() (*Stats, error) {
 	}
 
 	if IsMBMEnabled() || IsCMTEnabled() {
-		err = getMonitoringStats(containerPath, stats)
+		monPath := m.GetMonitoringPath()
+		if monPath == "" {
+			monPath = containerPath
+		}
+		err = getMonitoringStats(monPath, stats)
 		if err != nil {
 			return nil, err
 		}
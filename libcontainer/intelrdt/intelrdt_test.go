@@ -125,3 +125,77 @@ func TestApply(t *testing.T) {
 		t.Fatalf("unexpected tasks file, expected '1235', got %q", pids)
 	}
 }
+
+func TestApplyMonitoring(t *testing.T) {
+	helper := NewIntelRdtTestUtil(t)
+
+	const closID = "test-clos"
+	if err := os.Mkdir(filepath.Join(intelRdtRoot, closID), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	helper.config.IntelRdt.ClosID = closID
+	helper.config.IntelRdt.EnableMonitoring = true
+	intelrdt := newManager(helper.config, "ctr", "")
+	if err := intelrdt.Apply(1236); err != nil {
+		t.Fatalf("Apply() failed: %v", err)
+	}
+
+	monPath := filepath.Join(intelRdtRoot, closID, "mon_groups", "ctr")
+	if intelrdt.GetMonitoringPath() != monPath {
+		t.Fatalf("expected monitoring group %q, got %q", monPath, intelrdt.GetMonitoringPath())
+	}
+	for _, path := range []string{intelrdt.GetPath(), monPath} {
+		pids, err := getIntelRdtParamString(path, "tasks")
+		if err != nil {
+			t.Fatalf("failed to read tasks file: %v", err)
+		}
+		if pids != "1236" {
+			t.Fatalf("unexpected tasks file in %s, expected '1236', got %q", path, pids)
+		}
+	}
+
+	// resctrl removes the files of a group along with it.
+	if err := os.Remove(filepath.Join(monPath, "tasks")); err != nil {
+		t.Fatal(err)
+	}
+	if err := intelrdt.Destroy(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(monPath); !os.IsNotExist(err) {
+		t.Fatalf("expected the monitoring group to be removed, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(intelRdtRoot, closID)); err != nil {
+		t.Fatalf("expected the shared clos group to be kept, got %v", err)
+	}
+}
+
+func TestApplyMonitoringOnly(t *testing.T) {
+	helper := NewIntelRdtTestUtil(t)
+
+	helper.config.IntelRdt.EnableMonitoring = true
+	intelrdt := newManager(helper.config, "ctr", "")
+	if err := intelrdt.Apply(1237); err != nil {
+		t.Fatalf("Apply() failed: %v", err)
+	}
+	// The container stays in the root group.
+	if intelrdt.GetPath() != intelRdtRoot {
+		t.Fatalf("expected the root group %q, got %q", intelRdtRoot, intelrdt.GetPath())
+	}
+	monPath := filepath.Join(intelRdtRoot, "mon_groups", "ctr")
+	if intelrdt.GetMonitoringPath() != monPath {
+		t.Fatalf("expected monitoring group %q, got %q", monPath, intelrdt.GetMonitoringPath())
+	}
+
+	if err := os.Remove(filepath.Join(monPath, "tasks")); err != nil {
+		t.Fatal(err)
+	}
+	if err := intelrdt.Destroy(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(monPath); !os.IsNotExist(err) {
+		t.Fatalf("expected the monitoring group to be removed, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(intelRdtRoot, "tasks")); err != nil {
+		t.Fatalf("expected the root group to be kept, got %v", err)
+	}
+}
@@ -12,7 +12,7 @@ func IsMBMEnabled() bool {
 func getMBMNumaNodeStats(numaPath string) (*MBMNumaNodeStats, error) {
 	stats := &MBMNumaNodeStats{}
 	if enabledMonFeatures.mbmTotalBytes {
-		mbmTotalBytes, err := getIntelRdtParamUint(numaPath, "mbm_total_bytes")
+		mbmTotalBytes, err := getMonitoringCounter(numaPath, "mbm_total_bytes")
 		if err != nil {
 			return nil, err
 		}
@@ -20,7 +20,7 @@ func getMBMNumaNodeStats(numaPath string) (*MBMNumaNodeStats, error) {
 	}
 
 	if enabledMonFeatures.mbmLocalBytes {
-		mbmLocalBytes, err := getIntelRdtParamUint(numaPath, "mbm_local_bytes")
+		mbmLocalBytes, err := getMonitoringCounter(numaPath, "mbm_local_bytes")
 		if err != nil {
 			return nil, err
 		}
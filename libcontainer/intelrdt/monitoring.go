@@ -2,13 +2,19 @@ package intelrdt
 
 import (
 	"bufio"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 
 	"github.com/sirupsen/logrus"
+
+	"github.com/szcdx/runc/libcontainer/cgroups/fscommon"
 )
 
+// monGroupsDir is the directory of the monitoring groups of a CLOS group.
+const monGroupsDir = "mon_groups"
+
 var enabledMonFeatures monFeatures
 
 type monFeatures struct {
@@ -81,3 +87,44 @@ func getMonitoringStats(containerPath string, stats *Stats) error {
 
 	return err
 }
+
+// GetMonitoringPath returns the path of the monitoring group of the
+// container, in the mon_groups directory of its CLOS group, or an empty
+// string if monitoring is not enabled.
+func (m *Manager) GetMonitoringPath() string {
+	if m.config.IntelRdt == nil || !m.config.IntelRdt.EnableMonitoring {
+		return ""
+	}
+	path := m.GetPath()
+	if path == "" {
+		return ""
+	}
+	return filepath.Join(path, monGroupsDir, m.id)
+}
+
+// isMonitoringOnly returns whether only monitoring is configured, in which
+// case the container stays in the root CLOS group rather than having one of
+// its own (the number of CLOS groups is much more limited than the number of
+// monitoring groups).
+func (m *Manager) isMonitoringOnly() bool {
+	c := m.config.IntelRdt
+	return c != nil && c.EnableMonitoring && c.ClosID == "" && c.L3CacheSchema == "" && c.MemBwSchema == ""
+}
+
+// getMonitoringCounter gets the value of an event counter of mon_data. The
+// kernel reports "Unavailable" for a counter it has no value for yet (such
+// as right after the group is created), which is reported as 0.
+func getMonitoringCounter(numaPath, file string) (uint64, error) {
+	value, err := getIntelRdtParamString(numaPath, file)
+	if err != nil {
+		return 0, err
+	}
+	if value == "Unavailable" {
+		return 0, nil
+	}
+	res, err := fscommon.ParseUint(value, 10, 64)
+	if err != nil {
+		return res, fmt.Errorf("unable to parse %q as a uint from file %q", value, filepath.Join(numaPath, file))
+	}
+	return res, nil
+}
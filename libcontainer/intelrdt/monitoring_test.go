@@ -101,3 +101,24 @@ func TestGetMonitoringStats(t *testing.T) {
 		}
 	})
 }
+
+func TestGetMonitoringCounterUnavailable(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "mbm_total_bytes"), []byte("Unavailable\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	value, err := getMonitoringCounter(dir, "mbm_total_bytes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != 0 {
+		t.Fatalf("expected 0, got %d", value)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "llc_occupancy"), []byte("Error\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := getMonitoringCounter(dir, "llc_occupancy"); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
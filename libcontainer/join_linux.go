@@ -0,0 +1,42 @@
+package libcontainer
+
+import "errors"
+
+// Join starts process in the namespaces, cgroups and security context (LSM
+// labels, capabilities, seccomp filter and rlimits) of the container, the
+// same way Start does for a non-init process, but without going through the
+// container's state machine. It is a lighter primitive meant for debuggers
+// and node agents which need a one-shot nsenter into the container:
+//
+//   - the container can be in any state in which its init process is alive
+//     and not frozen, that is, either created or running;
+//   - the process start rate limit (see configs.ExecRateLimit) does not
+//     apply, as it is there to limit the container's own workload.
+//
+// The settings which process does not set (such as Capabilities, Rlimits,
+// AppArmorProfile or Label) are the ones of the container.
+func (c *Container) Join(process *Process) error {
+	if process.Init {
+		return errors.New("can't join the container with an init process")
+	}
+	c.m.Lock()
+	defer c.m.Unlock()
+	if c.config.Cgroups.Resources.SkipDevices {
+		return errors.New("can't start process with SkipDevices set")
+	}
+	status, err := c.currentStatus()
+	if err != nil {
+		return err
+	}
+	switch status {
+	case Stopped:
+		return ErrNotRunning
+	case Paused:
+		// The process would be frozen as soon as it joins the cgroup,
+		// before it is done with runc init.
+		return ErrPaused
+	}
+	// Nothing is recorded in the journal for a non-init process.
+	var j journal
+	return c.start(process, &j)
+}
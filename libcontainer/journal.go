@@ -0,0 +1,50 @@
+package libcontainer
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// journal records the operations done while creating a container (or
+// starting a process in it), so that if a later step fails, everything
+// created so far (cgroups and systemd units, the exec fifo, the container
+// init, etc.) is removed, rather than being leaked. The namespaces and the
+// mounts in them go away together with the container init.
+//
+// An undo operation is recorded before the operation it undoes is
+// performed, as the latter may fail halfway (for example, after creating
+// some of the cgroups), and it has to be safe to call in that case, or
+// if the operation was not performed at all. On rollback, the operations
+// are undone in the reverse order, and all of them are attempted even if
+// some fail.
+type journal struct {
+	ops []journalOp
+}
+
+type journalOp struct {
+	name string
+	undo func() error
+}
+
+// record adds an undo operation to the journal.
+func (j *journal) record(name string, undo func() error) {
+	j.ops = append(j.ops, journalOp{name: name, undo: undo})
+}
+
+// rollback undoes all the recorded operations, in the reverse order, and
+// empties the journal. The errors of the undo operations are returned
+// joined together.
+func (j *journal) rollback() error {
+	var errs []error
+	for i := len(j.ops) - 1; i >= 0; i-- {
+		op := j.ops[i]
+		logrus.Debugf("rollback: %s", op.name)
+		if err := op.undo(); err != nil {
+			errs = append(errs, fmt.Errorf("rollback: unable to %s: %w", op.name, err))
+		}
+	}
+	j.ops = nil
+	return errors.Join(errs...)
+}
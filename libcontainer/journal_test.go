@@ -0,0 +1,44 @@
+package libcontainer
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestJournalRollback(t *testing.T) {
+	var (
+		j    journal
+		done []string
+	)
+	errUndo := errors.New("undo failed")
+	for _, name := range []string{"first", "second", "third"} {
+		name := name
+		j.record(name, func() error {
+			done = append(done, name)
+			if name == "second" {
+				return errUndo
+			}
+			return nil
+		})
+	}
+
+	err := j.rollback()
+	// All the operations are undone, in the reverse order,
+	// even though one of them fails.
+	if exp := []string{"third", "second", "first"}; !reflect.DeepEqual(done, exp) {
+		t.Errorf("expected %v to be undone, got %v", exp, done)
+	}
+	if !errors.Is(err, errUndo) {
+		t.Errorf("expected %v, got %v", errUndo, err)
+	}
+
+	// The journal is empty after the rollback.
+	done = nil
+	if err := j.rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if len(done) != 0 {
+		t.Errorf("expected nothing to be undone, got %v", done)
+	}
+}
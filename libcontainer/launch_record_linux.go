@@ -0,0 +1,158 @@
+package libcontainer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/szcdx/runc/libcontainer/configs"
+)
+
+// launchRecordVersion is the version of the LaunchRecord format.
+const launchRecordVersion = 1
+
+// LaunchRecord is the measurement of what a container was launched with
+// (see configs.Config.LaunchRecord), made when the container is created.
+// The digests are in the "sha256:<hex>" form. The record can be signed, for
+// example by signing its Digest, to attest what the container was launched
+// with.
+type LaunchRecord struct {
+	// Version is the version of the record format.
+	Version int `json:"version"`
+	// ID is the container id.
+	ID string `json:"id"`
+	// Created is when the record was made.
+	Created time.Time `json:"created"`
+	// Config is the digest of the container configuration, in its JSON
+	// encoding.
+	Config string `json:"config"`
+	// Rootfs is the digest of the manifest of the container root
+	// filesystem (see writeRootfsManifest), if it was measured (see
+	// configs.Config.LaunchRecordRootfs).
+	Rootfs string `json:"rootfs,omitempty"`
+	// Seccomp is the digest of the seccomp profile, in its JSON encoding,
+	// if any.
+	Seccomp string `json:"seccomp,omitempty"`
+	// AppArmorProfile, ProcessLabel and MountLabel are the LSM labels
+	// applied to the container.
+	AppArmorProfile string `json:"apparmor_profile,omitempty"`
+	ProcessLabel    string `json:"process_label,omitempty"`
+	MountLabel      string `json:"mount_label,omitempty"`
+	// Digest is the digest of the JSON encoding of the record, with
+	// Digest being empty.
+	Digest string `json:"digest"`
+}
+
+// digest returns the digest of the data written to h.
+func digest(h hash.Hash) string {
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))
+}
+
+// jsonDigest returns the digest of the JSON encoding of v.
+func jsonDigest(v interface{}) (string, error) {
+	h := sha256.New()
+	if err := json.NewEncoder(h).Encode(v); err != nil {
+		return "", err
+	}
+	return digest(h), nil
+}
+
+// newLaunchRecord measures the container with the given id and config.
+func newLaunchRecord(id string, config *configs.Config) (*LaunchRecord, error) {
+	r := &LaunchRecord{
+		Version:         launchRecordVersion,
+		ID:              id,
+		Created:         time.Now().UTC(),
+		AppArmorProfile: config.AppArmorProfile,
+		ProcessLabel:    config.ProcessLabel,
+		MountLabel:      config.MountLabel,
+	}
+	var err error
+	if r.Config, err = jsonDigest(config); err != nil {
+		return nil, err
+	}
+	if config.Seccomp != nil {
+		if r.Seccomp, err = jsonDigest(config.Seccomp); err != nil {
+			return nil, err
+		}
+	}
+	if config.LaunchRecordRootfs {
+		h := sha256.New()
+		if err := writeRootfsManifest(h, config.Rootfs); err != nil {
+			return nil, fmt.Errorf("unable to measure the rootfs: %w", err)
+		}
+		r.Rootfs = digest(h)
+	}
+	if r.Digest, err = jsonDigest(r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// writeRootfsManifest writes the manifest of the root filesystem to w: a
+// line for each file, in lexical order, with its path (relative to the
+// root), mode, owner, and either the digest of its contents (for regular
+// files), its target (for symlinks), or its device number (for devices).
+// The file systems mounted under the root are not included.
+func writeRootfsManifest(w io.Writer, root string) error {
+	var rootSt unix.Stat_t
+	if err := unix.Lstat(root, &rootSt); err != nil {
+		return &os.PathError{Op: "lstat", Path: root, Err: err}
+	}
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		var st unix.Stat_t
+		if err := unix.Lstat(path, &st); err != nil {
+			return &os.PathError{Op: "lstat", Path: path, Err: err}
+		}
+		if st.Dev != rootSt.Dev {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		var data string
+		switch st.Mode & unix.S_IFMT {
+		case unix.S_IFREG:
+			data, err = fileDigest(path)
+		case unix.S_IFLNK:
+			data, err = os.Readlink(path)
+		case unix.S_IFCHR, unix.S_IFBLK:
+			data = fmt.Sprintf("%d:%d", unix.Major(st.Rdev), unix.Minor(st.Rdev))
+		}
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%q %o %d:%d %s\n", rel, st.Mode, st.Uid, st.Gid, data)
+		return err
+	})
+}
+
+// fileDigest returns the digest of the contents of the file at path.
+func fileDigest(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return digest(h), nil
+}
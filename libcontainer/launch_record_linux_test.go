@@ -0,0 +1,101 @@
+package libcontainer
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/szcdx/runc/libcontainer/configs"
+)
+
+func TestWriteRootfsManifest(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "etc"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "etc", "hostname"), []byte("kitten\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("etc/hostname", filepath.Join(root, "name")); err != nil {
+		t.Fatal(err)
+	}
+	manifest := func() string {
+		t.Helper()
+		var b bytes.Buffer
+		if err := writeRootfsManifest(&b, root); err != nil {
+			t.Fatal(err)
+		}
+		return b.String()
+	}
+
+	m := manifest()
+	lines := strings.Split(strings.TrimSuffix(m, "\n"), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected 4 lines, got %q", m)
+	}
+	for i, prefix := range []string{`"." 40`, `"etc" 40`, `"etc/hostname" 100644`, `"name" 120777`} {
+		if !strings.HasPrefix(lines[i], prefix) {
+			t.Errorf("expected line %d to start with %s, got %s", i, prefix, lines[i])
+		}
+	}
+	if !strings.HasSuffix(lines[3], " etc/hostname") {
+		t.Errorf("expected the symlink target, got %s", lines[3])
+	}
+	if m != manifest() {
+		t.Error("the manifest is not the same for the same rootfs")
+	}
+
+	if err := os.WriteFile(filepath.Join(root, "etc", "hostname"), []byte("puppy\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if m == manifest() {
+		t.Error("the manifest is the same once a file is changed")
+	}
+}
+
+func TestNewLaunchRecord(t *testing.T) {
+	config := &configs.Config{
+		Rootfs:          t.TempDir(),
+		Seccomp:         &configs.Seccomp{DefaultAction: configs.Allow},
+		AppArmorProfile: "runc-default",
+	}
+	r, err := newLaunchRecord("test", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.ID != "test" || r.AppArmorProfile != "runc-default" || r.Seccomp == "" || r.Config == "" {
+		t.Fatalf("unexpected record %+v", r)
+	}
+	// The rootfs is only measured on request.
+	if r.Rootfs != "" {
+		t.Fatalf("expected no rootfs digest, got %s", r.Rootfs)
+	}
+	config.LaunchRecordRootfs = true
+	if r, err = newLaunchRecord("test", config); err != nil {
+		t.Fatal(err)
+	}
+	if r.Rootfs == "" {
+		t.Fatal("expected a rootfs digest")
+	}
+	// The digest can be checked from the record itself.
+	unsigned := *r
+	unsigned.Digest = ""
+	d, err := jsonDigest(&unsigned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d != r.Digest {
+		t.Errorf("expected digest %s, got %s", d, r.Digest)
+	}
+
+	config.AppArmorProfile = "unconfined"
+	r2, err := newLaunchRecord("test", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r2.Config == r.Config {
+		t.Error("the config digest is the same once the config is changed")
+	}
+}
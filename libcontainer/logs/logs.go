@@ -2,8 +2,11 @@ package logs
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
 	"io"
+	"os"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
@@ -21,10 +24,11 @@ func ForwardLogs(logPipe io.ReadCloser) chan error {
 		logNoCaller.ReportCaller = false
 		logger = &logNoCaller
 	}
+	passthrough := canPassthrough(logger)
 
 	go func() {
 		for s.Scan() {
-			processEntry(s.Bytes(), logger)
+			processEntry(s.Bytes(), logger, passthrough)
 		}
 		if err := logPipe.Close(); err != nil {
 			logrus.Errorf("error closing log source: %v", err)
@@ -38,7 +42,26 @@ func ForwardLogs(logPipe io.ReadCloser) chan error {
 	return done
 }
 
-func processEntry(text []byte, logger *logrus.Logger) {
+// canPassthrough returns whether the entries logged by runc init (using the
+// default JSON formatter) can be written to the logger output as they are,
+// since the logger would produce the very same lines out of them. The output
+// has to be a file: the logger lock is not exported, but the writes to an
+// os.File are serialized, and each line is written with a single write, so
+// the lines can not get mixed with the ones logged by runc itself.
+func canPassthrough(logger *logrus.Logger) bool {
+	if len(logger.Hooks) != 0 {
+		return false
+	}
+	if _, ok := logger.Out.(*os.File); !ok {
+		return false
+	}
+	f, ok := logger.Formatter.(*logrus.JSONFormatter)
+	return ok && f.TimestampFormat == "" && !f.DisableTimestamp &&
+		!f.DisableHTMLEscape && f.DataKey == "" && f.FieldMap == nil &&
+		f.CallerPrettyfier == nil && !f.PrettyPrint
+}
+
+func processEntry(text []byte, logger *logrus.Logger, passthrough bool) {
 	if len(text) == 0 {
 		return
 	}
@@ -46,11 +69,31 @@ func processEntry(text []byte, logger *logrus.Logger) {
 	var jl struct {
 		Level logrus.Level `json:"level"`
 		Msg   string       `json:"msg"`
+		Time  string       `json:"time"`
 	}
 	if err := json.Unmarshal(text, &jl); err != nil {
 		logrus.Errorf("failed to decode %q to json: %v", text, err)
 		return
 	}
 
-	logger.Log(jl.Level, jl.Msg)
+	// The entries with no timestamp (such as the ones from nsenter) still
+	// have to get one from the logger. Panic entries are logged so that the
+	// logger panics, as it would for its own.
+	if passthrough && jl.Time != "" && jl.Level != logrus.PanicLevel {
+		if logger.IsLevelEnabled(jl.Level) {
+			line := make([]byte, 0, len(text)+1)
+			line = append(append(line, bytes.TrimSpace(text)...), '\n')
+			_, _ = logger.Out.Write(line)
+		}
+		return
+	}
+
+	entry := logrus.NewEntry(logger)
+	if jl.Time != "" {
+		if t, err := time.Parse(time.RFC3339, jl.Time); err == nil {
+			// Keep the time the entry was logged at by runc init.
+			entry = entry.WithTime(t)
+		}
+	}
+	entry.Log(jl.Level, jl.Msg)
 }
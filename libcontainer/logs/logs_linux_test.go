@@ -67,6 +67,87 @@ func TestLogForwardingStopsAfterClosingTheWriter(t *testing.T) {
 	check(t, l, msg, msgErr)
 }
 
+func TestLogForwardingKeepsTime(t *testing.T) {
+	l := runLogForwarding(t)
+
+	msg := `"level":"info","msg":"kitten","time":"2006-01-02T15:04:05Z"`
+	logToLogWriter(t, l, msg)
+	finish(t, l)
+	check(t, l, msg, msgErr)
+}
+
+func TestLogForwardingReformatsEntries(t *testing.T) {
+	l := runLogForwardingWithFormatter(t, &logrus.TextFormatter{DisableColors: true})
+
+	logToLogWriter(t, l, `"level":"warning","msg":"kitten","time":"2006-01-02T15:04:05Z"`)
+	finish(t, l)
+	check(t, l, `time="2006-01-02T15:04:05Z" level=warning msg=kitten`, msgErr)
+}
+
+func TestLogForwardingAddsMissingTime(t *testing.T) {
+	l := runLogForwarding(t)
+
+	logToLogWriter(t, l, `"level":"info", "msg":"nsenter[1]: kitten"`)
+	finish(t, l)
+	check(t, l, `"time":"`, msgErr)
+}
+
+func TestLogForwardingFiltersLevel(t *testing.T) {
+	l := runLogForwarding(t)
+	level := logrus.GetLevel()
+	logrus.SetLevel(logrus.InfoLevel)
+	t.Cleanup(func() { logrus.SetLevel(level) })
+
+	logToLogWriter(t, l, `"level":"debug","msg":"kitten","time":"2006-01-02T15:04:05Z"`)
+	logToLogWriter(t, l, `"level":"info","msg":"puppy","time":"2006-01-02T15:04:05Z"`)
+	finish(t, l)
+	check(t, l, "puppy", "kitten")
+}
+
+func TestCanPassthrough(t *testing.T) {
+	logger := logrus.New()
+	logger.SetFormatter(new(logrus.JSONFormatter))
+	if !canPassthrough(logger) {
+		t.Error("expected passthrough to stderr")
+	}
+	// Writes to other writers may not be serialized.
+	logger.SetOutput(new(bytes.Buffer))
+	if canPassthrough(logger) {
+		t.Error("expected no passthrough to a buffer")
+	}
+	logger.SetOutput(os.Stderr)
+	logger.SetFormatter(&logrus.TextFormatter{})
+	if canPassthrough(logger) {
+		t.Error("expected no passthrough with the text formatter")
+	}
+}
+
+func BenchmarkForwardLogs(b *testing.B) {
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer devNull.Close()
+	logger := logrus.New()
+	logger.SetOutput(devNull)
+	logger.SetFormatter(new(logrus.JSONFormatter))
+	line := []byte(`{"level":"info","msg":"nsexec-1[42]: ~> nsexec-1","time":"2006-01-02T15:04:05Z"}`)
+	for _, tc := range []struct {
+		name        string
+		passthrough bool
+	}{
+		{"relog", false},
+		{"passthrough", true},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				processEntry(line, logger, tc.passthrough)
+			}
+		})
+	}
+}
+
 func logToLogWriter(t *testing.T, l *log, message string) {
 	t.Helper()
 	_, err := l.w.Write([]byte("{" + message + "}\n"))
@@ -82,6 +163,11 @@ type log struct {
 }
 
 func runLogForwarding(t *testing.T) *log {
+	t.Helper()
+	return runLogForwardingWithFormatter(t, &logrus.JSONFormatter{})
+}
+
+func runLogForwardingWithFormatter(t *testing.T, formatter logrus.Formatter) *log {
 	t.Helper()
 	logR, logW, err := os.Pipe()
 	if err != nil {
@@ -102,7 +188,7 @@ func runLogForwarding(t *testing.T) *log {
 	})
 
 	logrus.SetOutput(tempFile)
-	logrus.SetFormatter(&logrus.JSONFormatter{})
+	logrus.SetFormatter(formatter)
 	doneForwarding := ForwardLogs(logR)
 
 	return &log{w: logW, done: doneForwarding, file: tempFile}
@@ -0,0 +1,109 @@
+package libcontainer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/szcdx/runc/libcontainer/configs"
+	"github.com/szcdx/runc/libcontainer/utils"
+)
+
+// mountDriverTimeout is how long a mount driver is given to create a mount.
+const mountDriverTimeout = time.Minute
+
+// mountDriverRequest is what a mount driver is given on its stdin.
+type mountDriverRequest struct {
+	ID          string   `json:"id"`
+	Type        string   `json:"type"`
+	Source      string   `json:"source"`
+	Destination string   `json:"destination"`
+	Options     []string `json:"options,omitempty"`
+}
+
+// runMountDriver runs the mount driver of m (see docs/mount-drivers.md),
+// which creates the mount, detached, and sends it back as an fsmount(2) or
+// open_tree(2) file descriptor, for runc init to attach it to the rootfs.
+//
+// The mount flags of m are then applied to the mount, with mount_setattr(2).
+func runMountDriver(id string, m *configs.Mount) (_ *mountSource, retErr error) {
+	req, err := json.Marshal(mountDriverRequest{
+		ID:          id,
+		Type:        m.Device,
+		Source:      m.Source,
+		Destination: m.Destination,
+		Options:     splitMountData(m.Data),
+	})
+	if err != nil {
+		return nil, err
+	}
+	parent, child, err := utils.NewSockPair("mount-driver")
+	if err != nil {
+		return nil, err
+	}
+	defer parent.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), mountDriverTimeout)
+	defer cancel()
+	var out bytes.Buffer
+	cmd := exec.CommandContext(ctx, m.Driver, "mount")
+	cmd.Stdin = bytes.NewReader(req)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	cmd.ExtraFiles = []*os.File{child}
+	err = cmd.Start()
+	child.Close()
+	if err != nil {
+		return nil, fmt.Errorf("mount driver %s: %w", m.Driver, err)
+	}
+	// If the driver exits without sending anything, this fails as the
+	// other end of the socket is closed.
+	f, recvErr := utils.RecvFile(parent)
+	if err := cmd.Wait(); err != nil {
+		if f != nil {
+			f.Close()
+		}
+		return nil, fmt.Errorf("mount driver %s: %w: %s", m.Driver, err, strings.TrimSpace(out.String()))
+	}
+	if recvErr != nil {
+		return nil, fmt.Errorf("mount driver %s: no mount received: %w", m.Driver, recvErr)
+	}
+	// Name the file after the mount source, as runc init expects.
+	fd, err := unix.FcntlInt(f.Fd(), unix.F_DUPFD_CLOEXEC, 0)
+	f.Close()
+	if err != nil {
+		return nil, os.NewSyscallError("fcntl(F_DUPFD_CLOEXEC)", err)
+	}
+	mountFile := os.NewFile(uintptr(fd), m.Source)
+	defer func() {
+		if retErr != nil {
+			mountFile.Close()
+		}
+	}()
+
+	attr := &unix.MountAttr{}
+	for flag, a := range fsMountAttrs {
+		if uintptr(m.Flags)&flag != 0 {
+			attr.Attr_set |= uint64(a)
+		}
+	}
+	if attr.Attr_set&unix.MOUNT_ATTR__ATIME != 0 {
+		attr.Attr_clr |= unix.MOUNT_ATTR__ATIME
+	}
+	if attr.Attr_set != 0 {
+		if err := unix.MountSetattr(fd, "", unix.AT_EMPTY_PATH, attr); err != nil {
+			return nil, fmt.Errorf("mount driver %s: setting mount flags: %w", m.Driver, os.NewSyscallError("mount_setattr", err))
+		}
+	}
+	return &mountSource{
+		Type: mountSourceOpenTree,
+		file: mountFile,
+	}, nil
+}
@@ -6,6 +6,7 @@ import (
 	"io/fs"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 	"golang.org/x/sys/unix"
@@ -43,6 +44,9 @@ type mountError struct {
 	flags   uintptr
 	data    string
 	err     error
+	// fsLog are the messages logged by the filesystem while it was set
+	// up with the new mount API (see fsMount), if it was.
+	fsLog []string
 }
 
 // Error provides a string error representation.
@@ -69,6 +73,9 @@ func (e *mountError) Error() string {
 	}
 
 	out += ": " + e.err.Error()
+	if len(e.fsLog) > 0 {
+		out += " (" + strings.Join(e.fsLog, "; ") + ")"
+	}
 	return out
 }
 
@@ -123,14 +130,33 @@ func mountViaFds(source string, srcFile *mountSource, target, dstFd, fstype stri
 		}
 	}
 
-	var op string
-	var err error
+	var (
+		op    string
+		err   error
+		fsLog []string
+	)
 	if isMoveMount {
 		op = "move_mount"
 		err = unix.MoveMount(int(srcFile.file.Fd()), "",
 			unix.AT_FDCWD, dstFd,
 			unix.MOVE_MOUNT_F_EMPTY_PATH|unix.MOVE_MOUNT_T_SYMLINKS)
 	} else {
+		if useFsMount(srcFile, fstype, flags) {
+			fsLog, err = fsMount(source, dst, fstype, flags, data)
+			if err == nil {
+				return nil
+			}
+			if !fsMountUnsupported(err) {
+				// It would fail the same way with mount(2), and the
+				// messages of the filesystem explain why.
+				var mntErr *mountError
+				if errors.As(err, &mntErr) {
+					mntErr.target, mntErr.dstFd, mntErr.fsLog = target, dstFd, fsLog
+				}
+				return err
+			}
+			logrus.Debugf("new mount api: %v, falling back to mount(2)", err)
+		}
 		op = "mount"
 		err = unix.Mount(src, dst, fstype, flags, data)
 	}
@@ -144,11 +170,47 @@ func mountViaFds(source string, srcFile *mountSource, target, dstFd, fstype stri
 			flags:   flags,
 			data:    data,
 			err:     err,
+			fsLog:   fsLog,
 		}
 	}
 	return nil
 }
 
+// passedMountFd returns the mount source for a mount with a fd://N source,
+// N being the index of the file in files (see Process.MountFiles).
+//
+// Unless the container is rootless, the file is cloned with
+// open_tree(OPEN_TREE_CLONE), so that a file or directory which is not a
+// mount can be bind mounted as well. Otherwise, or if that isn't possible
+// because the file is a detached mount already, a copy of the file is used
+// as is, which then has to be a detached mount.
+func passedMountFd(files []*os.File, m *configs.Mount, rootless bool) (*mountSource, error) {
+	n, err := m.SourceFd()
+	if err != nil {
+		return nil, err
+	}
+	if n >= len(files) {
+		return nil, fmt.Errorf("mount source %s: only %d files passed", m.Source, len(files))
+	}
+	f := files[n]
+	if !rootless {
+		flags := uint(unix.OPEN_TREE_CLONE | unix.OPEN_TREE_CLOEXEC | unix.AT_EMPTY_PATH)
+		if m.Flags&unix.MS_REC == unix.MS_REC {
+			flags |= unix.AT_RECURSIVE
+		}
+		fd, err := unix.OpenTree(int(f.Fd()), "", flags)
+		if err == nil {
+			return &mountSource{Type: mountSourceOpenTree, file: os.NewFile(uintptr(fd), m.Source)}, nil
+		}
+		logrus.Debugf("mount source %s: %v, using it as is", m.Source, os.NewSyscallError("open_tree", err))
+	}
+	fd, err := unix.FcntlInt(f.Fd(), unix.F_DUPFD_CLOEXEC, 0)
+	if err != nil {
+		return nil, os.NewSyscallError("fcntl(F_DUPFD_CLOEXEC)", err)
+	}
+	return &mountSource{Type: mountSourceOpenTree, file: os.NewFile(uintptr(fd), m.Source)}, nil
+}
+
 // unmount is a simple unix.Unmount wrapper.
 func unmount(target string, flags int) error {
 	err := unix.Unmount(target, flags)
@@ -223,7 +285,11 @@ func mountFd(nsHandles *userns.Handles, m *configs.Mount) (*mountSource, error)
 		}
 		fd, err := unix.OpenTree(unix.AT_FDCWD, m.Source, flags)
 		if err != nil {
-			return nil, &os.PathError{Op: "open_tree(OPEN_TREE_CLONE)", Path: m.Source, Err: err}
+			err = &os.PathError{Op: "open_tree(OPEN_TREE_CLONE)", Path: m.Source, Err: err}
+			if errors.Is(err, unix.ENOSYS) {
+				return nil, fmt.Errorf("id-mapped mounts are not supported by the kernel: %w", err)
+			}
+			return nil, err
 		}
 		mountFile = os.NewFile(uintptr(fd), m.Source)
 		sourceType = mountSourceOpenTree
@@ -259,8 +325,11 @@ func mountFd(nsHandles *userns.Handles, m *configs.Mount) (*mountSource, error)
 			Userns_fd: uint64(usernsFile.Fd()),
 		}); err != nil {
 			extraMsg := ""
-			if err == unix.EINVAL {
+			switch err { //nolint:errorlint // unix errors are bare
+			case unix.EINVAL:
 				extraMsg = " (maybe the filesystem used doesn't support idmap mounts on this kernel?)"
+			case unix.ENOSYS:
+				extraMsg = " (id-mapped mounts are not supported by the kernel)"
 			}
 
 			return nil, fmt.Errorf("failed to set MOUNT_ATTR_IDMAP on %s: %w%s", m.Source, err, extraMsg)
@@ -0,0 +1,42 @@
+package libcontainer
+
+import (
+	"os"
+	"testing"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/szcdx/runc/libcontainer/configs"
+)
+
+func TestPassedMountFd(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("requires root")
+	}
+	dir, err := os.OpenFile(t.TempDir(), unix.O_PATH|unix.O_CLOEXEC, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dir.Close()
+	files := []*os.File{dir}
+
+	m := &configs.Mount{Source: "fd://0", Device: "bind", Flags: unix.MS_BIND}
+	for _, rootless := range []bool{false, true} {
+		src, err := passedMountFd(files, m, rootless)
+		if err != nil {
+			t.Fatalf("rootless=%v: %v", rootless, err)
+		}
+		if src.Type != mountSourceOpenTree || src.file.Name() != m.Source {
+			t.Errorf("rootless=%v: unexpected mount source %+v (%s)", rootless, src, src.file.Name())
+		}
+		if src.file.Fd() == dir.Fd() {
+			t.Errorf("rootless=%v: the passed file is used rather than a copy", rootless)
+		}
+		src.file.Close()
+	}
+
+	m.Source = "fd://1"
+	if _, err := passedMountFd(files, m, false); err == nil {
+		t.Error("expected error for a missing file, got nil")
+	}
+}
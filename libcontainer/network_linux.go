@@ -2,7 +2,11 @@ package libcontainer
 
 import (
 	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -10,10 +14,13 @@ import (
 	"github.com/szcdx/runc/libcontainer/configs"
 	"github.com/szcdx/runc/types"
 	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
 )
 
 var strategies = map[string]networkStrategy{
 	"loopback": &loopback{},
+	"veth":     &veth{},
+	"macvlan":  &macvlan{},
 }
 
 // networkStrategy represents a specific network configuration for
@@ -71,6 +78,60 @@ func getNetworkInterfaceStats(interfaceName string) (*types.NetworkInterface, er
 	return out, nil
 }
 
+// netnsInterfaceStats returns the statistics of the network interfaces in
+// the network namespace of the container init.
+func (c *Container) netnsInterfaceStats() ([]*types.NetworkInterface, error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	if c.initProcess == nil {
+		return nil, ErrNotRunning
+	}
+	ns, err := netns.GetFromPath("/proc/" + strconv.Itoa(c.initProcess.pid()) + "/ns/net")
+	if err != nil {
+		return nil, err
+	}
+	defer ns.Close()
+	// The PID may have been reused before the namespace was opened, the
+	// init process being gone.
+	if !c.hasInit() {
+		return nil, ErrNotRunning
+	}
+	return getNetnsInterfaceStats(ns)
+}
+
+// getNetnsInterfaceStats returns the statistics of all the network
+// interfaces in the network namespace ns, as seen from inside the
+// namespace (so, unlike the ones of the host end of a veth pair, the received
+// bytes are the ones received by the container).
+func getNetnsInterfaceStats(ns netns.NsHandle) ([]*types.NetworkInterface, error) {
+	h, err := netlink.NewHandleAt(ns)
+	if err != nil {
+		return nil, err
+	}
+	defer h.Delete()
+	links, err := h.LinkList()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*types.NetworkInterface, 0, len(links))
+	for _, link := range links {
+		attrs := link.Attrs()
+		iface := &types.NetworkInterface{Name: attrs.Name}
+		if s := attrs.Statistics; s != nil {
+			iface.RxBytes = s.RxBytes
+			iface.RxPackets = s.RxPackets
+			iface.RxErrors = s.RxErrors
+			iface.RxDropped = s.RxDropped
+			iface.TxBytes = s.TxBytes
+			iface.TxPackets = s.TxPackets
+			iface.TxErrors = s.TxErrors
+			iface.TxDropped = s.TxDropped
+		}
+		out = append(out, iface)
+	}
+	return out, nil
+}
+
 // Reads the specified statistics available under /sys/class/net/<EthInterface>/statistics
 func readSysfsNetworkStats(ethInterface, statsFile string) (uint64, error) {
 	data, err := os.ReadFile(filepath.Join("/sys/class/net", ethInterface, "statistics", statsFile))
@@ -98,3 +159,225 @@ func (l *loopback) attach(n *configs.Network) (err error) {
 func (l *loopback) detach(n *configs.Network) (err error) {
 	return nil
 }
+
+// veth is a network strategy that uses a bridge and creates a veth pair, one
+// end of which is attached to the bridge on the host and the other is placed
+// inside the container's namespace.
+type veth struct{}
+
+// detach the host end of the veth pair from the bridge.
+func (v *veth) detach(n *configs.Network) error {
+	host, err := netlink.LinkByName(n.HostInterfaceName)
+	if err != nil {
+		return err
+	}
+	return netlink.LinkSetNoMaster(host)
+}
+
+// attach the host end of the veth pair to the bridge.
+func (v *veth) attach(n *configs.Network) error {
+	brl, err := netlink.LinkByName(n.Bridge)
+	if err != nil {
+		return err
+	}
+	br, ok := brl.(*netlink.Bridge)
+	if !ok {
+		return fmt.Errorf("%s is not a bridge (%s)", n.Bridge, brl.Type())
+	}
+	host, err := netlink.LinkByName(n.HostInterfaceName)
+	if err != nil {
+		return err
+	}
+	if err := netlink.LinkSetMaster(host, br); err != nil {
+		return err
+	}
+	if n.Mtu != 0 {
+		if err := netlink.LinkSetMTU(host, n.Mtu); err != nil {
+			return err
+		}
+	}
+	if n.HairpinMode {
+		if err := netlink.LinkSetHairpin(host, true); err != nil {
+			return err
+		}
+	}
+	return netlink.LinkSetUp(host)
+}
+
+func (v *veth) create(n *network, nspid int) (err error) {
+	if n.Bridge == "" {
+		return errors.New("veth network: bridge is not specified")
+	}
+	n.TempVethPeerName, err = tempInterfaceName("veth")
+	if err != nil {
+		return err
+	}
+	veth := &netlink.Veth{
+		LinkAttrs: netlink.LinkAttrs{
+			Name:   n.HostInterfaceName,
+			TxQLen: n.TxQueueLen,
+		},
+		PeerName: n.TempVethPeerName,
+	}
+	if err := netlink.LinkAdd(veth); err != nil {
+		return fmt.Errorf("veth network: unable to create %s: %w", n.HostInterfaceName, err)
+	}
+	defer func() {
+		if err != nil {
+			_ = netlink.LinkDel(veth)
+		}
+	}()
+	if err := v.attach(&n.Network); err != nil {
+		return err
+	}
+	child, err := netlink.LinkByName(n.TempVethPeerName)
+	if err != nil {
+		return err
+	}
+	return netlink.LinkSetNsPid(child, nspid)
+}
+
+func (v *veth) initialize(n *network) error {
+	return setupInterface(n)
+}
+
+// macvlan is a network strategy that creates a macvlan interface on top of
+// an interface of the host, and places it inside the container's namespace.
+type macvlan struct{}
+
+var macvlanModes = map[string]netlink.MacvlanMode{
+	"":         netlink.MACVLAN_MODE_BRIDGE,
+	"bridge":   netlink.MACVLAN_MODE_BRIDGE,
+	"private":  netlink.MACVLAN_MODE_PRIVATE,
+	"vepa":     netlink.MACVLAN_MODE_VEPA,
+	"passthru": netlink.MACVLAN_MODE_PASSTHRU,
+}
+
+func (m *macvlan) create(n *network, nspid int) (err error) {
+	if n.Parent == "" {
+		return errors.New("macvlan network: parent is not specified")
+	}
+	mode, ok := macvlanModes[n.MacvlanMode]
+	if !ok {
+		return fmt.Errorf("macvlan network: invalid mode %q", n.MacvlanMode)
+	}
+	parent, err := netlink.LinkByName(n.Parent)
+	if err != nil {
+		return err
+	}
+	n.TempVethPeerName, err = tempInterfaceName("mvl")
+	if err != nil {
+		return err
+	}
+	link := &netlink.Macvlan{
+		LinkAttrs: netlink.LinkAttrs{
+			Name:        n.TempVethPeerName,
+			ParentIndex: parent.Attrs().Index,
+			TxQLen:      n.TxQueueLen,
+		},
+		Mode: mode,
+	}
+	if err := netlink.LinkAdd(link); err != nil {
+		return fmt.Errorf("macvlan network: unable to create an interface on %s: %w", n.Parent, err)
+	}
+	defer func() {
+		if err != nil {
+			_ = netlink.LinkDel(link)
+		}
+	}()
+	return netlink.LinkSetNsPid(link, nspid)
+}
+
+func (m *macvlan) initialize(n *network) error {
+	return setupInterface(n)
+}
+
+// The macvlan interface has no host end to detach, the network stays locked
+// by CRIU itself.
+func (m *macvlan) attach(n *configs.Network) error {
+	return nil
+}
+
+func (m *macvlan) detach(n *configs.Network) error {
+	return nil
+}
+
+// tempInterfaceName returns a random interface name with the given prefix,
+// to create an interface on the host before it is moved into the container's
+// namespace and renamed there.
+func tempInterfaceName(prefix string) (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return prefix + hex.EncodeToString(b), nil
+}
+
+// setupInterface renames the interface moved into the container's namespace
+// to its final name, and sets its MAC address, addresses, MTU and default
+// gateways. It is run in the container's network namespace.
+func setupInterface(n *network) error {
+	if n.TempVethPeerName == "" {
+		return errors.New("interface moved into the container is not specified")
+	}
+	child, err := netlink.LinkByName(n.TempVethPeerName)
+	if err != nil {
+		return err
+	}
+	if err := netlink.LinkSetDown(child); err != nil {
+		return err
+	}
+	if err := netlink.LinkSetName(child, n.Name); err != nil {
+		return err
+	}
+	// Get the interface again after the rename.
+	if child, err = netlink.LinkByName(n.Name); err != nil {
+		return err
+	}
+	if n.MacAddress != "" {
+		mac, err := net.ParseMAC(n.MacAddress)
+		if err != nil {
+			return err
+		}
+		if err := netlink.LinkSetHardwareAddr(child, mac); err != nil {
+			return err
+		}
+	}
+	for _, addr := range []string{n.Address, n.IPv6Address} {
+		if addr == "" {
+			continue
+		}
+		ip, err := netlink.ParseAddr(addr)
+		if err != nil {
+			return err
+		}
+		if err := netlink.AddrAdd(child, ip); err != nil {
+			return fmt.Errorf("unable to add address %s to %s: %w", addr, n.Name, err)
+		}
+	}
+	if n.Mtu != 0 {
+		if err := netlink.LinkSetMTU(child, n.Mtu); err != nil {
+			return err
+		}
+	}
+	if err := netlink.LinkSetUp(child); err != nil {
+		return err
+	}
+	for _, gateway := range []string{n.Gateway, n.IPv6Gateway} {
+		if gateway == "" {
+			continue
+		}
+		gw := net.ParseIP(gateway)
+		if gw == nil {
+			return fmt.Errorf("invalid gateway %q", gateway)
+		}
+		if err := netlink.RouteAdd(&netlink.Route{
+			Scope:     netlink.SCOPE_UNIVERSE,
+			LinkIndex: child.Attrs().Index,
+			Gw:        gw,
+		}); err != nil {
+			return fmt.Errorf("unable to add the default route via %s: %w", gateway, err)
+		}
+	}
+	return nil
+}
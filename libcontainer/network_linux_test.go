@@ -0,0 +1,25 @@
+package libcontainer
+
+import (
+	"testing"
+
+	"github.com/vishvananda/netns"
+)
+
+func TestGetNetnsInterfaceStats(t *testing.T) {
+	ns, err := netns.GetFromPath("/proc/self/ns/net")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ns.Close()
+	ifaces, err := getNetnsInterfaceStats(ns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, iface := range ifaces {
+		if iface.Name == "lo" {
+			return
+		}
+	}
+	t.Errorf("expected the loopback interface, got %+v", ifaces)
+}
@@ -5,8 +5,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"time"
 
 	"golang.org/x/sys/unix"
+
+	"github.com/szcdx/runc/libcontainer/cgroups/fscommon"
 )
 
 type PressureLevel uint
@@ -17,12 +21,18 @@ const (
 	CriticalPressure
 )
 
-func registerMemoryEvent(cgDir string, evName string, arg string) (<-chan struct{}, error) {
+// registerMemoryEvent returns a channel on which you can expect an event
+// when the kernel signals the evName event of the cgroup with arg. The
+// channel is closed once the cgroup is gone, or once done (if not nil) is
+// closed.
+func registerMemoryEvent(cgDir, evName, arg string, done <-chan struct{}) (<-chan struct{}, error) {
 	evFile, err := os.Open(filepath.Join(cgDir, evName))
 	if err != nil {
 		return nil, err
 	}
-	fd, err := unix.Eventfd(0, unix.EFD_CLOEXEC)
+	// Non-blocking, so that reading it can be interrupted once done is
+	// closed.
+	fd, err := unix.Eventfd(0, unix.EFD_CLOEXEC|unix.EFD_NONBLOCK)
 	if err != nil {
 		evFile.Close()
 		return nil, err
@@ -32,16 +42,31 @@ func registerMemoryEvent(cgDir string, evName string, arg string) (<-chan struct
 
 	eventControlPath := filepath.Join(cgDir, "cgroup.event_control")
 	data := fmt.Sprintf("%d %d %s", eventfd.Fd(), evFile.Fd(), arg)
-	if err := os.WriteFile(eventControlPath, []byte(data), 0o700); err != nil {
+	err = os.WriteFile(eventControlPath, []byte(data), 0o700)
+	// The kernel does not need the event file once the event is
+	// registered, only the eventfd.
+	evFile.Close()
+	if err != nil {
 		eventfd.Close()
-		evFile.Close()
 		return nil, err
 	}
+	trackFd(fd, "memory event", "", evFile.Name())
 	ch := make(chan struct{})
+	stop := make(chan struct{})
+	if done != nil {
+		go func() {
+			select {
+			case <-done:
+				// Makes the pending read, if any, return.
+				_ = eventfd.SetReadDeadline(time.Now())
+			case <-stop:
+			}
+		}()
+	}
 	go func() {
 		defer func() {
-			eventfd.Close()
-			evFile.Close()
+			close(stop)
+			closeTracked(eventfd)
 			close(ch)
 		}()
 		buf := make([]byte, 8)
@@ -54,7 +79,11 @@ func registerMemoryEvent(cgDir string, evName string, arg string) (<-chan struct
 			if _, err := os.Lstat(eventControlPath); os.IsNotExist(err) {
 				return
 			}
-			ch <- struct{}{}
+			select {
+			case ch <- struct{}{}:
+			case <-done:
+				return
+			}
 		}
 	}()
 	return ch, nil
@@ -67,10 +96,13 @@ func notifyOnOOM(dir string) (<-chan struct{}, error) {
 		return nil, errors.New("memory controller missing")
 	}
 
-	return registerMemoryEvent(dir, "memory.oom_control", "")
+	return registerMemoryEvent(dir, "memory.oom_control", "", nil)
 }
 
-func notifyMemoryPressure(dir string, level PressureLevel) (<-chan struct{}, error) {
+// notifyMemoryPressure returns a channel on which you can expect an event
+// when the cgroup reaches the given memory pressure level. The channel is
+// closed once the cgroup is gone, or once done is closed.
+func notifyMemoryPressure(dir string, level PressureLevel, done <-chan struct{}) (<-chan struct{}, error) {
 	if dir == "" {
 		return nil, errors.New("memory controller missing")
 	}
@@ -80,5 +112,83 @@ func notifyMemoryPressure(dir string, level PressureLevel) (<-chan struct{}, err
 	}
 
 	levelStr := []string{"low", "medium", "critical"}[level]
-	return registerMemoryEvent(dir, "memory.pressure_level", levelStr)
+	return registerMemoryEvent(dir, "memory.pressure_level", levelStr, done)
+}
+
+// notifyOnMemoryThreshold returns a channel on which you can expect an event
+// when memory usage rises above threshold (in bytes). The channel is closed
+// once the cgroup is gone, or once done is closed.
+func notifyOnMemoryThreshold(dir string, threshold uint64, done <-chan struct{}) (<-chan struct{}, error) {
+	if dir == "" {
+		return nil, errors.New("memory controller missing")
+	}
+
+	evCh, err := registerMemoryEvent(dir, "memory.usage_in_bytes", strconv.FormatUint(threshold, 10), done)
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan struct{})
+	go func() {
+		defer close(ch)
+		for range evCh {
+			// The kernel notifies about crossing the threshold in
+			// either direction, but we only care about going up.
+			usage, err := fscommon.GetCgroupParamUint(dir, "memory.usage_in_bytes")
+			if err != nil || usage >= threshold {
+				select {
+				case ch <- struct{}{}:
+				case <-done:
+					// Only close ch once the event is
+					// unregistered.
+					for range evCh {
+					}
+					return
+				}
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// pidsLimitPollInterval is how often pids.events is checked by
+// notifyOnPidsLimit.
+const pidsLimitPollInterval = time.Second
+
+// notifyOnPidsLimit returns a channel on which you can expect an event when
+// a fork or clone fails because of the pids limit. The channel is closed
+// once the cgroup is gone, or once done is closed.
+//
+// Unlike cgroup v2, cgroup v1 has no notifications for pids.events, so the
+// file is polled.
+func notifyOnPidsLimit(dir string, done <-chan struct{}) (<-chan struct{}, error) {
+	last, err := fscommon.GetValueByKey(dir, "pids.events", "max")
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan struct{})
+	go func() {
+		defer close(ch)
+		ticker := time.NewTicker(pidsLimitPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+			cur, err := fscommon.GetValueByKey(dir, "pids.events", "max")
+			if err != nil {
+				return
+			}
+			if cur > last {
+				last = cur
+				select {
+				case ch <- struct{}{}:
+				case <-done:
+					return
+				}
+			}
+		}
+	}()
+	return ch, nil
 }
@@ -2,12 +2,14 @@ package libcontainer
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/szcdx/runc/libcontainer/cgroups"
 	"golang.org/x/sys/unix"
 )
 
@@ -43,6 +45,10 @@ func testMemoryNotification(t *testing.T, evName string, notify notifyFunc, targ
 	if err != nil || arg != targ {
 		t.Fatalf("invalid control data %q: %s", data, err)
 	}
+	// The event file is not needed once the event is registered.
+	if _, _, err := unix.Syscall(unix.SYS_FCNTL, uintptr(evFd), unix.F_GETFD, 0); err != unix.EBADF {
+		t.Errorf("expected event control to be closed, but received error %s", err.Error())
+	}
 
 	// dup the eventfd
 	efd, err := unix.Dup(eventFd)
@@ -83,10 +89,6 @@ func testMemoryNotification(t *testing.T, evName string, notify notifyFunc, targ
 		t.Fatal("channel not closed after 100ms")
 	}
 
-	if _, _, err := unix.Syscall(unix.SYS_FCNTL, uintptr(evFd), unix.F_GETFD, 0); err != unix.EBADF {
-		t.Errorf("expected event control to be closed, but received error %s", err.Error())
-	}
-
 	if _, _, err := unix.Syscall(unix.SYS_FCNTL, uintptr(eventFd), unix.F_GETFD, 0); err != unix.EBADF {
 		t.Errorf("expected event fd to be closed, but received error %s", err.Error())
 	}
@@ -109,9 +111,368 @@ func TestNotifyMemoryPressure(t *testing.T) {
 
 	for level, arg := range tests {
 		f := func(path string) (<-chan struct{}, error) {
-			return notifyMemoryPressure(path, level)
+			return notifyMemoryPressure(path, level, nil)
 		}
 
 		testMemoryNotification(t, "memory.pressure_level", f, arg)
 	}
 }
+
+func TestNotifyOnMemoryThreshold(t *testing.T) {
+	f := func(path string) (<-chan struct{}, error) {
+		return notifyOnMemoryThreshold(path, 4096, nil)
+	}
+
+	testMemoryNotification(t, "memory.usage_in_bytes", f, "4096")
+}
+
+func TestNotifyOnMemoryThresholdDone(t *testing.T) {
+	memoryPath := t.TempDir()
+	for _, name := range []string{"memory.usage_in_bytes", "cgroup.event_control"} {
+		if err := os.WriteFile(filepath.Join(memoryPath, name), []byte{}, 0o700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	done := make(chan struct{})
+	ch, err := notifyOnMemoryThreshold(memoryPath, 4096, done)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(memoryPath, "cgroup.event_control"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var eventFd int
+	if _, err := fmt.Sscanf(string(data), "%d", &eventFd); err != nil {
+		t.Fatalf("invalid control data %q: %s", data, err)
+	}
+
+	// An event no one receives does not keep the notifier from stopping.
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, 1)
+	if _, err := unix.Write(eventFd, buf); err != nil {
+		t.Fatal("unable to write to eventfd:", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	close(done)
+	select {
+	case <-ch:
+		// The pending event may still be received first.
+		if _, ok := <-ch; ok {
+			t.Fatal("expected the channel to be closed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("channel is not closed")
+	}
+	if _, _, err := unix.Syscall(unix.SYS_FCNTL, uintptr(eventFd), unix.F_GETFD, 0); err != unix.EBADF {
+		t.Errorf("expected event fd to be closed, but received error %s", err.Error())
+	}
+}
+
+func TestNotifyOnMemoryThresholdV2(t *testing.T) {
+	// We're using a fake cgroupfs.
+	cgroups.TestMode = true
+	dir := t.TempDir()
+	// The files are rewritten in place (rather than truncated), so the
+	// notifier never sees them empty.
+	write := func(name, data string) {
+		t.Helper()
+		f, err := os.OpenFile(filepath.Join(dir, name), os.O_WRONLY|os.O_CREATE, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if _, err := f.WriteAt([]byte(data), 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("memory.current", "1024\n")
+	write("memory.events", "high 0\n")
+	write("cgroup.events", "populated 1\n")
+
+	done := make(chan struct{})
+	ch, err := notifyOnMemoryThresholdV2(dir, 4096, done)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Reaching memory.high reports the usage right away.
+	write("memory.current", "8192\n")
+	write("memory.events", "high 1\n")
+	select {
+	case <-ch:
+	case <-time.After(memoryThresholdPollInterval / 2):
+		t.Fatal("no memory threshold notification")
+	}
+	// Staying above the threshold is not reported again.
+	write("memory.events", "high 2\n")
+	select {
+	case <-ch:
+		t.Fatal("unexpected memory threshold notification")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// Going back above the threshold is, but an event no one receives
+	// does not keep the notifier from stopping.
+	write("memory.current", "1024\n")
+	write("memory.events", "high 2\n")
+	time.Sleep(100 * time.Millisecond)
+	write("memory.current", "8192\n")
+	write("memory.events", "high 3\n")
+	time.Sleep(100 * time.Millisecond)
+	close(done)
+	select {
+	case <-ch:
+		// The pending event may still be received first.
+		if _, ok := <-ch; ok {
+			t.Fatal("expected the channel to be closed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("channel is not closed")
+	}
+
+	// The cgroup getting empty closes the channel too.
+	ch, err = notifyOnMemoryThresholdV2(dir, 4096, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	write("cgroup.events", "populated 0\n")
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Fatal("expected the channel to be closed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("channel is not closed")
+	}
+}
+
+func TestNotifyOnOOMV2(t *testing.T) {
+	// We're using a fake cgroupfs.
+	cgroups.TestMode = true
+	dir := t.TempDir()
+	// The files are rewritten in place (rather than truncated), so the
+	// notifier never sees them empty.
+	write := func(name, data string) {
+		t.Helper()
+		f, err := os.OpenFile(filepath.Join(dir, name), os.O_WRONLY|os.O_CREATE, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if _, err := f.WriteAt([]byte(data), 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	const events = "low 0\nhigh %d\nmax 0\noom 0\noom_kill %d\n"
+	write("memory.events", fmt.Sprintf(events, 0, 1))
+	write("cgroup.events", "populated 1\n")
+
+	ch, err := notifyOnOOMV2(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Neither the OOM kill which happened before, nor other events are reported.
+	write("memory.events", fmt.Sprintf(events, 5, 1))
+	select {
+	case <-ch:
+		t.Fatal("unexpected OOM notification")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	write("memory.events", fmt.Sprintf(events, 5, 2))
+	select {
+	case <-ch:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no OOM notification")
+	}
+
+	write("cgroup.events", "populated 0\n")
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Fatal("expected the channel to be closed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("channel is not closed")
+	}
+}
+
+func TestInotifyWatcherFail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "memory.events")
+	if err := os.WriteFile(path, []byte("oom_kill 0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var w inotifyWatcher
+	sub, err := w.subscribe(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("oom_kill 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-sub.wake:
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscriber not woken up")
+	}
+
+	// Once the events can no longer be read, the subscribers are told so,
+	// and can still unsubscribe, and new subscriptions fail.
+	fd := w.fd
+	failErr := errors.New("test failure")
+	w.fail(failErr)
+	select {
+	case <-sub.failed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscriber not told about the failure")
+	}
+	sub.unsubscribe()
+	if _, err := w.subscribe(path); !errors.Is(err, failErr) {
+		t.Fatalf("expected %v, got %v", failErr, err)
+	}
+	for _, f := range TrackedFds() {
+		if f.Fd == fd && f.Kind == "cgroup events" {
+			t.Fatal("the inotify fd is still tracked")
+		}
+	}
+}
+
+func TestNotifyMemoryPressureV2(t *testing.T) {
+	// We're using a fake cgroupfs.
+	cgroups.TestMode = true
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "memory.pressure"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "cgroup.events"), []byte("populated 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := notifyMemoryPressureV2(dir, CriticalPressure+1, nil); err == nil {
+		t.Fatal("expected error for an invalid pressure level, got nil")
+	}
+	ch, err := notifyMemoryPressureV2(dir, CriticalPressure, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trigger, err := os.ReadFile(filepath.Join(dir, "memory.pressure"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(trigger) != "full 400000 2000000" {
+		t.Fatalf("unexpected trigger %q", trigger)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "cgroup.events"), []byte("populated 0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Fatal("expected the channel to be closed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("channel is not closed")
+	}
+
+	// Closing done closes the channel, and removes the trigger.
+	if err := os.WriteFile(filepath.Join(dir, "cgroup.events"), []byte("populated 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	ch, err = notifyMemoryPressureV2(dir, LowPressure, done)
+	if err != nil {
+		t.Fatal(err)
+	}
+	close(done)
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Fatal("expected the channel to be closed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("channel is not closed")
+	}
+	for _, fd := range TrackedFds() {
+		if fd.Kind == "memory pressure" {
+			t.Errorf("memory pressure trigger fd %d is not closed", fd.Fd)
+		}
+	}
+}
+
+func TestNotifyOnPidsLimit(t *testing.T) {
+	for _, v2 := range []bool{false, true} {
+		t.Run(fmt.Sprintf("v2=%t", v2), func(t *testing.T) {
+			// We're using a fake cgroupfs.
+			cgroups.TestMode = true
+			dir := t.TempDir()
+			write := func(name, data string) {
+				t.Helper()
+				f, err := os.OpenFile(filepath.Join(dir, name), os.O_WRONLY|os.O_CREATE, 0o644)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer f.Close()
+				if _, err := f.WriteAt([]byte(data), 0); err != nil {
+					t.Fatal(err)
+				}
+			}
+			write("pids.events", "max 1\n")
+			write("cgroup.events", "populated 1\n")
+
+			notify := notifyOnPidsLimit
+			if v2 {
+				notify = notifyOnPidsLimitV2
+			}
+			ch, err := notify(dir, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			write("pids.events", "max 2\n")
+			select {
+			case <-ch:
+			case <-time.After(5 * time.Second):
+				t.Fatal("no pids limit notification")
+			}
+
+			if v2 {
+				write("cgroup.events", "populated 0\n")
+			} else if err := os.Remove(filepath.Join(dir, "pids.events")); err != nil {
+				t.Fatal(err)
+			}
+			select {
+			case _, ok := <-ch:
+				if ok {
+					t.Fatal("expected the channel to be closed")
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("channel is not closed")
+			}
+
+			// Closing done closes the channel, even with an event no one
+			// received.
+			write("pids.events", "max 2\n")
+			write("cgroup.events", "populated 1\n")
+			done := make(chan struct{})
+			ch, err = notify(dir, done)
+			if err != nil {
+				t.Fatal(err)
+			}
+			write("pids.events", "max 3\n")
+			time.Sleep(2 * pidsLimitPollInterval)
+			close(done)
+			select {
+			case <-ch:
+				// The pending event may still be received first.
+				if _, ok := <-ch; ok {
+					t.Fatal("expected the channel to be closed")
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("channel is not closed")
+			}
+		})
+	}
+}
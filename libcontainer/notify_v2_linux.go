@@ -1,8 +1,12 @@
 package libcontainer
 
 import (
+	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
+	"sync"
+	"time"
 	"unsafe"
 
 	"github.com/sirupsen/logrus"
@@ -10,71 +14,363 @@ import (
 	"golang.org/x/sys/unix"
 )
 
-func registerMemoryEventV2(cgDir, evName, cgEvName string) (<-chan struct{}, error) {
-	fd, err := unix.InotifyInit()
+// eventWatcher is the inotify instance shared by all the cgroup v2 event
+// notifications of the process, so that they do not take an fd each, which
+// matters to the programs watching thousands of containers.
+var eventWatcher inotifyWatcher
+
+// inotifyWatcher is an inotify instance dispatching its events to its
+// subscribers. It is created on first use.
+type inotifyWatcher struct {
+	sync.Mutex
+	fd   int
+	err  error
+	once sync.Once
+	// failed is closed once the events can no longer be read, which is
+	// then err.
+	failed chan struct{}
+	// subs are the subscribers, by watch descriptor. A watch descriptor
+	// is shared by all the subscribers watching the same file.
+	subs map[int]map[*eventSub]struct{}
+}
+
+// eventSub is a subscriber to the events of an inotifyWatcher.
+type eventSub struct {
+	w *inotifyWatcher
+	// wake is sent to, without blocking, on the events of the watched
+	// files, which the subscriber is then to read again.
+	wake chan struct{}
+	// failed is closed once no more events are to come, as the watcher
+	// failed.
+	failed <-chan struct{}
+	wds    []int
+}
+
+// init creates the inotify instance, and starts dispatching its events.
+func (w *inotifyWatcher) init() {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC)
 	if err != nil {
-		return nil, fmt.Errorf("unable to init inotify: %w", err)
+		w.err = fmt.Errorf("unable to init inotify: %w", err)
+		return
 	}
-	// watching oom kill
-	evFd, err := unix.InotifyAddWatch(fd, filepath.Join(cgDir, evName), unix.IN_MODIFY)
-	if err != nil {
-		unix.Close(fd)
-		return nil, fmt.Errorf("unable to add inotify watch: %w", err)
+	w.fd = fd
+	w.failed = make(chan struct{})
+	w.subs = make(map[int]map[*eventSub]struct{})
+	trackFd(fd, "cgroup events", "", "anon_inode:inotify")
+	go w.dispatch(fd)
+}
+
+// dispatch wakes the subscribers up on the events of the inotify fd, until
+// it fails to read them.
+func (w *inotifyWatcher) dispatch(fd int) {
+	var buffer [unix.SizeofInotifyEvent + unix.PathMax + 1]byte
+	for {
+		n, err := unix.Read(fd, buffer[:])
+		if err != nil {
+			if err == unix.EINTR { //nolint:errorlint // unix errors are bare
+				continue
+			}
+			w.fail(fmt.Errorf("unable to read event data from inotify: %w", err))
+			return
+		}
+		w.Lock()
+		for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+			rawEvent := (*unix.InotifyEvent)(unsafe.Pointer(&buffer[offset]))
+			offset += unix.SizeofInotifyEvent + int(rawEvent.Len)
+			wd := int(rawEvent.Wd)
+			for sub := range w.subs[wd] {
+				select {
+				case sub.wake <- struct{}{}:
+				default:
+				}
+			}
+			// The watch is gone, such as with the cgroup.
+			if rawEvent.Mask&unix.IN_IGNORED != 0 {
+				delete(w.subs, wd)
+			}
+		}
+		w.Unlock()
+	}
+}
+
+// fail records that the events can no longer be read, makes all the
+// subscribers return, and closes the inotify fd. The subscriptions made
+// afterwards fail with err.
+func (w *inotifyWatcher) fail(err error) {
+	logrus.Warn(err)
+	w.Lock()
+	defer w.Unlock()
+	if w.err != nil {
+		return
+	}
+	w.err = err
+	w.subs = nil
+	close(w.failed)
+	untrackFd(w.fd)
+	_ = unix.Close(w.fd)
+}
+
+// subscribe subscribes to the modifications of the files at paths.
+func (w *inotifyWatcher) subscribe(paths ...string) (*eventSub, error) {
+	w.once.Do(w.init)
+	w.Lock()
+	defer w.Unlock()
+	if w.err != nil {
+		return nil, w.err
+	}
+	sub := &eventSub{w: w, wake: make(chan struct{}, 1), failed: w.failed}
+	for _, path := range paths {
+		wd, err := unix.InotifyAddWatch(w.fd, path, unix.IN_MODIFY)
+		if err != nil {
+			sub.unsubscribeLocked()
+			return nil, fmt.Errorf("unable to add inotify watch: %w", err)
+		}
+		if w.subs[wd] == nil {
+			w.subs[wd] = make(map[*eventSub]struct{})
+		}
+		w.subs[wd][sub] = struct{}{}
+		sub.wds = append(sub.wds, wd)
+	}
+	return sub, nil
+}
+
+// subscribeEvents subscribes to the modifications of the files at paths,
+// using eventWatcher.
+func subscribeEvents(paths ...string) (*eventSub, error) {
+	return eventWatcher.subscribe(paths...)
+}
+
+// unsubscribe stops the events of sub, removing the watches no other
+// subscriber uses.
+func (sub *eventSub) unsubscribe() {
+	sub.w.Lock()
+	defer sub.w.Unlock()
+	sub.unsubscribeLocked()
+}
+
+func (sub *eventSub) unsubscribeLocked() {
+	w := sub.w
+	for _, wd := range sub.wds {
+		// None are left if the watcher failed.
+		subs, ok := w.subs[wd]
+		if !ok {
+			continue
+		}
+		delete(subs, sub)
+		if len(subs) == 0 {
+			delete(w.subs, wd)
+			// This fails if the watch is already gone.
+			_, _ = unix.InotifyRmWatch(w.fd, uint32(wd))
+		}
 	}
+	sub.wds = nil
+}
+
+// registerEventV2 returns a channel on which you can expect an event when
+// the key counter of the evName cgroup file (such as "oom_kill" of
+// memory.events) increments. The channel is closed once the cgroup is empty
+// or gone, once done (if not nil) is closed, or once the events can no longer
+// be read.
+func registerEventV2(cgDir, evName, key string, done <-chan struct{}) (<-chan struct{}, error) {
+	const cgEvName = "cgroup.events"
 	// Because no `unix.IN_DELETE|unix.IN_DELETE_SELF` event for cgroup file system, so watching all process exited
-	cgFd, err := unix.InotifyAddWatch(fd, filepath.Join(cgDir, cgEvName), unix.IN_MODIFY)
+	sub, err := subscribeEvents(filepath.Join(cgDir, evName), filepath.Join(cgDir, cgEvName))
 	if err != nil {
-		unix.Close(fd)
-		return nil, fmt.Errorf("unable to add inotify watch: %w", err)
+		return nil, err
 	}
+	// Only the events happening from now on are reported. The file is
+	// modified on other events as well (such as "high" or "max" of
+	// memory.events), so a notification is only sent once the counter
+	// increments.
+	last, _ := fscommon.GetValueByKey(cgDir, evName, key)
 	ch := make(chan struct{})
 	go func() {
-		var (
-			buffer [unix.SizeofInotifyEvent + unix.PathMax + 1]byte
-			offset uint32
-		)
 		defer func() {
-			unix.Close(fd)
+			sub.unsubscribe()
 			close(ch)
 		}()
+		for {
+			select {
+			case <-sub.wake:
+			case <-sub.failed:
+				return
+			case <-done:
+				return
+			}
+			cur, err := fscommon.GetValueByKey(cgDir, evName, key)
+			notify := err != nil
+			if err == nil && cur > last {
+				last = cur
+				notify = true
+			}
+			if notify {
+				select {
+				case ch <- struct{}{}:
+				case <-done:
+					return
+				}
+			}
+			pids, err := fscommon.GetValueByKey(cgDir, cgEvName, "populated")
+			if err != nil || pids == 0 {
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// notifyOnOOMV2 returns channel on which you can expect event about OOM,
+// if process died without OOM this channel will be closed.
+func notifyOnOOMV2(path string) (<-chan struct{}, error) {
+	return registerEventV2(path, "memory.events", "oom_kill", nil)
+}
+
+// notifyOnPidsLimitV2 returns a channel on which you can expect an event
+// when a fork or clone fails because of the pids limit. The channel is
+// closed once the cgroup is empty or gone, or once done is closed.
+func notifyOnPidsLimitV2(path string, done <-chan struct{}) (<-chan struct{}, error) {
+	return registerEventV2(path, "pids.events", "max", done)
+}
 
+// memoryThresholdPollInterval is how often memory.current is checked by
+// notifyOnMemoryThresholdV2, when nothing else wakes it up.
+const memoryThresholdPollInterval = time.Second
+
+// notifyOnMemoryThresholdV2 returns a channel on which you can expect an event
+// when memory usage rises above threshold (in bytes). The channel is closed
+// once the cgroup is empty or gone, once done is closed, or once the events
+// can no longer be read.
+//
+// Unlike cgroup v1, cgroup v2 has no usage threshold notifications. The
+// memory usage is checked on the memory.events modifications (which happen
+// as soon as the usage reaches memory.high or memory.max), and polled
+// otherwise.
+func notifyOnMemoryThresholdV2(path string, threshold uint64, done <-chan struct{}) (<-chan struct{}, error) {
+	// Make sure we can read the file before starting to poll.
+	if _, err := fscommon.GetCgroupParamUint(path, "memory.current"); err != nil {
+		return nil, err
+	}
+	sub, err := subscribeEvents(filepath.Join(path, "memory.events"), filepath.Join(path, "cgroup.events"))
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan struct{})
+	go func() {
+		defer func() {
+			sub.unsubscribe()
+			close(ch)
+		}()
+		ticker := time.NewTicker(memoryThresholdPollInterval)
+		defer ticker.Stop()
+		above := false
 		for {
-			n, err := unix.Read(fd, buffer[:])
+			select {
+			case <-sub.wake:
+			case <-ticker.C:
+			case <-sub.failed:
+				return
+			case <-done:
+				return
+			}
+			if pids, err := fscommon.GetValueByKey(path, "cgroup.events", "populated"); err != nil || pids == 0 {
+				return
+			}
+			usage, err := fscommon.GetCgroupParamUint(path, "memory.current")
 			if err != nil {
-				logrus.Warnf("unable to read event data from inotify, got error: %v", err)
 				return
 			}
-			if n < unix.SizeofInotifyEvent {
-				logrus.Warnf("we should read at least %d bytes from inotify, but got %d bytes.", unix.SizeofInotifyEvent, n)
+			if usage < threshold {
+				above = false
+				continue
+			}
+			if !above {
+				above = true
+				select {
+				case ch <- struct{}{}:
+				case <-done:
+					return
+				}
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// memoryPressureTriggers are the PSI triggers used for the memory pressure
+// levels on cgroup v2, which has no memory.pressure_level. A trigger fires
+// once the tasks of the cgroup are stalled on memory for the given time (in
+// microseconds) within the window, which is 2s as unprivileged users can
+// only use windows which are multiples of it. For the low and medium levels,
+// some of the tasks have to be stalled (for 5% and 20% of the time), and for
+// the critical level, all of them (for 20% of the time).
+var memoryPressureTriggers = []string{
+	LowPressure:      "some 100000 2000000",
+	MediumPressure:   "some 400000 2000000",
+	CriticalPressure: "full 400000 2000000",
+}
+
+// memoryPressurePollTimeout is how often notifyMemoryPressureV2 checks if
+// the cgroup is still populated, and if done is closed, while waiting for
+// the trigger to fire.
+const memoryPressurePollTimeout = time.Second
+
+// notifyMemoryPressureV2 returns a channel on which you can expect an event
+// when the cgroup reaches the given memory pressure level, as reported by
+// PSI (see Documentation/accounting/psi.rst in the kernel tree). The channel
+// is closed, and the trigger removed, once the cgroup is empty or gone, or
+// once done is closed.
+func notifyMemoryPressureV2(path string, level PressureLevel, done <-chan struct{}) (<-chan struct{}, error) {
+	if path == "" {
+		return nil, errors.New("memory controller missing")
+	}
+	if level > CriticalPressure {
+		return nil, fmt.Errorf("invalid pressure level %d", level)
+	}
+	file, err := os.OpenFile(filepath.Join(path, "memory.pressure"), os.O_RDWR|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, err
+	}
+	// The trigger is bound to the file descriptor it is written to, and is
+	// removed once the file is closed.
+	if _, err := file.WriteString(memoryPressureTriggers[level]); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("unable to set memory pressure trigger: %w", err)
+	}
+	trackFd(int(file.Fd()), "memory pressure", "", file.Name())
+	ch := make(chan struct{})
+	go func() {
+		defer func() {
+			closeTracked(file)
+			close(ch)
+		}()
+		fds := []unix.PollFd{{Fd: int32(file.Fd()), Events: unix.POLLPRI}}
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			n, err := unix.Poll(fds, int(memoryPressurePollTimeout.Milliseconds()))
+			if err != nil && err != unix.EINTR { // nolint:errorlint // unix errors are bare
+				logrus.Warnf("unable to poll memory pressure trigger: %v", err)
 				return
 			}
-			offset = 0
-			for offset <= uint32(n-unix.SizeofInotifyEvent) {
-				rawEvent := (*unix.InotifyEvent)(unsafe.Pointer(&buffer[offset]))
-				offset += unix.SizeofInotifyEvent + rawEvent.Len
-				if rawEvent.Mask&unix.IN_MODIFY != unix.IN_MODIFY {
-					continue
+			if n > 0 {
+				if fds[0].Revents&unix.POLLERR != 0 {
+					return
 				}
-				switch int(rawEvent.Wd) {
-				case evFd:
-					oom, err := fscommon.GetValueByKey(cgDir, evName, "oom_kill")
-					if err != nil || oom > 0 {
-						ch <- struct{}{}
-					}
-				case cgFd:
-					pids, err := fscommon.GetValueByKey(cgDir, cgEvName, "populated")
-					if err != nil || pids == 0 {
+				if fds[0].Revents&unix.POLLPRI != 0 {
+					select {
+					case ch <- struct{}{}:
+					case <-done:
 						return
 					}
 				}
 			}
+			if pids, err := fscommon.GetValueByKey(path, "cgroup.events", "populated"); err != nil || pids == 0 {
+				return
+			}
 		}
 	}()
 	return ch, nil
 }
-
-// notifyOnOOMV2 returns channel on which you can expect event about OOM,
-// if process died without OOM this channel will be closed.
-func notifyOnOOMV2(path string) (<-chan struct{}, error) {
-	return registerMemoryEventV2(path, "memory.events", "cgroup.events")
-}
@@ -0,0 +1,70 @@
+package libcontainer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/szcdx/runc/libcontainer/cgroups"
+)
+
+func TestRegisterEventV2Shared(t *testing.T) {
+	cgroups.TestMode = true
+	dirs := []string{t.TempDir(), t.TempDir()}
+	for _, dir := range dirs {
+		if err := os.WriteFile(filepath.Join(dir, "memory.events"), []byte("oom_kill 0\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "cgroup.events"), []byte("populated 1\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var chs []<-chan struct{}
+	for _, dir := range dirs {
+		ch, err := registerEventV2(dir, "memory.events", "oom_kill", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		chs = append(chs, ch)
+	}
+
+	watchers := 0
+	for _, fd := range TrackedFds() {
+		if fd.Kind == "cgroup events" {
+			watchers++
+		}
+	}
+	if watchers != 1 {
+		t.Errorf("expected a single shared inotify fd, got %d", watchers)
+	}
+
+	for i, dir := range dirs {
+		// Other keys changing are not reported.
+		if err := os.WriteFile(filepath.Join(dir, "memory.events"), []byte("high 1\noom_kill 0\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "memory.events"), []byte("high 1\noom_kill 1\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case _, ok := <-chs[i]:
+			if !ok {
+				t.Fatal("unexpected close of the channel")
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("expected an event")
+		}
+		if err := os.WriteFile(filepath.Join(dir, "cgroup.events"), []byte("populated 0\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case _, ok := <-chs[i]:
+			if ok {
+				t.Fatal("expected the channel to be closed")
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("expected the channel to be closed")
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package libcontainer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/szcdx/runc/libcontainer/cgroups"
+	"github.com/szcdx/runc/libcontainer/cgroups/fs2"
+	"github.com/szcdx/runc/libcontainer/cgroups/fscommon"
+)
+
+// OOMKill describes the OOM kills which happened in the container.
+type OOMKill struct {
+	// Count is the number of processes in the container killed
+	// by the OOM killer so far.
+	Count uint64
+
+	// Pid, Comm and RSS (in bytes) describe the last OOM killer victim,
+	// as reported by the kernel log. Pid is 0 if the victim is unknown,
+	// e.g. because the kernel log is not readable.
+	Pid  int
+	Comm string
+	RSS  uint64
+}
+
+// OOMKillInfo returns the number of OOM kills in the container, and details
+// about the last victim where available.
+func (c *Container) OOMKillInfo() (*OOMKill, error) {
+	path := c.cgroupManager.Path("memory")
+	if path == "" {
+		return nil, errors.New("memory controller missing")
+	}
+	var (
+		info OOMKill
+		root string
+		err  error
+	)
+	if cgroups.IsCgroup2UnifiedMode() {
+		root = fs2.UnifiedMountpoint
+		// memory.events.local is only available since Linux 5.2.
+		info.Count, err = fscommon.GetValueByKey(path, "memory.events.local", "oom_kill")
+		if errors.Is(err, os.ErrNotExist) {
+			info.Count, err = fscommon.GetValueByKey(path, "memory.events", "oom_kill")
+		}
+	} else {
+		root, err = cgroups.FindCgroupMountpoint("", "memory")
+		if err != nil {
+			return nil, err
+		}
+		// oom_kill is only available since Linux 4.13.
+		info.Count, err = fscommon.GetValueByKey(path, "memory.oom_control", "oom_kill")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if info.Count == 0 {
+		return &info, nil
+	}
+
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return nil, err
+	}
+	msgs, err := readKmsg()
+	if err != nil {
+		// Not fatal, the kernel log may not be readable by us.
+		return &info, nil
+	}
+	info.Pid, info.Comm, info.RSS = parseOOMVictim(msgs, "/"+rel)
+	return &info, nil
+}
+
+// readKmsg returns all the messages currently in the kernel log buffer.
+func readKmsg() ([]string, error) {
+	fd, err := unix.Open("/dev/kmsg", unix.O_RDONLY|unix.O_NONBLOCK|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: "/dev/kmsg", Err: err}
+	}
+	defer unix.Close(fd)
+
+	var (
+		msgs []string
+		buf  [8192]byte
+	)
+	for {
+		n, err := unix.Read(fd, buf[:])
+		switch {
+		case errors.Is(err, unix.EAGAIN):
+			return msgs, nil
+		case errors.Is(err, unix.EPIPE):
+			// The record was overwritten while we were reading; skip it.
+			continue
+		case errors.Is(err, unix.EINTR):
+			continue
+		case err != nil:
+			return nil, &os.PathError{Op: "read", Path: "/dev/kmsg", Err: err}
+		}
+		// Each record is "prefix;message\n" followed by optional
+		// continuation lines, which we don't need.
+		rec := string(buf[:n])
+		if i := strings.IndexByte(rec, ';'); i >= 0 {
+			rec = rec[i+1:]
+		}
+		if i := strings.IndexByte(rec, '\n'); i >= 0 {
+			rec = rec[:i]
+		}
+		msgs = append(msgs, rec)
+	}
+}
+
+// parseOOMVictim looks through the kernel log messages for the last OOM kill
+// of a process from the memory cgroup memcg (or any of its descendants), and
+// returns its pid, comm, and rss. The kernel log looks like this:
+//
+//	oom-kill:constraint=CONSTRAINT_MEMCG,...,oom_memcg=/foo,task_memcg=/foo/bar,task=sh,pid=1234,uid=0
+//	Memory cgroup out of memory: Killed process 1234 (sh) total-vm:4228kB, anon-rss:1024kB, file-rss:512kB, shmem-rss:0kB, UID:0 pgtables:44kB oom_score_adj:0
+func parseOOMVictim(msgs []string, memcg string) (pid int, comm string, rss uint64) {
+	for i, msg := range msgs {
+		const oomKill = "oom-kill:"
+		if !strings.HasPrefix(msg, oomKill) {
+			continue
+		}
+		var (
+			p       int
+			c, task string
+		)
+		for _, kv := range strings.Split(msg[len(oomKill):], ",") {
+			k, v, _ := strings.Cut(kv, "=")
+			switch k {
+			case "task_memcg":
+				task = v
+			case "task":
+				c = v
+			case "pid":
+				p, _ = strconv.Atoi(v)
+			}
+		}
+		if p == 0 || (task != memcg && !strings.HasPrefix(task, memcg+"/")) {
+			continue
+		}
+		pid, comm, rss = p, c, 0
+		// Find the matching "Killed process" message for the rss.
+		killed := "Killed process " + strconv.Itoa(p) + " "
+		for _, m := range msgs[i+1:] {
+			j := strings.Index(m, killed)
+			if j < 0 {
+				continue
+			}
+			for _, f := range strings.Fields(m[j+len(killed):]) {
+				k, v, ok := strings.Cut(strings.TrimSuffix(f, ","), ":")
+				if !ok || !strings.HasSuffix(k, "-rss") {
+					continue
+				}
+				if kb, err := strconv.ParseUint(strings.TrimSuffix(v, "kB"), 10, 64); err == nil {
+					rss += kb * 1024
+				}
+			}
+			break
+		}
+	}
+	return pid, comm, rss
+}
@@ -0,0 +1,31 @@
+package libcontainer
+
+import "testing"
+
+func TestParseOOMVictim(t *testing.T) {
+	msgs := []string{
+		"oom-kill:constraint=CONSTRAINT_MEMCG,nodemask=(null),cpuset=/,mems_allowed=0,oom_memcg=/other,task_memcg=/other,task=sh,pid=100,uid=0",
+		"Memory cgroup out of memory: Killed process 100 (sh) total-vm:4228kB, anon-rss:8kB, file-rss:0kB, shmem-rss:0kB, UID:0 pgtables:44kB oom_score_adj:0",
+		"oom-kill:constraint=CONSTRAINT_MEMCG,nodemask=(null),cpuset=/,mems_allowed=0,oom_memcg=/foo,task_memcg=/foo/bar,task=stress,pid=1234,uid=0",
+		"Memory cgroup out of memory: Killed process 1234 (stress) total-vm:264388kB, anon-rss:1024kB, file-rss:512kB, shmem-rss:4kB, UID:0 pgtables:556kB oom_score_adj:0",
+		"oom-kill:constraint=CONSTRAINT_MEMCG,nodemask=(null),cpuset=/,mems_allowed=0,oom_memcg=/foobar,task_memcg=/foobar,task=sh,pid=200,uid=0",
+	}
+
+	for _, tc := range []struct {
+		memcg string
+		pid   int
+		comm  string
+		rss   uint64
+	}{
+		{memcg: "/foo", pid: 1234, comm: "stress", rss: 1540 * 1024},
+		{memcg: "/foo/bar", pid: 1234, comm: "stress", rss: 1540 * 1024},
+		{memcg: "/other", pid: 100, comm: "sh", rss: 8 * 1024},
+		{memcg: "/foobar", pid: 200, comm: "sh"},
+		{memcg: "/none"},
+	} {
+		pid, comm, rss := parseOOMVictim(msgs, tc.memcg)
+		if pid != tc.pid || comm != tc.comm || rss != tc.rss {
+			t.Errorf("memcg %s: expected (%d, %q, %d), got (%d, %q, %d)", tc.memcg, tc.pid, tc.comm, tc.rss, pid, comm, rss)
+		}
+	}
+}
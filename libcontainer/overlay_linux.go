@@ -0,0 +1,97 @@
+package libcontainer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/szcdx/runc/libcontainer/configs"
+)
+
+// managedMountsDir is the directory, in the state directory of a container,
+// with the upper and work directories of its managed overlay mounts.
+const managedMountsDir = "overlay"
+
+// setupManagedMounts creates the upper and work directories of the managed
+// overlay mounts (the ones with managed=true) of the container in its state
+// directory, and adds them to the options of these mounts in the
+// configuration passed to runc init. They are removed, along with the state
+// directory, when the container is destroyed. As they are neither reachable
+// nor idmapped for a user namespace, such mounts are rejected for the
+// containers with one (see validate).
+func (c *Container) setupManagedMounts(cfg *initConfig) error {
+	var mounts []*configs.Mount
+	for i, m := range cfg.Config.Mounts {
+		if m.Extensions&configs.EXT_MANAGED == 0 {
+			continue
+		}
+		if mounts == nil {
+			mounts = make([]*configs.Mount, len(cfg.Config.Mounts))
+			copy(mounts, cfg.Config.Mounts)
+		}
+		upper, work, err := c.makeManagedDirs(strconv.Itoa(i))
+		if err != nil {
+			return fmt.Errorf("managed overlay mount %s: %w", m.Destination, err)
+		}
+		mc := *m
+		if mc.Data != "" {
+			mc.Data += ","
+		}
+		mc.Data += "upperdir=" + upper + ",workdir=" + work
+		mounts[i] = &mc
+	}
+	if mounts == nil {
+		return nil
+	}
+	config := *cfg.Config
+	config.Mounts = mounts
+	cfg.Config = &config
+	return nil
+}
+
+// checkManagedMounts checks that the container has no managed overlay mounts,
+// which can not be checkpointed nor restored: their upper directories are not
+// in the images, and are removed along with the container.
+func (c *Container) checkManagedMounts() error {
+	for _, m := range c.config.Mounts {
+		if m.Extensions&configs.EXT_MANAGED != 0 {
+			return fmt.Errorf("checkpoint/restore is not supported for the containers with managed overlay mounts (%s)", m.Destination)
+		}
+	}
+	return nil
+}
+
+// makeManagedDirs creates the (empty) upper and work directories of a
+// managed overlay mount, owned by the container root.
+func (c *Container) makeManagedDirs(name string) (upper, work string, _ error) {
+	uid, err := c.config.HostRootUID()
+	if err != nil {
+		return "", "", err
+	}
+	gid, err := c.config.HostRootGID()
+	if err != nil {
+		return "", "", err
+	}
+	dir := filepath.Join(c.stateDir, managedMountsDir, name)
+	if err := os.MkdirAll(filepath.Dir(dir), 0o711); err != nil {
+		return "", "", err
+	}
+	// Start anew, in case of a leftover from a failed start.
+	if err := os.RemoveAll(dir); err != nil {
+		return "", "", err
+	}
+	if err := os.Mkdir(dir, 0o711); err != nil {
+		return "", "", err
+	}
+	upper, work = filepath.Join(dir, "upper"), filepath.Join(dir, "work")
+	for _, d := range []string{upper, work} {
+		if err := os.Mkdir(d, 0o755); err != nil {
+			return "", "", err
+		}
+		if err := os.Lchown(d, uid, gid); err != nil {
+			return "", "", err
+		}
+	}
+	return upper, work, nil
+}
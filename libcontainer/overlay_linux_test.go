@@ -0,0 +1,55 @@
+package libcontainer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/szcdx/runc/libcontainer/configs"
+)
+
+func TestSetupManagedMounts(t *testing.T) {
+	c := &Container{
+		stateDir: t.TempDir(),
+		config: &configs.Config{
+			Mounts: []*configs.Mount{
+				{Destination: "/proc", Device: "proc"},
+				{Destination: "/data", Device: "overlay", Data: "lowerdir=/a", Extensions: configs.EXT_MANAGED},
+			},
+		},
+	}
+	cfg := &initConfig{Config: c.config}
+	if err := c.setupManagedMounts(cfg); err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(c.stateDir, managedMountsDir, "1")
+	expected := "lowerdir=/a,upperdir=" + dir + "/upper,workdir=" + dir + "/work"
+	if data := cfg.Config.Mounts[1].Data; data != expected {
+		t.Errorf("expected data %q, got %q", expected, data)
+	}
+	// The container configuration itself is left alone.
+	if data := c.config.Mounts[1].Data; data != "lowerdir=/a" {
+		t.Errorf("container configuration changed: data %q", data)
+	}
+	if cfg.Config.Mounts[0] != c.config.Mounts[0] {
+		t.Error("unmanaged mount was copied")
+	}
+	for _, d := range []string{"upper", "work"} {
+		if fi, err := os.Stat(filepath.Join(dir, d)); err != nil || !fi.IsDir() {
+			t.Errorf("%s: %v", d, err)
+		}
+	}
+}
+
+func TestCheckManagedMounts(t *testing.T) {
+	c := &Container{config: &configs.Config{
+		Mounts: []*configs.Mount{{Destination: "/proc", Device: "proc"}},
+	}}
+	if err := c.checkManagedMounts(); err != nil {
+		t.Fatal(err)
+	}
+	c.config.Mounts = append(c.config.Mounts, &configs.Mount{Destination: "/data", Device: "overlay", Extensions: configs.EXT_MANAGED})
+	if err := c.checkManagedMounts(); err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+}
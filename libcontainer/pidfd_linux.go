@@ -0,0 +1,154 @@
+package libcontainer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/szcdx/runc/libcontainer/system"
+)
+
+// processAlive tells whether the process with the given pid is the one
+// started at startTime (as in /proc/<pid>/stat), and has not exited yet.
+func processAlive(pid int, startTime uint64) bool {
+	stat, err := system.Stat(pid)
+	if err != nil {
+		return false
+	}
+	return stat.StartTime == startTime && stat.State != system.Zombie && stat.State != system.Dead
+}
+
+// openPidfd returns a pidfd of the process with the given pid, which must
+// have been started at startTime (as in /proc/<pid>/stat). The start time
+// is checked once the pidfd is open, so that the pidfd is known to refer to
+// that process, and to keep doing so even if its pid is reused later.
+//
+// It returns an error wrapping unix.ENOSYS if pidfd_open(2) is not
+// supported.
+func openPidfd(pid int, startTime uint64) (*os.File, error) {
+	fd, err := unix.PidfdOpen(pid, 0)
+	if err != nil {
+		if errors.Is(err, unix.ESRCH) {
+			return nil, fmt.Errorf("pid %d: %w", pid, ErrProcessGone)
+		}
+		return nil, os.NewSyscallError("pidfd_open", err)
+	}
+	pidfd := os.NewFile(uintptr(fd), "[pidfd]")
+	if !processAlive(pid, startTime) {
+		pidfd.Close()
+		return nil, fmt.Errorf("pid %d: %w", pid, ErrProcessGone)
+	}
+	return pidfd, nil
+}
+
+// pidfdSignal sends the signal s to the process referred to by pidfd. It
+// returns ErrProcessGone if the process has been reaped.
+func pidfdSignal(pidfd *os.File, s os.Signal) error {
+	sig, ok := s.(unix.Signal)
+	if !ok {
+		return errors.New("os: unsupported signal type")
+	}
+	if err := unix.PidfdSendSignal(int(pidfd.Fd()), sig, nil, 0); err != nil {
+		if errors.Is(err, unix.ESRCH) {
+			return ErrProcessGone
+		}
+		return os.NewSyscallError("pidfd_send_signal", err)
+	}
+	return nil
+}
+
+// signalProcess sends the signal s to the process with the given pid,
+// which must have been started at startTime, through a pidfd if possible.
+// It returns an error wrapping ErrProcessGone if that process has exited.
+//
+// Without pidfd_open(2), the start time is checked right before the signal
+// is sent, which leaves a (much shorter) window for the pid to be reused.
+func signalProcess(pid int, startTime uint64, s os.Signal) error {
+	pidfd, err := openPidfd(pid, startTime)
+	if err == nil {
+		defer pidfd.Close()
+		if err := pidfdSignal(pidfd, s); err != nil {
+			return fmt.Errorf("pid %d: %w", pid, err)
+		}
+		return nil
+	}
+	if !errors.Is(err, unix.ENOSYS) {
+		return err
+	}
+	sig, ok := s.(unix.Signal)
+	if !ok {
+		return errors.New("os: unsupported signal type")
+	}
+	if !processAlive(pid, startTime) {
+		return fmt.Errorf("pid %d: %w", pid, ErrProcessGone)
+	}
+	if err := unix.Kill(pid, sig); err != nil {
+		if errors.Is(err, unix.ESRCH) {
+			return fmt.Errorf("pid %d: %w", pid, ErrProcessGone)
+		}
+		return err
+	}
+	return nil
+}
+
+// pidfdExited tells whether the process referred to by pidfd has exited,
+// without waiting for it to.
+func pidfdExited(pidfd *os.File) bool {
+	// A pidfd is readable once the process has exited.
+	fds := []unix.PollFd{{Fd: int32(pidfd.Fd()), Events: unix.POLLIN}}
+	for {
+		n, err := unix.Poll(fds, 0)
+		if err == unix.EINTR { //nolint:errorlint // unix errors are bare
+			continue
+		}
+		return err == nil && n > 0
+	}
+}
+
+// initPidfd returns a pidfd of the container init, opening it the first
+// time. It returns nil (and no error) if pidfd_open(2) is not supported, in
+// which case the start time of the init has to be checked instead.
+func (c *Container) initPidfd() (*os.File, error) {
+	if c.initProcessPidfd != nil || c.noPidfd {
+		return c.initProcessPidfd, nil
+	}
+	if c.initProcess == nil {
+		return nil, fmt.Errorf("init: %w", ErrProcessGone)
+	}
+	pid := c.initProcess.pid()
+	pidfd, err := openPidfd(pid, c.initProcessStartTime)
+	if err != nil {
+		if errors.Is(err, unix.ENOSYS) {
+			c.noPidfd = true
+			return nil, nil
+		}
+		return nil, err
+	}
+	trackFd(int(pidfd.Fd()), "init pidfd", c.id, "anon_inode:[pidfd]")
+	c.initProcessPidfd = pidfd
+	return pidfd, nil
+}
+
+// setInitProcess sets the container init process to p, dropping the pidfd
+// of the previous one, if any.
+func (c *Container) setInitProcess(p parentProcess) {
+	closeTracked(c.initProcessPidfd)
+	c.initProcessPidfd = nil
+	c.initProcess = p
+}
+
+// signalInit sends the signal s to the container init, through its pidfd
+// if possible, so that it can't be sent to another process reusing the
+// pid of the init once it has exited.
+func (c *Container) signalInit(s os.Signal) error {
+	pidfd, err := c.initPidfd()
+	if err != nil {
+		return err
+	}
+	if pidfd == nil {
+		return c.initProcess.signal(s)
+	}
+	return pidfdSignal(pidfd, s)
+}
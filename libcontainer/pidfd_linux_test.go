@@ -0,0 +1,119 @@
+package libcontainer
+
+import (
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/szcdx/runc/libcontainer/system"
+)
+
+func TestOpenPidfd(t *testing.T) {
+	cmd := exec.Command("sleep", "30")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}()
+	pid := cmd.Process.Pid
+	stat, err := system.Stat(pid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := openPidfd(pid, stat.StartTime+1); !errors.Is(err, ErrProcessGone) {
+		if errors.Is(err, unix.ENOSYS) {
+			t.Skip("pidfd_open is not supported")
+		}
+		t.Fatalf("expected ErrProcessGone for a wrong start time, got %v", err)
+	}
+	pidfd, err := openPidfd(pid, stat.StartTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pidfd.Close()
+
+	if pidfdExited(pidfd) {
+		t.Fatal("process exited before it was killed")
+	}
+	if err := pidfdSignal(pidfd, unix.SIGKILL); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !pidfdExited(pidfd) {
+		if time.Now().After(deadline) {
+			t.Fatal("exit not noticed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if st, err := cmd.Process.Wait(); err != nil || st.Success() {
+		t.Fatalf("unexpected wait result: %v, %v", st, err)
+	}
+	// Once reaped, the process can't be signaled by mistake.
+	if err := pidfdSignal(pidfd, unix.SIGKILL); !errors.Is(err, ErrProcessGone) {
+		t.Errorf("expected ErrProcessGone, got %v", err)
+	}
+	if _, err := openPidfd(pid, stat.StartTime); !errors.Is(err, ErrProcessGone) {
+		t.Errorf("expected ErrProcessGone for a reaped process, got %v", err)
+	}
+}
+
+func TestNonChildProcessSignal(t *testing.T) {
+	cmd := exec.Command("sleep", "30")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}()
+	stat, err := system.Stat(cmd.Process.Pid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Another process than the one the pid was recorded for.
+	p := &nonChildProcess{processPid: cmd.Process.Pid, processStartTime: stat.StartTime + 1}
+	if err := p.signal(unix.SIGKILL); !errors.Is(err, ErrProcessGone) {
+		t.Fatalf("expected ErrProcessGone, got %v", err)
+	}
+	p.processStartTime = stat.StartTime
+	if err := p.signal(unix.SIGKILL); err != nil {
+		t.Fatal(err)
+	}
+	if st, err := cmd.Process.Wait(); err != nil || st.Success() {
+		t.Fatalf("unexpected wait result: %v, %v", st, err)
+	}
+	if err := p.signal(unix.SIGKILL); !errors.Is(err, ErrProcessGone) {
+		t.Errorf("expected ErrProcessGone for a reaped process, got %v", err)
+	}
+}
+
+func TestProcessAlive(t *testing.T) {
+	cmd := exec.Command("true")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	pid := cmd.Process.Pid
+	stat, err := system.Stat(pid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Until it is reaped, the exited process is a zombie.
+	deadline := time.Now().Add(5 * time.Second)
+	for processAlive(pid, stat.StartTime) {
+		if time.Now().After(deadline) {
+			t.Fatal("zombie process is seen as alive")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	_ = cmd.Wait()
+	if processAlive(pid, stat.StartTime) {
+		t.Fatal("reaped process is seen as alive")
+	}
+}
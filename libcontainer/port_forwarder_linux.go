@@ -0,0 +1,341 @@
+package libcontainer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/szcdx/runc/libcontainer/cgroups"
+	"github.com/szcdx/runc/libcontainer/configs"
+	"github.com/szcdx/runc/libcontainer/system"
+	"github.com/szcdx/runc/libcontainer/utils"
+)
+
+// The ports of the container (see configs.PortForward) are forwarded by a
+// helper process (a "runc init" of the initPortForwarder type), in a socket
+// activation style: runc listens on the host ports itself, so that the
+// errors (such as a port already in use) are reported when the container
+// is created, and passes the listening sockets to the helper. The helper
+// joins the user and network namespaces of the container using nsexec, in
+// the same way as a "runc exec" process does, which works for rootless
+// containers too, and connects to the container ports from there. It lives
+// as long as the container init does, in the container's cgroup (if it can
+// be moved there), and is recorded in the container state, so that it is
+// stopped along with the container, and started again on restore.
+
+const (
+	// portForwarderInitFd is the fd number of the init pipe in the helper.
+	portForwarderInitFd = 3
+	// portForwarderPidFd is the fd number of the pidfd of the container
+	// init in the helper.
+	portForwarderPidFd = 4
+	// portForwarderListenFd is the fd number of the socket listening on the
+	// first forwarded port in the helper, the sockets of the next ones
+	// following it.
+	portForwarderListenFd = 5
+
+	// udpSessionTimeout is the time after which a UDP "session" (the socket
+	// forwarding the datagrams of a client) is closed, if the container
+	// does not reply anymore.
+	udpSessionTimeout = 90 * time.Second
+
+	// maxPortForwardConns is the maximum number of the connections (or UDP
+	// sessions) forwarded at once for a port. The next ones wait in the
+	// listen backlog (or their datagrams are dropped).
+	maxPortForwardConns = 1024
+)
+
+// portForwarder is the process forwarding the ports of the container.
+type portForwarder struct {
+	pid       int
+	startTime uint64
+}
+
+// startPortForwarder listens on the host ports of c.config.PortForwards
+// and starts the process forwarding them into the namespaces of the
+// container init process initPid.
+func (c *Container) startPortForwarder(initPid int) (_ *portForwarder, retErr error) {
+	var files []*os.File
+	defer func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}()
+	for _, p := range c.config.PortForwards {
+		f, err := listenPortForward(p)
+		if err != nil {
+			return nil, fmt.Errorf("unable to listen on port %s/%d: %w", portForwardProtocol(p), p.HostPort, err)
+		}
+		files = append(files, f)
+	}
+
+	pidFd, err := unix.PidfdOpen(initPid, 0)
+	if err != nil {
+		return nil, os.NewSyscallError("pidfd_open", err)
+	}
+	pidFile := os.NewFile(uintptr(pidFd), "[pidfd]")
+	defer pidFile.Close()
+
+	nsPaths := map[configs.NamespaceType]string{
+		configs.NEWNET: "/proc/" + strconv.Itoa(initPid) + "/ns/net",
+	}
+	if c.config.Namespaces.Contains(configs.NEWUSER) {
+		nsPaths[configs.NEWUSER] = "/proc/" + strconv.Itoa(initPid) + "/ns/user"
+	}
+	data, err := c.bootstrapData(0, nsPaths)
+	if err != nil {
+		return nil, err
+	}
+	parent, child, err := utils.NewSockPair("port-forwarder")
+	if err != nil {
+		return nil, fmt.Errorf("unable to create init pipe: %w", err)
+	}
+	defer parent.Close()
+
+	cmd := exec.Command("/proc/self/exe", "init")
+	cmd.Env = []string{
+		"_LIBCONTAINER_INITTYPE=" + string(initPortForwarder),
+		"_LIBCONTAINER_INITPIPE=" + strconv.Itoa(portForwarderInitFd),
+	}
+	cmd.ExtraFiles = append([]*os.File{child, pidFile}, files...)
+	err = cmd.Start()
+	_ = child.Close()
+	if err != nil {
+		return nil, fmt.Errorf("unable to start port forwarder: %w", err)
+	}
+	waitInit := initWaiter(parent)
+	if _, err := io.Copy(parent, data); err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return nil, fmt.Errorf("unable to copy bootstrap data to pipe: %w", err)
+	}
+	if err := <-waitInit; err != nil {
+		_ = cmd.Wait()
+		return nil, err
+	}
+	// As for a setns process, the first process exits once nsexec is done,
+	// and sends the pid of the final one.
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("unable to start port forwarder: %w", err)
+	}
+	var pid *pid
+	if err := json.NewDecoder(parent).Decode(&pid); err != nil {
+		return nil, fmt.Errorf("unable to read port forwarder pid from init pipe: %w", err)
+	}
+	if firstChild, err := os.FindProcess(pid.PidFirstChild); err == nil {
+		_, _ = firstChild.Wait()
+	}
+	stat, err := system.Stat(pid.Pid)
+	if err != nil {
+		_ = unix.Kill(pid.Pid, unix.SIGKILL)
+		return nil, fmt.Errorf("unable to start port forwarder: %w", err)
+	}
+	fw := &portForwarder{pid: pid.Pid, startTime: stat.StartTime}
+	defer func() {
+		if retErr != nil {
+			_ = fw.stop()
+		}
+	}()
+	// Account the forwarder to the container, as it works for it.
+	for _, path := range c.cgroupManager.GetPaths() {
+		if err := cgroups.WriteCgroupProc(path, fw.pid); err != nil {
+			logrus.Warnf("unable to move the port forwarder to the container cgroup: %v", err)
+			break
+		}
+	}
+	if err := utils.WriteJSON(parent, c.config.PortForwards); err != nil {
+		return nil, fmt.Errorf("unable to send ports to port forwarder: %w", err)
+	}
+	return fw, nil
+}
+
+// stop stops the port forwarder, if it is still running.
+func (fw *portForwarder) stop() error {
+	pidfd, err := openPidfd(fw.pid, fw.startTime)
+	if err != nil {
+		if errors.Is(err, ErrProcessGone) {
+			return nil
+		}
+		return err
+	}
+	defer pidfd.Close()
+	if err := pidfdSignal(pidfd, unix.SIGKILL); err != nil && !errors.Is(err, ErrProcessGone) {
+		return err
+	}
+	return nil
+}
+
+// portForwardProtocol returns the protocol of the forwarded port p.
+func portForwardProtocol(p *configs.PortForward) string {
+	if p.Protocol == "" {
+		return "tcp"
+	}
+	return p.Protocol
+}
+
+// listenPortForward listens on the host port of p, and returns the
+// listening socket.
+func listenPortForward(p *configs.PortForward) (*os.File, error) {
+	addr := net.JoinHostPort(p.HostIP, strconv.Itoa(int(p.HostPort)))
+	if portForwardProtocol(p) == "udp" {
+		conn, err := net.ListenPacket("udp", addr)
+		if err != nil {
+			return nil, err
+		}
+		defer conn.Close()
+		return conn.(*net.UDPConn).File()
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer l.Close()
+	return l.(*net.TCPListener).File()
+}
+
+// portForwarderMain is the main function of the port forwarder. It
+// forwards the ports until the container init exits.
+func portForwarderMain() error {
+	initPipe := os.NewFile(portForwarderInitFd, "init")
+	var forwards []*configs.PortForward
+	err := json.NewDecoder(initPipe).Decode(&forwards)
+	_ = initPipe.Close()
+	if err != nil {
+		return fmt.Errorf("unable to read ports from init pipe: %w", err)
+	}
+	unix.CloseOnExec(portForwarderPidFd)
+
+	for i, p := range forwards {
+		f := os.NewFile(uintptr(portForwarderListenFd+i), "port-forward")
+		ip := p.ContainerIP
+		if ip == "" {
+			ip = "127.0.0.1"
+		}
+		target := net.JoinHostPort(ip, strconv.Itoa(int(p.ContainerPort)))
+		if portForwardProtocol(p) == "udp" {
+			conn, err := net.FilePacketConn(f)
+			if err != nil {
+				return err
+			}
+			go forwardUDP(conn, target)
+		} else {
+			l, err := net.FileListener(f)
+			if err != nil {
+				return err
+			}
+			go forwardTCP(l, target)
+		}
+		_ = f.Close()
+	}
+
+	fds := []unix.PollFd{{Fd: portForwarderPidFd, Events: unix.POLLIN}}
+	for {
+		// The pidfd becomes readable once the process exits.
+		_, err := unix.Poll(fds, -1)
+		if errors.Is(err, unix.EINTR) {
+			continue
+		}
+		return os.NewSyscallError("poll", err)
+	}
+}
+
+// forwardTCP forwards the connections accepted by l to target, at most
+// maxPortForwardConns at once.
+func forwardTCP(l net.Listener, target string) {
+	sem := make(chan struct{}, maxPortForwardConns)
+	for {
+		sem <- struct{}{}
+		conn, err := l.Accept()
+		if err != nil {
+			<-sem
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			// Such as EMFILE, which may not last.
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+		go func() {
+			proxyTCP(conn, target)
+			<-sem
+		}()
+	}
+}
+
+// proxyTCP proxies the connection conn to target.
+func proxyTCP(conn net.Conn, target string) {
+	defer conn.Close()
+	dst, err := net.Dial("tcp", target)
+	if err != nil {
+		return
+	}
+	defer dst.Close()
+	done := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(dst, conn)
+		_ = dst.(*net.TCPConn).CloseWrite()
+		close(done)
+	}()
+	_, _ = io.Copy(conn, dst)
+	_ = conn.(*net.TCPConn).CloseWrite()
+	<-done
+}
+
+// forwardUDP forwards the datagrams received on conn to target, and the
+// replies back to their sender. Each sender gets its own socket, so that
+// the replies can be told apart, up to maxPortForwardConns senders at once.
+func forwardUDP(conn net.PacketConn, target string) {
+	var mu sync.Mutex
+	sessions := make(map[string]net.Conn)
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		mu.Lock()
+		dst := sessions[addr.String()]
+		if dst == nil {
+			if len(sessions) >= maxPortForwardConns {
+				mu.Unlock()
+				continue
+			}
+			dst, err = net.Dial("udp", target)
+			if err != nil {
+				mu.Unlock()
+				continue
+			}
+			sessions[addr.String()] = dst
+			go func(dst net.Conn, addr net.Addr) {
+				reply := make([]byte, 65535)
+				for {
+					_ = dst.SetReadDeadline(time.Now().Add(udpSessionTimeout))
+					n, err := dst.Read(reply)
+					if err != nil {
+						break
+					}
+					_, _ = conn.WriteTo(reply[:n], addr)
+				}
+				mu.Lock()
+				delete(sessions, addr.String())
+				mu.Unlock()
+				_ = dst.Close()
+			}(dst, addr)
+		}
+		mu.Unlock()
+		_, _ = dst.Write(buf[:n])
+	}
+}
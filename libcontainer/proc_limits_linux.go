@@ -0,0 +1,280 @@
+package libcontainer
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/szcdx/runc/libcontainer/cgroups"
+	"github.com/szcdx/runc/libcontainer/configs"
+)
+
+// procLimitsDir is the directory, in the state directory of a container,
+// with the files bind mounted in the container if configs.Config.ProcLimits
+// is set.
+const procLimitsDir = "proc-limits"
+
+// procLimitsFiles are the files reflecting the cgroup limits of a container,
+// with the destination they are bind mounted on, and the mount they are
+// bind mounted over.
+var procLimitsFiles = []struct {
+	name, dest, mount string
+	content           func(*configs.Resources, uint64) ([]byte, error)
+}{
+	{"meminfo", "/proc/meminfo", "/proc", procLimitsMeminfo},
+	{"cpuinfo", "/proc/cpuinfo", "/proc", procLimitsCpuinfo},
+	{"cpu-online", "/sys/devices/system/cpu/online", "/sys", procLimitsCPUOnline},
+}
+
+// procLimitsMounts returns the bind mounts of the files reflecting the
+// cgroup limits of the container, out of the ones of procLimitsFiles that
+// apply to mounts, which are the mounts of the container.
+func (c *Container) procLimitsMounts(mounts []*configs.Mount) []*configs.Mount {
+	if !c.config.ProcLimits {
+		return nil
+	}
+	mounted := make(map[string]bool)
+	for _, m := range mounts {
+		mounted[m.Destination] = true
+	}
+	var binds []*configs.Mount
+	for _, f := range procLimitsFiles {
+		// Such as when the container has no /sys, or has its own
+		// files mounted already.
+		if !mounted[f.mount] || mounted[f.dest] {
+			continue
+		}
+		binds = append(binds, &configs.Mount{
+			Source:      filepath.Join(c.stateDir, procLimitsDir, f.name),
+			Destination: f.dest,
+			Device:      "bind",
+			Flags:       unix.MS_BIND | unix.MS_RDONLY | unix.MS_NOSUID | unix.MS_NODEV | unix.MS_NOEXEC,
+		})
+	}
+	return binds
+}
+
+// setupProcLimits writes the files reflecting the cgroup limits of the
+// container, and adds their bind mounts to the configuration passed to runc
+// init.
+func (c *Container) setupProcLimits(cfg *initConfig) error {
+	binds := c.procLimitsMounts(cfg.Config.Mounts)
+	if len(binds) == 0 {
+		return nil
+	}
+	if err := c.writeProcLimits(c.config.Cgroups.Resources); err != nil {
+		return err
+	}
+	config := *cfg.Config
+	config.Mounts = append(append([]*configs.Mount(nil), config.Mounts...), binds...)
+	cfg.Config = &config
+	return nil
+}
+
+// refreshProcLimits rewrites the files reflecting the cgroup limits of the
+// container, if any, such as for a new memory usage to be seen by a process
+// about to be executed in the container. It only warns about errors, as the
+// files are informational.
+func (c *Container) refreshProcLimits(r *configs.Resources) {
+	if !c.config.ProcLimits {
+		return
+	}
+	if err := c.writeProcLimits(r); err != nil {
+		logrus.Warnf("unable to update the proc limits files: %v", err)
+	}
+}
+
+// writeProcLimits writes the files reflecting the cgroup limits r and the
+// current memory usage of the container. The existing files are rewritten
+// in place, so that the new content is seen through their bind mounts.
+func (c *Container) writeProcLimits(r *configs.Resources) error {
+	dir := filepath.Join(c.stateDir, procLimitsDir)
+	if err := os.MkdirAll(dir, 0o711); err != nil {
+		return err
+	}
+	usage := c.memoryUsage()
+	for _, f := range procLimitsFiles {
+		data, err := f.content(r, usage)
+		if err != nil {
+			return fmt.Errorf("proc limits: %s: %w", f.dest, err)
+		}
+		if err := rewriteFile(filepath.Join(dir, f.name), data); err != nil {
+			return fmt.Errorf("proc limits: %w", err)
+		}
+	}
+	return nil
+}
+
+// memoryUsage returns the memory usage of the container cgroup, or 0 if it
+// is unknown, such as before the cgroup is created.
+func (c *Container) memoryUsage() uint64 {
+	if c.cgroupManager == nil {
+		return 0
+	}
+	stats, err := c.cgroupManager.GetStats()
+	if err != nil {
+		return 0
+	}
+	return stats.MemoryStats.Usage.Usage
+}
+
+// rewriteFile writes data to the file at path, over its current content
+// (rather than replacing it with a new file, which its bind mounts would
+// not see). The data is written with a single write, and the file is only
+// then truncated, so that readers get the whole of the new content, as the
+// files are much smaller than a page.
+func rewriteFile(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.WriteAt(data, 0); err != nil {
+		return err
+	}
+	return f.Truncate(int64(len(data)))
+}
+
+func procLimitsMeminfo(r *configs.Resources, usage uint64) ([]byte, error) {
+	host, err := os.ReadFile("/proc/meminfo")
+	if err != nil {
+		return nil, err
+	}
+	return limitMeminfo(host, r, usage), nil
+}
+
+func procLimitsCpuinfo(r *configs.Resources, _ uint64) ([]byte, error) {
+	host, err := os.ReadFile("/proc/cpuinfo")
+	if err != nil {
+		return nil, err
+	}
+	cpus, err := limitCPUs(r)
+	if err != nil {
+		return nil, err
+	}
+	return limitCpuinfo(host, cpus), nil
+}
+
+func procLimitsCPUOnline(r *configs.Resources, _ uint64) ([]byte, error) {
+	cpus, err := limitCPUs(r)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(cgroups.FormatCPUList(cpus) + "\n"), nil
+}
+
+// limitMeminfo returns the host /proc/meminfo with the memory and swap
+// totals capped by the memory limits r, and the free and available memory
+// capped by what is left of the memory limit once usage (in bytes) is taken
+// out of it.
+func limitMeminfo(host []byte, r *configs.Resources, usage uint64) []byte {
+	type field struct {
+		i     int
+		value uint64
+	}
+	lines := strings.Split(string(host), "\n")
+	fields := make(map[string]*field)
+	for i, line := range lines {
+		f := strings.Fields(line)
+		if len(f) != 3 || f[2] != "kB" {
+			continue
+		}
+		v, err := strconv.ParseUint(f[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		fields[strings.TrimSuffix(f[0], ":")] = &field{i: i, value: v}
+	}
+	limit := func(key string, max uint64) {
+		if f := fields[key]; f != nil && f.value > max {
+			f.value = max
+			lines[f.i] = fmt.Sprintf("%-16s%8d kB", key+":", max)
+		}
+	}
+
+	if r.Memory <= 0 {
+		return host
+	}
+	memory := uint64(r.Memory) / 1024
+	limit("MemTotal", memory)
+	if f := fields["MemTotal"]; f != nil {
+		free := uint64(0)
+		if used := usage / 1024; used < f.value {
+			free = f.value - used
+		}
+		limit("MemFree", free)
+		limit("MemAvailable", free)
+	}
+	// The swap limit is the one of memory and swap together.
+	if r.MemorySwap > 0 && r.MemorySwap >= r.Memory {
+		limit("SwapTotal", uint64(r.MemorySwap-r.Memory)/1024)
+		if f := fields["SwapTotal"]; f != nil {
+			limit("SwapFree", f.value)
+		}
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// limitCPUs returns the CPUs of the container, as far as the tools counting
+// them are concerned: the ones of its cpuset (or all the CPUs of the host),
+// but only as many as its CPU quota amounts to.
+func limitCPUs(r *configs.Resources) ([]int, error) {
+	list := r.CpusetCpus
+	if list == "" {
+		online, err := os.ReadFile("/sys/devices/system/cpu/online")
+		if err != nil {
+			return nil, err
+		}
+		list = strings.TrimSpace(string(online))
+	}
+	cpus, err := cgroups.ParseCPUList(list)
+	if err != nil {
+		return nil, err
+	}
+	if r.CpuQuota > 0 {
+		period := r.CpuPeriod
+		if period == 0 {
+			period = 100000
+		}
+		n := int((uint64(r.CpuQuota) + period - 1) / period)
+		if n < len(cpus) {
+			cpus = cpus[:n]
+		}
+	}
+	return cpus, nil
+}
+
+// limitCpuinfo returns the host /proc/cpuinfo with only the processors in
+// cpus. It is returned as is if it is not made of processor entries (as on
+// some architectures).
+func limitCpuinfo(host []byte, cpus []int) []byte {
+	keep := make(map[int]bool, len(cpus))
+	for _, cpu := range cpus {
+		keep[cpu] = true
+	}
+	var out []byte
+	for _, entry := range bytes.SplitAfter(host, []byte("\n\n")) {
+		if len(bytes.TrimSpace(entry)) == 0 {
+			continue
+		}
+		key, value, _ := bytes.Cut(entry, []byte(":"))
+		if !bytes.Equal(bytes.TrimSpace(key), []byte("processor")) {
+			return host
+		}
+		line, _, _ := bytes.Cut(value, []byte("\n"))
+		cpu, err := strconv.Atoi(string(bytes.TrimSpace(line)))
+		if err != nil {
+			return host
+		}
+		if keep[cpu] {
+			out = append(out, entry...)
+		}
+	}
+	return out
+}
@@ -0,0 +1,101 @@
+package libcontainer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/szcdx/runc/libcontainer/configs"
+)
+
+func TestLimitMeminfo(t *testing.T) {
+	host := "MemTotal:       16318872 kB\n" +
+		"MemFree:         8042628 kB\n" +
+		"MemAvailable:   12345678 kB\n" +
+		"Buffers:          123456 kB\n" +
+		"SwapTotal:       2097148 kB\n" +
+		"SwapFree:        2097148 kB\n" +
+		"HugePages_Total:       0\n"
+	r := &configs.Resources{Memory: 1 << 30, MemorySwap: 1<<30 + 1<<20}
+	expected := "MemTotal:        1048576 kB\n" +
+		"MemFree:          786432 kB\n" +
+		"MemAvailable:     786432 kB\n" +
+		"Buffers:          123456 kB\n" +
+		"SwapTotal:          1024 kB\n" +
+		"SwapFree:           1024 kB\n" +
+		"HugePages_Total:       0\n"
+	if out := string(limitMeminfo([]byte(host), r, 256<<20)); out != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, out)
+	}
+
+	// No memory limit.
+	if out := string(limitMeminfo([]byte(host), &configs.Resources{}, 256<<20)); out != host {
+		t.Errorf("expected the host meminfo, got:\n%s", out)
+	}
+}
+
+func TestRewriteFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "meminfo")
+	if err := rewriteFile(path, []byte("long content\n")); err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rewriteFile(path, []byte("short\n")); err != nil {
+		t.Fatal(err)
+	}
+	after, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The file is rewritten in place, for its bind mounts to see it.
+	if !os.SameFile(before, after) {
+		t.Error("expected the same file")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "short\n" {
+		t.Errorf("expected the new content, got %q", data)
+	}
+}
+
+func TestLimitCPUs(t *testing.T) {
+	for _, tc := range []struct {
+		r        configs.Resources
+		expected []int
+	}{
+		{configs.Resources{CpusetCpus: "2-5"}, []int{2, 3, 4, 5}},
+		{configs.Resources{CpusetCpus: "2-5", CpuQuota: 150000}, []int{2, 3}},
+		{configs.Resources{CpusetCpus: "0,3", CpuQuota: 50000, CpuPeriod: 10000}, []int{0, 3}},
+	} {
+		cpus, err := limitCPUs(&tc.r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(cpus, tc.expected) {
+			t.Errorf("%+v: expected %v, got %v", tc.r, tc.expected, cpus)
+		}
+	}
+}
+
+func TestLimitCpuinfo(t *testing.T) {
+	host := "processor\t: 0\nmodel name\t: x\n\n" +
+		"processor\t: 1\nmodel name\t: x\n\n" +
+		"processor\t: 2\nmodel name\t: x\n\n"
+	expected := "processor\t: 0\nmodel name\t: x\n\n" +
+		"processor\t: 2\nmodel name\t: x\n\n"
+	if out := string(limitCpuinfo([]byte(host), []int{0, 2})); out != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, out)
+	}
+
+	// Not made of processor entries.
+	other := "vendor_id       : IBM/S390\n# processors    : 4\n\n"
+	if out := string(limitCpuinfo([]byte(other), []int{0})); out != other {
+		t.Errorf("expected the host cpuinfo, got:\n%s", out)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"math"
 	"os"
 
+	"golang.org/x/sys/unix"
+
 	"github.com/szcdx/runc/libcontainer/configs"
 )
 
@@ -99,7 +101,47 @@ type Process struct {
 	// For cgroup v2, the only key allowed is "".
 	SubCgroupPaths map[string]string
 
+	// Resources, if set, are the limits of the sub-cgroups the process is
+	// run in (see SubCgroupPaths), which are created if they do not exist.
+	// This lets a process, such as a debug shell, be limited apart from
+	// the rest of the container. Device rules are ignored. Only supported
+	// on cgroup v1.
+	Resources *configs.Resources
+
 	Scheduler *configs.Scheduler
+
+	// SeccompNotify, if set, makes libcontainer keep the seccomp notify fd
+	// of the process (created if the seccomp profile has SCMP_ACT_NOTIFY
+	// rules) rather than sending it to the seccomp agent listening on
+	// Seccomp.ListenerPath. The fd can then be obtained by SeccompNotifyFd,
+	// and the notifications handled in-process (see the seccomp/notify
+	// package).
+	SeccompNotify bool
+
+	// Seccomp, if set, is the seccomp profile of the process, used rather
+	// than the one of the container, such as for a debug shell which needs
+	// more syscalls than the container processes. It can only be used for
+	// non-init processes.
+	Seccomp *configs.Seccomp
+
+	// SeccompUnconfined, if set, makes the process run without any seccomp
+	// profile. It can only be used for non-init processes.
+	SeccompUnconfined bool
+
+	// MountFiles are the files which the fd://N mount sources of the
+	// container refer to, N being the index in MountFiles: either detached
+	// mounts (from open_tree(2) or fsmount(2)) or, unless the container is
+	// rootless, any file or directory to bind mount. It can only be used for
+	// the init process.
+	MountFiles []*os.File
+
+	seccompNotifyFd *os.File
+
+	// Exe, if set, is an open handle to the executable to run, rather than
+	// looking up Args[0] inside the container. It is executed using
+	// execveat(2), so it can be a file from the host which is not present
+	// in the container. It can only be used for non-init processes.
+	Exe *os.File
 }
 
 // Wait waits for the process to exit.
@@ -129,6 +171,30 @@ func (p Process) Signal(sig os.Signal) error {
 	return p.ops.signal(sig)
 }
 
+// SeccompNotifyFd returns a new copy of the seccomp notify fd of the
+// process, or nil if there is none. It is only available if SeccompNotify
+// is set, and until the process is waited for. The caller is responsible
+// for closing it.
+func (p Process) SeccompNotifyFd() (*os.File, error) {
+	if p.seccompNotifyFd == nil {
+		return nil, nil
+	}
+	fd, err := unix.FcntlInt(p.seccompNotifyFd.Fd(), unix.F_DUPFD_CLOEXEC, 0)
+	if err != nil {
+		return nil, os.NewSyscallError("fcntl(F_DUPFD_CLOEXEC)", err)
+	}
+	return os.NewFile(uintptr(fd), p.seccompNotifyFd.Name()), nil
+}
+
+// closeSeccompNotifyFd closes the seccomp notify fd kept for the process,
+// once it is waited for. The copies returned by SeccompNotifyFd are left
+// open.
+func (p *Process) closeSeccompNotifyFd() {
+	if p.seccompNotifyFd != nil {
+		_ = p.seccompNotifyFd.Close()
+	}
+}
+
 // closeClonedExes cleans up any existing cloned binaries associated with the
 // Process.
 func (p *Process) closeClonedExes() {
@@ -10,6 +10,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"strconv"
 	"sync"
@@ -21,7 +22,10 @@ import (
 
 	"github.com/szcdx/runc/libcontainer/cgroups"
 	"github.com/szcdx/runc/libcontainer/cgroups/fs2"
+	"github.com/szcdx/runc/libcontainer/cgroups/fscommon"
+	"github.com/szcdx/runc/libcontainer/cgroups/manager"
 	"github.com/szcdx/runc/libcontainer/configs"
+	"github.com/szcdx/runc/libcontainer/firewall"
 	"github.com/szcdx/runc/libcontainer/intelrdt"
 	"github.com/szcdx/runc/libcontainer/logs"
 	"github.com/szcdx/runc/libcontainer/system"
@@ -102,11 +106,15 @@ type setnsProcess struct {
 	rootlessCgroups bool
 	manager         cgroups.Manager
 	intelRdtPath    string
+	intelRdtMonPath string
 	config          *initConfig
 	fds             []string
 	process         *Process
 	bootstrapData   io.Reader
 	initProcessPid  int
+	// createdCgroups are the sub-cgroups created for the process, which are
+	// removed once it exits.
+	createdCgroups []string
 }
 
 func (p *setnsProcess) startTime() (uint64, error) {
@@ -114,6 +122,98 @@ func (p *setnsProcess) startTime() (uint64, error) {
 	return stat.StartTime, err
 }
 
+// setSubCgroupResources creates the sub-cgroups of the process, if needed,
+// and sets the limits of the process on them. An existing sub-cgroup is only
+// reused if it has the same limits, so that these are not changed under the
+// feet of the processes already in it.
+func (p *setnsProcess) setSubCgroupResources() error {
+	paths := make(map[string]string)
+	for ctrl, path := range p.cgroupPaths {
+		if path != p.manager.Path(ctrl) {
+			paths[ctrl] = path
+		}
+	}
+	if len(paths) == 0 {
+		return errors.New("no sub-cgroup to set the process resources on")
+	}
+	r := *p.process.Resources
+	r.SkipDevices = true
+	existing := false
+	for _, path := range paths {
+		if _, err := os.Stat(path); err == nil {
+			existing = true
+			break
+		}
+	}
+	if existing {
+		return checkSubCgroupResources(paths, &r)
+	}
+	m, err := manager.NewWithPaths(&configs.Cgroup{
+		Resources: &r,
+		Rootless:  p.rootlessCgroups,
+	}, paths)
+	if err != nil {
+		return err
+	}
+	// Only create the cgroups, the process is added to them afterwards.
+	// Apply skips some controllers (such as devices), so create them all.
+	for _, path := range paths {
+		if err := os.MkdirAll(path, 0o755); err != nil {
+			return err
+		}
+		p.createdCgroups = append(p.createdCgroups, path)
+	}
+	if err := m.Apply(-1); err != nil {
+		return err
+	}
+	return m.Set(&r)
+}
+
+// checkSubCgroupResources returns an error if the limits of the existing
+// (cgroup v1) sub-cgroups at paths are not the ones of r, or if r has limits
+// other than the memory and CPU quota ones, which are not compared.
+func checkSubCgroupResources(paths map[string]string, r *configs.Resources) error {
+	other := *r
+	other.Memory, other.CpuQuota, other.SkipDevices = 0, 0, false
+	if !reflect.DeepEqual(other, configs.Resources{}) {
+		return errors.New("an existing sub-cgroup can only be used with process resources limited to the memory and CPU quota")
+	}
+	for _, limit := range []struct {
+		ctrl, file string
+		value      int64
+	}{
+		{ctrl: "memory", file: "memory.limit_in_bytes", value: r.Memory},
+		{ctrl: "cpu", file: "cpu.cfs_quota_us", value: r.CpuQuota},
+	} {
+		if limit.value == 0 {
+			continue
+		}
+		path, ok := paths[limit.ctrl]
+		if !ok {
+			continue
+		}
+		cur, err := fscommon.GetCgroupParamInt(path, limit.file)
+		if err != nil {
+			return err
+		}
+		if cur != limit.value {
+			return fmt.Errorf("sub-cgroup %s already exists with a different %s (%d)", path, limit.file, cur)
+		}
+	}
+	return nil
+}
+
+// removeSubCgroups removes the sub-cgroups created for the process, unless
+// other processes were put in them since.
+func (p *setnsProcess) removeSubCgroups() {
+	for _, path := range p.createdCgroups {
+		if err := unix.Rmdir(path); err != nil && err != unix.EBUSY && err != unix.ENOENT { //nolint:errorlint // unix errors are bare
+			logrus.WithError(err).Warnf("unable to remove sub-cgroup %s", path)
+		}
+	}
+	p.createdCgroups = nil
+}
+
 func (p *setnsProcess) signal(sig os.Signal) error {
 	s, ok := sig.(unix.Signal)
 	if !ok {
@@ -163,6 +263,11 @@ func (p *setnsProcess) start() (retErr error) {
 	if err := p.execSetns(); err != nil {
 		return fmt.Errorf("error executing setns process: %w", err)
 	}
+	if p.process.Resources != nil {
+		if err := p.setSubCgroupResources(); err != nil {
+			return fmt.Errorf("error setting up sub-cgroup: %w", err)
+		}
+	}
 	for _, path := range p.cgroupPaths {
 		if err := cgroups.WriteCgroupProc(path, p.pid()); err != nil && !p.rootlessCgroups {
 			// On cgroup v2 + nesting + domain controllers, WriteCgroupProc may fail with EBUSY.
@@ -193,6 +298,11 @@ func (p *setnsProcess) start() (retErr error) {
 			if err := intelrdt.WriteIntelRdtTasks(p.intelRdtPath, p.pid()); err != nil {
 				return fmt.Errorf("error adding pid %d to Intel RDT: %w", p.pid(), err)
 			}
+			if p.intelRdtMonPath != "" {
+				if err := intelrdt.WriteIntelRdtTasks(p.intelRdtMonPath, p.pid()); err != nil {
+					return fmt.Errorf("error adding pid %d to Intel RDT monitoring group: %w", p.pid(), err)
+				}
+			}
 		}
 	}
 	// set rlimits, this has to be done here because we lose permissions
@@ -216,7 +326,7 @@ func (p *setnsProcess) start() (retErr error) {
 			// This shouldn't happen.
 			panic("unexpected procMountPlease in setns")
 		case procSeccomp:
-			if p.config.Config.Seccomp.ListenerPath == "" {
+			if p.config.Config.Seccomp.ListenerPath == "" && !p.process.SeccompNotify {
 				return errors.New("seccomp listenerPath is not set")
 			}
 			if sync.Arg == nil {
@@ -230,6 +340,11 @@ func (p *setnsProcess) start() (retErr error) {
 			if err != nil {
 				return fmt.Errorf("sync %q get fd %d from child failed: %w", sync.Type, srcFd, err)
 			}
+			if p.process.SeccompNotify {
+				// Keep the fd for the caller to handle notifications.
+				p.process.seccompNotifyFd = seccompFd
+				return writeSync(p.comm.syncSockParent, procSeccompDone)
+			}
 			defer seccompFd.Close()
 			// We have a copy, the child can keep working. We don't need to
 			// wait for the seccomp notify listener to get the fd before we
@@ -326,6 +441,8 @@ func (p *setnsProcess) terminate() error {
 
 func (p *setnsProcess) wait() (*os.ProcessState, error) {
 	err := p.cmd.Wait()
+	p.removeSubCgroups()
+	p.process.closeSeccompNotifyFd()
 
 	// Return actual ProcessState even on Wait error
 	return p.cmd.ProcessState, err
@@ -520,6 +637,18 @@ func (p *initProcess) start() (retErr error) {
 		return fmt.Errorf("unable to start init: %w", err)
 	}
 
+	// The cgroups (and the systemd unit, if any) and the Intel RDT group
+	// are created by Apply below, and can only be removed after the init
+	// is terminated.
+	var j journal
+	j.record("remove cgroups", p.manager.Destroy)
+	if p.intelRdtManager != nil {
+		j.record("remove Intel RDT group", p.intelRdtManager.Destroy)
+	}
+	j.record("terminate init", func() error {
+		return ignoreTerminateErrors(p.terminate())
+	})
+
 	waitInit := initWaiter(p.comm.initSockParent)
 	defer func() {
 		if retErr != nil {
@@ -548,14 +677,8 @@ func (p *initProcess) start() (retErr error) {
 				logrus.WithError(werr).Warn()
 			}
 
-			// Terminate the process to ensure we can remove cgroups.
-			if err := ignoreTerminateErrors(p.terminate()); err != nil {
-				logrus.WithError(err).Warn("unable to terminate initProcess")
-			}
-
-			_ = p.manager.Destroy()
-			if p.intelRdtManager != nil {
-				_ = p.intelRdtManager.Destroy()
+			if err := j.rollback(); err != nil {
+				logrus.Warn(err)
 			}
 		}
 	}()
@@ -583,6 +706,14 @@ func (p *initProcess) start() (retErr error) {
 	if err != nil {
 		return fmt.Errorf("can't get final child's PID from pipe: %w", err)
 	}
+	// In the read-only degraded mode, there is no cgroup to find the
+	// processes of the container from, so tell the manager which one is
+	// the init process instead.
+	if p.container.config.Cgroups.ReadOnlyDegraded {
+		if err := p.manager.Apply(childPid); err != nil {
+			return fmt.Errorf("unable to apply cgroup configuration: %w", err)
+		}
+	}
 
 	// Save the standard descriptor names before the container process
 	// can potentially move them (e.g., via dup2()).  If we don't do this now,
@@ -617,6 +748,27 @@ func (p *initProcess) start() (retErr error) {
 	if err := p.createNetworkInterfaces(); err != nil {
 		return fmt.Errorf("error creating network interfaces: %w", err)
 	}
+	if p.config.Config.RootlessNetwork != nil {
+		n, err := p.container.startRootlessNetwork(p.pid())
+		if err != nil {
+			return err
+		}
+		j.record("stop rootless network", n.stop)
+		p.container.rootlessNetwork = n
+	}
+	if len(p.config.Config.PortForwards) > 0 {
+		fw, err := p.container.startPortForwarder(p.pid())
+		if err != nil {
+			return err
+		}
+		j.record("stop port forwarder", fw.stop)
+		p.container.portForwarder = fw
+	}
+	if fw := p.config.Config.Firewall; fw != nil {
+		if err := firewall.Apply("/proc/"+strconv.Itoa(p.pid())+"/ns/net", p.container.id, fw); err != nil {
+			return err
+		}
+	}
 	if err := p.updateSpecState(); err != nil {
 		return fmt.Errorf("error updating spec state: %w", err)
 	}
@@ -628,9 +780,6 @@ func (p *initProcess) start() (retErr error) {
 	ierr := parseSync(p.comm.syncSockParent, func(sync *syncT) error {
 		switch sync.Type {
 		case procMountPlease:
-			if mountRequest == nil {
-				return fmt.Errorf("cannot fulfil mount requests as a rootless user")
-			}
 			var m *configs.Mount
 			if sync.Arg == nil {
 				return fmt.Errorf("sync %q is missing an argument", sync.Type)
@@ -638,7 +787,22 @@ func (p *initProcess) start() (retErr error) {
 			if err := json.Unmarshal(*sync.Arg, &m); err != nil {
 				return fmt.Errorf("sync %q passed invalid mount arg: %w", sync.Type, err)
 			}
-			mnt, err := mountRequest(m)
+			var (
+				mnt *mountSource
+				err error
+			)
+			switch {
+			case m.Driver != "":
+				// Run in the host mount namespace, for the driver to
+				// have access to whatever it needs to create the mount.
+				mnt, err = runMountDriver(p.container.ID(), m)
+			case m.IsFdSource():
+				mnt, err = passedMountFd(p.process.MountFiles, m, p.container.config.RootlessEUID)
+			case mountRequest == nil:
+				return fmt.Errorf("cannot fulfil mount requests as a rootless user")
+			default:
+				mnt, err = mountRequest(m)
+			}
 			if err != nil {
 				return fmt.Errorf("failed to fulfil mount request: %w", err)
 			}
@@ -657,7 +821,7 @@ func (p *initProcess) start() (retErr error) {
 				return err
 			}
 		case procSeccomp:
-			if p.config.Config.Seccomp.ListenerPath == "" {
+			if p.config.Config.Seccomp.ListenerPath == "" && !p.process.SeccompNotify {
 				return errors.New("seccomp listenerPath is not set")
 			}
 			var srcFd int
@@ -671,6 +835,11 @@ func (p *initProcess) start() (retErr error) {
 			if err != nil {
 				return fmt.Errorf("sync %q get fd %d from child failed: %w", sync.Type, srcFd, err)
 			}
+			if p.process.SeccompNotify {
+				// Keep the fd for the caller to handle notifications.
+				p.process.seccompNotifyFd = seccompFd
+				return writeSync(p.comm.syncSockParent, procSeccompDone)
+			}
 			defer seccompFd.Close()
 			// We have a copy, the child can keep working. We don't need to
 			// wait for the seccomp notify listener to get the fd before we
@@ -699,6 +868,13 @@ func (p *initProcess) start() (retErr error) {
 				containerProcessState, seccompFd); err != nil {
 				return err
 			}
+			if p.config.Config.Seccomp.KeepListenerFd {
+				holder, err := startSeccompHolder(p.pid(), seccompFd)
+				if err != nil {
+					return err
+				}
+				p.container.seccompHolder = holder
+			}
 		case procReady:
 			seenProcReady = true
 			// set rlimits, this has to be done here because we lose permissions
@@ -783,6 +959,7 @@ func (p *initProcess) start() (retErr error) {
 
 func (p *initProcess) wait() (*os.ProcessState, error) {
 	err := p.cmd.Wait()
+	p.process.closeSeccompNotifyFd()
 	return p.cmd.ProcessState, err
 }
 
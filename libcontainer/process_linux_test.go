@@ -0,0 +1,87 @@
+package libcontainer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/szcdx/runc/libcontainer/cgroups"
+	"github.com/szcdx/runc/libcontainer/configs"
+)
+
+func TestCheckSubCgroupResources(t *testing.T) {
+	cgroups.TestMode = true
+	memory, cpu := t.TempDir(), t.TempDir()
+	if err := os.WriteFile(filepath.Join(memory, "memory.limit_in_bytes"), []byte("67108864\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(cpu, "cpu.cfs_quota_us"), []byte("-1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	paths := map[string]string{"memory": memory, "cpu": cpu}
+	for _, tc := range []struct {
+		name  string
+		r     configs.Resources
+		isErr bool
+	}{
+		{name: "same memory", r: configs.Resources{Memory: 64 << 20}},
+		{name: "different memory", r: configs.Resources{Memory: 32 << 20}, isErr: true},
+		{name: "different quota", r: configs.Resources{Memory: 64 << 20, CpuQuota: 20000}, isErr: true},
+		{name: "no limits"},
+		{name: "other limits", r: configs.Resources{Memory: 64 << 20, PidsLimit: 10}, isErr: true},
+	} {
+		err := checkSubCgroupResources(paths, &tc.r)
+		if tc.isErr && err == nil {
+			t.Errorf("%s: expected error, got nil", tc.name)
+		} else if !tc.isErr && err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+	}
+}
+
+func TestProcessSeccompNotifyFd(t *testing.T) {
+	var p Process
+	if f, err := p.SeccompNotifyFd(); f != nil || err != nil {
+		t.Fatalf("expected no fd, got %v, %v", f, err)
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	p.seccompNotifyFd = r
+
+	// Every call returns a new copy, which can be closed on its own.
+	f1, err := p.SeccompNotifyFd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f2, err := p.SeccompNotifyFd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f2.Close()
+	if f1.Fd() == r.Fd() || f1.Fd() == f2.Fd() {
+		t.Fatalf("expected new fds, got %d and %d (kept %d)", f1.Fd(), f2.Fd(), r.Fd())
+	}
+	f1.Close()
+	f3, err := p.SeccompNotifyFd()
+	if err != nil {
+		t.Fatalf("kept fd closed with its copy: %v", err)
+	}
+	f3.Close()
+
+	// Once the kept fd is closed, no more copies are available, but the
+	// existing ones are still usable.
+	p.closeSeccompNotifyFd()
+	if _, err := p.SeccompNotifyFd(); err == nil {
+		t.Fatal("expected error once the fd is closed, got nil")
+	}
+	if _, err := w.Write([]byte("x")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f2.Read(make([]byte, 1)); err != nil {
+		t.Fatalf("copy closed with the kept fd: %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+package libcontainer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/szcdx/runc/libcontainer/cgroups"
+	"github.com/szcdx/runc/libcontainer/system"
+)
+
+// ProcessInfo is a process of the container, as returned by ProcessTree.
+type ProcessInfo struct {
+	// Pid is the PID of the process, in the PID namespace of the caller.
+	Pid int `json:"pid"`
+	// PPid is the PID of the parent of the process, which is not
+	// necessarily a process of the container.
+	PPid int `json:"ppid"`
+	// StartTime is the time the process started at, in clock ticks since
+	// the system boot (see proc(5)).
+	StartTime uint64 `json:"start_time"`
+	// Command is the command line of the process, or its name in square
+	// brackets if it has none (for example, if it is a zombie).
+	Command string `json:"command"`
+	// Cgroup is the cgroup of the process, relative to the container's
+	// cgroup ("/" for the container's cgroup itself).
+	Cgroup string `json:"cgroup"`
+	// Children are the child processes of the process, by PID.
+	Children []*ProcessInfo `json:"children,omitempty"`
+}
+
+// ProcessTree returns the processes of the container as a forest: the
+// processes the parents of which are not in the container (normally, the
+// container's init and the processes started by exec) are the roots, and
+// every process has its children in the container. The roots are sorted by
+// their start time, and the children by PID.
+//
+// As with Processes, the processes may exit (or new ones may be started)
+// while the tree is being built, unless the container is paused.
+func (c *Container) ProcessTree() ([]*ProcessInfo, error) {
+	path := c.cgroupManager.Path("")
+	if !cgroups.IsCgroup2UnifiedMode() {
+		path = c.cgroupManager.Path("devices")
+	}
+	if path == "" {
+		return nil, errors.New("unable to get container process tree: no cgroup path")
+	}
+	pids, err := cgroups.GetAllPidsByCgroup(path)
+	if err = c.ignoreCgroupError(err); err != nil {
+		return nil, fmt.Errorf("unable to get container process tree: %w", err)
+	}
+	return buildProcessTree(pids, readProcessInfo)
+}
+
+// buildProcessTree builds the process tree of pids (mapped to their cgroups)
+// using info to get the information about a process. The processes for which
+// info returns os.ErrNotExist are gone, and are left out.
+func buildProcessTree(pids map[int]string, info func(pid int) (*ProcessInfo, error)) ([]*ProcessInfo, error) {
+	procs := make(map[int]*ProcessInfo, len(pids))
+	for pid, cgroup := range pids {
+		p, err := info(pid)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		p.Cgroup = cgroup
+		procs[pid] = p
+	}
+
+	var roots []*ProcessInfo
+	for _, p := range procs {
+		if parent, ok := procs[p.PPid]; ok && p.PPid != p.Pid {
+			parent.Children = append(parent.Children, p)
+		} else {
+			roots = append(roots, p)
+		}
+	}
+	for _, p := range procs {
+		sort.Slice(p.Children, func(i, j int) bool {
+			return p.Children[i].Pid < p.Children[j].Pid
+		})
+	}
+	sort.Slice(roots, func(i, j int) bool {
+		if roots[i].StartTime != roots[j].StartTime {
+			return roots[i].StartTime < roots[j].StartTime
+		}
+		return roots[i].Pid < roots[j].Pid
+	})
+	return roots, nil
+}
+
+// readProcessInfo reads the information about the process from procfs.
+func readProcessInfo(pid int) (*ProcessInfo, error) {
+	stat, err := system.Stat(pid)
+	if err != nil {
+		return nil, err
+	}
+	p := &ProcessInfo{
+		Pid:       pid,
+		PPid:      stat.PPid,
+		StartTime: stat.StartTime,
+	}
+	cmdline, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/cmdline")
+	if err != nil {
+		return nil, err
+	}
+	cmdline = bytes.TrimRight(cmdline, "\x00")
+	if len(cmdline) > 0 {
+		p.Command = strings.ReplaceAll(string(cmdline), "\x00", " ")
+	} else {
+		p.Command = "[" + stat.Name + "]"
+	}
+	return p, nil
+}
@@ -0,0 +1,65 @@
+package libcontainer
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestBuildProcessTree(t *testing.T) {
+	procs := map[int]*ProcessInfo{
+		// init and its children
+		10: {PPid: 1, StartTime: 100, Command: "init"},
+		12: {PPid: 10, StartTime: 102, Command: "b"},
+		11: {PPid: 10, StartTime: 101, Command: "a"},
+		13: {PPid: 11, StartTime: 103, Command: "c"},
+		// an exec'd process, started by runc
+		20: {PPid: 5, StartTime: 90, Command: "exec"},
+	}
+	pids := map[int]string{10: "/", 11: "/", 12: "/sub", 13: "/sub", 20: "/", 30: "/"}
+	info := func(pid int) (*ProcessInfo, error) {
+		p, ok := procs[pid]
+		if !ok {
+			// The process is gone.
+			return nil, os.ErrNotExist
+		}
+		p.Pid = pid
+		return p, nil
+	}
+
+	roots, err := buildProcessTree(pids, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dump func(ps []*ProcessInfo) string
+	dump = func(ps []*ProcessInfo) string {
+		s := ""
+		for _, p := range ps {
+			s += fmt.Sprintf("%d:%s:%s[%s]", p.Pid, p.Command, p.Cgroup, dump(p.Children))
+		}
+		return s
+	}
+	expected := "20:exec:/[]10:init:/[11:a:/[13:c:/sub[]]12:b:/sub[]]"
+	if got := dump(roots); got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+
+	if _, err := buildProcessTree(pids, func(int) (*ProcessInfo, error) {
+		return nil, os.ErrPermission
+	}); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestReadProcessInfo(t *testing.T) {
+	p, err := readProcessInfo(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Pid != os.Getpid() || p.PPid != os.Getppid() {
+		t.Errorf("expected pid %d, ppid %d, got %+v", os.Getpid(), os.Getppid(), p)
+	}
+	if p.Command == "" || p.StartTime == 0 {
+		t.Errorf("expected command and start time, got %+v", p)
+	}
+}
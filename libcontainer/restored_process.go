@@ -2,9 +2,13 @@ package libcontainer
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"os/exec"
 
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
 	"github.com/szcdx/runc/libcontainer/system"
 )
 
@@ -15,17 +19,27 @@ func newRestoredProcess(cmd *exec.Cmd, fds []string) (*restoredProcess, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &restoredProcess{
+	p := &restoredProcess{
 		cmd:              cmd,
 		processStartTime: stat.StartTime,
 		fds:              fds,
-	}, nil
+	}
+	// The process is not resumed yet, so it can't have been reaped.
+	if fd, err := unix.PidfdOpen(pid, 0); err == nil {
+		p.pidfd = os.NewFile(uintptr(fd), "pidfd")
+		trackFd(fd, "restored process pidfd", "", "anon_inode:[pidfd]")
+	} else {
+		logrus.Debugf("unable to open a pidfd of restored process %d: %v", pid, err)
+	}
+	return p, nil
 }
 
 type restoredProcess struct {
 	cmd              *exec.Cmd
 	processStartTime uint64
 	fds              []string
+	// pidfd refers to the restored process, if pidfd_open(2) is supported.
+	pidfd *os.File
 }
 
 func (p *restoredProcess) start() error {
@@ -45,8 +59,23 @@ func (p *restoredProcess) terminate() error {
 }
 
 func (p *restoredProcess) wait() (*os.ProcessState, error) {
-	// TODO: how do we wait on the actual process?
-	// maybe use --exec-cmd in criu
+	// The restored init is a child of the calling process, as criu restores
+	// it as its own sibling (see RstSibling), and cmd.Process has been
+	// replaced by it. Its exit can also be noticed without waiting for it
+	// (see Container.NotifyExit), for a caller reaping its children itself.
+	//
+	// Wait for it through its pidfd first, without reaping it, so that if
+	// it has been reaped already (and its pid possibly reused), this fails
+	// rather than waiting for another process by its pid.
+	if p.pidfd != nil {
+		err := waitPidfd(p.pidfd)
+		closeTracked(p.pidfd)
+		p.pidfd = nil
+		if err != nil {
+			p.waitCopying()
+			return nil, fmt.Errorf("unable to wait for restored process %d: %w", p.pid(), err)
+		}
+	}
 	err := p.cmd.Wait()
 	if err != nil {
 		var exitErr *exec.ExitError
@@ -58,11 +87,40 @@ func (p *restoredProcess) wait() (*os.ProcessState, error) {
 	return st, nil
 }
 
+// waitCopying waits for the copying of the stdio of the process to be done,
+// without waiting for the process itself, which has been reaped already.
+func (p *restoredProcess) waitCopying() {
+	proc := p.cmd.Process
+	// A released process is not waited for.
+	gone, err := os.FindProcess(proc.Pid)
+	if err != nil {
+		return
+	}
+	_ = gone.Release()
+	p.cmd.Process = gone
+	_ = p.cmd.Wait()
+	p.cmd.Process = proc
+}
+
+// waitPidfd waits for the child process referred to by pidfd to exit,
+// leaving it waitable.
+func waitPidfd(pidfd *os.File) error {
+	for {
+		err := unix.Waitid(unix.P_PIDFD, int(pidfd.Fd()), nil, unix.WEXITED|unix.WNOWAIT, nil)
+		if err != unix.EINTR { //nolint:errorlint // unix errors are bare
+			return os.NewSyscallError("waitid", err)
+		}
+	}
+}
+
 func (p *restoredProcess) startTime() (uint64, error) {
 	return p.processStartTime, nil
 }
 
 func (p *restoredProcess) signal(s os.Signal) error {
+	if p.pidfd != nil {
+		return pidfdSignal(p.pidfd, s)
+	}
 	return p.cmd.Process.Signal(s)
 }
 
@@ -108,11 +166,9 @@ func (p *nonChildProcess) startTime() (uint64, error) {
 }
 
 func (p *nonChildProcess) signal(s os.Signal) error {
-	proc, err := os.FindProcess(p.processPid)
-	if err != nil {
-		return err
-	}
-	return proc.Signal(s)
+	// The process is not waited for by the calling process, so its pid
+	// could have been reused by another process once it has exited.
+	return signalProcess(p.processPid, p.processStartTime, s)
 }
 
 func (p *nonChildProcess) externalDescriptors() []string {
@@ -0,0 +1,51 @@
+package libcontainer
+
+import (
+	"errors"
+	"os/exec"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func startRestoredProcess(t *testing.T, script string) *restoredProcess {
+	t.Helper()
+	cmd := exec.Command("sh", "-c", script)
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	p, err := newRestoredProcess(cmd, nil)
+	if err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		t.Fatal(err)
+	}
+	if p.pidfd == nil {
+		_ = p.terminate()
+		t.Skip("pidfd_open is not supported")
+	}
+	return p
+}
+
+func TestRestoredProcessWait(t *testing.T) {
+	p := startRestoredProcess(t, "sleep 0.1; exit 7")
+	st, err := p.wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.ExitCode() != 7 {
+		t.Fatalf("expected exit code 7, got %d", st.ExitCode())
+	}
+}
+
+func TestRestoredProcessWaitReaped(t *testing.T) {
+	p := startRestoredProcess(t, "exit 3")
+	// Reaped elsewhere, as by the reaping loop of a subreaper.
+	var ws unix.WaitStatus
+	if _, err := unix.Wait4(p.pid(), &ws, 0, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.wait(); !errors.Is(err, unix.ECHILD) {
+		t.Fatalf("expected ECHILD, got %v", err)
+	}
+}
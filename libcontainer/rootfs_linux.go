@@ -7,6 +7,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"syscall"
@@ -101,6 +102,11 @@ func prepareRootfs(pipe *syncSocket, iConfig *initConfig) (err error) {
 	if err := prepareRoot(config); err != nil {
 		return fmt.Errorf("error preparing rootfs: %w", err)
 	}
+	if config.Time.TimezoneSource() != "" {
+		if err := replaceLocaltimeSymlink(config.Rootfs); err != nil {
+			return fmt.Errorf("error replacing %s symlink: %w", configs.LocaltimePath, err)
+		}
+	}
 
 	mountConfig := &mountConfig{
 		root:            config.Rootfs,
@@ -112,11 +118,13 @@ func prepareRootfs(pipe *syncSocket, iConfig *initConfig) (err error) {
 	for _, m := range config.Mounts {
 		entry := mountEntry{Mount: m}
 		// Figure out whether we need to request runc to give us an
-		// open_tree(2)-style mountfd. For idmapped mounts, this is always
-		// necessary. For bind-mounts, this is only necessary if we cannot
-		// resolve the parent mount (this is only hit if you are running in a
-		// userns -- but for rootless the host-side thread can't help).
-		wantSourceFile := m.IsIDMapped()
+		// open_tree(2)-style mountfd. For idmapped mounts, mounts made by a
+		// mount driver and mounts of a file descriptor passed to runc, this
+		// is always necessary. For bind-mounts, this is only necessary if we
+		// cannot resolve the parent mount (this is only hit if you are
+		// running in a userns -- but for rootless the host-side thread can't
+		// help).
+		wantSourceFile := m.IsIDMapped() || m.Driver != "" || m.IsFdSource()
 		if m.IsBind() && !config.RootlessEUID {
 			if _, err := os.Stat(m.Source); err != nil {
 				wantSourceFile = true
@@ -385,9 +393,7 @@ func mountCgroupV2(m *configs.Mount, c *mountConfig) error {
 		//
 		// Mask `/sys/fs/cgroup` to ensure it is read-only, even when `/sys` is mounted
 		// with `rbind,ro` (`runc spec --rootless` produces `rbind,ro` for `/sys`).
-		err = utils.WithProcfd(c.root, m.Destination, func(procfd string) error {
-			return maskPath(procfd, c.label)
-		})
+		err = maskPath(c.root, m.Destination, c.label)
 	}
 	return err
 }
@@ -482,7 +488,16 @@ func statfsToMountFlags(st unix.Statfs_t) int {
 	return flags
 }
 
+// mountToRootfs mounts m in the container rootfs, and applies its
+// recursive mount attributes (such as rro) to the whole mount tree there.
 func mountToRootfs(c *mountConfig, m mountEntry) error {
+	if err := mountEntryToRootfs(c, m); err != nil {
+		return err
+	}
+	return setRecAttr(m.Mount, c.root)
+}
+
+func mountEntryToRootfs(c *mountConfig, m mountEntry) error {
 	rootfs := c.root
 
 	// procfs and sysfs are special because we need to ensure they are actually
@@ -672,7 +687,7 @@ func mountToRootfs(c *mountConfig, m mountEntry) error {
 				return err
 			}
 		}
-		return setRecAttr(m.Mount, rootfs)
+		return nil
 	case "cgroup":
 		if cgroups.IsCgroup2UnifiedMode() {
 			return mountCgroupV2(m.Mount, c)
@@ -808,6 +823,29 @@ func checkProcMount(rootfs, dest string, m mountEntry) error {
 	return fmt.Errorf("%q cannot be mounted because it is inside /proc", dest)
 }
 
+// replaceLocaltimeSymlink removes the /etc/localtime symlink of the rootfs,
+// if any, so that the timezone file is bind mounted onto a file created in
+// its place, rather than onto the symlink target. The target is a file of
+// the container's timezone database, which is to be left as is.
+func replaceLocaltimeSymlink(rootfs string) error {
+	dir, err := securejoin.SecureJoin(rootfs, filepath.Dir(configs.LocaltimePath))
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, filepath.Base(configs.LocaltimePath))
+	fi, err := os.Lstat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if fi.Mode()&os.ModeSymlink == 0 {
+		return nil
+	}
+	return os.Remove(path)
+}
+
 func setupDevSymlinks(rootfs string) error {
 	// In theory, these should be links to /proc/thread-self, but systems
 	// expect these to be /proc/self and this matches how most distributions
@@ -1199,29 +1237,33 @@ func createIfNotExists(path string, isDir bool) error {
 	return nil
 }
 
-// readonlyPath will make a path read only.
+// readonlyPath will make a path (inside the container root, which must be
+// the current root) read only.
 func readonlyPath(path string) error {
-	if err := mount(path, path, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+	err := utils.WithProcfd("/", path, func(procfd string) error {
+		return mountViaFds(path, nil, path, procfd, "", unix.MS_BIND|unix.MS_REC, "")
+	})
+	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
 			return nil
 		}
 		return err
 	}
 
-	var s unix.Statfs_t
-	if err := unix.Statfs(path, &s); err != nil {
-		return &os.PathError{Op: "statfs", Path: path, Err: err}
-	}
-	flags := uintptr(s.Flags) & (unix.MS_NOSUID | unix.MS_NODEV | unix.MS_NOEXEC)
-
-	if err := mount(path, path, "", flags|unix.MS_BIND|unix.MS_REMOUNT|unix.MS_RDONLY, ""); err != nil {
-		return err
-	}
-
-	return nil
+	// Reopen the path, to get to the bind mount just made rather than to
+	// what it is on top of.
+	return utils.WithProcfd("/", path, func(procfd string) error {
+		var s unix.Statfs_t
+		if err := unix.Statfs(procfd, &s); err != nil {
+			return &os.PathError{Op: "statfs", Path: path, Err: err}
+		}
+		flags := uintptr(s.Flags) & (unix.MS_NOSUID | unix.MS_NODEV | unix.MS_NOEXEC)
+		return mountViaFds(path, nil, path, procfd, "", flags|unix.MS_BIND|unix.MS_REMOUNT|unix.MS_RDONLY, "")
+	})
 }
 
-// remountReadonly will remount an existing mount point and ensure that it is read-only.
+// remountReadonly will remount an existing mount point (inside the container
+// root, which must be the current root) and ensure that it is read-only.
 func remountReadonly(m *configs.Mount) error {
 	var (
 		dest  = m.Destination
@@ -1235,7 +1277,10 @@ func remountReadonly(m *configs.Mount) error {
 		// nosuid, etc.). So, let's use that case so that we can do
 		// this re-mount without failing in a userns.
 		flags |= unix.MS_REMOUNT | unix.MS_BIND | unix.MS_RDONLY
-		if err := mount("", dest, "", uintptr(flags), ""); err != nil {
+		err := utils.WithProcfd("/", dest, func(procfd string) error {
+			return mountViaFds("", nil, dest, procfd, "", uintptr(flags), "")
+		})
+		if err != nil {
 			if errors.Is(err, unix.EBUSY) {
 				time.Sleep(100 * time.Millisecond)
 				continue
@@ -1249,15 +1294,19 @@ func remountReadonly(m *configs.Mount) error {
 
 // maskPath masks the top of the specified path inside a container to avoid
 // security issues from processes reading information from non-namespace aware
-// mounts ( proc/kcore ).
+// mounts ( proc/kcore ). The path is resolved within root.
 // For files, maskPath bind mounts /dev/null over the top of the specified path.
 // For directories, maskPath mounts read-only tmpfs over the top of the specified path.
-func maskPath(path string, mountLabel string) error {
-	if err := mount("/dev/null", path, "", unix.MS_BIND, ""); err != nil && !errors.Is(err, os.ErrNotExist) {
+func maskPath(root, path, mountLabel string) error {
+	err := utils.WithProcfd(root, path, func(procfd string) error {
+		err := mountViaFds("/dev/null", nil, path, procfd, "", unix.MS_BIND, "")
 		if errors.Is(err, unix.ENOTDIR) {
-			return mount("tmpfs", path, "tmpfs", unix.MS_RDONLY, label.FormatMountLabel("", mountLabel))
+			return mountViaFds("tmpfs", nil, path, procfd, "tmpfs", unix.MS_RDONLY, label.FormatMountLabel("", mountLabel))
 		}
 		return err
+	})
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
 	}
 	return nil
 }
@@ -1269,6 +1318,39 @@ func writeSystemProperty(key, value string) error {
 	return os.WriteFile(path.Join("/proc/sys", keyPath), []byte(value), 0o644)
 }
 
+// writeSysctls writes the sysctls, in batches of the same namespace (in the
+// order of configs.NamespaceTypes), the sysctls of the typed network ones
+// last. All of them are written even if some fail, and the ones which
+// failed are returned in a *configs.SysctlError.
+func writeSysctls(sysctl map[string]string, netSysctl map[string]string) error {
+	batches := make(map[configs.NamespaceType][]string)
+	for key := range sysctl {
+		ns := configs.SysctlNamespace(strings.ReplaceAll(key, "/", "."))
+		batches[ns] = append(batches[ns], key)
+	}
+	rejected := new(configs.SysctlError)
+	write := func(keys []string, values map[string]string) {
+		sort.Strings(keys)
+		for _, key := range keys {
+			if err := writeSystemProperty(key, values[key]); err != nil {
+				rejected.Add(key, err)
+			}
+		}
+	}
+	for _, ns := range append(configs.NamespaceTypes(), "") {
+		write(batches[ns], sysctl)
+	}
+	keys := make([]string, 0, len(netSysctl))
+	for key := range netSysctl {
+		keys = append(keys, key)
+	}
+	write(keys, netSysctl)
+	if len(rejected.Rejected) > 0 {
+		return rejected
+	}
+	return nil
+}
+
 // Do the mount operation followed by additional mounts required to take care
 // of propagation flags. This will always be scoped inside the container rootfs.
 func mountPropagate(m mountEntry, rootfs string, mountLabel string) error {
@@ -1314,6 +1396,14 @@ func setRecAttr(m *configs.Mount, rootfs string) error {
 		return nil
 	}
 	return utils.WithProcfd(rootfs, m.Destination, func(procfd string) error {
-		return unix.MountSetattr(-1, procfd, unix.AT_RECURSIVE, m.RecAttr)
+		err := unix.MountSetattr(-1, procfd, unix.AT_RECURSIVE, m.RecAttr)
+		if err == nil {
+			return nil
+		}
+		err = &os.PathError{Op: "mount_setattr", Path: m.Destination, Err: err}
+		if errors.Is(err, unix.ENOSYS) {
+			return fmt.Errorf("recursive mount options are not supported by the kernel (Linux 5.12 or later is needed): %w", err)
+		}
+		return err
 	})
 }
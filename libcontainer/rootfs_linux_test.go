@@ -1,6 +1,8 @@
 package libcontainer
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/szcdx/runc/libcontainer/configs"
@@ -193,3 +195,46 @@ func TestNeedsSetupDevStrangeSourceDest(t *testing.T) {
 		t.Fatal("expected needsSetupDev to be true, got false")
 	}
 }
+
+func TestReplaceLocaltimeSymlink(t *testing.T) {
+	rootfs := t.TempDir()
+	zoneinfo := filepath.Join(rootfs, "usr/share/zoneinfo")
+	if err := os.MkdirAll(zoneinfo, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(zoneinfo, "UTC"), []byte("TZif"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// Replacing nothing is fine.
+	if err := replaceLocaltimeSymlink(rootfs); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(rootfs, "etc"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	localtime := filepath.Join(rootfs, "etc/localtime")
+	if err := os.Symlink("../usr/share/zoneinfo/UTC", localtime); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := replaceLocaltimeSymlink(rootfs); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(localtime); !os.IsNotExist(err) {
+		t.Fatalf("expected the symlink to be removed, got %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(zoneinfo, "UTC")); err != nil || string(data) != "TZif" {
+		t.Fatalf("expected the symlink target to be left as is, got %q, %v", data, err)
+	}
+
+	// A regular file is left as is.
+	if err := os.WriteFile(localtime, []byte("TZif"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := replaceLocaltimeSymlink(rootfs); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(localtime); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,152 @@
+package libcontainer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/szcdx/runc/libcontainer/system"
+)
+
+// slirp4netnsMTU is the MTU of the tap interface created by slirp4netns,
+// the largest one it supports (its default of 1500 is much slower).
+const slirp4netnsMTU = 65520
+
+// rootlessNetwork is the user-mode network stack process of the container
+// (see configs.RootlessNetwork).
+type rootlessNetwork struct {
+	pid       int
+	startTime uint64
+}
+
+// startRootlessNetwork starts the user-mode network stack of the container,
+// in the user and network namespaces of its init process initPid, and
+// waits for it to be ready.
+func (c *Container) startRootlessNetwork(initPid int) (*rootlessNetwork, error) {
+	cfg := c.config.RootlessNetwork
+	path := cfg.Path
+	if path == "" {
+		var err error
+		if path, err = exec.LookPath(cfg.Mode); err != nil {
+			return nil, fmt.Errorf("rootless network: %w", err)
+		}
+	}
+	// The network stack outlives runc, so its output goes to a log file
+	// in the state directory rather than to a pipe.
+	logFile, err := os.OpenFile(filepath.Join(c.stateDir, cfg.Mode+".log"), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("rootless network: %w", err)
+	}
+	defer logFile.Close()
+
+	var n *rootlessNetwork
+	switch cfg.Mode {
+	case "slirp4netns":
+		n, err = startSlirp4netns(path, cfg.Args, initPid, logFile)
+	case "pasta":
+		n, err = startPasta(path, cfg.Args, initPid, logFile, filepath.Join(c.stateDir, "pasta.pid"))
+	default:
+		err = errors.New("unknown mode")
+	}
+	if err != nil {
+		if out, _ := os.ReadFile(logFile.Name()); len(out) > 0 {
+			err = fmt.Errorf("%w: %s", err, bytes.TrimSpace(out))
+		}
+		return nil, fmt.Errorf("rootless network %s: %w", cfg.Mode, err)
+	}
+	return n, nil
+}
+
+// startSlirp4netns starts slirp4netns, which creates and configures the
+// tap0 interface in the network namespace of initPid, and stays in the
+// foreground (detached from runc) until it is stopped.
+func startSlirp4netns(path string, args []string, initPid int, logFile *os.File) (*rootlessNetwork, error) {
+	readyR, readyW, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	defer readyR.Close()
+
+	cmd := exec.Command(path, "--configure", "--mtu="+strconv.Itoa(slirp4netnsMTU), "--ready-fd=3")
+	cmd.Args = append(cmd.Args, args...)
+	// With a pid, slirp4netns joins both its user and network namespaces.
+	cmd.Args = append(cmd.Args, strconv.Itoa(initPid), "tap0")
+	cmd.ExtraFiles = []*os.File{readyW}
+	cmd.Stdout, cmd.Stderr = logFile, logFile
+	// Detach slirp4netns, so that it is not killed together with runc.
+	cmd.SysProcAttr = &unix.SysProcAttr{Setsid: true}
+	err = cmd.Start()
+	_ = readyW.Close()
+	if err != nil {
+		return nil, err
+	}
+	// slirp4netns writes "1" to the ready fd once the interface is
+	// configured. If it fails, the fd is closed on exit instead.
+	buf := make([]byte, 1)
+	if n, _ := readyR.Read(buf); n != 1 || buf[0] != '1' {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return nil, errors.New("not ready")
+	}
+	stat, err := system.Stat(cmd.Process.Pid)
+	if err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return nil, err
+	}
+	n := &rootlessNetwork{pid: cmd.Process.Pid, startTime: stat.StartTime}
+	_ = cmd.Process.Release()
+	return n, nil
+}
+
+// startPasta starts pasta, which configures the network namespace of
+// initPid like the host one and daemonizes once it is done, writing its
+// pid to pidFile.
+func startPasta(path string, args []string, initPid int, logFile *os.File, pidFile string) (*rootlessNetwork, error) {
+	proc := "/proc/" + strconv.Itoa(initPid)
+	cmd := exec.Command(path, "--config-net", "--quiet",
+		"--netns", proc+"/ns/net", "--userns", proc+"/ns/user", "--pid", pidFile)
+	cmd.Args = append(cmd.Args, args...)
+	cmd.Stdout, cmd.Stderr = logFile, logFile
+	cmd.SysProcAttr = &unix.SysProcAttr{Setsid: true}
+	if err := cmd.Run(); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(pidFile)
+	if err != nil {
+		return nil, err
+	}
+	_ = os.Remove(pidFile)
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid pid file: %w", err)
+	}
+	stat, err := system.Stat(pid)
+	if err != nil {
+		return nil, err
+	}
+	return &rootlessNetwork{pid: pid, startTime: stat.StartTime}, nil
+}
+
+// stop stops the network stack, if it is still running.
+func (n *rootlessNetwork) stop() error {
+	pidfd, err := openPidfd(n.pid, n.startTime)
+	if err != nil {
+		if errors.Is(err, ErrProcessGone) {
+			return nil
+		}
+		return err
+	}
+	defer pidfd.Close()
+	if err := pidfdSignal(pidfd, unix.SIGKILL); err != nil && !errors.Is(err, ErrProcessGone) {
+		return err
+	}
+	return nil
+}
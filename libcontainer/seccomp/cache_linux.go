@@ -0,0 +1,252 @@
+//go:build cgo && seccomp
+// +build cgo,seccomp
+
+package seccomp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	libseccomp "github.com/seccomp/libseccomp-golang"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/szcdx/runc/libcontainer/configs"
+)
+
+// maxCacheEntries is the number of compiled programs kept in a cache
+// directory. The least recently used ones are removed beyond that.
+const maxCacheEntries = 64
+
+// cacheEntry is the content of a cache entry file.
+type cacheEntry struct {
+	// Key is the cache key of the config the program was compiled from,
+	// so that an entry is only used for the same config, whatever the name
+	// of its file.
+	Key     string          `json:"key"`
+	Program json.RawMessage `json:"program"`
+}
+
+// CompileCached is like Compile, but the compiled program is cached in dir,
+// so subsequent calls with the same config skip libseccomp entirely.
+//
+// The cache key is the hash of the config, and of the libseccomp version and
+// API level, as those affect the resulting program. An entry is only used if
+// it records the same key. The cache directory, and the files in it, are
+// only used if they are owned by the current user and can't be written by
+// others, as no one else is to be trusted with the programs loaded into the
+// containers. Errors accessing the cache are not fatal.
+func CompileCached(dir string, config *configs.Seccomp) (*Program, error) {
+	if config == nil {
+		return nil, errors.New("cannot initialize Seccomp - nil config passed")
+	}
+	key, err := cacheKey(config)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCacheDir(dir); err != nil {
+		logrus.Debugf("seccomp: not using the cache: %v", err)
+		return Compile(config)
+	}
+	path := filepath.Join(dir, key+".json")
+
+	prog, err := readCacheEntry(path, key)
+	if err == nil {
+		// Keep track of the recently used entries, for the eviction.
+		now := time.Now()
+		_ = os.Chtimes(path, now, now)
+		return prog, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		logrus.Debugf("seccomp: ignoring cache entry %s: %v", path, err)
+	}
+
+	prog, err = Compile(config)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeCacheEntry(dir, path, key, prog); err != nil {
+		logrus.Debugf("seccomp: unable to cache compiled filter: %v", err)
+	}
+	evictCacheEntries(dir)
+	return prog, nil
+}
+
+func cacheKey(config *configs.Seccomp) (string, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+	major, minor, micro := libseccomp.GetLibraryVersion()
+	// Ignore the error since pre-2.4 libseccomp is treated as API level 0.
+	apiLevel, _ := libseccomp.GetAPI()
+
+	h := sha256.New()
+	fmt.Fprintf(h, "libseccomp %d.%d.%d api %d\n", major, minor, micro, apiLevel)
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// checkOwner returns an error if st is not owned by the current user, or
+// can be written by others.
+func checkOwner(st *unix.Stat_t) error {
+	if int(st.Uid) != os.Geteuid() {
+		return fmt.Errorf("owned by uid %d", st.Uid)
+	}
+	if st.Mode&0o022 != 0 {
+		return fmt.Errorf("writable by others (mode %#o)", st.Mode&0o7777)
+	}
+	return nil
+}
+
+// openCacheFile opens the cache file at path, which must be a regular file
+// (not a symlink), owned by the current user and not writable by others.
+func openCacheFile(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_RDONLY|unix.O_NOFOLLOW, 0)
+	if err != nil {
+		return nil, err
+	}
+	var st unix.Stat_t
+	if err := unix.Fstat(int(f.Fd()), &st); err != nil {
+		f.Close()
+		return nil, &os.PathError{Op: "fstat", Path: path, Err: err}
+	}
+	if st.Mode&unix.S_IFMT != unix.S_IFREG {
+		err = errors.New("not a regular file")
+	} else {
+		err = checkOwner(&st)
+	}
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return f, nil
+}
+
+func readCacheFile(path string) ([]byte, error) {
+	f, err := openCacheFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// checkCacheDir creates the cache directory dir if needed, and checks it.
+func checkCacheDir(dir string) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	var st unix.Stat_t
+	if err := unix.Lstat(dir, &st); err != nil {
+		return &os.PathError{Op: "lstat", Path: dir, Err: err}
+	}
+	if st.Mode&unix.S_IFMT != unix.S_IFDIR {
+		return fmt.Errorf("%s: not a directory", dir)
+	}
+	if err := checkOwner(&st); err != nil {
+		return fmt.Errorf("%s: %w", dir, err)
+	}
+	return nil
+}
+
+// readCacheEntry reads the program cached at path for the cache key.
+func readCacheEntry(path, key string) (*Program, error) {
+	data, err := readCacheFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	if entry.Key != key {
+		return nil, errors.New("entry of another config")
+	}
+	var prog Program
+	if err := json.Unmarshal(entry.Program, &prog); err != nil {
+		return nil, err
+	}
+	if len(prog.Filter) == 0 {
+		return nil, errors.New("empty filter")
+	}
+	return &prog, nil
+}
+
+// writeCacheEntry atomically writes prog to path.
+func writeCacheEntry(dir, path, key string, prog *Program) error {
+	data, err := json.Marshal(prog)
+	if err != nil {
+		return err
+	}
+	data, err = json.Marshal(&cacheEntry{Key: key, Program: data})
+	if err != nil {
+		return err
+	}
+	return writeTempFile(dir, data, func(name string) error {
+		return os.Rename(name, path)
+	})
+}
+
+// writeTempFile writes data to a temporary file in dir, and calls install
+// with its name to put it in place. The temporary file is then removed, if
+// it is still there.
+func writeTempFile(dir string, data []byte, install func(name string) error) error {
+	f, err := os.CreateTemp(dir, ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return install(f.Name())
+}
+
+// evictCacheEntries removes the least recently used entries of the cache
+// directory dir, beyond maxCacheEntries.
+func evictCacheEntries(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	type entry struct {
+		name  string
+		mtime time.Time
+	}
+	var cached []entry
+	for _, e := range entries {
+		if !e.Type().IsRegular() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		cached = append(cached, entry{name: e.Name(), mtime: info.ModTime()})
+	}
+	if len(cached) <= maxCacheEntries {
+		return
+	}
+	sort.Slice(cached, func(i, j int) bool {
+		return cached[i].mtime.Before(cached[j].mtime)
+	})
+	for _, e := range cached[:len(cached)-maxCacheEntries] {
+		if err := os.Remove(filepath.Join(dir, e.name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			logrus.Debugf("seccomp: unable to evict cache entry %s: %v", e.name, err)
+		}
+	}
+}
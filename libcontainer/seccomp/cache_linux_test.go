@@ -0,0 +1,170 @@
+//go:build cgo && seccomp
+// +build cgo,seccomp
+
+package seccomp
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/szcdx/runc/libcontainer/configs"
+)
+
+func TestCompileCached(t *testing.T) {
+	dir := t.TempDir()
+	config := &configs.Seccomp{
+		DefaultAction: configs.Errno,
+		Syscalls: []*configs.Syscall{
+			{Name: "read", Action: configs.Allow},
+			{Name: "write", Action: configs.Allow},
+		},
+	}
+
+	prog, err := CompileCached(dir, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 cache entry, got %d", len(entries))
+	}
+
+	cached, err := CompileCached(dir, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(prog, cached) {
+		t.Fatalf("cached program differs:\n%+v\n%+v", prog, cached)
+	}
+
+	// A corrupt entry is ignored and replaced.
+	if err := os.WriteFile(entries[0], []byte("garbage"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cached, err = CompileCached(dir, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(prog, cached) {
+		t.Fatalf("recompiled program differs:\n%+v\n%+v", prog, cached)
+	}
+
+	// A different config gets its own entry.
+	config.Syscalls = config.Syscalls[:1]
+	if _, err := CompileCached(dir, config); err != nil {
+		t.Fatal(err)
+	}
+	entries, err = filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 cache entries, got %d", len(entries))
+	}
+}
+
+func TestCompileCachedUntrusted(t *testing.T) {
+	dir := t.TempDir()
+	config := &configs.Seccomp{
+		DefaultAction: configs.Errno,
+		Syscalls:      []*configs.Syscall{{Name: "read", Action: configs.Allow}},
+	}
+	prog, err := CompileCached(dir, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := cacheKey(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, key+".json")
+
+	// An entry of another config (such as one renamed) is not used.
+	data, err := json.Marshal(&Program{Filter: prog.Filter[:1]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err = json.Marshal(&cacheEntry{Key: "00", Program: data})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cached, err := CompileCached(dir, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(prog, cached) {
+		t.Fatalf("entry of another config used:\n%+v\n%+v", prog, cached)
+	}
+
+	// Nor is an entry writable by others.
+	if err := os.Chmod(path, 0o666); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readCacheEntry(path, key); err == nil {
+		t.Fatal("expected error for an entry writable by others, got nil")
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nor a directory writable by others.
+	if err := os.Chmod(dir, 0o777); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkCacheDir(dir); err == nil {
+		t.Fatal("expected error for a directory writable by others, got nil")
+	}
+	if err := os.Chmod(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nor a symlink to an entry.
+	if err := os.Rename(path, path+".orig"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(path+".orig", path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readCacheFile(path); err == nil {
+		t.Fatal("expected error for a symlink, got nil")
+	}
+}
+
+func TestEvictCacheEntries(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	for i := 0; i < maxCacheEntries+2; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("%03d.json", i))
+		if err := os.WriteFile(path, nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		mtime := now.Add(time.Duration(i) * time.Second)
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	evictCacheEntries(dir)
+	entries, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != maxCacheEntries {
+		t.Fatalf("expected %d entries, got %d", maxCacheEntries, len(entries))
+	}
+	for _, name := range []string{"000.json", "001.json"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be evicted, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+// Package defaults generates the widely used default seccomp profile (the
+// one used by Docker, Podman, and containerd, among others), so that users
+// of libcontainer do not have to ship it as a JSON file.
+//
+// The profile allows a list of syscalls which are considered safe for any
+// container, plus the syscalls which are only safe if the container has been
+// granted the capabilities they require, and denies all other syscalls with
+// EPERM.
+package defaults
+
+import (
+	"fmt"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// arches maps the Go architecture names to the seccomp architectures used
+// by the processes on them (the native one first).
+var arches = map[string][]specs.Arch{
+	"386":      {specs.ArchX86},
+	"amd64":    {specs.ArchX86_64, specs.ArchX86, specs.ArchX32},
+	"arm":      {specs.ArchARM},
+	"arm64":    {specs.ArchAARCH64, specs.ArchARM},
+	"mips":     {specs.ArchMIPS},
+	"mips64":   {specs.ArchMIPS64, specs.ArchMIPS, specs.ArchMIPS64N32},
+	"mips64le": {specs.ArchMIPSEL64, specs.ArchMIPSEL, specs.ArchMIPSEL64N32},
+	"mipsle":   {specs.ArchMIPSEL},
+	"ppc64":    {specs.ArchPPC64},
+	"ppc64le":  {specs.ArchPPC64LE},
+	"riscv64":  {specs.ArchRISCV64},
+	"s390x":    {specs.ArchS390X, specs.ArchS390},
+}
+
+// allowed are the syscalls allowed regardless of the architecture and the
+// capabilities.
+var allowed = []string{
+	"accept", "accept4", "access", "adjtimex", "alarm", "bind", "brk",
+	"cachestat", "capget", "capset", "chdir", "chmod", "chown", "chown32",
+	"clock_adjtime", "clock_adjtime64", "clock_getres", "clock_getres_time64",
+	"clock_gettime", "clock_gettime64", "clock_nanosleep",
+	"clock_nanosleep_time64", "close", "close_range", "connect",
+	"copy_file_range", "creat", "dup", "dup2", "dup3", "epoll_create",
+	"epoll_create1", "epoll_ctl", "epoll_ctl_old", "epoll_pwait",
+	"epoll_pwait2", "epoll_wait", "epoll_wait_old", "eventfd", "eventfd2",
+	"execve", "execveat", "exit", "exit_group", "faccessat", "faccessat2",
+	"fadvise64", "fadvise64_64", "fallocate", "fanotify_mark", "fchdir",
+	"fchmod", "fchmodat", "fchmodat2", "fchown", "fchown32", "fchownat",
+	"fcntl", "fcntl64", "fdatasync", "fgetxattr", "flistxattr", "flock",
+	"fork", "fremovexattr", "fsetxattr", "fstat", "fstat64", "fstatat64",
+	"fstatfs", "fstatfs64", "fsync", "ftruncate", "ftruncate64", "futex",
+	"futex_requeue", "futex_time64", "futex_wait", "futex_waitv",
+	"futex_wake", "futimesat", "get_robust_list", "get_thread_area",
+	"getcpu", "getcwd", "getdents", "getdents64", "getegid", "getegid32",
+	"geteuid", "geteuid32", "getgid", "getgid32", "getgroups", "getgroups32",
+	"getitimer", "getpeername", "getpgid", "getpgrp", "getpid", "getppid",
+	"getpriority", "getrandom", "getresgid", "getresgid32", "getresuid",
+	"getresuid32", "getrlimit", "getrusage", "getsid", "getsockname",
+	"getsockopt", "gettid", "gettimeofday", "getuid", "getuid32", "getxattr",
+	"inotify_add_watch", "inotify_init", "inotify_init1", "inotify_rm_watch",
+	"io_cancel", "io_destroy", "io_getevents", "io_pgetevents",
+	"io_pgetevents_time64", "io_setup", "io_submit", "ioctl", "ioprio_get",
+	"ioprio_set", "ipc", "kill", "landlock_add_rule",
+	"landlock_create_ruleset", "landlock_restrict_self", "lchown",
+	"lchown32", "lgetxattr", "link", "linkat", "listen", "listxattr",
+	"llistxattr", "_llseek", "lremovexattr", "lseek", "lsetxattr", "lstat",
+	"lstat64", "madvise", "map_shadow_stack", "membarrier", "memfd_create",
+	"memfd_secret", "mincore", "mkdir", "mkdirat", "mknod", "mknodat",
+	"mlock", "mlock2", "mlockall", "mmap", "mmap2", "mprotect",
+	"mq_getsetattr", "mq_notify", "mq_open", "mq_timedreceive",
+	"mq_timedreceive_time64", "mq_timedsend", "mq_timedsend_time64",
+	"mq_unlink", "mremap", "msgctl", "msgget", "msgrcv", "msgsnd", "msync",
+	"munlock", "munlockall", "munmap", "name_to_handle_at", "nanosleep",
+	"newfstatat", "_newselect", "open", "openat", "openat2", "pause",
+	"pidfd_open", "pidfd_send_signal", "pipe", "pipe2", "pkey_alloc",
+	"pkey_free", "pkey_mprotect", "poll", "ppoll", "ppoll_time64", "prctl",
+	"pread64", "preadv", "preadv2", "prlimit64", "process_mrelease",
+	"pselect6", "pselect6_time64", "pwrite64", "pwritev", "pwritev2", "read",
+	"readahead", "readlink", "readlinkat", "readv", "recv", "recvfrom",
+	"recvmmsg", "recvmmsg_time64", "recvmsg", "remap_file_pages",
+	"removexattr", "rename", "renameat", "renameat2", "restart_syscall",
+	"rmdir", "rseq", "rt_sigaction", "rt_sigpending", "rt_sigprocmask",
+	"rt_sigqueueinfo", "rt_sigreturn", "rt_sigsuspend", "rt_sigtimedwait",
+	"rt_sigtimedwait_time64", "rt_tgsigqueueinfo", "sched_get_priority_max",
+	"sched_get_priority_min", "sched_getaffinity", "sched_getattr",
+	"sched_getparam", "sched_getscheduler", "sched_rr_get_interval",
+	"sched_rr_get_interval_time64", "sched_setaffinity", "sched_setattr",
+	"sched_setparam", "sched_setscheduler", "sched_yield", "seccomp",
+	"select", "semctl", "semget", "semop", "semtimedop",
+	"semtimedop_time64", "send", "sendfile", "sendfile64", "sendmmsg",
+	"sendmsg", "sendto", "set_robust_list", "set_thread_area",
+	"set_tid_address", "setfsgid", "setfsgid32", "setfsuid", "setfsuid32",
+	"setgid", "setgid32", "setgroups", "setgroups32", "setitimer", "setpgid",
+	"setpriority", "setregid", "setregid32", "setresgid", "setresgid32",
+	"setresuid", "setresuid32", "setreuid", "setreuid32", "setrlimit",
+	"setsid", "setsockopt", "setuid", "setuid32", "setxattr", "shmat",
+	"shmctl", "shmdt", "shmget", "shutdown", "sigaltstack", "signalfd",
+	"signalfd4", "sigprocmask", "sigreturn", "socketcall", "socketpair",
+	"splice", "stat", "stat64", "statfs", "statfs64", "statx", "symlink",
+	"symlinkat", "sync", "sync_file_range", "syncfs", "sysinfo", "tee",
+	"tgkill", "time", "timer_create", "timer_delete", "timer_getoverrun",
+	"timer_gettime", "timer_gettime64", "timer_settime", "timer_settime64",
+	"timerfd_create", "timerfd_gettime", "timerfd_gettime64",
+	"timerfd_settime", "timerfd_settime64", "times", "tkill", "truncate",
+	"truncate64", "ugetrlimit", "umask", "uname", "unlink", "unlinkat",
+	"utime", "utimensat", "utimensat_time64", "utimes", "vfork", "vmsplice",
+	"wait4", "waitid", "waitpid", "write", "writev",
+}
+
+// archAllowed are the additional syscalls allowed on some architectures.
+var archAllowed = map[string][]string{
+	"amd64":   {"arch_prctl", "modify_ldt"},
+	"386":     {"modify_ldt"},
+	"arm":     {"arm_fadvise64_64", "arm_sync_file_range", "sync_file_range2", "breakpoint", "cacheflush", "set_tls"},
+	"arm64":   {"arm_fadvise64_64", "arm_sync_file_range", "sync_file_range2", "breakpoint", "cacheflush", "set_tls"},
+	"ppc64le": {"sync_file_range2", "swapcontext"},
+	"riscv64": {"riscv_flush_icache"},
+	"s390x":   {"s390_pci_mmio_read", "s390_pci_mmio_write", "s390_runtime_instr"},
+}
+
+// capAllowed are the additional syscalls allowed if a capability is granted.
+var capAllowed = []struct {
+	caps  []string // any of these
+	names []string
+}{
+	{[]string{"CAP_BPF", "CAP_SYS_ADMIN"}, []string{"bpf"}},
+	{[]string{"CAP_DAC_READ_SEARCH"}, []string{"open_by_handle_at"}},
+	{[]string{"CAP_PERFMON", "CAP_SYS_ADMIN"}, []string{"perf_event_open"}},
+	{[]string{"CAP_SYS_ADMIN"}, []string{
+		"clone", "clone3", "fanotify_init", "fsconfig", "fsmount", "fsopen",
+		"fspick", "lookup_dcookie", "mount", "mount_setattr", "move_mount",
+		"open_tree", "quotactl", "quotactl_fd", "setdomainname", "sethostname",
+		"setns", "umount", "umount2", "unshare",
+	}},
+	{[]string{"CAP_SYS_ADMIN", "CAP_SYSLOG"}, []string{"syslog"}},
+	{[]string{"CAP_SYS_BOOT"}, []string{"reboot"}},
+	{[]string{"CAP_SYS_CHROOT"}, []string{"chroot"}},
+	{[]string{"CAP_SYS_MODULE"}, []string{"delete_module", "init_module", "finit_module"}},
+	{[]string{"CAP_SYS_NICE"}, []string{"get_mempolicy", "mbind", "set_mempolicy", "set_mempolicy_home_node"}},
+	{[]string{"CAP_SYS_PACCT"}, []string{"acct"}},
+	{[]string{"CAP_SYS_PTRACE"}, []string{"kcmp", "pidfd_getfd", "process_madvise"}},
+	{[]string{"CAP_SYS_RAWIO"}, []string{"iopl", "ioperm"}},
+	{[]string{"CAP_SYS_TIME"}, []string{"settimeofday", "stime", "clock_settime", "clock_settime64"}},
+	{[]string{"CAP_SYS_TTY_CONFIG"}, []string{"vhangup"}},
+}
+
+const (
+	// Namespace flags of clone(2); they must not be used without
+	// CAP_SYS_ADMIN.
+	cloneNamespaceFlags = 0x00020000 | // CLONE_NEWNS
+		0x02000000 | // CLONE_NEWCGROUP
+		0x04000000 | // CLONE_NEWUTS
+		0x08000000 | // CLONE_NEWIPC
+		0x10000000 | // CLONE_NEWUSER
+		0x20000000 | // CLONE_NEWPID
+		0x40000000 // CLONE_NEWNET
+
+	afVsock = 40 // AF_VSOCK
+	enosys  = 38 // ENOSYS
+	eperm   = 1  // EPERM
+)
+
+// Profile returns the default seccomp profile for a container running on
+// the architecture arch (a Go architecture name, such as "amd64") which is
+// granted the capabilities caps (such as "CAP_SYS_ADMIN"), usually the
+// bounding set of the container's process.
+func Profile(arch string, caps []string) (*specs.LinuxSeccomp, error) {
+	archs, ok := arches[arch]
+	if !ok {
+		return nil, fmt.Errorf("unsupported architecture %q", arch)
+	}
+	granted := make(map[string]bool, len(caps))
+	for _, c := range caps {
+		granted[c] = true
+	}
+	hasAny := func(caps []string) bool {
+		for _, c := range caps {
+			if granted[c] {
+				return true
+			}
+		}
+		return false
+	}
+
+	errnoRet := uint(eperm)
+	p := &specs.LinuxSeccomp{
+		DefaultAction:   specs.ActErrno,
+		DefaultErrnoRet: &errnoRet,
+		Architectures:   archs,
+	}
+	allow := func(names []string, args ...specs.LinuxSeccompArg) {
+		p.Syscalls = append(p.Syscalls, specs.LinuxSyscall{
+			Names:  names,
+			Action: specs.ActAllow,
+			Args:   args,
+		})
+	}
+
+	allow(allowed)
+	// The process_vm_* syscalls and ptrace are safe since the kernel
+	// checks the ptrace access mode against the target process.
+	allow([]string{"process_vm_readv", "process_vm_writev", "ptrace"})
+	// Vsock sockets are not namespaced, so do not let containers use them.
+	allow([]string{"socket"}, specs.LinuxSeccompArg{Index: 0, Value: afVsock, Op: specs.OpNotEqual})
+	// Only allow the personalities which are known to be safe.
+	for _, pers := range []uint64{
+		0x0,        // PER_LINUX
+		0x8,        // PER_LINUX32
+		0x20000,    // UNAME26
+		0x20008,    // UNAME26 | PER_LINUX32
+		0xffffffff, // query the current personality
+	} {
+		allow([]string{"personality"}, specs.LinuxSeccompArg{Index: 0, Value: pers, Op: specs.OpEqualTo})
+	}
+	if names := archAllowed[arch]; len(names) > 0 {
+		allow(names)
+	}
+	for _, c := range capAllowed {
+		if hasAny(c.caps) {
+			allow(c.names)
+		}
+	}
+	if !granted["CAP_SYS_ADMIN"] {
+		// Allow clone(2) as long as no namespaces are created. On s390,
+		// the flags are the second argument.
+		idx := uint(0)
+		if arch == "s390x" {
+			idx = 1
+		}
+		allow([]string{"clone"}, specs.LinuxSeccompArg{
+			Index:    idx,
+			Value:    cloneNamespaceFlags,
+			ValueTwo: 0,
+			Op:       specs.OpMaskedEqual,
+		})
+		// The arguments of clone3(2) are in memory and can not be
+		// inspected, so make it fail with ENOSYS for the libc to fall
+		// back to clone(2).
+		errnoRet := uint(enosys)
+		p.Syscalls = append(p.Syscalls, specs.LinuxSyscall{
+			Names:    []string{"clone3"},
+			Action:   specs.ActErrno,
+			ErrnoRet: &errnoRet,
+		})
+	}
+
+	return p, nil
+}
@@ -0,0 +1,60 @@
+package defaults
+
+import (
+	"testing"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// rulesFor returns the rules of profile p for the syscall name.
+func rulesFor(p *specs.LinuxSeccomp, name string) []specs.LinuxSyscall {
+	var rules []specs.LinuxSyscall
+	for _, s := range p.Syscalls {
+		for _, n := range s.Names {
+			if n == name {
+				rules = append(rules, s)
+			}
+		}
+	}
+	return rules
+}
+
+func TestProfile(t *testing.T) {
+	p, err := Profile("amd64", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.DefaultAction != specs.ActErrno || len(p.Architectures) != 3 {
+		t.Errorf("unexpected profile defaults: %+v", p)
+	}
+	if r := rulesFor(p, "arch_prctl"); len(r) != 1 {
+		t.Errorf("expected arch_prctl to be allowed on amd64, got %+v", r)
+	}
+	if r := rulesFor(p, "mount"); len(r) != 0 {
+		t.Errorf("expected mount not to be allowed without CAP_SYS_ADMIN, got %+v", r)
+	}
+	if r := rulesFor(p, "clone"); len(r) != 1 || len(r[0].Args) != 1 {
+		t.Errorf("expected clone to be allowed without namespace flags, got %+v", r)
+	}
+	if r := rulesFor(p, "clone3"); len(r) != 1 || r[0].Action != specs.ActErrno || *r[0].ErrnoRet != enosys {
+		t.Errorf("expected clone3 to fail with ENOSYS, got %+v", r)
+	}
+
+	p, err = Profile("s390x", []string{"CAP_SYS_ADMIN"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := rulesFor(p, "arch_prctl"); len(r) != 0 {
+		t.Errorf("expected arch_prctl not to be allowed on s390x, got %+v", r)
+	}
+	if r := rulesFor(p, "mount"); len(r) != 1 {
+		t.Errorf("expected mount to be allowed with CAP_SYS_ADMIN, got %+v", r)
+	}
+	if r := rulesFor(p, "clone"); len(r) != 1 || len(r[0].Args) != 0 {
+		t.Errorf("expected clone to be allowed unconditionally, got %+v", r)
+	}
+
+	if _, err := Profile("pdp11", nil); err == nil {
+		t.Error("expected error for an unknown architecture")
+	}
+}
@@ -0,0 +1,88 @@
+// Package notify provides helpers for handling seccomp user notifications
+// (SCMP_ACT_NOTIFY) in-process, without an external seccomp agent.
+//
+// The seccomp notify file descriptor of a container process can be obtained
+// from libcontainer by setting libcontainer.Process.SeccompNotify before
+// starting the process, and then calling Process.SeccompNotifyFd (or
+// Container.SeccompNotifyFd for the container init).
+package notify
+
+import (
+	"os"
+	"syscall"
+)
+
+// Request is a seccomp user notification, describing a system call made by
+// a process in the container.
+type Request struct {
+	// ID is the notification ID, which must be used in the Response.
+	ID uint64
+	// Pid is the PID (in the listener's PID namespace) of the process
+	// which made the system call.
+	Pid uint32
+	// Syscall is the name of the system call.
+	Syscall string
+	// Arch is the architecture of the system call, as named by libseccomp.
+	Arch string
+	// InstrPointer is the address of the instruction which made the call.
+	InstrPointer uint64
+	// Args are the system call arguments.
+	Args []uint64
+}
+
+// Response is a reply to a Request.
+type Response struct {
+	// ID is the notification ID, as received in the Request.
+	ID uint64
+	// Errno, if non-zero, is the error returned to the system call.
+	Errno int32
+	// Val is the return value of the system call (if Errno is 0).
+	Val uint64
+	// Continue tells the kernel to execute the system call as usual.
+	// Errno and Val must be unset.
+	Continue bool
+}
+
+// Handler handles a single Request. A nil Response makes the system call
+// fail with EPERM.
+type Handler func(*Request) *Response
+
+// Listener receives and responds to seccomp user notifications using
+// a seccomp notify file descriptor.
+type Listener struct {
+	f *os.File
+}
+
+// NewListener returns a new Listener for the seccomp notify fd f.
+// The Listener takes ownership of f.
+func NewListener(f *os.File) *Listener {
+	return &Listener{f: f}
+}
+
+// Close closes the seccomp notify fd.
+func (l *Listener) Close() error {
+	return l.f.Close()
+}
+
+// Serve receives notifications and responds to them using h, until an error
+// occurs (for example, all the processes using the seccomp filter are gone).
+func (l *Listener) Serve(h Handler) error {
+	for {
+		req, err := l.Receive()
+		if err != nil {
+			return err
+		}
+		resp := h(req)
+		if resp == nil {
+			resp = &Response{ID: req.ID, Errno: int32(syscall.EPERM)}
+		}
+		if err := l.Respond(resp); err != nil {
+			// The notification may be gone (e.g. the process was
+			// killed while we were handling it); this is not fatal.
+			if l.IDValid(req.ID) != nil {
+				continue
+			}
+			return err
+		}
+	}
+}
@@ -0,0 +1,64 @@
+//go:build cgo && seccomp
+// +build cgo,seccomp
+
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+
+	libseccomp "github.com/seccomp/libseccomp-golang"
+)
+
+// Receive waits for a notification and returns it.
+func (l *Listener) Receive() (*Request, error) {
+	req, err := libseccomp.NotifReceive(libseccomp.ScmpFd(l.f.Fd()))
+	runtime.KeepAlive(l.f)
+	if err != nil {
+		return nil, fmt.Errorf("unable to receive seccomp notification: %w", err)
+	}
+	name, err := req.Data.Syscall.GetNameByArch(req.Data.Arch)
+	if err != nil {
+		name = fmt.Sprintf("%d", int32(req.Data.Syscall))
+	}
+	return &Request{
+		ID:           req.ID,
+		Pid:          req.Pid,
+		Syscall:      name,
+		Arch:         req.Data.Arch.String(),
+		InstrPointer: req.Data.InstrPointer,
+		Args:         req.Data.Args,
+	}, nil
+}
+
+// Respond sends a response to a previously received notification.
+func (l *Listener) Respond(resp *Response) error {
+	r := &libseccomp.ScmpNotifResp{
+		ID:    resp.ID,
+		Error: resp.Errno,
+		Val:   resp.Val,
+	}
+	if resp.Continue {
+		if resp.Errno != 0 || resp.Val != 0 {
+			return errors.New("seccomp notification response: Continue can't be used with Errno or Val")
+		}
+		r.Flags = libseccomp.NotifRespFlagContinue
+	}
+	err := libseccomp.NotifRespond(libseccomp.ScmpFd(l.f.Fd()), r)
+	runtime.KeepAlive(l.f)
+	if err != nil {
+		return fmt.Errorf("unable to respond to seccomp notification: %w", err)
+	}
+	return nil
+}
+
+// IDValid checks whether a notification with the given ID is still valid,
+// i.e. the process which made the system call is still waiting for the
+// response. It should be used to avoid TOCTOU races when reading the
+// memory of the process (see seccomp_unotify(2)).
+func (l *Listener) IDValid(id uint64) error {
+	err := libseccomp.NotifIDValid(libseccomp.ScmpFd(l.f.Fd()), id)
+	runtime.KeepAlive(l.f)
+	return err
+}
@@ -0,0 +1,23 @@
+//go:build !linux || !cgo || !seccomp
+// +build !linux !cgo !seccomp
+
+package notify
+
+import "errors"
+
+var errNotEnabled = errors.New("seccomp notify: seccomp not supported")
+
+// Receive returns an error because seccomp is not supported.
+func (l *Listener) Receive() (*Request, error) {
+	return nil, errNotEnabled
+}
+
+// Respond returns an error because seccomp is not supported.
+func (l *Listener) Respond(_ *Response) error {
+	return errNotEnabled
+}
+
+// IDValid returns an error because seccomp is not supported.
+func (l *Listener) IDValid(_ uint64) error {
+	return errNotEnabled
+}
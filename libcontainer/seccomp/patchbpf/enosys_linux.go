@@ -11,8 +11,10 @@ import (
 	"io"
 	"os"
 	"runtime"
+	"sync"
 	"unsafe"
 
+	"github.com/opencontainers/runtime-spec/specs-go"
 	libseccomp "github.com/seccomp/libseccomp-golang"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/net/bpf"
@@ -53,6 +55,11 @@ const uintptr_t C_FILTER_FLAG_SPEC_ALLOW = SECCOMP_FILTER_FLAG_SPEC_ALLOW;
 #endif
 const uintptr_t C_FILTER_FLAG_NEW_LISTENER = SECCOMP_FILTER_FLAG_NEW_LISTENER;
 
+#ifndef SECCOMP_FILTER_FLAG_WAIT_KILLABLE_RECV
+#	define SECCOMP_FILTER_FLAG_WAIT_KILLABLE_RECV (1UL << 5)
+#endif
+const uintptr_t C_FILTER_FLAG_WAIT_KILLABLE_RECV = SECCOMP_FILTER_FLAG_WAIT_KILLABLE_RECV;
+
 #ifndef AUDIT_ARCH_RISCV64
 #ifndef EM_RISCV
 #define EM_RISCV		243
@@ -259,6 +266,9 @@ func findLastSyscalls(config *configs.Seccomp) (lastSyscallMap, error) {
 		// Find the largest syscall in the filter for this architecture.
 		var largestSyscall libseccomp.ScmpSyscall
 		for _, rule := range config.Syscalls {
+			if !ruleAppliesToArch(rule, arch) {
+				continue
+			}
 			sysno, err := libseccomp.GetSyscallFromNameByArch(rule.Name, arch)
 			if err != nil {
 				// Ignore unknown syscalls.
@@ -278,6 +288,20 @@ func findLastSyscalls(config *configs.Seccomp) (lastSyscallMap, error) {
 	return lastSyscalls, nil
 }
 
+// ruleAppliesToArch tells whether the rule is not scoped to specific
+// architectures, or arch is one of them.
+func ruleAppliesToArch(rule *configs.Syscall, arch libseccomp.ScmpArch) bool {
+	if len(rule.Arches) == 0 {
+		return true
+	}
+	for _, a := range rule.Arches {
+		if scmpArch, err := libseccomp.GetArchFromString(a); err == nil && scmpArch == arch {
+			return true
+		}
+	}
+	return false
+}
+
 // FIXME FIXME FIXME
 //
 // This solution is less than ideal. In the future it would be great to have
@@ -652,9 +676,45 @@ func filterFlags(config *configs.Seccomp, filter *libseccomp.ScmpFilter) (flags
 		}
 	}
 
+	// libseccomp-golang does not support SECCOMP_FILTER_FLAG_WAIT_KILLABLE_RECV
+	// yet, so set it here. Like libseccomp, only set it together with
+	// SECCOMP_FILTER_FLAG_NEW_LISTENER, as the kernel requires.
+	if flags&uint(C.C_FILTER_FLAG_NEW_LISTENER) != 0 {
+		for _, flag := range config.Flags {
+			if flag == specs.LinuxSeccompFlagWaitKillableRecv {
+				flags |= uint(C.C_FILTER_FLAG_WAIT_KILLABLE_RECV)
+				break
+			}
+		}
+	}
+
 	return
 }
 
+var (
+	waitKillableRecvOnce      sync.Once
+	waitKillableRecvSupported bool
+)
+
+// SupportsWaitKillableRecv reports whether the kernel supports the
+// SECCOMP_FILTER_FLAG_WAIT_KILLABLE_RECV filter flag.
+func SupportsWaitKillableRecv() bool {
+	waitKillableRecvOnce.Do(func() {
+		waitKillableRecvSupported = flagsSupported(uint(C.C_FILTER_FLAG_NEW_LISTENER | C.C_FILTER_FLAG_WAIT_KILLABLE_RECV))
+	})
+	return waitKillableRecvSupported
+}
+
+// flagsSupported checks whether the kernel supports the given set of filter
+// flags. The kernel validates the flags before reading the filter program,
+// so passing a NULL program results in EFAULT for supported flags and
+// EINVAL for unsupported ones, without a filter being loaded.
+func flagsSupported(flags uint) bool {
+	_, _, errno := unix.RawSyscall(unix.SYS_SECCOMP,
+		uintptr(C.C_SET_MODE_FILTER), uintptr(flags), 0)
+	return errno == unix.EFAULT
+}
+
 func sysSeccompSetFilter(flags uint, filter []unix.SockFilter) (fd int, err error) {
 	// This debug output is validated in tests/integration/seccomp.bats
 	// by the SECCOMP_FILTER_FLAG_* test.
@@ -685,24 +745,29 @@ func sysSeccompSetFilter(flags uint, filter []unix.SockFilter) (fd int, err erro
 	return
 }
 
-// PatchAndLoad takes a seccomp configuration and a libseccomp filter which has
+// Compile takes a seccomp configuration and a libseccomp filter which has
 // been pre-configured with the set of rules in the seccomp config. It then
 // patches said filter to handle -ENOSYS in a much nicer manner than the
-// default libseccomp default action behaviour, and loads the patched filter
-// into the kernel for the current process.
-func PatchAndLoad(config *configs.Seccomp, filter *libseccomp.ScmpFilter) (*os.File, error) {
+// default libseccomp default action behaviour, and returns the patched
+// filter along with the flags it has to be loaded with (see Load).
+func Compile(config *configs.Seccomp, filter *libseccomp.ScmpFilter) (fprog []unix.SockFilter, flags uint, noNewPrivs bool, err error) {
 	// Generate a patched filter.
-	fprog, err := enosysPatchFilter(config, filter)
+	fprog, err = enosysPatchFilter(config, filter)
 	if err != nil {
-		return nil, fmt.Errorf("error patching filter: %w", err)
+		return nil, 0, false, fmt.Errorf("error patching filter: %w", err)
 	}
 
 	// Get the set of libseccomp flags set.
-	seccompFlags, noNewPrivs, err := filterFlags(config, filter)
+	flags, noNewPrivs, err = filterFlags(config, filter)
 	if err != nil {
-		return nil, fmt.Errorf("unable to fetch seccomp filter flags: %w", err)
+		return nil, 0, false, fmt.Errorf("unable to fetch seccomp filter flags: %w", err)
 	}
+	return fprog, flags, noNewPrivs, nil
+}
 
+// Load loads a filter previously generated by Compile into the kernel for
+// the current process.
+func Load(fprog []unix.SockFilter, flags uint, noNewPrivs bool) (*os.File, error) {
 	// Set no_new_privs if it was requested, though in runc we handle
 	// no_new_privs separately so warn if we hit this path.
 	if noNewPrivs {
@@ -713,9 +778,18 @@ func PatchAndLoad(config *configs.Seccomp, filter *libseccomp.ScmpFilter) (*os.F
 	}
 
 	// Finally, load the filter.
-	fd, err := sysSeccompSetFilter(seccompFlags, fprog)
+	fd, err := sysSeccompSetFilter(flags, fprog)
 	if err != nil {
 		return nil, fmt.Errorf("error loading seccomp filter: %w", err)
 	}
 	return os.NewFile(uintptr(fd), "[seccomp filter]"), nil
 }
+
+// PatchAndLoad is Compile followed by Load.
+func PatchAndLoad(config *configs.Seccomp, filter *libseccomp.ScmpFilter) (*os.File, error) {
+	fprog, flags, noNewPrivs, err := Compile(config, filter)
+	if err != nil {
+		return nil, err
+	}
+	return Load(fprog, flags, noNewPrivs)
+}
@@ -0,0 +1,14 @@
+package seccomp
+
+import "golang.org/x/net/bpf"
+
+// Program is a compiled seccomp filter, ready to be loaded into the kernel
+// by LoadProgram.
+type Program struct {
+	// Filter is the BPF program.
+	Filter []bpf.RawInstruction `json:"filter"`
+	// Flags are the SECCOMP_FILTER_FLAG_* flags to load Filter with.
+	Flags uint `json:"flags"`
+	// NoNewPrivs tells whether no_new_privs is to be set before loading Filter.
+	NoNewPrivs bool `json:"no_new_privs,omitempty"`
+}
@@ -10,6 +10,7 @@ import (
 
 	libseccomp "github.com/seccomp/libseccomp-golang"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/net/bpf"
 	"golang.org/x/sys/unix"
 
 	"github.com/opencontainers/runtime-spec/specs-go"
@@ -31,6 +32,34 @@ const (
 // specified in config. Returns the seccomp file descriptor if any of the
 // filters include a SCMP_ACT_NOTIFY action.
 func InitSeccomp(config *configs.Seccomp) (*os.File, error) {
+	prog, err := Compile(config)
+	if err != nil {
+		return nil, err
+	}
+	return LoadProgram(prog)
+}
+
+// LoadProgram installs the seccomp filter previously compiled by Compile.
+// Returns the seccomp file descriptor if the filter includes a
+// SCMP_ACT_NOTIFY action.
+func LoadProgram(prog *Program) (*os.File, error) {
+	if prog == nil || len(prog.Filter) == 0 {
+		return nil, errors.New("cannot load an empty seccomp program")
+	}
+	fprog := make([]unix.SockFilter, len(prog.Filter))
+	for i, insn := range prog.Filter {
+		fprog[i] = unix.SockFilter{Code: insn.Op, Jt: insn.Jt, Jf: insn.Jf, K: insn.K}
+	}
+	seccompFd, err := patchbpf.Load(fprog, prog.Flags, prog.NoNewPrivs)
+	if err != nil {
+		return nil, fmt.Errorf("error loading seccomp filter into kernel: %w", err)
+	}
+	return seccompFd, nil
+}
+
+// Compile compiles the seccomp filter specified in config into a program
+// which can be loaded by LoadProgram.
+func Compile(config *configs.Seccomp) (*Program, error) {
 	if config == nil {
 		return nil, errors.New("cannot initialize Seccomp - nil config passed")
 	}
@@ -42,6 +71,9 @@ func InitSeccomp(config *configs.Seccomp) (*os.File, error) {
 
 	// Ignore the error since pre-2.4 libseccomp is treated as API level 0.
 	apiLevel, _ := libseccomp.GetAPI()
+	if apiLevel < 6 {
+		config = withFallbackActions(config)
+	}
 	for _, call := range config.Syscalls {
 		if call.Action == configs.Notify {
 			if apiLevel < 6 {
@@ -71,20 +103,91 @@ func InitSeccomp(config *configs.Seccomp) (*os.File, error) {
 		return nil, errors.New("SCMP_ACT_NOTIFY cannot be used as default action")
 	}
 
+	var filter *libseccomp.ScmpFilter
+	if hasArchRules(config) {
+		filter, err = newPerArchFilter(config, defaultAction)
+	} else {
+		filter, err = newFilter(config, defaultAction, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer filter.Release()
+
+	fprog, flags, noNewPrivs, err := patchbpf.Compile(config, filter)
+	if err != nil {
+		return nil, fmt.Errorf("error compiling seccomp filter: %w", err)
+	}
+	prog := &Program{
+		Filter:     make([]bpf.RawInstruction, len(fprog)),
+		Flags:      flags,
+		NoNewPrivs: noNewPrivs,
+	}
+	for i, insn := range fprog {
+		prog.Filter[i] = bpf.RawInstruction{Op: insn.Code, Jt: insn.Jt, Jf: insn.Jf, K: insn.K}
+	}
+	return prog, nil
+}
+
+// withFallbackActions returns a copy of config in which every Notify rule
+// having a FallbackAction set uses that action instead. The original config
+// is returned as is if there are no such rules.
+func withFallbackActions(config *configs.Seccomp) *configs.Seccomp {
+	var syscalls []*configs.Syscall
+	for i, call := range config.Syscalls {
+		if call == nil || call.Action != configs.Notify || call.FallbackAction == 0 {
+			continue
+		}
+		if syscalls == nil {
+			syscalls = make([]*configs.Syscall, len(config.Syscalls))
+			copy(syscalls, config.Syscalls)
+		}
+		fallback := *call
+		fallback.Action = call.FallbackAction
+		syscalls[i] = &fallback
+		logrus.Debugf("seccomp notify unsupported, using fallback action for syscall %q", call.Name)
+	}
+	if syscalls == nil {
+		return config
+	}
+	c := *config
+	c.Syscalls = syscalls
+	return &c
+}
+
+// newFilter creates a libseccomp filter for config. If arch is nil, the
+// filter is created for the native architecture and config.Architectures,
+// otherwise for arch only, and with only the rules which apply to arch.
+func newFilter(config *configs.Seccomp, defaultAction libseccomp.ScmpAction, arch *libseccomp.ScmpArch) (_ *libseccomp.ScmpFilter, retErr error) {
 	filter, err := libseccomp.NewFilter(defaultAction)
 	if err != nil {
 		return nil, fmt.Errorf("error creating filter: %w", err)
 	}
+	defer func() {
+		if retErr != nil {
+			filter.Release()
+		}
+	}()
 
-	// Add extra architectures
-	for _, arch := range config.Architectures {
-		scmpArch, err := libseccomp.GetArchFromString(arch)
-		if err != nil {
-			return nil, fmt.Errorf("error validating Seccomp architecture: %w", err)
+	if arch == nil {
+		// Add extra architectures
+		for _, arch := range config.Architectures {
+			scmpArch, err := libseccomp.GetArchFromString(arch)
+			if err != nil {
+				return nil, fmt.Errorf("error validating Seccomp architecture: %w", err)
+			}
+			if err := filter.AddArch(scmpArch); err != nil {
+				return nil, fmt.Errorf("error adding architecture to seccomp filter: %w", err)
+			}
 		}
-		if err := filter.AddArch(scmpArch); err != nil {
+	} else if native, _ := libseccomp.GetNativeArch(); *arch != native {
+		// Replace the native architecture with arch.
+		if err := filter.AddArch(*arch); err != nil {
 			return nil, fmt.Errorf("error adding architecture to seccomp filter: %w", err)
 		}
+		if err := filter.RemoveArch(native); err != nil {
+			return nil, fmt.Errorf("error removing architecture from seccomp filter: %w", err)
+		}
 	}
 
 	// Add extra flags.
@@ -118,17 +221,96 @@ func InitSeccomp(config *configs.Seccomp) (*os.File, error) {
 		if call == nil {
 			return nil, errors.New("encountered nil syscall while initializing Seccomp")
 		}
+		if arch != nil {
+			ok, err := appliesToArch(call, *arch)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+		}
 
 		if err := matchCall(filter, call, defaultAction); err != nil {
 			return nil, err
 		}
 	}
 
-	seccompFd, err := patchbpf.PatchAndLoad(config, filter)
+	return filter, nil
+}
+
+// hasArchRules tells whether any of the config rules is scoped
+// to specific architectures.
+func hasArchRules(config *configs.Seccomp) bool {
+	for _, call := range config.Syscalls {
+		if call != nil && len(call.Arches) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// appliesToArch tells whether the rule applies to arch.
+func appliesToArch(call *configs.Syscall, arch libseccomp.ScmpArch) (bool, error) {
+	if len(call.Arches) == 0 {
+		return true, nil
+	}
+	for _, a := range call.Arches {
+		scmpArch, err := libseccomp.GetArchFromString(a)
+		if err != nil {
+			return false, fmt.Errorf("error validating architecture of seccomp rule for syscall %s: %w", call.Name, err)
+		}
+		if scmpArch == arch {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// newPerArchFilter creates a libseccomp filter for config which has some
+// rules scoped to specific architectures. As libseccomp applies every rule
+// to all the filter architectures, a separate filter is created for each
+// architecture, and those are merged together.
+func newPerArchFilter(config *configs.Seccomp, defaultAction libseccomp.ScmpAction) (_ *libseccomp.ScmpFilter, retErr error) {
+	native, err := libseccomp.GetNativeArch()
 	if err != nil {
-		return nil, fmt.Errorf("error loading seccomp filter into kernel: %w", err)
+		return nil, fmt.Errorf("unable to get native architecture: %w", err)
 	}
-	return seccompFd, nil
+	arches := []libseccomp.ScmpArch{native}
+	seen := map[libseccomp.ScmpArch]bool{native: true}
+	for _, arch := range config.Architectures {
+		scmpArch, err := libseccomp.GetArchFromString(arch)
+		if err != nil {
+			return nil, fmt.Errorf("error validating Seccomp architecture: %w", err)
+		}
+		if !seen[scmpArch] {
+			seen[scmpArch] = true
+			arches = append(arches, scmpArch)
+		}
+	}
+
+	var filter *libseccomp.ScmpFilter
+	defer func() {
+		if retErr != nil && filter != nil {
+			filter.Release()
+		}
+	}()
+	for i := range arches {
+		f, err := newFilter(config, defaultAction, &arches[i])
+		if err != nil {
+			return nil, err
+		}
+		if filter == nil {
+			filter = f
+			continue
+		}
+		// On success, Merge consumes f.
+		if err := filter.Merge(f); err != nil {
+			f.Release()
+			return nil, fmt.Errorf("error merging seccomp filter for architecture %s: %w", arches[i], err)
+		}
+	}
+	return filter, nil
 }
 
 type unknownFlagError struct {
@@ -139,6 +321,14 @@ func (e *unknownFlagError) Error() string {
 	return "seccomp flag " + string(e.flag) + " is not known to runc"
 }
 
+type unsupportedFlagError struct {
+	flag specs.LinuxSeccompFlag
+}
+
+func (e *unsupportedFlagError) Error() string {
+	return "seccomp flag " + string(e.flag) + " is not supported by the kernel"
+}
+
 func setFlag(filter *libseccomp.ScmpFilter, flag specs.LinuxSeccompFlag) error {
 	switch flag {
 	case flagTsync:
@@ -159,6 +349,14 @@ func setFlag(filter *libseccomp.ScmpFilter, flag specs.LinuxSeccompFlag) error {
 			return fmt.Errorf("error adding SSB flag to seccomp filter: %w", err)
 		}
 		return nil
+	case specs.LinuxSeccompFlagWaitKillableRecv:
+		// Not supported by libseccomp-golang, so the flag is set by
+		// patchbpf when loading the filter (only if there are
+		// SCMP_ACT_NOTIFY rules, as the kernel requires).
+		if !patchbpf.SupportsWaitKillableRecv() {
+			return &unsupportedFlagError{flag: flag}
+		}
+		return nil
 	}
 	// NOTE when adding more flags above, do not forget to also:
 	// - add new flags to `flags` slice in config.go;
@@ -179,6 +377,12 @@ func FlagSupported(flag specs.LinuxSeccompFlag) error {
 	if errors.As(err, &uf) {
 		return err
 	}
+	// For flags that runc handles itself, unsupportedFlagError is
+	// returned if the kernel is too old.
+	var usf *unsupportedFlagError
+	if errors.As(err, &usf) {
+		return err
+	}
 	// For flags that are known to runc and libseccomp-golang but can not
 	// be applied because either libseccomp or the kernel is too old,
 	// seccomp.VersionError is returned.
@@ -0,0 +1,219 @@
+//go:build cgo && seccomp
+// +build cgo,seccomp
+
+package seccomp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+	libseccomp "github.com/seccomp/libseccomp-golang"
+	"golang.org/x/net/bpf"
+
+	"github.com/szcdx/runc/libcontainer/configs"
+)
+
+// runFilter runs prog against a syscall and returns the filter action.
+func runFilter(t *testing.T, prog *Program, auditArch uint32, sysno int32) uint32 {
+	t.Helper()
+	insns, ok := bpf.Disassemble(prog.Filter)
+	if !ok {
+		t.Fatal("unable to disassemble the filter")
+	}
+	vm, err := bpf.NewVM(insns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// struct seccomp_data, in big endian as the BPF VM expects.
+	var buf bytes.Buffer
+	data := struct {
+		Nr   int32
+		Arch uint32
+		IP   uint64
+		Args [6]uint64
+	}{Nr: sysno, Arch: auditArch}
+	if err := binary.Write(&buf, binary.BigEndian, data); err != nil {
+		t.Fatal(err)
+	}
+	ret, err := vm.Run(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return uint32(ret)
+}
+
+func TestCompileArchRules(t *testing.T) {
+	if native, _ := libseccomp.GetNativeArch(); native != libseccomp.ArchAMD64 {
+		t.Skip("test requires amd64")
+	}
+	const (
+		auditArchX86_64 = 0xC000003E
+		auditArchI386   = 0x40000003
+		retAllow        = 0x7fff0000
+	)
+
+	config := &configs.Seccomp{
+		DefaultAction: configs.Errno,
+		Architectures: []string{"amd64", "x86"},
+		Syscalls: []*configs.Syscall{
+			{Name: "read", Action: configs.Allow},
+			{Name: "personality", Action: configs.Allow, Arches: []string{"amd64"}},
+		},
+	}
+	prog, err := Compile(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		arch  uint32
+		sysno int32
+		allow bool
+	}{
+		{arch: auditArchX86_64, sysno: 0, allow: true},    // read
+		{arch: auditArchX86_64, sysno: 135, allow: true},  // personality
+		{arch: auditArchI386, sysno: 3, allow: true},      // read
+		{arch: auditArchI386, sysno: 136, allow: false},   // personality
+		{arch: auditArchX86_64, sysno: 101, allow: false}, // ptrace
+	} {
+		ret := runFilter(t, prog, tc.arch, tc.sysno)
+		if (ret == retAllow) != tc.allow {
+			t.Errorf("arch %#x syscall %d: got action %#x, want allow=%v", tc.arch, tc.sysno, ret, tc.allow)
+		}
+	}
+
+	config.Syscalls[1].Arches = []string{"bogus"}
+	if _, err := Compile(config); err == nil {
+		t.Error("expected an error for an invalid rule architecture")
+	}
+}
+
+func TestFlagSupported(t *testing.T) {
+	var uf *unknownFlagError
+	if err := FlagSupported("SECCOMP_FILTER_FLAG_BOGUS"); !errors.As(err, &uf) {
+		t.Errorf("expected unknown flag error, got %v", err)
+	}
+	// Whether the flag is supported depends on the kernel, but the only
+	// possible error is unsupportedFlagError.
+	var usf *unsupportedFlagError
+	if err := FlagSupported(specs.LinuxSeccompFlagWaitKillableRecv); err != nil && !errors.As(err, &usf) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestWithFallbackActions(t *testing.T) {
+	errnoRet := uint(38) // ENOSYS
+	for _, tc := range []struct {
+		name     string
+		syscalls []*configs.Syscall
+		expected []configs.Action // nil if config is expected to be returned as is
+	}{
+		{
+			name: "no rules",
+		},
+		{
+			name: "no notify rules",
+			syscalls: []*configs.Syscall{
+				{Name: "read", Action: configs.Allow},
+				{Name: "ptrace", Action: configs.Errno, FallbackAction: configs.Kill},
+			},
+		},
+		{
+			name: "notify without fallback",
+			syscalls: []*configs.Syscall{
+				{Name: "mount", Action: configs.Notify},
+			},
+		},
+		{
+			name: "notify with errno fallback",
+			syscalls: []*configs.Syscall{
+				{Name: "read", Action: configs.Allow},
+				{Name: "mount", Action: configs.Notify, FallbackAction: configs.Errno, ErrnoRet: &errnoRet},
+			},
+			expected: []configs.Action{configs.Allow, configs.Errno},
+		},
+		{
+			name: "notify with allow fallback",
+			syscalls: []*configs.Syscall{
+				{Name: "mknod", Action: configs.Notify, FallbackAction: configs.Allow},
+			},
+			expected: []configs.Action{configs.Allow},
+		},
+		{
+			name: "notify with kill process fallback",
+			syscalls: []*configs.Syscall{
+				{Name: "bpf", Action: configs.Notify, FallbackAction: configs.KillProcess},
+			},
+			expected: []configs.Action{configs.KillProcess},
+		},
+		{
+			name: "notify with log fallback",
+			syscalls: []*configs.Syscall{
+				{Name: "bpf", Action: configs.Notify, FallbackAction: configs.Log},
+			},
+			expected: []configs.Action{configs.Log},
+		},
+		{
+			name: "mixed, with nil rules",
+			syscalls: []*configs.Syscall{
+				nil,
+				{Name: "mount", Action: configs.Notify, FallbackAction: configs.Errno},
+				{Name: "umount2", Action: configs.Notify},
+				nil,
+				{Name: "mknod", Action: configs.Notify, FallbackAction: configs.Trap},
+			},
+			expected: []configs.Action{0, configs.Errno, configs.Notify, 0, configs.Trap},
+		},
+	} {
+		config := &configs.Seccomp{DefaultAction: configs.Errno, Syscalls: tc.syscalls}
+		orig := make([]configs.Syscall, 0, len(tc.syscalls))
+		for _, call := range tc.syscalls {
+			if call != nil {
+				orig = append(orig, *call)
+			}
+		}
+
+		c := withFallbackActions(config)
+		if tc.expected == nil {
+			if c != config {
+				t.Errorf("%s: expected config to be returned as is", tc.name)
+			}
+			continue
+		}
+		if c == config || &c.Syscalls[0] == &config.Syscalls[0] {
+			t.Fatalf("%s: expected a copy of config", tc.name)
+		}
+		if c.DefaultAction != config.DefaultAction || len(c.Syscalls) != len(tc.expected) {
+			t.Fatalf("%s: unexpected config %+v", tc.name, c)
+		}
+		for i, call := range c.Syscalls {
+			if call == nil {
+				if tc.expected[i] != 0 {
+					t.Errorf("%s: rule %d: unexpected nil rule", tc.name, i)
+				}
+				continue
+			}
+			if call.Action != tc.expected[i] {
+				t.Errorf("%s: rule %d (%s): expected action %d, got %d", tc.name, i, call.Name, tc.expected[i], call.Action)
+			}
+			if call.Name != config.Syscalls[i].Name || call.ErrnoRet != config.Syscalls[i].ErrnoRet {
+				t.Errorf("%s: rule %d: expected name and errno of %+v, got %+v", tc.name, i, config.Syscalls[i], call)
+			}
+		}
+		// The original rules are left alone.
+		j := 0
+		for _, call := range tc.syscalls {
+			if call == nil {
+				continue
+			}
+			if !reflect.DeepEqual(*call, orig[j]) {
+				t.Errorf("%s: original rule modified: %+v, was %+v", tc.name, *call, orig[j])
+			}
+			j++
+		}
+	}
+}
@@ -21,6 +21,29 @@ func InitSeccomp(config *configs.Seccomp) (*os.File, error) {
 	return nil, nil
 }
 
+// Compile does nothing because seccomp is not supported.
+func Compile(config *configs.Seccomp) (*Program, error) {
+	return nil, ErrSeccompNotEnabled
+}
+
+// CompileCached does nothing because seccomp is not supported.
+func CompileCached(dir string, config *configs.Seccomp) (*Program, error) {
+	return nil, ErrSeccompNotEnabled
+}
+
+// LoadProgram does nothing because seccomp is not supported.
+func LoadProgram(prog *Program) (*os.File, error) {
+	return nil, ErrSeccompNotEnabled
+}
+
+// Validate reports an error if config is set, because seccomp is not supported.
+func Validate(config *configs.Seccomp) []error {
+	if config != nil {
+		return []error{ErrSeccompNotEnabled}
+	}
+	return nil
+}
+
 // FlagSupported tells if a provided seccomp flag is supported.
 func FlagSupported(_ specs.LinuxSeccompFlag) error {
 	return ErrSeccompNotEnabled
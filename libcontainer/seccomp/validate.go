@@ -0,0 +1,11 @@
+package seccomp
+
+// Warning is an issue reported by Validate which does not prevent the
+// profile from being loaded, such as a redundant rule or an unknown syscall.
+type Warning struct {
+	Msg string
+}
+
+func (w *Warning) Error() string {
+	return w.Msg
+}
@@ -0,0 +1,160 @@
+//go:build cgo && seccomp
+// +build cgo,seccomp
+
+package seccomp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+	libseccomp "github.com/seccomp/libseccomp-golang"
+
+	"github.com/szcdx/runc/libcontainer/configs"
+)
+
+// Validate checks the seccomp profile in config against the libseccomp
+// library and the kernel in use, and returns all the issues found. Issues
+// which do not prevent the profile from being loaded are returned as
+// *Warning; all other errors mean InitSeccomp will fail.
+func Validate(config *configs.Seccomp) []error {
+	if config == nil {
+		return nil
+	}
+	var errs []error
+	errorf := func(format string, a ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, a...))
+	}
+	warnf := func(format string, a ...interface{}) {
+		errs = append(errs, &Warning{Msg: fmt.Sprintf(format, a...)})
+	}
+
+	defaultAction, err := getAction(config.DefaultAction, config.DefaultErrnoRet)
+	if err != nil {
+		errorf("invalid default action: %v", err)
+	} else if defaultAction == libseccomp.ActNotify {
+		errorf("SCMP_ACT_NOTIFY cannot be used as default action")
+	}
+
+	for _, arch := range config.Architectures {
+		if _, err := libseccomp.GetArchFromString(arch); err != nil {
+			errorf("invalid architecture %q: %v", arch, err)
+		}
+	}
+
+	for _, flag := range config.Flags {
+		if err := FlagSupported(flag); err != nil {
+			errorf("flag %s: %v", flag, err)
+		}
+	}
+
+	// Ignore the error since pre-2.4 libseccomp is treated as API level 0.
+	apiLevel, _ := libseccomp.GetAPI()
+
+	// Unconditional rules seen so far, to find duplicates and conflicts.
+	type ruleKey struct {
+		name   string
+		arches string
+	}
+	seen := make(map[ruleKey]libseccomp.ScmpAction)
+
+	for i, call := range config.Syscalls {
+		if call == nil {
+			errorf("rule %d: nil syscall", i)
+			continue
+		}
+		if call.Name == "" {
+			errorf("rule %d: empty syscall name", i)
+			continue
+		}
+		action := call.Action
+		if action == configs.Notify && apiLevel < 6 {
+			if call.FallbackAction == 0 {
+				errorf("syscall %s: seccomp notify is not supported by libseccomp or the kernel (API level %d), and no fallback action is set", call.Name, apiLevel)
+				continue
+			}
+			action = call.FallbackAction
+		}
+		callAct, err := getAction(action, call.ErrnoRet)
+		if err != nil {
+			errorf("syscall %s: %v", call.Name, err)
+			continue
+		}
+		if callAct == libseccomp.ActNotify && call.Name == "write" {
+			errorf("SCMP_ACT_NOTIFY cannot be used for the write syscall")
+		}
+		for _, arch := range call.Arches {
+			if _, err := libseccomp.GetArchFromString(arch); err != nil {
+				errorf("syscall %s: invalid architecture %q: %v", call.Name, arch, err)
+			}
+		}
+		argsOK := true
+		for _, arg := range call.Args {
+			if arg == nil {
+				errorf("syscall %s: nil argument condition", call.Name)
+				argsOK = false
+				continue
+			}
+			if arg.Index >= uint(syscallMaxArguments) {
+				errorf("syscall %s: invalid argument index %d, must be less than %d", call.Name, arg.Index, syscallMaxArguments)
+				argsOK = false
+			}
+			if _, err := getOperator(arg.Op); err != nil {
+				errorf("syscall %s: %v", call.Name, err)
+				argsOK = false
+			}
+		}
+		if !argsOK {
+			continue
+		}
+
+		if _, err := libseccomp.GetSyscallFromName(call.Name); err != nil {
+			warnf("syscall %s is not known to libseccomp, the rule is ignored", call.Name)
+			continue
+		}
+		if callAct == defaultAction {
+			warnf("syscall %s: the rule action is the same as the default action, the rule is redundant", call.Name)
+			continue
+		}
+		if len(call.Args) > 0 {
+			continue
+		}
+		arches := append([]string(nil), call.Arches...)
+		sort.Strings(arches)
+		key := ruleKey{name: call.Name, arches: strings.Join(arches, ",")}
+		if prev, ok := seen[key]; ok {
+			if prev == callAct {
+				warnf("syscall %s: duplicate rule", call.Name)
+			} else {
+				errorf("syscall %s: conflicting rules with different actions", call.Name)
+			}
+			continue
+		}
+		seen[key] = callAct
+	}
+
+	if hasFlag(config, specs.LinuxSeccompFlagWaitKillableRecv) && !hasNotify(config) {
+		warnf("flag %s has no effect without SCMP_ACT_NOTIFY rules", specs.LinuxSeccompFlagWaitKillableRecv)
+	}
+
+	return errs
+}
+
+func hasFlag(config *configs.Seccomp, flag specs.LinuxSeccompFlag) bool {
+	for _, f := range config.Flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+func hasNotify(config *configs.Seccomp) bool {
+	for _, call := range config.Syscalls {
+		if call != nil && call.Action == configs.Notify {
+			return true
+		}
+	}
+	return false
+}
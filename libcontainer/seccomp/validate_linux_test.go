@@ -0,0 +1,47 @@
+//go:build cgo && seccomp
+// +build cgo,seccomp
+
+package seccomp
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/szcdx/runc/libcontainer/configs"
+)
+
+func TestValidate(t *testing.T) {
+	config := &configs.Seccomp{
+		DefaultAction: configs.Errno,
+		Architectures: []string{"amd64"},
+		Syscalls: []*configs.Syscall{
+			{Name: "read", Action: configs.Allow},
+			{Name: "read", Action: configs.Allow},            // duplicate (warning)
+			{Name: "getpid", Action: configs.Errno},          // same as default (warning)
+			{Name: "no_such_syscall", Action: configs.Allow}, // unknown (warning)
+			{Name: "write", Action: configs.Allow},
+			{Name: "write", Action: configs.Kill}, // conflict (error)
+			// Bad argument index (error).
+			{Name: "open", Action: configs.Allow, Args: []*configs.Arg{{Index: 6, Op: configs.EqualTo}}},
+			{Name: "close", Action: configs.Allow, Arches: []string{"bogus"}}, // bad arch (error)
+		},
+	}
+
+	var warns, errs int
+	for _, err := range Validate(config) {
+		var w *Warning
+		if errors.As(err, &w) {
+			warns++
+		} else {
+			errs++
+		}
+	}
+	if warns != 3 || errs != 3 {
+		t.Errorf("expected 3 warnings and 3 errors, got %d and %d: %v", warns, errs, Validate(config))
+	}
+
+	config.Syscalls = config.Syscalls[:1]
+	if errs := Validate(config); len(errs) != 0 {
+		t.Errorf("expected no issues, got %v", errs)
+	}
+}
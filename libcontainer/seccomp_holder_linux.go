@@ -0,0 +1,135 @@
+package libcontainer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"golang.org/x/sys/unix"
+
+	"github.com/szcdx/runc/libcontainer/system"
+)
+
+// The kernel provides no way to get a new notify fd for a seccomp filter
+// which is already loaded, so once the seccomp agent which received the fd
+// exits, the notifications of the container can not be handled anymore.
+// To let a new agent take over, runc can keep a copy of the fd in a small
+// holder process (a "runc init" of the initSeccompHolder type), which lives
+// as long as the container init does. The fd is then obtained from the
+// holder using pidfd_getfd(2) and re-sent to the agent.
+
+const (
+	// seccompHolderFd is the fd number of the seccomp notify fd in the
+	// holder process.
+	seccompHolderFd = 3
+	// seccompHolderPidFd is the fd number of the pidfd of the container
+	// init in the holder process.
+	seccompHolderPidFd = 4
+)
+
+// seccompHolder is the process keeping a copy of the seccomp notify fd.
+type seccompHolder struct {
+	pid       int
+	startTime uint64
+}
+
+// startSeccompHolder starts the process keeping a copy of seccompFd, which
+// is the seccomp notify fd of the container init process initPid.
+func startSeccompHolder(initPid int, seccompFd *os.File) (*seccompHolder, error) {
+	pidFd, err := unix.PidfdOpen(initPid, 0)
+	if err != nil {
+		return nil, os.NewSyscallError("pidfd_open", err)
+	}
+	pidFile := os.NewFile(uintptr(pidFd), "[pidfd]")
+	defer pidFile.Close()
+
+	cmd := exec.Command("/proc/self/exe", "init")
+	cmd.Env = []string{"_LIBCONTAINER_INITTYPE=" + string(initSeccompHolder)}
+	cmd.ExtraFiles = []*os.File{seccompFd, pidFile}
+	// Detach the holder, so that it is not killed together with runc.
+	cmd.SysProcAttr = &unix.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("unable to start seccomp fd holder: %w", err)
+	}
+	stat, err := system.Stat(cmd.Process.Pid)
+	if err != nil {
+		_ = cmd.Process.Kill()
+		_, _ = cmd.Process.Wait()
+		return nil, fmt.Errorf("unable to start seccomp fd holder: %w", err)
+	}
+	h := &seccompHolder{pid: cmd.Process.Pid, startTime: stat.StartTime}
+	_ = cmd.Process.Release()
+	return h, nil
+}
+
+// seccompHolderMain is the main function of the holder process. It keeps
+// the seccomp notify fd open until the container init exits.
+func seccompHolderMain() error {
+	unix.CloseOnExec(seccompHolderFd)
+	unix.CloseOnExec(seccompHolderPidFd)
+	fds := []unix.PollFd{{Fd: seccompHolderPidFd, Events: unix.POLLIN}}
+	for {
+		// The pidfd becomes readable once the process exits.
+		_, err := unix.Poll(fds, -1)
+		if errors.Is(err, unix.EINTR) {
+			continue
+		}
+		return os.NewSyscallError("poll", err)
+	}
+}
+
+// getFd returns a copy of the seccomp notify fd kept by the holder.
+func (h *seccompHolder) getFd() (*os.File, error) {
+	pidfd, err := openPidfd(h.pid, h.startTime)
+	if err != nil {
+		return nil, fmt.Errorf("seccomp fd holder process: %w", err)
+	}
+	defer pidfd.Close()
+	fd, err := unix.PidfdGetfd(int(pidfd.Fd()), seccompHolderFd, 0)
+	if err != nil {
+		return nil, os.NewSyscallError("pidfd_getfd", err)
+	}
+	return os.NewFile(uintptr(fd), "[pidfd_getfd]"), nil
+}
+
+// ResendSeccompNotifyFd sends the seccomp notify fd of the container init
+// to the seccomp agent listening on Seccomp.ListenerPath again, so that a
+// new agent can take over handling the notifications of a running
+// container, for example after the previous agent was upgraded. It is only
+// possible if Seccomp.KeepListenerFd was set when the container was created.
+func (c *Container) ResendSeccompNotifyFd() error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	status, err := c.currentStatus()
+	if err != nil {
+		return err
+	}
+	if status == Stopped {
+		return ErrNotRunning
+	}
+	if c.config.Seccomp == nil || c.config.Seccomp.ListenerPath == "" {
+		return errors.New("container has no seccomp listener")
+	}
+	if c.seccompHolder == nil {
+		return errors.New("seccomp notify fd of the container was not kept")
+	}
+	seccompFd, err := c.seccompHolder.getFd()
+	if err != nil {
+		return err
+	}
+	defer seccompFd.Close()
+
+	s, err := c.currentOCIState()
+	if err != nil {
+		return err
+	}
+	return sendContainerProcessState(c.config.Seccomp.ListenerPath, &specs.ContainerProcessState{
+		Version:  specs.Version,
+		Fds:      []string{specs.SeccompFdName},
+		Pid:      s.Pid,
+		Metadata: c.config.Seccomp.ListenerMetadata,
+		State:    *s,
+	}, seccompFd)
+}
@@ -3,6 +3,7 @@ package libcontainer
 import (
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"strconv"
@@ -12,8 +13,8 @@ import (
 	"golang.org/x/sys/unix"
 
 	"github.com/szcdx/runc/libcontainer/apparmor"
+	"github.com/szcdx/runc/libcontainer/dmz"
 	"github.com/szcdx/runc/libcontainer/keys"
-	"github.com/szcdx/runc/libcontainer/seccomp"
 	"github.com/szcdx/runc/libcontainer/system"
 )
 
@@ -26,6 +27,7 @@ type linuxSetnsInit struct {
 	config        *initConfig
 	logFd         int
 	dmzExe        *os.File
+	exe           *os.File
 }
 
 func (l *linuxSetnsInit) getSessionRingName() string {
@@ -33,6 +35,15 @@ func (l *linuxSetnsInit) getSessionRingName() string {
 }
 
 func (l *linuxSetnsInit) Init() error {
+	// Done first, as the container seccomp profile may not allow it.
+	if l.exe != nil && isScript(l.exe) {
+		exe, err := cloneScript(l.exe)
+		if err != nil {
+			return err
+		}
+		l.exe.Close()
+		l.exe = exe
+	}
 	if !l.config.Config.NoNewKeyring {
 		if err := selinux.SetKeyLabel(l.config.ProcessLabel); err != nil {
 			return err
@@ -85,7 +96,7 @@ func (l *linuxSetnsInit) Init() error {
 	// do this before dropping capabilities; otherwise do it as late as possible
 	// just before execve so as few syscalls take place after it as possible.
 	if l.config.Config.Seccomp != nil && !l.config.NoNewPrivileges {
-		seccompFd, err := seccomp.InitSeccomp(l.config.Config.Seccomp)
+		seccompFd, err := initSeccomp(l.config)
 		if err != nil {
 			return err
 		}
@@ -104,23 +115,27 @@ func (l *linuxSetnsInit) Init() error {
 			return err
 		}
 	}
-	// Check for the arg early to make sure it exists.
-	name, err := exec.LookPath(l.config.Args[0])
-	if err != nil {
-		return err
-	}
-	// exec.LookPath in Go < 1.20 might return no error for an executable
-	// residing on a file system mounted with noexec flag, so perform this
-	// extra check now while we can still return a proper error.
-	// TODO: remove this once go < 1.20 is not supported.
-	if err := eaccess(name); err != nil {
-		return &os.PathError{Op: "eaccess", Path: name, Err: err}
+	var name string
+	if l.exe == nil {
+		// Check for the arg early to make sure it exists.
+		var err error
+		name, err = exec.LookPath(l.config.Args[0])
+		if err != nil {
+			return err
+		}
+		// exec.LookPath in Go < 1.20 might return no error for an executable
+		// residing on a file system mounted with noexec flag, so perform this
+		// extra check now while we can still return a proper error.
+		// TODO: remove this once go < 1.20 is not supported.
+		if err := eaccess(name); err != nil {
+			return &os.PathError{Op: "eaccess", Path: name, Err: err}
+		}
 	}
 	// Set seccomp as close to execve as possible, so as few syscalls take
 	// place afterward (reducing the amount of syscalls that users need to
 	// enable in their seccomp profiles).
 	if l.config.Config.Seccomp != nil && l.config.NoNewPrivileges {
-		seccompFd, err := seccomp.InitSeccomp(l.config.Config.Seccomp)
+		seccompFd, err := initSeccomp(l.config)
 		if err != nil {
 			return fmt.Errorf("unable to init seccomp: %w", err)
 		}
@@ -135,9 +150,50 @@ func (l *linuxSetnsInit) Init() error {
 		return &os.PathError{Op: "close log pipe", Path: "fd " + strconv.Itoa(l.logFd), Err: err}
 	}
 
+	if l.exe != nil {
+		// The executable was opened by the parent (possibly on the host),
+		// so execute it directly. Its fd is closed on exec, but for the
+		// copy of a script (see cloneScript), which its interpreter opens
+		// as /dev/fd/N.
+		if isScript(l.exe) {
+			if _, err := unix.FcntlInt(l.exe.Fd(), unix.F_SETFD, 0); err != nil {
+				return &os.PathError{Op: "fcntl", Path: l.exe.Name(), Err: err}
+			}
+		}
+		return system.Fexecve(l.exe.Fd(), l.config.Args, os.Environ())
+	}
 	if l.dmzExe != nil {
 		l.config.Args[0] = name
 		return system.Fexecve(l.dmzExe.Fd(), l.config.Args, os.Environ())
 	}
 	return system.Exec(name, l.config.Args, os.Environ())
 }
+
+// isScript returns whether the executable f is a script, starting with "#!".
+func isScript(f *os.File) bool {
+	var b [2]byte
+	n, _ := f.ReadAt(b[:], 0)
+	return n == len(b) && string(b[:]) == "#!"
+}
+
+// cloneScript returns a sealed copy of the script f. The interpreter of a
+// script executed from an fd opens it as /dev/fd/N, so the fd has to be
+// left open on exec, and the script process can then use it. Leaving it a
+// copy, rather than the file opened by the parent (possibly on the host),
+// keeps the file itself out of its reach.
+func cloneScript(f *os.File) (*os.File, error) {
+	st, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	exe, err := dmz.CloneBinary(io.NewSectionReader(f, 0, st.Size()), st.Size(), "script", "")
+	if err != nil {
+		return nil, fmt.Errorf("unable to copy script: %w", err)
+	}
+	// Readable by the interpreter, whatever the user of the process.
+	if err := exe.Chmod(0o555); err != nil {
+		exe.Close()
+		return nil, fmt.Errorf("unable to copy script: %w", err)
+	}
+	return exe, nil
+}
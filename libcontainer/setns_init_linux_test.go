@@ -0,0 +1,60 @@
+package libcontainer
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestCloneScript(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hook.sh")
+	content := "#!/bin/sh\necho script\n"
+	if err := os.WriteFile(path, []byte(content), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if !isScript(f) {
+		t.Fatal("expected a script")
+	}
+
+	exe, err := cloneScript(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer exe.Close()
+	// The copy does not lead to the script file.
+	link, err := os.Readlink("/proc/self/fd/" + strconv.Itoa(int(exe.Fd())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(link, "hook.sh") {
+		t.Errorf("expected a copy of the script, got %s", link)
+	}
+	// It can be opened again as /dev/fd/N by the interpreter, whatever
+	// its user, but not modified.
+	st, err := exe.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := st.Mode().Perm(); mode != 0o555 {
+		t.Errorf("expected mode 0555, got %#o", mode)
+	}
+	data, err := os.ReadFile("/proc/self/fd/" + strconv.Itoa(int(exe.Fd())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != content {
+		t.Errorf("expected %q, got %q", content, data)
+	}
+	if _, err := unix.Pwrite(int(exe.Fd()), []byte("#"), 0); err == nil {
+		t.Error("expected the copy to be sealed")
+	}
+}
@@ -3,11 +3,14 @@
 package specconv
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net/netip"
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -133,7 +136,9 @@ func initMaps() {
 			clear bool
 			flag  int
 		}{
-			"tmpcopyup": {false, configs.EXT_COPYUP},
+			"tmpcopyup":     {false, configs.EXT_COPYUP},
+			"managed=true":  {false, configs.EXT_MANAGED},
+			"managed=false": {true, configs.EXT_MANAGED},
 		}
 
 		complexFlags = map[string]func(*configs.Mount){
@@ -318,13 +323,14 @@ var AllowedDevices = []*devices.Device{
 }
 
 type CreateOpts struct {
-	CgroupName       string
-	UseSystemdCgroup bool
-	NoPivotRoot      bool
-	NoNewKeyring     bool
-	Spec             *specs.Spec
-	RootlessEUID     bool
-	RootlessCgroups  bool
+	CgroupName                  string
+	UseSystemdCgroup            bool
+	NoPivotRoot                 bool
+	NoNewKeyring                bool
+	AllowNamespaceOwnerMismatch bool
+	Spec                        *specs.Spec
+	RootlessEUID                bool
+	RootlessCgroups             bool
 }
 
 // getwd is a wrapper similar to os.Getwd, except it always gets
@@ -370,6 +376,8 @@ func CreateLibcontainerConfig(opts *CreateOpts) (*configs.Config, error) {
 		NoNewKeyring:    opts.NoNewKeyring,
 		RootlessEUID:    opts.RootlessEUID,
 		RootlessCgroups: opts.RootlessCgroups,
+
+		AllowNamespaceOwnerMismatch: opts.AllowNamespaceOwnerMismatch,
 	}
 
 	for _, m := range spec.Mounts {
@@ -421,6 +429,23 @@ func CreateLibcontainerConfig(opts *CreateOpts) (*configs.Config, error) {
 					Type: "loopback",
 				},
 			}
+			if val := spec.Annotations[networksAnnotation]; val != "" {
+				var networks []*configs.Network
+				if err := json.Unmarshal([]byte(val), &networks); err != nil {
+					return nil, fmt.Errorf("annotation %s: %w", networksAnnotation, err)
+				}
+				config.Networks = append(config.Networks, networks...)
+			}
+		}
+		if val := spec.Annotations[portForwardsAnnotation]; val != "" {
+			if err := json.Unmarshal([]byte(val), &config.PortForwards); err != nil {
+				return nil, fmt.Errorf("annotation %s: %w", portForwardsAnnotation, err)
+			}
+		}
+		if val := spec.Annotations[rootlessNetworkAnnotation]; val != "" {
+			if err := json.Unmarshal([]byte(val), &config.RootlessNetwork); err != nil {
+				return nil, fmt.Errorf("annotation %s: %w", rootlessNetworkAnnotation, err)
+			}
 		}
 		if config.Namespaces.Contains(configs.NEWUSER) {
 			if err := setupUserNamespace(spec, config); err != nil {
@@ -445,11 +470,39 @@ func CreateLibcontainerConfig(opts *CreateOpts) (*configs.Config, error) {
 		config.MountLabel = spec.Linux.MountLabel
 		config.Sysctl = spec.Linux.Sysctl
 		config.TimeOffsets = spec.Linux.TimeOffsets
+		if err := setupTime(spec, config); err != nil {
+			return nil, err
+		}
+		if err := setupNetSysctl(spec, config); err != nil {
+			return nil, err
+		}
+		if err := setupFirewall(spec, config); err != nil {
+			return nil, err
+		}
+		if err := setupStop(spec, config); err != nil {
+			return nil, err
+		}
+		if err := setupMountDrivers(spec, config); err != nil {
+			return nil, err
+		}
+		if err := setupExecRateLimit(spec, config); err != nil {
+			return nil, err
+		}
+		if val := spec.Annotations[exclusiveCPUsAnnotation]; val != "" {
+			n, err := strconv.Atoi(val)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("annotation %s: invalid number of CPUs %q", exclusiveCPUsAnnotation, val)
+			}
+			config.ExclusiveCPUs = n
+		}
 		if spec.Linux.Seccomp != nil {
 			seccomp, err := SetupSeccomp(spec.Linux.Seccomp)
 			if err != nil {
 				return nil, err
 			}
+			if seccomp != nil && spec.Annotations[seccompKeepListenerFdAnnotation] == "true" {
+				seccomp.KeepListenerFd = true
+			}
 			config.Seccomp = seccomp
 		}
 		if spec.Linux.IntelRdt != nil {
@@ -457,7 +510,17 @@ func CreateLibcontainerConfig(opts *CreateOpts) (*configs.Config, error) {
 				ClosID:        spec.Linux.IntelRdt.ClosID,
 				L3CacheSchema: spec.Linux.IntelRdt.L3CacheSchema,
 				MemBwSchema:   spec.Linux.IntelRdt.MemBwSchema,
+				EnableCMT:     spec.Linux.IntelRdt.EnableCMT,
+				EnableMBM:     spec.Linux.IntelRdt.EnableMBM,
+			}
+			config.IntelRdt.EnableMonitoring = spec.Linux.IntelRdt.EnableCMT || spec.Linux.IntelRdt.EnableMBM
+		}
+		if spec.Annotations[intelRdtMonitoringAnnotation] == "true" {
+			logrus.Warnf("annotation %s is deprecated, use linux.intelRdt.enableCMT or linux.intelRdt.enableMBM instead", intelRdtMonitoringAnnotation)
+			if config.IntelRdt == nil {
+				config.IntelRdt = &configs.IntelRdt{}
 			}
+			config.IntelRdt.EnableMonitoring = true
 		}
 		if spec.Linux.Personality != nil {
 			if len(spec.Linux.Personality.Flags) > 0 {
@@ -499,7 +562,21 @@ func CreateLibcontainerConfig(opts *CreateOpts) (*configs.Config, error) {
 		// user namespace).
 		processUid = int(spec.Process.User.UID)
 	}
-	if hasCgroupNS && hasRwCgroupfs {
+	if config.Cgroups.Delegate {
+		// The cgroup is delegated to the container's root user, no
+		// matter how the cgroupfs is mounted (the validator checks
+		// that there are user and cgroup namespaces).
+		ownerUid, err := config.HostRootUID()
+		if err != nil {
+			return nil, fmt.Errorf("annotation %s: %w", cgroupDelegateAnnotation, err)
+		}
+		ownerGid, err := config.HostRootGID()
+		if err != nil {
+			return nil, fmt.Errorf("annotation %s: %w", cgroupDelegateAnnotation, err)
+		}
+		config.Cgroups.OwnerUID = &ownerUid
+		config.Cgroups.OwnerGID = &ownerGid
+	} else if hasCgroupNS && hasRwCgroupfs {
 		ownerUid, err := config.HostUID(processUid)
 		// There are two error cases; we can ignore both.
 		//
@@ -535,7 +612,16 @@ func CreateLibcontainerConfig(opts *CreateOpts) (*configs.Config, error) {
 			config.Scheduler = &s
 		}
 	}
+	config.LaunchRecord = spec.Annotations[launchRecordAnnotation] == "true"
+	config.LaunchRecordRootfs = config.LaunchRecord && spec.Annotations[launchRecordRootfsAnnotation] == "true"
+	config.ProcLimits = spec.Annotations[procLimitsAnnotation] == "true"
 	createHooks(spec, config)
+	if err := setupNetworkRestoreHooks(spec, config); err != nil {
+		return nil, err
+	}
+	if err := setupInContainerHooks(spec, config); err != nil {
+		return nil, err
+	}
 	config.Version = specs.Version
 	return config, nil
 }
@@ -572,7 +658,7 @@ func createLibcontainerMount(cwd string, m specs.Mount) (*configs.Mount, error)
 		// bind-mounts -- so we set it to "bind" because rootfs_linux.go
 		// (incorrectly) relies on this for some checks.
 		mnt.Device = "bind"
-		if !filepath.IsAbs(mnt.Source) {
+		if !filepath.IsAbs(mnt.Source) && !mnt.IsFdSource() {
 			mnt.Source = filepath.Join(cwd, m.Source)
 		}
 	}
@@ -667,29 +753,432 @@ func initSystemdProps(spec *specs.Spec) ([]systemdDbus.Property, error) {
 		if len(name) == len(k) { // prefix not there
 			continue
 		}
-		if err := checkPropertyName(name); err != nil {
-			return nil, fmt.Errorf("annotation %s name incorrect: %w", k, err)
+		prop, err := ParseSystemdProperty(name, v)
+		if err != nil {
+			return nil, fmt.Errorf("annotation %s: %w", k, err)
 		}
-		value, err := dbus.ParseVariant(v, dbus.Signature{})
+		sp = append(sp, prop)
+	}
+
+	return sp, nil
+}
+
+// ParseSystemdProperty parses a systemd unit property, as given in an
+// org.systemd.property.<name> annotation (or to runc update). The value is
+// in the GVariant text format. The properties which are documented with the
+// "Sec" suffix (such as TimeoutStopSec) are converted to their "USec"
+// counterparts.
+func ParseSystemdProperty(name, v string) (systemdDbus.Property, error) {
+	if err := checkPropertyName(name); err != nil {
+		return systemdDbus.Property{}, fmt.Errorf("property name %q incorrect: %w", name, err)
+	}
+	value, err := dbus.ParseVariant(v, dbus.Signature{})
+	if err != nil {
+		return systemdDbus.Property{}, fmt.Errorf("property %s=%s value parse error: %w", name, v, err)
+	}
+	// Check for Sec suffix.
+	if trimName := strings.TrimSuffix(name, "Sec"); len(trimName) < len(name) {
+		// Check for a lowercase ascii a-z just before Sec.
+		if ch := trimName[len(trimName)-1]; ch >= 'a' && ch <= 'z' {
+			// Convert from Sec to USec.
+			value, err = convertSecToUSec(value)
+			if err != nil {
+				return systemdDbus.Property{}, fmt.Errorf("property %s=%s value parse error: %w", name, v, err)
+			}
+			name = trimName + "USec"
+		}
+	}
+	return systemdDbus.Property{Name: name, Value: value}, nil
+}
+
+// unifiedAllowUnknownAnnotation is the annotation which allows the unified
+// cgroup resources (linux.resources.unified) to contain files unknown to
+// runc (see configs.Resources.UnifiedAllowUnknown).
+const unifiedAllowUnknownAnnotation = "org.opencontainers.runc.cgroup.unified.allow-unknown"
+
+// cgroupAccountingOnlyAnnotation is the annotation which makes runc use the
+// container's cgroup for resource accounting only (see
+// configs.Cgroup.AccountingOnly), if set to "true".
+const cgroupAccountingOnlyAnnotation = "org.opencontainers.runc.cgroup.accounting-only"
+
+// systemdFallbackAnnotation is the annotation which makes runc fall back
+// to the fs cgroup manager if systemd can not be reached when the container
+// is created (see configs.Cgroup.SystemdFallback), if set to "true".
+const systemdFallbackAnnotation = "org.opencontainers.runc.systemd.fallback"
+
+// cgroupDelegateAnnotation is the annotation which makes runc delegate the
+// container's cgroup to the container's root user (see
+// configs.Cgroup.Delegate), if set to "true".
+const cgroupDelegateAnnotation = "org.opencontainers.runc.cgroup.delegate"
+
+// cgroupReadOnlyAnnotation is the annotation which sets what to do if the
+// cgroup filesystem is read-only: "fail" (the default), or "skip-limits" to
+// run the container without a cgroup of its own (see
+// configs.Cgroup.SkipLimitsIfReadOnly).
+const cgroupReadOnlyAnnotation = "org.opencontainers.runc.cgroup.read-only"
+
+// cpusetPartitionAnnotation is the annotation which sets the cpuset
+// partition type of the container's cgroup (see
+// configs.Resources.CpusetPartition).
+const cpusetPartitionAnnotation = "org.opencontainers.runc.cpuset.partition"
+
+// hugetlbAccountingAnnotation is the annotation which chooses between the
+// page fault and the reservation based hugetlb limits (see
+// configs.Resources.HugetlbAccounting).
+const hugetlbAccountingAnnotation = "org.opencontainers.runc.hugetlb.accounting"
+
+// devicesPinDirAnnotation is the annotation which sets the bpf filesystem
+// directory where the device filter program is pinned, to be shared with
+// the containers having the same device rules (see
+// configs.Resources.DevicesPinDir).
+const devicesPinDirAnnotation = "org.opencontainers.runc.devices.bpf-pin-dir"
+
+// ioLatencyAnnotation is the annotation which sets the IO latency targets of
+// the container's cgroup (see configs.Resources.BlkioLatencyDevice), as a
+// comma separated list of major:minor=target pairs, the targets being
+// durations such as "10ms".
+const ioLatencyAnnotation = "org.opencontainers.runc.io.latency"
+
+// seccompKeepListenerFdAnnotation is the annotation which makes runc keep a
+// copy of the seccomp notify fd, so it can be re-sent to the seccomp agent
+// (see configs.Seccomp.KeepListenerFd).
+const seccompKeepListenerFdAnnotation = "org.opencontainers.runc.seccomp.keep-listener-fd"
+
+// launchRecordAnnotation is the annotation which makes runc record the
+// measurement of what the container is launched with, when set to "true"
+// (see configs.Config.LaunchRecord).
+const launchRecordAnnotation = "org.opencontainers.runc.launch-record"
+
+// launchRecordRootfsAnnotation is the annotation which makes the launch
+// record include the measurement of the root filesystem, when set to "true"
+// (see configs.Config.LaunchRecordRootfs).
+const launchRecordRootfsAnnotation = "org.opencontainers.runc.launch-record.rootfs"
+
+// procLimitsAnnotation is the annotation which makes runc mask the /proc and
+// /sys files reporting the memory and CPUs of the host with ones reflecting
+// the cgroup limits of the container, when set to "true" (see
+// configs.Config.ProcLimits).
+const procLimitsAnnotation = "org.opencontainers.runc.proc-limits"
+
+// networkRestoreHooksAnnotation is the annotation which sets the hooks run
+// once the container is restored, to set up its network again (see
+// configs.NetworkRestore), as a JSON array of hooks in the format of the OCI
+// runtime spec.
+const networkRestoreHooksAnnotation = "org.opencontainers.runc.hooks.network-restore"
+
+// inContainerHooksAnnotation is the annotation which sets the hooks run
+// inside the container namespaces once it is created (see
+// configs.InContainerHook), as a JSON array of hooks in the format of the
+// OCI runtime spec, with an optional "dir" (in the container) and
+// "capabilities" (in the format of process.capabilities, none if not set).
+const inContainerHooksAnnotation = "org.opencontainers.runc.hooks.in-container"
+
+// exclusiveCPUsAnnotation is the annotation which sets the number of CPUs
+// to allocate to the container for its exclusive use, from the CPU pool
+// (see configs.Config.ExclusiveCPUs).
+const exclusiveCPUsAnnotation = "org.opencontainers.runc.cpus.exclusive"
+
+// networksAnnotation is the annotation which sets the network interfaces
+// runc creates in the new network namespace of the container, such as veth
+// pairs attached to a bridge or macvlan interfaces (see configs.Network),
+// as a JSON array of configs.Network.
+const networksAnnotation = "org.opencontainers.runc.networks"
+
+// portForwardsAnnotation is the annotation which sets the host ports
+// forwarded into the network namespace of the container (see
+// configs.PortForward), as a JSON array of configs.PortForward.
+const portForwardsAnnotation = "org.opencontainers.runc.port-forwards"
+
+// rootlessNetworkAnnotation is the annotation which sets the user-mode
+// network stack (slirp4netns or pasta) runc starts for the network namespace
+// of the container (see configs.RootlessNetwork), as a JSON
+// configs.RootlessNetwork, such as {"mode": "pasta"}.
+const rootlessNetworkAnnotation = "org.opencontainers.runc.rootless-network"
+
+// intelRdtMonitoringAnnotation is the annotation which, set to "true",
+// creates an Intel RDT monitoring group for the container (see
+// configs.IntelRdt.EnableMonitoring), with or without linux.intelRdt.
+//
+// Deprecated: use linux.intelRdt.enableCMT or linux.intelRdt.enableMBM.
+const intelRdtMonitoringAnnotation = "org.opencontainers.runc.intelrdt.enable-monitoring"
+
+// timezoneAnnotation is the annotation which sets the container's timezone
+// (see configs.Time). Its value is either a timezone name, such as
+// "Europe/Berlin", or an absolute path to a timezone file on the host.
+const timezoneAnnotation = "org.opencontainers.runc.time.timezone"
+
+// timeOffsetsAnnotation is the annotation which gives the container a new
+// time namespace, with the clock offsets set to its value, which is in the
+// "CLOCK=DURATION[,CLOCK=DURATION]" format, such as "boottime=-1h30m".
+const timeOffsetsAnnotation = "org.opencontainers.runc.time.offsets"
+
+// setupTime sets the time configuration of the container from the
+// annotations of the spec.
+func setupTime(spec *specs.Spec, config *configs.Config) error {
+	if tz := spec.Annotations[timezoneAnnotation]; tz != "" {
+		if filepath.IsAbs(tz) {
+			config.Time = &configs.Time{TimezoneFile: tz}
+		} else {
+			config.Time = &configs.Time{Timezone: tz}
+		}
+	}
+	val := spec.Annotations[timeOffsetsAnnotation]
+	if val == "" {
+		return nil
+	}
+	if config.TimeOffsets != nil || config.Namespaces.PathOf(configs.NEWTIME) != "" {
+		return fmt.Errorf("annotation %s can't be used together with linux.timeOffsets or a time namespace path", timeOffsetsAnnotation)
+	}
+	offsets := make(map[string]specs.LinuxTimeOffset)
+	for _, o := range strings.Split(val, ",") {
+		clock, d, ok := strings.Cut(o, "=")
+		if !ok || (clock != "boottime" && clock != "monotonic") {
+			return fmt.Errorf("annotation %s: invalid clock offset %q", timeOffsetsAnnotation, o)
+		}
+		offset, err := time.ParseDuration(d)
 		if err != nil {
-			return nil, fmt.Errorf("annotation %s=%s value parse error: %w", k, v, err)
-		}
-		// Check for Sec suffix.
-		if trimName := strings.TrimSuffix(name, "Sec"); len(trimName) < len(name) {
-			// Check for a lowercase ascii a-z just before Sec.
-			if ch := trimName[len(trimName)-1]; ch >= 'a' && ch <= 'z' {
-				// Convert from Sec to USec.
-				name = trimName + "USec"
-				value, err = convertSecToUSec(value)
+			return fmt.Errorf("annotation %s: %w", timeOffsetsAnnotation, err)
+		}
+		secs, nsecs := int64(offset/time.Second), int64(offset%time.Second)
+		if nsecs < 0 {
+			// Nanoseconds must be positive.
+			secs--
+			nsecs += int64(time.Second)
+		}
+		offsets[clock] = specs.LinuxTimeOffset{Secs: secs, Nanosecs: uint32(nsecs)}
+	}
+	if !config.Namespaces.Contains(configs.NEWTIME) {
+		config.Namespaces.Add(configs.NEWTIME, "")
+	}
+	config.TimeOffsets = offsets
+	return nil
+}
+
+// pingGroupRangeAnnotation is the annotation which sets the range of group
+// IDs allowed to create ICMP echo sockets, in the "<start>-<end>" format.
+const pingGroupRangeAnnotation = "org.opencontainers.runc.net.ping-group-range"
+
+// unprivilegedPortStartAnnotation is the annotation which sets the first
+// port unprivileged processes can bind to.
+const unprivilegedPortStartAnnotation = "org.opencontainers.runc.net.unprivileged-port-start"
+
+// setupNetSysctl sets the typed network sysctls of the container from the
+// annotations.
+func setupNetSysctl(spec *specs.Spec, config *configs.Config) error {
+	var n configs.NetSysctl
+	if val := spec.Annotations[pingGroupRangeAnnotation]; val != "" {
+		start, end, ok := strings.Cut(val, "-")
+		s, err1 := strconv.ParseUint(start, 10, 32)
+		e, err2 := strconv.ParseUint(end, 10, 32)
+		if !ok || err1 != nil || err2 != nil {
+			return fmt.Errorf("annotation %s: invalid group range %q", pingGroupRangeAnnotation, val)
+		}
+		n.PingGroupRange = &configs.GroupRange{Start: uint32(s), End: uint32(e)}
+	}
+	if val := spec.Annotations[unprivilegedPortStartAnnotation]; val != "" {
+		p, err := strconv.ParseUint(val, 10, 16)
+		if err != nil {
+			return fmt.Errorf("annotation %s: invalid port %q", unprivilegedPortStartAnnotation, val)
+		}
+		port := uint16(p)
+		n.UnprivilegedPortStart = &port
+	}
+	if n != (configs.NetSysctl{}) {
+		config.NetSysctl = &n
+	}
+	return nil
+}
+
+// firewallAnnotation is the annotation which sets up the container's
+// firewall (see configs.Firewall). Its value is a comma-separated list of
+// the rules for the incoming traffic to allow, each being a source network
+// and/or a "PROTOCOL[/PORT[-PORT]]", separated by a space, such as
+// "tcp/80, 10.0.0.0/8 tcp/22". The value "none" allows no incoming traffic.
+const firewallAnnotation = "org.opencontainers.runc.net.firewall"
+
+// setupFirewall sets the container's firewall from the annotations.
+func setupFirewall(spec *specs.Spec, config *configs.Config) error {
+	val := spec.Annotations[firewallAnnotation]
+	if val == "" {
+		return nil
+	}
+	fw := &configs.Firewall{}
+	if val == "none" {
+		config.Firewall = fw
+		return nil
+	}
+	for _, r := range strings.Split(val, ",") {
+		rule := &configs.FirewallRule{}
+		fields := strings.Fields(r)
+		if len(fields) == 0 || len(fields) > 2 {
+			return fmt.Errorf("annotation %s: invalid rule %q", firewallAnnotation, r)
+		}
+		for _, f := range fields {
+			if _, err := netip.ParsePrefix(f); err == nil && rule.Source == "" {
+				rule.Source = f
+				continue
+			}
+			proto, ports, _ := strings.Cut(f, "/")
+			if rule.Protocol != "" || (proto != "tcp" && proto != "udp") {
+				return fmt.Errorf("annotation %s: invalid rule %q", firewallAnnotation, r)
+			}
+			rule.Protocol = proto
+			if ports == "" {
+				continue
+			}
+			first, last, isRange := strings.Cut(ports, "-")
+			p, err := strconv.ParseUint(first, 10, 16)
+			if err != nil {
+				return fmt.Errorf("annotation %s: invalid port %q", firewallAnnotation, first)
+			}
+			rule.Port = uint16(p)
+			if isRange {
+				p, err := strconv.ParseUint(last, 10, 16)
 				if err != nil {
-					return nil, fmt.Errorf("annotation %s=%s value parse error: %w", k, v, err)
+					return fmt.Errorf("annotation %s: invalid port %q", firewallAnnotation, last)
 				}
+				rule.PortEnd = uint16(p)
 			}
 		}
-		sp = append(sp, systemdDbus.Property{Name: name, Value: value})
+		fw.Allow = append(fw.Allow, rule)
 	}
+	config.Firewall = fw
+	return nil
+}
 
-	return sp, nil
+// mountDriverAnnotationPrefix is the prefix of the annotations which set the
+// mount driver for a type of mounts, such as
+// "org.opencontainers.runc.mount-driver.nfs", the value being the absolute
+// path of the driver (see docs/mount-drivers.md).
+const mountDriverAnnotationPrefix = "org.opencontainers.runc.mount-driver."
+
+// setupMountDrivers sets the drivers of the mounts from the annotations.
+func setupMountDrivers(spec *specs.Spec, config *configs.Config) error {
+	for k, v := range spec.Annotations {
+		fstype, ok := strings.CutPrefix(k, mountDriverAnnotationPrefix)
+		if !ok {
+			continue
+		}
+		if fstype == "" || !filepath.IsAbs(v) {
+			return fmt.Errorf("annotation %s: invalid mount driver %q", k, v)
+		}
+		for _, m := range config.Mounts {
+			if m.Device == fstype {
+				m.Driver = v
+			}
+		}
+	}
+	return nil
+}
+
+// stopSignalAnnotation is the annotation which sets the container's stop
+// signal, by name (such as "SIGINT") or by number.
+const stopSignalAnnotation = "org.opencontainers.runc.stop.signal"
+
+// stopGracePeriodAnnotation is the annotation which sets how long the
+// container is given to exit after the stop signal, such as "30s".
+const stopGracePeriodAnnotation = "org.opencontainers.runc.stop.grace-period"
+
+// setupStop sets how the container is stopped from the annotations.
+func setupStop(spec *specs.Spec, config *configs.Config) error {
+	var stop configs.Stop
+	if val := spec.Annotations[stopSignalAnnotation]; val != "" {
+		sig, err := libcontainerUtils.ParseSignal(val)
+		if err != nil {
+			return fmt.Errorf("annotation %s: %w", stopSignalAnnotation, err)
+		}
+		stop.Signal = int(sig)
+	}
+	if val := spec.Annotations[stopGracePeriodAnnotation]; val != "" {
+		d, err := time.ParseDuration(val)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("annotation %s: invalid grace period %q", stopGracePeriodAnnotation, val)
+		}
+		stop.GracePeriod = d
+	}
+	if stop != (configs.Stop{}) {
+		config.Stop = &stop
+	}
+	return nil
+}
+
+// execRateAnnotation is the annotation which limits the rate at which
+// processes can be started in the container, in processes per second
+// (see configs.ExecRateLimit).
+const execRateAnnotation = "org.opencontainers.runc.exec.rate"
+
+// execBurstAnnotation is the annotation which sets how many processes can
+// be started in the container at once, if their rate is limited.
+const execBurstAnnotation = "org.opencontainers.runc.exec.burst"
+
+// setupExecRateLimit sets the limit of the process start rate of the
+// container from the annotations.
+func setupExecRateLimit(spec *specs.Spec, config *configs.Config) error {
+	val := spec.Annotations[execRateAnnotation]
+	if val == "" {
+		if _, ok := spec.Annotations[execBurstAnnotation]; ok {
+			return fmt.Errorf("annotation %s requires %s", execBurstAnnotation, execRateAnnotation)
+		}
+		return nil
+	}
+	rate, err := strconv.ParseFloat(val, 64)
+	if err != nil || !(rate > 0) {
+		return fmt.Errorf("annotation %s: invalid rate %q", execRateAnnotation, val)
+	}
+	l := &configs.ExecRateLimit{Rate: rate}
+	if val := spec.Annotations[execBurstAnnotation]; val != "" {
+		burst, err := strconv.Atoi(val)
+		if err != nil || burst <= 0 {
+			return fmt.Errorf("annotation %s: invalid burst %q", execBurstAnnotation, val)
+		}
+		l.Burst = burst
+	}
+	config.ExecRateLimit = l
+	return nil
+}
+
+// expandCgroupsPath expands the placeholders in the cgroupsPath of the
+// container with the given id: %id% is replaced by the container id, %uid%
+// and %gid% by the effective user and group ids of runc, and %% by a single
+// percent sign. Any other percent sign is kept as is. It also tells if the
+// path has placeholders, but not %id%, so that it may be the path of other
+// containers as well.
+func expandCgroupsPath(path, id string) (string, bool) {
+	if !strings.Contains(path, "%") {
+		return path, false
+	}
+	var (
+		b                   strings.Builder
+		templated, uniqueID bool
+	)
+	for rest := path; rest != ""; {
+		before, after, ok := strings.Cut(rest, "%")
+		b.WriteString(before)
+		if !ok {
+			break
+		}
+		rest = after
+		switch {
+		case strings.HasPrefix(rest, "%"):
+			b.WriteByte('%')
+			rest = rest[1:]
+		case strings.HasPrefix(rest, "id%"):
+			b.WriteString(id)
+			rest = rest[3:]
+			templated, uniqueID = true, true
+		case strings.HasPrefix(rest, "uid%"):
+			b.WriteString(strconv.Itoa(os.Geteuid()))
+			rest = rest[4:]
+			templated = true
+		case strings.HasPrefix(rest, "gid%"):
+			b.WriteString(strconv.Itoa(os.Getegid()))
+			rest = rest[4:]
+			templated = true
+		default:
+			b.WriteByte('%')
+		}
+	}
+	return b.String(), templated && !uniqueID
 }
 
 func CreateCgroupConfig(opts *CreateOpts, defaultDevs []*devices.Device) (*configs.Cgroup, error) {
@@ -716,10 +1205,14 @@ func CreateCgroupConfig(opts *CreateOpts, defaultDevs []*devices.Device) (*confi
 	}
 
 	if spec.Linux != nil && spec.Linux.CgroupsPath != "" {
+		path, shared := expandCgroupsPath(spec.Linux.CgroupsPath, name)
+		// The cgroup may be the one of another container, which the
+		// container deleted first would remove.
+		c.Exclusive = shared
 		if useSystemdCgroup {
-			myCgroupPath = spec.Linux.CgroupsPath
+			myCgroupPath = path
 		} else {
-			myCgroupPath = libcontainerUtils.CleanPath(spec.Linux.CgroupsPath)
+			myCgroupPath = libcontainerUtils.CleanPath(path)
 		}
 	}
 
@@ -746,6 +1239,33 @@ func CreateCgroupConfig(opts *CreateOpts, defaultDevs []*devices.Device) (*confi
 		c.Path = myCgroupPath
 	}
 
+	if spec.Annotations[cgroupAccountingOnlyAnnotation] == "true" {
+		c.AccountingOnly = true
+	}
+	if spec.Annotations[cgroupDelegateAnnotation] == "true" {
+		c.Delegate = true
+	}
+	if useSystemdCgroup && spec.Annotations[systemdFallbackAnnotation] == "true" {
+		c.SystemdFallback = true
+	}
+	switch val := spec.Annotations[cgroupReadOnlyAnnotation]; val {
+	case "", "fail":
+	case "skip-limits":
+		c.SkipLimitsIfReadOnly = true
+	default:
+		return nil, fmt.Errorf("annotation %s: invalid value %q (must be fail or skip-limits)", cgroupReadOnlyAnnotation, val)
+	}
+	c.Resources.CpusetPartition = spec.Annotations[cpusetPartitionAnnotation]
+	c.Resources.HugetlbAccounting = spec.Annotations[hugetlbAccountingAnnotation]
+	c.Resources.DevicesPinDir = spec.Annotations[devicesPinDirAnnotation]
+	if val := spec.Annotations[ioLatencyAnnotation]; val != "" {
+		devices, err := parseIoLatency(val)
+		if err != nil {
+			return nil, fmt.Errorf("annotation %s: %w", ioLatencyAnnotation, err)
+		}
+		c.Resources.BlkioLatencyDevice = devices
+	}
+
 	// In rootless containers, any attempt to make cgroup changes is likely to fail.
 	// libcontainer will validate this but ignores the error.
 	if spec.Linux != nil {
@@ -905,6 +1425,9 @@ func CreateCgroupConfig(opts *CreateOpts, defaultDevs []*devices.Device) (*confi
 					c.Resources.Unified[k] = v
 				}
 			}
+			if spec.Annotations[unifiedAllowUnknownAnnotation] == "true" {
+				c.Resources.UnifiedAllowUnknown = true
+			}
 		}
 	}
 
@@ -915,6 +1438,27 @@ func CreateCgroupConfig(opts *CreateOpts, defaultDevs []*devices.Device) (*confi
 	return c, nil
 }
 
+// parseIoLatency parses the value of ioLatencyAnnotation.
+func parseIoLatency(val string) ([]*configs.LatencyDevice, error) {
+	var devices []*configs.LatencyDevice
+	for _, d := range strings.Split(val, ",") {
+		dev, target, ok := strings.Cut(d, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid latency target %q", d)
+		}
+		var major, minor int64
+		if n, err := fmt.Sscanf(dev, "%d:%d", &major, &minor); err != nil || n != 2 || dev != fmt.Sprintf("%d:%d", major, minor) {
+			return nil, fmt.Errorf("invalid latency target device %q", dev)
+		}
+		t, err := time.ParseDuration(target)
+		if err != nil || t < 0 {
+			return nil, fmt.Errorf("invalid latency target %q", target)
+		}
+		devices = append(devices, configs.NewLatencyDevice(major, minor, uint64(t/time.Microsecond)))
+	}
+	return devices, nil
+}
+
 func stringToCgroupDeviceRune(s string) (devices.Type, error) {
 	switch s {
 	case "a":
@@ -941,15 +1485,82 @@ func stringToDeviceRune(s string) (devices.Type, error) {
 	}
 }
 
+// isDeviceGlob tells whether the path of a device is a glob pattern, such as
+// "/dev/nvidia*".
+func isDeviceGlob(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+// expandDeviceGlobs returns the devices specDevs, with the ones whose path is
+// a glob pattern replaced by the matching device nodes of the host (of the
+// given type, if any), with their type and numbers. As for the other devices,
+// the access to them is the one linux.resources.devices allows.
+func expandDeviceGlobs(specDevs []specs.LinuxDevice) ([]specs.LinuxDevice, error) {
+	var (
+		out     []specs.LinuxDevice
+		matched map[string]bool
+	)
+	for _, d := range specDevs {
+		if !isDeviceGlob(d.Path) {
+			out = append(out, d)
+			continue
+		}
+		paths, err := filepath.Glob(d.Path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid device path pattern %q: %w", d.Path, err)
+		}
+		n := 0
+		for _, path := range paths {
+			host, err := devices.DeviceFromPath(path, "")
+			if err != nil {
+				if errors.Is(err, devices.ErrNotADevice) || errors.Is(err, os.ErrNotExist) {
+					continue
+				}
+				return nil, err
+			}
+			typ := string(host.Type)
+			if d.Type != "" && d.Type != typ && !(d.Type == "u" && typ == "c") {
+				continue
+			}
+			if matched == nil {
+				matched = make(map[string]bool)
+			}
+			if matched[path] {
+				continue
+			}
+			matched[path] = true
+			dev := d
+			dev.Path = path
+			dev.Type = typ
+			dev.Major, dev.Minor = host.Major, host.Minor
+			out = append(out, dev)
+			n++
+		}
+		if n == 0 {
+			logrus.Warnf("no device matching %s on the host", d.Path)
+		}
+	}
+	return out, nil
+}
+
 func createDevices(spec *specs.Spec, config *configs.Config) ([]*devices.Device, error) {
+	var specDevs []specs.LinuxDevice
+	if spec.Linux != nil {
+		var err error
+		specDevs, err = expandDeviceGlobs(spec.Linux.Devices)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// If a spec device is redundant with a default device, remove that default
 	// device (the spec one takes priority).
 	dedupedAllowDevs := []*devices.Device{}
 
 next:
 	for _, ad := range AllowedDevices {
-		if ad.Path != "" && spec.Linux != nil {
-			for _, sd := range spec.Linux.Devices {
+		if ad.Path != "" {
+			for _, sd := range specDevs {
 				if sd.Path == ad.Path {
 					continue next
 				}
@@ -963,7 +1574,7 @@ next:
 
 	// Merge in additional devices from the spec.
 	if spec.Linux != nil {
-		for _, d := range spec.Linux.Devices {
+		for _, d := range specDevs {
 			var uid, gid uint32
 			var filemode os.FileMode = 0o666
 
@@ -1234,6 +1845,61 @@ func createHooks(rspec *specs.Spec, config *configs.Config) {
 	}
 }
 
+// setupNetworkRestoreHooks adds the hooks set by
+// networkRestoreHooksAnnotation to config.Hooks.
+func setupNetworkRestoreHooks(spec *specs.Spec, config *configs.Config) error {
+	val, ok := spec.Annotations[networkRestoreHooksAnnotation]
+	if !ok {
+		return nil
+	}
+	var hooks []specs.Hook
+	if err := json.Unmarshal([]byte(val), &hooks); err != nil {
+		return fmt.Errorf("annotation %s: %w", networkRestoreHooksAnnotation, err)
+	}
+	for _, h := range hooks {
+		if h.Path == "" {
+			return fmt.Errorf("annotation %s: hook path is empty", networkRestoreHooksAnnotation)
+		}
+		config.Hooks[configs.NetworkRestore] = append(config.Hooks[configs.NetworkRestore], configs.NewCommandHook(createCommandHook(h)))
+	}
+	return nil
+}
+
+// setupInContainerHooks sets config.InContainerHooks from
+// inContainerHooksAnnotation.
+func setupInContainerHooks(spec *specs.Spec, config *configs.Config) error {
+	val, ok := spec.Annotations[inContainerHooksAnnotation]
+	if !ok {
+		return nil
+	}
+	var hooks []struct {
+		specs.Hook
+		Dir          string                   `json:"dir,omitempty"`
+		Capabilities *specs.LinuxCapabilities `json:"capabilities,omitempty"`
+	}
+	if err := json.Unmarshal([]byte(val), &hooks); err != nil {
+		return fmt.Errorf("annotation %s: %w", inContainerHooksAnnotation, err)
+	}
+	for _, h := range hooks {
+		if h.Path == "" {
+			return fmt.Errorf("annotation %s: hook path is empty", inContainerHooksAnnotation)
+		}
+		hook := configs.InContainerHook{Command: createCommandHook(h.Hook)}
+		hook.Dir = h.Dir
+		if c := h.Capabilities; c != nil {
+			hook.Capabilities = &configs.Capabilities{
+				Bounding:    c.Bounding,
+				Effective:   c.Effective,
+				Permitted:   c.Permitted,
+				Inheritable: c.Inheritable,
+				Ambient:     c.Ambient,
+			}
+		}
+		config.InContainerHooks = append(config.InContainerHooks, hook)
+	}
+	return nil
+}
+
 func createCommandHook(h specs.Hook) configs.Command {
 	cmd := configs.Command{
 		Path: h.Path,
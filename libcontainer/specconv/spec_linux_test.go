@@ -2,8 +2,11 @@ package specconv
 
 import (
 	"os"
+	"reflect"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	dbus "github.com/godbus/dbus/v5"
 	"github.com/opencontainers/runtime-spec/specs-go"
@@ -517,6 +520,45 @@ func TestLinuxCgroupsPathSpecified(t *testing.T) {
 	}
 }
 
+func TestLinuxCgroupsPathTemplated(t *testing.T) {
+	uid, gid := strconv.Itoa(os.Geteuid()), strconv.Itoa(os.Getegid())
+	testCases := []struct {
+		path, expected string
+		systemd        bool
+		exclusive      bool
+	}{
+		{path: "/tenants/%uid%/%id%", expected: "/tenants/" + uid + "/ContainerID"},
+		{path: "/tenants/%gid%/100%%", expected: "/tenants/" + gid + "/100%", exclusive: true},
+		{path: "/tenants/%user%", expected: "/tenants/%user%"},
+		{path: "/tenants/%uid", expected: "/tenants/%uid"},
+		{path: "/tenants/50%", expected: "/tenants/50%"},
+		{path: "user-%uid%.slice:runc:%id%", expected: "user-" + uid + ".slice", systemd: true},
+	}
+	for _, tc := range testCases {
+		spec := &specs.Spec{Linux: &specs.Linux{CgroupsPath: tc.path}}
+		opts := &CreateOpts{
+			CgroupName:       "ContainerID",
+			UseSystemdCgroup: tc.systemd,
+			Spec:             spec,
+		}
+		cgroup, err := CreateCgroupConfig(opts, nil)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.path, err)
+			continue
+		}
+		if cgroup.Exclusive != tc.exclusive {
+			t.Errorf("%q: expected exclusive %v, got %v", tc.path, tc.exclusive, cgroup.Exclusive)
+		}
+		if tc.systemd {
+			if cgroup.Parent != tc.expected || cgroup.Name != "ContainerID" {
+				t.Errorf("%q: expected parent %q and name %q, got %q and %q", tc.path, tc.expected, "ContainerID", cgroup.Parent, cgroup.Name)
+			}
+		} else if cgroup.Path != tc.expected {
+			t.Errorf("%q: expected %q, got %q", tc.path, tc.expected, cgroup.Path)
+		}
+	}
+}
+
 func TestLinuxCgroupsPathNotSpecified(t *testing.T) {
 	spec := &specs.Spec{}
 	opts := &CreateOpts{
@@ -642,6 +684,112 @@ func TestUserNamespaceMappingAndPath(t *testing.T) {
 	}
 }
 
+func TestCgroupDelegate(t *testing.T) {
+	spec := Example()
+	spec.Root.Path = "/"
+	spec.Annotations = map[string]string{cgroupDelegateAnnotation: "true"}
+	spec.Linux.Namespaces = append(spec.Linux.Namespaces,
+		specs.LinuxNamespace{Type: specs.UserNamespace},
+		specs.LinuxNamespace{Type: specs.CgroupNamespace},
+	)
+	spec.Linux.UIDMappings = []specs.LinuxIDMapping{{ContainerID: 0, HostID: 1000, Size: 1000}}
+	spec.Linux.GIDMappings = []specs.LinuxIDMapping{{ContainerID: 0, HostID: 2000, Size: 1000}}
+	// The container's root owns the cgroup, not the process user.
+	spec.Process.User.UID = 10
+
+	config, err := CreateLibcontainerConfig(&CreateOpts{
+		CgroupName: "ContainerID",
+		Spec:       spec,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := config.Cgroups
+	if !c.Delegate {
+		t.Error("expected the cgroup to be delegated")
+	}
+	if c.OwnerUID == nil || *c.OwnerUID != 1000 {
+		t.Errorf("expected owner uid 1000, got %v", c.OwnerUID)
+	}
+	if c.OwnerGID == nil || *c.OwnerGID != 2000 {
+		t.Errorf("expected owner gid 2000, got %v", c.OwnerGID)
+	}
+}
+
+func TestNetworksAnnotation(t *testing.T) {
+	spec := Example()
+	spec.Root.Path = "/"
+	spec.Annotations = map[string]string{networksAnnotation: `[
+		{"type": "veth", "name": "eth0", "bridge": "br0", "host_interface_name": "veth-test", "address": "10.0.0.2/24", "gateway": "10.0.0.1"},
+		{"type": "macvlan", "name": "eth1", "parent": "eno1", "macvlan_mode": "private"}
+	]`}
+	config, err := CreateLibcontainerConfig(&CreateOpts{
+		CgroupName: "ContainerID",
+		Spec:       spec,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config.Networks) != 3 {
+		t.Fatalf("expected 3 networks, got %d", len(config.Networks))
+	}
+	if n := config.Networks[1]; n.Type != "veth" || n.Bridge != "br0" || n.HostInterfaceName != "veth-test" || n.Gateway != "10.0.0.1" {
+		t.Errorf("unexpected veth network %+v", n)
+	}
+	if n := config.Networks[2]; n.Type != "macvlan" || n.Parent != "eno1" || n.MacvlanMode != "private" {
+		t.Errorf("unexpected macvlan network %+v", n)
+	}
+
+	spec.Annotations[networksAnnotation] = `{"type": "veth"}`
+	if _, err := CreateLibcontainerConfig(&CreateOpts{CgroupName: "ContainerID", Spec: spec}); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+func TestIntelRdtMonitoring(t *testing.T) {
+	spec := Example()
+	spec.Root.Path = "/"
+	spec.Linux.IntelRdt = &specs.LinuxIntelRdt{EnableCMT: true}
+	config, err := CreateLibcontainerConfig(&CreateOpts{CgroupName: "ContainerID", Spec: spec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !config.IntelRdt.EnableMonitoring || !config.IntelRdt.EnableCMT || config.IntelRdt.EnableMBM || config.IntelRdt.ClosID != "" {
+		t.Errorf("expected CMT monitoring only, got %+v", config.IntelRdt)
+	}
+
+	spec.Linux.IntelRdt = &specs.LinuxIntelRdt{ClosID: "clos", L3CacheSchema: "L3:0=f", EnableMBM: true}
+	config, err = CreateLibcontainerConfig(&CreateOpts{CgroupName: "ContainerID", Spec: spec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !config.IntelRdt.EnableMonitoring || !config.IntelRdt.EnableMBM || config.IntelRdt.ClosID != "clos" || config.IntelRdt.L3CacheSchema != "L3:0=f" {
+		t.Errorf("expected MBM monitoring in clos, got %+v", config.IntelRdt)
+	}
+
+	spec.Linux.IntelRdt = &specs.LinuxIntelRdt{ClosID: "clos"}
+	config, err = CreateLibcontainerConfig(&CreateOpts{CgroupName: "ContainerID", Spec: spec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.IntelRdt.EnableMonitoring {
+		t.Errorf("expected no monitoring, got %+v", config.IntelRdt)
+	}
+}
+
+func TestIntelRdtMonitoringAnnotation(t *testing.T) {
+	spec := Example()
+	spec.Root.Path = "/"
+	spec.Annotations = map[string]string{intelRdtMonitoringAnnotation: "true"}
+	config, err := CreateLibcontainerConfig(&CreateOpts{CgroupName: "ContainerID", Spec: spec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.IntelRdt == nil || !config.IntelRdt.EnableMonitoring || config.IntelRdt.ClosID != "" {
+		t.Errorf("expected monitoring only, got %+v", config.IntelRdt)
+	}
+}
+
 func TestNonZeroEUIDCompatibleSpecconvValidate(t *testing.T) {
 	if _, err := os.Stat("/proc/self/ns/user"); os.IsNotExist(err) {
 		t.Skip("Test requires userns.")
@@ -772,6 +920,11 @@ func TestInitSystemdProps(t *testing.T) {
 			in:   inT{"org.systemd.property.ValidName", "invalid-value"},
 			exp:  expT{true, "", ""},
 		},
+		{
+			desc: "property also set by runc",
+			in:   inT{"org.systemd.property.Slice", "'system.slice'"},
+			exp:  expT{false, "Slice", "system.slice"},
+		},
 	}
 
 	spec := &specs.Spec{}
@@ -956,3 +1109,460 @@ func TestCreateDevices(t *testing.T) {
 		t.Errorf("device /dev/ram0 not found in config devices; got %v", conf.Devices)
 	}
 }
+
+func TestCreateDevicesGlob(t *testing.T) {
+	spec := Example()
+	spec.Linux = &specs.Linux{
+		Devices: []specs.LinuxDevice{
+			// /dev/null is a default device, /dev/full is not.
+			{Path: "/dev/[fn]ul[l]", Type: "c"},
+			{Path: "/dev/nul*", Type: "b"},
+			{Path: "/dev/no-such-device*"},
+		},
+	}
+	conf := &configs.Config{}
+	allowed, err := createDevices(spec, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]devices.Rule{
+		"/dev/full": {Type: devices.CharDevice, Major: 1, Minor: 7},
+		"/dev/null": {Type: devices.CharDevice, Major: 1, Minor: 3},
+	}
+	for path, rule := range expected {
+		var n int
+		for _, d := range conf.Devices {
+			if d.Path == path {
+				n++
+				if d.Rule != rule {
+					t.Errorf("%s: expected %+v, got %+v", path, rule, d.Rule)
+				}
+			}
+		}
+		if n != 1 {
+			t.Errorf("expected one %s device, got %d", path, n)
+		}
+		// The access is the one linux.resources.devices allows, and the
+		// default rule of a default device is replaced.
+		for _, d := range allowed {
+			if d.Path == path {
+				t.Errorf("%s: unexpected rule %+v", path, d.Rule)
+			}
+		}
+	}
+}
+
+func TestSetupTime(t *testing.T) {
+	testCases := []struct {
+		annotations map[string]string
+		time        *configs.Time
+		offsets     map[string]specs.LinuxTimeOffset
+		isErr       bool
+	}{
+		{
+			annotations: map[string]string{timezoneAnnotation: "Europe/Berlin"},
+			time:        &configs.Time{Timezone: "Europe/Berlin"},
+		},
+		{
+			annotations: map[string]string{timezoneAnnotation: "/etc/localtime"},
+			time:        &configs.Time{TimezoneFile: "/etc/localtime"},
+		},
+		{
+			annotations: map[string]string{timeOffsetsAnnotation: "boottime=1h,monotonic=-1.5s"},
+			offsets: map[string]specs.LinuxTimeOffset{
+				"boottime":  {Secs: 3600},
+				"monotonic": {Secs: -2, Nanosecs: 500000000},
+			},
+		},
+		{
+			annotations: map[string]string{timeOffsetsAnnotation: "realtime=1h"},
+			isErr:       true,
+		},
+		{
+			annotations: map[string]string{timeOffsetsAnnotation: "boottime=1x"},
+			isErr:       true,
+		},
+		{
+			annotations: map[string]string{timeOffsetsAnnotation: "boottime"},
+			isErr:       true,
+		},
+	}
+
+	for _, tc := range testCases {
+		spec := &specs.Spec{Annotations: tc.annotations}
+		config := &configs.Config{}
+		err := setupTime(spec, config)
+		if tc.isErr {
+			if err == nil {
+				t.Errorf("%v: expected error, got nil", tc.annotations)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", tc.annotations, err)
+			continue
+		}
+		if !reflect.DeepEqual(config.Time, tc.time) {
+			t.Errorf("%v: expected time %+v, got %+v", tc.annotations, tc.time, config.Time)
+		}
+		if !reflect.DeepEqual(config.TimeOffsets, tc.offsets) {
+			t.Errorf("%v: expected offsets %+v, got %+v", tc.annotations, tc.offsets, config.TimeOffsets)
+		}
+		if hasNS := config.Namespaces.Contains(configs.NEWTIME); hasNS != (tc.offsets != nil) {
+			t.Errorf("%v: unexpected time namespace presence: %v", tc.annotations, hasNS)
+		}
+	}
+
+	// The annotation can't be used together with the spec offsets.
+	spec := &specs.Spec{Annotations: map[string]string{timeOffsetsAnnotation: "boottime=1s"}}
+	config := &configs.Config{TimeOffsets: map[string]specs.LinuxTimeOffset{"boottime": {Secs: 1}}}
+	if err := setupTime(spec, config); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+func TestSetupNetSysctl(t *testing.T) {
+	port := uint16(0)
+	testCases := []struct {
+		annotations map[string]string
+		netSysctl   *configs.NetSysctl
+		isErr       bool
+	}{
+		{},
+		{
+			annotations: map[string]string{pingGroupRangeAnnotation: "0-2147483647"},
+			netSysctl:   &configs.NetSysctl{PingGroupRange: &configs.GroupRange{Start: 0, End: 2147483647}},
+		},
+		{
+			annotations: map[string]string{unprivilegedPortStartAnnotation: "0"},
+			netSysctl:   &configs.NetSysctl{UnprivilegedPortStart: &port},
+		},
+		{
+			annotations: map[string]string{pingGroupRangeAnnotation: "0 1"},
+			isErr:       true,
+		},
+		{
+			annotations: map[string]string{pingGroupRangeAnnotation: "-1-1"},
+			isErr:       true,
+		},
+		{
+			annotations: map[string]string{unprivilegedPortStartAnnotation: "65536"},
+			isErr:       true,
+		},
+	}
+
+	for _, tc := range testCases {
+		spec := &specs.Spec{Annotations: tc.annotations}
+		config := &configs.Config{}
+		err := setupNetSysctl(spec, config)
+		if tc.isErr {
+			if err == nil {
+				t.Errorf("%v: expected error, got nil", tc.annotations)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", tc.annotations, err)
+			continue
+		}
+		if !reflect.DeepEqual(config.NetSysctl, tc.netSysctl) {
+			t.Errorf("%v: expected %+v, got %+v", tc.annotations, tc.netSysctl, config.NetSysctl)
+		}
+	}
+}
+
+func TestSetupStop(t *testing.T) {
+	testCases := []struct {
+		annotations map[string]string
+		stop        *configs.Stop
+		isErr       bool
+	}{
+		{},
+		{
+			annotations: map[string]string{stopSignalAnnotation: "SIGINT"},
+			stop:        &configs.Stop{Signal: int(unix.SIGINT)},
+		},
+		{
+			annotations: map[string]string{stopSignalAnnotation: "quit", stopGracePeriodAnnotation: "1m30s"},
+			stop:        &configs.Stop{Signal: int(unix.SIGQUIT), GracePeriod: 90 * time.Second},
+		},
+		{
+			annotations: map[string]string{stopGracePeriodAnnotation: "5s"},
+			stop:        &configs.Stop{GracePeriod: 5 * time.Second},
+		},
+		{
+			annotations: map[string]string{stopSignalAnnotation: "SIGFOO"},
+			isErr:       true,
+		},
+		{
+			annotations: map[string]string{stopGracePeriodAnnotation: "5"},
+			isErr:       true,
+		},
+		{
+			annotations: map[string]string{stopGracePeriodAnnotation: "-5s"},
+			isErr:       true,
+		},
+	}
+
+	for _, tc := range testCases {
+		spec := &specs.Spec{Annotations: tc.annotations}
+		config := &configs.Config{}
+		err := setupStop(spec, config)
+		if tc.isErr {
+			if err == nil {
+				t.Errorf("%v: expected error, got nil", tc.annotations)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", tc.annotations, err)
+			continue
+		}
+		if !reflect.DeepEqual(config.Stop, tc.stop) {
+			t.Errorf("%v: expected %+v, got %+v", tc.annotations, tc.stop, config.Stop)
+		}
+	}
+}
+
+func TestSetupNetworkRestoreHooks(t *testing.T) {
+	testCases := []struct {
+		annotations map[string]string
+		hooks       int
+		isErr       bool
+	}{
+		{},
+		{
+			annotations: map[string]string{
+				networkRestoreHooksAnnotation: `[{"path": "/usr/libexec/cni/restore"}, {"path": "/bin/true", "timeout": 5}]`,
+			},
+			hooks: 2,
+		},
+		{
+			annotations: map[string]string{networkRestoreHooksAnnotation: `{"path": "/bin/agent"}`},
+			isErr:       true,
+		},
+		{
+			annotations: map[string]string{networkRestoreHooksAnnotation: `[{"args": ["agent"]}]`},
+			isErr:       true,
+		},
+	}
+
+	for _, tc := range testCases {
+		spec := &specs.Spec{Annotations: tc.annotations}
+		config := &configs.Config{Hooks: configs.Hooks{}}
+		err := setupNetworkRestoreHooks(spec, config)
+		if tc.isErr {
+			if err == nil {
+				t.Errorf("%v: expected error, got nil", tc.annotations)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", tc.annotations, err)
+			continue
+		}
+		if len(config.Hooks[configs.NetworkRestore]) != tc.hooks {
+			t.Errorf("%v: expected %d hooks, got %d", tc.annotations, tc.hooks, len(config.Hooks[configs.NetworkRestore]))
+		}
+	}
+}
+
+func TestSetupInContainerHooks(t *testing.T) {
+	spec := &specs.Spec{Annotations: map[string]string{
+		inContainerHooksAnnotation: `[{"path": "/usr/libexec/setup", "args": ["setup", "-v"], "timeout": 5},
+			{"path": "/usr/libexec/net-setup", "dir": "/tmp", "capabilities": {"effective": ["CAP_NET_ADMIN"], "permitted": ["CAP_NET_ADMIN"]}}]`,
+	}}
+	config := &configs.Config{}
+	if err := setupInContainerHooks(spec, config); err != nil {
+		t.Fatal(err)
+	}
+	hooks := config.InContainerHooks
+	if len(hooks) != 2 {
+		t.Fatalf("expected 2 hooks, got %+v", hooks)
+	}
+	if hooks[0].Path != "/usr/libexec/setup" || len(hooks[0].Args) != 2 || *hooks[0].Timeout != 5*time.Second || hooks[0].Capabilities != nil {
+		t.Errorf("unexpected first hook %+v", hooks[0])
+	}
+	if hooks[1].Dir != "/tmp" || hooks[1].Capabilities == nil || !reflect.DeepEqual(hooks[1].Capabilities.Effective, []string{"CAP_NET_ADMIN"}) {
+		t.Errorf("unexpected second hook %+v", hooks[1])
+	}
+
+	for _, val := range []string{`{"path": "/bin/true"}`, `[{"args": ["true"]}]`} {
+		spec.Annotations[inContainerHooksAnnotation] = val
+		if err := setupInContainerHooks(spec, &configs.Config{}); err == nil {
+			t.Errorf("%s: expected error, got nil", val)
+		}
+	}
+}
+
+func TestSetupExecRateLimit(t *testing.T) {
+	testCases := []struct {
+		annotations map[string]string
+		limit       *configs.ExecRateLimit
+		isErr       bool
+	}{
+		{},
+		{
+			annotations: map[string]string{execRateAnnotation: "0.5"},
+			limit:       &configs.ExecRateLimit{Rate: 0.5},
+		},
+		{
+			annotations: map[string]string{execRateAnnotation: "10", execBurstAnnotation: "20"},
+			limit:       &configs.ExecRateLimit{Rate: 10, Burst: 20},
+		},
+		{
+			annotations: map[string]string{execBurstAnnotation: "20"},
+			isErr:       true,
+		},
+		{
+			annotations: map[string]string{execRateAnnotation: "0"},
+			isErr:       true,
+		},
+		{
+			annotations: map[string]string{execRateAnnotation: "NaN"},
+			isErr:       true,
+		},
+		{
+			annotations: map[string]string{execRateAnnotation: "1", execBurstAnnotation: "0"},
+			isErr:       true,
+		},
+	}
+
+	for _, tc := range testCases {
+		spec := &specs.Spec{Annotations: tc.annotations}
+		config := &configs.Config{}
+		err := setupExecRateLimit(spec, config)
+		if tc.isErr {
+			if err == nil {
+				t.Errorf("%v: expected error, got nil", tc.annotations)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", tc.annotations, err)
+			continue
+		}
+		if !reflect.DeepEqual(config.ExecRateLimit, tc.limit) {
+			t.Errorf("%v: expected %+v, got %+v", tc.annotations, tc.limit, config.ExecRateLimit)
+		}
+	}
+}
+
+func TestSetupFirewall(t *testing.T) {
+	testCases := []struct {
+		value    string
+		firewall *configs.Firewall
+		isErr    bool
+	}{
+		{},
+		{value: "none", firewall: &configs.Firewall{}},
+		{
+			value: "tcp/80, udp/8000-8080,10.0.0.0/8 tcp/22,fd00::/8, udp",
+			firewall: &configs.Firewall{Allow: []*configs.FirewallRule{
+				{Protocol: "tcp", Port: 80},
+				{Protocol: "udp", Port: 8000, PortEnd: 8080},
+				{Source: "10.0.0.0/8", Protocol: "tcp", Port: 22},
+				{Source: "fd00::/8"},
+				{Protocol: "udp"},
+			}},
+		},
+		{value: "tcp/80,", isErr: true},
+		{value: "icmp", isErr: true},
+		{value: "tcp/http", isErr: true},
+		{value: "tcp/80-x", isErr: true},
+		{value: "tcp/22 udp/53", isErr: true},
+		{value: "10.0.0.0/8 fd00::/8", isErr: true},
+		{value: "10.0.0.0/8 tcp/22 x", isErr: true},
+	}
+	for _, tc := range testCases {
+		spec := &specs.Spec{Annotations: map[string]string{firewallAnnotation: tc.value}}
+		config := &configs.Config{}
+		err := setupFirewall(spec, config)
+		if tc.isErr {
+			if err == nil {
+				t.Errorf("%q: expected error, got nil", tc.value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.value, err)
+			continue
+		}
+		if !reflect.DeepEqual(config.Firewall, tc.firewall) {
+			t.Errorf("%q: expected %+v, got %+v", tc.value, tc.firewall, config.Firewall)
+		}
+	}
+}
+
+func TestSetupMountDrivers(t *testing.T) {
+	newConfig := func() *configs.Config {
+		return &configs.Config{Mounts: []*configs.Mount{
+			{Destination: "/a", Device: "nfs"},
+			{Destination: "/b", Device: "tmpfs"},
+			{Destination: "/c", Device: "nfs"},
+		}}
+	}
+
+	spec := &specs.Spec{Annotations: map[string]string{
+		mountDriverAnnotationPrefix + "nfs": "/usr/libexec/nfs-driver",
+		mountDriverAnnotationPrefix + "xfs": "/usr/libexec/xfs-driver",
+	}}
+	config := newConfig()
+	if err := setupMountDrivers(spec, config); err != nil {
+		t.Fatal(err)
+	}
+	for i, driver := range []string{"/usr/libexec/nfs-driver", "", "/usr/libexec/nfs-driver"} {
+		if config.Mounts[i].Driver != driver {
+			t.Errorf("mount %s: expected driver %q, got %q", config.Mounts[i].Destination, driver, config.Mounts[i].Driver)
+		}
+	}
+
+	for _, annotations := range []map[string]string{
+		{mountDriverAnnotationPrefix + "nfs": "nfs-driver"},
+		{mountDriverAnnotationPrefix: "/usr/libexec/driver"},
+	} {
+		if err := setupMountDrivers(&specs.Spec{Annotations: annotations}, newConfig()); err == nil {
+			t.Errorf("%v: expected error, got nil", annotations)
+		}
+	}
+}
+
+func TestParseIoLatency(t *testing.T) {
+	testCases := []struct {
+		value   string
+		devices []*configs.LatencyDevice
+		isErr   bool
+	}{
+		{
+			value: "8:0=10ms,8:16=250us,259:0=0",
+			devices: []*configs.LatencyDevice{
+				configs.NewLatencyDevice(8, 0, 10000),
+				configs.NewLatencyDevice(8, 16, 250),
+				configs.NewLatencyDevice(259, 0, 0),
+			},
+		},
+		{value: "8:0", isErr: true},
+		{value: "8=10ms", isErr: true},
+		{value: "8:0x=10ms", isErr: true},
+		{value: "8:0=10", isErr: true},
+		{value: "8:0=-10ms", isErr: true},
+		{value: "8:0=10ms,", isErr: true},
+	}
+	for _, tc := range testCases {
+		devices, err := parseIoLatency(tc.value)
+		if tc.isErr {
+			if err == nil {
+				t.Errorf("%q: expected error, got nil", tc.value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.value, err)
+			continue
+		}
+		if !reflect.DeepEqual(devices, tc.devices) {
+			t.Errorf("%q: expected %+v, got %+v", tc.value, tc.devices, devices)
+		}
+	}
+}
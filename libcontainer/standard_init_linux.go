@@ -15,7 +15,6 @@ import (
 	"github.com/szcdx/runc/libcontainer/apparmor"
 	"github.com/szcdx/runc/libcontainer/configs"
 	"github.com/szcdx/runc/libcontainer/keys"
-	"github.com/szcdx/runc/libcontainer/seccomp"
 	"github.com/szcdx/runc/libcontainer/system"
 	"github.com/szcdx/runc/libcontainer/utils"
 )
@@ -28,6 +27,7 @@ type linuxStandardInit struct {
 	fifoFd        int
 	logFd         int
 	dmzExe        *os.File
+	initLog       *os.File
 	config        *initConfig
 }
 
@@ -132,10 +132,8 @@ func (l *linuxStandardInit) Init() error {
 		return fmt.Errorf("unable to apply apparmor profile: %w", err)
 	}
 
-	for key, value := range l.config.Config.Sysctl {
-		if err := writeSystemProperty(key, value); err != nil {
-			return err
-		}
+	if err := writeSysctls(l.config.Config.Sysctl, l.config.Config.NetSysctl.Sysctl()); err != nil {
+		return err
 	}
 	for _, path := range l.config.Config.ReadonlyPaths {
 		if err := readonlyPath(path); err != nil {
@@ -143,7 +141,7 @@ func (l *linuxStandardInit) Init() error {
 		}
 	}
 	for _, path := range l.config.Config.MaskPaths {
-		if err := maskPath(path, l.config.Config.MountLabel); err != nil {
+		if err := maskPath("/", path, l.config.Config.MountLabel); err != nil {
 			return fmt.Errorf("can't mask path %s: %w", path, err)
 		}
 	}
@@ -177,7 +175,7 @@ func (l *linuxStandardInit) Init() error {
 	// do this before dropping capabilities; otherwise do it as late as possible
 	// just before execve so as few syscalls take place after it as possible.
 	if l.config.Config.Seccomp != nil && !l.config.NoNewPrivileges {
-		seccompFd, err := seccomp.InitSeccomp(l.config.Config.Seccomp)
+		seccompFd, err := initSeccomp(l.config)
 		if err != nil {
 			return err
 		}
@@ -221,7 +219,7 @@ func (l *linuxStandardInit) Init() error {
 	// before closing the pipe since we need it to pass the seccompFd to
 	// the parent.
 	if l.config.Config.Seccomp != nil && l.config.NoNewPrivileges {
-		seccompFd, err := seccomp.InitSeccomp(l.config.Config.Seccomp)
+		seccompFd, err := initSeccomp(l.config)
 		if err != nil {
 			return fmt.Errorf("unable to init seccomp: %w", err)
 		}
@@ -247,6 +245,17 @@ func (l *linuxStandardInit) Init() error {
 	if err := unix.Close(l.logFd); err != nil {
 		return &os.PathError{Op: "close log pipe", Path: "fd " + strconv.Itoa(l.logFd), Err: err}
 	}
+	// From now on, nobody is reading the log pipe. The log, and whatever
+	// else is written to stderr, goes to the init log until the container
+	// process is executed.
+	savedStderr := -1
+	if l.initLog != nil {
+		logrus.SetOutput(l.initLog)
+		savedStderr, err = redirectStderr(l.initLog)
+		if err != nil {
+			return err
+		}
+	}
 
 	fifoPath, closer := utils.ProcThreadSelf("fd/" + strconv.Itoa(l.fifoFd))
 	defer closer()
@@ -278,9 +287,38 @@ func (l *linuxStandardInit) Init() error {
 		return err
 	}
 
+	if savedStderr != -1 {
+		if err := restoreStderr(savedStderr); err != nil {
+			return err
+		}
+	}
+
 	if l.dmzExe != nil {
 		l.config.Args[0] = name
 		return system.Fexecve(l.dmzExe.Fd(), l.config.Args, os.Environ())
 	}
 	return system.Exec(name, l.config.Args, os.Environ())
 }
+
+// redirectStderr makes f the stderr of the process, and returns a copy of
+// the original stderr fd, to be put back with restoreStderr.
+func redirectStderr(f *os.File) (int, error) {
+	saved, err := unix.FcntlInt(uintptr(unix.Stderr), unix.F_DUPFD_CLOEXEC, 3)
+	if err != nil {
+		return -1, os.NewSyscallError("fcntl", err)
+	}
+	if err := unix.Dup3(int(f.Fd()), unix.Stderr, 0); err != nil {
+		_ = unix.Close(saved)
+		return -1, os.NewSyscallError("dup3", err)
+	}
+	return saved, nil
+}
+
+// restoreStderr puts back the stderr fd saved by redirectStderr.
+func restoreStderr(saved int) error {
+	defer unix.Close(saved)
+	if err := unix.Dup3(saved, unix.Stderr, 0); err != nil {
+		return os.NewSyscallError("dup3", err)
+	}
+	return nil
+}
@@ -6,7 +6,10 @@ import (
 	"path/filepath"
 
 	"github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/sirupsen/logrus"
+	"github.com/szcdx/runc/libcontainer/cgroups"
 	"github.com/szcdx/runc/libcontainer/configs"
+	"github.com/szcdx/runc/libcontainer/firewall"
 	"golang.org/x/sys/unix"
 )
 
@@ -46,9 +49,35 @@ func destroy(c *Container) error {
 	if !c.config.Namespaces.IsPrivate(configs.NEWPID) {
 		_ = signalAllProcesses(c.cgroupManager, unix.SIGKILL)
 	}
+	// The sub-cgroups of the processes which were not waited for.
+	for _, path := range c.subCgroups {
+		if err := cgroups.RemovePath(path); err != nil {
+			logrus.Warnf("unable to remove sub-cgroup %s: %v", path, err)
+		}
+	}
+	c.subCgroups = nil
 	if err := c.cgroupManager.Destroy(); err != nil {
 		return fmt.Errorf("unable to remove container's cgroup: %w", err)
 	}
+	// The firewall ruleset is gone with the container's own network
+	// namespace, but not with the one it joined.
+	if path := c.config.Namespaces.PathOf(configs.NEWNET); path != "" && c.config.Firewall != nil {
+		if err := firewall.Remove(path, c.id); err != nil {
+			logrus.Warnf("unable to remove the container's firewall: %v", err)
+		}
+	}
+	if c.rootlessNetwork != nil {
+		if err := c.rootlessNetwork.stop(); err != nil {
+			logrus.Warnf("unable to stop the container's rootless network: %v", err)
+		}
+		c.rootlessNetwork = nil
+	}
+	if c.portForwarder != nil {
+		if err := c.portForwarder.stop(); err != nil {
+			logrus.Warnf("unable to stop the container's port forwarder: %v", err)
+		}
+		c.portForwarder = nil
+	}
 	if c.intelRdtManager != nil {
 		if err := c.intelRdtManager.Destroy(); err != nil {
 			return fmt.Errorf("unable to remove container's IntelRDT group: %w", err)
@@ -57,7 +86,12 @@ func destroy(c *Container) error {
 	if err := os.RemoveAll(c.stateDir); err != nil {
 		return fmt.Errorf("unable to remove container state dir: %w", err)
 	}
-	c.initProcess = nil
+	if c.exclusiveCPUs != "" {
+		if err := releaseCPUs(filepath.Dir(c.stateDir), c.id); err != nil {
+			return fmt.Errorf("unable to release container's exclusive CPUs: %w", err)
+		}
+	}
+	c.setInitProcess(nil)
 	err := runPoststopHooks(c)
 	c.state = &stoppedState{c: c}
 	return err
@@ -155,7 +189,7 @@ func (i *createdState) transition(s containerState) error {
 }
 
 func (i *createdState) destroy() error {
-	_ = i.c.initProcess.signal(unix.SIGKILL)
+	_ = i.c.signalInit(unix.SIGKILL)
 	return destroy(i.c)
 }
 
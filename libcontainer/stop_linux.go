@@ -0,0 +1,112 @@
+package libcontainer
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/szcdx/runc/libcontainer/configs"
+)
+
+const (
+	// stopPollInterval is how often the container is checked while
+	// waiting for it to stop.
+	stopPollInterval = 100 * time.Millisecond
+	// stopKillTimeout is how long to wait for the container to stop
+	// after it is killed with SIGKILL.
+	stopKillTimeout = 10 * time.Second
+	// maxSignal is the highest signal number (SIGRTMAX).
+	maxSignal = 64
+)
+
+// StopSettings returns the stop signal and the grace period of the
+// container (see configs.Stop), with the defaults filled in.
+func (c *Container) StopSettings() (unix.Signal, time.Duration) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	sig, grace := unix.SIGTERM, configs.DefaultStopGracePeriod
+	if s := c.config.Stop; s != nil {
+		if s.Signal != 0 {
+			sig = unix.Signal(s.Signal)
+		}
+		if s.GracePeriod != 0 {
+			grace = s.GracePeriod
+		}
+	}
+	return sig, grace
+}
+
+// SetStop changes how the container is stopped, and saves it in the
+// container state.
+func (c *Container) SetStop(stop *configs.Stop) error {
+	if stop != nil {
+		if stop.Signal < 0 || stop.Signal > maxSignal {
+			return fmt.Errorf("invalid stop signal %d", stop.Signal)
+		}
+		if stop.GracePeriod < 0 {
+			return fmt.Errorf("invalid stop grace period %s", stop.GracePeriod)
+		}
+	}
+	c.m.Lock()
+	defer c.m.Unlock()
+	status, err := c.currentStatus()
+	if err != nil {
+		return err
+	}
+	if status == Stopped {
+		return ErrNotRunning
+	}
+	c.config.Stop = stop
+	_, err = c.updateState(nil)
+	return err
+}
+
+// Stop stops the container. It sends the stop signal to the container init
+// (thawing the container if it is paused, so it can handle the signal),
+// waits for the container to exit for up to the grace period, and kills it
+// with SIGKILL if it is still running then. A container which is created
+// but not started is killed right away. See StopSettings.
+func (c *Container) Stop() error {
+	sig, grace := c.StopSettings()
+	status, err := c.Status()
+	if err != nil {
+		return err
+	}
+	switch status {
+	case Stopped:
+		return nil
+	case Running, Paused:
+		if err := c.SignalWithPolicy(sig, PausedSignalThaw); err != nil && !errors.Is(err, ErrNotRunning) {
+			return err
+		}
+		if c.waitStopped(grace) {
+			return nil
+		}
+		logrus.Debugf("container %s did not stop within %s after %s, killing it", c.id, grace, unix.SignalName(sig))
+	}
+	if err := c.Signal(unix.SIGKILL); err != nil && !errors.Is(err, ErrNotRunning) {
+		return err
+	}
+	if c.waitStopped(stopKillTimeout) {
+		return nil
+	}
+	return errors.New("container init still running")
+}
+
+// waitStopped waits for the container to stop for up to timeout, and
+// reports whether it did.
+func (c *Container) waitStopped(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		if s, err := c.Status(); err == nil && s == Stopped {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(stopPollInterval)
+	}
+}
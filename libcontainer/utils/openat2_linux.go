@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+
+	securejoin "github.com/cyphar/filepath-securejoin"
+	"golang.org/x/sys/unix"
+)
+
+// noOpenat2 is set once openat2(2) is found not to be supported.
+var noOpenat2 atomic.Bool
+
+// OpenInRoot opens unsafePath, resolved within root as if root was the root
+// of the filesystem, as an O_PATH file handle. Symlinks (including absolute
+// ones and ".." components) can't take the resolution outside of root, and
+// magic links (such as /proc/self/root) are refused. As the returned handle
+// is pinned to the inode it was resolved to, it can be operated on (through
+// its /proc/self/fd path) without racing with changes made to the root
+// afterwards.
+//
+// openat2(2) with RESOLVE_IN_ROOT is used to do the resolution in the
+// kernel. On older kernels, the path is resolved with securejoin instead,
+// and the file handle is then checked to have ended up at the resolved path.
+func OpenInRoot(root, unsafePath string) (*os.File, error) {
+	// Remove the root then forcefully resolve inside the root.
+	unsafePath = stripRoot(root, unsafePath)
+	if !noOpenat2.Load() {
+		fh, err := openat2InRoot(root, unsafePath)
+		if !errors.Is(err, unix.ENOSYS) {
+			return fh, err
+		}
+		noOpenat2.Store(true)
+	}
+
+	path, err := securejoin.SecureJoin(root, unsafePath)
+	if err != nil {
+		return nil, fmt.Errorf("resolving path inside rootfs failed: %w", err)
+	}
+	fh, err := os.OpenFile(path, unix.O_PATH|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("open o_path procfd: %w", err)
+	}
+	procSelfFd, closer := ProcThreadSelf("fd/")
+	defer closer()
+	// Double-check the path is the one we expected.
+	if realpath, err := os.Readlink(filepath.Join(procSelfFd, strconv.Itoa(int(fh.Fd())))); err != nil {
+		fh.Close()
+		return nil, fmt.Errorf("procfd verification failed: %w", err)
+	} else if realpath != path {
+		fh.Close()
+		return nil, fmt.Errorf("possibly malicious path detected -- refusing to operate on %s", realpath)
+	}
+	return fh, nil
+}
+
+// openat2InRoot opens unsafePath within root with openat2(2).
+func openat2InRoot(root, unsafePath string) (*os.File, error) {
+	rootFd, err := unix.Open(root, unix.O_PATH|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: root, Err: err}
+	}
+	defer unix.Close(rootFd)
+
+	how := &unix.OpenHow{
+		Flags:   unix.O_PATH | unix.O_CLOEXEC,
+		Resolve: unix.RESOLVE_IN_ROOT | unix.RESOLVE_NO_MAGICLINKS,
+	}
+	// The kernel returns EAGAIN if a rename (anywhere on the system) may
+	// have affected the resolution; retry a few times before giving up.
+	for i := 0; ; i++ {
+		fd, err := unix.Openat2(rootFd, unsafePath, how)
+		if err == nil {
+			return os.NewFile(uintptr(fd), filepath.Join(root, unsafePath)), nil
+		}
+		if (!errors.Is(err, unix.EAGAIN) && !errors.Is(err, unix.EINTR)) || i == 32 {
+			return nil, &os.PathError{Op: "openat2", Path: filepath.Join(root, unsafePath), Err: err}
+		}
+	}
+}
+
+// WithProcfd runs the passed closure with a procfd path (/proc/self/fd/...)
+// corresponding to the unsafePath resolved within the root (see OpenInRoot),
+// so operating on it through the passed fdpath should be safe. Do not access
+// this path through the original path strings, and do not attempt to use the
+// pathname outside of the passed closure (the file handle will be freed once
+// the closure returns).
+func WithProcfd(root, unsafePath string, fn func(procfd string) error) error {
+	fh, err := OpenInRoot(root, unsafePath)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	procSelfFd, closer := ProcThreadSelf("fd/")
+	defer closer()
+
+	return fn(filepath.Join(procSelfFd, strconv.Itoa(int(fh.Fd()))))
+}
@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestOpenInRoot(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "etc"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "etc/passwd"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for name, target := range map[string]string{
+		"abs":    "/etc",
+		"dotdot": "../../../../../../etc",
+	} {
+		if err := os.Symlink(target, filepath.Join(root, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, fallback := range []bool{false, true} {
+		noOpenat2.Store(fallback)
+		for _, path := range []string{"/etc/passwd", "abs/passwd", "dotdot/passwd", filepath.Join(root, "abs/passwd")} {
+			fh, err := OpenInRoot(root, path)
+			if err != nil {
+				t.Errorf("fallback=%v: %s: %v", fallback, path, err)
+				continue
+			}
+			if p, _ := os.Readlink("/proc/self/fd/" + strconv.Itoa(int(fh.Fd()))); p != filepath.Join(root, "etc/passwd") {
+				t.Errorf("fallback=%v: %s: resolved to %s", fallback, path, p)
+			}
+			fh.Close()
+		}
+		if _, err := OpenInRoot(root, "/nonexistent"); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("fallback=%v: expected ErrNotExist, got %v", fallback, err)
+		}
+	}
+	noOpenat2.Store(false)
+}
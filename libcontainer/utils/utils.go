@@ -2,7 +2,6 @@ package utils
 
 import (
 	"encoding/binary"
-	"encoding/json"
 	"io"
 	"os"
 	"path/filepath"
@@ -10,6 +9,8 @@ import (
 	"unsafe"
 
 	"golang.org/x/sys/unix"
+
+	"github.com/szcdx/runc/internal/jsonutil"
 )
 
 const (
@@ -39,12 +40,12 @@ func ExitStatus(status unix.WaitStatus) int {
 	return status.ExitStatus()
 }
 
-// WriteJSON writes the provided struct v to w using standard json marshaling
+// WriteJSON writes the provided struct v to w using jsonutil.Marshal
 // without a trailing newline. This is used instead of json.Encoder because
 // there might be a problem in json decoder in some cases, see:
 // https://github.com/docker/docker/issues/14203#issuecomment-174177790
 func WriteJSON(w io.Writer, v interface{}) error {
-	data, err := json.Marshal(v)
+	data, err := jsonutil.Marshal(v)
 	if err != nil {
 		return err
 	}
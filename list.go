@@ -38,6 +38,20 @@ type containerState struct {
 	Annotations map[string]string `json:"annotations,omitempty"`
 	// The owner of the state directory (the owner of the container).
 	Owner string `json:"owner"`
+	// ExclusiveCPUs are the CPUs allocated to the container for its
+	// exclusive use, if any.
+	ExclusiveCPUs string `json:"exclusiveCpus,omitempty"`
+	// StopSignal is the signal sent to the container init to stop it.
+	StopSignal string `json:"stopSignal,omitempty"`
+	// StopGracePeriod is how long the container is given to exit after
+	// the stop signal, before it is killed.
+	StopGracePeriod string `json:"stopGracePeriod,omitempty"`
+	// LaunchRecord is the measurement of what the container was launched
+	// with, if it was made.
+	LaunchRecord *libcontainer.LaunchRecord `json:"launchRecord,omitempty"`
+	// CheckpointChain is the chain of the dumps of the container left
+	// running by runc checkpoint, if any.
+	CheckpointChain *libcontainer.CheckpointChain `json:"checkpointChain,omitempty"`
 }
 
 var listCommand = cli.Command{
@@ -123,7 +137,7 @@ func getContainers(context *cli.Context) ([]containerState, error) {
 	}
 	var s []containerState
 	for _, item := range list {
-		if !item.IsDir() {
+		if !item.IsDir() || item.Name() == libcontainer.SeccompCacheDir {
 			continue
 		}
 		st, err := item.Info()
@@ -143,17 +157,17 @@ func getContainers(context *cli.Context) ([]containerState, error) {
 
 		container, err := libcontainer.Load(root, item.Name())
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "load container %s: %v\n", item.Name(), err)
+			warn("load container "+item.Name(), err)
 			continue
 		}
 		containerStatus, err := container.Status()
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "status for %s: %v\n", item.Name(), err)
+			warn("status for "+item.Name(), err)
 			continue
 		}
 		state, err := container.State()
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "state for %s: %v\n", item.Name(), err)
+			warn("state for "+item.Name(), err)
 			continue
 		}
 		pid := state.BaseState.InitProcessPid
@@ -171,6 +185,7 @@ func getContainers(context *cli.Context) ([]containerState, error) {
 			Created:        state.BaseState.Created,
 			Annotations:    annotations,
 			Owner:          owner.Name,
+			ExclusiveCPUs:  state.ExclusiveCPUs,
 		})
 	}
 	return s, nil
@@ -94,6 +94,11 @@ func main() {
 			Value: "text",
 			Usage: "set the log format ('text' (default), or 'json')",
 		},
+		cli.StringFlag{
+			Name:  "error-format",
+			Value: "text",
+			Usage: "set the format of the error runc fails with ('text' (default), or 'json')",
+		},
 		cli.StringFlag{
 			Name:  "root",
 			Value: root,
@@ -113,13 +118,27 @@ func main() {
 			Value: "auto",
 			Usage: "ignore cgroup permission errors ('true', 'false', or 'auto')",
 		},
+		cli.StringFlag{
+			Name:  "config",
+			Value: defaultRuntimeConfig,
+			Usage: "runtime configuration file with the defaults for the global options and the container specs",
+		},
+		cli.BoolFlag{
+			Name:  "no-config",
+			Usage: "do not use the runtime configuration file",
+		},
 	}
 	app.Commands = []cli.Command{
+		batchCommand,
+		checkEnvironmentCommand,
 		checkpointCommand,
+		cpuPoolCommand,
 		createCommand,
+		debugCommand,
 		deleteCommand,
 		eventsCommand,
 		execCommand,
+		gcCommand,
 		killCommand,
 		listCommand,
 		pauseCommand,
@@ -127,23 +146,31 @@ func main() {
 		restoreCommand,
 		resumeCommand,
 		runCommand,
+		seccompCommand,
 		specCommand,
 		startCommand,
 		stateCommand,
+		stopCommand,
 		updateCommand,
 		featuresCommand,
 	}
 	app.Before = func(context *cli.Context) error {
+		switch f := context.GlobalString("error-format"); f {
+		case "text", "json":
+			errorFormat = f
+		default:
+			return errors.New("invalid error-format: " + f)
+		}
 		if !context.IsSet("root") && xdgDirUsed {
 			// According to the XDG specification, we need to set anything in
 			// XDG_RUNTIME_DIR to have a sticky bit if we don't want it to get
 			// auto-pruned.
 			if err := os.MkdirAll(root, 0o700); err != nil {
-				fmt.Fprintln(os.Stderr, "the path in $XDG_RUNTIME_DIR must be writable by the user")
+				warn("the path in $XDG_RUNTIME_DIR must be writable by the user", nil)
 				fatal(err)
 			}
 			if err := os.Chmod(root, os.FileMode(0o700)|os.ModeSticky); err != nil {
-				fmt.Fprintln(os.Stderr, "you should check permission of the path in $XDG_RUNTIME_DIR")
+				warn("you should check permission of the path in $XDG_RUNTIME_DIR", nil)
 				fatal(err)
 			}
 		}
@@ -152,8 +179,18 @@ func main() {
 		}
 		// TODO: remove this in runc 1.3.0.
 		if context.IsSet("criu") {
-			fmt.Fprintln(os.Stderr, "WARNING: --criu ignored (criu binary from $PATH is used); do not use")
+			warn("WARNING: --criu ignored (criu binary from $PATH is used); do not use", nil)
 		}
+		config, err := loadRuntimeConfig(context)
+		if err != nil {
+			return err
+		}
+		if config != nil {
+			if err := config.applyGlobalOptions(context); err != nil {
+				return err
+			}
+		}
+		hostConfig = config
 
 		return configLogrus(context)
 	}
@@ -162,6 +199,11 @@ func main() {
 	// the error on cli.ErrWriter and exit.
 	// Use our own writer here to ensure the log gets sent to the right location.
 	cli.ErrWriter = &FatalWriter{cli.ErrWriter}
+	app.OnUsageError = onUsageError
+	for i := range app.Commands {
+		app.Commands[i].OnUsageError = onUsageError
+	}
+	setErrorFormat(os.Args, app.Flags)
 	if err := app.Run(os.Args); err != nil {
 		fatal(err)
 	}
@@ -172,6 +214,13 @@ type FatalWriter struct {
 }
 
 func (f *FatalWriter) Write(p []byte) (n int, err error) {
+	if errorFormat == "json" {
+		// The error is written by fatal, as JSON.
+		if !logrusToStderr() {
+			logrus.Error(string(p))
+		}
+		return len(p), nil
+	}
 	logrus.Error(string(p))
 	if !logrusToStderr() {
 		return f.cliErrWriter.Write(p)
@@ -215,6 +264,9 @@ func configLogrus(context *cli.Context) error {
 		}
 		logrus.SetOutput(f)
 	}
+	if errorFormat == "json" && logrusToStderr() {
+		logrus.SetFormatter(jsonErrorFormatter{})
+	}
 
 	return nil
 }
@@ -4,13 +4,17 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"strconv"
 	"strings"
+	"text/tabwriter"
 
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli"
+
+	"github.com/szcdx/runc/libcontainer"
 )
 
 var psCommand = cli.Command{
@@ -23,6 +27,10 @@ var psCommand = cli.Command{
 			Value: "table",
 			Usage: `select one of: ` + formatOptions,
 		},
+		cli.BoolFlag{
+			Name:  "tree",
+			Usage: "show the container processes as a tree, with their cgroups",
+		},
 	},
 	Action: func(context *cli.Context) error {
 		if err := checkArgs(context, 1, minArgs); err != nil {
@@ -41,6 +49,24 @@ var psCommand = cli.Command{
 			return err
 		}
 
+		if context.Bool("tree") {
+			if len(context.Args()) > 1 {
+				return errors.New("ps options can not be used with --tree")
+			}
+			tree, err := container.ProcessTree()
+			if err != nil {
+				return err
+			}
+			switch context.String("format") {
+			case "table":
+				return printProcessTree(os.Stdout, tree)
+			case "json":
+				return json.NewEncoder(os.Stdout).Encode(tree)
+			default:
+				return errors.New("invalid format option")
+			}
+		}
+
 		pids, err := container.Processes()
 		if err != nil {
 			return err
@@ -98,6 +124,26 @@ var psCommand = cli.Command{
 	SkipArgReorder: true,
 }
 
+// printProcessTree prints the process tree as a table, with the children
+// indented under their parents.
+func printProcessTree(out io.Writer, tree []*libcontainer.ProcessInfo) error {
+	w := tabwriter.NewWriter(out, 8, 1, 3, ' ', 0)
+	fmt.Fprint(w, "PID\tPPID\tCGROUP\tCOMMAND\n")
+	var printProcs func(procs []*libcontainer.ProcessInfo, indent string)
+	printProcs = func(procs []*libcontainer.ProcessInfo, indent string) {
+		for _, p := range procs {
+			fmt.Fprintf(w, "%d\t%d\t%s\t%s%s\n", p.Pid, p.PPid, p.Cgroup, indent, p.Command)
+			if indent == "" {
+				printProcs(p.Children, "\\_ ")
+			} else {
+				printProcs(p.Children, "   "+indent)
+			}
+		}
+	}
+	printProcs(tree, "")
+	return w.Flush()
+}
+
 func getPidIndex(title string) (int, error) {
 	titles := strings.Fields(title)
 
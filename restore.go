@@ -1,8 +1,12 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 
+	"github.com/opencontainers/runtime-spec/specs-go"
 	"github.com/sirupsen/logrus"
 	"github.com/szcdx/runc/libcontainer/userns"
 	"github.com/urfave/cli"
@@ -28,6 +32,11 @@ using the runc checkpoint command.`,
 			Value: "",
 			Usage: "path to criu image files for restoring",
 		},
+		cli.StringFlag{
+			Name:  "from-snapshot",
+			Value: "",
+			Usage: "path to criu image files to restore a new container from, leaving them untouched so that more containers can be restored from them",
+		},
 		cli.StringFlag{
 			Name:  "work-path",
 			Value: "",
@@ -37,6 +46,15 @@ using the runc checkpoint command.`,
 			Name:  "tcp-established",
 			Usage: "allow open tcp connections",
 		},
+		cli.BoolFlag{
+			Name:  "tcp-close",
+			Usage: "restore connected tcp connections as closed",
+		},
+		cli.StringFlag{
+			Name:  "network-lock",
+			Value: "",
+			Usage: "method the network was locked with on checkpoint, to unlock it with: iptables|nftables (default: iptables)",
+		},
 		cli.BoolFlag{
 			Name:  "ext-unix-sk",
 			Usage: "allow external unix sockets",
@@ -88,6 +106,16 @@ using the runc checkpoint command.`,
 			Name:  "lazy-pages",
 			Usage: "use userfaultfd to lazily restore memory pages",
 		},
+		cli.StringFlag{
+			Name:  "stream",
+			Value: "",
+			Usage: "tcp://ADDRESS:PORT to receive the images streamed by runc checkpoint --stream at, instead of reading them from the image path",
+		},
+		cli.StringFlag{
+			Name:  "page-server",
+			Value: "",
+			Usage: "ADDRESS:PORT of the page server to lazily restore memory pages from (requires --lazy-pages)",
+		},
 		cli.StringFlag{
 			Name:  "lsm-profile",
 			Value: "",
@@ -98,6 +126,15 @@ using the runc checkpoint command.`,
 			Value: "",
 			Usage: "Specify an LSM mount context to be used during restore.",
 		},
+		cli.StringSliceFlag{
+			Name:  "network-restore-hook",
+			Usage: "path of a program to run once the container is restored, before it is resumed, with the restored network namespace as fd 3",
+		},
+		cli.StringFlag{
+			Name:  "update-resources",
+			Value: "",
+			Usage: "path to the file containing the resources to set before the restored processes are resumed (in the runc update --resources format)",
+		},
 	},
 	Action: func(context *cli.Context) error {
 		if err := checkArgs(context, 1, exactArgs); err != nil {
@@ -108,6 +145,9 @@ using the runc checkpoint command.`,
 			logrus.Warn("runc checkpoint is untested with rootless containers")
 		}
 
+		if context.String("page-server") != "" && !context.Bool("lazy-pages") {
+			return errors.New("--page-server requires --lazy-pages")
+		}
 		options, err := criuOptions(context)
 		if err != nil {
 			return err
@@ -122,3 +162,24 @@ using the runc checkpoint command.`,
 		return nil
 	},
 }
+
+// updateSpecResources sets the resources from the file at path, which has
+// the format of runc update --resources, in the container spec. The
+// resources which are not in the file are left as they are.
+func updateSpecResources(spec *specs.Spec, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if spec.Linux == nil {
+		spec.Linux = &specs.Linux{}
+	}
+	if spec.Linux.Resources == nil {
+		spec.Linux.Resources = &specs.LinuxResources{}
+	}
+	if err := json.NewDecoder(f).Decode(spec.Linux.Resources); err != nil {
+		return fmt.Errorf("unable to parse %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+)
+
+func TestUpdateSpecResources(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "resources.json")
+	if err := os.WriteFile(path, []byte(`{"memory": {"limit": 209715200}, "pids": {"limit": 50}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	limit, swap := int64(104857600), int64(314572800)
+	spec := &specs.Spec{Linux: &specs.Linux{Resources: &specs.LinuxResources{
+		Memory: &specs.LinuxMemory{Limit: &limit, Swap: &swap},
+	}}}
+	if err := updateSpecResources(spec, path); err != nil {
+		t.Fatal(err)
+	}
+	r := spec.Linux.Resources
+	if *r.Memory.Limit != 209715200 {
+		t.Errorf("expected memory limit 209715200, got %d", *r.Memory.Limit)
+	}
+	// The resources which are not in the file are kept.
+	if *r.Memory.Swap != 314572800 {
+		t.Errorf("expected memory swap 314572800, got %d", *r.Memory.Swap)
+	}
+	if r.Pids == nil || r.Pids.Limit != 50 {
+		t.Errorf("expected pids limit 50, got %+v", r.Pids)
+	}
+
+	spec = &specs.Spec{}
+	if err := updateSpecResources(spec, path); err != nil {
+		t.Fatal(err)
+	}
+	if spec.Linux.Resources.Pids == nil || spec.Linux.Resources.Pids.Limit != 50 {
+		t.Errorf("expected pids limit 50, got %+v", spec.Linux.Resources.Pids)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"memory": 1}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := updateSpecResources(spec, path); err == nil {
+		t.Fatal("expected an error")
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/urfave/cli"
+)
+
+// defaultRuntimeConfig is the host-level runtime configuration file, which
+// is used (if it exists) unless --config or --no-config is given.
+const defaultRuntimeConfig = "/etc/runc/config.json"
+
+// runtimeConfig is the host-level runtime configuration, which lets the
+// administrator set the defaults for the global options, and for the
+// container specs.
+//
+// The global options given on the command line take precedence over the
+// ones set in the file. The container spec settings are only used for the
+// containers the specs of which do not set them (even if to an empty
+// value, such as "maskedPaths": []).
+type runtimeConfig struct {
+	// SystemdCgroup is the default of --systemd-cgroup.
+	SystemdCgroup *bool `json:"systemdCgroup,omitempty"`
+	// LogFormat is the default of --log-format.
+	LogFormat string `json:"logFormat,omitempty"`
+
+	// Seccomp is the default linux.seccomp of the container specs.
+	Seccomp *specs.LinuxSeccomp `json:"seccomp,omitempty"`
+	// MaskedPaths is the default linux.maskedPaths of the container specs.
+	MaskedPaths []string `json:"maskedPaths,omitempty"`
+	// ReadonlyPaths is the default linux.readonlyPaths of the container
+	// specs.
+	ReadonlyPaths []string `json:"readonlyPaths,omitempty"`
+}
+
+// hostConfig is the runtime configuration in use, or nil if there is none.
+var hostConfig *runtimeConfig
+
+// loadRuntimeConfig reads the runtime configuration file, as chosen by the
+// --config and --no-config global options. If the default file does not
+// exist, nil is returned.
+func loadRuntimeConfig(context *cli.Context) (*runtimeConfig, error) {
+	if context.GlobalBool("no-config") {
+		if context.GlobalIsSet("config") {
+			return nil, errors.New("--config and --no-config can not be used together")
+		}
+		return nil, nil
+	}
+	path := context.GlobalString("config")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && !context.GlobalIsSet("config") {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("unable to read runtime config: %w", err)
+	}
+	config := &runtimeConfig{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(config); err != nil {
+		return nil, fmt.Errorf("unable to parse runtime config %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// applyGlobalOptions sets the global options which are not given on the
+// command line to the values from the runtime configuration.
+func (r *runtimeConfig) applyGlobalOptions(context *cli.Context) error {
+	if r.SystemdCgroup != nil && !context.GlobalIsSet("systemd-cgroup") {
+		if err := context.GlobalSet("systemd-cgroup", fmt.Sprint(*r.SystemdCgroup)); err != nil {
+			return err
+		}
+	}
+	if r.LogFormat != "" && !context.GlobalIsSet("log-format") {
+		if err := context.GlobalSet("log-format", r.LogFormat); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applySpec fills in the settings of the container spec which it does not
+// set with the ones from the runtime configuration.
+func (r *runtimeConfig) applySpec(spec *specs.Spec) {
+	if r.Seccomp == nil && r.MaskedPaths == nil && r.ReadonlyPaths == nil {
+		return
+	}
+	if spec.Linux == nil {
+		spec.Linux = &specs.Linux{}
+	}
+	if spec.Linux.Seccomp == nil && r.Seccomp != nil {
+		seccomp := *r.Seccomp
+		spec.Linux.Seccomp = &seccomp
+	}
+	if spec.Linux.MaskedPaths == nil && r.MaskedPaths != nil {
+		spec.Linux.MaskedPaths = append([]string(nil), r.MaskedPaths...)
+	}
+	if spec.Linux.ReadonlyPaths == nil && r.ReadonlyPaths != nil {
+		spec.Linux.ReadonlyPaths = append([]string(nil), r.ReadonlyPaths...)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/urfave/cli"
+)
+
+func TestRuntimeConfigApplySpec(t *testing.T) {
+	config := &runtimeConfig{
+		Seccomp:     &specs.LinuxSeccomp{DefaultAction: specs.ActErrno},
+		MaskedPaths: []string{"/proc/kcore"},
+	}
+
+	spec := &specs.Spec{}
+	config.applySpec(spec)
+	if spec.Linux.Seccomp == nil || spec.Linux.Seccomp.DefaultAction != specs.ActErrno {
+		t.Errorf("expected the default seccomp profile, got %+v", spec.Linux.Seccomp)
+	}
+	if !reflect.DeepEqual(spec.Linux.MaskedPaths, config.MaskedPaths) {
+		t.Errorf("expected the default masked paths, got %v", spec.Linux.MaskedPaths)
+	}
+	if spec.Linux.ReadonlyPaths != nil {
+		t.Errorf("expected no readonly paths, got %v", spec.Linux.ReadonlyPaths)
+	}
+
+	// The spec settings take precedence, even if empty.
+	spec = &specs.Spec{Linux: &specs.Linux{
+		Seccomp:     &specs.LinuxSeccomp{DefaultAction: specs.ActAllow},
+		MaskedPaths: []string{},
+	}}
+	config.applySpec(spec)
+	if spec.Linux.Seccomp.DefaultAction != specs.ActAllow {
+		t.Errorf("expected the spec seccomp profile, got %+v", spec.Linux.Seccomp)
+	}
+	if len(spec.Linux.MaskedPaths) != 0 {
+		t.Errorf("expected no masked paths, got %v", spec.Linux.MaskedPaths)
+	}
+}
+
+func TestLoadRuntimeConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	newContext := func(args ...string) *cli.Context {
+		set := flag.NewFlagSet("test", flag.ContinueOnError)
+		set.String("config", path, "")
+		set.Bool("no-config", false, "")
+		set.Bool("systemd-cgroup", false, "")
+		set.String("log-format", "text", "")
+		if err := set.Parse(args); err != nil {
+			t.Fatal(err)
+		}
+		return cli.NewContext(nil, set, nil)
+	}
+
+	// A missing default file is not an error, unless it is given explicitly.
+	if config, err := loadRuntimeConfig(newContext()); err != nil || config != nil {
+		t.Fatalf("expected no config and no error, got %+v, %v", config, err)
+	}
+	if _, err := loadRuntimeConfig(newContext("--config", path)); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	if err := os.WriteFile(path, []byte(`{"systemdCgroup": true, "logFormat": "json"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	context := newContext("--log-format", "text")
+	config, err := loadRuntimeConfig(context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := config.applyGlobalOptions(context); err != nil {
+		t.Fatal(err)
+	}
+	if !context.GlobalBool("systemd-cgroup") {
+		t.Error("expected --systemd-cgroup to be set from the config")
+	}
+	// The command line takes precedence.
+	if f := context.GlobalString("log-format"); f != "text" {
+		t.Errorf("expected --log-format text, got %s", f)
+	}
+
+	if config, err := loadRuntimeConfig(newContext("--no-config")); err != nil || config != nil {
+		t.Fatalf("expected no config and no error, got %+v, %v", config, err)
+	}
+
+	// Unknown fields are errors.
+	if err := os.WriteFile(path, []byte(`{"systemdCgroups": true}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadRuntimeConfig(newContext()); err == nil {
+		t.Fatal("expected an error")
+	}
+}
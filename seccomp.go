@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/urfave/cli"
+	"golang.org/x/net/bpf"
+
+	"github.com/szcdx/runc/libcontainer/seccomp"
+	"github.com/szcdx/runc/libcontainer/specconv"
+)
+
+var seccompCommand = cli.Command{
+	Name:  "seccomp",
+	Usage: "seccomp related operations",
+	Subcommands: []cli.Command{
+		seccompCompileCommand,
+		seccompResendCommand,
+	},
+}
+
+var seccompCompileCommand = cli.Command{
+	Name:  "compile",
+	Usage: "print the seccomp BPF program which would be loaded for a bundle",
+	Description: `The compile command compiles the seccomp profile from the bundle's config.json
+exactly as runc does when starting a container (including the -ENOSYS stub
+runc prepends to the filter), and prints the resulting BPF program in a
+human-readable form, along with the flags it would be loaded with.`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "bundle, b",
+			Value: "",
+			Usage: `path to the root of the bundle directory, defaults to the current directory`,
+		},
+	},
+	Action: func(context *cli.Context) error {
+		if err := checkArgs(context, 0, exactArgs); err != nil {
+			return err
+		}
+		if !seccomp.Enabled {
+			return errors.New("seccomp support is not compiled in")
+		}
+		spec, err := setupSpec(context)
+		if err != nil {
+			return err
+		}
+		if spec.Linux == nil || spec.Linux.Seccomp == nil {
+			return errors.New("no seccomp profile in the spec")
+		}
+		config, err := specconv.SetupSeccomp(spec.Linux.Seccomp)
+		if err != nil {
+			return err
+		}
+		prog, err := seccomp.Compile(config)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("# flags: %#x\n", prog.Flags)
+		if prog.NoNewPrivs {
+			fmt.Println("# no_new_privs: true")
+		}
+		insns, _ := bpf.Disassemble(prog.Filter)
+		for i, insn := range insns {
+			fmt.Printf("%4d: %s\n", i, insn)
+		}
+		return nil
+	},
+}
+
+var seccompResendCommand = cli.Command{
+	Name:      "resend",
+	Usage:     "send the seccomp notify fd of a running container to the seccomp agent again",
+	ArgsUsage: `<container-id>`,
+	Description: `The resend command sends the seccomp notify fd of the container's init
+process to the seccomp agent listening on the profile's listenerPath again,
+so that a new agent (for example, an upgraded one) can take over handling
+the notifications without the container being restarted.
+
+This is only possible if the container was created with the annotation
+"org.opencontainers.runc.seccomp.keep-listener-fd" set to "true", which
+makes runc keep a copy of the fd for the container's lifetime.`,
+	Action: func(context *cli.Context) error {
+		if err := checkArgs(context, 1, exactArgs); err != nil {
+			return err
+		}
+		container, err := getContainer(context)
+		if err != nil {
+			return err
+		}
+		return container.ResendSeccompNotifyFd()
+	},
+}
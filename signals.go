@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"os"
 	"os/signal"
 
@@ -49,8 +50,9 @@ type signalHandler struct {
 }
 
 // forward handles the main signal event loop forwarding, resizing, or reaping depending
-// on the signal received.
-func (h *signalHandler) forward(process *libcontainer.Process, tty *tty, detach bool) (int, error) {
+// on the signal received. If exited is not nil, it is to be closed once the
+// process has exited, so that its exit is noticed without relying on SIGCHLD.
+func (h *signalHandler) forward(process *libcontainer.Process, exited <-chan struct{}, tty *tty, detach bool) (int, error) {
 	// make sure we know the pid of our main process so that we can return
 	// after it dies.
 	if detach && h.notifySocket == nil {
@@ -75,28 +77,30 @@ func (h *signalHandler) forward(process *libcontainer.Process, tty *tty, detach
 	// stdout might have disappeared (due to races with when SIGHUP is sent).
 	_ = tty.resize()
 	// Handle and forward signals.
-	for s := range h.signals {
+	for {
+		var s os.Signal
+		select {
+		case sig, ok := <-h.signals:
+			if !ok {
+				return -1, nil
+			}
+			s = sig
+		case <-exited:
+			exited = nil
+			if status, ok := h.reapExits(process, pid1); ok {
+				return status, nil
+			}
+			// The process has not been reparented to runc (such as
+			// with --no-subreaper), so its exit status is unknown.
+			return -1, fmt.Errorf("process %d exited, but its exit status is unknown as it is not a child of runc", pid1)
+		}
 		switch s {
 		case unix.SIGWINCH:
 			// Ignore errors resizing, as above.
 			_ = tty.resize()
 		case unix.SIGCHLD:
-			exits, err := h.reap()
-			if err != nil {
-				logrus.Error(err)
-			}
-			for _, e := range exits {
-				logrus.WithFields(logrus.Fields{
-					"pid":    e.pid,
-					"status": e.status,
-				}).Debug("process exited")
-				if e.pid == pid1 {
-					// call Wait() on the process even though we already have the exit
-					// status because we must ensure that any of the go specific process
-					// fun such as flushing pipes are complete before we return.
-					_, _ = process.Wait()
-					return e.status, nil
-				}
+			if status, ok := h.reapExits(process, pid1); ok {
+				return status, nil
 			}
 		case unix.SIGURG:
 			// SIGURG is used by go runtime for async preemptive
@@ -111,7 +115,30 @@ func (h *signalHandler) forward(process *libcontainer.Process, tty *tty, detach
 			}
 		}
 	}
-	return -1, nil
+}
+
+// reapExits reaps the exited children of runc, including the processes
+// reparented to it as a child subreaper, and returns the exit status of the
+// process with pid1, if it is one of them.
+func (h *signalHandler) reapExits(process *libcontainer.Process, pid1 int) (int, bool) {
+	exits, err := h.reap()
+	if err != nil {
+		logrus.Error(err)
+	}
+	for _, e := range exits {
+		logrus.WithFields(logrus.Fields{
+			"pid":    e.pid,
+			"status": e.status,
+		}).Debug("process exited")
+		if e.pid == pid1 {
+			// call Wait() on the process even though we already have the exit
+			// status because we must ensure that any of the go specific process
+			// fun such as flushing pipes are complete before we return.
+			_, _ = process.Wait()
+			return e.status, true
+		}
+	}
+	return 0, false
 }
 
 // reap runs wait4 in a loop until we have finished processing any existing exits
@@ -5,9 +5,13 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"runtime"
 
 	"github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/szcdx/runc/internal/jsonutil"
 	"github.com/szcdx/runc/libcontainer/configs"
+	"github.com/szcdx/runc/libcontainer/seccomp"
+	"github.com/szcdx/runc/libcontainer/seccomp/defaults"
 	"github.com/szcdx/runc/libcontainer/specconv"
 	"github.com/urfave/cli"
 )
@@ -74,11 +78,22 @@ created by an unprivileged user.
 			Name:  "rootless",
 			Usage: "generate a configuration for a rootless container",
 		},
+		cli.StringFlag{
+			Name:  "seccomp",
+			Usage: "add a seccomp profile to the generated specification (only \"default\" is supported)",
+		},
+		cli.BoolFlag{
+			Name:  "validate-seccomp",
+			Usage: "validate the seccomp profile of the existing specification file instead of creating a new one",
+		},
 	},
 	Action: func(context *cli.Context) error {
 		if err := checkArgs(context, 0, exactArgs); err != nil {
 			return err
 		}
+		if context.Bool("validate-seccomp") {
+			return validateSeccomp(context)
+		}
 		spec := specconv.Example()
 
 		rootless := context.Bool("rootless")
@@ -86,6 +101,18 @@ created by an unprivileged user.
 			specconv.ToRootless(spec)
 		}
 
+		switch profile := context.String("seccomp"); profile {
+		case "":
+		case "default":
+			p, err := defaults.Profile(runtime.GOARCH, spec.Process.Capabilities.Bounding)
+			if err != nil {
+				return err
+			}
+			spec.Linux.Seccomp = p
+		default:
+			return fmt.Errorf("unknown seccomp profile %q", profile)
+		}
+
 		checkNoFile := func(name string) error {
 			_, err := os.Stat(name)
 			if err == nil {
@@ -113,18 +140,52 @@ created by an unprivileged user.
 	},
 }
 
+// validateSeccomp validates the seccomp profile from the bundle's spec,
+// printing all the issues found.
+func validateSeccomp(context *cli.Context) error {
+	spec, err := setupSpec(context)
+	if err != nil {
+		return err
+	}
+	if spec.Linux == nil || spec.Linux.Seccomp == nil {
+		return errors.New("no seccomp profile in the spec")
+	}
+	config, err := specconv.SetupSeccomp(spec.Linux.Seccomp)
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for _, err := range seccomp.Validate(config) {
+		var w *seccomp.Warning
+		if errors.As(err, &w) {
+			fmt.Println("warning:", w)
+			continue
+		}
+		fmt.Println("error:", err)
+		failed++
+	}
+	if failed > 0 {
+		return fmt.Errorf("seccomp profile has %d error(s)", failed)
+	}
+	return nil
+}
+
+// errSpecNotFound is the error loadSpec wraps if there is no specification
+// file.
+var errSpecNotFound = errors.New("not found")
+
 // loadSpec loads the specification from the provided path.
 func loadSpec(cPath string) (spec *specs.Spec, err error) {
 	cf, err := os.Open(cPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("JSON specification file %s not found", cPath)
+			return nil, fmt.Errorf("JSON specification file %s %w", cPath, errSpecNotFound)
 		}
 		return nil, err
 	}
 	defer cf.Close()
 
-	if err = json.NewDecoder(cf).Decode(&spec); err != nil {
+	if err = jsonutil.NewDecoder(cf).Decode(&spec); err != nil {
 		return nil, err
 	}
 	if spec == nil {
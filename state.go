@@ -7,6 +7,7 @@ import (
 	"github.com/szcdx/runc/libcontainer"
 	"github.com/szcdx/runc/libcontainer/utils"
 	"github.com/urfave/cli"
+	"golang.org/x/sys/unix"
 )
 
 var stateCommand = cli.Command{
@@ -39,15 +40,21 @@ instance of a container.`,
 		}
 		bundle, annotations := utils.Annotations(state.Config.Labels)
 		cs := containerState{
-			Version:        state.BaseState.Config.Version,
-			ID:             state.BaseState.ID,
-			InitProcessPid: pid,
-			Status:         containerStatus.String(),
-			Bundle:         bundle,
-			Rootfs:         state.BaseState.Config.Rootfs,
-			Created:        state.BaseState.Created,
-			Annotations:    annotations,
+			Version:         state.BaseState.Config.Version,
+			ID:              state.BaseState.ID,
+			InitProcessPid:  pid,
+			Status:          containerStatus.String(),
+			Bundle:          bundle,
+			Rootfs:          state.BaseState.Config.Rootfs,
+			Created:         state.BaseState.Created,
+			Annotations:     annotations,
+			ExclusiveCPUs:   state.ExclusiveCPUs,
+			LaunchRecord:    state.LaunchRecord,
+			CheckpointChain: state.CheckpointChain,
 		}
+		sig, grace := container.StopSettings()
+		cs.StopSignal = unix.SignalName(sig)
+		cs.StopGracePeriod = grace.String()
 		data, err := json.MarshalIndent(cs, "", "  ")
 		if err != nil {
 			return err
@@ -0,0 +1,28 @@
+package main
+
+import "github.com/urfave/cli"
+
+var stopCommand = cli.Command{
+	Name:  "stop",
+	Usage: "stop a container gracefully",
+	ArgsUsage: `<container-id>
+
+Where "<container-id>" is the name for the instance of the container.`,
+	Description: `The stop command sends the stop signal of the container (SIGTERM, unless
+configured otherwise) to its init process, waits for the container to exit
+for up to the grace period (10s, unless configured otherwise), and kills it
+with SIGKILL if it is still running then. The stop signal and grace period
+are set with the "org.opencontainers.runc.stop.signal" and
+"org.opencontainers.runc.stop.grace-period" annotations, or "runc update",
+and are shown by "runc state".`,
+	Action: func(context *cli.Context) error {
+		if err := checkArgs(context, 1, exactArgs); err != nil {
+			return err
+		}
+		container, err := getContainer(context)
+		if err != nil {
+			return err
+		}
+		return container.Stop()
+	},
+}
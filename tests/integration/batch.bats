@@ -0,0 +1,64 @@
+#!/usr/bin/env bats
+
+load helpers
+
+function setup() {
+	setup_busybox
+}
+
+function teardown() {
+	teardown_bundle
+}
+
+@test "runc batch start/kill/delete" {
+	for i in 1 2 3; do
+		runc create --console-socket "$CONSOLE_SOCKET" test_box$i
+		[ "$status" -eq 0 ]
+		testcontainer test_box$i created
+	done
+
+	runc batch --jobs 2 start test_box1 test_box2 test_box3
+	[ "$status" -eq 0 ]
+	[[ ${lines[0]} =~ ID\ +RESULT ]]
+	for i in 1 2 3; do
+		testcontainer test_box$i running
+	done
+
+	runc batch --signal KILL kill test_box1 test_box2 test_box3
+	[ "$status" -eq 0 ]
+	for i in 1 2 3; do
+		wait_for_container 10 1 test_box$i stopped
+	done
+
+	runc batch --format json delete test_box1 test_box2 test_box3
+	[ "$status" -eq 0 ]
+	[ "$(jq -r '.[].id' <<<"$output" | xargs)" = "test_box1 test_box2 test_box3" ]
+	[ "$(jq -r '.[] | select(.error != null)' <<<"$output")" = "" ]
+	for i in 1 2 3; do
+		runc state test_box$i
+		[ "$status" -ne 0 ]
+	done
+}
+
+@test "runc batch [partial failure]" {
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_box1
+	[ "$status" -eq 0 ]
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_box2
+	[ "$status" -eq 0 ]
+
+	runc batch --format json delete test_box1 nonexistent
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"2 of 2 operations failed"* ]]
+
+	# As with runc delete --force, a missing container is not an error.
+	runc batch --format json --force delete test_box1 nonexistent
+	[ "$status" -eq 0 ]
+	runc state test_box1
+	[ "$status" -ne 0 ]
+
+	# The flags are checked before any container is deleted.
+	runc batch --format yaml --force delete test_box2
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"invalid format option"* ]]
+	testcontainer test_box2 running
+}
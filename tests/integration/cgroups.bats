@@ -199,20 +199,6 @@ function setup() {
 	check_cgroup_value "cpu.idle" "1"
 }
 
-# Convert size in KB to hugetlb size suffix.
-convert_hugetlb_size() {
-	local size=$1
-	local units=("KB" "MB" "GB")
-	local idx=0
-
-	while ((size >= 1024)); do
-		((size /= 1024))
-		((idx++))
-	done
-
-	echo "$size${units[$idx]}"
-}
-
 @test "runc run (hugetlb limits)" {
 	requires cgroups_hugetlb
 	[ $EUID -ne 0 ] && requires rootless_cgroup
@@ -263,6 +249,49 @@ convert_hugetlb_size() {
 	done
 }
 
+@test "runc run (hugetlb accounting)" {
+	requires cgroups_hugetlb
+	[ $EUID -ne 0 ] && requires rootless_cgroup
+	# shellcheck disable=SC2012 # ls is fine here.
+	size_kb=$(ls /sys/kernel/mm/hugepages/ | sed -e 's/.*hugepages-//' -e 's/kB$//' | head -1)
+	if [ -z "$size_kb" ]; then
+		skip "requires hugetlb"
+	fi
+	size=$(convert_hugetlb_size "$size_kb")
+	limit=$((size_kb * 1024))
+
+	lim="max"
+	[ -v CGROUP_V1 ] && lim="limit_in_bytes"
+
+	update_config '.linux.resources.hugepageLimits = [{ pagesize: "'"$size"'", limit: '"$limit"' }]
+		| .annotations += {"org.opencontainers.runc.hugetlb.accounting": "fault"}'
+	set_cgroups_path
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_hugetlb
+	[ "$status" -eq 0 ]
+	check_cgroup_value "hugetlb.${size}.$lim" "$limit"
+	if test -f "$(get_cgroup_path hugetlb)/hugetlb.${size}.rsvd.$lim"; then
+		[ "$(get_cgroup_value "hugetlb.${size}.rsvd.$lim")" != "$limit" ]
+		rsvd=1
+	fi
+	runc delete -f test_hugetlb
+	[ "$status" -eq 0 ]
+
+	update_config '.annotations += {"org.opencontainers.runc.hugetlb.accounting": "reservation"}'
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_hugetlb
+	if [ -v rsvd ]; then
+		[ "$status" -eq 0 ]
+		check_cgroup_value "hugetlb.${size}.rsvd.$lim" "$limit"
+		[ "$(get_cgroup_value "hugetlb.${size}.$lim")" != "$limit" ]
+
+		runc events --stats test_hugetlb
+		[ "$status" -eq 0 ]
+		[[ "$output" == *'"fault"'* ]]
+	else
+		[ "$status" -ne 0 ]
+		[[ "$output" == *"hugetlb reservation accounting is not supported"* ]]
+	fi
+}
+
 @test "runc run (cgroup v2 resources.unified only)" {
 	requires root cgroups_v2
 
@@ -303,6 +332,33 @@ convert_hugetlb_size() {
 	check_cpu_weight 42
 }
 
+@test "runc run (cgroup v2 resources.unified validation)" {
+	requires cgroups_v2
+	[ $EUID -ne 0 ] && requires rootless_cgroup
+
+	set_cgroups_path
+	update_config '.linux.resources.unified |= {"cgroup.procs": "1"}'
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_cgroups_unified
+	[ "$status" -ne 0 ]
+	[[ "$output" == *'"cgroup.procs" can'"'"'t be set'* ]]
+
+	update_config '.linux.resources.unified = {"memory.max": "1 2"}'
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_cgroups_unified
+	[ "$status" -ne 0 ]
+	[[ "$output" == *'"memory.max" value invalid'* ]]
+
+	update_config '.linux.resources.unified = {"memory.bogus": "1"}'
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_cgroups_unified
+	[ "$status" -ne 0 ]
+	[[ "$output" == *'not known to runc'* ]]
+
+	# With the opt-in, the value is written as is (and fails, as there is no such file).
+	update_config '.annotations += {"org.opencontainers.runc.cgroup.unified.allow-unknown": "true"}'
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_cgroups_unified
+	[ "$status" -ne 0 ]
+	[[ "$output" != *'not known to runc'* ]]
+}
+
 @test "runc run (cgroup v2 resources.unified override)" {
 	requires root cgroups_v2
 
@@ -473,3 +529,193 @@ convert_hugetlb_size() {
 	# Cleanup.
 	rmdir "$FREEZER_DIR"
 }
+
+@test "runc run (accounting-only cgroup)" {
+	requires root
+
+	set_cgroups_path
+	update_config '	  .annotations += {"org.opencontainers.runc.cgroup.accounting-only": "true"}
+			| .linux.resources.memory.limit |= 33554432
+			| .linux.resources.pids.limit |= 10'
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_cgroups_accounting
+	[ "$status" -eq 0 ]
+
+	# The container is in its cgroup, but no limits are set.
+	if [ -v CGROUP_V2 ]; then
+		check_cgroup_value "memory.max" "max"
+	else
+		[ "$(get_cgroup_value "memory.limit_in_bytes")" != "33554432" ]
+	fi
+	check_cgroup_value "pids.max" "max"
+
+	runc events --stats test_cgroups_accounting
+	[ "$status" -eq 0 ]
+	jq -e '.data.memory.usage.usage > 0' <<<"${lines[0]}"
+	jq -e '.data.pids.current > 0' <<<"${lines[0]}"
+
+	runc update --pids-limit 20 test_cgroups_accounting
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"accounting-only"* ]]
+
+	runc features
+	[ "$status" -eq 0 ]
+	jq -e '.annotations["org.opencontainers.runc.cgroup.accounting-only.enabled"] == "true"' <<<"$output"
+}
+
+@test "runc run (read-only cgroupfs)" {
+	requires root cgroups_v2
+
+	set_cgroups_path
+	update_config '.linux.resources.pids.limit |= 10'
+
+	# Runs runc in a mount namespace with /sys/fs/cgroup remounted read-only.
+	function runc_ro_cgroupfs() {
+		run unshare -m sh -c 'mount -o remount,bind,ro /sys/fs/cgroup && exec "$@"' - \
+			"$RUNC" ${ROOT:+--root "$ROOT/state"} "$@"
+		echo "$output" >&2
+	}
+
+	runc_ro_cgroupfs run -d --console-socket "$CONSOLE_SOCKET" test_cgroups_ro
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"cgroup filesystem is read-only"* ]]
+
+	update_config '.annotations += {"org.opencontainers.runc.cgroup.read-only": "skip-limits"}'
+	runc_ro_cgroupfs run -d --console-socket "$CONSOLE_SOCKET" test_cgroups_ro
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"running the container without cgroup limits"* ]]
+	testcontainer test_cgroups_ro running
+
+	runc update --pids-limit 20 test_cgroups_ro
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"no cgroup of its own"* ]]
+
+	# The processes are still found, from the init process.
+	runc ps test_cgroups_ro
+	[ "$status" -eq 0 ]
+	[ ${#lines[@]} -ge 2 ]
+
+	runc delete -f test_cgroups_ro
+	[ "$status" -eq 0 ]
+}
+
+@test "runc run (cpuset partition)" {
+	requires root cgroups_v2 smp cgroups_cpuset
+
+	set_cgroups_path
+
+	update_config '.annotations += {"org.opencontainers.runc.cpuset.partition": "leaf"}'
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_cgroups_partition
+	[ "$status" -ne 0 ]
+	[[ "$output" == *'invalid cpuset partition "leaf"'* ]]
+
+	# A partition root needs the CPUs for its exclusive use.
+	update_config '.annotations += {"org.opencontainers.runc.cpuset.partition": "isolated"}'
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_cgroups_partition
+	[ "$status" -ne 0 ]
+	[[ "$output" == *'requires cpuset CPUs'* ]]
+
+	update_config '.annotations += {"org.opencontainers.runc.cpuset.partition": "member"}'
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_cgroups_partition
+	[ "$status" -eq 0 ]
+	check_cgroup_value "cpuset.cpus.partition" "member"
+}
+
+@test "runc run (io latency)" {
+	requires root cgroups_v2 cgroups_io_latency
+
+	set_cgroups_path
+
+	update_config '.annotations += {"org.opencontainers.runc.io.latency": "8:0=10"}'
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_cgroups_io_latency
+	[ "$status" -ne 0 ]
+	[[ "$output" == *'invalid latency target "10"'* ]]
+
+	# Use a block device the host has IO stats for.
+	dev=$(awk '{ print $1; exit }' /sys/fs/cgroup/io.stat)
+	[ -n "$dev" ] || skip "requires a block device with IO stats"
+	update_config '.annotations += {"org.opencontainers.runc.io.latency": "'"$dev"'=10ms"}'
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_cgroups_io_latency
+	[ "$status" -eq 0 ]
+	[[ "$(get_cgroup_value "io.latency")" == *"$dev target=10000"* ]]
+}
+
+@test "runc run (templated cgroupsPath)" {
+	requires root
+
+	set_cgroups_path
+	local template="${OCI_CGROUPS_PATH}-%uid%"
+	if [ -v RUNC_USE_SYSTEMD ]; then
+		template="${OCI_CGROUPS_PATH%:*}:%id%"
+	fi
+	update_config '.linux.cgroupsPath |= "'"$template"'"'
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_cgroups_template
+	[ "$status" -eq 0 ]
+	runc exec test_cgroups_template cat /proc/self/cgroup
+	[ "$status" -eq 0 ]
+	if [ -v RUNC_USE_SYSTEMD ]; then
+		[[ "$output" == *"test_cgroups_template.scope"* ]]
+	else
+		[[ "$output" == *"${REL_CGROUPS_PATH}-0"* ]]
+	fi
+
+	# Without %id%, the cgroup is the same for both containers.
+	[ -v RUNC_USE_SYSTEMD ] && return
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_cgroups_template2
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"cgroup already exists"* ]]
+	# The cgroup of the first container is left alone.
+	runc exec test_cgroups_template true
+	[ "$status" -eq 0 ]
+
+	# Other percent signs are kept as is.
+	update_config '.linux.cgroupsPath |= "'"$template"'/50%"'
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_cgroups_template2
+	[ "$status" -eq 0 ]
+	runc exec test_cgroups_template2 cat /proc/self/cgroup
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"${REL_CGROUPS_PATH}-0/50%"* ]]
+}
+
+@test "runc run (unsupported resource limits warning)" {
+	requires root cgroups_v2
+
+	set_cgroups_path
+	# There is no OOM killer switch in cgroup v2.
+	update_config '.linux.resources.memory.disableOOMKiller = true'
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_cgroups_unsupported
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"resource limit can not be applied on this host"*"field=OomKillDisable"* ]]
+}
+
+@test "runc run (systemd fallback)" {
+	requires root no_systemd
+	[ -d /run/systemd/system ] && skip "requires systemd to be unavailable"
+
+	update_config '.linux.cgroupsPath = "system.slice:runc-test:fallback"'
+
+	# Without the fallback, the systemd cgroup driver can't be used.
+	runc --systemd-cgroup run -d --console-socket "$CONSOLE_SOCKET" test_cgroups_fallback
+	[ "$status" -ne 0 ]
+
+	update_config '.annotations += {"org.opencontainers.runc.systemd.fallback": "true"}'
+	runc --systemd-cgroup run -d --console-socket "$CONSOLE_SOCKET" test_cgroups_fallback
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"falling back to the fs cgroup manager"* ]]
+	jq -e '.config.cgroups.systemd_degraded' "$ROOT/state/test_cgroups_fallback/state.json"
+
+	# The cgroup is the one systemd would use.
+	runc exec test_cgroups_fallback cat /proc/self/cgroup
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"/system.slice/runc-test-fallback.scope"* ]]
+
+	# The container can't be registered without systemd, but can be updated.
+	runc --systemd-cgroup update --pids-limit 42 test_cgroups_fallback
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"unable to register the container with systemd"* ]]
+
+	runc --systemd-cgroup delete -f test_cgroups_fallback
+	[ "$status" -eq 0 ]
+}
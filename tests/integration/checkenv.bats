@@ -0,0 +1,23 @@
+#!/usr/bin/env bats
+
+load helpers
+
+@test "runc check-environment" {
+	runc check-environment --format json
+	# Findings may make runc exit with an error, but the output must be valid.
+	echo "${lines[0]}" | jq -e 'type == "array"'
+
+	runc check-environment --format table
+	[[ "${lines[0]}" == "SEVERITY"* ]]
+}
+
+@test "runc check-environment [world-writable root]" {
+	ROOT="$(mktemp -d "$BATS_RUN_TMPDIR/runc.XXXXXX")"
+	mkdir "$ROOT/state"
+	chmod 0777 "$ROOT/state"
+
+	runc check-environment --format json
+	[ "$status" -ne 0 ]
+	echo "${lines[0]}" | jq -e '.[] | select(.check == "state-root" and .severity == "error")'
+	rm -rf "$ROOT"
+}
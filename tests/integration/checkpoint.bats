@@ -155,6 +155,14 @@ function simple_cr() {
 	simple_cr
 }
 
+@test "runc features (checkpoint)" {
+	runc features
+	[ "$status" -eq 0 ]
+	jq -e '.annotations | has("org.opencontainers.runc.checkpoint.criu.version")' <<<"$output"
+	# As run by root, the features are checked.
+	jq -e '.annotations | has("org.opencontainers.runc.checkpoint.features")' <<<"$output"
+}
+
 @test "checkpoint --pre-dump (bad --parent-path)" {
 	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
 	[ "$status" -eq 0 ]
@@ -201,6 +209,118 @@ function simple_cr() {
 	check_pipes
 }
 
+@test "checkpoint --track-mem chain and restore" {
+	setup_pipes
+	runc_run_with_pipes test_busybox
+
+	mkdir pre-1 pre-2 image-dir work-dir
+	runc checkpoint --pre-dump --track-mem --image-path ./pre-1 test_busybox
+	[ "$status" -eq 0 ]
+	# The second pre-dump is on top of the first one.
+	runc checkpoint --pre-dump --track-mem --auto-dedup --image-path ./pre-2 test_busybox
+	[ "$status" -eq 0 ]
+	[ "$(readlink ./pre-2/parent)" = "../pre-1" ]
+
+	runc state test_busybox
+	[ "$status" -eq 0 ]
+	[ "$(echo "$output" | jq '.checkpointChain.dumps | length')" -eq 2 ]
+	[ "$(echo "$output" | jq -r '.checkpointChain.dumps[1].parent')" = "../pre-1" ]
+	[ "$(echo "$output" | jq '.checkpointChain.dumps[1].iteration')" -eq 2 ]
+
+	testcontainer test_busybox running
+
+	# The final dump is on top of the last pre-dump.
+	runc checkpoint --track-mem --work-path ./work-dir --image-path ./image-dir test_busybox
+	grep -B 5 Error ./work-dir/dump.log || true
+	[ "$status" -eq 0 ]
+	[ "$(readlink ./image-dir/parent)" = "../pre-2" ]
+
+	testcontainer test_busybox checkpointed
+
+	runc_restore_with_pipes ./work-dir test_busybox
+	check_pipes
+}
+
+@test "checkpoint --track-mem (broken chain)" {
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	runc checkpoint --pre-dump --track-mem --image-path ./pre-1 test_busybox
+	[ "$status" -eq 0 ]
+	rm -rf ./pre-1
+
+	runc checkpoint --pre-dump --track-mem --image-path ./pre-2 test_busybox
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"broken checkpoint chain"* ]]
+
+	# A pre-dump not on top of the last one starts a new chain.
+	runc checkpoint --pre-dump --image-path ./pre-0 test_busybox
+	[ "$status" -eq 0 ]
+	runc checkpoint --pre-dump --track-mem --image-path ./pre-2 test_busybox
+	[ "$status" -eq 0 ]
+	[ "$(readlink ./pre-2/parent)" = "../pre-0" ]
+}
+
+@test "checkpoint --sparse-images and restore" {
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	testcontainer test_busybox running
+
+	runc checkpoint --leave-running --work-path ./work-dir --image-path ./image-full test_busybox
+	grep -B 5 Error ./work-dir/dump.log || true
+	[ "$status" -eq 0 ]
+
+	runc checkpoint --sparse-images --work-path ./work-dir --image-path ./image-sparse test_busybox
+	grep -B 5 Error ./work-dir/dump.log || true
+	[ "$status" -eq 0 ]
+
+	testcontainer test_busybox checkpointed
+
+	# Same contents, but no more disk space used.
+	cmp <(cat image-full/pages-*.img) <(cat image-sparse/pages-*.img)
+	full=$(du -k -c image-full/pages-*.img | tail -1 | cut -f1)
+	sparse=$(du -k -c image-sparse/pages-*.img | tail -1 | cut -f1)
+	echo "pages images: $full KiB, $sparse KiB sparse"
+	[ "$sparse" -le "$full" ]
+
+	runc restore -d --work-path ./work-dir --image-path ./image-sparse --console-socket "$CONSOLE_SOCKET" test_busybox
+	grep -B 5 Error ./work-dir/restore.log || true
+	[ "$status" -eq 0 ]
+
+	testcontainer test_busybox running
+}
+
+@test "checkpoint --freeze-timeout and restore" {
+	requires cgroups_v2
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	# A busy multi-threaded container.
+	runc exec -d test_busybox sh -c 'for i in 1 2 3 4; do (while :; do :; done) & done; wait'
+	[ "$status" -eq 0 ]
+
+	runc checkpoint --freeze-timeout 5s --leave-running --work-path ./work-dir --image-path ./image-dir test_busybox
+	grep -B 5 Error ./work-dir/dump.log || true
+	[ "$status" -eq 0 ]
+
+	# The container is thawed after the checkpoint.
+	testcontainer test_busybox running
+
+	runc checkpoint --freeze-timeout 5s --work-path ./work-dir --image-path ./image-dir test_busybox
+	grep -B 5 Error ./work-dir/dump.log || true
+	[ "$status" -eq 0 ]
+
+	testcontainer test_busybox checkpointed
+
+	runc restore -d --work-path ./work-dir --image-path ./image-dir --console-socket "$CONSOLE_SOCKET" test_busybox
+	grep -B 5 Error ./work-dir/restore.log || true
+	[ "$status" -eq 0 ]
+
+	testcontainer test_busybox running
+}
+
 @test "checkpoint --lazy-pages and restore" {
 	# check if lazy-pages is supported
 	if ! criu check --feature uffd-noncoop; then
@@ -273,6 +393,152 @@ function simple_cr() {
 	check_pipes
 }
 
+@test "checkpoint --lazy-pages and restore --page-server" {
+	# check if lazy-pages is supported
+	if ! criu check --feature uffd-noncoop; then
+		skip "this criu does not support lazy migration"
+	fi
+
+	setup_pipes
+	runc_run_with_pipes test_busybox
+
+	mkdir image-dir
+	mkdir work-dir
+
+	exec {pipe}<> <(:)
+	# shellcheck disable=SC2094
+	exec {lazy_r}</proc/self/fd/$pipe {lazy_w}>/proc/self/fd/$pipe
+	exec {pipe}>&-
+
+	port=27278
+
+	__runc checkpoint \
+		--lazy-pages \
+		--page-server 0.0.0.0:${port} \
+		--status-fd ${lazy_w} \
+		--manage-cgroups-mode=ignore \
+		--work-path ./work-dir \
+		--image-path ./image-dir \
+		test_busybox &
+	cpt_pid=$!
+
+	# wait for lazy page server to be ready
+	out=$(timeout 2 dd if=/proc/self/fd/${lazy_r} bs=1 count=1 2>/dev/null | od)
+	exec {lazy_r}>&-
+	exec {lazy_w}>&-
+	# shellcheck disable=SC2116,SC2086
+	out=$(echo $out) # rm newlines
+	[ "$out" = "0000000 000000 0000001" ]
+
+	# runc starts the lazy pages daemon itself.
+	runc_restore_with_pipes ./image-dir test_busybox_restore \
+		--lazy-pages \
+		--page-server 127.0.0.1:${port} \
+		--manage-cgroups-mode=ignore
+
+	# The checkpointed container is gone once all the pages are transferred.
+	wait $cpt_pid
+
+	check_pipes
+}
+
+@test "restore --page-server without --lazy-pages" {
+	runc restore --page-server 127.0.0.1:27277 --image-path ./image-dir test_busybox
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"--page-server requires --lazy-pages"* ]]
+}
+
+@test "checkpoint --stream and restore --stream" {
+	if ! command -v criu-image-streamer >/dev/null; then
+		skip "requires criu-image-streamer"
+	fi
+
+	setup_pipes
+	runc_run_with_pipes test_busybox
+
+	mkdir image-dir work-dir
+	port=27279
+
+	# The restoring side listens for the images.
+	__runc restore -d --stream tcp://127.0.0.1:${port} --work-path ./work-dir \
+		--image-path ./image-dir test_busybox_restore <&${in_r} >&${out_w} 2>&${err_w} &
+	restore_pid=$!
+	sleep 1
+
+	runc checkpoint --stream tcp://127.0.0.1:${port} --work-path ./work-dir \
+		--image-path ./image-dir test_busybox
+	[ "$status" -eq 0 ]
+	wait $restore_pid
+
+	# No images are written.
+	[ ! -e image-dir/inventory.img ]
+
+	testcontainer test_busybox_restore running
+	check_pipes
+}
+
+@test "checkpoint --stream with invalid address" {
+	runc checkpoint --stream 127.0.0.1:27279 test_busybox
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"--stream tcp://HOST:PORT"* ]]
+}
+
+@test "checkpoint --tcp-close and restore --network-lock nftables" {
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	runc checkpoint --tcp-close --network-lock nftables --work-path ./work-dir --image-path ./image-dir test_busybox
+	if [[ "$output" == *"requires at least CRIU"* ]]; then
+		skip "requires criu >= 3.16"
+	fi
+	grep -B 5 Error ./work-dir/dump.log || true
+	[ "$status" -eq 0 ]
+
+	testcontainer test_busybox checkpointed
+
+	runc restore -d --tcp-close --network-lock nftables --work-path ./work-dir --image-path ./image-dir --console-socket "$CONSOLE_SOCKET" test_busybox
+	grep -B 5 Error ./work-dir/restore.log || true
+	[ "$status" -eq 0 ]
+
+	testcontainer test_busybox running
+}
+
+@test "checkpoint --leave-running --network-lock nftables" {
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	runc checkpoint --leave-running --network-lock nftables --work-path ./work-dir --image-path ./image-dir test_busybox
+	if [[ "$output" == *"requires at least CRIU"* ]]; then
+		skip "requires criu >= 3.16"
+	fi
+	grep -B 5 Error ./work-dir/dump.log || true
+	[ "$status" -eq 0 ]
+
+	testcontainer test_busybox running
+	# The network is unlocked once criu is done.
+	if command -v nft >/dev/null; then
+		pid=$(__runc state test_busybox | jq '.pid')
+		run ! nsenter -t "$pid" -n nft list table inet runc-criu-lock
+	fi
+}
+
+@test "checkpoint with invalid tcp options" {
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	runc checkpoint --tcp-close --tcp-established test_busybox
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"--tcp-close and --tcp-established can not be used together"* ]]
+
+	runc checkpoint --tcp-skip-in-flight test_busybox
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"--tcp-skip-in-flight requires --tcp-established"* ]]
+
+	runc checkpoint --network-lock ebtables test_busybox
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"Invalid network-lock value"* ]]
+}
+
 @test "checkpoint and restore in external network namespace" {
 	# check if external_net_ns is supported; only with criu 3.10++
 	if ! criu check --feature external_net_ns; then
@@ -457,3 +723,113 @@ function simple_cr() {
 	pid=$(cat "pid")
 	grep -q "${REL_CGROUPS_PATH}$" "/proc/$pid/cgroup"
 }
+
+@test "checkpoint and restore with --update-resources" {
+	set_resources_limit
+	set_cgroups_path
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+	testcontainer test_busybox running
+	check_cgroup_value "pids.max" 100
+
+	runc checkpoint --work-path ./work-dir test_busybox
+	grep -B 5 Error ./work-dir/dump.log || true
+	[ "$status" -eq 0 ]
+	testcontainer test_busybox checkpointed
+
+	echo '{"pids": {"limit": 50}}' >resources.json
+	runc restore -d --work-path ./work-dir --console-socket "$CONSOLE_SOCKET" \
+		--update-resources resources.json test_busybox
+	grep -B 5 Error ./work-dir/restore.log || true
+	[ "$status" -eq 0 ]
+	testcontainer test_busybox running
+	check_cgroup_value "pids.max" 50
+}
+
+@test "checkpoint and restore --from-snapshot twice" {
+	update_config '.hostname = "original"'
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+	testcontainer test_busybox running
+
+	# The clones are restored into their own cgroups.
+	runc checkpoint --image-path ./snapshot --work-path ./work-dir --manage-cgroups-mode ignore test_busybox
+	grep -B 5 Error ./work-dir/dump.log || true
+	[ "$status" -eq 0 ]
+	testcontainer test_busybox checkpointed
+
+	for i in 1 2; do
+		update_config '.hostname = "clone-'"$i"'"'
+		runc restore -d --from-snapshot ./snapshot --manage-cgroups-mode ignore \
+			--console-socket "$CONSOLE_SOCKET" "clone_$i"
+		[ "$status" -eq 0 ]
+		testcontainer "clone_$i" running
+
+		runc exec "clone_$i" hostname
+		[ "$status" -eq 0 ]
+		[ "$output" = "clone-$i" ]
+	done
+
+	# The logs are kept apart from the snapshot.
+	[ ! -e ./snapshot/restore.log ]
+}
+
+@test "restore --from-snapshot with --image-path" {
+	runc restore --from-snapshot ./snapshot --image-path ./image-dir test_busybox
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"--from-snapshot and --image-path can not be used together"* ]]
+}
+
+@test "checkpoint and restore with network-restore hooks" {
+	# shellcheck disable=SC2016
+	hook='[{"path": "/bin/sh", "args": ["sh", "-c", "readlink /proc/self/fd/3 > '"$(pwd)"'/annotation.netns"]}]'
+	update_config '.annotations += {
+		"org.opencontainers.runc.hooks.network-restore": ('"$hook"' | tojson)
+	}'
+	cat >net-hook.sh <<-EOF
+		#!/bin/sh
+		readlink /proc/self/fd/3 > $(pwd)/flag.netns
+	EOF
+	chmod +x net-hook.sh
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	runc checkpoint --work-path ./work-dir test_busybox
+	grep -B 5 Error ./work-dir/dump.log || true
+	[ "$status" -eq 0 ]
+
+	runc restore -d --work-path ./work-dir --console-socket "$CONSOLE_SOCKET" --network-restore-hook "$(pwd)/net-hook.sh" test_busybox
+	grep -B 5 Error ./work-dir/restore.log || true
+	[ "$status" -eq 0 ]
+	testcontainer test_busybox running
+
+	# The hooks got the network namespace of the restored container.
+	netns="$(readlink "/proc/$(__runc state test_busybox | jq .pid)/ns/net")"
+	[ "$(cat annotation.netns)" = "$netns" ]
+	[ "$(cat flag.netns)" = "$netns" ]
+}
+
+@test "restore --network-restore-hook (relative path)" {
+	runc restore --network-restore-hook ./net-hook.sh test_busybox
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"--network-restore-hook must be an absolute path"* ]]
+}
+
+@test "checkpoint and restore (exit code)" {
+	update_config '	  .process.terminal = false
+			| .process.args = ["sh", "-c", "while [ ! -e /exit ]; do sleep 0.1; done; exit 42"]'
+	runc run -d test_busybox </dev/null
+	[ "$status" -eq 0 ]
+
+	runc checkpoint --work-path ./work-dir test_busybox
+	grep -B 5 Error ./work-dir/dump.log || true
+	[ "$status" -eq 0 ]
+
+	# The restored process exits right away, and runc restore reports
+	# its exit code.
+	touch rootfs/exit
+	runc restore --work-path ./work-dir test_busybox </dev/null
+	grep -B 5 Error ./work-dir/restore.log || true
+	[ "$status" -eq 42 ]
+}
@@ -0,0 +1,78 @@
+#!/usr/bin/env bats
+
+load helpers
+
+function setup() {
+	setup_busybox
+}
+
+function teardown() {
+	for ct in test_excl1 test_excl2 test_excl3; do
+		__runc delete -f "$ct"
+	done
+	teardown_bundle
+}
+
+@test "runc cpu-pool" {
+	runc cpu-pool
+	[ "$status" -eq 0 ]
+	[ "$(jq -r .cpus <<<"$output")" = "" ]
+
+	runc cpu-pool 3,1-2
+	[ "$status" -eq 0 ]
+
+	runc cpu-pool
+	[ "$status" -eq 0 ]
+	[ "$(jq -r .cpus <<<"$output")" = "1-3" ]
+
+	runc cpu-pool 1-x
+	[ "$status" -ne 0 ]
+}
+
+@test "runc run [exclusive CPUs]" {
+	requires smp cgroups_cpuset
+	[ $EUID -ne 0 ] && requires rootless_cgroup
+
+	runc cpu-pool 0-1
+	[ "$status" -eq 0 ]
+
+	update_config '.annotations += {"org.opencontainers.runc.cpus.exclusive": "1"}'
+	for ct in test_excl1 test_excl2; do
+		runc run -d --console-socket "$CONSOLE_SOCKET" "$ct"
+		[ "$status" -eq 0 ]
+	done
+
+	runc state test_excl1
+	[ "$status" -eq 0 ]
+	cpus1=$(jq -r .exclusiveCpus <<<"$output")
+	runc state test_excl2
+	[ "$status" -eq 0 ]
+	cpus2=$(jq -r .exclusiveCpus <<<"$output")
+	[ "$cpus1" = "0" ] && [ "$cpus2" = "1" ]
+
+	runc exec test_excl1 grep Cpus_allowed_list /proc/self/status
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"	0" ]]
+
+	# The pool is exhausted.
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_excl3
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"unable to allocate 1 exclusive CPUs"* ]]
+
+	# The cpuset can't be changed.
+	runc update --cpuset-cpus 1 test_excl1
+	[ "$status" -ne 0 ]
+
+	# Nor can the allocated CPUs be removed from the pool.
+	runc cpu-pool 1
+	[ "$status" -ne 0 ]
+
+	# Once the container is deleted, its CPU can be reused.
+	runc delete -f test_excl1
+	[ "$status" -eq 0 ]
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_excl3
+	[ "$status" -eq 0 ]
+	runc state test_excl3
+	[ "$status" -eq 0 ]
+	[ "$(jq -r .exclusiveCpus <<<"$output")" = "0" ]
+}
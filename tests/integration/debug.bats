@@ -71,3 +71,57 @@ function check_debug() {
 	[[ "${output}" == *'"level":"debug"'* ]]
 	check_debug "$output"
 }
+
+@test "runc debug" {
+	# The startContainer hook runs after the container is created,
+	# so its failure can not be reported by runc start.
+	update_config '.hooks = {"startContainer": [{"path": "/bin/false"}]}'
+
+	runc create --console-socket "$CONSOLE_SOCKET" test_hello
+	[ "$status" -eq 0 ]
+
+	runc debug test_hello
+	[ "$status" -eq 0 ]
+	[ "${#lines[@]}" -eq 1 ] # Just the header.
+
+	runc start test_hello
+	[ "$status" -eq 0 ]
+	wait_for_container 10 1 test_hello stopped
+
+	runc debug test_hello
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"error"*"startContainer"* ]]
+
+	runc debug --format json test_hello
+	[ "$status" -eq 0 ]
+	[ "$(jq -r '.[0].level' <<<"$output")" = "error" ]
+
+	runc delete test_hello
+	[ "$status" -eq 0 ]
+}
+
+@test "runc debug --fds" {
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_fds
+	[ "$status" -eq 0 ]
+
+	runc debug --fds test_fds
+	[ "$status" -eq 0 ]
+	[ "${#lines[@]}" -eq 1 ] # Just the header.
+
+	# Hold the container's network namespace open, as leaked fds would.
+	pid=$(__runc state test_fds | jq '.pid')
+	sleep 100 3<"/proc/$pid/ns/net" &
+	holder=$!
+
+	runc debug --fds test_fds
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"$holder"*"sleep"*"namespace"*"net:["* ]]
+
+	runc debug --fds --format json test_fds
+	[ "$status" -eq 0 ]
+	[ "$(jq -r '.[0].pid' <<<"$output")" = "$holder" ]
+	[ "$(jq -r '.[0].fd' <<<"$output")" = "3" ]
+
+	kill "$holder"
+	wait "$holder" || true
+}
@@ -123,6 +123,24 @@ function test_runc_delete_host_pidns() {
 	[ "$status" -ne 0 ]
 }
 
+@test "runc delete --usage-file" {
+	[ $EUID -ne 0 ] && requires rootless_cgroup
+	set_cgroups_path
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+	testcontainer test_busybox running
+
+	runc delete --force --usage-file "$ROOT/usage.json" test_busybox
+	[ "$status" -eq 0 ]
+
+	runc state test_busybox
+	[ "$status" -ne 0 ]
+
+	[ "$(jq -r .id "$ROOT/usage.json")" = "test_busybox" ]
+	[ "$(jq -r .cpu_total "$ROOT/usage.json")" -gt 0 ]
+}
+
 @test "runc delete --force ignore not exist" {
 	runc delete --force notexists
 	[ "$status" -eq 0 ]
@@ -270,3 +288,34 @@ EOF
 	# Expect "no such unit" exit code.
 	run -4 systemctl status $user "$SD_UNIT_NAME"
 }
+
+@test "runc gc removes leaked systemd unit" {
+	requires systemd_v244 # Older systemd lacks RuntimeMaxSec support.
+
+	set_cgroups_path
+	update_config '	  .annotations += {
+				"org.systemd.property.RuntimeMaxSec": "2",
+				"org.systemd.property.TimeoutStopSec": "1"
+			   }
+			| .process.args |= ["/bin/sleep", "10"]'
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test-leaked-unit
+	[ "$status" -eq 0 ]
+
+	wait_for_container 10 1 test-leaked-unit stopped
+
+	# Leak the unit by removing the container state.
+	rm -rf "$ROOT/state/test-leaked-unit"
+
+	local user=""
+	[ $EUID -ne 0 ] && user="--user"
+
+	# Expect "unit is not active" exit code.
+	run -3 systemctl status $user "$SD_UNIT_NAME"
+
+	runc gc --scope-prefix runc-cgroups
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"$SD_UNIT_NAME"* ]]
+	# Expect "no such unit" exit code.
+	run -4 systemctl status $user "$SD_UNIT_NAME"
+}
@@ -141,3 +141,81 @@ function teardown() {
 	runc exec -t test_exec sh -c "ls -l /proc/self/fd/0; echo 123"
 	[ "$status" -eq 0 ]
 }
+
+@test "runc run [device filter pinned in bpffs]" {
+	requires root cgroups_v2
+
+	pin_dir=$(mktemp -d /sys/fs/bpf/runc-test.XXXXXX) || skip "no bpf filesystem"
+	update_config '.annotations += {"org.opencontainers.runc.devices.bpf-pin-dir": "'"$pin_dir"'"}'
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_pinned1
+	[ "$status" -eq 0 ]
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_pinned2
+	[ "$status" -eq 0 ]
+
+	# Both containers share the same program.
+	[ "$(find "$pin_dir" -maxdepth 1 -name 'runc-devices-*' ! -name '*.users' | wc -l)" -eq 1 ]
+
+	runc exec test_pinned1 head -c 1 /dev/zero
+	[ "$status" -eq 0 ]
+	runc exec test_pinned1 mknod /dev/kmsg2 c 1 11
+	[ "$status" -ne 0 ]
+
+	# Once no container uses it, the program is removed when another one
+	# is pinned.
+	runc delete -f test_pinned1
+	runc delete -f test_pinned2
+	update_config '.linux.resources.devices += [{"allow": true, "type": "c", "major": 1, "minor": 11, "access": "r"}]'
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_pinned3
+	[ "$status" -eq 0 ]
+	[ "$(find "$pin_dir" -maxdepth 1 -name 'runc-devices-*' ! -name '*.users' | wc -l)" -eq 1 ]
+
+	rm -rf "$pin_dir"
+}
+
+@test "runc update [device hot-add and remove]" {
+	requires root
+
+	update_config '.linux.resources.devices = [{"allow": false, "access": "rwm"}]'
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_hotplug
+	[ "$status" -eq 0 ]
+
+	runc update --device-add /dev/kmsg:/dev/kmsg2:w test_hotplug
+	[ "$status" -eq 0 ]
+	runc exec test_hotplug ls -l /dev/kmsg2
+	[ "$status" -eq 0 ]
+	[[ "${lines[0]}" == "c"* ]]
+	runc exec test_hotplug sh -c 'echo hotplug > /dev/kmsg2'
+	[ "$status" -eq 0 ]
+	# The access is limited to the given permissions.
+	runc exec test_hotplug sh -c 'head -c 1 /dev/kmsg2'
+	[ "$status" -ne 0 ]
+
+	runc update --device-rm /dev/kmsg2 test_hotplug
+	[ "$status" -eq 0 ]
+	runc exec test_hotplug test -e /dev/kmsg2
+	[ "$status" -ne 0 ]
+
+	runc update --device-rm /dev/kmsg2 test_hotplug
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"not found"* ]]
+}
+
+@test "runc run [device path glob]" {
+	requires root
+
+	update_config ' .linux.resources.devices = [{"allow": false, "access": "rwm"}]
+			| .linux.devices = [{"path": "/dev/kmsg*", "type": "c"}]
+			| .process.args |= ["sh", "-c", "ls -ln /dev/kmsg && echo glob > /dev/kmsg"]'
+
+	# The access is the one linux.resources.devices allows.
+	runc run test_glob
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"Operation not permitted"* ]]
+
+	update_config '.linux.resources.devices += [{"allow": true, "type": "c", "major": 1, "minor": 11, "access": "rw"}]'
+	runc run test_glob
+	[ "$status" -eq 0 ]
+	# The device numbers are the ones of the host.
+	[[ "${lines[0]}" =~ "crw-rw-rw".+"1,".+"11".+"/dev/kmsg" ]]
+}
@@ -0,0 +1,50 @@
+#!/usr/bin/env bats
+
+load helpers
+
+function setup() {
+	setup_busybox
+}
+
+function teardown() {
+	teardown_bundle
+}
+
+@test "runc --error-format json" {
+	runc --error-format json state nosuchcontainer
+	[ "$status" -ne 0 ]
+	[ "${#lines[@]}" -eq 1 ]
+	[ "$(jq -r .code <<<"$output")" = "container-not-found" ]
+	[ "$(jq -r .subsystem <<<"$output")" = "container" ]
+	[ "$(jq -r .message <<<"$output")" = "container does not exist" ]
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+	runc --error-format json run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -ne 0 ]
+	[ "$(jq -r .code <<<"${lines[-1]}")" = "container-exists" ]
+
+	runc --error-format json resume test_busybox
+	[ "$status" -ne 0 ]
+	[ "$(jq -r .code <<<"$output")" = "container-not-paused" ]
+
+	runc --error-format json start
+	[ "$status" -ne 0 ]
+	[ "$(jq -r .code <<<"${lines[-1]}")" = "invalid-argument" ]
+
+	runc --error-format json state --no-such-option test_busybox
+	[ "$status" -ne 0 ]
+	[ "$(jq -r .code <<<"${lines[-1]}")" = "invalid-argument" ]
+
+	runc --error-format json --no-such-option state test_busybox
+	[ "$status" -ne 0 ]
+	[ "$(jq -r .code <<<"${lines[-1]}")" = "invalid-argument" ]
+
+	runc --error-format json run -b /no/such/bundle test_busybox2
+	[ "$status" -ne 0 ]
+	[ "$(jq -r .code <<<"${lines[-1]}")" = "not-found" ]
+
+	runc --error-format yaml state test_busybox
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"invalid error-format: yaml"* ]]
+}
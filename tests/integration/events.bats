@@ -60,6 +60,23 @@ function test_events() {
 	[[ "${lines[0]}" == *"data"* ]]
 }
 
+@test "events --stats with network interfaces" {
+	requires root
+	init_cgroup_paths
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+	runc exec test_busybox ping -c 2 127.0.0.1
+	[ "$status" -eq 0 ]
+
+	runc events --stats test_busybox
+	[ "$status" -eq 0 ]
+	# The interfaces of the container network namespace are reported.
+	lo=$(jq -c '.data.network_interfaces[] | select(.Name == "lo")' <<<"${lines[0]}")
+	[ -n "$lo" ]
+	[ "$(jq .RxPackets <<<"$lo")" -ge 4 ]
+}
+
 @test "events --stats with psi data" {
 	requires root cgroups_v2 psi
 	init_cgroup_paths
@@ -88,6 +105,28 @@ function test_events() {
 	done
 }
 
+@test "events --stats with memory events" {
+	requires root cgroups_v2
+	init_cgroup_paths
+
+	# Set memory.high low enough to be hit.
+	update_config '.linux.resources.unified |= {"memory.high": "16777216"}'
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	# shellcheck disable=SC2016
+	runc exec test_busybox sh -c 'test=$(dd if=/dev/urandom bs=1M count=32)'
+	[ "$status" -eq 0 ]
+
+	runc events --stats test_busybox
+	[ "$status" -eq 0 ]
+
+	jq '.data.memory.events' <<<"${lines[0]}"
+	jq -e '.data.memory.events.high > 0' <<<"${lines[0]}"
+	jq -e '.data.memory.events.oom_kill == 0' <<<"${lines[0]}"
+}
+
 @test "events --interval default" {
 	test_events
 }
@@ -128,3 +167,52 @@ function test_events() {
 
 	grep -q '{"type":"oom","id":"test_busybox"}' events.log
 }
+
+@test "events --memory-pressure" {
+	requires root cgroups_v2 psi
+	init_cgroup_paths
+
+	# Set memory.high low enough for the container to be throttled.
+	update_config '.linux.resources.unified |= {"memory.high": "8388608"}'
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	runc events --memory-pressure bogus test_busybox
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"invalid memory pressure level"* ]]
+
+	(__runc events --memory-pressure low test_busybox >events.log) &
+	(
+		retry 10 1 grep -q test_busybox events.log
+		# shellcheck disable=SC2016
+		__runc exec -d test_busybox sh -c 'while :; do test=$(dd if=/dev/urandom bs=1M count=32); done'
+		retry 30 1 grep -q memory_pressure events.log
+		__runc delete -f test_busybox
+	) &
+	wait # wait for the above sub shells to finish
+
+	grep -q '{"type":"memory_pressure","id":"test_busybox","data":{"level":"low"}}' events.log
+}
+
+@test "events pids_limit_hit" {
+	# XXX: currently cgroups require root containers.
+	requires root
+	init_cgroup_paths
+
+	update_config '.linux.resources.pids.limit |= 10'
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	(__runc events test_busybox >events.log) &
+	(
+		retry 10 1 grep -q test_busybox events.log
+		__runc exec -d test_busybox sh -c 'for i in $(seq 20); do sleep 100 & done'
+		retry 30 1 grep -q pids_limit_hit events.log
+		__runc delete -f test_busybox
+	) &
+	wait # wait for the above sub shells to finish
+
+	grep pids_limit_hit events.log | jq -e '.data.count > 0 and .data.limit == 10'
+}
@@ -253,6 +253,67 @@ function check_exec_debug() {
 	[[ "$output" == *":cpu"*":$REL_CGROUPS_PATH/subcpu"* ]]
 }
 
+@test "runc exec --cgroup --memory --cpu-quota [v1]" {
+	requires root cgroups_v1
+
+	set_cgroups_path
+	set_cgroup_mount_writable
+
+	__runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	testcontainer test_busybox running
+
+	# The limits need a sub-cgroup.
+	runc exec --memory 64M test_busybox true
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"require --cgroup"* ]]
+
+	# The sub-cgroup is created, with its own limits.
+	runc exec --cgroup debug --memory 64M --cpu-quota 20000 test_busybox \
+		cat /proc/self/cgroup /sys/fs/cgroup/memory/debug/memory.limit_in_bytes /sys/fs/cgroup/cpu/debug/cpu.cfs_quota_us
+	[ "$status" -eq 0 ]
+	[[ "$output" == *":memory:$REL_CGROUPS_PATH/debug"* ]]
+	[[ "${lines[-2]}" == "67108864" ]]
+	[[ "${lines[-1]}" == "20000" ]]
+
+	# The limits of the container are left as is.
+	runc exec test_busybox cat /sys/fs/cgroup/cpu/cpu.cfs_quota_us
+	[ "$status" -eq 0 ]
+	[[ "$output" == "-1" ]]
+
+	# The sub-cgroup is removed once the process exits.
+	runc exec test_busybox test -e /sys/fs/cgroup/memory/debug
+	[ "$status" -ne 0 ]
+
+	# An existing sub-cgroup with different limits is not reused.
+	runc exec test_busybox mkdir /sys/fs/cgroup/memory/other
+	[ "$status" -eq 0 ]
+	runc exec --cgroup memory:other --memory 64M test_busybox true
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"already exists with a different memory.limit_in_bytes"* ]]
+
+	# The sub-cgroup of a detached process is removed with the container.
+	runc exec -d --cgroup detached --memory 64M test_busybox sleep 1d
+	[ "$status" -eq 0 ]
+	[ -d "/sys/fs/cgroup/memory$REL_CGROUPS_PATH/detached" ]
+	runc delete --force test_busybox
+	[ "$status" -eq 0 ]
+	[ ! -e "/sys/fs/cgroup/memory$REL_CGROUPS_PATH/detached" ]
+}
+
+@test "runc exec --cgroup --memory [v2]" {
+	requires root cgroups_v2
+
+	set_cgroups_path
+	set_cgroup_mount_writable
+
+	__runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	testcontainer test_busybox running
+
+	runc exec --cgroup debug --memory 64M test_busybox true
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"not supported on cgroup v2"* ]]
+}
+
 @test "runc exec --cgroup subcgroup [v2]" {
 	requires root cgroups_v2
 
@@ -322,3 +383,50 @@ function check_exec_debug() {
 	runc exec --cgroup second test_busybox grep -w second /proc/self/cgroup
 	[ "$status" -eq 0 ]
 }
+
+@test "runc exec (rate limit)" {
+	update_config '.annotations += {"org.opencontainers.runc.exec.rate": "0.01", "org.opencontainers.runc.exec.burst": "2"}'
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	runc exec test_busybox true
+	[ "$status" -eq 0 ]
+	runc exec test_busybox true
+	[ "$status" -eq 0 ]
+
+	runc exec test_busybox true
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"too many processes started in the container, retry after"* ]]
+}
+
+@test "runc exec --join" {
+	update_config '.annotations += {"org.opencontainers.runc.exec.rate": "0.01", "org.opencontainers.runc.exec.burst": "1"}
+		| .process.env += ["FOO=bar"]
+		| .process.cwd = "/tmp"
+		| .process.user = {"uid": 1000, "gid": 1000}'
+	runc create --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	# The user, environment and working directory are the ones of config.json.
+	runc exec --join test_busybox sh -c 'echo $FOO; pwd; id -u'
+	[ "$status" -eq 0 ]
+	[[ "${lines[0]}" == "bar" ]]
+	[[ "${lines[1]}" == "/tmp" ]]
+	[[ "${lines[2]}" == "1000" ]]
+
+	# The process start rate limit does not apply.
+	runc exec test_busybox true
+	[ "$status" -eq 0 ]
+	runc exec --join test_busybox true
+	[ "$status" -eq 0 ]
+
+	runc exec --join --cgroup / test_busybox true
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"--cgroup can not be used with --join"* ]]
+
+	runc pause test_busybox
+	[ "$status" -eq 0 ]
+	runc exec --join test_busybox true
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"cannot exec in a paused container"* ]]
+}
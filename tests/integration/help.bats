@@ -38,6 +38,10 @@ function setup() {
 	[ "$status" -eq 0 ]
 	[[ ${lines[1]} =~ runc\ exec+ ]]
 
+	runc gc -h
+	[ "$status" -eq 0 ]
+	[[ ${lines[1]} =~ runc\ gc+ ]]
+
 	runc kill -h
 	[ "$status" -eq 0 ]
 	[[ ${lines[1]} =~ runc\ kill+ ]]
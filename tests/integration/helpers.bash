@@ -19,6 +19,8 @@ SECCOMP_AGENT="${INTEGRATION_ROOT}/../../contrib/cmd/seccompagent/seccompagent"
 FS_IDMAP="${INTEGRATION_ROOT}/../../contrib/cmd/fs-idmap/fs-idmap"
 PIDFD_KILL="${INTEGRATION_ROOT}/../../contrib/cmd/pidfd-kill/pidfd-kill"
 REMAP_ROOTFS="${INTEGRATION_ROOT}/../../contrib/cmd/remap-rootfs/remap-rootfs"
+MOUNT_DRIVER="${INTEGRATION_ROOT}/../../contrib/cmd/mount-driver/mount-driver"
+SEND_FDS="${INTEGRATION_ROOT}/../../contrib/cmd/send-fds/send-fds"
 
 # Some variables may not always be set. Set those to empty value,
 # if unset, to avoid "unbound variable" error.
@@ -252,6 +254,20 @@ function get_cgroup_path() {
 	echo "$cgroup"
 }
 
+# Convert size in KB to hugetlb size suffix.
+function convert_hugetlb_size() {
+	local size=$1
+	local units=("KB" "MB" "GB")
+	local idx=0
+
+	while ((size >= 1024)); do
+		((size /= 1024))
+		((idx++))
+	done
+
+	echo "$size${units[$idx]}"
+}
+
 # Get a value from a cgroup file.
 function get_cgroup_value() {
 	local cgroup
@@ -385,6 +401,11 @@ function requires() {
 				skip_me=1
 			fi
 			;;
+		nft)
+			if ! command -v nft >/dev/null; then
+				skip_me=1
+			fi
+			;;
 		root)
 			if [ $EUID -ne 0 ]; then
 				skip_me=1
@@ -450,6 +471,12 @@ function requires() {
 				skip_me=1
 			fi
 			;;
+		cgroups_io_latency)
+			init_cgroup_paths
+			if [ ! -v CGROUP_V2 ] || [ -z "$(find "$CGROUP_BASE_PATH" -name io.latency -print -quit)" ]; then
+				skip_me=1
+			fi
+			;;
 		cgroupns)
 			if [ ! -e "/proc/self/ns/cgroup" ]; then
 				skip_me=1
@@ -42,3 +42,25 @@ function teardown() {
 		[[ "$output" == *"error running $hook hook #1:"* ]]
 	done
 }
+
+@test "runc run [hook fails, nothing is leaked]" {
+	requires root
+	set_cgroups_path
+
+	update_config '.process.args = ["/bin/echo", "Hello World"]'
+	for hook in createRuntime startContainer poststart; do
+		echo "testing hook $hook"
+		# shellcheck disable=SC2016
+		update_config '.hooks |= {"'$hook'": [{"path": "/bin/false"}]}'
+		runc run test_hooks
+		[ "$status" -ne 0 ]
+		[[ "$output" == *"error running $hook hook #0:"* ]]
+
+		[ ! -d "$(get_cgroup_path pids)" ]
+		if [ -v RUNC_USE_SYSTEMD ]; then
+			# Exit code 4 means "no such unit".
+			run -4 systemctl status "$SD_UNIT_NAME"
+		fi
+		[ ! -d "$ROOT/state/test_hooks" ]
+	done
+}
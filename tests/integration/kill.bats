@@ -124,3 +124,37 @@ test_host_pidns_kill() {
 	test_host_pidns_kill
 	unset KILL_INIT
 }
+
+@test "kill --thaw [paused container]" {
+	requires cgroups_freezer
+	if [ $EUID -ne 0 ]; then
+		requires rootless_cgroup
+		set_cgroups_path
+	fi
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	runc pause test_busybox
+	[ "$status" -eq 0 ]
+
+	# With "fail" policy, the signal is not sent.
+	runc kill --thaw fail test_busybox 0
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"container paused"* ]]
+	testcontainer test_busybox paused
+
+	# With "refreeze" policy, the container stays paused.
+	runc kill --thaw refreeze test_busybox 0
+	[ "$status" -eq 0 ]
+	testcontainer test_busybox paused
+
+	# With "thaw" policy, the container is resumed.
+	runc kill --thaw thaw test_busybox 0
+	[ "$status" -eq 0 ]
+	testcontainer test_busybox running
+
+	runc kill --thaw invalid test_busybox 0
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"invalid paused signal policy"* ]]
+}
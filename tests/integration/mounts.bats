@@ -145,6 +145,119 @@ function test_mount_order() {
 	[[ "${lines[0]}" == *'drwxrwxrwx'* ]]
 }
 
+@test "runc run [tmpfs with invalid options]" {
+	update_config '	  .mounts += [{
+					source: "tmpfs",
+					destination: "/dir1",
+					type: "tmpfs",
+					options: ["size=lots"]
+				}]'
+
+	runc run test_busybox
+	[ "$status" -ne 0 ]
+	[[ "$output" == *'invalid tmpfs size'* ]]
+}
+
+@test "runc run [overlay managed=true]" {
+	requires root
+	mkdir -p lower
+	echo lower >lower/file
+	update_config '	  .mounts += [{
+					source: "overlay",
+					destination: "/data",
+					type: "overlay",
+					options: ["lowerdir='"$PWD"'/lower", "managed=true"]
+				}]
+			| .process.args |= ["sh", "-c", "echo upper >/data/file && sleep 1d"]'
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+	testcontainer test_busybox running
+
+	runc exec test_busybox cat /data/file
+	[ "$status" -eq 0 ]
+	[ "$output" = "upper" ]
+	# The lower directory is left untouched.
+	[ "$(cat lower/file)" = "lower" ]
+
+	local dir="$ROOT/state/test_busybox/overlay"
+	[ -d "$dir" ]
+
+	runc delete --force test_busybox
+	[ "$status" -eq 0 ]
+	[ ! -e "$dir" ]
+
+	# Not supported with user namespaces.
+	update_config '	  .linux.namespaces += [{"type": "user"}]
+			| .linux.uidMappings += [{"hostID": 100000, "containerID": 0, "size": 65534}]
+			| .linux.gidMappings += [{"hostID": 200000, "containerID": 0, "size": 65534}]'
+	runc run test_busybox
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"not supported with user namespaces"* ]]
+}
+
+@test "runc run [mount driver]" {
+	requires root
+	update_config '	  .annotations += {"org.opencontainers.runc.mount-driver.tmpfs": "'"$MOUNT_DRIVER"'"}
+			| .mounts += [{
+					source: "driven",
+					destination: "/data",
+					type: "tmpfs",
+					options: ["size=1m", "nosuid", "ro"]
+				}]
+			| .process.args |= ["grep", " /data ", "/proc/self/mountinfo"]'
+
+	runc run test_busybox
+	[ "$status" -eq 0 ]
+	[[ "$output" == *" ro,nosuid"*" tmpfs driven "* ]]
+}
+
+@test "runc run [mount driver failure]" {
+	requires root
+	update_config '	  .annotations += {"org.opencontainers.runc.mount-driver.nosuchfs": "'"$MOUNT_DRIVER"'"}
+			| .mounts += [{
+					source: "driven",
+					destination: "/data",
+					type: "nosuchfs"
+				}]'
+
+	runc run test_busybox
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"mount driver"*"no such device"* ]]
+}
+
+@test "runc run [bind mount of a passed fd]" {
+	requires root
+	mkdir -p passed
+	echo hello >passed/file
+	update_config '	  .mounts += [{
+					source: "fd://0",
+					destination: "/data",
+					options: ["bind", "ro"]
+				}]
+			| .process.args |= ["sh", "-c", "cat /data/file && ! touch /data/file"]'
+
+	"$SEND_FDS" mount.sock "$PWD/passed" &
+	retry 10 0.1 [ -S mount.sock ]
+
+	runc run --mount-socket mount.sock test_busybox
+	[ "$status" -eq 0 ]
+	[ "$output" = "hello" ]
+	wait
+}
+
+@test "runc run [bind mount of a missing fd]" {
+	update_config '	  .mounts += [{
+					source: "fd://0",
+					destination: "/data",
+					options: ["bind"]
+				}]'
+
+	runc run test_busybox
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"fd://0: only 0 files passed"* ]]
+}
+
 @test "runc run [bind mount]" {
 	update_config '	  .mounts += [{
 					source: ".",
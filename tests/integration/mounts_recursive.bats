@@ -76,3 +76,19 @@ function teardown() {
 	[ "$status" -eq 1 ]
 	[[ "${output}" == *"Read-only file system"* ]]
 }
+
+@test "runc run [tmpfs,rro,rnoexec mount]" {
+	requires_kernel 5.12
+	update_config '.mounts += [{source: "tmpfs", type: "tmpfs", destination: "/mnt", options: ["rro","rnoexec"]}]'
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_tmpfs_rro
+	[ "$status" -eq 0 ]
+
+	runc exec test_tmpfs_rro touch /mnt/foo
+	[ "$status" -eq 1 ]
+	[[ "${output}" == *"Read-only file system"* ]]
+
+	runc exec test_tmpfs_rro grep -w /mnt /proc/self/mountinfo
+	[ "$status" -eq 0 ]
+	[[ "${output}" == *" ro,noexec"* ]]
+}
@@ -0,0 +1,42 @@
+#!/usr/bin/env bats
+
+load helpers
+
+function setup() {
+	requires root
+	setup_busybox
+	ip link add runc-br0 type bridge
+	ip addr add 10.200.0.1/24 dev runc-br0
+	ip link set runc-br0 up
+}
+
+function teardown() {
+	teardown_bundle
+	ip link del runc-br0 || true
+}
+
+@test "runc run [veth network]" {
+	update_config ' .annotations += {"org.opencontainers.runc.networks": "[{\"type\": \"veth\", \"name\": \"eth0\", \"bridge\": \"runc-br0\", \"host_interface_name\": \"runc-veth0\", \"address\": \"10.200.0.2/24\", \"gateway\": \"10.200.0.1\"}]"}
+			| .process.args |= ["sh", "-c", "ip addr show eth0 && ip route && ping -c 1 -W 5 10.200.0.1"]'
+
+	runc run test_veth
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"inet 10.200.0.2/24"* ]]
+	[[ "$output" == *"default via 10.200.0.1 dev eth0"* ]]
+
+	# The host end is gone with the network namespace.
+	! ip link show runc-veth0
+}
+
+@test "runc run [macvlan network]" {
+	ip link add runc-dummy0 type dummy
+	ip link set runc-dummy0 up
+
+	update_config ' .annotations += {"org.opencontainers.runc.networks": "[{\"type\": \"macvlan\", \"name\": \"eth1\", \"parent\": \"runc-dummy0\", \"address\": \"10.201.0.2/24\"}]"}
+			| .process.args |= ["ip", "addr", "show", "eth1"]'
+
+	runc run test_macvlan
+	ip link del runc-dummy0
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"inet 10.201.0.2/24"* ]]
+}
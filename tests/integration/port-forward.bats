@@ -0,0 +1,64 @@
+#!/usr/bin/env bats
+
+load helpers
+
+function setup() {
+	setup_busybox
+}
+
+function teardown() {
+	teardown_bundle
+}
+
+@test "runc run [port forward]" {
+	update_config ' .annotations += {"org.opencontainers.runc.port-forwards": "[{\"host_ip\": \"127.0.0.1\", \"host_port\": 18000, \"container_port\": 8000}]"}
+			| .process.args |= ["sh", "-c", "while true; do echo hello | nc -l -p 8000; done"]'
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_forward
+	[ "$status" -eq 0 ]
+
+	# The forwarder connects to 127.0.0.1 in the container, retry until
+	# the container listens.
+	for _ in $(seq 50); do
+		exec 3<>/dev/tcp/127.0.0.1/18000 || break
+		output=$(cat <&3)
+		exec 3>&-
+		[ "$output" = "hello" ] && break
+		sleep 0.1
+	done
+	[ "$output" = "hello" ]
+
+	# The forwarder exits with the container, releasing the port.
+	runc kill test_forward KILL
+	[ "$status" -eq 0 ]
+	wait_for_container 10 1 test_forward stopped
+	retry 10 0.5 bash -c '! exec 3<>/dev/tcp/127.0.0.1/18000'
+}
+
+@test "runc run [port forward, port in use]" {
+	update_config ' .annotations += {"org.opencontainers.runc.port-forwards": "[{\"host_ip\": \"127.0.0.1\", \"host_port\": 18001, \"container_port\": 8000}]"}'
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_forward1
+	[ "$status" -eq 0 ]
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_forward2
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"unable to listen on port tcp/18001"* ]]
+}
+
+@test "runc delete [port forward]" {
+	update_config ' .annotations += {"org.opencontainers.runc.port-forwards": "[{\"host_ip\": \"127.0.0.1\", \"host_port\": 18002, \"container_port\": 8000}]"}'
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_forward
+	[ "$status" -eq 0 ]
+
+	# The forwarder is recorded in the state, and stopped by delete.
+	local pid
+	pid=$(jq .port_forwarder_pid "$ROOT/state/test_forward/state.json")
+	[ "$pid" -gt 0 ]
+	kill -0 "$pid"
+
+	runc delete --force test_forward
+	[ "$status" -eq 0 ]
+	retry 10 0.5 bash -c "! kill -0 $pid"
+}
@@ -40,6 +40,32 @@ function teardown() {
 	[[ "$output" =~ [0-9]+ ]]
 }
 
+@test "ps --tree" {
+	runc exec -d test_busybox sh -c 'sleep 100 & sleep 100 & wait'
+	[ "$status" -eq 0 ]
+	# Wait for the sleeps to start.
+	function sleeps_started() {
+		[ "$(__runc exec test_busybox ps | grep -c 'sleep 100')" -eq 2 ]
+	}
+	retry 10 0.5 sleeps_started
+
+	runc ps --tree test_busybox
+	[ "$status" -eq 0 ]
+	[[ "${lines[0]}" =~ ^PID\ +PPID\ +CGROUP\ +COMMAND$ ]]
+	# The container's init.
+	[[ "${lines[1]}" =~ ^[0-9]+\ +[0-9]+\ +/\ +sh$ ]]
+	# The exec'd shell, and its children.
+	[[ "$output" =~ /\ +sh\ -c\ sleep\ 100\ \&\ sleep\ 100\ \&\ wait ]]
+	[[ "$output" =~ /\ +\\_\ sleep\ 100 ]]
+
+	runc ps --tree -f json test_busybox
+	[ "$status" -eq 0 ]
+	[ "$(jq '[.[].children // [] | length] | add' <<<"$output")" -eq 2 ]
+
+	runc ps --tree test_busybox -ef
+	[ "$status" -ne 0 ]
+}
+
 @test "ps after the container stopped" {
 	runc ps test_busybox
 	[ "$status" -eq 0 ]
@@ -0,0 +1,65 @@
+#!/usr/bin/env bats
+
+load helpers
+
+function setup() {
+	setup_busybox
+}
+
+function teardown() {
+	teardown_bundle
+}
+
+@test "runc run [rootless network slirp4netns]" {
+	command -v slirp4netns || skip "requires slirp4netns"
+
+	update_config ' .annotations += {"org.opencontainers.runc.rootless-network": "{\"mode\": \"slirp4netns\"}"}
+			| .process.args |= ["sleep", "infinity"]'
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_slirp
+	[ "$status" -eq 0 ]
+
+	runc exec test_slirp ip addr show tap0
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"inet 10.0.2.100/24"* ]]
+	runc exec test_slirp ip route
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"default via 10.0.2.2 dev tap0"* ]]
+
+	pid=$(jq -r .rootless_network_pid "$ROOT/state/test_slirp/state.json")
+	[ -d "/proc/$pid" ]
+
+	# slirp4netns is stopped with the container deletion.
+	runc delete --force test_slirp
+	[ "$status" -eq 0 ]
+	retry 10 0.5 test ! -d "/proc/$pid"
+}
+
+@test "runc run [rootless network pasta]" {
+	command -v pasta || skip "requires pasta"
+
+	update_config ' .annotations += {"org.opencontainers.runc.rootless-network": "{\"mode\": \"pasta\"}"}
+			| .process.args |= ["sleep", "infinity"]'
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_pasta
+	[ "$status" -eq 0 ]
+
+	runc exec test_pasta ip route
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"default via"* ]]
+
+	pid=$(jq -r .rootless_network_pid "$ROOT/state/test_pasta/state.json")
+	[ -d "/proc/$pid" ]
+
+	runc delete --force test_pasta
+	[ "$status" -eq 0 ]
+	retry 10 0.5 test ! -d "/proc/$pid"
+}
+
+@test "runc run [rootless network, unknown binary]" {
+	update_config ' .annotations += {"org.opencontainers.runc.rootless-network": "{\"mode\": \"slirp4netns\", \"path\": \"/nonexistent/slirp4netns\"}"}'
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_slirp
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"rootless network slirp4netns"* ]]
+}
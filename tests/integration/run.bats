@@ -77,6 +77,85 @@ function teardown() {
 	[[ "${lines[0]}" == *'mydomainname'* ]]
 }
 
+@test "runc run [domainname validation]" {
+	update_config ' .domainname = "'"$(printf 'a%.0s' {1..65})"'"'
+	runc run test_utc
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"invalid domainname"* ]]
+
+	update_config ' .domainname = "mydomainname"
+			| .linux.sysctl["kernel.domainname"] = "otherdomainname"'
+	runc run test_utc
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"conflicts with the OCI"* ]]
+}
+
+@test "runc run [net sysctl annotations]" {
+	update_config ' .process.args |= ["sh", "-c", "cat /proc/sys/net/ipv4/ping_group_range /proc/sys/net/ipv4/ip_unprivileged_port_start"]
+			| .annotations += {
+				"org.opencontainers.runc.net.ping-group-range": "0-0",
+				"org.opencontainers.runc.net.unprivileged-port-start": "80"
+			}'
+
+	runc run test_net_sysctl
+	[ "$status" -eq 0 ]
+	[[ "${lines[0]}" =~ ^0[[:space:]]+0$ ]]
+	[ "${lines[1]}" = "80" ]
+
+	# A conflicting raw sysctl is rejected.
+	update_config '.linux.sysctl += {"net.ipv4.ip_unprivileged_port_start": "1024"}'
+	runc run test_net_sysctl
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"conflicts with the typed network sysctl"* ]]
+}
+
+@test "runc run [rejected sysctls are all listed]" {
+	update_config ' .linux.namespaces -= [{"type": "network"}]
+			| .linux.sysctl += {"net.ipv4.ip_forward": "1", "net.core.somaxconn": "1024", "kernel.ctl": "1"}'
+
+	runc run test_sysctl
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"3 sysctls rejected"* ]]
+	[[ "$output" == *'"net.core.somaxconn": not allowed in the host'"'"'s network namespace'* ]]
+	[[ "$output" == *'"net.ipv4.ip_forward": not allowed in the host'"'"'s network namespace'* ]]
+	[[ "$output" == *'"kernel.ctl": not in a separate kernel namespace'* ]]
+}
+
+@test "runc run [firewall annotation]" {
+	requires root nft
+
+	update_config '.annotations += {"org.opencontainers.runc.net.firewall": "tcp/80, 10.0.0.0/8 udp/53"}'
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_firewall
+	[ "$status" -eq 0 ]
+
+	local pid
+	pid=$(__runc state test_firewall | jq .pid)
+	run nsenter -t "$pid" -n nft list table inet runc-test_firewall
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"policy drop"* ]]
+	[[ "$output" == *"tcp dport 80 accept"* ]]
+	[[ "$output" == *"ip saddr 10.0.0.0/8 udp dport 53 accept"* ]]
+
+	# A container joining the network namespace has a table of its own,
+	# which is removed along with it, leaving the other one alone.
+	update_config '.linux.namespaces |= map(if .type == "network" then .path = "/proc/'"$pid"'/ns/net" else . end)'
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_firewall_join
+	[ "$status" -eq 0 ]
+	run nsenter -t "$pid" -n nft list table inet runc-test_firewall_join
+	[ "$status" -eq 0 ]
+	runc delete -f test_firewall_join
+	[ "$status" -eq 0 ]
+	run ! nsenter -t "$pid" -n nft list table inet runc-test_firewall_join
+	run nsenter -t "$pid" -n nft list table inet runc-test_firewall
+	[ "$status" -eq 0 ]
+
+	# The firewall requires a network namespace.
+	update_config '.linux.namespaces -= [{"type": "network"}]'
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_firewall_host
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"firewall requires a network namespace"* ]]
+}
+
 # https://github.com/szcdx/runc/issues/3952
 @test "runc run with tmpfs" {
 	requires root
@@ -0,0 +1,57 @@
+#!/usr/bin/env bats
+
+load helpers
+
+function setup() {
+	setup_busybox
+}
+
+function teardown() {
+	teardown_bundle
+}
+
+@test "runc run (runtime config defaults)" {
+	cat >runtime-config.json <<-EOF
+		{
+			"maskedPaths": ["/proc/version"]
+		}
+	EOF
+	update_config '.process.args = ["cat", "/proc/version"] | del(.linux.maskedPaths)'
+
+	runc --config "$(pwd)/runtime-config.json" run test_config
+	[ "$status" -eq 0 ]
+	[ -z "$output" ]
+
+	# The spec takes precedence, even if empty.
+	update_config '.linux.maskedPaths = []'
+	runc --config "$(pwd)/runtime-config.json" run test_config
+	[ "$status" -eq 0 ]
+	[[ "$output" == "Linux version"* ]]
+}
+
+@test "runc --config (invalid)" {
+	echo '{"unknown": true}' >runtime-config.json
+	runc --config "$(pwd)/runtime-config.json" list
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"unable to parse runtime config"* ]]
+
+	# The file is not used with --no-config.
+	runc --no-config list
+	[ "$status" -eq 0 ]
+
+	# An explicitly given file must exist.
+	runc --config "$(pwd)/nonexistent.json" list
+	[ "$status" -ne 0 ]
+}
+
+@test "runc --config (global options)" {
+	echo '{"logFormat": "json"}' >runtime-config.json
+	runc --config "$(pwd)/runtime-config.json" --debug list
+	[ "$status" -eq 0 ]
+	[[ "$output" == *'"level":"debug"'* ]]
+
+	# The command line takes precedence.
+	runc --config "$(pwd)/runtime-config.json" --log-format text --debug list
+	[ "$status" -eq 0 ]
+	[[ "$output" != *'"level":"debug"'* ]]
+}
@@ -98,6 +98,18 @@ function scmp_act_notify_template() {
 	[ "$status" -eq 0 ]
 }
 
+@test "runc run [seccomp] (SCMP_ACT_NOTIFY with SECCOMP_FILTER_FLAG_WAIT_KILLABLE_RECV)" {
+	requires_kernel 5.19
+
+	scmp_act_notify_template "mkdir /dev/shm/foo && stat /dev/shm/foo-bar" false '"mkdir"'
+	update_config '.linux.seccomp.flags = [ "SECCOMP_FILTER_FLAG_WAIT_KILLABLE_RECV" ]'
+
+	runc --debug run test_busybox
+	[ "$status" -eq 0 ]
+	# SECCOMP_FILTER_FLAG_NEW_LISTENER (8) | SECCOMP_FILTER_FLAG_WAIT_KILLABLE_RECV (32).
+	[[ "$output" == *"seccomp filter flags: 40"* ]]
+}
+
 # Ignore listenerPath if the profile doesn't use seccomp notify actions.
 @test "runc run [seccomp] (ignore listener path if no notify act)" {
 	update_config '   .process.args = ["/bin/sh", "-c", "mkdir /dev/shm/foo && stat /dev/shm/foo"]
@@ -224,3 +236,50 @@ function scmp_act_notify_template() {
 	[ "$status" -eq 0 ]
 	[[ "$output" == *"chmod:"*"test-file"*"No medium found"* ]]
 }
+
+@test "runc seccomp resend" {
+	scmp_act_notify_template "sleep 1d" false '"mkdir"'
+	update_config '.annotations += {"org.opencontainers.runc.seccomp.keep-listener-fd": "true"}'
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	runc exec test_busybox sh -c "mkdir /dev/shm/foo && stat /dev/shm/foo-bar"
+	[ "$status" -eq 0 ]
+
+	# Replace the agent, and hand the notify fd over to the new one.
+	teardown_seccompagent
+	setup_seccompagent
+	retry 10 0.5 test -S "$SECCCOMP_AGENT_SOCKET"
+
+	runc seccomp resend test_busybox
+	[ "$status" -eq 0 ]
+
+	runc exec test_busybox sh -c "mkdir /dev/shm/baz && stat /dev/shm/baz-bar"
+	[ "$status" -eq 0 ]
+}
+
+@test "runc seccomp resend (fd not kept)" {
+	scmp_act_notify_template "sleep 1d" false '"mkdir"'
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	runc seccomp resend test_busybox
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"seccomp notify fd of the container was not kept"* ]]
+}
+
+@test "runc checkpoint [seccomp] (SCMP_ACT_NOTIFY is refused)" {
+	requires criu root
+
+	scmp_act_notify_template "sleep 1d" false '"mkdir"'
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	runc checkpoint --work-path ./work-dir test_busybox
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"unable to checkpoint a container with SCMP_ACT_NOTIFY rules (for mkdir)"* ]]
+	testcontainer test_busybox running
+}
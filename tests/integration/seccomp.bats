@@ -34,6 +34,41 @@ function teardown() {
 	[ "$status" -eq 0 ]
 }
 
+@test "runc exec --seccomp" {
+	update_config '   .process.args = ["/bin/sleep", "1h"]
+			| .process.noNewPrivileges = false
+			| .linux.seccomp = {
+				"defaultAction":"SCMP_ACT_ALLOW",
+				"architectures":["SCMP_ARCH_X86","SCMP_ARCH_X32","SCMP_ARCH_X86_64","SCMP_ARCH_AARCH64","SCMP_ARCH_ARM"],
+				"syscalls":[{"names":["mkdir","mkdirat"], "action":"SCMP_ACT_ERRNO"}]
+			}'
+	cat >profile.json <<-EOF
+		{
+			"defaultAction": "SCMP_ACT_ALLOW",
+			"architectures": ["SCMP_ARCH_X86","SCMP_ARCH_X32","SCMP_ARCH_X86_64","SCMP_ARCH_AARCH64","SCMP_ARCH_ARM"],
+			"syscalls": [{"names": ["rmdir"], "action": "SCMP_ACT_ERRNO"}]
+		}
+	EOF
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	# The container's profile.
+	runc exec test_busybox mkdir /dev/shm/foo
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"Operation not permitted"* ]]
+
+	# The process' own profile, rather than the container's one.
+	runc exec --seccomp profile.json test_busybox mkdir /dev/shm/foo
+	[ "$status" -eq 0 ]
+	runc exec --seccomp profile.json test_busybox rmdir /dev/shm/foo
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"Operation not permitted"* ]]
+
+	runc exec --seccomp unconfined test_busybox rmdir /dev/shm/foo
+	[ "$status" -eq 0 ]
+}
+
 # TODO:
 # - Test other actions like SCMP_ACT_TRAP, SCMP_ACT_TRACE, SCMP_ACT_LOG.
 # - Test args (index, value, valueTwo, etc).
@@ -75,6 +110,8 @@ function flags_value() {
 		['"SECCOMP_FILTER_FLAG_TSYNC"']=0 # Supported but ignored by runc, thus 0.
 		['"SECCOMP_FILTER_FLAG_LOG"']=2
 		['"SECCOMP_FILTER_FLAG_SPEC_ALLOW"']=4
+		# Only set with SCMP_ACT_NOTIFY rules, thus 0 here.
+		['"SECCOMP_FILTER_FLAG_WAIT_KILLABLE_RECV"']=0
 		# XXX: add new values above this line.
 	)
 	# Split the flags.
@@ -185,3 +222,20 @@ function flags_value() {
 	[[ "$output" == *"error running startContainer hook"* ]]
 	[[ "$output" == *"bad system call"* ]]
 }
+
+@test "runc seccomp compile" {
+	update_config '	  .linux.seccomp = {
+				"defaultAction": "SCMP_ACT_ERRNO",
+				"syscalls": [{"names": ["read", "write"], "action": "SCMP_ACT_ALLOW"}]
+			}'
+
+	runc seccomp compile
+	[ "$status" -eq 0 ]
+	[[ "${lines[0]}" == "# flags: "* ]]
+	[[ "$output" == *"ret #2147418112"* ]] # SECCOMP_RET_ALLOW
+
+	update_config '	  del(.linux.seccomp)'
+	runc seccomp compile
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"no seccomp profile"* ]]
+}
@@ -39,3 +39,49 @@ function teardown() {
 
 	./validate "$SCHEMA" config.json
 }
+
+@test "spec --validate-seccomp" {
+	update_config '	  .linux.seccomp = {
+				"defaultAction": "SCMP_ACT_ERRNO",
+				"syscalls": [
+					{"names": ["read", "write"], "action": "SCMP_ACT_ALLOW"},
+					{"names": ["getpid"], "action": "SCMP_ACT_ERRNO"},
+					{"names": ["no_such_syscall"], "action": "SCMP_ACT_ALLOW"}
+				]
+			}'
+	runc spec --validate-seccomp
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"warning: syscall getpid"*"redundant"* ]]
+	[[ "$output" == *"warning: syscall no_such_syscall is not known"* ]]
+
+	update_config '	  .linux.seccomp.syscalls += [{"names": ["write"], "action": "SCMP_ACT_KILL"}]'
+	runc spec --validate-seccomp
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"error: syscall write: conflicting rules"* ]]
+
+	# Such a profile is also rejected at create time.
+	runc run test_hello
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"invalid seccomp profile"* ]]
+}
+
+@test "spec --seccomp default" {
+	rm config.json
+	runc spec --seccomp default
+	[ "$status" -eq 0 ]
+	[ "$(jq -r '.linux.seccomp.defaultAction' config.json)" = "SCMP_ACT_ERRNO" ]
+
+	runc spec --validate-seccomp
+	[ "$status" -eq 0 ]
+
+	update_config '	  .root.path = "rootfs"
+			| .process.args = ["/bin/echo", "Hello World"]
+			| .process.terminal = false'
+	runc run test_hello
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"Hello World"* ]]
+
+	runc spec --seccomp no-such-profile
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"unknown seccomp profile"* ]]
+}
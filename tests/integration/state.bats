@@ -61,3 +61,41 @@ function teardown() {
 	# test state of busybox is back to running
 	testcontainer test_busybox running
 }
+
+@test "state with launch record" {
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+	runc state test_busybox
+	[ "$status" -eq 0 ]
+	[ "$(jq .launchRecord <<<"$output")" = "null" ]
+
+	update_config '.annotations += {"org.opencontainers.runc.launch-record": "true"}'
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_measured
+	[ "$status" -eq 0 ]
+	runc state test_measured
+	[ "$status" -eq 0 ]
+	record=$(jq -c .launchRecord <<<"$output")
+	[ "$(jq -r .id <<<"$record")" = "test_measured" ]
+	[[ "$(jq -r .config <<<"$record")" == sha256:* ]]
+	# The rootfs is only measured on request.
+	[ "$(jq .rootfs <<<"$record")" = "null" ]
+
+	# The digest is the one of the record without it.
+	digest="sha256:$(jq -c '.digest = ""' <<<"$record" | sha256sum | cut -d' ' -f1)"
+	[ "$(jq -r .digest <<<"$record")" = "$digest" ]
+
+	update_config '.annotations += {"org.opencontainers.runc.launch-record.rootfs": "true"}'
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_measured2
+	[ "$status" -eq 0 ]
+	runc state test_measured2
+	[ "$status" -eq 0 ]
+	rootfs=$(jq -r .launchRecord.rootfs <<<"$output")
+	[[ "$rootfs" == sha256:* ]]
+
+	# The same rootfs gives the same manifest.
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_measured3
+	[ "$status" -eq 0 ]
+	runc state test_measured3
+	[ "$status" -eq 0 ]
+	[ "$(jq -r .launchRecord.rootfs <<<"$output")" = "$rootfs" ]
+}
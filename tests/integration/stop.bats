@@ -0,0 +1,77 @@
+#!/usr/bin/env bats
+
+load helpers
+
+function setup() {
+	setup_busybox
+}
+
+function teardown() {
+	teardown_bundle
+}
+
+@test "runc stop" {
+	# The shell, being the init of its PID namespace, ignores SIGTERM,
+	# so it is killed after the grace period.
+	update_config '.annotations += {"org.opencontainers.runc.stop.grace-period": "1s"}'
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_stop
+	[ "$status" -eq 0 ]
+	testcontainer test_stop running
+
+	runc state test_stop
+	[ "$status" -eq 0 ]
+	[[ "$(jq -r .stopSignal <<<"$output")" == "SIGTERM" ]]
+	[[ "$(jq -r .stopGracePeriod <<<"$output")" == "1s" ]]
+
+	runc stop test_stop
+	[ "$status" -eq 0 ]
+	testcontainer test_stop stopped
+}
+
+@test "runc stop (stop signal)" {
+	# shellcheck disable=SC2016
+	update_config '	  .process.args = ["sh", "-c", "trap \"exit 0\" INT; while :; do sleep 0.1; done"]
+			| .annotations += {"org.opencontainers.runc.stop.signal": "SIGINT"}'
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_stop
+	[ "$status" -eq 0 ]
+	testcontainer test_stop running
+
+	runc state test_stop
+	[ "$status" -eq 0 ]
+	[[ "$(jq -r .stopSignal <<<"$output")" == "SIGINT" ]]
+	[[ "$(jq -r .stopGracePeriod <<<"$output")" == "10s" ]]
+
+	# The container handles the signal, so it exits long before the
+	# grace period ends.
+	SECONDS=0
+	runc stop test_stop
+	[ "$status" -eq 0 ]
+	[ "$SECONDS" -lt 5 ]
+	testcontainer test_stop stopped
+}
+
+@test "runc update --stop-signal --stop-grace-period" {
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_stop
+	[ "$status" -eq 0 ]
+
+	runc update --stop-signal INT --stop-grace-period 2s test_stop
+	[ "$status" -eq 0 ]
+
+	runc state test_stop
+	[ "$status" -eq 0 ]
+	[[ "$(jq -r .stopSignal <<<"$output")" == "SIGINT" ]]
+	[[ "$(jq -r .stopGracePeriod <<<"$output")" == "2s" ]]
+
+	runc update --stop-signal FOO test_stop
+	[ "$status" -ne 0 ]
+
+	# delete --force uses the stop settings.
+	SECONDS=0
+	runc delete --force test_stop
+	[ "$status" -eq 0 ]
+	[ "$SECONDS" -ge 2 ]
+	runc state test_stop
+	[ "$status" -ne 0 ]
+}
@@ -75,3 +75,58 @@ function teardown() {
 	grep -E '^monotonic\s+7881\s+2718281$' <<<"$output"
 	grep -E '^boottime\s+1337\s+3141519$' <<<"$output"
 }
+
+@test "runc run [time offsets annotation]" {
+	requires timens
+
+	update_config '.process.args = ["cat", "/proc/self/timens_offsets"]'
+	update_config '.linux.namespaces = (.linux.namespaces | map(select(.type != "time")))
+		| .linux.timeOffsets = null
+		| .annotations += {"org.opencontainers.runc.time.offsets": "monotonic=2h11m21s,boottime=-1s"}'
+
+	runc run test_busybox
+	[ "$status" -eq 0 ]
+	grep -E '^monotonic\s+7881\s+0$' <<<"$output"
+	grep -E '^boottime\s+-1\s+0$' <<<"$output"
+}
+
+@test "runc run [timezone]" {
+	local tz="Europe/Berlin"
+	if [ ! -f "/usr/share/zoneinfo/$tz" ]; then
+		skip "requires $tz in the host's timezone database"
+	fi
+
+	update_config '.process.args = ["sh", "-c", "echo $TZ; md5sum < /etc/localtime"]
+		| .annotations += {"org.opencontainers.runc.time.timezone": "'"$tz"'"}'
+
+	runc run test_busybox
+	[ "$status" -eq 0 ]
+	[ "${lines[0]}" = ":/etc/localtime" ]
+	[[ "${lines[1]}" == "$(md5sum <"/usr/share/zoneinfo/$tz" | cut -d' ' -f1)"* ]]
+
+	# TZ set by the user is kept.
+	update_config '.process.env += ["TZ=UTC"]'
+	runc run test_busybox
+	[ "$status" -eq 0 ]
+	[ "${lines[0]}" = "UTC" ]
+}
+
+@test "runc run [timezone, /etc/localtime symlink]" {
+	local tz="Europe/Berlin"
+	if [ ! -f "/usr/share/zoneinfo/$tz" ]; then
+		skip "requires $tz in the host's timezone database"
+	fi
+
+	# The image's own timezone database, which is to be left as is.
+	mkdir -p rootfs/usr/share/zoneinfo
+	echo "image UTC" >rootfs/usr/share/zoneinfo/UTC
+	ln -sf ../usr/share/zoneinfo/UTC rootfs/etc/localtime
+
+	update_config '.process.args = ["sh", "-c", "md5sum < /etc/localtime; cat /usr/share/zoneinfo/UTC"]
+		| .annotations += {"org.opencontainers.runc.time.timezone": "'"$tz"'"}'
+
+	runc run test_busybox
+	[ "$status" -eq 0 ]
+	[[ "${lines[0]}" == "$(md5sum <"/usr/share/zoneinfo/$tz" | cut -d' ' -f1)"* ]]
+	[ "${lines[1]}" = "image UTC" ]
+}
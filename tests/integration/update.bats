@@ -471,14 +471,12 @@ EOF
 		check_cgroup_value "cpu.idle" "$val"
 	done
 
-	# Values other than 1 or 0 are ignored by the kernel, see
-	# sched_group_set_idle() in kernel/sched/fair.c.
-	#
-	# If this ever fails, it means that the kernel now accepts values
-	# other than 0 or 1, and runc needs to adopt.
+	# Values other than 1 or 0 are rejected by runc, as they are by the
+	# kernel, see sched_group_set_idle() in kernel/sched/fair.c.
 	for val in -1 2 3; do
 		runc update --cpu-idle "$val" test_update
 		[ "$status" -ne 0 ]
+		[[ "$output" == *"must be 0 or 1"* ]]
 		check_cgroup_value "cpu.idle" "1"
 	done
 
@@ -490,6 +488,26 @@ EOF
 	check_cgroup_value "cpu.idle" "1"
 }
 
+@test "update cgroup cpu.idle (with cpu shares)" {
+	requires cgroups_cpu_idle
+	[ $EUID -ne 0 ] && requires rootless_cgroup
+
+	update_config '.linux.resources.cpu.shares = 512'
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_update
+	[ "$status" -eq 0 ]
+
+	# The shares (or weight) of an idle cgroup can not be set, so they
+	# are skipped rather than failing the update.
+	runc update --cpu-idle 1 --cpu-share 256 test_update
+	[ "$status" -eq 0 ]
+	check_cgroup_value "cpu.idle" "1"
+
+	runc update --cpu-idle 0 --cpu-share 256 test_update
+	[ "$status" -eq 0 ]
+	check_cgroup_value "cpu.idle" "0"
+	check_cpu_shares 256
+}
+
 @test "update cgroup cpu.idle via systemd v252+" {
 	requires cgroups_v2 systemd_v252 cgroups_cpu_idle
 	[ $EUID -ne 0 ] && requires rootless_cgroup
@@ -873,3 +891,128 @@ EOF
 	runc update test_update --memory 1024
 	wait_for_container 10 1 test_update stopped
 }
+
+@test "update hugetlb limits" {
+	requires cgroups_hugetlb
+	[ $EUID -ne 0 ] && requires rootless_cgroup
+	# shellcheck disable=SC2012 # ls is fine here.
+	size_kb=$(ls /sys/kernel/mm/hugepages/ | sed -e 's/.*hugepages-//' -e 's/kB$//' | head -1)
+	if [ -z "$size_kb" ]; then
+		skip "requires hugetlb"
+	fi
+	size=$(convert_hugetlb_size "$size_kb")
+	page=$((size_kb * 1024))
+
+	set_cgroups_path
+	update_config '.linux.resources.hugepageLimits = [{ pagesize: "'"$size"'", limit: '"$page"' }]'
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_update
+	[ "$status" -eq 0 ]
+
+	lim="max"
+	[ -v CGROUP_V1 ] && lim="limit_in_bytes"
+	check_cgroup_value "hugetlb.${size}.$lim" "$page"
+
+	runc update --hugetlb-limit "${size}:$((page * 2))" test_update
+	[ "$status" -eq 0 ]
+	check_cgroup_value "hugetlb.${size}.$lim" "$((page * 2))"
+
+	runc update -r - test_update <<EOF
+{
+  "hugepageLimits": [{ "pageSize": "$size", "limit": $((page * 3)) }]
+}
+EOF
+	[ "$status" -eq 0 ]
+	check_cgroup_value "hugetlb.${size}.$lim" "$((page * 3))"
+
+	# Other limits are kept.
+	runc update --pids-limit 42 test_update
+	[ "$status" -eq 0 ]
+	check_cgroup_value "hugetlb.${size}.$lim" "$((page * 3))"
+
+	runc update --hugetlb-limit "3KB:1M" test_update
+	[ "$status" -ne 0 ]
+	[[ "$output" == *'page size "3KB" is not supported'* ]]
+}
+
+@test "update blkio device throttles" {
+	requires root # to create a loop device
+
+	dd if=/dev/zero of=backing.img bs=4096 count=1
+	dev=$(losetup --find --show backing.img) || skip "unable to create a loop device"
+	IFS=$' \t:' read -r major minor <<<"$(lsblk -nd -o MAJ:MIN "$dev")"
+
+	update_config '.linux.resources.blockIO.throttleReadBpsDevice |= [
+			{ major: '"$major"', minor: '"$minor"', rate: 1048576 }
+		]'
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_update
+	[ "$status" -eq 0 ]
+
+	runc update -r - test_update <<EOF
+{
+  "blockIO": {
+    "throttleReadBpsDevice": [{ "major": $major, "minor": $minor, "rate": 0 }],
+    "throttleWriteIOPSDevice": [{ "major": $major, "minor": $minor, "rate": 100 }]
+  }
+}
+EOF
+	[ "$status" -eq 0 ]
+
+	if [ -v CGROUP_V2 ]; then
+		limits=$(get_cgroup_value "io.max")
+		[[ "$limits" == *"$major:$minor rbps=max wbps=max riops=max wiops=100"* ]]
+	else
+		[ -z "$(get_cgroup_value "blkio.throttle.read_bps_device")" ]
+		[ "$(get_cgroup_value "blkio.throttle.write_iops_device")" = "$major:$minor 100" ]
+	fi
+
+	# The limits are reported the same way on cgroup v1 and v2.
+	runc events --stats test_update
+	[ "$status" -eq 0 ]
+	jq -e '.data.blkio.throttleDevice == [{"major": '"$major"', "minor": '"$minor"', "op": "wiops", "value": 100}]' <<<"${lines[0]}"
+
+	runc delete -f test_update
+	losetup -d "$dev"
+}
+
+@test "update systemd unit properties" {
+	requires systemd
+	[ $EUID -ne 0 ] && requires rootless_cgroup
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_update
+	[ "$status" -eq 0 ]
+
+	runc update --systemd-property "CollectMode='inactive-or-failed'" --systemd-property TimeoutStopSec=30 test_update
+	[ "$status" -eq 0 ]
+	check_systemd_value "CollectMode" "inactive-or-failed"
+	check_systemd_value "TimeoutStopUSec" "30s"
+
+	# The properties set by runc can not be changed.
+	runc update --systemd-property "Slice='other.slice'" test_update
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"set by runc"* ]]
+
+	runc update --systemd-property NoValue test_update
+	[ "$status" -ne 0 ]
+}
+
+@test "update with proc limits" {
+	[ $EUID -ne 0 ] && requires rootless_cgroup
+	requires cgroups_memory
+
+	update_config '.annotations += {"org.opencontainers.runc.proc-limits": "true"}'
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_update
+	[ "$status" -eq 0 ]
+
+	runc exec test_update grep MemTotal /proc/meminfo
+	[ "$status" -eq 0 ]
+	[[ "$output" == *" 32768 kB"* ]]
+	runc exec test_update cat /sys/devices/system/cpu/online
+	[ "$status" -eq 0 ]
+	[ "$output" = "0" ]
+
+	runc update test_update --memory 67108864
+	[ "$status" -eq 0 ]
+	runc exec test_update grep MemTotal /proc/meminfo
+	[ "$status" -eq 0 ]
+	[[ "$output" == *" 65536 kB"* ]]
+}
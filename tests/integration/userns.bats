@@ -26,6 +26,11 @@ function setup() {
 function teardown() {
 	teardown_bundle
 
+	if [ -v ns_pid ]; then
+		kill "$ns_pid" || :
+		unset ns_pid
+	fi
+
 	if [ -v to_umount_list ]; then
 		while read -r mount_path; do
 			umount -l "$mount_path" || :
@@ -125,6 +130,33 @@ function teardown() {
 	fi
 }
 
+@test "userns join netns owned by another userns" {
+	requires root
+
+	update_config '.process.args = ["sleep", "infinity"]'
+	runc run -d --console-socket "$CONSOLE_SOCKET" target_userns
+	[ "$status" -eq 0 ]
+	target_pid="$(__runc state target_userns | jq .pid)"
+
+	# A network namespace owned by a user namespace of its own.
+	unshare --user --net sleep infinity &
+	ns_pid=$!
+	retry 10 0.1 [ "$(readlink /proc/$ns_pid/ns/net)" != "$(readlink /proc/self/ns/net)" ]
+
+	update_config '.linux.namespaces |= map(if .type == "user" then (.path = "/proc/'"$target_pid"'/ns/user") else . end)
+		| .linux.namespaces |= map(if .type == "network" then (.path = "/proc/'"$ns_pid"'/ns/net") else . end)
+		| del(.linux.uidMappings)
+		| del(.linux.gidMappings)'
+	runc --error-format json run -d --console-socket "$CONSOLE_SOCKET" in_userns
+	[ "$status" -ne 0 ]
+	[ "$(jq -r .code <<<"${lines[-1]}")" = "namespace-owner-mismatch" ]
+
+	# The override only makes it a warning.
+	runc run -d --allow-ns-owner-mismatch --console-socket "$CONSOLE_SOCKET" in_userns
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"level=warning"*"namespace is owned by another user namespace"* ]]
+}
+
 @test "userns join other container userns [bind-mounted nsfd]" {
 	requires root
 
@@ -164,3 +196,38 @@ function teardown() {
 		grep -E '^\s+0\s+'$EUID'\s+1$' <<<"$output"
 	fi
 }
+
+@test "userns with delegated cgroup" {
+	requires root cgroups_v2
+
+	update_config '.linux.namespaces += [{"type": "cgroup"}]
+		| .mounts |= map(if .destination == "/sys/fs/cgroup" then .options -= ["ro"] else . end)
+		| .annotations += {"org.opencontainers.runc.cgroup.delegate": "true"}'
+	set_cgroups_path
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_delegate
+	[ "$status" -eq 0 ]
+
+	# The cgroup and the delegated files are owned by the container's root,
+	# the resource limits are not.
+	cgroup="$(get_cgroup_path)"
+	[ "$(stat -c %u:%g "$cgroup")" = "100000:200000" ]
+	[ "$(stat -c %u:%g "$cgroup/cgroup.procs")" = "100000:200000" ]
+	[ "$(stat -c %u:%g "$cgroup/cgroup.subtree_control")" = "100000:200000" ]
+	[ "$(stat -c %u:%g "$cgroup/cgroup.controllers")" = "0:0" ]
+
+	# So the container can create its own sub-cgroups.
+	runc exec test_delegate mkdir /sys/fs/cgroup/sub
+	[ "$status" -eq 0 ]
+}
+
+@test "userns with delegated cgroup [no cgroupns]" {
+	requires root cgroups_v2
+
+	update_config '.linux.namespaces -= [{"type": "cgroup"}]
+		| .annotations += {"org.opencontainers.runc.cgroup.delegate": "true"}'
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_delegate
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"cgroup delegation requires a new cgroup namespace"* ]]
+}
@@ -12,6 +12,55 @@ type Event struct {
 	Data interface{} `json:"data,omitempty"`
 }
 
+// MemoryThreshold is the data of a "memory_threshold" event, which is emitted
+// when the container memory usage rises above the given percentage of its
+// memory limit.
+type MemoryThreshold struct {
+	Percent uint `json:"percent"`
+}
+
+// MemoryPressure is the data of a "memory_pressure" event, which is emitted
+// when the container reaches the given memory pressure level (one of "low",
+// "medium", or "critical").
+type MemoryPressure struct {
+	Level string `json:"level"`
+}
+
+// PidsLimitHit is the data of a "pids_limit_hit" event, which is emitted
+// when a process of the container fails to fork or clone because of the
+// pids limit.
+type PidsLimitHit struct {
+	// Count is the number of times the limit was hit so far.
+	Count uint64 `json:"count"`
+	Limit uint64 `json:"limit,omitempty"`
+}
+
+// OOM is the data of an "oom" event. The victim details (Pid, Comm, and RSS)
+// are only present if they can be obtained from the kernel log.
+type OOM struct {
+	// Count is the number of OOM kills in the container so far.
+	Count uint64 `json:"count"`
+	Pid   int    `json:"pid,omitempty"`
+	Comm  string `json:"comm,omitempty"`
+	// Units: bytes.
+	RSS uint64 `json:"rss,omitempty"`
+}
+
+// Usage is a summary of the container resource usage, written by
+// "runc delete --usage-file" just before the container is destroyed.
+type Usage struct {
+	ID string `json:"id"`
+	// Units: nanoseconds.
+	CPUTotal  uint64 `json:"cpu_total"`
+	CPUUser   uint64 `json:"cpu_user"`
+	CPUKernel uint64 `json:"cpu_kernel"`
+	// Units: bytes.
+	MemoryPeak uint64 `json:"memory_peak"`
+	BlkioRead  uint64 `json:"blkio_read"`
+	BlkioWrite uint64 `json:"blkio_write"`
+	OOMKills   uint64 `json:"oom_kills"`
+}
+
 // stats is the runc specific stats structure for stability when encoding and decoding stats.
 type Stats struct {
 	CPU               Cpu                 `json:"cpu"`
@@ -20,6 +69,8 @@ type Stats struct {
 	Pids              Pids                `json:"pids"`
 	Blkio             Blkio               `json:"blkio"`
 	Hugetlb           map[string]Hugetlb  `json:"hugetlb"`
+	Misc              map[string]Misc     `json:"misc,omitempty"`
+	Rdma              *Rdma               `json:"rdma,omitempty"`
 	IntelRdt          IntelRdt            `json:"intel_rdt"`
 	NetworkInterfaces []*NetworkInterface `json:"network_interfaces"`
 }
@@ -29,9 +80,23 @@ type PSIData = cgroups.PSIData
 type PSIStats = cgroups.PSIStats
 
 type Hugetlb struct {
-	Usage   uint64 `json:"usage,omitempty"`
-	Max     uint64 `json:"max,omitempty"`
-	Failcnt uint64 `json:"failcnt"`
+	Usage   uint64   `json:"usage,omitempty"`
+	Max     uint64   `json:"max,omitempty"`
+	Failcnt uint64   `json:"failcnt"`
+	Fault   *Hugetlb `json:"fault,omitempty"`
+}
+
+type Misc struct {
+	Usage  uint64 `json:"usage,omitempty"`
+	Events uint64 `json:"events,omitempty"`
+	Limit  uint64 `json:"limit,omitempty"`
+}
+
+type RdmaEntry = cgroups.RdmaEntry
+
+type Rdma struct {
+	Limit   []RdmaEntry `json:"limit,omitempty"`
+	Current []RdmaEntry `json:"current,omitempty"`
 }
 
 type BlkioEntry struct {
@@ -51,17 +116,23 @@ type Blkio struct {
 	IoTimeRecursive         []BlkioEntry `json:"ioTimeRecursive,omitempty"`
 	SectorsRecursive        []BlkioEntry `json:"sectorsRecursive,omitempty"`
 	PSI                     *PSIStats    `json:"psi,omitempty"`
+	Weight                  uint64       `json:"weight,omitempty"`
+	WeightDevice            []BlkioEntry `json:"weightDevice,omitempty"`
+	ThrottleDevice          []BlkioEntry `json:"throttleDevice,omitempty"`
 }
 
 type Pids struct {
-	Current uint64 `json:"current,omitempty"`
-	Limit   uint64 `json:"limit,omitempty"`
+	Current   uint64 `json:"current,omitempty"`
+	Limit     uint64 `json:"limit,omitempty"`
+	LimitHits uint64 `json:"limitHits,omitempty"`
 }
 
 type Throttling struct {
 	Periods          uint64 `json:"periods,omitempty"`
 	ThrottledPeriods uint64 `json:"throttledPeriods,omitempty"`
 	ThrottledTime    uint64 `json:"throttledTime,omitempty"`
+	Bursts           uint64 `json:"bursts,omitempty"`
+	BurstTime        uint64 `json:"burstTime,omitempty"`
 }
 
 type CpuUsage struct {
@@ -105,12 +176,18 @@ type Memory struct {
 	Cache     uint64            `json:"cache,omitempty"`
 	Usage     MemoryEntry       `json:"usage,omitempty"`
 	Swap      MemoryEntry       `json:"swap,omitempty"`
+	Zswap     MemoryEntry       `json:"zswap,omitempty"`
 	Kernel    MemoryEntry       `json:"kernel,omitempty"`
 	KernelTCP MemoryEntry       `json:"kernelTCP,omitempty"`
 	Raw       map[string]uint64 `json:"raw,omitempty"`
 	PSI       *PSIStats         `json:"psi,omitempty"`
+	// Events and EventsLocal are only available on cgroup v2.
+	Events      *MemoryEvents `json:"events,omitempty"`
+	EventsLocal *MemoryEvents `json:"events_local,omitempty"`
 }
 
+type MemoryEvents = cgroups.MemoryEvents
+
 type L3CacheInfo struct {
 	CbmMask    string `json:"cbm_mask,omitempty"`
 	MinCbmBits uint64 `json:"min_cbm_bits,omitempty"`
@@ -19,6 +19,23 @@ const (
 	// Third party implementations such as crun and runsc MAY use this annotation.
 	AnnotationRuncCheckpointEnabled = "org.opencontainers.runc.checkpoint.enabled"
 
+	// AnnotationRuncCheckpointCriuVersion is the version of the criu binary found by runc, e.g., "3.17.1".
+	// Not present if criu is not found.
+	AnnotationRuncCheckpointCriuVersion = "org.opencontainers.runc.checkpoint.criu.version"
+
+	// AnnotationRuncCheckpointFeatures is the comma-separated list of the checkpoint/restore features
+	// supported by criu and the host, out of "mem_track", "lazy_pages", "pidfd_store", "memfd_hugetlb",
+	// "tun" and "sk_unix_file" (as named by criu), e.g., "lazy_pages,mem_track,tun".
+	// Not present if criu is not found.
+	AnnotationRuncCheckpointFeatures = "org.opencontainers.runc.checkpoint.features"
+
+	// AnnotationRuncCgroupAccountingOnlyEnabled is set to "true" if the accounting-only cgroup
+	// mode is supported. In this mode, enabled per container by the
+	// "org.opencontainers.runc.cgroup.accounting-only" annotation, the container's cgroup is only
+	// used for resource accounting, and no resource limits are applied.
+	// Always set to "true" in the current version of runc.
+	AnnotationRuncCgroupAccountingOnlyEnabled = "org.opencontainers.runc.cgroup.accounting-only.enabled"
+
 	// AnnotationLibseccompVersion is the version of libseccomp, e.g., "2.5.1".
 	// Note that the runtime MAY support seccomp even when this annotation is not present.
 	AnnotationLibseccompVersion = "io.github.seccomp.libseccomp.version"
@@ -6,14 +6,20 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 	"github.com/szcdx/runc/libcontainer/cgroups"
 
+	systemdDbus "github.com/coreos/go-systemd/v22/dbus"
 	"github.com/docker/go-units"
 	"github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/szcdx/runc/libcontainer"
 	"github.com/szcdx/runc/libcontainer/configs"
+	"github.com/szcdx/runc/libcontainer/devices"
 	"github.com/szcdx/runc/libcontainer/intelrdt"
+	"github.com/szcdx/runc/libcontainer/specconv"
+	"github.com/szcdx/runc/libcontainer/utils"
 	"github.com/urfave/cli"
 )
 
@@ -53,7 +59,39 @@ The accepted format is as follow (unchanged values can be omitted):
     "idle": 0
   },
   "blockIO": {
-    "weight": 0
+    "weight": 0,
+    "weightDevice": [
+      {
+        "major": 0,
+        "minor": 0,
+        "weight": 0
+      }
+    ],
+    "throttleReadBpsDevice": [
+      {
+        "major": 0,
+        "minor": 0,
+        "rate": 0
+      }
+    ],
+    "throttleWriteBpsDevice": [],
+    "throttleReadIOPSDevice": [],
+    "throttleWriteIOPSDevice": []
+  },
+  "pids": {
+    "limit": 0
+  },
+  "hugepageLimits": [
+    {
+      "pageSize": "",
+      "limit": 0
+    }
+  ],
+  "rdma": {
+    "<device>": {
+      "hcaHandles": 0,
+      "hcaObjects": 0
+    }
   }
 }
 
@@ -128,6 +166,14 @@ other options are ignored.
 			Name:  "pids-limit",
 			Usage: "Maximum number of pids allowed in the container",
 		},
+		cli.StringSliceFlag{
+			Name:  "hugetlb-limit",
+			Usage: "Hugetlb limit, in the form PAGESIZE:LIMIT (e.g. 2MB:64M); can be specified multiple times",
+		},
+		cli.StringSliceFlag{
+			Name:  "rdma-limit",
+			Usage: "RDMA limit, in the form DEVICE:hca_handle=NUM,hca_object=NUM; can be specified multiple times",
+		},
 		cli.StringFlag{
 			Name:  "l3-cache-schema",
 			Usage: "The string of Intel RDT/CAT L3 cache schema",
@@ -136,6 +182,26 @@ other options are ignored.
 			Name:  "mem-bw-schema",
 			Usage: "The string of Intel RDT/MBA memory bandwidth schema",
 		},
+		cli.StringFlag{
+			Name:  "stop-signal",
+			Usage: "signal sent to the container init by runc stop and runc delete --force",
+		},
+		cli.DurationFlag{
+			Name:  "stop-grace-period",
+			Usage: "how long to wait for the container to exit after the stop signal, before killing it",
+		},
+		cli.StringSliceFlag{
+			Name:  "device-add",
+			Usage: "device to add to the container, in the form HOST_PATH[:CONTAINER_PATH][:PERMISSIONS]; can be specified multiple times",
+		},
+		cli.StringSliceFlag{
+			Name:  "device-rm",
+			Usage: "path (in the container) of a device to remove from the container; can be specified multiple times",
+		},
+		cli.StringSliceFlag{
+			Name:  "systemd-property",
+			Usage: "systemd unit property to set, in the form NAME=VALUE (VALUE in the GVariant format); can be specified multiple times",
+		},
 	},
 	Action: func(context *cli.Context) error {
 		if err := checkArgs(context, 1, exactArgs); err != nil {
@@ -146,6 +212,37 @@ other options are ignored.
 			return err
 		}
 
+		// The flags which are not about the resources.
+		other := 0
+		if context.IsSet("stop-signal") || context.IsSet("stop-grace-period") {
+			n, err := updateStop(context, container)
+			if err != nil {
+				return err
+			}
+			other += n
+		}
+		if context.IsSet("device-add") || context.IsSet("device-rm") {
+			if err := updateDevices(context, container); err != nil {
+				return err
+			}
+			if context.IsSet("device-add") {
+				other++
+			}
+			if context.IsSet("device-rm") {
+				other++
+			}
+		}
+		if props := context.StringSlice("systemd-property"); len(props) > 0 {
+			if err := updateSystemdProperties(container, props); err != nil {
+				return err
+			}
+			other++
+		}
+		// Only update the resources if asked to.
+		if other > 0 && context.NumFlags() == other {
+			return nil
+		}
+
 		r := specs.LinuxResources{
 			Memory: &specs.LinuxMemory{
 				Limit:             i64Ptr(0),
@@ -270,6 +367,24 @@ other options are ignored.
 			}
 
 			r.Pids.Limit = int64(context.Int("pids-limit"))
+
+			for _, val := range context.StringSlice("hugetlb-limit") {
+				l, err := parseHugetlbLimit(val)
+				if err != nil {
+					return fmt.Errorf("invalid value for hugetlb-limit: %w", err)
+				}
+				r.HugepageLimits = append(r.HugepageLimits, l)
+			}
+			for _, val := range context.StringSlice("rdma-limit") {
+				dev, l, err := parseRdmaLimit(val)
+				if err != nil {
+					return fmt.Errorf("invalid value for rdma-limit: %w", err)
+				}
+				if r.Rdma == nil {
+					r.Rdma = make(map[string]specs.LinuxRdma)
+				}
+				r.Rdma[dev] = l
+			}
 		}
 
 		if *r.Memory.Kernel != 0 || *r.Memory.KernelTCP != 0 {
@@ -278,6 +393,18 @@ other options are ignored.
 
 		// Update the values
 		config.Cgroups.Resources.BlkioWeight = *r.BlockIO.Weight
+		config.Cgroups.Resources.BlkioWeightDevice = updateWeightDevices(config.Cgroups.Resources.BlkioWeightDevice, r.BlockIO.WeightDevice)
+		for _, t := range []struct {
+			dest *[]*configs.ThrottleDevice
+			upd  []specs.LinuxThrottleDevice
+		}{
+			{&config.Cgroups.Resources.BlkioThrottleReadBpsDevice, r.BlockIO.ThrottleReadBpsDevice},
+			{&config.Cgroups.Resources.BlkioThrottleWriteBpsDevice, r.BlockIO.ThrottleWriteBpsDevice},
+			{&config.Cgroups.Resources.BlkioThrottleReadIOPSDevice, r.BlockIO.ThrottleReadIOPSDevice},
+			{&config.Cgroups.Resources.BlkioThrottleWriteIOPSDevice, r.BlockIO.ThrottleWriteIOPSDevice},
+		} {
+			*t.dest = updateThrottleDevices(*t.dest, t.upd)
+		}
 
 		// Setting CPU quota and period independently does not make much sense,
 		// but historically runc allowed it and this needs to be supported
@@ -315,11 +442,20 @@ other options are ignored.
 		config.Cgroups.Resources.CpusetCpus = r.CPU.Cpus
 		config.Cgroups.Resources.CpusetMems = r.CPU.Mems
 		config.Cgroups.Resources.Memory = *r.Memory.Limit
+		if idle := r.CPU.Idle; idle != nil && *idle != 0 && *idle != 1 {
+			return fmt.Errorf("invalid value for cpu-idle: %d (must be 0 or 1)", *idle)
+		}
 		config.Cgroups.Resources.CPUIdle = r.CPU.Idle
 		config.Cgroups.Resources.MemoryReservation = *r.Memory.Reservation
 		config.Cgroups.Resources.MemorySwap = *r.Memory.Swap
 		config.Cgroups.Resources.MemoryCheckBeforeUpdate = *r.Memory.CheckBeforeUpdate
 		config.Cgroups.Resources.PidsLimit = r.Pids.Limit
+		hugetlb, err := updateHugetlbLimits(config.Cgroups.Resources.HugetlbLimit, r.HugepageLimits)
+		if err != nil {
+			return err
+		}
+		config.Cgroups.Resources.HugetlbLimit = hugetlb
+		config.Cgroups.Resources.Rdma = updateRdmaLimits(config.Cgroups.Resources.Rdma, r.Rdma)
 		config.Cgroups.Resources.Unified = r.Unified
 
 		// Update Intel RDT
@@ -363,3 +499,248 @@ other options are ignored.
 		return container.Set(config)
 	},
 }
+
+// updateStop changes the stop signal and grace period of the container to
+// the ones given, and returns the number of flags used.
+func updateStop(context *cli.Context, container *libcontainer.Container) (int, error) {
+	n := 0
+	stop := &configs.Stop{}
+	if s := container.Config().Stop; s != nil {
+		*stop = *s
+	}
+	if context.IsSet("stop-signal") {
+		sig, err := utils.ParseSignal(context.String("stop-signal"))
+		if err != nil {
+			return 0, err
+		}
+		stop.Signal = int(sig)
+		n++
+	}
+	if context.IsSet("stop-grace-period") {
+		d := context.Duration("stop-grace-period")
+		if d <= 0 {
+			return 0, fmt.Errorf("invalid stop grace period %s", d)
+		}
+		stop.GracePeriod = d
+		n++
+	}
+	return n, container.SetStop(stop)
+}
+
+// updateDevices adds the devices given with --device-add to the container,
+// and removes the ones given with --device-rm.
+func updateDevices(context *cli.Context, container *libcontainer.Container) error {
+	var add []*devices.Device
+	for _, val := range context.StringSlice("device-add") {
+		dev, err := parseDeviceAdd(val)
+		if err != nil {
+			return fmt.Errorf("invalid value for device-add: %w", err)
+		}
+		add = append(add, dev)
+	}
+	return container.UpdateDevices(add, context.StringSlice("device-rm"))
+}
+
+// parseDeviceAdd parses a device in the
+// HOST_PATH[:CONTAINER_PATH][:PERMISSIONS] form. The container path defaults
+// to the host path, and the permissions to rwm.
+func parseDeviceAdd(val string) (*devices.Device, error) {
+	parts := strings.Split(val, ":")
+	if len(parts) > 3 || parts[0] == "" {
+		return nil, fmt.Errorf("%q: expected HOST_PATH[:CONTAINER_PATH][:PERMISSIONS]", val)
+	}
+	path, perms := parts[0], "rwm"
+	switch len(parts) {
+	case 2:
+		// The second part is the permissions if it looks like them.
+		if devices.Permissions(parts[1]).IsValid() && !strings.HasPrefix(parts[1], "/") {
+			perms = parts[1]
+		} else {
+			path = parts[1]
+		}
+	case 3:
+		path, perms = parts[1], parts[2]
+	}
+	dev, err := devices.DeviceFromPath(parts[0], perms)
+	if err != nil {
+		return nil, err
+	}
+	dev.Path = path
+	return dev, nil
+}
+
+// updateSystemdProperties sets the systemd unit properties of the
+// container, given in the NAME=VALUE form.
+func updateSystemdProperties(container *libcontainer.Container, props []string) error {
+	properties := make([]systemdDbus.Property, 0, len(props))
+	for _, p := range props {
+		name, value, ok := strings.Cut(p, "=")
+		if !ok || name == "" {
+			return fmt.Errorf("invalid systemd property %q: expected NAME=VALUE", p)
+		}
+		prop, err := specconv.ParseSystemdProperty(name, value)
+		if err != nil {
+			return err
+		}
+		properties = append(properties, prop)
+	}
+	return container.SetSystemdProperties(properties...)
+}
+
+// parseHugetlbLimit parses a hugetlb limit in the PAGESIZE:LIMIT form.
+func parseHugetlbLimit(val string) (specs.LinuxHugepageLimit, error) {
+	size, limit, ok := strings.Cut(val, ":")
+	if !ok || size == "" {
+		return specs.LinuxHugepageLimit{}, fmt.Errorf("%q: expected PAGESIZE:LIMIT", val)
+	}
+	v, err := units.RAMInBytes(limit)
+	if err != nil {
+		return specs.LinuxHugepageLimit{}, err
+	}
+	if v < 0 {
+		return specs.LinuxHugepageLimit{}, fmt.Errorf("%q: negative limit", val)
+	}
+	return specs.LinuxHugepageLimit{Pagesize: size, Limit: uint64(v)}, nil
+}
+
+// parseRdmaLimit parses an RDMA limit in the
+// DEVICE:hca_handle=NUM,hca_object=NUM form (either of the limits
+// can be omitted).
+func parseRdmaLimit(val string) (string, specs.LinuxRdma, error) {
+	var l specs.LinuxRdma
+	dev, limits, ok := strings.Cut(val, ":")
+	if !ok || dev == "" || limits == "" {
+		return "", l, fmt.Errorf("%q: expected DEVICE:hca_handle=NUM,hca_object=NUM", val)
+	}
+	for _, kv := range strings.Split(limits, ",") {
+		k, v, _ := strings.Cut(kv, "=")
+		n, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return "", l, fmt.Errorf("%q: %w", val, err)
+		}
+		n32 := uint32(n)
+		switch k {
+		case "hca_handle":
+			l.HcaHandles = &n32
+		case "hca_object":
+			l.HcaObjects = &n32
+		default:
+			return "", l, fmt.Errorf("%q: unknown limit %q", val, k)
+		}
+	}
+	return dev, l, nil
+}
+
+// updateHugetlbLimits returns the hugetlb limits from cur, with the limits
+// for the page sizes present in upd replaced (or added).
+func updateHugetlbLimits(cur []*configs.HugepageLimit, upd []specs.LinuxHugepageLimit) ([]*configs.HugepageLimit, error) {
+	if len(upd) == 0 {
+		return cur, nil
+	}
+	sizes := cgroups.HugePageSizes()
+	supported := make(map[string]bool, len(sizes))
+	for _, size := range sizes {
+		supported[size] = true
+	}
+	limits := make([]*configs.HugepageLimit, 0, len(cur)+len(upd))
+	for _, l := range cur {
+		limits = append(limits, &configs.HugepageLimit{Pagesize: l.Pagesize, Limit: l.Limit})
+	}
+next:
+	for _, u := range upd {
+		if !supported[u.Pagesize] {
+			return nil, fmt.Errorf("hugetlb page size %q is not supported (supported sizes: %s)", u.Pagesize, strings.Join(sizes, ", "))
+		}
+		for _, l := range limits {
+			if l.Pagesize == u.Pagesize {
+				l.Limit = u.Limit
+				continue next
+			}
+		}
+		limits = append(limits, &configs.HugepageLimit{Pagesize: u.Pagesize, Limit: u.Limit})
+	}
+	return limits, nil
+}
+
+// updateRdmaLimits returns the RDMA limits from cur, updated with the
+// limits from upd. The limits which are not set in upd are left as is.
+func updateRdmaLimits(cur map[string]configs.LinuxRdma, upd map[string]specs.LinuxRdma) map[string]configs.LinuxRdma {
+	if len(upd) == 0 {
+		return cur
+	}
+	limits := make(map[string]configs.LinuxRdma, len(cur)+len(upd))
+	for dev, l := range cur {
+		limits[dev] = l
+	}
+	for dev, u := range upd {
+		l := limits[dev]
+		if u.HcaHandles != nil {
+			l.HcaHandles = u.HcaHandles
+		}
+		if u.HcaObjects != nil {
+			l.HcaObjects = u.HcaObjects
+		}
+		limits[dev] = l
+	}
+	return limits
+}
+
+// updateWeightDevices returns the block IO device weights from cur, updated
+// with the weights from upd. The weights which are not set in upd are left
+// as is.
+func updateWeightDevices(cur []*configs.WeightDevice, upd []specs.LinuxWeightDevice) []*configs.WeightDevice {
+	if len(upd) == 0 {
+		return cur
+	}
+	devices := make([]*configs.WeightDevice, 0, len(cur)+len(upd))
+	for _, wd := range cur {
+		devices = append(devices, configs.NewWeightDevice(wd.Major, wd.Minor, wd.Weight, wd.LeafWeight))
+	}
+next:
+	for _, u := range upd {
+		for _, wd := range devices {
+			if wd.Major == u.Major && wd.Minor == u.Minor {
+				if u.Weight != nil {
+					wd.Weight = *u.Weight
+				}
+				if u.LeafWeight != nil {
+					wd.LeafWeight = *u.LeafWeight
+				}
+				continue next
+			}
+		}
+		var weight, leafWeight uint16
+		if u.Weight != nil {
+			weight = *u.Weight
+		}
+		if u.LeafWeight != nil {
+			leafWeight = *u.LeafWeight
+		}
+		devices = append(devices, configs.NewWeightDevice(u.Major, u.Minor, weight, leafWeight))
+	}
+	return devices
+}
+
+// updateThrottleDevices returns the block IO device throttles from cur, with
+// the throttles for the devices present in upd replaced (or added). A zero
+// rate removes the limit.
+func updateThrottleDevices(cur []*configs.ThrottleDevice, upd []specs.LinuxThrottleDevice) []*configs.ThrottleDevice {
+	if len(upd) == 0 {
+		return cur
+	}
+	devices := make([]*configs.ThrottleDevice, 0, len(cur)+len(upd))
+	for _, td := range cur {
+		devices = append(devices, configs.NewThrottleDevice(td.Major, td.Minor, td.Rate))
+	}
+next:
+	for _, u := range upd {
+		for _, td := range devices {
+			if td.Major == u.Major && td.Minor == u.Minor {
+				td.Rate = u.Rate
+				continue next
+			}
+		}
+		devices = append(devices, configs.NewThrottleDevice(u.Major, u.Minor, u.Rate))
+	}
+	return devices
+}
@@ -20,21 +20,36 @@ const (
 	maxArgs
 )
 
+// errUsage is matched (by errors.Is) by the errors about the command line
+// usage.
+var errUsage = errors.New("incorrect usage")
+
+// usageError is an error about the command line usage.
+type usageError string
+
+func (e usageError) Error() string {
+	return string(e)
+}
+
+func (usageError) Is(target error) bool {
+	return target == errUsage
+}
+
 func checkArgs(context *cli.Context, expected, checkType int) error {
 	var err error
 	cmdName := context.Command.Name
 	switch checkType {
 	case exactArgs:
 		if context.NArg() != expected {
-			err = fmt.Errorf("%s: %q requires exactly %d argument(s)", os.Args[0], cmdName, expected)
+			err = usageError(fmt.Sprintf("%s: %q requires exactly %d argument(s)", os.Args[0], cmdName, expected))
 		}
 	case minArgs:
 		if context.NArg() < expected {
-			err = fmt.Errorf("%s: %q requires a minimum of %d argument(s)", os.Args[0], cmdName, expected)
+			err = usageError(fmt.Sprintf("%s: %q requires a minimum of %d argument(s)", os.Args[0], cmdName, expected))
 		}
 	case maxArgs:
 		if context.NArg() > expected {
-			err = fmt.Errorf("%s: %q requires a maximum of %d argument(s)", os.Args[0], cmdName, expected)
+			err = usageError(fmt.Sprintf("%s: %q requires a maximum of %d argument(s)", os.Args[0], cmdName, expected))
 		}
 	}
 
@@ -58,6 +73,14 @@ func fatal(err error) {
 }
 
 func fatalWithCode(err error, ret int) {
+	if errorFormat == "json" {
+		// The JSON error is the only thing written to stderr.
+		if !logrusToStderr() {
+			logrus.Error(err)
+		}
+		writeJSONError(err)
+		os.Exit(ret)
+	}
 	// Make sure the error is written to the logger.
 	logrus.Error(err)
 	if !logrusToStderr() {
@@ -79,6 +102,9 @@ func setupSpec(context *cli.Context) (*specs.Spec, error) {
 	if err != nil {
 		return nil, err
 	}
+	if hostConfig != nil {
+		hostConfig.applySpec(spec)
+	}
 	return spec, nil
 }
 
@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"time"
 
 	"github.com/coreos/go-systemd/v22/activation"
 	"github.com/opencontainers/runtime-spec/specs-go"
@@ -16,6 +17,8 @@ import (
 	"golang.org/x/sys/unix"
 
 	"github.com/szcdx/runc/libcontainer"
+	"github.com/szcdx/runc/libcontainer/cdi"
+	"github.com/szcdx/runc/libcontainer/cgroups"
 	"github.com/szcdx/runc/libcontainer/configs"
 	"github.com/szcdx/runc/libcontainer/specconv"
 	"github.com/szcdx/runc/libcontainer/system/kernelversion"
@@ -168,22 +171,41 @@ func createContainer(context *cli.Context, id string, spec *specs.Spec) (*libcon
 		return nil, err
 	}
 	config, err := specconv.CreateLibcontainerConfig(&specconv.CreateOpts{
-		CgroupName:       id,
-		UseSystemdCgroup: context.GlobalBool("systemd-cgroup"),
-		NoPivotRoot:      context.Bool("no-pivot"),
-		NoNewKeyring:     context.Bool("no-new-keyring"),
-		Spec:             spec,
-		RootlessEUID:     os.Geteuid() != 0,
-		RootlessCgroups:  rootlessCg,
+		CgroupName:                  id,
+		UseSystemdCgroup:            context.GlobalBool("systemd-cgroup"),
+		NoPivotRoot:                 context.Bool("no-pivot"),
+		NoNewKeyring:                context.Bool("no-new-keyring"),
+		AllowNamespaceOwnerMismatch: context.Bool("allow-ns-owner-mismatch"),
+		Spec:                        spec,
+		RootlessEUID:                os.Geteuid() != 0,
+		RootlessCgroups:             rootlessCg,
 	})
 	if err != nil {
 		return nil, err
 	}
 
+	warnUnsupportedResources(config.Cgroups)
+
 	root := context.GlobalString("root")
 	return libcontainer.Create(root, id, config)
 }
 
+// warnUnsupportedResources warns about the resource limits of the container
+// which can not be applied on this host, and would be ignored otherwise.
+func warnUnsupportedResources(cg *configs.Cgroup) {
+	// In the accounting-only mode, no limits are applied anyway.
+	if cg == nil || cg.AccountingOnly {
+		return
+	}
+	for _, f := range cgroups.UnsupportedFields(cg.Resources) {
+		logrus.WithFields(logrus.Fields{
+			"field":      f.Field,
+			"controller": f.Controller,
+			"reason":     f.Reason,
+		}).Warn("resource limit can not be applied on this host, ignoring")
+	}
+}
+
 type runner struct {
 	init            bool
 	enableSubreaper bool
@@ -194,11 +216,15 @@ type runner struct {
 	pidFile         string
 	consoleSocket   string
 	pidfdSocket     string
+	mountSocket     string
 	container       *libcontainer.Container
 	action          CtAct
 	notifySocket    *notifySocket
 	criuOpts        *libcontainer.CriuOpts
 	subCgroupPaths  map[string]string
+	subCgroupRes    *configs.Resources
+	seccomp         *configs.Seccomp
+	noSeccomp       bool
 }
 
 func (r *runner) run(config *specs.Process) (int, error) {
@@ -219,6 +245,9 @@ func (r *runner) run(config *specs.Process) (int, error) {
 	// Populate the fields that come from runner.
 	process.Init = r.init
 	process.SubCgroupPaths = r.subCgroupPaths
+	process.Resources = r.subCgroupRes
+	process.Seccomp = r.seccomp
+	process.SeccompUnconfined = r.noSeccomp
 	if len(r.listenFDs) > 0 {
 		process.Env = append(process.Env, "LISTEN_FDS="+strconv.Itoa(len(r.listenFDs)), "LISTEN_PID=1")
 		process.ExtraFiles = append(process.ExtraFiles, r.listenFDs...)
@@ -260,6 +289,19 @@ func (r *runner) run(config *specs.Process) (int, error) {
 		defer connClose()
 	}
 
+	if r.mountSocket != "" {
+		files, err := recvMountFiles(r.mountSocket, mountFilesTimeout)
+		if err != nil {
+			return -1, err
+		}
+		defer func() {
+			for _, f := range files {
+				f.Close()
+			}
+		}()
+		process.MountFiles = files
+	}
+
 	switch r.action {
 	case CT_ACT_CREATE:
 		err = r.container.Start(process)
@@ -267,6 +309,8 @@ func (r *runner) run(config *specs.Process) (int, error) {
 		err = r.container.Restore(process, r.criuOpts)
 	case CT_ACT_RUN:
 		err = r.container.Run(process)
+	case CT_ACT_JOIN:
+		err = r.container.Join(process)
 	default:
 		panic("Unknown action")
 	}
@@ -284,7 +328,15 @@ func (r *runner) run(config *specs.Process) (int, error) {
 			return -1, err
 		}
 	}
-	status, err := handler.forward(process, tty, detach)
+	var exited <-chan struct{}
+	if !detach {
+		var nerr error
+		if exited, nerr = r.container.NotifyExit(process); nerr != nil {
+			// Only rely on SIGCHLD then.
+			logrus.Debugf("unable to watch for the process exit: %v", nerr)
+		}
+	}
+	status, err := handler.forward(process, exited, tty, detach)
 	if err != nil {
 		r.terminate(process)
 	}
@@ -347,6 +399,7 @@ const (
 	CT_ACT_CREATE CtAct = iota + 1
 	CT_ACT_RUN
 	CT_ACT_RESTORE
+	CT_ACT_JOIN
 )
 
 func startContainer(context *cli.Context, action CtAct, criuOpts *libcontainer.CriuOpts) (int, error) {
@@ -357,6 +410,11 @@ func startContainer(context *cli.Context, action CtAct, criuOpts *libcontainer.C
 	if err != nil {
 		return -1, err
 	}
+	if action == CT_ACT_RESTORE && context.String("update-resources") != "" {
+		if err := updateSpecResources(spec, context.String("update-resources")); err != nil {
+			return -1, err
+		}
+	}
 
 	id := context.Args().First()
 	if id == "" {
@@ -367,11 +425,17 @@ func startContainer(context *cli.Context, action CtAct, criuOpts *libcontainer.C
 	if notifySocket != nil {
 		notifySocket.setupSpec(spec)
 	}
+	if err := cdi.ApplyAnnotation(spec, cdi.DefaultDirs); err != nil {
+		return -1, err
+	}
 
 	container, err := createContainer(context, id, spec)
 	if err != nil {
 		return -1, err
 	}
+	if action == CT_ACT_RESTORE && context.String("update-resources") != "" {
+		criuOpts.Resources = container.Config().Cgroups.Resources
+	}
 
 	if notifySocket != nil {
 		if err := notifySocket.setupSocketDirectory(); err != nil {
@@ -398,6 +462,7 @@ func startContainer(context *cli.Context, action CtAct, criuOpts *libcontainer.C
 		notifySocket:    notifySocket,
 		consoleSocket:   context.String("console-socket"),
 		pidfdSocket:     context.String("pidfd-socket"),
+		mountSocket:     context.String("mount-socket"),
 		detach:          context.Bool("detach"),
 		pidFile:         context.String("pid-file"),
 		preserveFDs:     context.Int("preserve-fds"),
@@ -408,6 +473,66 @@ func startContainer(context *cli.Context, action CtAct, criuOpts *libcontainer.C
 	return r.run(spec.Process)
 }
 
+// mountFilesTimeout is how long runc waits for all the files of the
+// --mount-socket to be sent.
+const mountFilesTimeout = 30 * time.Second
+
+// recvMountFiles connects to the AF_UNIX socket at sockpath, and receives
+// the files the fd://N mount sources refer to, in order, one per message,
+// until the other end closes the connection, which it has to do within
+// timeout.
+func recvMountFiles(sockpath string, timeout time.Duration) ([]*os.File, error) {
+	conn, err := net.Dial("unix", sockpath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial %s: %w", sockpath, err)
+	}
+	defer conn.Close()
+	socket, err := conn.(*net.UnixConn).File()
+	if err != nil {
+		return nil, fmt.Errorf("failed to dup socket: %w", err)
+	}
+	defer socket.Close()
+
+	var files []*os.File
+	deadline := time.Now().Add(timeout)
+	for {
+		// RecvFile can't be given a deadline, so the receive timeout
+		// of the socket is set to the time left instead.
+		left := time.Until(deadline)
+		if left <= 0 {
+			left = time.Microsecond
+		}
+		tv := unix.NsecToTimeval(left.Nanoseconds())
+		err := unix.SetsockoptTimeval(int(socket.Fd()), unix.SOL_SOCKET, unix.SO_RCVTIMEO, &tv)
+		if err != nil {
+			for _, f := range files {
+				f.Close()
+			}
+			return nil, os.NewSyscallError("setsockopt SO_RCVTIMEO", err)
+		}
+		// Check for the end of the stream, which RecvFile can't tell
+		// from an error.
+		n, _, err := unix.Recvfrom(int(socket.Fd()), make([]byte, 1), unix.MSG_PEEK)
+		if err == nil && n == 0 {
+			return files, nil
+		}
+		var f *os.File
+		if err == nil {
+			f, err = utils.RecvFile(socket)
+		}
+		if errors.Is(err, unix.EAGAIN) {
+			err = fmt.Errorf("timed out after %v", timeout)
+		}
+		if err != nil {
+			for _, f := range files {
+				f.Close()
+			}
+			return nil, fmt.Errorf("failed to receive mount fd %d from %s: %w", len(files), sockpath, err)
+		}
+		files = append(files, f)
+	}
+}
+
 func setupPidfdSocket(process *libcontainer.Process, sockpath string) (_clean func(), _ error) {
 	linux530 := kernelversion.KernelVersion{Kernel: 5, Major: 3}
 	ok, err := kernelversion.GreaterEqualThan(linux530)
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/szcdx/runc/libcontainer/utils"
+)
+
+// serveMountFiles accepts a connection on the socket at sockpath, sends it
+// a copy of f, and closes it if closeConn is set.
+func serveMountFiles(t *testing.T, sockpath string, f *os.File, closeConn bool) {
+	t.Helper()
+	l, err := net.Listen("unix", sockpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done, exited := make(chan struct{}), make(chan struct{})
+	t.Cleanup(func() {
+		close(done)
+		l.Close()
+		<-exited
+	})
+	go func() {
+		defer close(exited)
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		socket, err := conn.(*net.UnixConn).File()
+		if err == nil {
+			_ = utils.SendFile(socket, f)
+			socket.Close()
+		}
+		if !closeConn {
+			<-done
+		}
+		conn.Close()
+	}()
+}
+
+func TestRecvMountFiles(t *testing.T) {
+	dir := t.TempDir()
+	f, err := os.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+
+	sockpath := dir + "/mount.sock"
+	serveMountFiles(t, sockpath, f, true)
+	files, err := recvMountFiles(sockpath, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		f.Close()
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected 1 file, got %d", len(files))
+	}
+
+	// The peer never closes the connection.
+	sockpath = dir + "/hung.sock"
+	serveMountFiles(t, sockpath, f, false)
+	start := time.Now()
+	_, err = recvMountFiles(sockpath, 100*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("expected a timeout error, got %v", err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("timed out after %v", d)
+	}
+}